@@ -0,0 +1,52 @@
+package ui
+
+// Tree queries
+//
+// The helpers below answer the usual questions about the position of an element in the tree without walking
+// the Parent chain by hand: which view element encloses it, which route displays it, and whether it belongs to
+// the subtree of another element.
+
+// IsDescendantOf returns whether e belongs to the subtree of ancestor, e excluded.
+func IsDescendantOf(e, ancestor AnyElement) bool {
+	a := ancestor.AsElement()
+	for p := e.AsElement().Parent; p != nil; p = p.Parent {
+		if p == a {
+			return true
+		}
+	}
+	return false
+}
+
+// ClosestAncestor returns the closest ancestor of e, e included, that satisfies the predicate.
+func ClosestAncestor(e AnyElement, predicate func(*Element) bool) (*Element, bool) {
+	for el := e.AsElement(); el != nil; el = el.Parent {
+		if predicate(el) {
+			return el, true
+		}
+	}
+	return nil, false
+}
+
+// ClosestView returns the closest view element enclosing e, e excluded.
+func ClosestView(e AnyElement) (ViewElement, bool) {
+	p := e.AsElement().Parent
+	if p == nil {
+		return ViewElement{}, false
+	}
+	v, ok := ClosestAncestor(p, (*Element).isViewElement)
+	if !ok {
+		return ViewElement{}, false
+	}
+	return ViewElement{v}, true
+}
+
+// RouteOf returns the route that displays e, i.e. the route of e or, if e is not itself part of a view,
+// the route of its closest ancestor that is. It returns an empty string if e is not displayed by any route.
+func RouteOf(e AnyElement) string {
+	for el := e.AsElement(); el != nil; el = el.Parent {
+		if r := el.Route(); r != "" {
+			return r
+		}
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package ui
+
+import "testing"
+
+func TestAncestry(t *testing.T) {
+	c := NewConfiguration("ancestrytest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	root := c.NewAppRoot("root")
+	panel, home, label, other := newdiv("panel"), newdiv("home"), newdiv("label"), newdiv("other")
+	for _, e := range []*Element{panel, home, label, other} {
+		RegisterElement(root, e)
+	}
+	home.AppendChild(label)
+	main := NewViewElement(panel, NewView("home", home))
+	root.AppendChild(main)
+	root.AppendChild(other)
+	main.ActivateView("home")
+
+	if !IsDescendantOf(label, panel) || !IsDescendantOf(label, root) {
+		t.Fatal("expected label to be a descendant of the view element and of the root")
+	}
+	if IsDescendantOf(label, label) || IsDescendantOf(panel, label) || IsDescendantOf(label, other) {
+		t.Fatal("unexpected descendant relationship")
+	}
+
+	if v, ok := ClosestView(label); !ok || v.AsElement() != panel {
+		t.Fatal("expected the enclosing view element to be found")
+	}
+	if _, ok := ClosestView(other); ok {
+		t.Fatal("expected no enclosing view element")
+	}
+
+	if r := RouteOf(label); r != home.Route() || r == "" {
+		t.Fatalf("expected label to be displayed by the route of home, got %q", r)
+	}
+	if r := RouteOf(other); r != "" {
+		t.Fatalf("expected no route for an element outside of any view, got %q", r)
+	}
+}
@@ -20,6 +20,10 @@ var WorkQueue = make(chan func())
 // Goroutines launched from the main thread that need access to the main UI tree must use it.
 // Only a single DoSync must be used within a DoAsync.
 func DoSync(fn func()) {
+	if s := currentScheduler(); s != nil {
+		s.enqueue(fn)
+		return
+	}
 	ch := make(chan struct{})
 	go func() {
 		WorkQueue <- newwork(fn, ch)
@@ -56,39 +60,24 @@ func newwork(f func(), signalDone chan struct{}) func() {
 // (reminder that a DoSync represent a critical section for the UI tree and in-between calls
 // the UI goroutine is preemptable).
 func DoAsync(e *Element, f func(context.Context)) {
-	var executionCtx context.Context
-	var cancel context.CancelFunc
-
-	var ctxchan <-chan struct{}
-
-	if e != nil {
-		if e.Root != nil {
-			if r := e.Root.router; r != nil {
-				ctxchan = r.NavContext.Done()
-				executionCtx, cancel = context.WithCancel(r.NavContext)
-			} else {
-				executionCtx = context.Background()
-				cancel = func() {}
-			}
-		} else {
-			executionCtx = context.Background()
-			cancel = func() {}
-			return
-		}
-	} else {
-		executionCtx = context.Background()
-		cancel = func() {}
-
+	if e != nil && e.Root == nil {
+		return
 	}
+	executionCtx, cancel := context.WithCancel(NavigationContext(e))
 
-	go func() {
+	run := func() {
 		select {
-		case <-ctxchan:
+		case <-executionCtx.Done():
 			cancel()
 		default:
 			f(executionCtx)
 		}
-	}()
+	}
+	if s := currentScheduler(); s != nil {
+		s.enqueue(run)
+		return
+	}
+	go run()
 }
 
 // DoAfter schedules a function to be executed after a certain duration.
@@ -96,32 +85,29 @@ func DoAsync(e *Element, f func(context.Context)) {
 // the function is cancellable when navigating.
 // When f is supposed to update the UI tree, it needs to use a single DoSync call when it does so.
 func DoAfter(d time.Duration, e *Element, f func(ectx context.Context)) {
-	t := time.NewTimer(d)
-
-	var executionCtx context.Context
-	var cancel context.CancelFunc
-
-	var ctxchan <-chan struct{}
-
-	if e != nil {
-		if e.Root != nil {
-			if r := e.Root.router; r != nil {
-				ctxchan = r.NavContext.Done()
-				executionCtx, cancel = context.WithCancel(r.NavContext)
-			} else {
-				executionCtx = context.Background()
-				cancel = func() {}
-			}
-		} else {
-			executionCtx = context.Background()
-			cancel = func() {}
-			return
-		}
+	if e != nil && e.Root == nil {
+		return
+	}
+	executionCtx, cancel := context.WithCancel(NavigationContext(e))
+	if s := currentScheduler(); s != nil {
+		AfterFunc(d, func() {
+			s.enqueue(func() {
+				select {
+				case <-executionCtx.Done():
+					cancel()
+				default:
+					f(executionCtx)
+				}
+			})
+		})
+		return
 	}
+	t := time.NewTimer(d)
 
 	go func() {
 		select {
-		case <-ctxchan:
+		case <-executionCtx.Done():
+			t.Stop()
 			cancel()
 			return
 		case <-t.C:
@@ -130,6 +116,21 @@ func DoAfter(d time.Duration, e *Element, f func(ectx context.Context)) {
 	}()
 }
 
+// NavigationContext returns the context of the current navigation of the document an element belongs to.
+// It is cancelled as soon as the navigation is cancelled or superseded by a new one, so that the work
+// started on behalf of the outgoing view, such as data fetches, does not outlive it.
+// If the element is nil or if its document has no router, the background context is returned.
+func NavigationContext(e *Element) context.Context {
+	if e == nil || e.Root == nil {
+		return context.Background()
+	}
+	r := e.Root.router
+	if r == nil || r.NavContext == nil {
+		return context.Background()
+	}
+	return r.NavContext
+}
+
 func (e *Element) setDataPrefetcher(propname string, reqfunc func(e *Element) *http.Request, responsehandler func(*http.Response) (Value, error)) {
 	// TODO panic if data fetcher already exists for this propname
 	if e.fetching(prefetchTxName(propname, "start")) { // todo this is not the right propname to check. should use the fetching transition prop name
@@ -277,12 +278,15 @@ func (e *Element) SetDataFetcher(propname string, reqfunc func(e *Element) *http
 }
 
 func (e *Element) SetURLDataFetcher(propname string, url string, responsehandler func(*http.Response) (Value, error), prefetchable bool) {
-	router := GetRouter(e.Root)
-	r, err := http.NewRequestWithContext(router.NavContext, "GET", url, nil)
-	if err != nil {
+	if _, err := http.NewRequest("GET", url, nil); err != nil {
 		panic(url + " might be malformed. Unable to create new request")
 	}
-	e.SetDataFetcher(propname, func(*Element) *http.Request { return r }, responsehandler, prefetchable)
+	// a new request is created for each fetch, within the navigation it is made for.
+	reqfunc := func(e *Element) *http.Request {
+		r, _ := http.NewRequestWithContext(NavigationContext(e), "GET", url, nil)
+		return r
+	}
+	e.SetDataFetcher(propname, reqfunc, responsehandler, prefetchable)
 }
 
 // CancelFetch will abort ongoing fetch requests.
@@ -381,6 +385,11 @@ func (e *Element) fetchData(propname string, r *http.Request, cancelFn context.C
 			return
 		}
 		DoSync(func() {
+			// a response arriving after the navigation was superseded must not clobber the state of the new page.
+			if !prefetching && execution.Err() != nil {
+				e.CancelFetch(propname)
+				return
+			}
 			e.SetData(propname, v)
 			if prefetching {
 				e.endprefetchTransition(propname)
@@ -707,7 +716,11 @@ func (e *Element) isPrefetchedDataValid(propname string) bool {
 	if err != nil {
 		return false
 	}
-	if time.Now().UTC().After(temps.UTC().Add(PrefetchMaxAge)) {
+	maxage := PrefetchMaxAge
+	if e.Configuration != nil {
+		maxage = e.Configuration.prefetchMaxAge()
+	}
+	if time.Now().UTC().After(temps.UTC().Add(maxage)) {
 		return false
 	}
 	return true
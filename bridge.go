@@ -0,0 +1,80 @@
+package ui
+
+// Cross-document properties
+//
+// A document embedded in another one, e.g. a widget mounted in a host app, has its own element tree.
+// Instead of sharing state through globals, a document exports some of its properties under a name with
+// Export, and another document imports them with Import.
+// Exported values are held in the "exports" property category of the root of the exporting document, so that
+// names are namespaced by document: two documents may export different properties under the same name.
+//
+// An import mirrors the exported value into a property of the importing document. A two-way import also
+// writes the changes of this property back, provided that the export is writable. Otherwise, they are
+// overwritten by the exported value.
+
+const exportsNS = "exports"
+
+// exportRoot returns the element holding the exports of the document of e.
+func exportRoot(e *Element) *Element {
+	if e.Root != nil {
+		return e.Root
+	}
+	return e
+}
+
+// Export publishes a watchable property of an element under a name, for other documents to import.
+// If writable is true, the property may be changed by two-way imports.
+func Export(e *Element, category string, propname string, name string, writable bool) {
+	root := exportRoot(e)
+	// Handlers read the current values rather than the event values: the latter may be stale when a write
+	// is reverted while the event is still being dispatched.
+	root.Watch(category, propname, e, NewMutationHandler(func(evt MutationEvent) bool {
+		if v, ok := e.Get(category, propname); ok {
+			root.Set(exportsNS, name, v)
+		}
+		return false
+	}).RunASAP())
+
+	root.Watch(exportsNS, name, root, NewMutationHandler(func(evt MutationEvent) bool {
+		x, _ := root.Get(exportsNS, name)
+		v, ok := e.Get(category, propname)
+		if ok && Equal(v, x) {
+			return false
+		}
+		if !writable {
+			if ok {
+				root.Set(exportsNS, name, v)
+			}
+			return false
+		}
+		e.Set(category, propname, x)
+		return false
+	}))
+}
+
+// Exported returns the current value exported under a name by the document of an element.
+func Exported(e *Element, name string) (Value, bool) {
+	return exportRoot(e).Get(exportsNS, name)
+}
+
+// Import mirrors the property exported under a name by the document of the from element into a property of
+// the target element. If twoWay is true, the changes of the target property are written back to the export.
+func Import(target *Element, category string, propname string, from *Element, name string, twoWay bool) {
+	src := exportRoot(from)
+	target.Watch(exportsNS, name, src, NewMutationHandler(func(evt MutationEvent) bool {
+		if v, ok := src.Get(exportsNS, name); ok {
+			target.Set(category, propname, v)
+		}
+		return false
+	}).RunASAP())
+
+	if !twoWay {
+		return
+	}
+	target.Watch(category, propname, target, NewMutationHandler(func(evt MutationEvent) bool {
+		if v, ok := target.Get(category, propname); ok {
+			src.Set(exportsNS, name, v)
+		}
+		return false
+	}))
+}
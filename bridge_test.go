@@ -0,0 +1,43 @@
+package ui
+
+import "testing"
+
+func TestExportImport(t *testing.T) {
+	c := NewConfiguration("bridgetest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	widget := newdiv("widget")
+	host := newdiv("host")
+
+	widget.SetData("count", Number(1))
+	Export(widget, Namespace.Data, "count", "count", false)
+	Import(host, Namespace.Data, "widgetcount", widget, "count", true)
+
+	if v, ok := host.GetData("widgetcount"); !ok || !Equal(v, Number(1)) {
+		t.Fatalf("expected the imported value to be 1, got %v", v)
+	}
+
+	widget.SetData("count", Number(2))
+	if v, _ := host.GetData("widgetcount"); !Equal(v, Number(2)) {
+		t.Fatalf("expected the imported value to follow the export, got %v", v)
+	}
+
+	// the export is read-only: writes from the host are reverted.
+	host.SetData("widgetcount", Number(5))
+	if v, _ := widget.GetData("count"); !Equal(v, Number(2)) {
+		t.Fatalf("expected a read-only export to be left unchanged, got %v", v)
+	}
+	if v, _ := host.GetData("widgetcount"); !Equal(v, Number(2)) {
+		t.Fatalf("expected the import to be reverted, got %v", v)
+	}
+
+	widget.SetData("name", String("a"))
+	Export(widget, Namespace.Data, "name", "name", true)
+	Import(host, Namespace.Data, "widgetname", widget, "name", true)
+	host.SetData("widgetname", String("b"))
+	if v, _ := widget.GetData("name"); !Equal(v, String("b")) {
+		t.Fatalf("expected a writable export to be updated, got %v", v)
+	}
+}
@@ -55,6 +55,9 @@ var buildCmd = &cobra.Command{
 		# building a mobile, desktop or terminal  project
 		zui build
 
+		# building a web project and reporting the code size per package and route
+		zui build -csr --bundle-report --usage=route-usage.json
+
 		# building a project with a given basepath
 		zui build -basepath=/path
 		The path needs to use a leading slash so as to be relative to the root.
@@ -128,6 +131,12 @@ var buildCmd = &cobra.Command{
 					fmt.Println("default app built.")
 				}
 
+				if bundleReport {
+					if err := writeBundleReport(filepath.Join(".", "dev", "build", "app", "main.wasm")); err != nil {
+						fmt.Println("Error: unable to generate the bundle report.", err)
+					}
+				}
+
 				var buildall bool
 				for _, a := range args {
 					if a == "." {
@@ -161,6 +170,12 @@ var buildCmd = &cobra.Command{
 					fmt.Println("wasm app built.")
 				}
 
+				if bundleReport {
+					if err := writeBundleReport(filepath.Join(".", "dev", "build", "app", "main.wasm")); err != nil {
+						fmt.Println("Error: unable to generate the bundle report.", err)
+					}
+				}
+
 				// Let's build the default server.
 				// The output file should be in dev/build/server/ssr/
 				err = Build(filepath.Join(".", "dev", "build", "server", "ssr", "main"), []string{"server", "ssr"})
@@ -236,4 +251,6 @@ func init() {
 	buildCmd.Flags().BoolVarP(&releaseMode, "release", "r", false, "build in release mode")
 	buildCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	buildCmd.Flags().BoolVarP(&nohmr, "nohmr", "", false, "disable hot module replacement")
+	buildCmd.Flags().BoolVarP(&bundleReport, "bundle-report", "", false, "write a code size report of the wasm app to ./dev/build/bundle-report.txt")
+	buildCmd.Flags().StringVarP(&usagePath, "usage", "", "", "route usage report (JSON) to include in the bundle report")
 }
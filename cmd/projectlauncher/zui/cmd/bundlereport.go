@@ -0,0 +1,324 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Bundle report
+//
+// The bundle report maps the code of the wasm binary to Go packages, using the function names section
+// emitted by the Go linker (package names are displayed in their sanitized form), and combines it with the route usage report recorded by the app in dev mode
+// (see zuiRouteUsage() in the browser console, or route-usage.json after static site generation).
+// For each route, it lists the constructors and components its UI tree uses, along with the code size of the
+// component packages. Components used by a single route are candidates for lazy loading.
+// Constructor sizes are estimated from the names of the functions of the driver package and are approximate.
+
+var bundleReport bool
+var usagePath string
+
+const driverPackage = "github.com/atdiar/particleui/drivers/js"
+
+type routeUsage map[string]struct {
+	Constructors map[string]int `json:"constructors"`
+	Components   map[string]int `json:"components"`
+}
+
+// wasmFunctionSizes returns the size of the code of each named function of a wasm binary.
+func wasmFunctionSizes(wasm []byte) (map[string]int, error) {
+	if len(wasm) < 8 || !bytes.Equal(wasm[:4], []byte("\x00asm")) {
+		return nil, errors.New("not a wasm binary")
+	}
+	r := bytes.NewReader(wasm[8:])
+
+	var imported int
+	var bodies []int
+	names := make(map[int]string)
+
+	for r.Len() > 0 {
+		id, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		section := make([]byte, size)
+		if _, err := io.ReadFull(r, section); err != nil {
+			return nil, err
+		}
+		s := bytes.NewReader(section)
+
+		switch id {
+		case 0: // custom section
+			name, err := readWasmName(s)
+			if err != nil {
+				return nil, err
+			}
+			if name != "name" {
+				continue
+			}
+			for s.Len() > 0 {
+				sub, _ := s.ReadByte()
+				subsize, err := binary.ReadUvarint(s)
+				if err != nil {
+					return nil, err
+				}
+				if sub != 1 { // function names
+					s.Seek(int64(subsize), io.SeekCurrent)
+					continue
+				}
+				count, _ := binary.ReadUvarint(s)
+				for i := uint64(0); i < count; i++ {
+					idx, _ := binary.ReadUvarint(s)
+					n, err := readWasmName(s)
+					if err != nil {
+						return nil, err
+					}
+					names[int(idx)] = n
+				}
+			}
+		case 2: // imports
+			count, _ := binary.ReadUvarint(s)
+			for i := uint64(0); i < count; i++ {
+				readWasmName(s) // module
+				readWasmName(s) // field
+				kind, _ := s.ReadByte()
+				switch kind {
+				case 0: // function
+					binary.ReadUvarint(s)
+					imported++
+				case 1: // table
+					s.ReadByte()
+					skipWasmLimits(s)
+				case 2: // memory
+					skipWasmLimits(s)
+				case 3: // global
+					s.ReadByte()
+					s.ReadByte()
+				}
+			}
+		case 10: // code
+			count, _ := binary.ReadUvarint(s)
+			for i := uint64(0); i < count; i++ {
+				bodysize, err := binary.ReadUvarint(s)
+				if err != nil {
+					return nil, err
+				}
+				s.Seek(int64(bodysize), io.SeekCurrent)
+				bodies = append(bodies, int(bodysize))
+			}
+		}
+	}
+
+	sizes := make(map[string]int, len(bodies))
+	for i, size := range bodies {
+		name, ok := names[imported+i]
+		if !ok {
+			name = fmt.Sprintf("func%d", imported+i)
+		}
+		sizes[name] += size
+	}
+	return sizes, nil
+}
+
+func readWasmName(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func skipWasmLimits(r *bytes.Reader) {
+	flags, _ := r.ReadByte()
+	binary.ReadUvarint(r)
+	if flags&1 != 0 {
+		binary.ReadUvarint(r)
+	}
+}
+
+// The Go linker sanitizes the function names of wasm binaries: every character that is neither a letter,
+// a digit, an underscore nor a dot is replaced by an underscore, e.g. github.com/org/pkg.(*T).M becomes
+// github.com_org_pkg.__T_.M.
+var symbolSanitizer = regexp.MustCompile(`[^\w.]`)
+
+func sanitizedPackage(importpath string) string {
+	return symbolSanitizer.ReplaceAllString(importpath, "_")
+}
+
+var topLevelDomains = map[string]bool{"com": true, "org": true, "net": true, "io": true, "dev": true, "in": true, "co": true, "app": true, "me": true}
+
+// symbolPackage returns the (sanitized) package a function of a wasm binary belongs to.
+func symbolPackage(symbol string) string {
+	i := strings.Index(symbol, ".")
+	if i < 0 {
+		return symbol
+	}
+	rest := symbol[i+1:]
+	// the import path may start with a domain name, whose dot is kept.
+	if k := strings.Index(rest, "_"); k > 0 && topLevelDomains[rest[:k]] && !strings.Contains(symbol[:i], "_") {
+		if j := strings.Index(rest, "."); j > k {
+			return symbol[:i+1+j]
+		}
+	}
+	return symbol[:i]
+}
+
+// constructorSize estimates the code size of a driver constructor, e.g. "div" matches the functions
+// of DivElement and newDiv.
+func constructorSize(sizes map[string]int, constructor string) int {
+	if constructor == "" {
+		return 0
+	}
+	title := strings.ToUpper(constructor[:1]) + constructor[1:]
+	var total int
+	for name, size := range sizes {
+		if !strings.HasPrefix(name, sanitizedPackage(driverPackage)+".") {
+			continue
+		}
+		if strings.Contains(name, title+"Element") || strings.Contains(name, ".new"+title) {
+			total += size
+		}
+	}
+	return total
+}
+
+// GenerateBundleReport writes the bundle report for a wasm binary. The route usage file is optional.
+func GenerateBundleReport(wasmPath string, usagePath string, out io.Writer) error {
+	wasm, err := os.ReadFile(wasmPath)
+	if err != nil {
+		return err
+	}
+	sizes, err := wasmFunctionSizes(wasm)
+	if err != nil {
+		return err
+	}
+
+	packages := make(map[string]int)
+	var total int
+	for name, size := range sizes {
+		packages[symbolPackage(name)] += size
+		total += size
+	}
+	pkgs := make([]string, 0, len(packages))
+	for p := range packages {
+		pkgs = append(pkgs, p)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return packages[pkgs[i]] > packages[pkgs[j]] })
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "wasm binary: %s (%d bytes, %d bytes of code)\n\n", wasmPath, len(wasm), total)
+	fmt.Fprintln(w, "package\tcode size\tshare")
+	for i, p := range pkgs {
+		if i == 30 {
+			fmt.Fprintf(w, "... (%d more)\t\t\n", len(pkgs)-30)
+			break
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.1f%%\n", p, packages[p], 100*float64(packages[p])/float64(total))
+	}
+	w.Flush()
+
+	if usagePath == "" {
+		return nil
+	}
+	b, err := os.ReadFile(usagePath)
+	if err != nil {
+		return err
+	}
+	var usage routeUsage
+	if err := json.Unmarshal(b, &usage); err != nil {
+		return fmt.Errorf("invalid route usage report: %w", err)
+	}
+
+	routes := make([]string, 0, len(usage))
+	componentRoutes := make(map[string][]string)
+	for route, u := range usage {
+		routes = append(routes, route)
+		for c := range u.Components {
+			componentRoutes[c] = append(componentRoutes[c], route)
+		}
+	}
+	sort.Strings(routes)
+
+	fmt.Fprintln(out)
+	for _, route := range routes {
+		u := usage[route]
+		fmt.Fprintf(out, "route %s\n", route)
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		constructors := make([]string, 0, len(u.Constructors))
+		for c := range u.Constructors {
+			constructors = append(constructors, c)
+		}
+		sort.Strings(constructors)
+		for _, c := range constructors {
+			fmt.Fprintf(w, "  constructor %s\t%d elements\t~%d bytes\n", c, u.Constructors[c], constructorSize(sizes, c))
+		}
+		components := make([]string, 0, len(u.Components))
+		for c := range u.Components {
+			components = append(components, c)
+		}
+		sort.Strings(components)
+		for _, c := range components {
+			fmt.Fprintf(w, "  component %s\t%d instances\t%d bytes\n", c, u.Components[c], packages[sanitizedPackage(c)])
+		}
+		w.Flush()
+	}
+
+	var candidates []string
+	for c, r := range componentRoutes {
+		if len(r) == 1 && len(routes) > 1 {
+			candidates = append(candidates, c)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return packages[sanitizedPackage(candidates[i])] > packages[sanitizedPackage(candidates[j])]
+	})
+	if len(candidates) > 0 {
+		fmt.Fprintln(out, "\nlazy loading candidates (components used by a single route)")
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for _, c := range candidates {
+			fmt.Fprintf(w, "  %s\t%s\t%d bytes\n", c, componentRoutes[c][0], packages[sanitizedPackage(c)])
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+// writeBundleReport generates the bundle report of a wasm binary in the build directory.
+func writeBundleReport(wasmPath string) error {
+	usage := usagePath
+	if usage == "" {
+		if _, err := os.Stat(filepath.Join(".", "dev", "build", "server", "ssg", "route-usage.json")); err == nil {
+			usage = filepath.Join(".", "dev", "build", "server", "ssg", "route-usage.json")
+		}
+	}
+	reportPath := filepath.Join(".", "dev", "build", "bundle-report.txt")
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := GenerateBundleReport(wasmPath, usage, f); err != nil {
+		return err
+	}
+	if verbose {
+		fmt.Println("bundle report written to " + reportPath)
+	}
+	return nil
+}
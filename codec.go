@@ -0,0 +1,419 @@
+package ui
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Codecs
+//
+// A Codec encodes Values for persistence (storage, SSR state, history state, mutation traces).
+// JSONCodec produces the versioned JSON encoding (see Marshal) which is convenient for debugging.
+// BinaryCodec produces a compact MessagePack encoding which is faster to produce and decode in wasm and
+// much smaller than the JSON encoding of the raw representation of Values, which wraps every value with its
+// type. Even once base64 encoded for text-only stores, typical state (records, lists of records, strings) is
+// about five times smaller than its JSON encoding.
+// PersistenceCodec is the codec used by drivers on their internal persistence paths.
+//
+// EncodeText and DecodeText are used where only strings can be stored: the output of codecs other than
+// JSONCodec is base64 encoded and prefixed by the codec name so that the data can be decoded whatever the
+// codec in use when it was written. Unprefixed data is decoded as JSON.
+
+// Codec encodes and decodes Values.
+type Codec interface {
+	Name() string
+	Encode(v Value) ([]byte, error)
+	Decode(data []byte) (Value, error)
+}
+
+var (
+	// JSONCodec encodes Values in the versioned JSON format.
+	JSONCodec Codec = jsonCodec{}
+	// BinaryCodec encodes Values in a compact MessagePack based format.
+	BinaryCodec Codec = binaryCodec{}
+
+	// PersistenceCodec is the codec used for internal persistence. It can be set to JSONCodec when debugging.
+	PersistenceCodec = BinaryCodec
+)
+
+var codecs = struct {
+	sync.Mutex
+	m map[string]Codec
+}{m: map[string]Codec{"json": JSONCodec, "msgpack": BinaryCodec}}
+
+// RegisterCodec makes a codec available to DecodeText.
+func RegisterCodec(c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.m[c.Name()] = c
+}
+
+// EncodeText returns the encoding of v by c as a string.
+func EncodeText(c Codec, v Value) (string, error) {
+	b, err := c.Encode(v)
+	if err != nil {
+		return "", err
+	}
+	if c.Name() == "json" {
+		return string(b), nil
+	}
+	return "zui-" + c.Name() + ":" + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// DecodeText decodes a string produced by EncodeText.
+func DecodeText(s string) (Value, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "zui-") {
+		return JSONCodec.Decode([]byte(s))
+	}
+	name, data, ok := strings.Cut(strings.TrimPrefix(s, "zui-"), ":")
+	if !ok {
+		return nil, errors.New("invalid encoded value")
+	}
+	codecs.Lock()
+	c, ok := codecs.m[name]
+	codecs.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q", name)
+	}
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decode(b)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                      { return "json" }
+func (jsonCodec) Encode(v Value) ([]byte, error)    { return Marshal(v) }
+func (jsonCodec) Decode(data []byte) (Value, error) { return Unmarshal(data) }
+
+// The binary format is a MessagePack encoding of the value preceded by a header made of the byte 0xc1
+// (never used by MessagePack) and the encoding version.
+// Objects are encoded as maps which hold their type under the "zui_object_typ" key when it is not "Object".
+
+const binaryMagic = 0xc1
+
+type binaryCodec struct{}
+
+func (binaryCodec) Name() string { return "msgpack" }
+
+func (binaryCodec) Encode(v Value) ([]byte, error) {
+	w := &binWriter{buf: make([]byte, 0, 256)}
+	w.buf = append(w.buf, binaryMagic, EncodingVersion)
+	if err := w.value(v); err != nil {
+		return nil, err
+	}
+	return w.buf, nil
+}
+
+func (binaryCodec) Decode(data []byte) (Value, error) {
+	if len(data) < 2 || data[0] != binaryMagic {
+		return nil, errors.New("not a binary encoded value")
+	}
+	version := int(data[1])
+	r := &binReader{data: data, pos: 2}
+	v, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errors.New("unable to decode value: null")
+	}
+	if version < EncodingVersion {
+		// migrations operate on the raw representation
+		b, err := json.Marshal(envelope{version, v.RawValue()})
+		if err != nil {
+			return nil, err
+		}
+		return Unmarshal(b)
+	}
+	return v, nil
+}
+
+type binWriter struct {
+	buf []byte
+}
+
+func (w *binWriter) value(v Value) error {
+	switch t := v.(type) {
+	case nil:
+		w.buf = append(w.buf, 0xc0)
+	case Bool:
+		if t {
+			w.buf = append(w.buf, 0xc3)
+		} else {
+			w.buf = append(w.buf, 0xc2)
+		}
+	case String:
+		w.str(string(t))
+	case Number:
+		w.number(float64(t))
+	case List:
+		w.header(len(t.l), 0x90, 0xdc, 0xdd, 16)
+		for _, val := range t.l {
+			if err := w.value(val); err != nil {
+				return err
+			}
+		}
+	case Object:
+		typ := t.ValueType()
+		n := 0
+		t.Range(func(string, Value) bool { n++; return false })
+		if typ != "Object" {
+			n++
+		}
+		w.header(n, 0x80, 0xde, 0xdf, 16)
+		if typ != "Object" {
+			w.str("zui_object_typ")
+			w.str(typ)
+		}
+		var err error
+		t.Range(func(k string, val Value) bool {
+			w.str(k)
+			err = w.value(val)
+			return err != nil
+		})
+		return err
+	case object:
+		switch t.ValueType() {
+		case "Bool", "String", "Number", "List", "Object":
+			return w.value(t.Value())
+		}
+		return w.value(t.AsObject())
+	default:
+		return fmt.Errorf("unable to encode value of type %T", v)
+	}
+	return nil
+}
+
+func (w *binWriter) header(n int, fix byte, b16 byte, b32 byte, fixmax int) {
+	switch {
+	case n < fixmax:
+		w.buf = append(w.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		w.buf = append(w.buf, b16)
+		w.uint16(uint16(n))
+	default:
+		w.buf = append(w.buf, b32)
+		w.uint32(uint32(n))
+	}
+}
+
+// uint16, uint32 and uint64 append big endian integers. (binary.AppendUintN requires Go 1.19)
+func (w *binWriter) uint16(v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *binWriter) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *binWriter) uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *binWriter) str(s string) {
+	if len(s) <= math.MaxUint8 && len(s) >= 32 {
+		w.buf = append(w.buf, 0xd9, byte(len(s)))
+	} else {
+		w.header(len(s), 0xa0, 0xda, 0xdb, 32)
+	}
+	w.buf = append(w.buf, s...)
+}
+
+func (w *binWriter) number(f float64) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || (f == 0 && math.Signbit(f)) {
+		w.buf = append(w.buf, 0xcb)
+		w.uint64(math.Float64bits(f))
+		return
+	}
+	i := int64(f)
+	switch {
+	case i >= 0 && i < 128:
+		w.buf = append(w.buf, byte(i))
+	case i < 0 && i >= -32:
+		w.buf = append(w.buf, byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		w.buf = append(w.buf, 0xd0, byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		w.buf = append(w.buf, 0xd1)
+		w.uint16(uint16(int16(i)))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		w.buf = append(w.buf, 0xd2)
+		w.uint32(uint32(int32(i)))
+	default:
+		w.buf = append(w.buf, 0xd3)
+		w.uint64(uint64(i))
+	}
+}
+
+var errTruncated = errors.New("truncated binary value")
+
+type binReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *binReader) next(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, errTruncated
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *binReader) uint(n int) (uint64, error) {
+	b, err := r.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+// value decodes the next value. It returns nil for nil.
+func (r *binReader) value() (Value, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c < 0x80:
+		return Number(c), nil
+	case c >= 0xe0:
+		return Number(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return r.str(int(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return r.list(int(c & 0x0f))
+	case c&0xf0 == 0x80:
+		return r.object(int(c & 0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return Bool(false), nil
+	case 0xc3:
+		return Bool(true), nil
+	case 0xca:
+		u, err := r.uint(4)
+		return Number(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := r.uint(8)
+		return Number(math.Float64frombits(u)), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := r.uint(1 << (c - 0xcc))
+		return Number(u), err
+	case 0xd0:
+		u, err := r.uint(1)
+		return Number(int8(u)), err
+	case 0xd1:
+		u, err := r.uint(2)
+		return Number(int16(u)), err
+	case 0xd2:
+		u, err := r.uint(4)
+		return Number(int32(u)), err
+	case 0xd3:
+		u, err := r.uint(8)
+		return Number(int64(u)), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.list(int(n))
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.object(int(n))
+	}
+	return nil, fmt.Errorf("unsupported binary value type 0x%x", c)
+}
+
+func (r *binReader) str(n int) (Value, error) {
+	b, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return String(b), nil
+}
+
+func (r *binReader) list(n int) (Value, error) {
+	if n > len(r.data)-r.pos {
+		return nil, errTruncated
+	}
+	l := NewList()
+	for i := 0; i < n; i++ {
+		v, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		l.Append(v) // nil items keep their position
+	}
+	return l.Commit(), nil
+}
+
+func (r *binReader) object(n int) (Value, error) {
+	if 2*n > len(r.data)-r.pos {
+		return nil, errTruncated
+	}
+	o := NewObject()
+	typ := "Object"
+	for i := 0; i < n; i++ {
+		k, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(String)
+		if !ok {
+			return nil, errors.New("binary object keys should be strings")
+		}
+		v, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		if key == "zui_object_typ" {
+			if t, ok := v.(String); ok {
+				typ = string(t)
+			}
+			continue
+		}
+		if v != nil {
+			o.Set(string(key), v)
+		}
+	}
+	obj := o.Commit()
+	if typ != "Object" {
+		obj = obj.setType(typ)
+	}
+	return obj, nil
+}
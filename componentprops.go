@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"sort"
+)
+
+// Component props
+//
+// Components built by wrapping an Element in a struct expose their inputs as data properties.
+// DeclareProps lists which data properties are the public props of such a component, so that a component can
+// react to any change of its inputs in one place: a "props-changed" event is triggered on the element after
+// each change of a declared prop.
+// Its value is an Object holding the names of the props that changed in a sorted "changed" List, and a
+// "props" Object with the current values of all the declared props.
+//
+// SetProps changes several props at once and triggers a single event for all of them.
+// OnPropChange watches a single prop.
+
+type propsBatch struct {
+	changed map[string]bool
+}
+
+var propsBatches = newscsmap[*Element, *propsBatch]()
+
+// DeclareProps declares data properties of an element as its public props. It may be called several times.
+func DeclareProps(a AnyElement, names ...string) {
+	e := a.AsElement()
+	declared := make(map[string]bool)
+	var list []Value
+	if v, ok := e.Get(Namespace.Internals, "props"); ok {
+		list = v.(List).UnsafelyUnwrap()
+		for _, name := range list {
+			declared[string(name.(String))] = true
+		}
+	}
+	for _, name := range names {
+		if declared[name] {
+			continue
+		}
+		declared[name] = true
+		list = append(list, String(name))
+		name := name
+		e.Watch(Namespace.Data, name, e, NewMutationHandler(func(evt MutationEvent) bool {
+			if b, ok := propsBatches.Get(evt.Origin()); ok {
+				b.changed[name] = true
+				return false
+			}
+			triggerPropsChanged(evt.Origin(), []string{name})
+			return false
+		}))
+	}
+	e.Set(Namespace.Internals, "props", NewListFrom(list))
+}
+
+// PropNames returns the names of the props declared for an element.
+func PropNames(a AnyElement) []string {
+	v, ok := a.AsElement().Get(Namespace.Internals, "props")
+	if !ok {
+		return nil
+	}
+	l := v.(List).UnsafelyUnwrap()
+	res := make([]string, 0, len(l))
+	for _, name := range l {
+		res = append(res, string(name.(String)))
+	}
+	return res
+}
+
+// Props returns the current values of the props declared for an element.
+func Props(a AnyElement) Object {
+	e := a.AsElement()
+	o := NewObject()
+	for _, name := range PropNames(e) {
+		if v, ok := e.GetData(name); ok {
+			o.Set(name, v)
+		}
+	}
+	return o.Commit()
+}
+
+// SetProps sets several props of an element and triggers a single "props-changed" event.
+func SetProps(a AnyElement, props Object) {
+	e := a.AsElement()
+	if _, ok := propsBatches.Get(e); ok {
+		props.Range(func(name string, v Value) bool {
+			e.SetData(name, v)
+			return false
+		})
+		return
+	}
+	b := &propsBatch{changed: make(map[string]bool)}
+	propsBatches.Set(e, b)
+	props.Range(func(name string, v Value) bool {
+		e.SetData(name, v)
+		return false
+	})
+	propsBatches.Delete(e)
+
+	if len(b.changed) == 0 {
+		return
+	}
+	changed := make([]string, 0, len(b.changed))
+	for name := range b.changed {
+		changed = append(changed, name)
+	}
+	triggerPropsChanged(e, changed)
+}
+
+// OnPropChange registers a handler for the changes of a prop of an element.
+func OnPropChange(a AnyElement, propname string, h *MutationHandler) {
+	e := a.AsElement()
+	e.Watch(Namespace.Data, propname, e, h)
+}
+
+// OnPropsChanged registers a handler for the "props-changed" event of an element.
+func OnPropsChanged(a AnyElement, h *MutationHandler) {
+	e := a.AsElement()
+	e.WatchEvent("props-changed", e, h)
+}
+
+func triggerPropsChanged(e *Element, changed []string) {
+	sort.Strings(changed)
+	names := make([]Value, len(changed))
+	for i, name := range changed {
+		names[i] = String(name)
+	}
+	e.TriggerEvent("props-changed", NewObject().
+		Set("changed", NewListFrom(names)).
+		Set("props", Props(e)).
+		Commit())
+}
@@ -0,0 +1,49 @@
+package ui
+
+import "testing"
+
+func TestComponentProps(t *testing.T) {
+	c := NewConfiguration("propstest", "test")
+	root := c.NewAppRoot("root")
+	e := c.NewElement("area", "test")
+	RegisterElement(root, e)
+
+	DeclareProps(e, "language", "theme")
+	DeclareProps(e, "theme", "readonly")
+	if names := PropNames(e); len(names) != 3 || names[2] != "readonly" {
+		t.Fatalf("unexpected props %v", names)
+	}
+
+	var events []Object
+	OnPropsChanged(e, NewMutationHandler(func(evt MutationEvent) bool {
+		events = append(events, evt.NewValue().(Object))
+		return false
+	}))
+	var language Value
+	OnPropChange(e, "language", NewMutationHandler(func(evt MutationEvent) bool {
+		language = evt.NewValue()
+		return false
+	}))
+
+	e.SetData("language", String("go"))
+	if len(events) != 1 || language != String("go") {
+		t.Fatalf("expected a single props-changed event, got %d", len(events))
+	}
+
+	SetProps(e, NewObject().Set("theme", String("dark")).Set("readonly", Bool(true)).Set("language", String("go")).Commit())
+	if len(events) != 2 {
+		t.Fatalf("expected one event for the batch, got %d", len(events)-1)
+	}
+	changed := events[1].MustGetList("changed").UnsafelyUnwrap()
+	if len(changed) != 2 || changed[0] != String("readonly") || changed[1] != String("theme") {
+		t.Fatalf("unexpected changed props %v", changed)
+	}
+	if v, _ := events[1].MustGetObject("props").Get("language"); v != String("go") {
+		t.Fatalf("expected the event to hold the current props, got %v", v)
+	}
+
+	e.SetData("other", String("x"))
+	if len(events) != 2 {
+		t.Fatal("expected undeclared properties not to trigger props-changed")
+	}
+}
@@ -0,0 +1,251 @@
+package doc
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Accessibility audit
+//
+// In dev mode, a subset of common accessibility checks is run on the document every time a navigation ends.
+// The checks are performed on the logical UI tree, except for the contrast check and the duplicate id check
+// which require access to the rendered native DOM.
+// Violations are logged to the console and also emitted as an "a11y-violations" event on the document
+// so that an overlay or a test harness may consume them.
+
+// A11yViolation describes an accessibility issue found on a given element.
+type A11yViolation struct {
+	Rule      string
+	ElementID string
+	Message   string
+}
+
+func (v A11yViolation) object() ui.Object {
+	return ui.NewObject().
+		Set("rule", ui.String(v.Rule)).
+		Set("id", ui.String(v.ElementID)).
+		Set("message", ui.String(v.Message)).
+		Commit()
+}
+
+// A11yMinContrastRatio is the minimum contrast ratio expected between the text color and the background color
+// of an element displaying text. (WCAG AA level for normal text)
+var A11yMinContrastRatio = 4.5
+
+// AuditAccessibility runs the accessibility checks on the whole document and returns the list of violations.
+func AuditAccessibility(d *Document) []A11yViolation {
+	var violations []A11yViolation
+
+	labelled := make(map[string]bool)
+	walkElements(d.Body(), func(e *ui.Element) {
+		if elementType(e) != "label" {
+			return
+		}
+		if f, ok := attrValue(e, "for"); ok {
+			labelled[f] = true
+		}
+	})
+
+	walkElements(d.Body(), func(e *ui.Element) {
+		switch elementType(e) {
+		case "img":
+			if _, ok := attrValue(e, "alt"); !ok {
+				violations = append(violations, A11yViolation{"image-alt", e.ID, "img element is missing an alt attribute"})
+			}
+		case "input", "textarea", "select":
+			if typ, _ := attrValue(e, "type"); typ == "hidden" || typ == "submit" || typ == "button" {
+				return
+			}
+			if labelled[e.ID] || hasLabelAncestor(e) {
+				return
+			}
+			if _, ok := attrValue(e, "aria-label"); ok {
+				return
+			}
+			if _, ok := attrValue(e, "aria-labelledby"); ok {
+				return
+			}
+			violations = append(violations, A11yViolation{"label", e.ID, elementType(e) + " element has no associated label"})
+		}
+	})
+
+	if InBrowser() {
+		violations = append(violations, duplicateIDViolations()...)
+		violations = append(violations, contrastViolations(d)...)
+	}
+
+	return violations
+}
+
+// enableAccessibilityAudit registers the audit so that it runs after each navigation.
+func enableAccessibilityAudit(d *Document) {
+	d.AfterEvent("navigation-end", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		violations := AuditAccessibility(d)
+		if len(violations) == 0 {
+			return false
+		}
+		l := ui.NewList()
+		for _, v := range violations {
+			log.Printf("a11y [%s] #%s: %s", v.Rule, v.ElementID, v.Message)
+			l.Append(v.object())
+		}
+		d.TriggerEvent("a11y-violations", l.Commit())
+		return false
+	}))
+}
+
+// OnA11yViolations registers a handler that is called with the list of accessibility violations
+// found at the end of a navigation, in dev mode.
+func (d *Document) OnA11yViolations(h *ui.MutationHandler) {
+	d.WatchEvent("a11y-violations", d, h)
+}
+
+func walkElements(e *ui.Element, fn func(*ui.Element)) {
+	if e == nil {
+		return
+	}
+	fn(e)
+	if e.Children == nil {
+		return
+	}
+	for _, c := range e.Children.List {
+		walkElements(c, fn)
+	}
+}
+
+func elementType(e *ui.Element) string {
+	c, ok := e.Get(Namespace.Internals, "constructor")
+	if !ok {
+		return ""
+	}
+	return string(c.(ui.String))
+}
+
+// attrValue returns the value of an attribute as tracked by the logical tree, whether it was set
+// via the corresponding ui property or via SetAttribute.
+func attrValue(e *ui.Element, name string) (string, bool) {
+	if v, ok := e.GetUI(name); ok {
+		if s, ok := v.(ui.String); ok {
+			return string(s), true
+		}
+	}
+	m, ok := e.Get(Namespace.Data, "attrs")
+	if !ok {
+		return "", false
+	}
+	v, ok := m.(ui.Object).Get(name)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(ui.String)
+	return string(s), ok
+}
+
+func hasLabelAncestor(e *ui.Element) bool {
+	if e.Parent == nil {
+		return false
+	}
+	_, ok := ui.ClosestAncestor(e.Parent, func(p *ui.Element) bool { return elementType(p) == "label" })
+	return ok
+}
+
+func duplicateIDViolations() []A11yViolation {
+	var violations []A11yViolation
+	nodes := js.Global().Get("document").Call("querySelectorAll", "[id]")
+	seen := make(map[string]bool)
+	for i := 0; i < nodes.Length(); i++ {
+		id := nodes.Index(i).Get("id").String()
+		if seen[id] {
+			violations = append(violations, A11yViolation{"duplicate-id", id, "id is used by more than one element"})
+			continue
+		}
+		seen[id] = true
+	}
+	return violations
+}
+
+func contrastViolations(d *Document) []A11yViolation {
+	var violations []A11yViolation
+	walkElements(d.Body(), func(e *ui.Element) {
+		if _, ok := e.GetUI("text"); !ok {
+			return
+		}
+		v, ok := JSValue(e)
+		if !ok || !v.Truthy() {
+			return
+		}
+		style := js.Global().Call("getComputedStyle", v)
+		fg, ok := parseRGB(style.Get("color").String())
+		if !ok {
+			return
+		}
+		bg, ok := backgroundColor(v)
+		if !ok {
+			return
+		}
+		r := contrastRatio(fg, bg)
+		if r < A11yMinContrastRatio {
+			violations = append(violations, A11yViolation{"color-contrast", e.ID, "insufficient text contrast ratio: " + strconv.FormatFloat(r, 'f', 2, 64)})
+		}
+	})
+	return violations
+}
+
+// backgroundColor returns the first non transparent background color found on the element or its ancestors.
+func backgroundColor(v js.Value) ([3]float64, bool) {
+	for n := v; n.Truthy() && n.Get("nodeType").Int() == 1; n = n.Get("parentElement") {
+		bg := js.Global().Call("getComputedStyle", n).Get("backgroundColor").String()
+		c, ok := parseRGB(bg)
+		if ok && !strings.HasPrefix(bg, "rgba(0, 0, 0, 0)") && bg != "transparent" {
+			return c, true
+		}
+	}
+	return [3]float64{255, 255, 255}, true
+}
+
+func parseRGB(s string) ([3]float64, bool) {
+	var c [3]float64
+	i := strings.Index(s, "(")
+	j := strings.LastIndex(s, ")")
+	if i < 0 || j < i {
+		return c, false
+	}
+	parts := strings.Split(s[i+1:j], ",")
+	if len(parts) < 3 {
+		return c, false
+	}
+	for k := 0; k < 3; k++ {
+		f, err := strconv.ParseFloat(strings.TrimSpace(parts[k]), 64)
+		if err != nil {
+			return c, false
+		}
+		c[k] = f
+	}
+	return c, true
+}
+
+func relativeLuminance(c [3]float64) float64 {
+	var l [3]float64
+	for i, v := range c {
+		v = v / 255
+		if v <= 0.03928 {
+			l[i] = v / 12.92
+		} else {
+			l[i] = math.Pow((v+0.055)/1.055, 2.4)
+		}
+	}
+	return 0.2126*l[0] + 0.7152*l[1] + 0.0722*l[2]
+}
+
+func contrastRatio(a, b [3]float64) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
@@ -0,0 +1,217 @@
+package doc
+
+import (
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Drafts
+//
+// NewDraft groups data properties of an element, typically the fields of a form, into a draft which is saved
+// to the browser storage while the user edits them, so that unsaved input survives a reload or a crash.
+//   - Saves are debounced by DraftSaveDelay. Nothing is saved as long as the properties hold the values they
+//     had when the draft was created, and the saved draft is removed when they get back to these values.
+//   - When a draft is found in storage, the "draft-available" ui property of the element is set to true and
+//     the "draft-available" event is triggered. Saving is then suspended until the draft is either restored
+//     with Restore, or discarded with Discard, so that the stored draft is not overwritten by the initial
+//     values of the properties.
+//   - A successful submission should be reported with Submitted, which discards the draft. Native form
+//     submissions are reported automatically when the element is a form or contains one.
+//
+// Property values are encoded according to their persistence policy, if any, as for persisted properties.
+
+// DraftSaveDelay is the delay without modification after which a draft is saved.
+var DraftSaveDelay = 500 * time.Millisecond
+
+// DraftOptions configures a draft.
+type DraftOptions struct {
+	Storage string        // "localStorage" (default) or "sessionStorage"
+	Key     string        // storage key, "zui-draft/" followed by the element id by default
+	Delay   time.Duration // DraftSaveDelay by default
+}
+
+// Draft is a group of data properties of an element saved to the browser storage while they are edited.
+type Draft struct {
+	Element *ui.Element
+	Props   []string
+
+	opts      DraftOptions
+	baseline  ui.Object
+	suspended bool
+}
+
+var drafts = newscsmap[*ui.Element, *Draft]()
+
+// NewDraft creates a draft for the given data properties of an element.
+func NewDraft(e *ui.Element, props []string, opts ...DraftOptions) *Draft {
+	if _, ok := drafts.Get(e); ok {
+		panic("a draft has already been created for element " + e.ID)
+	}
+	var o DraftOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Storage == "" {
+		o.Storage = "localStorage"
+	}
+	if o.Key == "" {
+		o.Key = "zui-draft/" + e.ID
+	}
+	if o.Delay <= 0 {
+		o.Delay = DraftSaveDelay
+	}
+
+	dr := &Draft{Element: e, Props: props, opts: o}
+	dr.baseline = dr.snapshot()
+	drafts.Set(e, dr)
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		drafts.Delete(evt.Origin())
+		return false
+	}).RunOnce())
+
+	if !InBrowser() {
+		return dr
+	}
+	if dr.Available() {
+		dr.suspended = true
+		e.SetUI("draft-available", ui.Bool(true))
+		e.TriggerEvent("draft-available")
+	}
+
+	h := ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		dr.save()
+		return false
+	}).Debounce(o.Delay)
+	for _, prop := range props {
+		e.Watch(Namespace.Data, prop, e, h)
+	}
+
+	e.AddEventListener("submit", ui.NewEventHandler(func(evt ui.Event) bool {
+		if evt.Native().(NativeEvent).Value.Get("defaultPrevented").Bool() {
+			return false
+		}
+		dr.Submitted()
+		return false
+	}))
+	return dr
+}
+
+// GetDraft returns the draft created for an element, if any.
+func GetDraft(e *ui.Element) (*Draft, bool) {
+	return drafts.Get(e)
+}
+
+// Available returns whether a draft is saved in storage.
+func (dr *Draft) Available() bool {
+	_, ok := dr.store()
+	return ok
+}
+
+// Restore sets the properties to the values saved in the draft, if any.
+func (dr *Draft) Restore() {
+	s, ok := dr.store()
+	if !ok {
+		dr.resume()
+		return
+	}
+	v, err := ui.DecodeText(s)
+	o, isobject := v.(ui.Object)
+	if err != nil || !isobject {
+		DEBUG("unable to decode draft ", dr.opts.Key, ": ", err)
+		dr.Discard()
+		return
+	}
+	dr.resume()
+	for _, prop := range dr.Props {
+		val, ok := o.Get(prop)
+		if !ok {
+			continue
+		}
+		val, err := ui.DecodePersisted(dr.Element, Namespace.Data, prop, val)
+		if err != nil {
+			DEBUG("unable to restore draft property ", prop, " of ", dr.Element.ID, ": ", err)
+			continue
+		}
+		dr.Element.SetData(prop, val)
+	}
+}
+
+// Discard removes the saved draft. The properties keep their current values.
+func (dr *Draft) Discard() {
+	if InBrowser() {
+		js.Global().Get(dr.opts.Storage).Call("removeItem", dr.opts.Key)
+	}
+	dr.resume()
+}
+
+// Submitted reports that the properties were submitted successfully: the draft is discarded and the current
+// values of the properties become the reference for the next edits.
+func (dr *Draft) Submitted() {
+	dr.baseline = dr.snapshot()
+	dr.Discard()
+}
+
+func (dr *Draft) resume() {
+	dr.suspended = false
+	if v, ok := dr.Element.GetUI("draft-available"); ok && bool(v.(ui.Bool)) {
+		dr.Element.SetUI("draft-available", ui.Bool(false))
+	}
+}
+
+func (dr *Draft) snapshot() ui.Object {
+	o := ui.NewObject()
+	all := dr.Element.SnapshotNamespace(Namespace.Data)
+	for _, prop := range dr.Props {
+		if v, ok := all.Get(prop); ok {
+			o.Set(prop, v)
+		}
+	}
+	return o.Commit()
+}
+
+func (dr *Draft) store() (string, bool) {
+	if !InBrowser() {
+		return "", false
+	}
+	v := js.Global().Get(dr.opts.Storage).Call("getItem", dr.opts.Key)
+	if !v.Truthy() {
+		return "", false
+	}
+	return v.String(), true
+}
+
+func (dr *Draft) save() {
+	if dr.suspended {
+		return
+	}
+	current := dr.snapshot()
+	if ui.DiffNamespaces(dr.baseline, current).Empty() {
+		js.Global().Get(dr.opts.Storage).Call("removeItem", dr.opts.Key)
+		return
+	}
+
+	o := ui.NewObject()
+	current.Range(func(prop string, val ui.Value) bool {
+		v, err := ui.EncodePersisted(dr.Element, Namespace.Data, prop, val)
+		if err != nil {
+			DEBUG("unable to save draft property ", prop, " of ", dr.Element.ID, ": ", err)
+			return false
+		}
+		o.Set(prop, v)
+		return false
+	})
+	s, err := ui.EncodeText(ui.PersistenceCodec, o.Commit())
+	if err != nil {
+		DEBUG("unable to save draft ", dr.opts.Key, ": ", err)
+		return
+	}
+	defer func() {
+		// the storage quota may be exceeded
+		if r := recover(); r != nil {
+			DEBUG("unable to save draft ", dr.opts.Key, ": ", r)
+		}
+	}()
+	js.Global().Get(dr.opts.Storage).Call("setItem", dr.opts.Key, s)
+}
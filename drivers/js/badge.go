@@ -0,0 +1,85 @@
+package doc
+
+import (
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Vibration and app badge
+//
+// Vibrate makes the device vibrate following a pattern of alternating vibration and pause durations.
+// SetBadge and ClearBadge show and clear a count on the icon of the installed app, via the App Badging API.
+// BindBadge keeps the badge in sync with a property holding a count, such as a number of unread messages,
+// so that the badge follows the app state without explicit calls.
+//
+// Both APIs are only available in some browsers, and only for installed apps in the case of badges. The
+// methods return false and have no effect when the capability is missing, which CanVibrate and CanBadge report.
+
+// CanVibrate returns whether the device may vibrate.
+func (d *Document) CanVibrate() bool {
+	return InBrowser() && js.Global().Get("navigator").Get("vibrate").Truthy()
+}
+
+// Vibrate makes the device vibrate. The pattern alternates vibration and pause durations.
+// It returns whether the vibration was accepted.
+func (d *Document) Vibrate(pattern ...time.Duration) bool {
+	if !d.CanVibrate() || len(pattern) == 0 {
+		return false
+	}
+	ms := make([]interface{}, len(pattern))
+	for i, p := range pattern {
+		ms[i] = p.Milliseconds()
+	}
+	return js.Global().Get("navigator").Call("vibrate", ms).Bool()
+}
+
+// CancelVibration stops an ongoing vibration.
+func (d *Document) CancelVibration() {
+	if d.CanVibrate() {
+		js.Global().Get("navigator").Call("vibrate", 0)
+	}
+}
+
+// CanBadge returns whether the app icon may display a badge.
+func (d *Document) CanBadge() bool {
+	return InBrowser() && js.Global().Get("navigator").Get("setAppBadge").Truthy()
+}
+
+// SetBadge displays a count on the icon of the app. A zero count displays a plain dot.
+func (d *Document) SetBadge(count int) bool {
+	if !d.CanBadge() {
+		return false
+	}
+	nav := js.Global().Get("navigator")
+	if count > 0 {
+		nav.Call("setAppBadge", count).Call("catch", js.Global().Get("Function").New())
+	} else {
+		nav.Call("setAppBadge").Call("catch", js.Global().Get("Function").New())
+	}
+	return true
+}
+
+// ClearBadge removes the badge from the icon of the app.
+func (d *Document) ClearBadge() bool {
+	if !d.CanBadge() {
+		return false
+	}
+	js.Global().Get("navigator").Call("clearAppBadge").Call("catch", js.Global().Get("Function").New())
+	return true
+}
+
+// BindBadge displays the count held by a property on the icon of the app. The badge is cleared when the count
+// is zero, or when the property does not hold a number.
+func (d *Document) BindBadge(source ui.Watchable, category string, propname string) {
+	d.Watch(category, propname, source, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		n, ok := evt.NewValue().(ui.Number)
+		if !ok || n <= 0 {
+			d.ClearBadge()
+			return false
+		}
+		d.SetBadge(int(n))
+		return false
+	}).RunASAP())
+}
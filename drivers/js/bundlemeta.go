@@ -0,0 +1,123 @@
+package doc
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Bundle analysis metadata
+//
+// In order to identify the parts of an app that would benefit from being loaded lazily, the document can
+// record which constructors and components the UI tree of each route uses.
+// Components are the elements built by higher level packages: they are identified by the import path of their
+// package, set via MarkComponent.
+// The resulting report is meant to be combined with the code size information extracted from the wasm binary
+// by the zui builder (zui build --bundle-report). In dev mode, it is available from the browser console by
+// calling zuiRouteUsage(). During static site generation, it is written next to the generated pages.
+//
+// Code may also be notified of every element creation with OnConstruction.
+
+// ConstructionHook is called after an element has been created by one of the document constructors.
+type ConstructionHook func(e *ui.Element, elapsed time.Duration)
+
+// OnConstruction registers a hook called each time a document constructor creates an element.
+func (d *Document) OnConstruction(h ConstructionHook) *Document {
+	d.constructionHooks = append(d.constructionHooks, h)
+	return d
+}
+
+// MarkComponent records the package that implements the component an element is the root of.
+// Component packages should call it from their constructors, e.g. MarkComponent(e, "github.com/org/app/widgets/chart").
+func MarkComponent(e *ui.Element, pkg string) *ui.Element {
+	e.Set(Namespace.Internals, "component", ui.String(pkg))
+	return e
+}
+
+// ComponentOf returns the package of the component an element is the root of, if any.
+func ComponentOf(e *ui.Element) (string, bool) {
+	v, ok := e.Get(Namespace.Internals, "component")
+	if !ok {
+		return "", false
+	}
+	return string(v.(ui.String)), true
+}
+
+// Usage counts the elements created by each constructor and the components used by the UI tree of a route,
+// as of the last visit of the route.
+type Usage struct {
+	Constructors map[string]int `json:"constructors"`
+	Components   map[string]int `json:"components"`
+}
+
+// RouteUsage maps routes to the constructors and components their UI tree uses.
+type RouteUsage map[string]Usage
+
+// Routes returns the routes of the report, sorted.
+func (r RouteUsage) Routes() []string {
+	routes := make([]string, 0, len(r))
+	for route := range r {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// JSON returns the JSON encoding of the report, as expected by the zui builder.
+func (r RouteUsage) JSON() []byte {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// EnableRouteUsage starts recording the constructors and components used by each route, every time a
+// navigation ends.
+func (d *Document) EnableRouteUsage() *Document {
+	if d.routeUsage != nil {
+		return d
+	}
+	d.routeUsage = make(RouteUsage)
+
+	d.AfterEvent("navigation-end", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		route := "/"
+		if r := d.Router(); r != nil {
+			if c := r.CurrentRoute(); c != "" {
+				route = c
+			}
+		}
+		u := Usage{make(map[string]int), make(map[string]int)}
+		walkElements(d.AsElement(), func(e *ui.Element) {
+			if !e.Mounted() {
+				return
+			}
+			if c := elementType(e); c != "" {
+				u.Constructors[c]++
+			}
+			if c, ok := ComponentOf(e); ok {
+				u.Components[c]++
+			}
+		})
+		d.routeUsage[route] = u
+		return false
+	}))
+
+	if InBrowser() {
+		js.Global().Set("zuiRouteUsage", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return string(d.RouteUsage().JSON())
+		}))
+	}
+	return d
+}
+
+// RouteUsage returns the constructors and components used by the routes visited so far.
+func (d *Document) RouteUsage() RouteUsage {
+	if d.routeUsage == nil {
+		return RouteUsage{}
+	}
+	return d.routeUsage
+}
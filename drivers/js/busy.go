@@ -0,0 +1,118 @@
+package doc
+
+import (
+	ui "github.com/atdiar/particleui"
+)
+
+// Busy state
+//
+// SetBusy marks an element as busy during an asynchronous operation: aria-busy is set, pointer events are
+// disabled on its subtree (zui-busy class) and, optionally, a spinner is overlaid (zui-busy-spinner class).
+// The busy state is aggregated upward: the "is-busy" ui property (ui.Bool) of an element is true when the element
+// itself or any of its descendants is busy, so that containers can watch a single property.
+// The classes are styled by a default stylesheet that may be overridden.
+
+// BusyStyleSheetID is the id of the stylesheet holding the default busy state rules.
+const BusyStyleSheetID = "zui-busy"
+
+// BusyOptions allows to customize the busy state of an element.
+type BusyOptions struct {
+	Spinner bool
+}
+
+// SetBusy sets or clears the busy state of an element.
+func SetBusy(e *ui.Element, busy bool, opts ...BusyOptions) {
+	var o BusyOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if IsBusy(e) == busy {
+		return
+	}
+	if _, ok := e.Get(Namespace.Internals, "busy"); !ok {
+		// a busy element being deleted should not keep its ancestors busy
+		e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if v, ok := evt.Origin().Get(Namespace.Internals, "busy"); ok && bool(v.(ui.Bool)) {
+				propagateBusy(evt.Origin(), -1)
+			}
+			return false
+		}).RunOnce())
+	}
+	e.Set(Namespace.Internals, "busy", ui.Bool(busy))
+
+	if busy {
+		busyStyleSheet(GetDocument(e))
+		SetAttribute(e, "aria-busy", "true")
+		AddClass(e, "zui-busy")
+		if o.Spinner {
+			AddClass(e, "zui-busy-spinner")
+		}
+		propagateBusy(e, 1)
+		return
+	}
+	RemoveAttribute(e, "aria-busy")
+	RemoveClass(e, "zui-busy")
+	RemoveClass(e, "zui-busy-spinner")
+	propagateBusy(e, -1)
+}
+
+// IsBusy returns whether the element itself is busy.
+func IsBusy(e *ui.Element) bool {
+	v, ok := e.Get(Namespace.Internals, "busy")
+	if !ok {
+		return false
+	}
+	return bool(v.(ui.Bool))
+}
+
+// IsSubtreeBusy returns whether the element or any of its descendants is busy.
+func IsSubtreeBusy(e *ui.Element) bool {
+	v, ok := e.GetUI("is-busy")
+	if !ok {
+		return false
+	}
+	return bool(v.(ui.Bool))
+}
+
+// propagateBusy updates the count of busy elements within the subtree of e and of each of its ancestors.
+func propagateBusy(e *ui.Element, delta int) {
+	for el := e; el != nil; el = el.Parent {
+		count := 0
+		if v, ok := el.Get(Namespace.Internals, "busy-count"); ok {
+			count = int(v.(ui.Number))
+		}
+		count += delta
+		if count < 0 {
+			count = 0
+		}
+		el.Set(Namespace.Internals, "busy-count", ui.Number(count))
+		el.SetUI("is-busy", ui.Bool(count > 0))
+	}
+}
+
+func busyStyleSheet(d *Document) {
+	if _, ok := d.GetStyleSheet(BusyStyleSheetID); ok {
+		return
+	}
+	sheet := d.NewStyleSheet(BusyStyleSheetID)
+	actives := append([]string{BusyStyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+
+	sheet.InsertRule(".zui-busy", "pointer-events: none; cursor: progress;")
+	sheet.InsertRule(".zui-busy-spinner", "position: relative;")
+	sheet.InsertRule(".zui-busy-spinner::after", `
+		content: "";
+		position: absolute;
+		top: 50%;
+		left: 50%;
+		width: 1.5em;
+		height: 1.5em;
+		margin: -0.75em 0 0 -0.75em;
+		border: 0.2em solid currentColor;
+		border-right-color: transparent;
+		border-radius: 50%;
+		animation: zui-busy-spin 0.75s linear infinite;
+	`)
+	sheet.InsertKeyframe("zui-busy-spin", "to", "transform: rotate(360deg);")
+	sheet.Update()
+}
@@ -0,0 +1,137 @@
+package doc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Managed callbacks
+//
+// Every js.Func created via js.FuncOf has to be released explicitly, otherwise it leaks for the lifetime of the
+// wasm instance. RegisterCallback creates a js.Func whose lifetime is tied to an element: it is released
+// automatically when the element is deleted.
+// In dev mode, the callbacks still alive can be listed from the console by calling zuiCallbackReport().
+// Callbacks held by elements that are neither mounted nor attached to a parent are reported as potential leaks.
+
+// CallbackLeakThreshold is the duration after which callbacks held by a detached element are reported as leaked.
+var CallbackLeakThreshold = 30 * time.Second
+
+type managedCallback struct {
+	fn         js.Func
+	registered time.Time
+}
+
+var managedCallbacks = struct {
+	sync.Mutex
+	elements map[*ui.Element][]managedCallback
+}{elements: make(map[*ui.Element][]managedCallback)}
+
+// RegisterCallback returns a js.Func which is released automatically when the element is deleted.
+func RegisterCallback(e *ui.Element, fn func(this js.Value, args []js.Value) interface{}) js.Func {
+	f := js.FuncOf(fn)
+	managedCallbacks.Lock()
+	l, ok := managedCallbacks.elements[e]
+	managedCallbacks.elements[e] = append(l, managedCallback{f, time.Now()})
+	managedCallbacks.Unlock()
+	if !ok {
+		e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			ReleaseCallbacks(e)
+			return false
+		}).RunOnce().RunASAP())
+	}
+	return f
+}
+
+// ReleaseCallback releases a callback registered for the element before the element is deleted.
+func ReleaseCallback(e *ui.Element, f js.Func) {
+	managedCallbacks.Lock()
+	defer managedCallbacks.Unlock()
+	l := managedCallbacks.elements[e]
+	for i, c := range l {
+		if c.fn.Value.Equal(f.Value) {
+			c.fn.Release()
+			managedCallbacks.elements[e] = append(l[:i], l[i+1:]...)
+			return
+		}
+	}
+}
+
+// ReleaseCallbacks releases every callback registered for the element.
+func ReleaseCallbacks(e *ui.Element) {
+	managedCallbacks.Lock()
+	defer managedCallbacks.Unlock()
+	for _, c := range managedCallbacks.elements[e] {
+		c.fn.Release()
+	}
+	delete(managedCallbacks.elements, e)
+}
+
+// CallbackStats describes the callbacks held by an element.
+type CallbackStats struct {
+	ID          string
+	Constructor string
+	Count       int
+	Oldest      time.Duration
+	Mounted     bool
+	Leaked      bool
+}
+
+// CallbackReport lists the elements holding managed callbacks, potential leaks first.
+type CallbackReport []CallbackStats
+
+func (r CallbackReport) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "id\tconstructor\tcallbacks\toldest\tmounted\tleaked")
+	for _, s := range r {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%v\t%t\t%t\n", s.ID, s.Constructor, s.Count, s.Oldest.Round(time.Millisecond), s.Mounted, s.Leaked)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// Callbacks returns the report of the managed callbacks currently alive.
+func Callbacks() CallbackReport {
+	managedCallbacks.Lock()
+	defer managedCallbacks.Unlock()
+	r := make(CallbackReport, 0, len(managedCallbacks.elements))
+	for e, l := range managedCallbacks.elements {
+		if len(l) == 0 {
+			continue
+		}
+		s := CallbackStats{ID: e.ID, Constructor: "unknown", Count: len(l), Mounted: e.Mounted()}
+		if c, ok := e.Get(Namespace.Internals, "constructor"); ok {
+			s.Constructor = string(c.(ui.String))
+		}
+		for _, c := range l {
+			if age := time.Since(c.registered); age > s.Oldest {
+				s.Oldest = age
+			}
+		}
+		s.Leaked = !s.Mounted && e.Parent == nil && !e.IsRoot() && s.Oldest > CallbackLeakThreshold
+		r = append(r, s)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Leaked != r[j].Leaked {
+			return r[i].Leaked
+		}
+		return r[i].ID < r[j].ID
+	})
+	return r
+}
+
+func enableCallbackAudit() {
+	if !InBrowser() {
+		return
+	}
+	js.Global().Set("zuiCallbackReport", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return Callbacks().String()
+	}))
+}
@@ -0,0 +1,591 @@
+package doc
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Collaboration
+//
+// A CollabSession shares data properties of elements with remote peers. Every shared property is replicated
+// with a CRDT, so that concurrent changes merge without conflict:
+//   - a ui.Object is a last-writer-wins map: each field keeps the value of the latest write, writes being
+//     ordered by Lamport clock, then by replica id.
+//   - a ui.List is a replicated growable array (RGA): insertions are anchored to the element they follow and
+//     removals leave tombstones, so that every replica converges to the same order.
+//
+// Local changes are regular mutations of the shared property. They are diffed against the replicated state,
+// encoded as operations and broadcast through a Transport. Remote operations are merged into the replicated
+// state, which is then set as the new value of the property, i.e. applied as a normal mutation.
+// When a property starts being shared, and whenever the transport reconnects, the session asks its peers for
+// their state.
+//
+// Any other value type is replicated as a single last-writer-wins register.
+
+// Transport is the channel through which a CollabSession exchanges messages with its peers.
+// Messages received from the transport must be handed to the callback registered with OnMessage.
+type Transport interface {
+	Send(msg []byte) error
+	OnMessage(func(msg []byte))
+	Close() error
+}
+
+type opID struct {
+	Clock   uint64 `json:"c"`
+	Replica string `json:"r"`
+}
+
+func (id opID) isZero() bool { return id.Clock == 0 && id.Replica == "" }
+
+func (id opID) greater(other opID) bool {
+	if id.Clock != other.Clock {
+		return id.Clock > other.Clock
+	}
+	return id.Replica > other.Replica
+}
+
+// op kinds
+const (
+	opSet    = "set"    // object field or register write
+	opInsert = "insert" // list insertion
+	opRemove = "remove" // list removal
+)
+
+type collabOp struct {
+	Doc   string          `json:"doc"`
+	Kind  string          `json:"kind"`
+	Key   string          `json:"key,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	ID    opID            `json:"id"`
+	After opID            `json:"after,omitempty"`
+}
+
+type collabMessage struct {
+	Type string          `json:"type"` // "ops", "sync" or the type of a custom message
+	From string          `json:"from,omitempty"`
+	Doc  string          `json:"doc,omitempty"`
+	Ops  []collabOp      `json:"ops,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+func encodeOpValue(v ui.Value) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	b, err := ui.Marshal(v)
+	if err != nil {
+		DEBUG("collab: unable to encode value ", err)
+		return nil
+	}
+	return b
+}
+
+func decodeOpValue(b json.RawMessage) ui.Value {
+	if len(b) == 0 || string(b) == "null" {
+		return nil
+	}
+	v, err := ui.Unmarshal(b)
+	if err != nil {
+		DEBUG("collab: unable to decode value ", err)
+		return nil
+	}
+	return v
+}
+
+// replicated is the CRDT state of a shared property.
+type replicated interface {
+	apply(op collabOp) bool // returns whether the visible value changed
+	value() ui.Value
+	diff(v ui.Value, next func() opID) []collabOp
+	ops() []collabOp
+}
+
+// lwwMap is a last-writer-wins map, used for ui.Object and single values (with an empty key).
+type lwwMap struct {
+	doc    string
+	object bool
+	fields map[string]lwwField
+}
+
+type lwwField struct {
+	value ui.Value // nil when deleted
+	raw   json.RawMessage
+	id    opID
+}
+
+func (m *lwwMap) apply(op collabOp) bool {
+	if op.Kind != opSet {
+		return false
+	}
+	if f, ok := m.fields[op.Key]; ok && !op.ID.greater(f.id) {
+		return false
+	}
+	m.fields[op.Key] = lwwField{decodeOpValue(op.Value), op.Value, op.ID}
+	return true
+}
+
+func (m *lwwMap) value() ui.Value {
+	if !m.object {
+		return m.fields[""].value
+	}
+	o := ui.NewObject()
+	for k, f := range m.fields {
+		if f.value != nil {
+			o.Set(k, f.value)
+		}
+	}
+	return o.Commit()
+}
+
+func (m *lwwMap) diff(v ui.Value, next func() opID) []collabOp {
+	var res []collabOp
+	set := func(key string, val ui.Value) {
+		id := next()
+		op := collabOp{Doc: m.doc, Kind: opSet, Key: key, Value: encodeOpValue(val), ID: id}
+		m.apply(op)
+		res = append(res, op)
+	}
+	if !m.object {
+		if cur := m.fields[""].value; cur == nil || v == nil || !ui.Equal(cur, v) {
+			set("", v)
+		}
+		return res
+	}
+	o, ok := v.(ui.Object)
+	if !ok {
+		DEBUG("collab: a shared object property was set to a value of another type, ignored")
+		return nil
+	}
+	seen := make(map[string]bool)
+	o.Range(func(k string, val ui.Value) bool {
+		seen[k] = true
+		if cur := m.fields[k].value; cur == nil || !ui.Equal(cur, val) {
+			set(k, val)
+		}
+		return false
+	})
+	for k, f := range m.fields {
+		if !seen[k] && f.value != nil {
+			set(k, nil)
+		}
+	}
+	return res
+}
+
+func (m *lwwMap) ops() []collabOp {
+	res := make([]collabOp, 0, len(m.fields))
+	for k, f := range m.fields {
+		res = append(res, collabOp{Doc: m.doc, Kind: opSet, Key: k, Value: f.raw, ID: f.id})
+	}
+	return res
+}
+
+// rga is a replicated growable array, used for ui.List.
+type rga struct {
+	doc     string
+	nodes   []*rgaNode
+	index   map[opID]*rgaNode
+	pending []collabOp    // insertions whose anchor is unknown yet
+	removed map[opID]bool // removals of unknown nodes
+}
+
+type rgaNode struct {
+	id      opID
+	after   opID
+	value   ui.Value
+	raw     json.RawMessage
+	deleted bool
+}
+
+func (r *rga) position(id opID) int {
+	for i, n := range r.nodes {
+		if n.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *rga) insert(op collabOp) bool {
+	if _, ok := r.index[op.ID]; ok {
+		return false
+	}
+	i := -1
+	if !op.After.isZero() {
+		if _, ok := r.index[op.After]; !ok {
+			r.pending = append(r.pending, op)
+			return false
+		}
+		i = r.position(op.After)
+	}
+	// concurrent insertions after the same node are ordered by decreasing id.
+	j := i + 1
+	for j < len(r.nodes) && r.nodes[j].id.greater(op.ID) {
+		j++
+	}
+	n := &rgaNode{id: op.ID, after: op.After, value: decodeOpValue(op.Value), raw: op.Value}
+	if r.removed[op.ID] {
+		n.deleted = true
+		delete(r.removed, op.ID)
+	}
+	r.nodes = append(r.nodes, nil)
+	copy(r.nodes[j+1:], r.nodes[j:])
+	r.nodes[j] = n
+	r.index[op.ID] = n
+	return true
+}
+
+func (r *rga) apply(op collabOp) bool {
+	switch op.Kind {
+	case opInsert:
+		if !r.insert(op) {
+			return false
+		}
+		// pending insertions may now be anchored
+		for progress := true; progress; {
+			progress = false
+			pending := r.pending
+			r.pending = nil
+			for _, p := range pending {
+				if r.insert(p) {
+					progress = true
+				}
+			}
+		}
+		return true
+	case opRemove:
+		n, ok := r.index[op.ID]
+		if !ok {
+			r.removed[op.ID] = true
+			return false
+		}
+		if n.deleted {
+			return false
+		}
+		n.deleted = true
+		return true
+	}
+	return false
+}
+
+func (r *rga) visible() []*rgaNode {
+	res := make([]*rgaNode, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		if !n.deleted {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+func (r *rga) value() ui.Value {
+	l := ui.NewList()
+	for _, n := range r.visible() {
+		l.Append(n.value)
+	}
+	return l.Commit()
+}
+
+// diff turns the changes between the replicated list and a new list into removals and insertions of the
+// differing middle section, the common prefix and suffix being preserved.
+func (r *rga) diff(v ui.Value, next func() opID) []collabOp {
+	l, ok := v.(ui.List)
+	if !ok {
+		DEBUG("collab: a shared list property was set to a value of another type, ignored")
+		return nil
+	}
+	cur := r.visible()
+	values := l.UnsafelyUnwrap()
+	p := 0
+	for p < len(cur) && p < len(values) && ui.Equal(cur[p].value, values[p]) {
+		p++
+	}
+	s := 0
+	for s < len(cur)-p && s < len(values)-p && ui.Equal(cur[len(cur)-1-s].value, values[len(values)-1-s]) {
+		s++
+	}
+
+	var res []collabOp
+	for _, n := range cur[p : len(cur)-s] {
+		op := collabOp{Doc: r.doc, Kind: opRemove, ID: n.id}
+		r.apply(op)
+		res = append(res, op)
+	}
+	var after opID
+	if p > 0 {
+		after = cur[p-1].id
+	}
+	for _, val := range values[p : len(values)-s] {
+		op := collabOp{Doc: r.doc, Kind: opInsert, Value: encodeOpValue(val), ID: next(), After: after}
+		r.apply(op)
+		res = append(res, op)
+		after = op.ID
+	}
+	return res
+}
+
+func (r *rga) ops() []collabOp {
+	res := make([]collabOp, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		res = append(res, collabOp{Doc: r.doc, Kind: opInsert, Value: n.raw, ID: n.id, After: n.after})
+	}
+	for _, n := range r.nodes {
+		if n.deleted {
+			res = append(res, collabOp{Doc: r.doc, Kind: opRemove, ID: n.id})
+		}
+	}
+	return res
+}
+
+type sharedProp struct {
+	e        *ui.Element
+	prop     string
+	state    replicated
+	applying bool
+}
+
+// CollabSession replicates shared properties with remote peers.
+type CollabSession struct {
+	replica   string
+	clock     uint64
+	transport Transport
+	shared    map[string]*sharedProp
+	handlers  map[string]func(from string, data json.RawMessage)
+}
+
+// NewCollabSession returns a session for the given replica, which must be unique among peers (e.g. a
+// random id per tab), exchanging operations through the given transport.
+func NewCollabSession(replica string, t Transport) *CollabSession {
+	s := &CollabSession{replica: replica, transport: t, shared: make(map[string]*sharedProp), handlers: make(map[string]func(string, json.RawMessage))}
+	t.OnMessage(func(msg []byte) {
+		go ui.DoSync(func() { s.receive(msg) })
+	})
+	if r, ok := t.(interface{ OnOpen(func()) }); ok {
+		r.OnOpen(func() {
+			go ui.DoSync(func() {
+				for key := range s.shared {
+					s.send(collabMessage{Type: "sync", Doc: key})
+				}
+			})
+		})
+	}
+	return s
+}
+
+func (s *CollabSession) next() opID {
+	s.clock++
+	return opID{s.clock, s.replica}
+}
+
+// Share replicates a data property of an element. Its current value, if any, determines whether it is
+// replicated as an object, a list or a single value; a missing property is replicated as a single value.
+// The element id is used as the key of the property among peers, so it must be the same on every replica.
+func (s *CollabSession) Share(e *ui.Element, prop string) {
+	key := e.ID + "/" + prop
+	if _, ok := s.shared[key]; ok {
+		return
+	}
+	sp := &sharedProp{e: e, prop: prop}
+	v, ok := e.GetData(prop)
+	switch v.(type) {
+	case ui.List:
+		sp.state = &rga{doc: key, index: make(map[opID]*rgaNode), removed: make(map[opID]bool)}
+	case ui.Object:
+		sp.state = &lwwMap{doc: key, object: true, fields: make(map[string]lwwField)}
+	default:
+		sp.state = &lwwMap{doc: key, fields: make(map[string]lwwField)}
+	}
+	s.shared[key] = sp
+	if ok {
+		s.broadcast(sp.state.diff(v, s.next))
+	}
+
+	e.Watch(Namespace.Data, prop, e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if sp.applying {
+			return false
+		}
+		s.broadcast(sp.state.diff(evt.NewValue(), s.next))
+		return false
+	}))
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		delete(s.shared, key)
+		return false
+	}).RunOnce())
+
+	s.send(collabMessage{Type: "sync", Doc: key})
+}
+
+// Close stops the replication and closes the transport.
+func (s *CollabSession) Close() error {
+	s.shared = make(map[string]*sharedProp)
+	return s.transport.Close()
+}
+
+// handle registers the handler of a custom message type, e.g. for presence.
+func (s *CollabSession) handle(typ string, h func(from string, data json.RawMessage)) {
+	s.handlers[typ] = h
+}
+
+// sendData sends a custom message to the peers.
+func (s *CollabSession) sendData(typ string, data any) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		DEBUG("collab: unable to encode message data ", err)
+		return
+	}
+	s.send(collabMessage{Type: typ, From: s.replica, Data: b})
+}
+
+func (s *CollabSession) broadcast(ops []collabOp) {
+	if len(ops) == 0 {
+		return
+	}
+	s.send(collabMessage{Type: "ops", Ops: ops})
+}
+
+func (s *CollabSession) send(m collabMessage) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		DEBUG("collab: unable to encode message ", err)
+		return
+	}
+	if err := s.transport.Send(b); err != nil {
+		DEBUG("collab: unable to send message ", err)
+	}
+}
+
+func (s *CollabSession) receive(msg []byte) {
+	var m collabMessage
+	if err := json.Unmarshal(msg, &m); err != nil {
+		DEBUG("collab: invalid message ", err)
+		return
+	}
+	switch m.Type {
+	case "sync":
+		if sp, ok := s.shared[m.Doc]; ok {
+			s.broadcast(sp.state.ops())
+		}
+	case "ops":
+		changed := make(map[*sharedProp]bool)
+		for _, op := range m.Ops {
+			if op.ID.Clock > s.clock {
+				s.clock = op.ID.Clock
+			}
+			sp, ok := s.shared[op.Doc]
+			if !ok {
+				continue
+			}
+			if sp.state.apply(op) {
+				changed[sp] = true
+			}
+		}
+		for sp := range changed {
+			sp.applying = true
+			if v := sp.state.value(); v != nil {
+				sp.e.SetData(sp.prop, v)
+			}
+			sp.applying = false
+		}
+	default:
+		if h, ok := s.handlers[m.Type]; ok && m.From != s.replica {
+			h(m.From, m.Data)
+		}
+	}
+}
+
+// WebSocketTransport is a Transport over a WebSocket. Messages sent while the socket is not open are queued.
+// When the connection is lost, it reconnects with an exponential backoff.
+type WebSocketTransport struct {
+	url     string
+	socket  js.Value
+	queue   [][]byte
+	handler func([]byte)
+	onopen  []func()
+	closed  bool
+	delay   time.Duration
+}
+
+// NewWebSocketTransport returns a transport connected to the given WebSocket url.
+func NewWebSocketTransport(url string) (*WebSocketTransport, error) {
+	if !InBrowser() || !js.Global().Get("WebSocket").Truthy() {
+		return nil, errors.New("WebSocket is not available")
+	}
+	t := &WebSocketTransport{url: url, delay: time.Second}
+	t.connect()
+	return t, nil
+}
+
+func (t *WebSocketTransport) connect() {
+	ws := js.Global().Get("WebSocket").New(t.url)
+	t.socket = ws
+	var onopen, onmessage, onclose js.Func
+	onopen = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		t.delay = time.Second
+		queue := t.queue
+		t.queue = nil
+		for _, msg := range queue {
+			ws.Call("send", string(msg))
+		}
+		for _, f := range t.onopen {
+			f()
+		}
+		return nil
+	})
+	onmessage = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if t.handler != nil {
+			t.handler([]byte(args[0].Get("data").String()))
+		}
+		return nil
+	})
+	onclose = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		onopen.Release()
+		onmessage.Release()
+		onclose.Release()
+		if t.closed {
+			return nil
+		}
+		delay := t.delay
+		if t.delay < time.Minute {
+			t.delay *= 2
+		}
+		time.AfterFunc(delay, t.connect)
+		return nil
+	})
+	ws.Set("onopen", onopen)
+	ws.Set("onmessage", onmessage)
+	ws.Set("onclose", onclose)
+}
+
+// Send sends a message, or queues it until the socket is open.
+func (t *WebSocketTransport) Send(msg []byte) error {
+	if t.closed {
+		return errors.New("transport is closed")
+	}
+	if t.socket.Get("readyState").Int() != 1 {
+		t.queue = append(t.queue, msg)
+		return nil
+	}
+	t.socket.Call("send", string(msg))
+	return nil
+}
+
+// OnMessage registers the function called with every received message.
+func (t *WebSocketTransport) OnMessage(f func(msg []byte)) {
+	t.handler = f
+}
+
+// OnOpen registers a function called whenever the socket opens, including after a reconnection.
+func (t *WebSocketTransport) OnOpen(f func()) {
+	t.onopen = append(t.onopen, f)
+}
+
+// Close closes the socket for good.
+func (t *WebSocketTransport) Close() error {
+	t.closed = true
+	t.socket.Call("close")
+	return nil
+}
@@ -25,3 +25,15 @@ var (
 	CopyBytesToJS = js.CopyBytesToJS
 	FuncOf        = js.FuncOf
 )
+
+// Re-exporting constants from syscall/js
+const (
+	TypeUndefined = js.TypeUndefined
+	TypeNull      = js.TypeNull
+	TypeBoolean   = js.TypeBoolean
+	TypeNumber    = js.TypeNumber
+	TypeString    = js.TypeString
+	TypeSymbol    = js.TypeSymbol
+	TypeObject    = js.TypeObject
+	TypeFunction  = js.TypeFunction
+)
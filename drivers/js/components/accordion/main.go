@@ -0,0 +1,259 @@
+// package accordion provides an accordion component built on details and summary elements.
+package accordion
+
+import (
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// An accordion is a list of sections, each of them being a details element whose summary is the title of the
+// section. Sections are expanded and collapsed natively by the browser, the accordion keeping track of them.
+// In exclusive mode, opening a section closes the others.
+// The height of a section is animated when it is expanded or collapsed, unless the user prefers reduced motion.
+//
+// With WithHashLinks, a section whose element id is the fragment of the URL is opened and scrolled into view,
+// on load and when the hash changes. The element id of a section is the accordion id followed by "-" and the
+// section id.
+//
+// Data properties of the accordion element:
+//   - "open" (ui.List): ids of the open sections
+//
+// Events triggered on the accordion element:
+//   - "accordion-toggle": a section was opened or closed. The event value is an Object with the "section" id
+//     and the "open" boolean.
+
+// StyleSheetID is the id of the stylesheet holding the accordion rules.
+const StyleSheetID = "zui-accordion"
+
+// Section describes a section of an accordion.
+type Section struct {
+	ID    string
+	Title string
+	// Content creates the element holding the content of the section.
+	Content func(d *Document, id string) *ui.Element
+	Open    bool
+}
+
+type AccordionElement struct {
+	*ui.Element
+}
+
+type config struct {
+	exclusive bool
+	hash      bool
+}
+
+// Option allows to configure an accordion.
+type Option func(*config)
+
+// WithExclusive allows at most one section to be open at a time.
+func WithExclusive() Option {
+	return func(c *config) { c.exclusive = true }
+}
+
+// WithHashLinks opens the section targeted by the URL hash.
+func WithHashLinks() Option {
+	return func(c *config) { c.hash = true }
+}
+
+// registries holds the sections of each accordion, indexed by accordion id.
+var registries = make(map[string][]Section)
+
+// New returns an accordion made of the given sections.
+func New(d *Document, id string, sections []Section, options ...Option) AccordionElement {
+	cfg := &config{}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/accordion")
+	AddClass(e, "zui-accordion")
+	a := AccordionElement{e}
+	registries[id] = sections
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		delete(registries, id)
+		return false
+	}).RunOnce())
+
+	children := make([]*ui.Element, 0, len(sections))
+	for _, section := range sections {
+		sid := id + "-" + section.ID
+		details := d.Details.WithID(sid)
+		AddClass(details.AsElement(), "zui-accordion-section")
+		if cfg.exclusive {
+			// native exclusive accordion, in browsers which support it
+			SetAttribute(details.AsElement(), "name", id)
+		}
+
+		summary := d.Summary.WithID(sid + "-title").SetText(section.Title)
+		AddClass(summary.AsElement(), "zui-accordion-title")
+		SetAttribute(summary.AsElement(), "aria-controls", sid+"-panel")
+
+		panel := d.Div.WithID(sid + "-panel")
+		AddClass(panel.AsElement(), "zui-accordion-panel")
+		SetAttribute(panel.AsElement(), "role", "region")
+		SetAttribute(panel.AsElement(), "aria-labelledby", sid+"-title")
+		if section.Content != nil {
+			panel.AsElement().SetChildren(section.Content(d, sid+"-content"))
+		}
+		details.AsElement().SetChildren(summary.AsElement(), panel.AsElement())
+
+		sectionID := section.ID
+		details.AsElement().Watch(Namespace.UI, "open", details, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			open := bool(evt.NewValue().(ui.Bool))
+			if open {
+				SetAttribute(summary.AsElement(), "aria-expanded", "true")
+			} else {
+				SetAttribute(summary.AsElement(), "aria-expanded", "false")
+			}
+			if open && cfg.exclusive {
+				for _, s := range registries[id] {
+					if s.ID != sectionID {
+						a.Close(s.ID)
+					}
+				}
+			}
+			a.sync()
+			e.TriggerEvent("accordion-toggle", ui.NewObject().Set("section", ui.String(sectionID)).Set("open", ui.Bool(open)).Commit())
+			return false
+		}))
+
+		// the open state is kept in sync when the user toggles the section natively.
+		details.AsElement().AddEventListener("toggle", ui.NewEventHandler(func(evt ui.Event) bool {
+			n, ok := JSValue(details.AsElement())
+			if !ok {
+				return false
+			}
+			open := n.Get("open").Bool()
+			if open != details.IsOpened() {
+				details.AsElement().SetDataSetUI("open", ui.Bool(open))
+			}
+			return false
+		}))
+
+		if section.Open {
+			details.Open()
+		} else {
+			details.Close()
+		}
+		children = append(children, details.AsElement())
+	}
+	e.SetChildren(children...)
+	style(d)
+
+	if cfg.hash {
+		a.openFromHash()
+		d.Window().AsElement().AddEventListener("hashchange", ui.NewEventHandler(func(evt ui.Event) bool {
+			a.openFromHash()
+			return false
+		}))
+	}
+
+	return a
+}
+
+func (a AccordionElement) details(sectionID string) (DetailsElement, bool) {
+	e := GetDocument(a.AsElement()).GetElementById(a.AsElement().ID + "-" + sectionID)
+	if e == nil {
+		return DetailsElement{}, false
+	}
+	return DetailsElement{Element: e}, true
+}
+
+// Open expands a section.
+func (a AccordionElement) Open(sectionID string) AccordionElement {
+	if d, ok := a.details(sectionID); ok && !d.IsOpened() {
+		d.Open()
+	}
+	return a
+}
+
+// Close collapses a section.
+func (a AccordionElement) Close(sectionID string) AccordionElement {
+	if d, ok := a.details(sectionID); ok && d.IsOpened() {
+		d.Close()
+	}
+	return a
+}
+
+// Toggle expands or collapses a section.
+func (a AccordionElement) Toggle(sectionID string) AccordionElement {
+	if d, ok := a.details(sectionID); ok {
+		if d.IsOpened() {
+			d.Close()
+		} else {
+			d.Open()
+		}
+	}
+	return a
+}
+
+// IsOpen returns whether a section is expanded.
+func (a AccordionElement) IsOpen(sectionID string) bool {
+	d, ok := a.details(sectionID)
+	return ok && d.IsOpened()
+}
+
+// OnToggle registers a handler called when a section is opened or closed.
+func (a AccordionElement) OnToggle(h *ui.MutationHandler) AccordionElement {
+	a.AsElement().WatchEvent("accordion-toggle", a, h)
+	return a
+}
+
+// sync updates the list of open sections.
+func (a AccordionElement) sync() {
+	l := ui.NewList()
+	for _, s := range registries[a.AsElement().ID] {
+		if a.IsOpen(s.ID) {
+			l.Append(ui.String(s.ID))
+		}
+	}
+	a.AsElement().SetData("open", l.Commit())
+}
+
+func (a AccordionElement) openFromHash() {
+	if !InBrowser() {
+		return
+	}
+	hash := strings.TrimPrefix(js.Global().Get("location").Get("hash").String(), "#")
+	prefix := a.AsElement().ID + "-"
+	if !strings.HasPrefix(hash, prefix) {
+		return
+	}
+	sectionID := strings.TrimPrefix(hash, prefix)
+	d, ok := a.details(sectionID)
+	if !ok {
+		return
+	}
+	a.Open(sectionID)
+	if n, ok := JSValue(d.AsElement()); ok {
+		n.Call("scrollIntoView", map[string]interface{}{"block": "start"})
+	}
+}
+
+var rules = map[string]string{
+	":where(.zui-accordion)":                                "interpolate-size: allow-keywords;",
+	":where(.zui-accordion-section)::details-content":       "block-size: 0; overflow: hidden; transition: block-size 0.2s ease, content-visibility 0.2s allow-discrete;",
+	":where(.zui-accordion-section[open])::details-content": "block-size: auto;",
+	":where(.zui-accordion-title)":                          "cursor: pointer;",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.UpdateNestedRule("@media (prefers-reduced-motion: reduce)", ".zui-accordion-section::details-content", "transition: none;")
+	sheet.Update()
+}
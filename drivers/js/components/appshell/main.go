@@ -0,0 +1,348 @@
+// package appshell provides a responsive navigation shell made of an app bar, a navigation drawer and
+// an optional bottom navigation.
+package appshell
+
+import (
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// The shell lays out an app bar at the top, a navigation drawer, the main content area and, optionally,
+// a bottom navigation bar.
+// Below the breakpoint (mobile), the drawer is hidden offscreen and toggled by the menu button of the app bar.
+// While open, it traps the focus and is closed by Escape, a click on the scrim or a navigation.
+// From the breakpoint upward (desktop), the drawer is persistent and the bottom navigation is hidden.
+//
+// Navigation items are bound to router links: the anchor of the active link has the "zui-appshell-active"
+// class and the aria-current="page" attribute.
+//
+// UI properties of the shell element:
+//   - "mode" (ui.String): "mobile" or "desktop"
+//   - "drawer" (ui.String): "open" or "closed"
+
+// StyleSheetID is the id of the stylesheet holding the shell rules.
+const StyleSheetID = "zui-appshell"
+
+// DefaultBreakpoint is the viewport width, in pixels, from which the drawer is persistent.
+var DefaultBreakpoint = 1024
+
+// NavItem describes a navigation entry.
+type NavItem struct {
+	Label string
+	Link  ui.Link
+	// Icon, if not empty, is the text (e.g. an icon font ligature or an emoji) displayed before the label.
+	Icon string
+}
+
+type ShellElement struct {
+	*ui.Element
+}
+
+type config struct {
+	breakpoint int
+	drawer     []NavItem
+	bottom     []NavItem
+}
+
+// Option allows to configure a shell.
+type Option func(*config)
+
+// WithBreakpoint sets the viewport width from which the drawer is persistent.
+func WithBreakpoint(px int) Option {
+	return func(c *config) { c.breakpoint = px }
+}
+
+// WithDrawer sets the navigation items of the drawer.
+func WithDrawer(items ...NavItem) Option {
+	return func(c *config) { c.drawer = items }
+}
+
+// WithBottomNav adds a bottom navigation bar, displayed on mobile, with the given items.
+func WithBottomNav(items ...NavItem) Option {
+	return func(c *config) { c.bottom = items }
+}
+
+// Shell returns an app shell. The page content should be appended to the element returned by Content.
+func Shell(d *Document, id string, title string, options ...Option) ShellElement {
+	cfg := &config{breakpoint: DefaultBreakpoint}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	shell := d.Div.WithID(id)
+	e := shell.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/appshell")
+	AddClass(e, "zui-appshell")
+	s := ShellElement{e}
+
+	appbar := d.Header.WithID(id + "-appbar")
+	AddClass(appbar.AsElement(), "zui-appshell-appbar")
+	menu := d.Button.WithID(id+"-menu", "button").SetText("☰")
+	AddClass(menu.AsElement(), "zui-appshell-menu")
+	SetAttribute(menu.AsElement(), "aria-label", "Menu")
+	SetAttribute(menu.AsElement(), "aria-controls", id+"-drawer")
+	SetAttribute(menu.AsElement(), "aria-expanded", "false")
+	titlespan := d.Span.WithID(id + "-title").SetText(title)
+	AddClass(titlespan.AsElement(), "zui-appshell-title")
+	actions := d.Div.WithID(id + "-actions")
+	AddClass(actions.AsElement(), "zui-appshell-actions")
+	appbar.AsElement().SetChildren(menu.AsElement(), titlespan.AsElement(), actions.AsElement())
+
+	drawer := d.Nav.WithID(id + "-drawer")
+	AddClass(drawer.AsElement(), "zui-appshell-drawer")
+	SetAttribute(drawer.AsElement(), "aria-label", "Main navigation")
+	drawer.AsElement().SetChildren(navList(d, id+"-drawer-list", cfg.drawer))
+
+	scrim := d.Div.WithID(id + "-scrim")
+	AddClass(scrim.AsElement(), "zui-appshell-scrim")
+
+	content := d.Main.WithID(id + "-content")
+	AddClass(content.AsElement(), "zui-appshell-content")
+
+	children := []*ui.Element{appbar.AsElement(), drawer.AsElement(), scrim.AsElement(), content.AsElement()}
+	if len(cfg.bottom) > 0 {
+		bottom := d.Nav.WithID(id + "-bottomnav")
+		AddClass(bottom.AsElement(), "zui-appshell-bottomnav")
+		SetAttribute(bottom.AsElement(), "aria-label", "Bottom navigation")
+		bottom.AsElement().SetChildren(navList(d, id+"-bottomnav-list", cfg.bottom))
+		children = append(children, bottom.AsElement())
+	}
+	e.SetChildren(children...)
+
+	e.SetUI("mode", ui.String("desktop"))
+	e.SetUI("drawer", ui.String("closed"))
+
+	e.Watch(Namespace.UI, "drawer", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		open := string(evt.NewValue().(ui.String)) == "open"
+		SetAttribute(e, "data-drawer", string(evt.NewValue().(ui.String)))
+		SetAttribute(menu.AsElement(), "aria-expanded", strconv.FormatBool(open))
+		if open && s.Mobile() {
+			if first := d.GetElementById(id + "-drawer-list-0-link"); first != nil {
+				SetFocus(first, false)
+			}
+		}
+		return false
+	}).RunASAP())
+
+	e.Watch(Namespace.UI, "mode", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		SetAttribute(e, "data-mode", string(evt.NewValue().(ui.String)))
+		if !s.Mobile() {
+			s.CloseDrawer()
+		}
+		return false
+	}).RunASAP())
+
+	menu.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		s.ToggleDrawer()
+		return false
+	}))
+	scrim.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		s.CloseDrawer()
+		return false
+	}))
+	drawer.AsElement().AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		k, ok := evt.(KeyboardEvent)
+		if !ok || !s.Mobile() || !s.DrawerOpen() {
+			return false
+		}
+		switch k.Key() {
+		case "Escape":
+			s.CloseDrawer()
+			SetFocus(menu.AsElement(), false)
+		case "Tab":
+			trapTab(drawer.AsElement(), evt, k.ShiftKey())
+		}
+		return false
+	}))
+
+	e.AfterEvent("navigation-end", e.Root, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if s.Mobile() {
+			s.CloseDrawer()
+		}
+		return false
+	}))
+
+	watchViewport(s, cfg.breakpoint)
+	style(d, id, cfg.breakpoint)
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		sheet, ok := d.GetStyleSheet(StyleSheetID)
+		if !ok {
+			return false
+		}
+		for _, sel := range rules(id) {
+			sheet.RemoveRule(sel)
+		}
+		sheet.RemoveNestedRule(desktopQuery(cfg.breakpoint), "#"+id)
+		sheet.RemoveNestedRule(desktopQuery(cfg.breakpoint), "#"+id+" .zui-appshell-menu, #"+id+" .zui-appshell-bottomnav, #"+id+" .zui-appshell-scrim")
+		sheet.RemoveNestedRule(desktopQuery(cfg.breakpoint), "#"+id+" .zui-appshell-drawer")
+		sheet.Update()
+		return false
+	}).RunOnce())
+
+	return s
+}
+
+// Actions returns the element holding the actions displayed on the right of the app bar.
+func (s ShellElement) Actions() *ui.Element {
+	return GetDocument(s.AsElement()).GetElementById(s.AsElement().ID + "-actions")
+}
+
+// Content returns the main content area of the shell.
+func (s ShellElement) Content() *ui.Element {
+	return GetDocument(s.AsElement()).GetElementById(s.AsElement().ID + "-content")
+}
+
+// Mobile returns whether the viewport is narrower than the breakpoint of the shell.
+func (s ShellElement) Mobile() bool {
+	v, ok := s.AsElement().GetUI("mode")
+	return ok && string(v.(ui.String)) == "mobile"
+}
+
+// DrawerOpen returns whether the drawer is open. On desktop, the drawer is always visible.
+func (s ShellElement) DrawerOpen() bool {
+	v, ok := s.AsElement().GetUI("drawer")
+	return ok && string(v.(ui.String)) == "open"
+}
+
+func (s ShellElement) OpenDrawer() ShellElement {
+	s.AsElement().SetUI("drawer", ui.String("open"))
+	return s
+}
+
+func (s ShellElement) CloseDrawer() ShellElement {
+	s.AsElement().SetUI("drawer", ui.String("closed"))
+	return s
+}
+
+func (s ShellElement) ToggleDrawer() ShellElement {
+	if s.DrawerOpen() {
+		return s.CloseDrawer()
+	}
+	return s.OpenDrawer()
+}
+
+func navList(d *Document, id string, items []NavItem) *ui.Element {
+	list := d.Ul.WithID(id)
+	AddClass(list.AsElement(), "zui-appshell-navlist")
+	lis := make([]*ui.Element, 0, len(items))
+	for i, item := range items {
+		iid := id + "-" + strconv.Itoa(i)
+		a := d.Anchor.WithID(iid + "-link").FromLink(item.Link)
+		AddClass(a.AsElement(), "zui-appshell-navlink")
+		children := make([]*ui.Element, 0, 2)
+		if item.Icon != "" {
+			icon := d.Span.WithID(iid + "-icon").SetText(item.Icon)
+			AddClass(icon.AsElement(), "zui-appshell-icon")
+			SetAttribute(icon.AsElement(), "aria-hidden", "true")
+			children = append(children, icon.AsElement())
+		}
+		children = append(children, d.Span.WithID(iid+"-label").SetText(item.Label).AsElement())
+		a.AsElement().SetChildren(children...)
+
+		a.AsElement().Watch(Namespace.UI, "active", a, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if b, ok := evt.NewValue().(ui.Bool); ok && bool(b) {
+				AddClass(evt.Origin(), "zui-appshell-active")
+				SetAttribute(evt.Origin(), "aria-current", "page")
+				return false
+			}
+			RemoveClass(evt.Origin(), "zui-appshell-active")
+			RemoveAttribute(evt.Origin(), "aria-current")
+			return false
+		}).RunASAP())
+
+		li := d.Li.WithID(iid)
+		li.AsElement().SetChildren(a.AsElement())
+		lis = append(lis, li.AsElement())
+	}
+	list.AsElement().SetChildren(lis...)
+	return list.AsElement()
+}
+
+// trapTab keeps the focus within the drawer when tabbing past its first or last focusable element.
+func trapTab(drawer *ui.Element, evt ui.Event, backward bool) {
+	n, ok := JSValue(drawer)
+	if !ok {
+		return
+	}
+	focusables := n.Call("querySelectorAll", `a[href], button, input, select, textarea, [tabindex]:not([tabindex="-1"])`)
+	count := focusables.Length()
+	if count == 0 {
+		return
+	}
+	first, last := focusables.Index(0), focusables.Index(count-1)
+	active := js.Global().Get("document").Get("activeElement")
+	if backward && active.Equal(first) {
+		evt.PreventDefault()
+		last.Call("focus")
+	} else if !backward && active.Equal(last) {
+		evt.PreventDefault()
+		first.Call("focus")
+	}
+}
+
+// watchViewport keeps the "mode" of the shell in sync with the viewport width.
+func watchViewport(s ShellElement, breakpoint int) {
+	if !InBrowser() {
+		return
+	}
+	mql := js.Global().Call("matchMedia", "(min-width: "+strconv.Itoa(breakpoint)+"px)")
+	update := func(desktop bool) {
+		if desktop {
+			s.AsElement().SetUI("mode", ui.String("desktop"))
+		} else {
+			s.AsElement().SetUI("mode", ui.String("mobile"))
+		}
+	}
+	update(mql.Get("matches").Bool())
+	cb := RegisterCallback(s.AsElement(), func(this js.Value, args []js.Value) interface{} {
+		desktop := args[0].Get("matches").Bool()
+		go ui.DoSync(func() { update(desktop) })
+		return nil
+	})
+	mql.Call("addEventListener", "change", cb)
+	s.AsElement().OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		mql.Call("removeEventListener", "change", cb)
+		return false
+	}).RunOnce())
+}
+
+func desktopQuery(breakpoint int) string {
+	return "@media (min-width: " + strconv.Itoa(breakpoint) + "px)"
+}
+
+func rules(id string) map[string]string {
+	sel := "#" + id
+	return map[string]string{
+		":where(" + sel + ")":                                               "display: grid; grid-template-columns: 1fr; grid-template-rows: auto 1fr auto; min-height: 100vh;",
+		":where(" + sel + " .zui-appshell-appbar)":                          "grid-column: 1 / -1; display: flex; align-items: center; gap: 0.5rem; position: sticky; top: 0; z-index: 2;",
+		":where(" + sel + " .zui-appshell-actions)":                         "margin-left: auto; display: flex; gap: 0.5rem;",
+		":where(" + sel + " .zui-appshell-drawer)":                          "position: fixed; top: 0; bottom: 0; left: 0; width: 16rem; overflow-y: auto; z-index: 4; background: Canvas; transform: translateX(-100%); visibility: hidden; transition: transform 0.2s ease, visibility 0.2s;",
+		":where(" + sel + "[data-drawer=open] .zui-appshell-drawer)":        "transform: none; visibility: visible;",
+		":where(" + sel + " .zui-appshell-scrim)":                           "display: none;",
+		":where(" + sel + "[data-drawer=open] .zui-appshell-scrim)":         "display: block; position: fixed; inset: 0; z-index: 3; background: rgba(0, 0, 0, 0.4);",
+		":where(" + sel + " .zui-appshell-navlist)":                         "list-style: none; margin: 0; padding: 0;",
+		":where(" + sel + " .zui-appshell-bottomnav)":                       "position: sticky; bottom: 0; z-index: 2;",
+		":where(" + sel + " .zui-appshell-bottomnav .zui-appshell-navlist)": "display: flex; justify-content: space-around;",
+	}
+}
+
+func style(d *Document, id string, breakpoint int) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if !ok {
+		sheet = d.NewStyleSheet(StyleSheetID)
+		actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+		d.SetActiveStyleSheets(actives...)
+	}
+	for sel, rule := range rules(id) {
+		sheet.UpdateRule(sel, rule)
+	}
+	q := desktopQuery(breakpoint)
+	sel := "#" + id
+	sheet.UpdateNestedRule(q, sel, "grid-template-columns: 16rem 1fr; grid-template-rows: auto 1fr;")
+	sheet.UpdateNestedRule(q, sel+" .zui-appshell-drawer", "position: sticky; top: 0; height: 100vh; transform: none; visibility: visible; transition: none;")
+	sheet.UpdateNestedRule(q, sel+" .zui-appshell-menu, "+sel+" .zui-appshell-bottomnav, "+sel+" .zui-appshell-scrim", "display: none;")
+	sheet.Update()
+}
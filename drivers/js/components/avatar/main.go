@@ -0,0 +1,270 @@
+// package avatar provides an avatar component with a presence indicator.
+package avatar
+
+import (
+	"encoding/base64"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"unicode"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// An avatar displays the picture of a user. When there is no picture, or when it fails to load, the initials
+// of the user name are displayed instead, or a generated identicon if there is no name or if identicons are
+// preferred. Identicons are deterministic: they only depend on the user id.
+// The background color of the initials is also derived from the user id, unless the --zui-avatar-background
+// CSS variable is set.
+//
+// Avatars may display a presence indicator, whose status is any string, typically one of the presence
+// status constants. It can be bound to a watchable property, e.g. of a presence service, with BindPresence.
+//
+// Data properties of the avatar element:
+//   - "status" (ui.String): the presence status, empty if none
+//
+// UI properties of the avatar element:
+//   - "display" (ui.String): "image", "initials" or "identicon", depending on what is displayed
+
+// StyleSheetID is the id of the stylesheet holding the avatar rules.
+const StyleSheetID = "zui-avatar"
+
+// Sizes
+const (
+	Small  = "small"
+	Medium = "medium"
+	Large  = "large"
+)
+
+// Presence statuses
+const (
+	Online  = "online"
+	Away    = "away"
+	Busy    = "busy"
+	Offline = "offline"
+)
+
+type AvatarElement struct {
+	*ui.Element
+}
+
+type config struct {
+	src       string
+	size      string
+	identicon bool
+	presence  bool
+}
+
+// Option allows to configure an avatar.
+type Option func(*config)
+
+// WithImage sets the url of the picture of the user.
+func WithImage(src string) Option {
+	return func(c *config) { c.src = src }
+}
+
+// WithSize sets the size variant of the avatar. The default is Medium.
+func WithSize(size string) Option {
+	return func(c *config) { c.size = size }
+}
+
+// WithIdenticon prefers the identicon to the initials as a fallback.
+func WithIdenticon() Option {
+	return func(c *config) { c.identicon = true }
+}
+
+// WithPresence displays the presence indicator.
+func WithPresence() Option {
+	return func(c *config) { c.presence = true }
+}
+
+// New returns an avatar for the given user.
+func New(d *Document, id string, userID string, name string, options ...Option) AvatarElement {
+	cfg := &config{size: Medium}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	root := d.Span.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/avatar")
+	AddClass(e, "zui-avatar")
+	SetAttribute(e, "data-size", cfg.size)
+	SetAttribute(e, "role", "img")
+	label := name
+	if label == "" {
+		label = userID
+	}
+	SetAttribute(e, "aria-label", label)
+	a := AvatarElement{e}
+	h := hash(userID)
+	SetCSSVariable(e, "zui-avatar-hue", strconv.Itoa(int(h%360)))
+
+	fallback := func() *ui.Element {
+		initials := Initials(name)
+		if cfg.identicon || initials == "" {
+			e.SetUI("display", ui.String("identicon"))
+			img := d.Img.WithID(id + "-identicon")
+			AddClass(img.AsElement(), "zui-avatar-identicon")
+			img.AsElement().SetUI("src", ui.String(Identicon(userID)))
+			img.AsElement().SetUI("alt", ui.String(""))
+			return img.AsElement()
+		}
+		e.SetUI("display", ui.String("initials"))
+		s := d.Span.WithID(id + "-initials").SetText(initials)
+		AddClass(s.AsElement(), "zui-avatar-initials")
+		SetAttribute(s.AsElement(), "aria-hidden", "true")
+		return s.AsElement()
+	}
+
+	var content *ui.Element
+	if cfg.src != "" {
+		e.SetUI("display", ui.String("image"))
+		img := d.Img.WithID(id + "-image")
+		AddClass(img.AsElement(), "zui-avatar-image")
+		img.AsElement().SetUI("alt", ui.String(""))
+		img.AsElement().SetUI("src", ui.String(cfg.src))
+		img.AsElement().AddEventListener("error", ui.NewEventHandler(func(evt ui.Event) bool {
+			e.ReplaceChild(img.AsElement(), fallback())
+			return false
+		}).TriggerOnce())
+		content = img.AsElement()
+	} else {
+		content = fallback()
+	}
+
+	children := []*ui.Element{content}
+	if cfg.presence {
+		dot := d.Span.WithID(id + "-presence").AsElement()
+		AddClass(dot, "zui-avatar-presence")
+		children = append(children, dot)
+		e.Watch(Namespace.Data, "status", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			status := string(evt.NewValue().(ui.String))
+			if status == "" {
+				SetAttribute(dot, "hidden", "")
+				SetAttribute(e, "aria-label", label)
+				return false
+			}
+			RemoveAttribute(dot, "hidden")
+			SetAttribute(dot, "data-status", status)
+			SetAttribute(e, "aria-label", label+" ("+status+")")
+			return false
+		}))
+		e.SetData("status", ui.String(""))
+	}
+	e.SetChildren(children...)
+	style(d)
+
+	return a
+}
+
+// SetStatus changes the presence status of the avatar.
+func (a AvatarElement) SetStatus(status string) AvatarElement {
+	a.AsElement().SetData("status", ui.String(status))
+	return a
+}
+
+// Status returns the presence status of the avatar.
+func (a AvatarElement) Status() string {
+	v, ok := a.AsElement().GetData("status")
+	if !ok {
+		return ""
+	}
+	return string(v.(ui.String))
+}
+
+// BindPresence keeps the presence status of the avatar in sync with a ui.String property of a source element.
+func (a AvatarElement) BindPresence(source ui.Watchable, category string, propname string) AvatarElement {
+	a.AsElement().Watch(category, propname, source, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		s, ok := evt.NewValue().(ui.String)
+		if !ok {
+			DEBUG("avatar: presence status is not a string")
+			return false
+		}
+		a.SetStatus(string(s))
+		return false
+	}))
+	return a
+}
+
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// Initials returns the uppercased initials of the first and last words of a name.
+func Initials(name string) string {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	first := func(w string) string {
+		for _, r := range w {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return string(unicode.ToUpper(r))
+			}
+		}
+		return ""
+	}
+	res := first(words[0])
+	if len(words) > 1 {
+		res += first(words[len(words)-1])
+	}
+	return res
+}
+
+// Identicon returns a data url of a 5x5 symmetric SVG identicon generated from a user id.
+func Identicon(userID string) string {
+	h := fnv.New64a()
+	h.Write([]byte(userID))
+	bits := h.Sum64()
+	hue := strconv.Itoa(int(hash(userID) % 360))
+
+	var b strings.Builder
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 5 5" shape-rendering="crispEdges">`)
+	b.WriteString(`<rect width="5" height="5" fill="hsl(` + hue + ` 40% 92%)"/>`)
+	// the three left columns are generated, the two right ones mirror them.
+	for col := 0; col < 3; col++ {
+		for row := 0; row < 5; row++ {
+			if bits&(1<<uint(col*5+row)) == 0 {
+				continue
+			}
+			y := strconv.Itoa(row)
+			for _, x := range []int{col, 4 - col} {
+				b.WriteString(`<rect x="` + strconv.Itoa(x) + `" y="` + y + `" width="1" height="1" fill="hsl(` + hue + ` 55% 45%)"/>`)
+				if col == 2 {
+					break
+				}
+			}
+		}
+	}
+	b.WriteString(`</svg>`)
+	return "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(b.String()))
+}
+
+var rules = map[string]string{
+	":where(.zui-avatar)":                              "position: relative; display: inline-flex; align-items: center; justify-content: center; flex-shrink: 0; width: var(--zui-avatar-size, 2.5rem); height: var(--zui-avatar-size, 2.5rem); border-radius: 50%; background: var(--zui-avatar-background, hsl(var(--zui-avatar-hue) 45% 45%)); color: #fff; font-size: calc(var(--zui-avatar-size, 2.5rem) * 0.4); vertical-align: middle;",
+	":where(.zui-avatar[data-size=small])":             "--zui-avatar-size: 1.5rem;",
+	":where(.zui-avatar[data-size=large])":             "--zui-avatar-size: 4rem;",
+	":where(.zui-avatar-image, .zui-avatar-identicon)": "width: 100%; height: 100%; border-radius: 50%; object-fit: cover;",
+	":where(.zui-avatar-presence)":                     "position: absolute; right: 0; bottom: 0; width: 28%; height: 28%; border-radius: 50%; border: 2px solid Canvas; background: GrayText;",
+	":where(.zui-avatar-presence[data-status=online])": "background: var(--zui-presence-online, #2e7d32);",
+	":where(.zui-avatar-presence[data-status=away])":   "background: var(--zui-presence-away, #f9a825);",
+	":where(.zui-avatar-presence[data-status=busy])":   "background: var(--zui-presence-busy, #c62828);",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.Update()
+}
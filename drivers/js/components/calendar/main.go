@@ -0,0 +1,615 @@
+// package calendar provides a calendar component with month, week and day views.
+package calendar
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// A calendar displays a list of events in a month, week or day view.
+// Events are ui.Objects with "id", "title", "start" and "end" fields, the dates being RFC 3339 strings.
+// Any other field is kept as metadata.
+// Dates are rendered in the location of the calendar (time.Local by default). Note that in wasm, loading
+// other locations requires the time/tzdata package to be imported.
+//
+// Events can be dragged onto another day (month view) or time slot (week and day views) to move them,
+// keeping their duration. In week and day views, dragging the handle at the bottom of an event resizes it.
+// Moves and resizes update the "events" data property and trigger an event, so that the app can persist
+// the change.
+//
+// With WithWeeks, the month view becomes a continuous list of weeks whose rows are virtualized: only the
+// rows within the scrolled viewport (plus an overscan) are rendered.
+//
+// Data properties of the calendar element:
+//   - "events" (ui.List): the events
+//   - "view" (ui.String): "month", "week" or "day"
+//   - "date" (ui.String): the displayed date (YYYY-MM-DD)
+//
+// Events triggered on the calendar element:
+//   - "calendar-event-move", "calendar-event-resize": the event value is an Object with the "id", "start" and
+//     "end" of the modified event.
+//   - "calendar-event-select": an event was clicked. The event value is the event.
+//   - "calendar-date-select": a day or time slot was clicked. The event value is the RFC 3339 date.
+
+// Views
+const (
+	Month = "month"
+	Week  = "week"
+	Day   = "day"
+)
+
+// Event is the Go representation of a calendar event.
+type Event struct {
+	ID    string
+	Title string
+	Start time.Time
+	End   time.Time
+	Meta  ui.Object
+}
+
+// Value returns the ui.Object representation of the event.
+func (ev Event) Value() ui.Object {
+	o := ui.NewObject()
+	if ev.Meta.UnsafelyUnwrap() != nil {
+		o = ev.Meta.MakeCopy()
+	}
+	return o.Set("id", ui.String(ev.ID)).
+		Set("title", ui.String(ev.Title)).
+		Set("start", ui.String(ev.Start.Format(time.RFC3339))).
+		Set("end", ui.String(ev.End.Format(time.RFC3339))).
+		Commit()
+}
+
+// EventFrom returns the event described by a ui.Object.
+func EventFrom(o ui.Object) (Event, bool) {
+	var ev Event
+	id, ok := o.Get("id")
+	if !ok {
+		return ev, false
+	}
+	ev.ID = string(id.(ui.String))
+	if t, ok := o.Get("title"); ok {
+		ev.Title = string(t.(ui.String))
+	}
+	s, ok := o.Get("start")
+	if !ok {
+		return ev, false
+	}
+	start, err := time.Parse(time.RFC3339, string(s.(ui.String)))
+	if err != nil {
+		return ev, false
+	}
+	ev.Start, ev.End = start, start.Add(time.Hour)
+	if e, ok := o.Get("end"); ok {
+		if end, err := time.Parse(time.RFC3339, string(e.(ui.String))); err == nil && end.After(start) {
+			ev.End = end
+		}
+	}
+	ev.Meta = o
+	return ev, true
+}
+
+type CalendarElement struct {
+	*ui.Element
+}
+
+type config struct {
+	loc       *time.Location
+	firstDay  time.Weekday
+	fromHour  int
+	toHour    int
+	slot      time.Duration
+	weeks     int
+	rowHeight int
+	overscan  int
+}
+
+// Option allows to configure a calendar.
+type Option func(*config)
+
+// WithLocation sets the location in which dates are rendered.
+func WithLocation(loc *time.Location) Option {
+	return func(c *config) { c.loc = loc }
+}
+
+// WithFirstDayOfWeek sets the first day of the week (Monday by default).
+func WithFirstDayOfWeek(d time.Weekday) Option {
+	return func(c *config) { c.firstDay = d }
+}
+
+// WithHours sets the range of hours displayed by the week and day views, and the duration of a time slot.
+func WithHours(from, to int, slot time.Duration) Option {
+	return func(c *config) { c.fromHour, c.toHour, c.slot = from, to, slot }
+}
+
+// WithWeeks turns the month view into a continuous, virtualized, list of n weeks starting at the displayed
+// date. rowHeight is the fixed height of a week row, in pixels.
+func WithWeeks(n int, rowHeight int) Option {
+	return func(c *config) { c.weeks, c.rowHeight = n, rowHeight }
+}
+
+// calendars holds the configuration of each calendar, indexed by calendar id.
+var calendars = make(map[string]*calendarState)
+
+type calendarState struct {
+	cfg  *config
+	drag struct {
+		id     string
+		resize bool
+	}
+	firstRow int
+}
+
+// New returns a calendar displaying the given events.
+func New(d *Document, id string, events ui.List, options ...Option) CalendarElement {
+	cfg := &config{loc: time.Local, firstDay: time.Monday, fromHour: 0, toHour: 24, slot: time.Hour, rowHeight: 120, overscan: 2}
+	for _, opt := range options {
+		opt(cfg)
+	}
+	if cfg.slot <= 0 {
+		cfg.slot = time.Hour
+	}
+
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/calendar")
+	AddClass(e, "zui-calendar")
+	c := CalendarElement{e}
+	state := &calendarState{cfg: cfg}
+	calendars[id] = state
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		delete(calendars, id)
+		return false
+	}).RunOnce())
+
+	toolbar := d.Div.WithID(id + "-toolbar")
+	AddClass(toolbar.AsElement(), "zui-calendar-toolbar")
+	prev := d.Button.WithID(id+"-prev", "button").SetText("‹")
+	SetAttribute(prev.AsElement(), "aria-label", "Previous")
+	today := d.Button.WithID(id+"-today", "button").SetText("Today")
+	next := d.Button.WithID(id+"-next", "button").SetText("›")
+	SetAttribute(next.AsElement(), "aria-label", "Next")
+	title := d.Span.WithID(id + "-title")
+	AddClass(title.AsElement(), "zui-calendar-title")
+	SetAttribute(title.AsElement(), "aria-live", "polite")
+	tools := []*ui.Element{prev.AsElement(), today.AsElement(), next.AsElement(), title.AsElement()}
+	for _, v := range []string{Month, Week, Day} {
+		v := v
+		b := d.Button.WithID(id+"-view-"+v, "button").SetText(v)
+		AddClass(b.AsElement(), "zui-calendar-view")
+		b.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+			c.SetView(v)
+			return false
+		}))
+		tools = append(tools, b.AsElement())
+	}
+	toolbar.AsElement().SetChildren(tools...)
+
+	body := d.Div.WithID(id + "-body")
+	AddClass(body.AsElement(), "zui-calendar-body")
+	e.SetChildren(toolbar.AsElement(), body.AsElement())
+
+	prev.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		c.shift(-1)
+		return false
+	}))
+	next.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		c.shift(1)
+		return false
+	}))
+	today.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		c.SetDate(time.Now())
+		return false
+	}))
+
+	if cfg.weeks > 0 {
+		SetAttribute(body.AsElement(), "style", "overflow-y: auto; height: "+strconv.Itoa(6*cfg.rowHeight)+"px;")
+		body.AsElement().AddEventListener("scroll", ui.NewEventHandler(func(evt ui.Event) bool {
+			if c.View() != Month {
+				return false
+			}
+			n, ok := JSValue(body.AsElement())
+			if !ok {
+				return false
+			}
+			first := int(n.Get("scrollTop").Float())/cfg.rowHeight - cfg.overscan
+			if first < 0 {
+				first = 0
+			}
+			if first != state.firstRow {
+				state.firstRow = first
+				c.render()
+			}
+			return false
+		}).AsPassive())
+	}
+
+	h := ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		c.render()
+		return false
+	})
+	e.Watch(Namespace.Data, "events", e, h)
+	e.Watch(Namespace.Data, "view", e, h)
+	e.Watch(Namespace.Data, "date", e, h)
+
+	e.SetData("view", ui.String(Month))
+	e.SetData("date", ui.String(time.Now().In(cfg.loc).Format(time.DateOnly)))
+	e.SetData("events", events)
+	return c
+}
+
+// View returns the current view.
+func (c CalendarElement) View() string {
+	v, ok := c.AsElement().GetData("view")
+	if !ok {
+		return Month
+	}
+	return string(v.(ui.String))
+}
+
+// SetView changes the view of the calendar.
+func (c CalendarElement) SetView(view string) CalendarElement {
+	c.AsElement().SetData("view", ui.String(view))
+	return c
+}
+
+// Date returns the displayed date.
+func (c CalendarElement) Date() time.Time {
+	cfg := c.state().cfg
+	v, ok := c.AsElement().GetData("date")
+	if ok {
+		if t, err := time.ParseInLocation(time.DateOnly, string(v.(ui.String)), cfg.loc); err == nil {
+			return t
+		}
+	}
+	now := time.Now().In(cfg.loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cfg.loc)
+}
+
+// SetDate changes the displayed date.
+func (c CalendarElement) SetDate(t time.Time) CalendarElement {
+	c.state().firstRow = 0
+	c.AsElement().SetData("date", ui.String(t.In(c.state().cfg.loc).Format(time.DateOnly)))
+	return c
+}
+
+// Events returns the events of the calendar.
+func (c CalendarElement) Events() []Event {
+	v, ok := c.AsElement().GetData("events")
+	if !ok {
+		return nil
+	}
+	l, ok := v.(ui.List)
+	if !ok {
+		return nil
+	}
+	res := make([]Event, 0, len(l.UnsafelyUnwrap()))
+	for _, item := range l.UnsafelyUnwrap() {
+		o, ok := item.(ui.Object)
+		if !ok {
+			continue
+		}
+		if ev, ok := EventFrom(o); ok {
+			res = append(res, ev)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Start.Before(res[j].Start) })
+	return res
+}
+
+// SetEvents replaces the events of the calendar.
+func (c CalendarElement) SetEvents(events ui.List) CalendarElement {
+	c.AsElement().SetData("events", events)
+	return c
+}
+
+func (c CalendarElement) state() *calendarState {
+	return calendars[c.AsElement().ID]
+}
+
+func (c CalendarElement) shift(n int) {
+	d := c.Date()
+	switch c.View() {
+	case Month:
+		c.SetDate(d.AddDate(0, n, 0))
+	case Week:
+		c.SetDate(d.AddDate(0, 0, 7*n))
+	default:
+		c.SetDate(d.AddDate(0, 0, n))
+	}
+}
+
+func (c CalendarElement) startOfWeek(t time.Time) time.Time {
+	cfg := c.state().cfg
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, cfg.loc)
+	offset := (int(t.Weekday()) - int(cfg.firstDay) + 7) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+// update applies a modification to the event with the given id and triggers the corresponding event.
+func (c CalendarElement) update(evtname string, eventID string, fn func(ev *Event)) {
+	v, ok := c.AsElement().GetData("events")
+	if !ok {
+		return
+	}
+	l := ui.NewList()
+	var changed ui.Object
+	for _, item := range v.(ui.List).UnsafelyUnwrap() {
+		if o, ok := item.(ui.Object); ok {
+			if ev, ok := EventFrom(o); ok && ev.ID == eventID {
+				fn(&ev)
+				changed = ev.Value()
+				l.Append(changed)
+				continue
+			}
+		}
+		l.Append(item)
+	}
+	if changed.UnsafelyUnwrap() == nil {
+		return
+	}
+	c.AsElement().SetData("events", l.Commit())
+	c.AsElement().TriggerEvent(evtname, ui.NewObject().
+		Set("id", ui.String(eventID)).
+		Set("start", changed.MustGetString("start")).
+		Set("end", changed.MustGetString("end")).
+		Commit())
+}
+
+func (c CalendarElement) render() {
+	e := c.AsElement()
+	d := GetDocument(e)
+	st := c.state()
+	if st == nil {
+		return
+	}
+	body := d.GetElementById(e.ID + "-body")
+	title := d.GetElementById(e.ID + "-title")
+	if body == nil || title == nil {
+		return
+	}
+	for _, v := range []string{Month, Week, Day} {
+		if b := d.GetElementById(e.ID + "-view-" + v); b != nil {
+			SetAttribute(b, "aria-pressed", strconv.FormatBool(v == c.View()))
+		}
+	}
+
+	date := c.Date()
+	events := c.Events()
+	body.DeleteChildren()
+	SetAttribute(e, "data-view", c.View())
+
+	switch c.View() {
+	case Week:
+		start := c.startOfWeek(date)
+		SpanElement{Element: title}.SetText(start.Format("Jan 2") + " – " + start.AddDate(0, 0, 6).Format("Jan 2, 2006"))
+		body.SetChildren(c.timeGrid(start, 7, events))
+	case Day:
+		SpanElement{Element: title}.SetText(date.Format("Monday, Jan 2, 2006"))
+		body.SetChildren(c.timeGrid(date, 1, events))
+	default:
+		SpanElement{Element: title}.SetText(date.Format("January 2006"))
+		body.SetChildren(c.monthGrid(date, events)...)
+	}
+}
+
+func overlaps(ev Event, from, to time.Time) bool {
+	return ev.Start.Before(to) && ev.End.After(from)
+}
+
+func (c CalendarElement) monthGrid(date time.Time, events []Event) []*ui.Element {
+	d := GetDocument(c.AsElement())
+	st := c.state()
+	id := c.AsElement().ID
+	first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, st.cfg.loc)
+	start := c.startOfWeek(first)
+
+	total := st.cfg.weeks
+	from, to := 0, total
+	if total == 0 {
+		last := first.AddDate(0, 1, -1)
+		total = int(c.startOfWeek(last).Sub(start).Hours()/24)/7 + 1
+		from, to = 0, total
+	} else {
+		start = c.startOfWeek(date)
+		from = st.firstRow
+		to = from + 2*st.cfg.overscan + 8
+		if to > total {
+			to = total
+		}
+	}
+
+	rows := make([]*ui.Element, 0, to-from+2)
+	if st.cfg.weeks > 0 {
+		rows = append(rows, spacer(d, id+"-spacer-top", from*st.cfg.rowHeight))
+	}
+	for w := from; w < to; w++ {
+		row := d.Div.WithID(id + "-week-" + strconv.Itoa(w))
+		AddClass(row.AsElement(), "zui-calendar-week")
+		SetAttribute(row.AsElement(), "role", "row")
+		if st.cfg.weeks > 0 {
+			SetAttribute(row.AsElement(), "style", "height: "+strconv.Itoa(st.cfg.rowHeight)+"px;")
+		}
+		cells := make([]*ui.Element, 0, 7)
+		for i := 0; i < 7; i++ {
+			day := start.AddDate(0, 0, 7*w+i)
+			cellid := id + "-day-" + day.Format("20060102")
+			cell := d.Div.WithID(cellid)
+			AddClass(cell.AsElement(), "zui-calendar-day")
+			SetAttribute(cell.AsElement(), "role", "gridcell")
+			if day.Month() != date.Month() && st.cfg.weeks == 0 {
+				AddClass(cell.AsElement(), "zui-calendar-outside")
+			}
+			if sameDay(day, time.Now().In(st.cfg.loc)) {
+				AddClass(cell.AsElement(), "zui-calendar-today")
+			}
+			children := []*ui.Element{d.Span.WithID(cellid + "-label").SetText(strconv.Itoa(day.Day())).AsElement()}
+			for _, ev := range events {
+				if overlaps(ev, day, day.AddDate(0, 0, 1)) {
+					children = append(children, c.chip(cellid, ev, false))
+				}
+			}
+			cell.AsElement().SetChildren(children...)
+			c.dropTarget(cell.AsElement(), day, 24*time.Hour)
+			cells = append(cells, cell.AsElement())
+		}
+		row.AsElement().SetChildren(cells...)
+		rows = append(rows, row.AsElement())
+	}
+	if st.cfg.weeks > 0 {
+		rows = append(rows, spacer(d, id+"-spacer-bottom", (total-to)*st.cfg.rowHeight))
+	}
+	return rows
+}
+
+func (c CalendarElement) timeGrid(start time.Time, days int, events []Event) *ui.Element {
+	d := GetDocument(c.AsElement())
+	cfg := c.state().cfg
+	id := c.AsElement().ID
+	grid := d.Div.WithID(id + "-grid")
+	AddClass(grid.AsElement(), "zui-calendar-timegrid")
+	SetAttribute(grid.AsElement(), "style", "display: grid; grid-template-columns: repeat("+strconv.Itoa(days)+", minmax(0, 1fr));")
+
+	columns := make([]*ui.Element, 0, days)
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i)
+		colid := id + "-col-" + day.Format("20060102")
+		col := d.Div.WithID(colid)
+		AddClass(col.AsElement(), "zui-calendar-column")
+		slots := []*ui.Element{d.Span.WithID(colid + "-label").SetText(day.Format("Mon 2")).AsElement()}
+		from := time.Date(day.Year(), day.Month(), day.Day(), cfg.fromHour, 0, 0, 0, cfg.loc)
+		to := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, cfg.loc).Add(time.Duration(cfg.toHour) * time.Hour)
+		for t := from; t.Before(to); t = t.Add(cfg.slot) {
+			slotid := colid + "-" + t.Format("1504")
+			slot := d.Div.WithID(slotid)
+			AddClass(slot.AsElement(), "zui-calendar-slot")
+			SetAttribute(slot.AsElement(), "data-time", t.Format(time.RFC3339))
+			children := []*ui.Element{d.Span.WithID(slotid + "-label").SetText(t.Format("15:04")).AsElement()}
+			for _, ev := range events {
+				// an event is displayed in the slot in which it starts, or in the first slot of the day
+				// if it started before.
+				if (!ev.Start.Before(t) && ev.Start.Before(t.Add(cfg.slot))) || (t.Equal(from) && ev.Start.Before(from) && ev.End.After(from)) {
+					chip := c.chip(slotid, ev, true)
+					slots := ev.End.Sub(ev.Start) / cfg.slot
+					if slots < 1 {
+						slots = 1
+					}
+					SetAttribute(chip, "style", "--zui-calendar-span: "+strconv.Itoa(int(slots))+";")
+					children = append(children, chip)
+				}
+			}
+			slot.AsElement().SetChildren(children...)
+			c.dropTarget(slot.AsElement(), t, cfg.slot)
+			slots = append(slots, slot.AsElement())
+		}
+		col.AsElement().SetChildren(slots...)
+		columns = append(columns, col.AsElement())
+	}
+	grid.AsElement().SetChildren(columns...)
+	return grid.AsElement()
+}
+
+func spacer(d *Document, id string, height int) *ui.Element {
+	s := d.Div.WithID(id)
+	SetAttribute(s.AsElement(), "aria-hidden", "true")
+	SetAttribute(s.AsElement(), "style", "height: "+strconv.Itoa(height)+"px;")
+	return s.AsElement()
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// chip returns the draggable element representing an event within a cell.
+func (c CalendarElement) chip(cellid string, ev Event, resizable bool) *ui.Element {
+	d := GetDocument(c.AsElement())
+	cfg := c.state().cfg
+	chipid := cellid + "-event-" + ev.ID
+	chip := d.Div.WithID(chipid)
+	AddClass(chip.AsElement(), "zui-calendar-event")
+	SetAttribute(chip.AsElement(), "draggable", "true")
+	SetAttribute(chip.AsElement(), "tabindex", "0")
+	SetAttribute(chip.AsElement(), "data-event", ev.ID)
+	label := ev.Start.In(cfg.loc).Format("15:04") + " " + ev.Title
+	children := []*ui.Element{d.Span.WithID(chipid + "-title").SetText(label).AsElement()}
+
+	chip.AsElement().AddEventListener("dragstart", ui.NewEventHandler(func(evt ui.Event) bool {
+		st := c.state()
+		if st.drag.id == "" {
+			st.drag.id, st.drag.resize = ev.ID, false
+		}
+		return false
+	}))
+	chip.AsElement().AddEventListener("dragend", ui.NewEventHandler(func(evt ui.Event) bool {
+		c.state().drag.id = ""
+		return false
+	}))
+	chip.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		c.AsElement().TriggerEvent("calendar-event-select", ev.Value())
+		return false
+	}))
+
+	if resizable {
+		handle := d.Div.WithID(chipid + "-resize")
+		AddClass(handle.AsElement(), "zui-calendar-resize")
+		SetAttribute(handle.AsElement(), "draggable", "true")
+		SetAttribute(handle.AsElement(), "aria-hidden", "true")
+		handle.AsElement().AddEventListener("dragstart", ui.NewEventHandler(func(evt ui.Event) bool {
+			st := c.state()
+			st.drag.id, st.drag.resize = ev.ID, true
+			return false
+		}))
+		children = append(children, handle.AsElement())
+	}
+	chip.AsElement().SetChildren(children...)
+	return chip.AsElement()
+}
+
+// dropTarget makes a day cell or time slot accept dragged events and report clicks.
+func (c CalendarElement) dropTarget(target *ui.Element, at time.Time, span time.Duration) {
+	target.AddEventListener("dragover", ui.NewEventHandler(func(evt ui.Event) bool {
+		if c.state().drag.id != "" {
+			evt.PreventDefault() // allows the drop
+		}
+		return false
+	}))
+	target.AddEventListener("drop", ui.NewEventHandler(func(evt ui.Event) bool {
+		st := c.state()
+		id, resize := st.drag.id, st.drag.resize
+		st.drag.id = ""
+		if id == "" {
+			return false
+		}
+		evt.PreventDefault()
+		if resize {
+			c.update("calendar-event-resize", id, func(ev *Event) {
+				if end := at.Add(span); end.After(ev.Start) {
+					ev.End = end
+				}
+			})
+			return false
+		}
+		c.update("calendar-event-move", id, func(ev *Event) {
+			duration := ev.End.Sub(ev.Start)
+			start := at
+			if span >= 24*time.Hour {
+				// moving to another day keeps the time of day.
+				local := ev.Start.In(at.Location())
+				start = time.Date(at.Year(), at.Month(), at.Day(), local.Hour(), local.Minute(), local.Second(), 0, at.Location())
+			}
+			ev.Start, ev.End = start, start.Add(duration)
+		})
+		return false
+	}))
+	target.AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		if evt.Target() != target {
+			return false
+		}
+		c.AsElement().TriggerEvent("calendar-date-select", ui.String(at.Format(time.RFC3339)))
+		return false
+	}))
+}
@@ -0,0 +1,378 @@
+// package chips provides a chip (tag) input with autocompletion.
+package chips
+
+import (
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// A chip input is a text input turning its entries into chips. An entry is added with Enter or a comma.
+// While typing, the suggestions starting with the text of the input are listed below it and can be selected
+// with the arrow keys. Backspace in an empty input removes the last chip; each chip also has a remove button.
+// Duplicate entries are ignored. Chips can be themed with the --zui-chip-background CSS variable.
+//
+// Data properties of the chip input element:
+//   - "values" (ui.List): the chips, as ui.String
+//
+// Events triggered on the chip input element:
+//   - "chips-change": the chips were changed by the user. The event value is the list of chips.
+
+// StyleSheetID is the id of the stylesheet holding the chip input rules.
+const StyleSheetID = "zui-chips"
+
+type ChipsElement struct {
+	*ui.Element
+}
+
+type config struct {
+	suggestions []string
+	strict      bool
+	max         int
+}
+
+// Option allows to configure a chip input.
+type Option func(*config)
+
+// WithSuggestions sets the autocompletion suggestions.
+func WithSuggestions(s ...string) Option {
+	return func(c *config) { c.suggestions = s }
+}
+
+// WithStrict only accepts entries which are suggestions.
+func WithStrict() Option {
+	return func(c *config) { c.strict = true }
+}
+
+// WithMax limits the number of chips.
+func WithMax(n int) Option {
+	return func(c *config) { c.max = n }
+}
+
+// configs holds the configuration of each chip input, indexed by id.
+var configs = make(map[string]*config)
+
+// New returns a chip input.
+func New(d *Document, id string, label string, options ...Option) ChipsElement {
+	cfg := &config{}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/chips")
+	AddClass(e, "zui-chips")
+	c := ChipsElement{e}
+	configs[id] = cfg
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		delete(configs, id)
+		return false
+	}).RunOnce())
+
+	list := d.Ul.WithID(id + "-list").AsElement()
+	AddClass(list, "zui-chips-list")
+	SetAttribute(list, "aria-label", label)
+
+	input := d.Input.WithID(id+"-input", "text")
+	AddClass(input.AsElement(), "zui-chips-input")
+	input.SetAttribute("aria-label", label).
+		SetAttribute("role", "combobox").
+		SetAttribute("aria-autocomplete", "list").
+		SetAttribute("aria-controls", id+"-suggestions").
+		SetAttribute("aria-expanded", "false")
+
+	suggestions := d.Ul.WithID(id + "-suggestions").AsElement()
+	AddClass(suggestions, "zui-chips-suggestions")
+	SetAttribute(suggestions, "role", "listbox")
+	SetAttribute(suggestions, "hidden", "")
+
+	e.SetChildren(list, input.AsElement(), suggestions)
+	style(d)
+
+	e.Watch(Namespace.Data, "values", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		values := c.Values()
+		items := make([]*ui.Element, 0, len(values))
+		for i, v := range values {
+			cid := id + "-chip-" + strconv.Itoa(i)
+			li := d.Li.WithID(cid).AsElement()
+			AddClass(li, "zui-chip")
+			text := d.Span.WithID(cid + "-text").SetText(v).AsElement()
+			remove := d.Button.WithID(cid+"-remove", "button").SetText("×").AsElement()
+			AddClass(remove, "zui-chip-remove")
+			SetAttribute(remove, "aria-label", "Remove "+v)
+			value := v
+			remove.AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+				c.Remove(value)
+				c.changed()
+				SetFocus(input.AsElement(), false)
+				return false
+			}))
+			li.SetChildren(text, remove)
+			items = append(items, li)
+		}
+		list.DeleteChildren()
+		list.SetChildren(items...)
+		return false
+	}))
+
+	e.Watch(Namespace.UI, "selected", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		idx := int(evt.NewValue().(ui.Number))
+		RemoveAttribute(input.AsElement(), "aria-activedescendant")
+		for i, li := range suggestions.Children.List {
+			if i == idx {
+				SetAttribute(li, "aria-selected", "true")
+				SetAttribute(input.AsElement(), "aria-activedescendant", li.ID)
+				continue
+			}
+			SetAttribute(li, "aria-selected", "false")
+		}
+		return false
+	}))
+
+	var matches []string
+	var refresh func(query string)
+	refresh = func(query string) {
+		matches = c.matches(query)
+		suggestions.DeleteChildren()
+		if len(matches) == 0 {
+			SetAttribute(suggestions, "hidden", "")
+			input.SetAttribute("aria-expanded", "false")
+			e.SetUI("selected", ui.Number(-1))
+			return
+		}
+		items := make([]*ui.Element, 0, len(matches))
+		for i, m := range matches {
+			li := d.Li.WithID(id + "-suggestion-" + strconv.Itoa(i)).AsElement()
+			SetAttribute(li, "role", "option")
+			li.SetChildren(d.Span.WithID(li.ID + "-text").SetText(m).AsElement())
+			value := m
+			li.AddEventListener("pointerdown", ui.NewEventHandler(func(evt ui.Event) bool {
+				// the input keeps the focus
+				evt.PreventDefault()
+				if c.Add(value) {
+					c.changed()
+				}
+				input.AsElement().SetDataSetUI("value", ui.String(""))
+				refresh("")
+				return false
+			}))
+			items = append(items, li)
+		}
+		suggestions.SetChildren(items...)
+		RemoveAttribute(suggestions, "hidden")
+		input.SetAttribute("aria-expanded", "true")
+		e.SetUI("selected", ui.Number(-1))
+	}
+
+	input.AsElement().AddEventListener("input", ui.NewEventHandler(func(evt ui.Event) bool {
+		v, ok := evt.Value().(ui.Object).Get("value")
+		if !ok {
+			return false
+		}
+		text := string(v.(ui.String))
+		if strings.HasSuffix(text, ",") {
+			if c.Add(strings.TrimSuffix(text, ",")) {
+				c.changed()
+			}
+			input.AsElement().SetDataSetUI("value", ui.String(""))
+			refresh("")
+			return false
+		}
+		refresh(text)
+		return false
+	}))
+
+	input.AsElement().AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		k, ok := evt.(KeyboardEvent)
+		if !ok {
+			return false
+		}
+		text := ""
+		if n, ok := JSValue(input.AsElement()); ok {
+			text = n.Get("value").String()
+		}
+		sel := -1
+		if v, ok := e.GetUI("selected"); ok {
+			sel = int(v.(ui.Number))
+		}
+		switch k.Key() {
+		case "ArrowDown":
+			if len(matches) > 0 {
+				evt.PreventDefault()
+				e.SetUI("selected", ui.Number((sel+1)%len(matches)))
+			}
+		case "ArrowUp":
+			if len(matches) > 0 {
+				evt.PreventDefault()
+				if sel <= 0 {
+					sel = len(matches)
+				}
+				e.SetUI("selected", ui.Number(sel-1))
+			}
+		case "Enter":
+			evt.PreventDefault()
+			if sel >= 0 && sel < len(matches) {
+				text = matches[sel]
+			}
+			if c.Add(text) {
+				c.changed()
+			}
+			input.AsElement().SetDataSetUI("value", ui.String(""))
+			refresh("")
+		case "Escape":
+			refresh("")
+		case "Backspace":
+			values := c.Values()
+			if text == "" && len(values) > 0 {
+				c.Remove(values[len(values)-1])
+				c.changed()
+			}
+		}
+		return false
+	}))
+
+	input.AsElement().AddEventListener("blur", ui.NewEventHandler(func(evt ui.Event) bool {
+		refresh("")
+		return false
+	}))
+
+	e.SetData("values", ui.NewList().Commit())
+	return c
+}
+
+func (c ChipsElement) config() *config {
+	if cfg, ok := configs[c.AsElement().ID]; ok {
+		return cfg
+	}
+	return &config{}
+}
+
+// matches returns the suggestions starting with the query which are not chips yet.
+func (c ChipsElement) matches(query string) []string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	var res []string
+	for _, s := range c.config().suggestions {
+		if strings.HasPrefix(strings.ToLower(s), query) && !c.Has(s) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// Values returns the chips.
+func (c ChipsElement) Values() []string {
+	v, ok := c.AsElement().GetData("values")
+	if !ok {
+		return nil
+	}
+	var res []string
+	for _, s := range v.(ui.List).UnsafelyUnwrap() {
+		res = append(res, string(s.(ui.String)))
+	}
+	return res
+}
+
+// SetValues replaces the chips.
+func (c ChipsElement) SetValues(values ...string) ChipsElement {
+	l := ui.NewList()
+	for _, v := range values {
+		l.Append(ui.String(v))
+	}
+	c.AsElement().SetData("values", l.Commit())
+	return c
+}
+
+// Has returns whether a chip exists.
+func (c ChipsElement) Has(value string) bool {
+	for _, v := range c.Values() {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Add adds a chip. It returns false if the chip was refused, because it is empty, a duplicate, not a
+// suggestion in strict mode, or because the maximum number of chips is reached.
+func (c ChipsElement) Add(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" || c.Has(value) {
+		return false
+	}
+	cfg := c.config()
+	values := c.Values()
+	if cfg.max > 0 && len(values) >= cfg.max {
+		return false
+	}
+	if cfg.strict {
+		found := false
+		for _, s := range cfg.suggestions {
+			if strings.EqualFold(s, value) {
+				value, found = s, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	c.SetValues(append(values, value)...)
+	return true
+}
+
+// Remove removes a chip.
+func (c ChipsElement) Remove(value string) ChipsElement {
+	values := c.Values()
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			res = append(res, v)
+		}
+	}
+	return c.SetValues(res...)
+}
+
+// OnChange registers a handler called when the chips are changed by the user.
+func (c ChipsElement) OnChange(h *ui.MutationHandler) ChipsElement {
+	c.AsElement().WatchEvent("chips-change", c, h)
+	return c
+}
+
+func (c ChipsElement) changed() {
+	v, ok := c.AsElement().GetData("values")
+	if !ok {
+		return
+	}
+	c.AsElement().TriggerEvent("chips-change", v)
+}
+
+var rules = map[string]string{
+	":where(.zui-chips)":                                  "position: relative; display: flex; flex-wrap: wrap; align-items: center; gap: 0.25rem; padding: 0.25rem; border: 1px solid #0004;",
+	":where(.zui-chips-list)":                             "display: contents; list-style: none;",
+	":where(.zui-chip)":                                   "display: inline-flex; align-items: center; gap: 0.25rem; padding: 0.125rem 0.5rem; border-radius: 1rem; background: var(--zui-chip-background, #0001);",
+	":where(.zui-chip-remove)":                            "padding: 0; border: none; background: none; cursor: pointer; font: inherit; line-height: 1;",
+	":where(.zui-chips-input)":                            "flex: 1; min-width: 6rem; border: none; outline: none; font: inherit;",
+	":where(.zui-chips-suggestions)":                      "position: absolute; top: 100%; left: 0; right: 0; z-index: 1; margin: 0; padding: 0; list-style: none; background: Canvas; border: 1px solid #0004;",
+	":where(.zui-chips-suggestions li)":                   "padding: 0.25rem 0.5rem; cursor: pointer;",
+	":where(.zui-chips-suggestions [aria-selected=true])": "background: Highlight; color: HighlightText;",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.Update()
+}
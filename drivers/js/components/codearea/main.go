@@ -31,6 +31,7 @@ type AreaElement struct {
 func Area(d *Document, id string) AreaElement {
 	addIfAbsent(d)
 	a := AreaElement{d.Div.WithID(id).AsElement()}
+	MarkComponent(a.AsElement(), "github.com/atdiar/particleui/drivers/js/components/codearea")
 	v, ok := JSValue(a)
 	if !ok {
 		panic("Element has no js value")
@@ -146,14 +147,10 @@ func (e AreaElement) On(event string, callback func()) AreaElement {
 		panic("Element has no js vlue")
 	}
 	ta := v.Get("textarea")
-	cb := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+	cb := RegisterCallback(e.AsElement(), func(this js.Value, args []js.Value) interface{} {
 		callback()
 		return nil
 	})
-	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
-		cb.Release()
-		return false
-	}).RunOnce().RunASAP())
 
 	ta.Call("on", event, cb)
 
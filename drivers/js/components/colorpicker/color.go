@@ -0,0 +1,186 @@
+package colorpicker
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// Color is a color in the HSV color space, with an alpha channel.
+// H is in [0,360), S, V and A in [0,1].
+type Color struct {
+	H, S, V, A float64
+}
+
+// ErrInvalidColor is returned when a color string cannot be parsed.
+var ErrInvalidColor = errors.New("invalid color")
+
+func clamp(x, min, max float64) float64 {
+	return math.Max(min, math.Min(max, x))
+}
+
+// RGB returns the red, green and blue components of the color.
+func (c Color) RGB() (r, g, b uint8) {
+	h := math.Mod(c.H, 360) / 60
+	chroma := c.V * c.S
+	x := chroma * (1 - math.Abs(math.Mod(h, 2)-1))
+	var rf, gf, bf float64
+	switch {
+	case h < 1:
+		rf, gf, bf = chroma, x, 0
+	case h < 2:
+		rf, gf, bf = x, chroma, 0
+	case h < 3:
+		rf, gf, bf = 0, chroma, x
+	case h < 4:
+		rf, gf, bf = 0, x, chroma
+	case h < 5:
+		rf, gf, bf = x, 0, chroma
+	default:
+		rf, gf, bf = chroma, 0, x
+	}
+	m := c.V - chroma
+	return uint8(math.Round((rf + m) * 255)), uint8(math.Round((gf + m) * 255)), uint8(math.Round((bf + m) * 255))
+}
+
+// FromRGB returns the color with the given red, green and blue components, and alpha.
+func FromRGB(r, g, b uint8, a float64) Color {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	max := math.Max(rf, math.Max(gf, bf))
+	min := math.Min(rf, math.Min(gf, bf))
+	d := max - min
+	c := Color{V: max, A: clamp(a, 0, 1)}
+	if max > 0 {
+		c.S = d / max
+	}
+	if d == 0 {
+		return c
+	}
+	switch max {
+	case rf:
+		c.H = 60 * math.Mod((gf-bf)/d, 6)
+	case gf:
+		c.H = 60 * ((bf-rf)/d + 2)
+	default:
+		c.H = 60 * ((rf-gf)/d + 4)
+	}
+	if c.H < 0 {
+		c.H += 360
+	}
+	return c
+}
+
+// HSL returns the hue, saturation and lightness of the color.
+func (c Color) HSL() (h, s, l float64) {
+	l = c.V * (1 - c.S/2)
+	if l > 0 && l < 1 {
+		s = (c.V - l) / math.Min(l, 1-l)
+	}
+	return c.H, s, l
+}
+
+func hex2(b uint8) string {
+	s := strconv.FormatUint(uint64(b), 16)
+	if len(s) == 1 {
+		return "0" + s
+	}
+	return s
+}
+
+// Hex returns the #rrggbb representation of the color, or #rrggbbaa if it is not opaque.
+func (c Color) Hex() string {
+	r, g, b := c.RGB()
+	s := "#" + hex2(r) + hex2(g) + hex2(b)
+	if c.A < 1 {
+		s += hex2(uint8(math.Round(c.A * 255)))
+	}
+	return s
+}
+
+func formatAlpha(a float64) string {
+	return strconv.FormatFloat(math.Round(a*100)/100, 'f', -1, 64)
+}
+
+// CSS returns the rgb() CSS representation of the color.
+func (c Color) CSS() string {
+	r, g, b := c.RGB()
+	s := "rgb(" + strconv.Itoa(int(r)) + " " + strconv.Itoa(int(g)) + " " + strconv.Itoa(int(b))
+	if c.A < 1 {
+		s += " / " + formatAlpha(c.A)
+	}
+	return s + ")"
+}
+
+// HSLString returns the hsl() CSS representation of the color.
+func (c Color) HSLString() string {
+	h, s, l := c.HSL()
+	str := "hsl(" + strconv.Itoa(int(math.Round(h))) + " " + strconv.Itoa(int(math.Round(s*100))) + "% " + strconv.Itoa(int(math.Round(l*100))) + "%"
+	if c.A < 1 {
+		str += " / " + formatAlpha(c.A)
+	}
+	return str + ")"
+}
+
+// ParseHex parses a #rgb, #rgba, #rrggbb or #rrggbbaa color.
+func ParseHex(s string) (Color, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 || len(s) == 4 {
+		var b strings.Builder
+		for _, r := range s {
+			b.WriteRune(r)
+			b.WriteRune(r)
+		}
+		s = b.String()
+	}
+	if len(s) != 6 && len(s) != 8 {
+		return Color{}, ErrInvalidColor
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return Color{}, ErrInvalidColor
+	}
+	a := 1.0
+	if len(s) == 8 {
+		a = float64(v&0xff) / 255
+		v >>= 8
+	}
+	return FromRGB(uint8(v>>16), uint8(v>>8), uint8(v), a), nil
+}
+
+// Value returns the representation of the color stored in the "color" data property of a picker.
+func (c Color) Value() ui.Object {
+	r, g, b := c.RGB()
+	return ui.NewObject().
+		Set("hex", ui.String(c.Hex())).
+		Set("rgb", ui.String(c.CSS())).
+		Set("hsl", ui.String(c.HSLString())).
+		Set("r", ui.Number(r)).
+		Set("g", ui.Number(g)).
+		Set("b", ui.Number(b)).
+		Set("h", ui.Number(c.H)).
+		Set("s", ui.Number(c.S)).
+		Set("v", ui.Number(c.V)).
+		Set("alpha", ui.Number(c.A)).
+		Commit()
+}
+
+// ColorFrom returns the color represented by an Object returned by Value.
+func ColorFrom(o ui.Object) Color {
+	c := Color{A: 1}
+	if v, ok := o.Get("h"); ok {
+		c.H = float64(v.(ui.Number))
+	}
+	if v, ok := o.Get("s"); ok {
+		c.S = float64(v.(ui.Number))
+	}
+	if v, ok := o.Get("v"); ok {
+		c.V = float64(v.(ui.Number))
+	}
+	if v, ok := o.Get("alpha"); ok {
+		c.A = float64(v.(ui.Number))
+	}
+	return c
+}
@@ -0,0 +1,402 @@
+// package colorpicker provides a color picker component.
+package colorpicker
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// A color picker is made of a saturation/brightness area, hue and alpha sliders, hex and RGB inputs, and
+// optionally a list of preset colors. Where the EyeDropper API is supported, a button allows to pick a
+// color from the screen.
+// The area can be used with a pointer or with the arrow keys once focused.
+//
+// Data properties of the picker element:
+//   - "color" (ui.Object): the current color, in several formats. See Color.Value.
+//
+// Events triggered on the picker element:
+//   - "colorpicker-change": the color was changed by the user. The event value is the color Object.
+
+// StyleSheetID is the id of the stylesheet holding the color picker rules.
+const StyleSheetID = "zui-colorpicker"
+
+type PickerElement struct {
+	*ui.Element
+}
+
+type config struct {
+	initial string
+	presets []string
+	alpha   bool
+}
+
+// Option allows to configure a color picker.
+type Option func(*config)
+
+// WithInitial sets the initial color of the picker, as a hex string.
+func WithInitial(hex string) Option {
+	return func(c *config) { c.initial = hex }
+}
+
+// WithPresets adds a palette of preset colors, as hex strings.
+func WithPresets(hex ...string) Option {
+	return func(c *config) { c.presets = append(c.presets, hex...) }
+}
+
+// WithoutAlpha hides the alpha slider. Colors are then always opaque.
+func WithoutAlpha() Option {
+	return func(c *config) { c.alpha = false }
+}
+
+// New returns a color picker.
+func New(d *Document, id string, options ...Option) PickerElement {
+	cfg := &config{initial: "#3366ff", alpha: true}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/colorpicker")
+	AddClass(e, "zui-colorpicker")
+	p := PickerElement{e}
+
+	area := d.Div.WithID(id + "-area").AsElement()
+	AddClass(area, "zui-colorpicker-area")
+	SetAttribute(area, "role", "slider")
+	SetAttribute(area, "tabindex", "0")
+	SetAttribute(area, "aria-label", "Saturation and brightness")
+	thumb := d.Div.WithID(id + "-thumb").AsElement()
+	AddClass(thumb, "zui-colorpicker-thumb")
+	area.SetChildren(thumb)
+
+	hue := d.Input.WithID(id+"-hue", "range")
+	AddClass(hue.AsElement(), "zui-colorpicker-hue")
+	hue.SetAttribute("min", "0").SetAttribute("max", "359").SetAttribute("aria-label", "Hue")
+
+	alpha := d.Input.WithID(id+"-alpha", "range")
+	AddClass(alpha.AsElement(), "zui-colorpicker-alpha")
+	alpha.SetAttribute("min", "0").SetAttribute("max", "100").SetAttribute("aria-label", "Alpha")
+
+	swatch := d.Div.WithID(id + "-swatch").AsElement()
+	AddClass(swatch, "zui-colorpicker-swatch")
+	SetAttribute(swatch, "aria-hidden", "true")
+
+	hex := d.Input.WithID(id+"-hex", "text")
+	AddClass(hex.AsElement(), "zui-colorpicker-hex")
+	hex.SetAttribute("aria-label", "Hex").SetAttribute("spellcheck", "false")
+
+	channels := make([]InputElement, 0, 3)
+	for _, name := range []string{"Red", "Green", "Blue"} {
+		in := d.Input.WithID(id+"-"+strings.ToLower(name), "number")
+		AddClass(in.AsElement(), "zui-colorpicker-channel")
+		in.SetAttribute("min", "0").SetAttribute("max", "255").SetAttribute("aria-label", name)
+		channels = append(channels, in)
+	}
+	fields := d.Div.WithID(id + "-fields").AsElement()
+	AddClass(fields, "zui-colorpicker-fields")
+	fields.SetChildren(hex.AsElement(), channels[0].AsElement(), channels[1].AsElement(), channels[2].AsElement())
+
+	children := []*ui.Element{area, hue.AsElement()}
+	if cfg.alpha {
+		children = append(children, alpha.AsElement())
+	}
+	children = append(children, swatch, fields)
+
+	if len(cfg.presets) > 0 {
+		presets := d.Div.WithID(id + "-presets").AsElement()
+		AddClass(presets, "zui-colorpicker-presets")
+		SetAttribute(presets, "role", "group")
+		SetAttribute(presets, "aria-label", "Presets")
+		buttons := make([]*ui.Element, 0, len(cfg.presets))
+		for i, preset := range cfg.presets {
+			c, err := ParseHex(preset)
+			if err != nil {
+				DEBUG("colorpicker: invalid preset color ", preset)
+				continue
+			}
+			b := d.Button.WithID(id+"-preset-"+strconv.Itoa(i), "button").AsElement()
+			AddClass(b, "zui-colorpicker-preset")
+			SetAttribute(b, "aria-label", c.Hex())
+			SetAttribute(b, "title", c.Hex())
+			SetCSSVariable(b, "zui-colorpicker-preset", c.CSS())
+			b.AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+				p.change(c)
+				return false
+			}))
+			buttons = append(buttons, b)
+		}
+		presets.SetChildren(buttons...)
+		children = append(children, presets)
+	}
+
+	if InBrowser() && js.Global().Get("EyeDropper").Truthy() {
+		eyedropper := d.Button.WithID(id+"-eyedropper", "button").SetText("Pick")
+		AddClass(eyedropper.AsElement(), "zui-colorpicker-eyedropper")
+		SetAttribute(eyedropper.AsElement(), "aria-label", "Pick a color from the screen")
+		eyedropper.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+			p.pickFromScreen()
+			return false
+		}))
+		children = append(children, eyedropper.AsElement())
+	}
+
+	e.SetChildren(children...)
+	style(d)
+
+	e.Watch(Namespace.Data, "color", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		c := ColorFrom(evt.NewValue().(ui.Object))
+		r, g, b := c.RGB()
+		SetCSSVariable(e, "zui-colorpicker-hue", strconv.Itoa(int(math.Round(c.H))))
+		SetCSSVariable(e, "zui-colorpicker-s", strconv.FormatFloat(c.S*100, 'f', 2, 64)+"%")
+		SetCSSVariable(e, "zui-colorpicker-v", strconv.FormatFloat((1-c.V)*100, 'f', 2, 64)+"%")
+		SetCSSVariable(e, "zui-colorpicker-color", c.CSS())
+		SetCSSVariable(e, "zui-colorpicker-opaque", Color{c.H, c.S, c.V, 1}.CSS())
+		SetAttribute(area, "aria-valuetext", "Saturation "+strconv.Itoa(int(math.Round(c.S*100)))+"%, brightness "+strconv.Itoa(int(math.Round(c.V*100)))+"%")
+
+		hue.AsElement().SetDataSetUI("value", ui.String(strconv.Itoa(int(math.Round(c.H)))))
+		alpha.AsElement().SetDataSetUI("value", ui.String(strconv.Itoa(int(math.Round(c.A*100)))))
+		hex.AsElement().SetDataSetUI("value", ui.String(c.Hex()))
+		for i, v := range []uint8{r, g, b} {
+			channels[i].AsElement().SetDataSetUI("value", ui.String(strconv.Itoa(int(v))))
+		}
+		return false
+	}))
+
+	// saturation/brightness area
+	var dragging bool
+	pick := func(evt ui.Event) {
+		m, ok := evt.(MouseEvent)
+		if !ok {
+			return
+		}
+		n, ok := JSValue(area)
+		if !ok {
+			return
+		}
+		rect := n.Call("getBoundingClientRect")
+		w, h := rect.Get("width").Float(), rect.Get("height").Float()
+		if w <= 0 || h <= 0 {
+			return
+		}
+		c := p.Color()
+		c.S = clamp((m.ClientX()-rect.Get("left").Float())/w, 0, 1)
+		c.V = 1 - clamp((m.ClientY()-rect.Get("top").Float())/h, 0, 1)
+		p.change(c)
+	}
+	area.AddEventListener("pointerdown", ui.NewEventHandler(func(evt ui.Event) bool {
+		m, ok := evt.(MouseEvent)
+		if !ok || m.Button() != 0 {
+			return false
+		}
+		dragging = true
+		if nevt, ok := evt.Native().(NativeEvent); ok {
+			if n, ok := JSValue(area); ok {
+				n.Call("setPointerCapture", nevt.Value.Get("pointerId"))
+			}
+		}
+		evt.PreventDefault()
+		SetFocus(area, false)
+		pick(evt)
+		return false
+	}))
+	area.AddEventListener("pointermove", ui.NewEventHandler(func(evt ui.Event) bool {
+		if dragging {
+			pick(evt)
+		}
+		return false
+	}))
+	stop := ui.NewEventHandler(func(evt ui.Event) bool {
+		dragging = false
+		return false
+	})
+	area.AddEventListener("pointerup", stop)
+	area.AddEventListener("pointercancel", stop)
+	area.AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		k, ok := evt.(KeyboardEvent)
+		if !ok {
+			return false
+		}
+		step := 0.01
+		if k.ShiftKey() {
+			step = 0.1
+		}
+		c := p.Color()
+		switch k.Key() {
+		case "ArrowLeft":
+			c.S = clamp(c.S-step, 0, 1)
+		case "ArrowRight":
+			c.S = clamp(c.S+step, 0, 1)
+		case "ArrowDown":
+			c.V = clamp(c.V-step, 0, 1)
+		case "ArrowUp":
+			c.V = clamp(c.V+step, 0, 1)
+		default:
+			return false
+		}
+		evt.PreventDefault()
+		p.change(c)
+		return false
+	}))
+
+	// sliders are applied while they move, text inputs once their value is committed.
+	hue.AsElement().AddEventListener("input", ui.NewEventHandler(func(evt ui.Event) bool {
+		if v, ok := numberValue(evt); ok {
+			c := p.Color()
+			c.H = clamp(v, 0, 359)
+			p.change(c)
+		}
+		return false
+	}))
+	alpha.AsElement().AddEventListener("input", ui.NewEventHandler(func(evt ui.Event) bool {
+		if v, ok := numberValue(evt); ok {
+			c := p.Color()
+			c.A = clamp(v/100, 0, 1)
+			p.change(c)
+		}
+		return false
+	}))
+	hex.AsElement().AddEventListener("change", ui.NewEventHandler(func(evt ui.Event) bool {
+		v, ok := evt.Value().(ui.Object).Get("value")
+		if !ok {
+			return false
+		}
+		c, err := ParseHex(string(v.(ui.String)))
+		if err != nil {
+			// the previous value is restored
+			hex.AsElement().SetDataSetUI("value", ui.String(p.Color().Hex()))
+			return false
+		}
+		if !cfg.alpha {
+			c.A = 1
+		}
+		p.change(c)
+		return false
+	}))
+	for i, in := range channels {
+		in.AsElement().AddEventListener("change", ui.NewEventHandler(func(evt ui.Event) bool {
+			v, ok := numberValue(evt)
+			if !ok {
+				return false
+			}
+			cur := p.Color()
+			r, g, b := cur.RGB()
+			rgb := []uint8{r, g, b}
+			rgb[i] = uint8(clamp(math.Round(v), 0, 255))
+			p.change(FromRGB(rgb[0], rgb[1], rgb[2], cur.A))
+			return false
+		}))
+	}
+
+	initial, err := ParseHex(cfg.initial)
+	if err != nil {
+		DEBUG("colorpicker: invalid initial color ", cfg.initial)
+		initial, _ = ParseHex("#3366ff")
+	}
+	if !cfg.alpha {
+		initial.A = 1
+	}
+	p.SetColor(initial)
+
+	return p
+}
+
+func numberValue(evt ui.Event) (float64, bool) {
+	o, ok := evt.Value().(ui.Object)
+	if !ok {
+		return 0, false
+	}
+	v, ok := o.Get("value")
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(string(v.(ui.String)), 64)
+	return f, err == nil
+}
+
+// Color returns the current color of the picker.
+func (p PickerElement) Color() Color {
+	v, ok := p.AsElement().GetData("color")
+	if !ok {
+		return Color{A: 1}
+	}
+	return ColorFrom(v.(ui.Object))
+}
+
+// SetColor changes the current color of the picker.
+func (p PickerElement) SetColor(c Color) PickerElement {
+	p.AsElement().SetData("color", c.Value())
+	return p
+}
+
+// OnChange registers a handler called when the color is changed by the user.
+func (p PickerElement) OnChange(h *ui.MutationHandler) PickerElement {
+	p.AsElement().WatchEvent("colorpicker-change", p, h)
+	return p
+}
+
+func (p PickerElement) change(c Color) {
+	p.SetColor(c)
+	p.AsElement().TriggerEvent("colorpicker-change", c.Value())
+}
+
+func (p PickerElement) pickFromScreen() {
+	var then, catch js.Func
+	release := func() {
+		then.Release()
+		catch.Release()
+	}
+	then = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		s := args[0].Get("sRGBHex").String()
+		go ui.DoSync(func() {
+			c, err := ParseHex(s)
+			if err != nil {
+				DEBUG("colorpicker: unexpected eyedropper color ", s)
+				return
+			}
+			c.A = p.Color().A
+			p.change(c)
+		})
+		release()
+		return nil
+	})
+	// the user may cancel the selection, which rejects the promise.
+	catch = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		release()
+		return nil
+	})
+	js.Global().Get("EyeDropper").New().Call("open").Call("then", then).Call("catch", catch)
+}
+
+var rules = map[string]string{
+	":where(.zui-colorpicker)":                    "display: grid; gap: 0.5rem; width: 16rem;",
+	":where(.zui-colorpicker-area)":               "position: relative; height: 10rem; touch-action: none; cursor: crosshair; background: linear-gradient(to top, #000, transparent), linear-gradient(to right, #fff, hsl(var(--zui-colorpicker-hue, 0) 100% 50%));",
+	":where(.zui-colorpicker-thumb)":              "position: absolute; left: var(--zui-colorpicker-s, 0); top: var(--zui-colorpicker-v, 0); width: 0.75rem; height: 0.75rem; border: 2px solid #fff; border-radius: 50%; box-shadow: 0 0 0 1px #0006; transform: translate(-50%, -50%); pointer-events: none;",
+	":where(.zui-colorpicker-hue)":                "width: 100%; background: linear-gradient(to right, red, yellow, lime, cyan, blue, magenta, red);",
+	":where(.zui-colorpicker-alpha)":              "width: 100%; background: linear-gradient(to right, transparent, var(--zui-colorpicker-opaque));",
+	":where(.zui-colorpicker-swatch)":             "height: 2rem; background: linear-gradient(var(--zui-colorpicker-color), var(--zui-colorpicker-color)), repeating-conic-gradient(#ccc 0 25%, #fff 0 50%) 0 0 / 1rem 1rem;",
+	":where(.zui-colorpicker-fields)":             "display: grid; grid-template-columns: 2fr 1fr 1fr 1fr; gap: 0.25rem;",
+	":where(.zui-colorpicker-fields input)":       "min-width: 0;",
+	":where(.zui-colorpicker-presets)":            "display: flex; flex-wrap: wrap; gap: 0.25rem;",
+	":where(.zui-colorpicker-preset)":             "width: 1.5rem; height: 1.5rem; padding: 0; border: 1px solid #0003; background: var(--zui-colorpicker-preset);",
+	":where(.zui-colorpicker-area:focus-visible)": "outline: 2px solid Highlight; outline-offset: 2px;",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.Update()
+}
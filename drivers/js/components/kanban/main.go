@@ -0,0 +1,427 @@
+// package kanban provides a kanban board component whose cards can be moved across columns.
+package kanban
+
+import (
+	"context"
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// A board is rendered from its "columns" data property, a ui.List of column Objects:
+//   - "id" (ui.String), "title" (ui.String)
+//   - "limit" (ui.Number, optional): the WIP limit of the column, i.e. its maximum number of cards
+//   - "cards" (ui.List): the cards of the column, Objects with at least an "id" and a "title"
+//
+// Cards can be dragged onto another card (they are inserted before it) or onto a column (they are appended).
+// With the keyboard, a focused card is moved with Alt+ArrowUp/ArrowDown within its column and with
+// Alt+ArrowLeft/ArrowRight to the adjacent columns. Moves are announced in a live region.
+// A move to a column that has reached its WIP limit is refused.
+//
+// Moves are applied optimistically. If a persistence function is registered with OnPersist, it is called
+// asynchronously with the move; should it fail, the board is reverted to its previous state.
+//
+// Events triggered on the board element:
+//   - "kanban-move": a card was moved. The event value is an Object with the "card" id, the "from" and "to"
+//     column ids and the "index" of the card in the target column.
+//   - "kanban-limit": a move was refused because of the WIP limit. The event value is the column id.
+//   - "kanban-move-failed": the persistence of a move failed and was reverted. The event value is the
+//     error message.
+
+// Move describes the move of a card.
+type Move struct {
+	Card  string
+	From  string
+	To    string
+	Index int
+}
+
+func (m Move) value() ui.Object {
+	return ui.NewObject().
+		Set("card", ui.String(m.Card)).
+		Set("from", ui.String(m.From)).
+		Set("to", ui.String(m.To)).
+		Set("index", ui.Number(m.Index)).
+		Commit()
+}
+
+type BoardElement struct {
+	*ui.Element
+}
+
+type boardState struct {
+	dragged string
+	persist func(context.Context, Move) error
+}
+
+// boards holds the state of each board, indexed by board id.
+var boards = make(map[string]*boardState)
+
+// Board returns a kanban board rendering the given columns.
+func Board(d *Document, id string, columns ui.List) BoardElement {
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/kanban")
+	AddClass(e, "zui-kanban")
+	b := BoardElement{e}
+	boards[id] = &boardState{}
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		delete(boards, id)
+		return false
+	}).RunOnce())
+
+	lanes := d.Div.WithID(id + "-columns")
+	AddClass(lanes.AsElement(), "zui-kanban-columns")
+	status := d.Div.WithID(id + "-status")
+	AddClass(status.AsElement(), "zui-kanban-status")
+	SetAttribute(status.AsElement(), "role", "status")
+	SetAttribute(status.AsElement(), "aria-live", "polite")
+	message := d.Span.WithID(id + "-status-message")
+	status.AsElement().SetChildren(message.AsElement())
+	e.SetChildren(lanes.AsElement(), status.AsElement())
+
+	e.Watch(Namespace.Data, "columns", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		b.render()
+		return false
+	}))
+	e.WatchEvent("kanban-move", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		o := evt.NewValue().(ui.Object)
+		message.SetText("Moved " + b.cardTitle(string(o.MustGetString("card"))) + " to " + b.columnTitle(string(o.MustGetString("to"))) + ", position " + strconv.Itoa(int(o.MustGetNumber("index"))+1))
+		return false
+	}))
+	e.WatchEvent("kanban-limit", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		message.SetText(b.columnTitle(string(evt.NewValue().(ui.String))) + " is full")
+		return false
+	}))
+
+	e.SetData("columns", columns)
+	return b
+}
+
+// OnPersist registers the function called to persist moves. It runs asynchronously.
+func (b BoardElement) OnPersist(fn func(ctx context.Context, m Move) error) BoardElement {
+	if st, ok := boards[b.AsElement().ID]; ok {
+		st.persist = fn
+	}
+	return b
+}
+
+// OnMove registers a handler called when a card has been moved.
+func (b BoardElement) OnMove(h *ui.MutationHandler) BoardElement {
+	b.AsElement().WatchEvent("kanban-move", b, h)
+	return b
+}
+
+// Columns returns the columns of the board.
+func (b BoardElement) Columns() ui.List {
+	v, ok := b.AsElement().GetData("columns")
+	if !ok {
+		return ui.NewList().Commit()
+	}
+	return v.(ui.List)
+}
+
+func (b BoardElement) column(colid string) (ui.Object, int, bool) {
+	for i, c := range b.Columns().UnsafelyUnwrap() {
+		o := c.(ui.Object)
+		if string(o.MustGetString("id")) == colid {
+			return o, i, true
+		}
+	}
+	return ui.Object{}, -1, false
+}
+
+func cards(col ui.Object) []ui.Value {
+	v, ok := col.Get("cards")
+	if !ok {
+		return nil
+	}
+	return v.(ui.List).UnsafelyUnwrap()
+}
+
+// locate returns the column id and the index of a card.
+func (b BoardElement) locate(cardid string) (string, int) {
+	for _, c := range b.Columns().UnsafelyUnwrap() {
+		col := c.(ui.Object)
+		for i, card := range cards(col) {
+			if string(card.(ui.Object).MustGetString("id")) == cardid {
+				return string(col.MustGetString("id")), i
+			}
+		}
+	}
+	return "", -1
+}
+
+func (b BoardElement) cardTitle(cardid string) string {
+	colid, idx := b.locate(cardid)
+	col, _, ok := b.column(colid)
+	if !ok || idx < 0 {
+		return cardid
+	}
+	if t, ok := cards(col)[idx].(ui.Object).Get("title"); ok {
+		return string(t.(ui.String))
+	}
+	return cardid
+}
+
+func (b BoardElement) columnTitle(colid string) string {
+	col, _, ok := b.column(colid)
+	if !ok {
+		return colid
+	}
+	if t, ok := col.Get("title"); ok {
+		return string(t.(ui.String))
+	}
+	return colid
+}
+
+func limit(col ui.Object) int {
+	if v, ok := col.Get("limit"); ok {
+		return int(v.(ui.Number))
+	}
+	return 0
+}
+
+// MoveCard moves a card to the given index of a column. A negative index appends the card.
+// It returns false if the move was refused.
+func (b BoardElement) MoveCard(cardid string, to string, index int) bool {
+	from, fromidx := b.locate(cardid)
+	if fromidx < 0 {
+		return false
+	}
+	target, _, ok := b.column(to)
+	if !ok {
+		return false
+	}
+	if l := limit(target); from != to && l > 0 && len(cards(target)) >= l {
+		b.AsElement().TriggerEvent("kanban-limit", ui.String(to))
+		return false
+	}
+
+	previous := b.Columns()
+	var card ui.Value
+	columns := ui.NewList()
+	// removal
+	for _, c := range previous.UnsafelyUnwrap() {
+		col := c.(ui.Object)
+		if string(col.MustGetString("id")) != from {
+			columns.Append(col)
+			continue
+		}
+		l := ui.NewList()
+		for i, cv := range cards(col) {
+			if i == fromidx {
+				card = cv
+				continue
+			}
+			l.Append(cv)
+		}
+		columns.Append(col.MakeCopy().Set("cards", l.Commit()).Commit())
+	}
+	// insertion
+	res := ui.NewList()
+	final := 0
+	for _, c := range columns.Commit().UnsafelyUnwrap() {
+		col := c.(ui.Object)
+		if string(col.MustGetString("id")) != to {
+			res.Append(col)
+			continue
+		}
+		cs := cards(col)
+		if index < 0 || index > len(cs) {
+			index = len(cs)
+		}
+		l := ui.NewList()
+		for i, cv := range cs {
+			if i == index {
+				l.Append(card)
+			}
+			l.Append(cv)
+		}
+		if index == len(cs) {
+			l.Append(card)
+		}
+		final = index
+		res.Append(col.MakeCopy().Set("cards", l.Commit()).Commit())
+	}
+	if from == to && final == fromidx {
+		return true
+	}
+
+	b.AsElement().SetData("columns", res.Commit())
+	m := Move{cardid, from, to, final}
+	b.AsElement().TriggerEvent("kanban-move", m.value())
+	b.persist(m, previous)
+	return true
+}
+
+func (b BoardElement) persist(m Move, previous ui.List) {
+	st, ok := boards[b.AsElement().ID]
+	if !ok || st.persist == nil {
+		return
+	}
+	fn := st.persist
+	ui.DoAsync(b.AsElement(), func(ctx context.Context) {
+		err := fn(ctx, m)
+		if err == nil {
+			return
+		}
+		ui.DoSync(func() {
+			b.AsElement().SetData("columns", previous)
+			b.AsElement().TriggerEvent("kanban-move-failed", ui.String(err.Error()))
+		})
+	})
+}
+
+func (b BoardElement) render() {
+	e := b.AsElement()
+	d := GetDocument(e)
+	lanes := d.GetElementById(e.ID + "-columns")
+	if lanes == nil {
+		return
+	}
+	focused := ""
+	if st, ok := boards[e.ID]; ok {
+		focused = st.dragged
+	}
+	lanes.DeleteChildren()
+
+	columns := b.Columns().UnsafelyUnwrap()
+	children := make([]*ui.Element, 0, len(columns))
+	for ci, c := range columns {
+		col := c.(ui.Object)
+		colid := string(col.MustGetString("id"))
+		cid := e.ID + "-column-" + colid
+
+		section := d.Section.WithID(cid)
+		AddClass(section.AsElement(), "zui-kanban-column")
+		SetAttribute(section.AsElement(), "aria-labelledby", cid+"-title")
+		cs := cards(col)
+		header := d.H2.WithID(cid + "-title").SetText(b.columnTitle(colid))
+		count := d.Span.WithID(cid + "-count")
+		AddClass(count.AsElement(), "zui-kanban-count")
+		if l := limit(col); l > 0 {
+			count.SetText(strconv.Itoa(len(cs)) + "/" + strconv.Itoa(l))
+			if len(cs) >= l {
+				AddClass(section.AsElement(), "zui-kanban-full")
+			}
+			if len(cs) > l {
+				AddClass(section.AsElement(), "zui-kanban-over-limit")
+			}
+		} else {
+			count.SetText(strconv.Itoa(len(cs)))
+		}
+
+		list := d.Ul.WithID(cid + "-cards")
+		AddClass(list.AsElement(), "zui-kanban-cards")
+		items := make([]*ui.Element, 0, len(cs))
+		for i, cv := range cs {
+			items = append(items, b.card(cid, ci, colid, i, cv.(ui.Object)))
+		}
+		list.AsElement().SetChildren(items...)
+		b.dropTarget(list.AsElement(), colid, -1)
+
+		section.AsElement().SetChildren(header.AsElement(), count.AsElement(), list.AsElement())
+		children = append(children, section.AsElement())
+	}
+	lanes.SetChildren(children...)
+
+	// the focus follows a card moved with the keyboard.
+	if focused != "" {
+		boards[e.ID].dragged = ""
+		colid, _ := b.locate(focused)
+		if el := d.GetElementById(e.ID + "-column-" + colid + "-card-" + focused); el != nil {
+			SetFocus(el, true)
+		}
+	}
+}
+
+func (b BoardElement) card(cid string, colindex int, colid string, index int, card ui.Object) *ui.Element {
+	d := GetDocument(b.AsElement())
+	cardid := string(card.MustGetString("id"))
+	li := d.Li.WithID(cid + "-card-" + cardid)
+	AddClass(li.AsElement(), "zui-kanban-card")
+	SetAttribute(li.AsElement(), "draggable", "true")
+	SetAttribute(li.AsElement(), "tabindex", "0")
+	SetAttribute(li.AsElement(), "aria-roledescription", "Draggable card")
+	title := ""
+	if t, ok := card.Get("title"); ok {
+		title = string(t.(ui.String))
+	}
+	li.AsElement().SetChildren(d.Span.WithID(cid + "-card-" + cardid + "-title").SetText(title).AsElement())
+
+	li.AsElement().AddEventListener("dragstart", ui.NewEventHandler(func(evt ui.Event) bool {
+		boards[b.AsElement().ID].dragged = cardid
+		AddClass(li.AsElement(), "zui-kanban-dragging")
+		return false
+	}))
+	li.AsElement().AddEventListener("dragend", ui.NewEventHandler(func(evt ui.Event) bool {
+		if st, ok := boards[b.AsElement().ID]; ok {
+			st.dragged = ""
+		}
+		RemoveClass(li.AsElement(), "zui-kanban-dragging")
+		return false
+	}))
+	b.dropTarget(li.AsElement(), colid, index)
+
+	li.AsElement().AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		k, ok := evt.(KeyboardEvent)
+		if !ok || !k.AltKey() {
+			return false
+		}
+		columns := b.Columns().UnsafelyUnwrap()
+		var to string
+		idx := index
+		switch k.Key() {
+		case "ArrowUp":
+			to, idx = colid, index-1
+			if idx < 0 {
+				return false
+			}
+		case "ArrowDown":
+			to, idx = colid, index+1
+		case "ArrowLeft":
+			if colindex == 0 {
+				return false
+			}
+			to = string(columns[colindex-1].(ui.Object).MustGetString("id"))
+		case "ArrowRight":
+			if colindex+1 >= len(columns) {
+				return false
+			}
+			to = string(columns[colindex+1].(ui.Object).MustGetString("id"))
+		default:
+			return false
+		}
+		evt.PreventDefault()
+		boards[b.AsElement().ID].dragged = cardid // restores the focus after rendering
+		if !b.MoveCard(cardid, to, idx) {
+			boards[b.AsElement().ID].dragged = ""
+		}
+		return false
+	}))
+	return li.AsElement()
+}
+
+// dropTarget makes an element accept dragged cards. A negative index appends the card to the column.
+func (b BoardElement) dropTarget(target *ui.Element, colid string, index int) {
+	target.AddEventListener("dragover", ui.NewEventHandler(func(evt ui.Event) bool {
+		if st, ok := boards[b.AsElement().ID]; ok && st.dragged != "" {
+			evt.PreventDefault()
+		}
+		return false
+	}))
+	target.AddEventListener("drop", ui.NewEventHandler(func(evt ui.Event) bool {
+		st, ok := boards[b.AsElement().ID]
+		if !ok || st.dragged == "" {
+			return false
+		}
+		evt.PreventDefault()
+		evt.StopPropagation()
+		cardid := st.dragged
+		st.dragged = ""
+		b.MoveCard(cardid, colid, index)
+		return false
+	}))
+}
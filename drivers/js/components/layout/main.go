@@ -0,0 +1,236 @@
+// package layout provides layout components (Stack, Row, Grid) whose CSS is generated automatically.
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// Layout components are containers whose layout is specified via UI properties:
+//   - "gap" (ui.String): space between items, e.g. "1rem"
+//   - "align" (ui.String): cross-axis alignment of items (align-items), e.g. "center"
+//   - "justify" (ui.String): main-axis distribution of items (justify-content), e.g. "space-between"
+//   - "wrap" (ui.Bool): whether the items of a Row may wrap
+//   - "columns" (ui.String): column specification of a Grid
+//   - "columns-at" (ui.Object): responsive column specifications of a Grid, indexed by breakpoint name
+//
+// A column specification is either a number of equal columns (e.g. "3") or a grid-template-columns
+// value (e.g. "200px 1fr").
+// The corresponding rules are scoped to the element id and kept up to date in a dedicated stylesheet.
+// Responsive specifications are mobile-first: a specification applies from its breakpoint upward until
+// a larger breakpoint provides another one.
+// The base rules use a zero-specificity selector so that they can easily be overridden by app styles.
+
+// StyleSheetID is the id of the stylesheet holding the layout rules.
+const StyleSheetID = "zui-layout"
+
+// Breakpoint is a named viewport minimum width, in pixels.
+type Breakpoint struct {
+	Name     string
+	MinWidth int
+}
+
+// Breakpoints lists the breakpoints that may be used for responsive column specifications, in increasing order.
+var Breakpoints = []Breakpoint{
+	{"sm", 640},
+	{"md", 768},
+	{"lg", 1024},
+	{"xl", 1280},
+}
+
+type LayoutElement struct {
+	*ui.Element
+}
+
+// Stack returns a container whose children are laid out vertically.
+func Stack(d *Document, id string, modifiers ...func(*ui.Element) *ui.Element) LayoutElement {
+	return newLayout(d, id, "stack", modifiers...)
+}
+
+// Row returns a container whose children are laid out horizontally.
+func Row(d *Document, id string, modifiers ...func(*ui.Element) *ui.Element) LayoutElement {
+	return newLayout(d, id, "row", modifiers...)
+}
+
+// Grid returns a container whose children are laid out in columns.
+func Grid(d *Document, id string, modifiers ...func(*ui.Element) *ui.Element) LayoutElement {
+	return newLayout(d, id, "grid", modifiers...)
+}
+
+func newLayout(d *Document, id string, kind string, modifiers ...func(*ui.Element) *ui.Element) LayoutElement {
+	e := d.Div.WithID(id).AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/layout")
+	AddClass(e, "zui-"+kind)
+	e.SetUI("layout", ui.String(kind))
+	l := LayoutElement{e}
+
+	h := ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		l.render()
+		return false
+	})
+	for _, p := range []string{"gap", "align", "justify", "wrap", "columns", "columns-at"} {
+		e.Watch(Namespace.UI, p, e, h)
+	}
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		sheet, ok := d.GetStyleSheet(StyleSheetID)
+		if !ok {
+			return false
+		}
+		sheet.RemoveRule(":where(#" + id + ")")
+		for _, r := range ranges() {
+			sheet.RemoveNestedRule(r, "#"+id)
+		}
+		sheet.Update()
+		return false
+	}).RunOnce())
+
+	for _, m := range modifiers {
+		e = m(e)
+	}
+	l.render()
+	return l
+}
+
+// Gap sets the space between the items of a layout.
+func Gap(value string) func(*ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		e.SetUI("gap", ui.String(value))
+		return e
+	}
+}
+
+// Align sets the cross-axis alignment of the items of a layout.
+func Align(value string) func(*ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		e.SetUI("align", ui.String(value))
+		return e
+	}
+}
+
+// Justify sets the main-axis distribution of the items of a layout.
+func Justify(value string) func(*ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		e.SetUI("justify", ui.String(value))
+		return e
+	}
+}
+
+// Wrap allows the items of a Row to wrap onto multiple lines.
+func Wrap(b bool) func(*ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		e.SetUI("wrap", ui.Bool(b))
+		return e
+	}
+}
+
+// Columns sets the column specification of a Grid.
+func Columns(spec string) func(*ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		e.SetUI("columns", ui.String(spec))
+		return e
+	}
+}
+
+// ColumnsAt sets the column specification of a Grid from the given breakpoint upward.
+func ColumnsAt(breakpoint string, spec string) func(*ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		o := ui.NewObject()
+		if v, ok := e.GetUI("columns-at"); ok {
+			o = v.(ui.Object).MakeCopy()
+		}
+		e.SetUI("columns-at", o.Set(breakpoint, ui.String(spec)).Commit())
+		return e
+	}
+}
+
+func stylesheet(d *Document) StyleSheet {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if !ok {
+		sheet = d.NewStyleSheet(StyleSheetID)
+		actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+		d.SetActiveStyleSheets(actives...)
+	}
+	return sheet
+}
+
+// ranges returns the media queries of the disjoint viewport ranges delimited by the breakpoints.
+// Using disjoint ranges makes the responsive rules independent of their order in the stylesheet.
+func ranges() []string {
+	res := make([]string, 0, len(Breakpoints))
+	for i, bp := range Breakpoints {
+		q := "@media (min-width: " + strconv.Itoa(bp.MinWidth) + "px)"
+		if i+1 < len(Breakpoints) {
+			q += " and (max-width: " + strconv.Itoa(Breakpoints[i+1].MinWidth-1) + ".98px)"
+		}
+		res = append(res, q)
+	}
+	return res
+}
+
+func columnTemplate(spec string) string {
+	if n, err := strconv.Atoi(strings.TrimSpace(spec)); err == nil {
+		return "repeat(" + strconv.Itoa(n) + ", minmax(0, 1fr))"
+	}
+	return spec
+}
+
+func (l LayoutElement) uistring(prop string) string {
+	v, ok := l.AsElement().GetUI(prop)
+	if !ok {
+		return ""
+	}
+	s, _ := v.(ui.String)
+	return string(s)
+}
+
+func (l LayoutElement) render() {
+	e := l.AsElement()
+	sheet := stylesheet(GetDocument(e))
+	kind := l.uistring("layout")
+
+	var rule strings.Builder
+	switch kind {
+	case "stack":
+		rule.WriteString("display: flex; flex-direction: column;")
+	case "row":
+		rule.WriteString("display: flex; flex-direction: row;")
+		if v, ok := e.GetUI("wrap"); ok && bool(v.(ui.Bool)) {
+			rule.WriteString(" flex-wrap: wrap;")
+		}
+	case "grid":
+		rule.WriteString("display: grid;")
+		if c := l.uistring("columns"); c != "" {
+			rule.WriteString(" grid-template-columns: " + columnTemplate(c) + ";")
+		}
+	}
+	if g := l.uistring("gap"); g != "" {
+		rule.WriteString(" gap: " + g + ";")
+	}
+	if a := l.uistring("align"); a != "" {
+		rule.WriteString(" align-items: " + a + ";")
+	}
+	if j := l.uistring("justify"); j != "" {
+		rule.WriteString(" justify-content: " + j + ";")
+	}
+	sheet.UpdateRule(":where(#"+e.ID+")", rule.String())
+
+	specs := ui.NewObject().Commit()
+	if v, ok := e.GetUI("columns-at"); ok {
+		specs = v.(ui.Object)
+	}
+	spec := ""
+	for i, r := range ranges() {
+		if v, ok := specs.Get(Breakpoints[i].Name); ok {
+			spec = string(v.(ui.String))
+		}
+		if kind != "grid" || spec == "" {
+			sheet.RemoveNestedRule(r, "#"+e.ID)
+			continue
+		}
+		sheet.UpdateNestedRule(r, "#"+e.ID, "grid-template-columns: "+columnTemplate(spec)+";")
+	}
+	sheet.Update()
+}
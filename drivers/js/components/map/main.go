@@ -0,0 +1,258 @@
+// package mapview provides an interactive map component backed by Leaflet or MapLibre.
+package mapview
+
+import (
+	"math"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// The map is rendered by an external library which owns the DOM of the map element: the element is a
+// third-party zone. The library script and stylesheet are loaded once, on first mount, and the map is
+// destroyed when the element is unmounted.
+// On the server, the map element is rendered empty.
+//
+// Data properties of the map element, which can be watched and set:
+//   - "center" (ui.Object): {lat, lng} of the center of the map,
+//   - "zoom" (ui.Number): the zoom level,
+//   - "markers" (ui.List): the markers, as Objects {id, lat, lng, title}.
+//
+// Events triggered on the map element:
+//   - "map-ready": the map has been created,
+//   - "map-move": the view was changed by the user. The event value is an Object {center, zoom},
+//   - "map-marker-click": a marker was clicked. The event value is the id of the marker.
+
+// StyleSheetID is the id of the stylesheet holding the map rules.
+const StyleSheetID = "zui-map"
+
+// Provider identifies the library rendering the map.
+type Provider int
+
+const (
+	Leaflet Provider = iota
+	MapLibre
+)
+
+// LatLng is a geographic position.
+type LatLng struct {
+	Lat, Lng float64
+}
+
+// Marker is a point of interest displayed on the map.
+type Marker struct {
+	ID       string
+	Position LatLng
+	Title    string
+}
+
+type MapElement struct {
+	*ui.Element
+}
+
+type config struct {
+	provider    Provider
+	center      LatLng
+	zoom        float64
+	script      string
+	stylesheet  string
+	tiles       string
+	attribution string
+	style       string
+}
+
+// Option allows to configure a map.
+type Option func(*config)
+
+// WithProvider selects the library rendering the map. Leaflet is used by default.
+func WithProvider(p Provider) Option {
+	return func(c *config) { c.provider = p }
+}
+
+// WithView sets the initial center and zoom level of the map.
+func WithView(center LatLng, zoom float64) Option {
+	return func(c *config) {
+		c.center = center
+		c.zoom = zoom
+	}
+}
+
+// WithLibrary overrides the URLs of the library script and stylesheet, e.g. to self-host them.
+func WithLibrary(script, stylesheet string) Option {
+	return func(c *config) {
+		c.script = script
+		c.stylesheet = stylesheet
+	}
+}
+
+// WithTiles sets the tile URL template and attribution of a Leaflet map.
+func WithTiles(urltemplate, attribution string) Option {
+	return func(c *config) {
+		c.tiles = urltemplate
+		c.attribution = attribution
+	}
+}
+
+// WithStyle sets the style URL of a MapLibre map.
+func WithStyle(url string) Option {
+	return func(c *config) { c.style = url }
+}
+
+// New returns a map.
+func New(d *Document, id string, options ...Option) MapElement {
+	cfg := &config{
+		zoom:        2,
+		tiles:       "https://tile.openstreetmap.org/{z}/{x}/{y}.png",
+		attribution: "&copy; OpenStreetMap contributors",
+		style:       "https://demotiles.maplibre.org/style.json",
+	}
+	for _, opt := range options {
+		opt(cfg)
+	}
+	if cfg.script == "" {
+		switch cfg.provider {
+		case MapLibre:
+			cfg.script = "https://unpkg.com/maplibre-gl@4.7.1/dist/maplibre-gl.js"
+			cfg.stylesheet = "https://unpkg.com/maplibre-gl@4.7.1/dist/maplibre-gl.css"
+		default:
+			cfg.script = "https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"
+			cfg.stylesheet = "https://unpkg.com/leaflet@1.9.4/dist/leaflet.css"
+		}
+	}
+
+	e := d.Div.WithID(id).AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/map")
+	AddClass(e, "zui-map")
+	m := MapElement{e}
+	style(d)
+
+	m.SetView(cfg.center, cfg.zoom)
+
+	ThirdPartyZone(e, func(host js.Value) func() {
+		var a adapter
+		switch cfg.provider {
+		case MapLibre:
+			a = &maplibreAdapter{style: cfg.style}
+		default:
+			a = &leafletAdapter{tiles: cfg.tiles, attribution: cfg.attribution}
+		}
+		v := &view{m: m, a: a, markers: make(map[string]js.Value)}
+		load(cfg.script, cfg.stylesheet, func() {
+			if v.destroyed {
+				return
+			}
+			v.create(host)
+		})
+		return v.destroy
+	})
+
+	return m
+}
+
+// Center returns the center of the map.
+func (m MapElement) Center() LatLng {
+	v, ok := m.AsElement().GetData("center")
+	if !ok {
+		return LatLng{}
+	}
+	return latLngFrom(v.(ui.Object))
+}
+
+// Zoom returns the zoom level of the map.
+func (m MapElement) Zoom() float64 {
+	v, ok := m.AsElement().GetData("zoom")
+	if !ok {
+		return 0
+	}
+	return float64(v.(ui.Number))
+}
+
+// SetView changes the center and zoom level of the map.
+func (m MapElement) SetView(center LatLng, zoom float64) MapElement {
+	m.AsElement().SetData("center", center.value())
+	m.AsElement().SetData("zoom", ui.Number(zoom))
+	return m
+}
+
+// Markers returns the markers displayed on the map.
+func (m MapElement) Markers() []Marker {
+	v, ok := m.AsElement().GetData("markers")
+	if !ok {
+		return nil
+	}
+	return markersFrom(v.(ui.List))
+}
+
+// SetMarkers replaces the markers displayed on the map.
+func (m MapElement) SetMarkers(markers ...Marker) MapElement {
+	l := ui.NewList()
+	for _, mk := range markers {
+		o := ui.NewObject()
+		o.Set("id", ui.String(mk.ID))
+		o.Set("lat", ui.Number(mk.Position.Lat))
+		o.Set("lng", ui.Number(mk.Position.Lng))
+		o.Set("title", ui.String(mk.Title))
+		l = l.Append(o.Commit())
+	}
+	m.AsElement().SetData("markers", l.Commit())
+	return m
+}
+
+// OnMove registers a handler called when the view is changed by the user.
+func (m MapElement) OnMove(h *ui.MutationHandler) MapElement {
+	m.AsElement().WatchEvent("map-move", m, h)
+	return m
+}
+
+// OnMarkerClick registers a handler called when a marker is clicked. The event value is the id of the marker.
+func (m MapElement) OnMarkerClick(h *ui.MutationHandler) MapElement {
+	m.AsElement().WatchEvent("map-marker-click", m, h)
+	return m
+}
+
+func (p LatLng) value() ui.Object {
+	o := ui.NewObject()
+	o.Set("lat", ui.Number(p.Lat))
+	o.Set("lng", ui.Number(p.Lng))
+	return o.Commit()
+}
+
+func (p LatLng) near(q LatLng) bool {
+	return math.Abs(p.Lat-q.Lat) < 1e-9 && math.Abs(p.Lng-q.Lng) < 1e-9
+}
+
+func latLngFrom(o ui.Object) LatLng {
+	return LatLng{float64(o.MustGetNumber("lat")), float64(o.MustGetNumber("lng"))}
+}
+
+func markersFrom(l ui.List) []Marker {
+	res := make([]Marker, 0, len(l.UnsafelyUnwrap()))
+	for _, v := range l.UnsafelyUnwrap() {
+		o := v.(ui.Object)
+		res = append(res, Marker{
+			ID:       string(o.MustGetString("id")),
+			Position: latLngFrom(o),
+			Title:    string(o.MustGetString("title")),
+		})
+	}
+	return res
+}
+
+var rules = map[string]string{
+	":where(.zui-map)": "display: block; position: relative; min-height: 20rem;",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.Update()
+}
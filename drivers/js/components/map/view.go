@@ -0,0 +1,322 @@
+package mapview
+
+import (
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// adapter abstracts the library rendering the map.
+type adapter interface {
+	create(host js.Value, center LatLng, zoom float64) js.Value
+	view(m js.Value) (LatLng, float64)
+	setView(m js.Value, center LatLng, zoom float64)
+	onMoveEnd(m js.Value, f js.Func)
+	addMarker(m js.Value, mk Marker, onclick js.Func) js.Value
+	moveMarker(marker js.Value, to LatLng)
+	removeMarker(marker js.Value)
+	destroy(m js.Value)
+}
+
+// view binds a map created by the library to the properties of the map element, between mount and unmount.
+type view struct {
+	m MapElement
+	a adapter
+
+	native    js.Value
+	created   bool
+	destroyed bool
+
+	moveend   js.Func
+	onview    *ui.MutationHandler
+	onmarkers *ui.MutationHandler
+
+	shown   map[string]Marker
+	markers map[string]js.Value
+	clicks  map[string]js.Func
+}
+
+func (v *view) create(host js.Value) {
+	e := v.m.AsElement()
+	v.native = v.a.create(host, v.m.Center(), v.m.Zoom())
+	v.created = true
+	v.shown = make(map[string]Marker)
+	v.clicks = make(map[string]js.Func)
+
+	v.moveend = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			if !v.destroyed {
+				v.moved()
+			}
+		})
+		return nil
+	})
+	v.a.onMoveEnd(v.native, v.moveend)
+
+	v.onview = ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		v.apply()
+		return false
+	})
+	e.Watch(Namespace.Data, "center", e, v.onview)
+	e.Watch(Namespace.Data, "zoom", e, v.onview)
+
+	v.onmarkers = ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		v.syncMarkers()
+		return false
+	})
+	e.Watch(Namespace.Data, "markers", e, v.onmarkers)
+	v.syncMarkers()
+
+	e.TriggerEvent("map-ready")
+}
+
+// moved updates the properties after the view of the map was changed by the user.
+func (v *view) moved() {
+	center, zoom := v.a.view(v.native)
+	if center.near(v.m.Center()) && zoom == v.m.Zoom() {
+		return
+	}
+	v.m.SetView(center, zoom)
+
+	o := ui.NewObject()
+	o.Set("center", center.value())
+	o.Set("zoom", ui.Number(zoom))
+	v.m.AsElement().TriggerEvent("map-move", o.Commit())
+}
+
+// apply updates the view of the map after the properties were changed.
+func (v *view) apply() {
+	center, zoom := v.a.view(v.native)
+	target, tzoom := v.m.Center(), v.m.Zoom()
+	if center.near(target) && zoom == tzoom {
+		return
+	}
+	v.a.setView(v.native, target, tzoom)
+}
+
+func (v *view) syncMarkers() {
+	wanted := make(map[string]Marker)
+	for _, mk := range v.m.Markers() {
+		wanted[mk.ID] = mk
+	}
+	for id, mk := range v.shown {
+		if w, ok := wanted[id]; !ok || w.Title != mk.Title {
+			v.removeMarker(id)
+		}
+	}
+	for id, mk := range wanted {
+		shown, ok := v.shown[id]
+		if !ok {
+			v.addMarker(mk)
+			continue
+		}
+		if !shown.Position.near(mk.Position) {
+			v.a.moveMarker(v.markers[id], mk.Position)
+			v.shown[id] = mk
+		}
+	}
+}
+
+func (v *view) addMarker(mk Marker) {
+	e := v.m.AsElement()
+	id := mk.ID
+	click := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			if !v.destroyed {
+				e.TriggerEvent("map-marker-click", ui.String(id))
+			}
+		})
+		return nil
+	})
+	v.clicks[id] = click
+	v.markers[id] = v.a.addMarker(v.native, mk, click)
+	v.shown[id] = mk
+}
+
+func (v *view) removeMarker(id string) {
+	v.a.removeMarker(v.markers[id])
+	v.clicks[id].Release()
+	delete(v.markers, id)
+	delete(v.clicks, id)
+	delete(v.shown, id)
+}
+
+// destroy removes the map. It is called on unmount, possibly before the library has loaded.
+func (v *view) destroy() {
+	v.destroyed = true
+	if !v.created {
+		return
+	}
+	e := v.m.AsElement()
+	e.RemoveMutationHandler(Namespace.Data, "center", e, v.onview)
+	e.RemoveMutationHandler(Namespace.Data, "zoom", e, v.onview)
+	e.RemoveMutationHandler(Namespace.Data, "markers", e, v.onmarkers)
+	for id := range v.shown {
+		v.removeMarker(id)
+	}
+	v.a.destroy(v.native)
+	v.moveend.Release()
+	v.created = false
+}
+
+type leafletAdapter struct {
+	tiles       string
+	attribution string
+}
+
+func (l *leafletAdapter) create(host js.Value, center LatLng, zoom float64) js.Value {
+	L := js.Global().Get("L")
+	m := L.Call("map", host)
+	L.Call("tileLayer", l.tiles, map[string]interface{}{"attribution": l.attribution}).Call("addTo", m)
+	m.Call("setView", []interface{}{center.Lat, center.Lng}, zoom)
+	return m
+}
+
+func (l *leafletAdapter) view(m js.Value) (LatLng, float64) {
+	c := m.Call("getCenter")
+	return LatLng{c.Get("lat").Float(), c.Get("lng").Float()}, m.Call("getZoom").Float()
+}
+
+func (l *leafletAdapter) setView(m js.Value, center LatLng, zoom float64) {
+	m.Call("setView", []interface{}{center.Lat, center.Lng}, zoom)
+}
+
+func (l *leafletAdapter) onMoveEnd(m js.Value, f js.Func) {
+	m.Call("on", "moveend", f)
+}
+
+func (l *leafletAdapter) addMarker(m js.Value, mk Marker, onclick js.Func) js.Value {
+	opts := map[string]interface{}{}
+	if mk.Title != "" {
+		opts["title"] = mk.Title
+	}
+	marker := js.Global().Get("L").Call("marker", []interface{}{mk.Position.Lat, mk.Position.Lng}, opts).Call("addTo", m)
+	marker.Call("on", "click", onclick)
+	return marker
+}
+
+func (l *leafletAdapter) moveMarker(marker js.Value, to LatLng) {
+	marker.Call("setLatLng", []interface{}{to.Lat, to.Lng})
+}
+
+func (l *leafletAdapter) removeMarker(marker js.Value) {
+	marker.Call("remove")
+}
+
+func (l *leafletAdapter) destroy(m js.Value) {
+	m.Call("remove")
+}
+
+type maplibreAdapter struct {
+	style string
+}
+
+func (l *maplibreAdapter) create(host js.Value, center LatLng, zoom float64) js.Value {
+	return js.Global().Get("maplibregl").Get("Map").New(map[string]interface{}{
+		"container": host,
+		"style":     l.style,
+		"center":    []interface{}{center.Lng, center.Lat},
+		"zoom":      zoom,
+	})
+}
+
+func (l *maplibreAdapter) view(m js.Value) (LatLng, float64) {
+	c := m.Call("getCenter")
+	return LatLng{c.Get("lat").Float(), c.Get("lng").Float()}, m.Call("getZoom").Float()
+}
+
+func (l *maplibreAdapter) setView(m js.Value, center LatLng, zoom float64) {
+	m.Call("jumpTo", map[string]interface{}{"center": []interface{}{center.Lng, center.Lat}, "zoom": zoom})
+}
+
+func (l *maplibreAdapter) onMoveEnd(m js.Value, f js.Func) {
+	m.Call("on", "moveend", f)
+}
+
+func (l *maplibreAdapter) addMarker(m js.Value, mk Marker, onclick js.Func) js.Value {
+	marker := js.Global().Get("maplibregl").Get("Marker").New()
+	if mk.Title != "" {
+		marker.Call("getElement").Call("setAttribute", "title", mk.Title)
+	}
+	marker.Call("setLngLat", []interface{}{mk.Position.Lng, mk.Position.Lat}).Call("addTo", m)
+	marker.Call("getElement").Call("addEventListener", "click", onclick)
+	return marker
+}
+
+func (l *maplibreAdapter) moveMarker(marker js.Value, to LatLng) {
+	marker.Call("setLngLat", []interface{}{to.Lng, to.Lat})
+}
+
+func (l *maplibreAdapter) removeMarker(marker js.Value) {
+	marker.Call("remove")
+}
+
+func (l *maplibreAdapter) destroy(m js.Value) {
+	m.Call("remove")
+}
+
+// libraries holds the loading state of the library scripts, by URL. It is only accessed from the UI thread.
+var libraries = make(map[string]*library)
+
+type library struct {
+	loaded  bool
+	waiting []func()
+}
+
+// load loads a library script and its stylesheet once, then calls done on the UI thread.
+// The nodes are inserted natively: they are not part of the UI tree, and thus neither rendered on the server
+// nor replayed.
+func load(script, stylesheet string, done func()) {
+	l, ok := libraries[script]
+	if ok {
+		if l.loaded {
+			done()
+			return
+		}
+		l.waiting = append(l.waiting, done)
+		return
+	}
+	l = &library{waiting: []func(){done}}
+	libraries[script] = l
+
+	doc := js.Global().Get("document")
+	head := doc.Get("head")
+	if stylesheet != "" {
+		link := doc.Call("createElement", "link")
+		link.Call("setAttribute", "rel", "stylesheet")
+		link.Call("setAttribute", "href", stylesheet)
+		head.Call("append", link)
+	}
+
+	s := doc.Call("createElement", "script")
+	s.Call("setAttribute", "src", script)
+	var onload, onerror js.Func
+	release := func() {
+		onload.Release()
+		onerror.Release()
+	}
+	onload = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		release()
+		go ui.DoSync(func() {
+			l.loaded = true
+			for _, f := range l.waiting {
+				f()
+			}
+			l.waiting = nil
+		})
+		return nil
+	})
+	// a failed load may be retried by a later mount.
+	onerror = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		release()
+		s.Call("remove")
+		go ui.DoSync(func() {
+			DEBUG("map: unable to load ", script)
+			delete(libraries, script)
+		})
+		return nil
+	})
+	s.Call("addEventListener", "load", onload)
+	s.Call("addEventListener", "error", onerror)
+	head.Call("append", s)
+}
@@ -0,0 +1,412 @@
+// package palette is a package that provides a command palette component (opened with Ctrl+K / Cmd+K).
+package palette
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// The command palette exposes the actions of an app in a single searchable list.
+// Commands are registered once and may either run a handler or navigate to a route.
+// Matching is fuzzy (subsequence based) over the label and keywords of each command, and
+// results are ranked by match quality, then by how frequently and recently a command was used.
+// Usage statistics are persisted in localstorage.
+//
+// The palette is rendered in a dialog appended to the document body when opened (portal) and
+// traps the focus while it is open.
+
+// Command describes an action that can be run from the palette.
+type Command struct {
+	ID       string
+	Label    string
+	Keywords []string
+	// Route, if not empty, is navigated to when the command is run.
+	Route string
+	// Handler, if not nil, is called when the command is run.
+	Handler func()
+}
+
+type PaletteElement struct {
+	*ui.Element
+}
+
+// MaxResults is the maximum number of commands displayed.
+var MaxResults = 20
+
+// New returns a command palette for the document. It is opened with Ctrl+K (or Cmd+K).
+func New(d *Document, id string) PaletteElement {
+	dialog := d.Dialog.WithID(id)
+	MarkComponent(dialog.AsElement(), "github.com/atdiar/particleui/drivers/js/components/palette")
+	AddClass(dialog.AsElement(), "zui-palette")
+	SetAttribute(dialog.AsElement(), "aria-label", "Command palette")
+	p := PaletteElement{dialog.AsElement()}
+
+	input := d.Input.WithID(id+"-input", "text")
+	SetAttribute(input.AsElement(), "role", "combobox")
+	SetAttribute(input.AsElement(), "aria-controls", id+"-listbox")
+	SetAttribute(input.AsElement(), "aria-expanded", "true")
+	SetAttribute(input.AsElement(), "placeholder", "Type a command...")
+
+	listbox := d.Ul.WithID(id + "-listbox")
+	SetAttribute(listbox.AsElement(), "role", "listbox")
+
+	d.NewObservable(id+"-usage", EnableLocalPersistence())
+
+	dialog.AsElement().SetChildren(input.AsElement(), listbox.AsElement())
+	TrapFocus(dialog.AsElement())
+
+	input.AsElement().AddEventListener("input", ui.NewEventHandler(func(evt ui.Event) bool {
+		v, ok := evt.Value().(ui.Object).Get("value")
+		if !ok {
+			return false
+		}
+		p.AsElement().SetData("query", v)
+		return false
+	}))
+
+	p.AsElement().Watch(Namespace.Data, "query", p, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		p.refresh()
+		return false
+	}))
+
+	p.AsElement().Watch(Namespace.Data, "selected", p, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		idx := int(evt.NewValue().(ui.Number))
+		for i, li := range listbox.AsElement().Children.List {
+			if i == idx {
+				SetAttribute(li, "aria-selected", "true")
+				AddClass(li, "zui-palette-selected")
+				SetAttribute(input.AsElement(), "aria-activedescendant", li.ID)
+				continue
+			}
+			SetAttribute(li, "aria-selected", "false")
+			RemoveClass(li, "zui-palette-selected")
+		}
+		return false
+	}))
+
+	input.AsElement().AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		k, ok := evt.(KeyboardEvent)
+		if !ok {
+			return false
+		}
+		results := p.results()
+		n := len(results)
+		sel := p.selected()
+		switch k.Key() {
+		case "ArrowDown":
+			evt.PreventDefault()
+			if n > 0 {
+				p.AsElement().SetData("selected", ui.Number((sel+1)%n))
+			}
+		case "ArrowUp":
+			evt.PreventDefault()
+			if n > 0 {
+				if sel <= 0 {
+					sel = n
+				}
+				p.AsElement().SetData("selected", ui.Number(sel-1))
+			}
+		case "Enter":
+			evt.PreventDefault()
+			if sel >= 0 && sel < n {
+				p.Run(results[sel].ID)
+			}
+		case "Escape":
+			p.Close()
+		}
+		return false
+	}))
+
+	d.AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		k, ok := evt.(KeyboardEvent)
+		if !ok {
+			return false
+		}
+		if (k.CtrlKey() || k.MetaKey()) && strings.ToLower(k.Key()) == "k" {
+			evt.PreventDefault()
+			if p.IsOpened() {
+				p.Close()
+			} else {
+				p.Open()
+			}
+		}
+		return false
+	}))
+
+	return p
+}
+
+// Register adds commands to the palette. A command with an existing ID replaces the previous one.
+//
+// Commands are stored on the palette element: their description in an internal property and their
+// handler in a watcher of the event the palette triggers when running them. They are therefore released
+// along with the palette.
+func (p PaletteElement) Register(commands ...Command) PaletteElement {
+	e := p.AsElement()
+	r := p.commands().MakeCopy()
+	for _, c := range commands {
+		keywords := ui.NewList()
+		for _, k := range c.Keywords {
+			keywords.Append(ui.String(k))
+		}
+		r.Set(c.ID, ui.NewObject().
+			Set("label", ui.String(c.Label)).
+			Set("keywords", keywords.Commit()).
+			Set("route", ui.String(c.Route)).
+			Commit())
+
+		e.Unwatch(Namespace.Event, runEvent(c.ID), e)
+		if h := c.Handler; h != nil {
+			e.WatchEvent(runEvent(c.ID), e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+				h()
+				return false
+			}))
+		}
+	}
+	e.Set(Namespace.Internals, "commands", r.Commit())
+	return p
+}
+
+// Unregister removes a command from the palette.
+func (p PaletteElement) Unregister(id string) PaletteElement {
+	e := p.AsElement()
+	e.Unwatch(Namespace.Event, runEvent(id), e)
+	e.Set(Namespace.Internals, "commands", p.commands().MakeCopy().Delete(id).Commit())
+	return p
+}
+
+// commands returns the descriptions of the commands registered on the palette, indexed by command ID.
+func (p PaletteElement) commands() ui.Object {
+	v, ok := p.AsElement().Get(Namespace.Internals, "commands")
+	if !ok {
+		return ui.NewObject().Commit()
+	}
+	return v.(ui.Object)
+}
+
+// command returns the command registered under the given ID, without its handler.
+func (p PaletteElement) command(id string) (Command, bool) {
+	v, ok := p.commands().Get(id)
+	if !ok {
+		return Command{}, false
+	}
+	o := v.(ui.Object)
+	c := Command{ID: id, Label: string(o.MustGetString("label")), Route: string(o.MustGetString("route"))}
+	for _, k := range o.MustGetList("keywords").UnsafelyUnwrap() {
+		c.Keywords = append(c.Keywords, string(k.(ui.String)))
+	}
+	return c, true
+}
+
+// runEvent returns the name of the event triggered on the palette to run the handler of a command.
+// Command IDs are escaped since property names may not contain slashes.
+func runEvent(id string) string {
+	return "palette-run-" + url.PathEscape(id)
+}
+
+// Open displays the palette.
+func (p PaletteElement) Open() PaletteElement {
+	d := GetDocument(p.AsElement())
+	body := d.Body()
+	if p.AsElement().Parent == nil || p.AsElement().Parent.ID != body.ID {
+		body.AppendChild(p)
+	}
+	p.AsElement().SetData("query", ui.String(""))
+	p.refresh()
+	DialogElement(p).Open()
+	if input := d.GetElementById(p.AsElement().ID + "-input"); input != nil {
+		SetFocus(input, false)
+	}
+	return p
+}
+
+// IsOpened returns whether the palette is displayed.
+func (p PaletteElement) IsOpened() bool {
+	return DialogElement(p).IsOpened()
+}
+
+// Close hides the palette and removes it from the document body.
+func (p PaletteElement) Close() PaletteElement {
+	DialogElement(p).Close()
+	if parent := p.AsElement().Parent; parent != nil {
+		parent.RemoveChild(p)
+	}
+	return p
+}
+
+// Run runs the command registered under the given ID and records its usage.
+func (p PaletteElement) Run(id string) {
+	c, ok := p.command(id)
+	if !ok {
+		return
+	}
+	p.recordUsage(id)
+	p.Close()
+	p.AsElement().TriggerEvent(runEvent(id))
+	if c.Route != "" {
+		if r := GetDocument(p.AsElement()).Router(); r != nil {
+			r.GoTo(c.Route)
+		}
+	}
+}
+
+func (p PaletteElement) query() string {
+	v, ok := p.AsElement().GetData("query")
+	if !ok {
+		return ""
+	}
+	return string(v.(ui.String))
+}
+
+func (p PaletteElement) selected() int {
+	v, ok := p.AsElement().GetData("selected")
+	if !ok {
+		return -1
+	}
+	return int(v.(ui.Number))
+}
+
+func (p PaletteElement) usage() ui.Object {
+	o := GetDocument(p.AsElement()).GetElementById(p.AsElement().ID + "-usage")
+	if o == nil {
+		return ui.NewObject().Commit()
+	}
+	v, ok := o.GetData("usage")
+	if !ok {
+		return ui.NewObject().Commit()
+	}
+	return v.(ui.Object)
+}
+
+func (p PaletteElement) recordUsage(id string) {
+	o := GetDocument(p.AsElement()).GetElementById(p.AsElement().ID + "-usage")
+	if o == nil {
+		return
+	}
+	u := p.usage()
+	count := ui.Number(0)
+	if v, ok := u.Get(id); ok {
+		count = v.(ui.Object).MustGetNumber("count")
+	}
+	entry := ui.NewObject().
+		Set("count", count+1).
+		Set("last", ui.Number(time.Now().Unix())).
+		Commit()
+	o.SetData("usage", u.MakeCopy().Set(id, entry).Commit())
+	PutInStorage(o)
+}
+
+type result struct {
+	Command
+	score int
+}
+
+// results returns the commands matching the current query, ranked.
+func (p PaletteElement) results() []result {
+	q := p.query()
+	usage := p.usage()
+	now := time.Now().Unix()
+	var res []result
+	p.commands().Range(func(id string, _ ui.Value) bool {
+		c, _ := p.command(id)
+		best, ok := FuzzyScore(q, c.Label)
+		for _, k := range c.Keywords {
+			if s, match := FuzzyScore(q, k); match && (!ok || s > best) {
+				best, ok = s, true
+			}
+		}
+		if !ok {
+			return false
+		}
+		if v, ok := usage.Get(c.ID); ok {
+			u := v.(ui.Object)
+			best += 2 * int(u.MustGetNumber("count"))
+			if now-int64(u.MustGetNumber("last")) < 24*3600 {
+				best += 10
+			}
+		}
+		res = append(res, result{c, best})
+		return false
+	})
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].score == res[j].score {
+			return res[i].Label < res[j].Label
+		}
+		return res[i].score > res[j].score
+	})
+	if len(res) > MaxResults {
+		res = res[:MaxResults]
+	}
+	return res
+}
+
+func (p PaletteElement) refresh() {
+	d := GetDocument(p.AsElement())
+	listbox := d.GetElementById(p.AsElement().ID + "-listbox")
+	if listbox == nil {
+		return
+	}
+	listbox.DeleteChildren()
+	results := p.results()
+	items := make([]*ui.Element, 0, len(results))
+	for i, r := range results {
+		cmdid := r.ID
+		li := d.Li.WithID(p.AsElement().ID + "-option-" + strconv.Itoa(i))
+		SetAttribute(li.AsElement(), "role", "option")
+		SetAttribute(li.AsElement(), "aria-selected", "false")
+		li.AsElement().SetChildren(d.Span.WithID(li.AsElement().ID + "-label").SetText(r.Label).AsElement())
+		li.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+			p.Run(cmdid)
+			return false
+		}))
+		items = append(items, li.AsElement())
+	}
+	listbox.SetChildren(items...)
+	if len(items) > 0 {
+		p.AsElement().SetData("selected", ui.Number(0))
+	} else {
+		p.AsElement().SetData("selected", ui.Number(-1))
+	}
+}
+
+// FuzzyScore returns whether the pattern is a (case insensitive) subsequence of the text and, if so,
+// a score measuring the quality of the match. Consecutive matches and matches at the beginning of words
+// score higher. An empty pattern matches everything with a score of zero.
+func FuzzyScore(pattern, text string) (int, bool) {
+	pr := []rune(strings.ToLower(pattern))
+	if len(pr) == 0 {
+		return 0, true
+	}
+	tr := []rune(text)
+	score := 0
+	j := 0
+	prevmatch := -2
+	for i, r := range tr {
+		if j >= len(pr) {
+			break
+		}
+		if unicode.ToLower(r) != pr[j] {
+			continue
+		}
+		score++
+		if prevmatch == i-1 {
+			score += 3
+		}
+		if i == 0 || !unicode.IsLetter(tr[i-1]) && !unicode.IsDigit(tr[i-1]) || unicode.IsUpper(r) && unicode.IsLower(tr[i-1]) {
+			score += 5
+		}
+		prevmatch = i
+		j++
+	}
+	if j < len(pr) {
+		return 0, false
+	}
+	return score, true
+}
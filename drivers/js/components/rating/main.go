@@ -0,0 +1,164 @@
+// package rating provides a star rating input.
+package rating
+
+import (
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// A rating is a radio group of stars. Clicking a star sets the rating, clicking the current star again
+// clears it. Once focused, the arrow keys change the rating, Home and End set it to the bounds.
+// The color of the stars can be themed with the --zui-rating-color and --zui-rating-empty-color CSS variables.
+//
+// Data properties of the rating element:
+//   - "value" (ui.Number): the rating, between 0 (not rated) and the number of stars
+//
+// Events triggered on the rating element:
+//   - "rating-change": the rating was changed by the user. The event value is the rating.
+
+// StyleSheetID is the id of the stylesheet holding the rating rules.
+const StyleSheetID = "zui-rating"
+
+type RatingElement struct {
+	*ui.Element
+}
+
+// New returns a rating input with the given number of stars.
+func New(d *Document, id string, label string, stars int) RatingElement {
+	if stars <= 0 {
+		stars = 5
+	}
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/rating")
+	AddClass(e, "zui-rating")
+	SetAttribute(e, "role", "radiogroup")
+	SetAttribute(e, "aria-label", label)
+	r := RatingElement{e}
+
+	buttons := make([]*ui.Element, stars)
+	for i := range buttons {
+		n := i + 1
+		b := d.Button.WithID(id+"-star-"+strconv.Itoa(n), "button").SetText("★").AsElement()
+		AddClass(b, "zui-rating-star")
+		SetAttribute(b, "role", "radio")
+		SetAttribute(b, "aria-label", strconv.Itoa(n)+" of "+strconv.Itoa(stars))
+		b.AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+			if r.Value() == n {
+				r.change(0)
+				return false
+			}
+			r.change(n)
+			return false
+		}))
+		b.AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+			k, ok := evt.(KeyboardEvent)
+			if !ok {
+				return false
+			}
+			v := r.Value()
+			switch k.Key() {
+			case "ArrowRight", "ArrowUp":
+				v++
+			case "ArrowLeft", "ArrowDown":
+				v--
+			case "Home":
+				v = 1
+			case "End":
+				v = stars
+			default:
+				return false
+			}
+			evt.PreventDefault()
+			if v < 1 {
+				v = 1
+			}
+			if v > stars {
+				v = stars
+			}
+			r.change(v)
+			SetFocus(buttons[v-1], false)
+			return false
+		}))
+		buttons[i] = b
+	}
+	e.SetChildren(buttons...)
+	style(d)
+
+	e.Watch(Namespace.Data, "value", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		v := int(evt.NewValue().(ui.Number))
+		for i, b := range buttons {
+			// roving tabindex: only the checked star, or the first one, is in the tab sequence.
+			if i+1 == v || (v == 0 && i == 0) {
+				SetAttribute(b, "tabindex", "0")
+			} else {
+				SetAttribute(b, "tabindex", "-1")
+			}
+			if i+1 == v {
+				SetAttribute(b, "aria-checked", "true")
+			} else {
+				SetAttribute(b, "aria-checked", "false")
+			}
+			if i < v {
+				AddClass(b, "zui-rating-filled")
+			} else {
+				RemoveClass(b, "zui-rating-filled")
+			}
+		}
+		return false
+	}))
+	e.SetData("value", ui.Number(0))
+
+	return r
+}
+
+// Value returns the rating.
+func (r RatingElement) Value() int {
+	v, ok := r.AsElement().GetData("value")
+	if !ok {
+		return 0
+	}
+	return int(v.(ui.Number))
+}
+
+// SetValue changes the rating.
+func (r RatingElement) SetValue(v int) RatingElement {
+	r.AsElement().SetData("value", ui.Number(v))
+	return r
+}
+
+// OnChange registers a handler called when the rating is changed by the user.
+func (r RatingElement) OnChange(h *ui.MutationHandler) RatingElement {
+	r.AsElement().WatchEvent("rating-change", r, h)
+	return r
+}
+
+func (r RatingElement) change(v int) {
+	r.SetValue(v)
+	r.AsElement().TriggerEvent("rating-change", ui.Number(v))
+}
+
+var rules = map[string]string{
+	":where(.zui-rating)":                        "display: inline-flex; gap: 0.125rem;",
+	":where(.zui-rating-star)":                   "padding: 0; border: none; background: none; cursor: pointer; font-size: 1.5rem; line-height: 1; color: var(--zui-rating-empty-color, #0003);",
+	":where(.zui-rating-star.zui-rating-filled)": "color: var(--zui-rating-color, gold);",
+	// hovering previews the rating
+	":where(.zui-rating:hover .zui-rating-star)":                    "color: var(--zui-rating-color, gold);",
+	":where(.zui-rating .zui-rating-star:hover ~ .zui-rating-star)": "color: var(--zui-rating-empty-color, #0003);",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.Update()
+}
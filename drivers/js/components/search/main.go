@@ -0,0 +1,428 @@
+// package search is a package that provides a search input component with asynchronous suggestions.
+package search
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// A search box is made of an input and a listbox of suggestions.
+// Suggestions are fetched asynchronously, after the user stops typing for a while (debounce).
+// A stale response (i.e. for a query that is not the current one anymore) is discarded.
+// The suggestions can be navigated with the keyboard (ArrowUp/ArrowDown, Enter to select, Escape to close).
+// While the query is empty, the listbox displays the recent searches instead (zui-search-recent class).
+// Choosing one of them makes it the query. The list of recent searches is persisted in localstorage.
+//
+// Data properties of the box element:
+//   - "query" (ui.String): the current query
+//   - "suggestions" (ui.List): the current suggestions
+//   - "selected" (ui.Number): index of the highlighted suggestion, -1 if none
+//   - "value": the last selected suggestion
+//
+// UI properties of the box element:
+//   - "loading" (ui.Bool): whether suggestions are being fetched. It is reflected by the aria-busy attribute.
+//
+// Events triggered on the box element:
+//   - "search-select": a suggestion was chosen. The event value is the suggestion.
+//   - "search-error": fetching suggestions failed. The event value is the error message.
+
+// Fetcher retrieves the suggestions for a given query.
+type Fetcher func(ctx context.Context, query string) (ui.List, error)
+
+// Renderer creates the element displaying a suggestion within its listbox option.
+type Renderer func(d *Document, id string, suggestion ui.Value, query string) *ui.Element
+
+// DefaultDebounce is the delay after the last keystroke before suggestions are fetched.
+var DefaultDebounce = 250 * time.Millisecond
+
+// MaxRecent is the maximum number of recent searches that are remembered.
+var MaxRecent = 10
+
+type BoxElement struct {
+	*ui.Element
+}
+
+type config struct {
+	debounce time.Duration
+	renderer Renderer
+	minchars int
+}
+
+// Option allows to configure a search box.
+type Option func(*config)
+
+// WithDebounce changes the delay after the last keystroke before suggestions are fetched.
+func WithDebounce(d time.Duration) Option {
+	return func(c *config) { c.debounce = d }
+}
+
+// WithRenderer allows to customize the rendering of suggestions.
+func WithRenderer(r Renderer) Option {
+	return func(c *config) { c.renderer = r }
+}
+
+// WithMinChars sets the minimum length of a query for which suggestions are fetched.
+func WithMinChars(n int) Option {
+	return func(c *config) { c.minchars = n }
+}
+
+// Box returns a search box whose suggestions are retrieved via the fetch function.
+func Box(d *Document, id string, fetch Fetcher, options ...Option) BoxElement {
+	cfg := &config{DefaultDebounce, DefaultRenderer, 1}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	box := d.Div.WithID(id)
+	MarkComponent(box.AsElement(), "github.com/atdiar/particleui/drivers/js/components/search")
+	AddClass(box.AsElement(), "zui-search")
+	box.AsElement().SetData("selected", ui.Number(-1))
+
+	input := d.Input.WithID(id+"-input", "search")
+	listbox := d.Ul.WithID(id + "-listbox")
+	recent := d.NewObservable(id+"-recent", EnableLocalPersistence())
+
+	SetAttribute(input.AsElement(), "role", "combobox")
+	SetAttribute(input.AsElement(), "autocomplete", "off")
+	SetAttribute(input.AsElement(), "aria-autocomplete", "list")
+	SetAttribute(input.AsElement(), "aria-controls", listbox.AsElement().ID)
+	SetAttribute(input.AsElement(), "aria-expanded", "false")
+	SetAttribute(listbox.AsElement(), "role", "listbox")
+	AddClass(listbox.AsElement(), "zui-search-listbox")
+
+	b := BoxElement{box.AsElement()}
+
+	input.AsElement().AddEventListener("input", ui.NewEventHandler(func(evt ui.Event) bool {
+		v, ok := evt.Value().(ui.Object).Get("value")
+		if !ok {
+			return false
+		}
+		b.AsElement().SetData("query", v)
+		return false
+	}))
+
+	input.AsElement().AddEventListener("focus", ui.NewEventHandler(func(evt ui.Event) bool {
+		if strings.TrimSpace(b.Query()) == "" {
+			b.AsElement().SetData("suggestions", recentSearches(recent.AsElement()))
+		}
+		return false
+	}))
+
+	// the query is only processed once the user stops typing.
+	// requests is the number of fetches in flight: loading is only over once the last one has returned.
+	var requests int
+	b.AsElement().Watch(Namespace.Data, "query", b, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		query := strings.TrimSpace(string(evt.NewValue().(ui.String)))
+		if query == "" {
+			b.AsElement().SetUI("loading", ui.Bool(false))
+			b.AsElement().SetData("suggestions", recentSearches(recent.AsElement()))
+			return false
+		}
+		if len([]rune(query)) < cfg.minchars {
+			b.AsElement().SetUI("loading", ui.Bool(false))
+			b.AsElement().SetData("suggestions", ui.NewList().Commit())
+			return false
+		}
+		b.AsElement().SetUI("loading", ui.Bool(true))
+		requests++
+		ui.DoAsync(b.AsElement(), func(ctx context.Context) {
+			suggestions, err := fetch(ctx, query)
+			ui.DoSync(func() {
+				requests--
+				if q, ok := b.AsElement().GetData("query"); !ok || strings.TrimSpace(string(q.(ui.String))) != query {
+					// stale response
+					if requests == 0 {
+						b.AsElement().SetUI("loading", ui.Bool(false))
+					}
+					return
+				}
+				b.AsElement().SetUI("loading", ui.Bool(false))
+				if err != nil {
+					b.AsElement().TriggerEvent("search-error", ui.String(err.Error()))
+					return
+				}
+				b.AsElement().SetData("suggestions", suggestions)
+			})
+		})
+		return false
+	}).Debounce(cfg.debounce))
+
+	b.AsElement().Watch(Namespace.UI, "loading", b, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		SetAttribute(b.AsElement(), "aria-busy", strconv.FormatBool(bool(evt.NewValue().(ui.Bool))))
+		return false
+	}))
+
+	b.AsElement().Watch(Namespace.Data, "suggestions", b, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		suggestions := evt.NewValue().(ui.List)
+		query := b.Query()
+		listbox.AsElement().DeleteChildren()
+		options := make([]*ui.Element, 0, len(suggestions.UnsafelyUnwrap()))
+		for i, s := range suggestions.UnsafelyUnwrap() {
+			i := i
+			oid := id + "-option-" + strconv.Itoa(i)
+			li := d.Li.WithID(oid)
+			SetAttribute(li.AsElement(), "role", "option")
+			SetAttribute(li.AsElement(), "aria-selected", "false")
+			li.AsElement().SetChildren(cfg.renderer(d, oid+"-content", s, query))
+			li.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+				b.choose(i, input, recent)
+				return false
+			}))
+			options = append(options, li.AsElement())
+		}
+		listbox.AsElement().SetChildren(options...)
+		if strings.TrimSpace(query) == "" {
+			AddClass(listbox.AsElement(), "zui-search-recent")
+		} else {
+			RemoveClass(listbox.AsElement(), "zui-search-recent")
+		}
+		b.AsElement().SetData("selected", ui.Number(-1))
+		SetAttribute(input.AsElement(), "aria-expanded", strconv.FormatBool(len(options) > 0))
+		return false
+	}))
+
+	b.AsElement().Watch(Namespace.Data, "selected", b, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		idx := int(evt.NewValue().(ui.Number))
+		for i, li := range listbox.AsElement().Children.List {
+			if i == idx {
+				SetAttribute(li, "aria-selected", "true")
+				AddClass(li, "zui-search-selected")
+				SetAttribute(input.AsElement(), "aria-activedescendant", li.ID)
+				continue
+			}
+			SetAttribute(li, "aria-selected", "false")
+			RemoveClass(li, "zui-search-selected")
+		}
+		if idx < 0 {
+			RemoveAttribute(input.AsElement(), "aria-activedescendant")
+		}
+		return false
+	}))
+
+	input.AsElement().AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		k, ok := evt.(KeyboardEvent)
+		if !ok {
+			return false
+		}
+		n := len(b.Suggestions().UnsafelyUnwrap())
+		sel := b.selected()
+		switch k.Key() {
+		case "ArrowDown":
+			evt.PreventDefault()
+			if n > 0 {
+				b.AsElement().SetData("selected", ui.Number((sel+1)%n))
+			}
+		case "ArrowUp":
+			evt.PreventDefault()
+			if n > 0 {
+				if sel <= 0 {
+					sel = n
+				}
+				b.AsElement().SetData("selected", ui.Number(sel-1))
+			}
+		case "Enter":
+			if sel >= 0 && sel < n {
+				evt.PreventDefault()
+				b.choose(sel, input, recent)
+			}
+		case "Escape":
+			b.AsElement().SetData("suggestions", ui.NewList().Commit())
+		}
+		return false
+	}))
+
+	box.AsElement().SetChildren(input.AsElement(), listbox.AsElement())
+	return b
+}
+
+// Query returns the current query.
+func (b BoxElement) Query() string {
+	v, ok := b.AsElement().GetData("query")
+	if !ok {
+		return ""
+	}
+	return string(v.(ui.String))
+}
+
+// Suggestions returns the current list of suggestions.
+func (b BoxElement) Suggestions() ui.List {
+	v, ok := b.AsElement().GetData("suggestions")
+	if !ok {
+		return ui.NewList().Commit()
+	}
+	return v.(ui.List)
+}
+
+// Recent returns the list of recent searches, most recent first.
+func (b BoxElement) Recent() ui.List {
+	r := GetDocument(b.AsElement()).GetElementById(b.AsElement().ID + "-recent")
+	if r == nil {
+		return ui.NewList().Commit()
+	}
+	return recentSearches(r)
+}
+
+// ClearRecent removes the recent searches.
+func (b BoxElement) ClearRecent() BoxElement {
+	r := GetDocument(b.AsElement()).GetElementById(b.AsElement().ID + "-recent")
+	if r != nil {
+		r.SetData("recent", ui.NewList().Commit())
+		PutInStorage(r)
+	}
+	return b
+}
+
+// OnSelect registers a handler that is called when a suggestion has been chosen.
+func (b BoxElement) OnSelect(h *ui.MutationHandler) BoxElement {
+	b.AsElement().WatchEvent("search-select", b, h)
+	return b
+}
+
+// OnError registers a handler that is called when suggestions could not be fetched.
+func (b BoxElement) OnError(h *ui.MutationHandler) BoxElement {
+	b.AsElement().WatchEvent("search-error", b, h)
+	return b
+}
+
+func (b BoxElement) selected() int {
+	v, ok := b.AsElement().GetData("selected")
+	if !ok {
+		return -1
+	}
+	return int(v.(ui.Number))
+}
+
+func recentSearches(recent *ui.Element) ui.List {
+	v, ok := recent.GetData("recent")
+	if !ok {
+		return ui.NewList().Commit()
+	}
+	return v.(ui.List)
+}
+
+func (b BoxElement) choose(i int, input InputElement, recent ui.Observable) {
+	suggestions := b.Suggestions().UnsafelyUnwrap()
+	if i < 0 || i >= len(suggestions) {
+		return
+	}
+	s := suggestions[i]
+
+	// a recent search becomes the query.
+	if strings.TrimSpace(b.Query()) == "" {
+		input.AsElement().SetUI("value", s)
+		b.AsElement().SetData("query", s)
+		return
+	}
+	b.AsElement().SetData("value", s)
+
+	// recent searches are deduplicated, most recent first.
+	query := ui.String(b.Query())
+	l := ui.NewList(query)
+	if v, ok := recent.AsElement().GetData("recent"); ok {
+		for _, r := range v.(ui.List).UnsafelyUnwrap() {
+			if ui.Equal(r, query) {
+				continue
+			}
+			if len(l.UnsafelyUnwrap()) >= MaxRecent {
+				break
+			}
+			l.Append(r)
+		}
+	}
+	recent.AsElement().SetData("recent", l.Commit())
+	PutInStorage(recent.AsElement())
+
+	b.AsElement().SetData("suggestions", ui.NewList().Commit())
+	b.AsElement().TriggerEvent("search-select", s)
+}
+
+// DefaultRenderer displays a suggestion as text, highlighting the occurences of the query terms.
+// A suggestion is either a ui.String or a ui.Object with a "label" string field.
+func DefaultRenderer(d *Document, id string, suggestion ui.Value, query string) *ui.Element {
+	var label string
+	switch s := suggestion.(type) {
+	case ui.String:
+		label = string(s)
+	case ui.Object:
+		if l, ok := s.Get("label"); ok {
+			label = string(l.(ui.String))
+		}
+	}
+
+	container := d.Span.WithID(id)
+	parts := Highlight(label, query)
+	children := make([]*ui.Element, 0, len(parts))
+	for i, p := range parts {
+		span := d.Span.WithID(id + "-" + strconv.Itoa(i)).SetText(p.Text)
+		if p.Match {
+			AddClass(span.AsElement(), "zui-search-highlight")
+		}
+		children = append(children, span.AsElement())
+	}
+	container.AsElement().SetChildren(children...)
+	return container.AsElement()
+}
+
+// Fragment is a part of a text that either matches a query term or not.
+type Fragment struct {
+	Text  string
+	Match bool
+}
+
+// Highlight splits a text into fragments, marking the ones matching any of the query terms (case insensitive).
+// Text and terms are folded rune by rune, so that the fragments remain aligned with the original text even
+// when the case mapping of a rune changes its encoded length.
+func Highlight(text string, query string) []Fragment {
+	terms := strings.Fields(query)
+	if len(terms) == 0 || text == "" {
+		return []Fragment{{text, false}}
+	}
+	// offsets[i] is the byte offset of the i-th rune of the text
+	var folded []rune
+	var offsets []int
+	for i, r := range text {
+		folded = append(folded, unicode.ToLower(r))
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+
+	match := make([]bool, len(folded))
+	for _, t := range terms {
+		term := []rune(strings.Map(unicode.ToLower, t))
+		for i := 0; i+len(term) <= len(folded); {
+			if !equalRunes(folded[i:i+len(term)], term) {
+				i++
+				continue
+			}
+			for k := i; k < i+len(term); k++ {
+				match[k] = true
+			}
+			i += len(term)
+		}
+	}
+
+	var res []Fragment
+	start := 0
+	for i := 1; i <= len(folded); i++ {
+		if i == len(folded) || match[i] != match[start] {
+			res = append(res, Fragment{text[offsets[start]:offsets[i]], match[start]})
+			start = i
+		}
+	}
+	return res
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
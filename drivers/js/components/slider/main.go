@@ -0,0 +1,430 @@
+// package slider provides a slider component with one or two handles.
+package slider
+
+import (
+	"math"
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// A slider allows to select a value, or a range of values with two handles, between a minimum and a maximum.
+// Values are snapped to the step of the slider. With two handles, they cannot cross each other.
+// Each handle follows the WAI-ARIA slider pattern: arrow keys move it by one step, PageUp and PageDown by
+// ten steps, Home and End to the bounds. A pointer press on the track moves the closest handle.
+// Tick marks and tooltips displaying the value of the handles are optional.
+//
+// Data properties of the slider element:
+//   - "values" (ui.List): the values of the handles, as ui.Number, in increasing order
+//
+// Events triggered on the slider element:
+//   - "slider-change": a handle was released or moved with the keyboard. The event value is the list of values.
+
+// StyleSheetID is the id of the stylesheet holding the slider rules.
+const StyleSheetID = "zui-slider"
+
+type SliderElement struct {
+	*ui.Element
+}
+
+type config struct {
+	min, max, step float64
+	ticks          float64
+	tooltips       bool
+	vertical       bool
+	labels         []string
+	format         func(float64) string
+}
+
+// Option allows to configure a slider.
+type Option func(*config)
+
+// WithStep sets the granularity of the slider values. The default step is 1.
+func WithStep(step float64) Option {
+	return func(c *config) { c.step = step }
+}
+
+// WithTicks displays a tick mark at every multiple of the given interval.
+func WithTicks(interval float64) Option {
+	return func(c *config) { c.ticks = interval }
+}
+
+// WithTooltips displays the value of a handle above it, formatted with the given function if not nil.
+func WithTooltips(format func(float64) string) Option {
+	return func(c *config) {
+		c.tooltips = true
+		if format != nil {
+			c.format = format
+		}
+	}
+}
+
+// WithVertical lays the slider out vertically, the minimum being at the bottom.
+func WithVertical() Option {
+	return func(c *config) { c.vertical = true }
+}
+
+// WithLabels sets the accessible labels of the handles.
+func WithLabels(labels ...string) Option {
+	return func(c *config) { c.labels = labels }
+}
+
+// configs holds the configuration of each slider, indexed by slider id.
+var configs = make(map[string]*config)
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// New returns a slider between min and max. It has as many handles as initial values, one or two.
+func New(d *Document, id string, min, max float64, values []float64, options ...Option) SliderElement {
+	cfg := &config{min: min, max: max, step: 1, format: formatNumber}
+	for _, opt := range options {
+		opt(cfg)
+	}
+	if max <= min {
+		panic("slider: max must be greater than min")
+	}
+	if len(values) == 0 {
+		values = []float64{min}
+	}
+	if len(values) > 2 {
+		DEBUG("slider: only two handles are supported, extra values are ignored")
+		values = values[:2]
+	}
+
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/slider")
+	AddClass(e, "zui-slider")
+	orientation := "horizontal"
+	if cfg.vertical {
+		orientation = "vertical"
+	}
+	SetAttribute(e, "data-orientation", orientation)
+	s := SliderElement{e}
+	configs[id] = cfg
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		delete(configs, id)
+		return false
+	}).RunOnce())
+
+	track := d.Div.WithID(id + "-track").AsElement()
+	AddClass(track, "zui-slider-track")
+	fill := d.Div.WithID(id + "-fill").AsElement()
+	AddClass(fill, "zui-slider-fill")
+	trackChildren := []*ui.Element{fill}
+
+	if cfg.ticks > 0 {
+		ticks := d.Div.WithID(id + "-ticks").AsElement()
+		AddClass(ticks, "zui-slider-ticks")
+		SetAttribute(ticks, "aria-hidden", "true")
+		var marks []*ui.Element
+		for i, v := 0, min; v <= max+cfg.ticks/1e6; i, v = i+1, min+float64(i+1)*cfg.ticks {
+			mark := d.Span.WithID(id + "-tick-" + strconv.Itoa(i)).AsElement()
+			AddClass(mark, "zui-slider-tick")
+			SetCSSVariable(mark, "zui-slider-pos", s.percent(v))
+			marks = append(marks, mark)
+		}
+		ticks.SetChildren(marks...)
+		trackChildren = append(trackChildren, ticks)
+	}
+
+	handles := make([]*ui.Element, len(values))
+	for i := range values {
+		h := d.Div.WithID(id + "-handle-" + strconv.Itoa(i)).AsElement()
+		AddClass(h, "zui-slider-handle")
+		SetAttribute(h, "role", "slider")
+		SetAttribute(h, "tabindex", "0")
+		SetAttribute(h, "aria-orientation", orientation)
+		if i < len(cfg.labels) {
+			SetAttribute(h, "aria-label", cfg.labels[i])
+		}
+		if cfg.tooltips {
+			tip := d.Span.WithID(id + "-handle-" + strconv.Itoa(i) + "-tooltip")
+			AddClass(tip.AsElement(), "zui-slider-tooltip")
+			SetAttribute(tip.AsElement(), "aria-hidden", "true")
+			h.SetChildren(tip.AsElement())
+		}
+
+		idx := i
+		h.AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+			k, ok := evt.(KeyboardEvent)
+			if !ok {
+				return false
+			}
+			v := s.Values()[idx]
+			switch k.Key() {
+			case "ArrowRight", "ArrowUp":
+				v += cfg.step
+			case "ArrowLeft", "ArrowDown":
+				v -= cfg.step
+			case "PageUp":
+				v += 10 * cfg.step
+			case "PageDown":
+				v -= 10 * cfg.step
+			case "Home":
+				v = cfg.min
+			case "End":
+				v = cfg.max
+			default:
+				return false
+			}
+			evt.PreventDefault()
+			s.SetValue(idx, v)
+			s.changed()
+			return false
+		}))
+		handles[i] = h
+	}
+	track.SetChildren(append(trackChildren, handles...)...)
+	e.SetChildren(track)
+	style(d)
+
+	e.Watch(Namespace.Data, "values", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		vals := s.Values()
+		for i, h := range handles {
+			if i >= len(vals) {
+				break
+			}
+			v := vals[i]
+			SetCSSVariable(h, "zui-slider-pos", s.percent(v))
+			SetAttribute(h, "aria-valuenow", formatNumber(v))
+			SetAttribute(h, "aria-valuetext", cfg.format(v))
+			// with two handles, each of them is bounded by the other one.
+			lo, hi := cfg.min, cfg.max
+			if len(handles) == 2 {
+				if i == 0 {
+					hi = vals[1]
+				} else {
+					lo = vals[0]
+				}
+			}
+			SetAttribute(h, "aria-valuemin", formatNumber(lo))
+			SetAttribute(h, "aria-valuemax", formatNumber(hi))
+			if cfg.tooltips {
+				if tip := d.GetElementById(h.ID + "-tooltip"); tip != nil {
+					SpanElement{Element: tip}.SetText(cfg.format(v))
+				}
+			}
+		}
+		start, end := cfg.min, vals[0]
+		if len(vals) == 2 {
+			start, end = vals[0], vals[1]
+		}
+		SetCSSVariable(e, "zui-slider-start", s.percent(start))
+		SetCSSVariable(e, "zui-slider-end", s.percent(end))
+		return false
+	}))
+
+	// pointer interaction
+	active := -1
+	move := func(evt ui.Event) {
+		m, ok := evt.(MouseEvent)
+		if !ok {
+			return
+		}
+		n, ok := JSValue(track)
+		if !ok {
+			return
+		}
+		rect := n.Call("getBoundingClientRect")
+		var ratio float64
+		if cfg.vertical {
+			h := rect.Get("height").Float()
+			if h <= 0 {
+				return
+			}
+			ratio = (rect.Get("bottom").Float() - m.ClientY()) / h
+		} else {
+			w := rect.Get("width").Float()
+			if w <= 0 {
+				return
+			}
+			ratio = (m.ClientX() - rect.Get("left").Float()) / w
+		}
+		v := cfg.min + math.Max(0, math.Min(1, ratio))*(cfg.max-cfg.min)
+		if active < 0 {
+			active = s.closest(v)
+		}
+		s.SetValue(active, v)
+	}
+	track.AddEventListener("pointerdown", ui.NewEventHandler(func(evt ui.Event) bool {
+		m, ok := evt.(MouseEvent)
+		if !ok || m.Button() != 0 {
+			return false
+		}
+		if nevt, ok := evt.Native().(NativeEvent); ok {
+			if n, ok := JSValue(track); ok {
+				n.Call("setPointerCapture", nevt.Value.Get("pointerId"))
+			}
+		}
+		evt.PreventDefault()
+		active = -1
+		move(evt)
+		if active >= 0 {
+			SetAttribute(e, "data-dragging", "")
+			SetFocus(handles[active], false)
+		}
+		return false
+	}))
+	track.AddEventListener("pointermove", ui.NewEventHandler(func(evt ui.Event) bool {
+		if active >= 0 {
+			move(evt)
+		}
+		return false
+	}))
+	stop := ui.NewEventHandler(func(evt ui.Event) bool {
+		if active < 0 {
+			return false
+		}
+		active = -1
+		RemoveAttribute(e, "data-dragging")
+		s.changed()
+		return false
+	})
+	track.AddEventListener("pointerup", stop)
+	track.AddEventListener("pointercancel", stop)
+
+	l := ui.NewList()
+	for _, v := range values {
+		l.Append(ui.Number(s.snap(v)))
+	}
+	vals := l.Commit()
+	if len(values) == 2 && s.valuesOf(vals)[0] > s.valuesOf(vals)[1] {
+		vals = ui.NewList(vals.UnsafelyUnwrap()[1], vals.UnsafelyUnwrap()[0]).Commit()
+	}
+	e.SetData("values", vals)
+
+	return s
+}
+
+func (s SliderElement) config() *config {
+	if cfg, ok := configs[s.AsElement().ID]; ok {
+		return cfg
+	}
+	return &config{max: 100, step: 1, format: formatNumber}
+}
+
+func (s SliderElement) percent(v float64) string {
+	cfg := s.config()
+	return strconv.FormatFloat((v-cfg.min)/(cfg.max-cfg.min)*100, 'f', 2, 64) + "%"
+}
+
+func (s SliderElement) snap(v float64) float64 {
+	cfg := s.config()
+	if cfg.step > 0 {
+		v = cfg.min + math.Round((v-cfg.min)/cfg.step)*cfg.step
+	}
+	return math.Max(cfg.min, math.Min(cfg.max, v))
+}
+
+func (s SliderElement) valuesOf(l ui.List) []float64 {
+	res := make([]float64, 0, 2)
+	for _, v := range l.UnsafelyUnwrap() {
+		res = append(res, float64(v.(ui.Number)))
+	}
+	return res
+}
+
+// closest returns the index of the handle closest to a value.
+func (s SliderElement) closest(v float64) int {
+	vals := s.Values()
+	if len(vals) < 2 {
+		return 0
+	}
+	switch {
+	case v <= vals[0]:
+		return 0
+	case v >= vals[1]:
+		return 1
+	case v-vals[0] < vals[1]-v:
+		return 0
+	}
+	return 1
+}
+
+// Values returns the values of the handles.
+func (s SliderElement) Values() []float64 {
+	v, ok := s.AsElement().GetData("values")
+	if !ok {
+		return []float64{s.config().min}
+	}
+	return s.valuesOf(v.(ui.List))
+}
+
+// Value returns the value of the first handle.
+func (s SliderElement) Value() float64 {
+	return s.Values()[0]
+}
+
+// SetValue changes the value of a handle. The value is snapped to the step of the slider and kept within
+// its bounds.
+func (s SliderElement) SetValue(handle int, v float64) SliderElement {
+	vals := s.Values()
+	if handle < 0 || handle >= len(vals) {
+		return s
+	}
+	v = s.snap(v)
+	if len(vals) == 2 {
+		if handle == 0 {
+			v = math.Min(v, vals[1])
+		} else {
+			v = math.Max(v, vals[0])
+		}
+	}
+	if vals[handle] == v {
+		return s
+	}
+	vals[handle] = v
+	l := ui.NewList()
+	for _, val := range vals {
+		l.Append(ui.Number(val))
+	}
+	s.AsElement().SetData("values", l.Commit())
+	return s
+}
+
+// OnChange registers a handler called when the user has changed the values.
+func (s SliderElement) OnChange(h *ui.MutationHandler) SliderElement {
+	s.AsElement().WatchEvent("slider-change", s, h)
+	return s
+}
+
+func (s SliderElement) changed() {
+	v, ok := s.AsElement().GetData("values")
+	if !ok {
+		return
+	}
+	s.AsElement().TriggerEvent("slider-change", v)
+}
+
+var rules = map[string]string{
+	":where(.zui-slider)":                                               "position: relative; padding: 0.75rem; touch-action: none;",
+	":where(.zui-slider[data-orientation=vertical])":                    "height: 12rem; width: max-content;",
+	":where(.zui-slider-track)":                                         "position: relative; height: 0.25rem; background: #0003; border-radius: 0.125rem; cursor: pointer;",
+	":where(.zui-slider[data-orientation=vertical] .zui-slider-track)":  "height: 100%; width: 0.25rem;",
+	":where(.zui-slider-fill)":                                          "position: absolute; top: 0; bottom: 0; left: var(--zui-slider-start); right: calc(100% - var(--zui-slider-end)); background: Highlight;",
+	":where(.zui-slider[data-orientation=vertical] .zui-slider-fill)":   "left: 0; right: 0; top: calc(100% - var(--zui-slider-end)); bottom: var(--zui-slider-start);",
+	":where(.zui-slider-tick)":                                          "position: absolute; top: 0.5rem; left: var(--zui-slider-pos); width: 1px; height: 0.375rem; background: currentColor;",
+	":where(.zui-slider[data-orientation=vertical] .zui-slider-tick)":   "top: auto; left: 0.5rem; bottom: var(--zui-slider-pos); width: 0.375rem; height: 1px;",
+	":where(.zui-slider-handle)":                                        "position: absolute; top: 50%; left: var(--zui-slider-pos); width: 1rem; height: 1rem; border-radius: 50%; background: Canvas; border: 2px solid Highlight; transform: translate(-50%, -50%);",
+	":where(.zui-slider[data-orientation=vertical] .zui-slider-handle)": "top: auto; left: 50%; bottom: var(--zui-slider-pos); transform: translate(-50%, 50%);",
+	":where(.zui-slider-tooltip)":                                       "position: absolute; bottom: calc(100% + 0.25rem); left: 50%; transform: translateX(-50%); padding: 0.125rem 0.25rem; white-space: nowrap; background: CanvasText; color: Canvas; font-size: 0.75rem; opacity: 0; pointer-events: none;",
+	":where(.zui-slider-handle:hover .zui-slider-tooltip, .zui-slider-handle:focus-visible .zui-slider-tooltip, .zui-slider[data-dragging] .zui-slider-tooltip)": "opacity: 1;",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.Update()
+}
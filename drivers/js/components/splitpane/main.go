@@ -0,0 +1,378 @@
+// package splitpane provides resizable split panes.
+package splitpane
+
+import (
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// A split displays two panes side by side (Horizontal) or on top of each other (Vertical), separated by a
+// divider that can be dragged with a pointer. The size of the first pane is a percentage of the split,
+// constrained between a minimum and a maximum.
+// The divider follows the WAI-ARIA window splitter pattern: it is focusable, the arrow keys resize the panes,
+// Home and End move it to the constraints and Enter collapses or expands the first pane.
+// A double-click on the divider resets the size to its default value.
+//
+// The size is persisted in localstorage per split id, so that it is restored on reload.
+// Splits can be nested, a pane being itself a split.
+//
+// Data properties of the split element:
+//   - "size" (ui.Number): size of the first pane, in percent
+//   - "collapsed" (ui.Bool): whether the first pane is collapsed
+//
+// UI properties of the split element, updated whenever the panes are laid out, so that their content (e.g.
+// an editor) can adapt:
+//   - "first-size", "second-size" (ui.Number): size of each pane in pixels, along the split axis
+
+// Orientations
+const (
+	Horizontal = "horizontal"
+	Vertical   = "vertical"
+)
+
+// StyleSheetID is the id of the stylesheet holding the split rules.
+const StyleSheetID = "zui-splitpane"
+
+const keyboardStep = 5
+
+type SplitElement struct {
+	*ui.Element
+}
+
+type config struct {
+	min, max, initial float64
+}
+
+// Option allows to configure a split.
+type Option func(*config)
+
+// WithBounds constrains the size of the first pane, in percent.
+func WithBounds(min, max float64) Option {
+	return func(c *config) { c.min, c.max = min, max }
+}
+
+// WithDefault sets the default size of the first pane, in percent.
+func WithDefault(size float64) Option {
+	return func(c *config) { c.initial = size }
+}
+
+// configs holds the configuration of each split, indexed by split id.
+var configs = make(map[string]*config)
+
+// New returns a split of the two given panes.
+func New(d *Document, id string, orientation string, first, second *ui.Element, options ...Option) SplitElement {
+	cfg := &config{min: 0, max: 100, initial: 50}
+	for _, opt := range options {
+		opt(cfg)
+	}
+	if orientation != Vertical {
+		orientation = Horizontal
+	}
+
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/splitpane")
+	AddClass(e, "zui-split")
+	SetAttribute(e, "data-orientation", orientation)
+	s := SplitElement{e}
+	configs[id] = cfg
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		delete(configs, id)
+		return false
+	}).RunOnce())
+
+	p1 := d.Div.WithID(id + "-first")
+	AddClass(p1.AsElement(), "zui-split-pane")
+	p1.AsElement().SetChildren(first)
+	p2 := d.Div.WithID(id + "-second")
+	AddClass(p2.AsElement(), "zui-split-pane")
+	p2.AsElement().SetChildren(second)
+
+	divider := d.Div.WithID(id + "-divider")
+	div := divider.AsElement()
+	AddClass(div, "zui-split-divider")
+	SetAttribute(div, "role", "separator")
+	SetAttribute(div, "tabindex", "0")
+	SetAttribute(div, "aria-controls", id+"-first")
+	SetAttribute(div, "aria-valuemin", strconv.FormatFloat(cfg.min, 'f', -1, 64))
+	SetAttribute(div, "aria-valuemax", strconv.FormatFloat(cfg.max, 'f', -1, 64))
+	if orientation == Horizontal {
+		SetAttribute(div, "aria-orientation", "vertical")
+	} else {
+		SetAttribute(div, "aria-orientation", "horizontal")
+	}
+
+	e.SetChildren(p1.AsElement(), div, p2.AsElement())
+	style(d)
+
+	state := d.NewObservable(id+"-state", EnableLocalPersistence())
+
+	e.Watch(Namespace.Data, "size", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		size := float64(evt.NewValue().(ui.Number))
+		if !s.Collapsed() {
+			SetCSSVariable(e, "zui-split-size", strconv.FormatFloat(size, 'f', 2, 64)+"%")
+			SetAttribute(div, "aria-valuenow", strconv.Itoa(int(size)))
+		}
+		state.AsElement().SetData("size", ui.Number(size))
+		PutInStorage(state.AsElement())
+		s.measure()
+		return false
+	}))
+
+	e.Watch(Namespace.Data, "collapsed", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if evt.NewValue().(ui.Bool) {
+			SetAttribute(e, "data-collapsed", "")
+			SetCSSVariable(e, "zui-split-size", "0%")
+			SetAttribute(div, "aria-valuenow", "0")
+		} else {
+			RemoveAttribute(e, "data-collapsed")
+			size := s.Size()
+			SetCSSVariable(e, "zui-split-size", strconv.FormatFloat(size, 'f', 2, 64)+"%")
+			SetAttribute(div, "aria-valuenow", strconv.Itoa(int(size)))
+		}
+		state.AsElement().SetData("collapsed", evt.NewValue())
+		PutInStorage(state.AsElement())
+		s.measure()
+		return false
+	}))
+
+	// pointer resizing
+	var dragging bool
+	var start, length float64
+	div.AddEventListener("pointerdown", ui.NewEventHandler(func(evt ui.Event) bool {
+		m, ok := evt.(MouseEvent)
+		if !ok || m.Button() != 0 {
+			return false
+		}
+		n, ok := JSValue(e)
+		if !ok {
+			return false
+		}
+		rect := n.Call("getBoundingClientRect")
+		if orientation == Horizontal {
+			start, length = rect.Get("left").Float(), rect.Get("width").Float()
+		} else {
+			start, length = rect.Get("top").Float(), rect.Get("height").Float()
+		}
+		if length <= 0 {
+			return false
+		}
+		dragging = true
+		if nevt, ok := evt.Native().(NativeEvent); ok {
+			if dn, ok := JSValue(div); ok {
+				dn.Call("setPointerCapture", nevt.Value.Get("pointerId"))
+			}
+		}
+		SetAttribute(e, "data-dragging", "")
+		evt.PreventDefault()
+		return false
+	}))
+	div.AddEventListener("pointermove", ui.NewEventHandler(func(evt ui.Event) bool {
+		m, ok := evt.(MouseEvent)
+		if !dragging || !ok {
+			return false
+		}
+		pos := m.ClientX()
+		if orientation == Vertical {
+			pos = m.ClientY()
+		}
+		if s.Collapsed() {
+			e.SetData("collapsed", ui.Bool(false))
+		}
+		s.SetSize((pos - start) / length * 100)
+		return false
+	}))
+	stop := ui.NewEventHandler(func(evt ui.Event) bool {
+		dragging = false
+		RemoveAttribute(e, "data-dragging")
+		return false
+	})
+	div.AddEventListener("pointerup", stop)
+	div.AddEventListener("pointercancel", stop)
+
+	div.AddEventListener("dblclick", ui.NewEventHandler(func(evt ui.Event) bool {
+		s.Reset()
+		return false
+	}))
+
+	div.AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		k, ok := evt.(KeyboardEvent)
+		if !ok {
+			return false
+		}
+		dec, inc := "ArrowLeft", "ArrowRight"
+		if orientation == Vertical {
+			dec, inc = "ArrowUp", "ArrowDown"
+		}
+		switch k.Key() {
+		case dec:
+			s.SetSize(s.Size() - keyboardStep)
+		case inc:
+			s.SetSize(s.Size() + keyboardStep)
+		case "Home":
+			s.SetSize(cfg.min)
+		case "End":
+			s.SetSize(cfg.max)
+		case "Enter":
+			s.Toggle()
+		default:
+			return false
+		}
+		evt.PreventDefault()
+		return false
+	}))
+
+	observeResize(s)
+
+	// the size is restored from storage.
+	size := cfg.initial
+	if v, ok := state.AsElement().GetData("size"); ok {
+		size = float64(v.(ui.Number))
+	}
+	s.SetSize(size)
+	if v, ok := state.AsElement().GetData("collapsed"); ok && bool(v.(ui.Bool)) {
+		e.SetData("collapsed", ui.Bool(true))
+	}
+
+	return s
+}
+
+// Size returns the size of the first pane, in percent. It is not affected by the collapsing of the pane.
+func (s SplitElement) Size() float64 {
+	v, ok := s.AsElement().GetData("size")
+	if !ok {
+		return 50
+	}
+	return float64(v.(ui.Number))
+}
+
+// SetSize sets the size of the first pane, in percent, within the bounds of the split.
+func (s SplitElement) SetSize(size float64) SplitElement {
+	if cfg, ok := configs[s.AsElement().ID]; ok {
+		if size < cfg.min {
+			size = cfg.min
+		}
+		if size > cfg.max {
+			size = cfg.max
+		}
+	}
+	s.AsElement().SetData("size", ui.Number(size))
+	return s
+}
+
+// Reset restores the default size of the first pane and expands it.
+func (s SplitElement) Reset() SplitElement {
+	if cfg, ok := configs[s.AsElement().ID]; ok {
+		s.SetSize(cfg.initial)
+	}
+	return s.Expand()
+}
+
+// Collapsed returns whether the first pane is collapsed.
+func (s SplitElement) Collapsed() bool {
+	v, ok := s.AsElement().GetData("collapsed")
+	return ok && bool(v.(ui.Bool))
+}
+
+// Collapse hides the first pane.
+func (s SplitElement) Collapse() SplitElement {
+	s.AsElement().SetData("collapsed", ui.Bool(true))
+	return s
+}
+
+// Expand shows the first pane again, with its previous size.
+func (s SplitElement) Expand() SplitElement {
+	if s.Collapsed() {
+		s.AsElement().SetData("collapsed", ui.Bool(false))
+	}
+	return s
+}
+
+// Toggle collapses or expands the first pane.
+func (s SplitElement) Toggle() SplitElement {
+	if s.Collapsed() {
+		return s.Expand()
+	}
+	return s.Collapse()
+}
+
+// OnResize registers a handler called when the size of the first pane changes. The event value is the size
+// in percent.
+func (s SplitElement) OnResize(h *ui.MutationHandler) SplitElement {
+	s.AsElement().Watch(Namespace.Data, "size", s, h)
+	return s
+}
+
+// measure updates the pixel size of the panes.
+func (s SplitElement) measure() {
+	if !InBrowser() {
+		return
+	}
+	d := GetDocument(s.AsElement())
+	id := s.AsElement().ID
+	prop := "width"
+	if GetAttribute(s.AsElement(), "data-orientation") == Vertical {
+		prop = "height"
+	}
+	for _, pane := range []string{"first", "second"} {
+		p := d.GetElementById(id + "-" + pane)
+		if p == nil {
+			continue
+		}
+		n, ok := JSValue(p)
+		if !ok {
+			continue
+		}
+		s.AsElement().SetUI(pane+"-size", ui.Number(n.Call("getBoundingClientRect").Get(prop).Float()))
+	}
+}
+
+// observeResize keeps the pixel size of the panes up to date when the split itself is resized.
+func observeResize(s SplitElement) {
+	if !InBrowser() || !js.Global().Get("ResizeObserver").Truthy() {
+		return
+	}
+	n, ok := JSValue(s.AsElement())
+	if !ok {
+		return
+	}
+	cb := RegisterCallback(s.AsElement(), func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(s.measure)
+		return nil
+	})
+	observer := js.Global().Get("ResizeObserver").New(cb)
+	observer.Call("observe", n)
+	s.AsElement().OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		observer.Call("disconnect")
+		return false
+	}).RunOnce())
+}
+
+var rules = map[string]string{
+	":where(.zui-split)":                                                 "display: flex; width: 100%; height: 100%; overflow: hidden;",
+	":where(.zui-split[data-orientation=vertical])":                      "flex-direction: column;",
+	":where(.zui-split > .zui-split-pane)":                               "overflow: auto; min-width: 0; min-height: 0;",
+	":where(.zui-split > .zui-split-pane:first-child)":                   "flex: 0 0 var(--zui-split-size, 50%);",
+	":where(.zui-split > .zui-split-pane:last-child)":                    "flex: 1 1 0;",
+	":where(.zui-split[data-collapsed] > .zui-split-pane:first-child)":   "visibility: hidden;",
+	":where(.zui-split > .zui-split-divider)":                            "flex: 0 0 6px; cursor: col-resize; touch-action: none; background: GrayText;",
+	":where(.zui-split[data-orientation=vertical] > .zui-split-divider)": "cursor: row-resize;",
+	":where(.zui-split[data-dragging])":                                  "user-select: none;",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.Update()
+}
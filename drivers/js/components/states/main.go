@@ -0,0 +1,250 @@
+// package states provides empty-state and error-state components, and a modifier switching the content of
+// a data-driven view according to the state of its data.
+package states
+
+import (
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// EmptyState and ErrorState display a message in place of missing data: an optional icon or illustration,
+// a title, a description and an optional action button (e.g. "Create" or "Retry").
+// They only differ by their role and styling: an error state is announced as an alert.
+//
+// WithStates is an element modifier which displays one of four contents in an element, depending on the
+// state of a Resource, i.e. of a data property loaded by a data fetcher (see ui.Element.SetDataFetcher):
+//   - loading: while the data is being fetched. If nil, the current content is marked busy instead.
+//   - empty: once the data is fetched, if it is empty
+//   - error: if the fetch failed. It receives the error message.
+//   - success: otherwise. It receives the data.
+//
+// UI properties of the element modified by WithStates:
+//   - "state" (ui.String): "loading", "empty", "error" or "success"
+//
+// The description of a state can be changed with SetDescription, e.g. to display the error message.
+
+// StyleSheetID is the id of the stylesheet holding the empty and error state rules.
+const StyleSheetID = "zui-states"
+
+type StateElement struct {
+	*ui.Element
+}
+
+type config struct {
+	icon        *ui.Element
+	description string
+	action      string
+	onaction    func()
+}
+
+// Option allows to configure an empty or error state.
+type Option func(*config)
+
+// WithIcon sets the icon or illustration displayed above the title.
+func WithIcon(icon *ui.Element) Option {
+	return func(c *config) { c.icon = icon }
+}
+
+// WithDescription sets the text displayed below the title.
+func WithDescription(text string) Option {
+	return func(c *config) { c.description = text }
+}
+
+// WithAction adds a button with the given label, calling f when clicked.
+func WithAction(label string, f func()) Option {
+	return func(c *config) {
+		c.action = label
+		c.onaction = f
+	}
+}
+
+// EmptyState returns a component indicating that there is no data to display.
+func EmptyState(d *Document, id string, title string, options ...Option) StateElement {
+	s := newState(d, id, title, options...)
+	AddClass(s.AsElement(), "zui-empty-state")
+	SetAttribute(s.AsElement(), "role", "status")
+	return s
+}
+
+// ErrorState returns a component indicating that the data could not be displayed.
+func ErrorState(d *Document, id string, title string, options ...Option) StateElement {
+	s := newState(d, id, title, options...)
+	AddClass(s.AsElement(), "zui-error-state")
+	SetAttribute(s.AsElement(), "role", "alert")
+	return s
+}
+
+func newState(d *Document, id string, title string, options ...Option) StateElement {
+	cfg := &config{}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/states")
+	AddClass(e, "zui-state")
+
+	var children []*ui.Element
+	if cfg.icon != nil {
+		AddClass(cfg.icon, "zui-state-icon")
+		SetAttribute(cfg.icon, "aria-hidden", "true")
+		children = append(children, cfg.icon)
+	}
+	h := d.H3.WithID(id + "-title").SetText(title).AsElement()
+	AddClass(h, "zui-state-title")
+	children = append(children, h)
+
+	desc := d.Paragraph.WithID(id + "-description").SetText(cfg.description).AsElement()
+	AddClass(desc, "zui-state-description")
+	if cfg.description == "" {
+		SetAttribute(desc, "hidden", "")
+	}
+	children = append(children, desc)
+
+	if cfg.action != "" {
+		b := d.Button.WithID(id+"-action", "button").SetText(cfg.action).AsElement()
+		AddClass(b, "zui-state-action")
+		b.AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+			if cfg.onaction != nil {
+				cfg.onaction()
+			}
+			return false
+		}))
+		children = append(children, b)
+	}
+	e.SetChildren(children...)
+	style(d)
+	return StateElement{e}
+}
+
+// SetDescription changes the text displayed below the title. It is hidden if empty.
+func (s StateElement) SetDescription(text string) StateElement {
+	desc := GetDocument(s.AsElement()).GetElementById(s.AsElement().ID + "-description")
+	if desc == nil {
+		return s
+	}
+	SetTextContent(desc, text)
+	if text == "" {
+		SetAttribute(desc, "hidden", "")
+	} else {
+		RemoveAttribute(desc, "hidden")
+	}
+	return s
+}
+
+// Resource designates a data property of an element, loaded by a data fetcher.
+type Resource struct {
+	Element *ui.Element
+	Prop    string
+
+	// IsEmpty reports whether the fetched data is empty. By default, empty strings, lists and objects are.
+	IsEmpty func(ui.Value) bool
+}
+
+// NewResource returns the resource corresponding to the data property of an element.
+func NewResource(e *ui.Element, prop string) Resource {
+	return Resource{e, prop, isEmpty}
+}
+
+// Retry fetches the data of the resource again.
+func (r Resource) Retry() {
+	r.Element.InvalidateFetch(r.Prop)
+	r.Element.Fetch(r.Prop)
+}
+
+func isEmpty(v ui.Value) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case ui.String:
+		return t == ""
+	case ui.List:
+		return len(t.UnsafelyUnwrap()) == 0
+	case ui.Object:
+		empty := true
+		t.Range(func(string, ui.Value) bool {
+			empty = false
+			return true
+		})
+		return empty
+	}
+	return false
+}
+
+// WithStates returns an element modifier displaying the content corresponding to the state of the resource.
+func WithStates(r Resource, loading, empty *ui.Element, errorview func(msg string) *ui.Element, success func(ui.Value) *ui.Element) func(*ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		if r.IsEmpty == nil {
+			r.IsEmpty = isEmpty
+		}
+		show := func(state string, content *ui.Element) {
+			SetBusy(e, false)
+			e.SetUI("state", ui.String(state))
+			if content != nil {
+				e.SetChildren(content)
+			}
+		}
+		render := func(v ui.Value) {
+			if r.IsEmpty(v) {
+				show("empty", empty)
+				return
+			}
+			show("success", success(v))
+		}
+
+		r.Element.OnFetch(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if loading == nil {
+				e.SetUI("state", ui.String("loading"))
+				SetBusy(e, true, BusyOptions{Spinner: true})
+				return false
+			}
+			show("loading", loading)
+			return false
+		}))
+		r.Element.OnFetchError(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			errs, ok := r.Element.Get("runtime", "fetcherrors")
+			if !ok {
+				return false
+			}
+			msg, ok := errs.(ui.Object).Get(r.Prop)
+			if !ok {
+				return false
+			}
+			show("error", errorview(string(msg.(ui.String))))
+			return false
+		}))
+		r.Element.OnFetchCancel(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			SetBusy(e, false)
+			return false
+		}))
+		e.Watch(Namespace.Data, r.Prop, r.Element, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			render(evt.NewValue())
+			return false
+		}))
+		return e
+	}
+}
+
+var rules = map[string]string{
+	":where(.zui-state)":                        "display: flex; flex-direction: column; align-items: center; gap: 0.5rem; padding: 2rem 1rem; text-align: center;",
+	":where(.zui-state-icon)":                   "max-width: 8rem; max-height: 8rem; opacity: 0.7;",
+	":where(.zui-state-title)":                  "margin: 0; font-size: 1.125rem;",
+	":where(.zui-state-description)":            "margin: 0; max-width: 40ch; color: GrayText;",
+	":where(.zui-state-action)":                 "margin-top: 0.5rem;",
+	":where(.zui-error-state .zui-state-title)": "color: var(--zui-error-color, #c62828);",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.Update()
+}
@@ -0,0 +1,108 @@
+// package toggle provides an on/off switch.
+package toggle
+
+import (
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+)
+
+// A switch is a button with the switch role, whose aria-checked attribute reflects its state.
+// It is toggled by a click, or by Space and Enter once focused, as any button.
+// Its appearance can be themed with the --zui-switch-on-color, --zui-switch-off-color and
+// --zui-switch-thumb-color CSS variables.
+//
+// Data properties of the switch element:
+//   - "checked" (ui.Bool): whether the switch is on
+//
+// Events triggered on the switch element:
+//   - "switch-change": the switch was toggled by the user. The event value is the new state.
+
+// StyleSheetID is the id of the stylesheet holding the switch rules.
+const StyleSheetID = "zui-switch"
+
+type SwitchElement struct {
+	*ui.Element
+}
+
+// New returns a switch with the given label.
+func New(d *Document, id string, label string, checked bool) SwitchElement {
+	root := d.Button.WithID(id, "button")
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/toggle")
+	AddClass(e, "zui-switch")
+	SetAttribute(e, "role", "switch")
+	s := SwitchElement{e}
+
+	thumb := d.Span.WithID(id + "-thumb").AsElement()
+	AddClass(thumb, "zui-switch-thumb")
+	SetAttribute(thumb, "aria-hidden", "true")
+	text := d.Span.WithID(id + "-label").SetText(label).AsElement()
+	AddClass(text, "zui-switch-label")
+	e.SetChildren(thumb, text)
+	style(d)
+
+	e.Watch(Namespace.Data, "checked", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if evt.NewValue().(ui.Bool) {
+			SetAttribute(e, "aria-checked", "true")
+		} else {
+			SetAttribute(e, "aria-checked", "false")
+		}
+		return false
+	}))
+
+	e.AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		s.Toggle()
+		e.TriggerEvent("switch-change", ui.Bool(s.Checked()))
+		return false
+	}))
+
+	e.SetData("checked", ui.Bool(checked))
+	return s
+}
+
+// Checked returns whether the switch is on.
+func (s SwitchElement) Checked() bool {
+	v, ok := s.AsElement().GetData("checked")
+	return ok && bool(v.(ui.Bool))
+}
+
+// SetChecked turns the switch on or off.
+func (s SwitchElement) SetChecked(b bool) SwitchElement {
+	s.AsElement().SetData("checked", ui.Bool(b))
+	return s
+}
+
+// Toggle inverts the state of the switch.
+func (s SwitchElement) Toggle() SwitchElement {
+	return s.SetChecked(!s.Checked())
+}
+
+// OnChange registers a handler called when the switch is toggled by the user.
+func (s SwitchElement) OnChange(h *ui.MutationHandler) SwitchElement {
+	s.AsElement().WatchEvent("switch-change", s, h)
+	return s
+}
+
+var rules = map[string]string{
+	":where(.zui-switch)":                                             "display: inline-flex; align-items: center; gap: 0.5rem; padding: 0; border: none; background: none; cursor: pointer; font: inherit; color: inherit;",
+	":where(.zui-switch-thumb)":                                       "position: relative; width: 2.25rem; height: 1.25rem; border-radius: 0.625rem; background: var(--zui-switch-off-color, #0004); transition: background 0.15s;",
+	":where(.zui-switch-thumb)::after":                                "content: ''; position: absolute; top: 0.125rem; left: 0.125rem; width: 1rem; height: 1rem; border-radius: 50%; background: var(--zui-switch-thumb-color, #fff); transition: transform 0.15s;",
+	":where(.zui-switch[aria-checked=true] .zui-switch-thumb)":        "background: var(--zui-switch-on-color, Highlight);",
+	":where(.zui-switch[aria-checked=true] .zui-switch-thumb)::after": "transform: translateX(1rem);",
+	":where(.zui-switch:focus-visible .zui-switch-thumb)":             "outline: 2px solid Highlight; outline-offset: 2px;",
+}
+
+func style(d *Document) {
+	sheet, ok := d.GetStyleSheet(StyleSheetID)
+	if ok {
+		return
+	}
+	sheet = d.NewStyleSheet(StyleSheetID)
+	actives := append([]string{StyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+	for sel, rule := range rules {
+		sheet.UpdateRule(sel, rule)
+	}
+	sheet.UpdateNestedRule("@media (prefers-reduced-motion: reduce)", ".zui-switch-thumb, .zui-switch-thumb::after", "transition: none;")
+	sheet.Update()
+}
@@ -0,0 +1,304 @@
+// package wizard provides a multi-step wizard (stepper) component with per-step validation.
+package wizard
+
+import (
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	. "github.com/atdiar/particleui/drivers/js"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// A wizard displays one step at a time, along with a progress indicator listing every step.
+// Moving to the next step requires the validation of the current one to succeed. When it fails, the error
+// message is displayed in an alert region and the step remains current.
+// The content of every step is created upfront and hidden when the step is not current, so that its state
+// (e.g. form inputs) is preserved when going back and forth.
+// The index of the current step is persisted in sessionstorage and may also be reflected in a query parameter
+// of the URL, so that a reload or a navigation back to the page restores it.
+// When the current step changes, the focus is moved to the step panel.
+//
+// Data properties of the wizard element:
+//   - "step" (ui.Number): index of the current step
+//   - "error" (ui.String): validation error of the current step, empty if none
+//
+// Events triggered on the wizard element:
+//   - "wizard-step": the current step changed. The event value is the step index.
+//   - "wizard-invalid": the validation of the current step failed. The event value is the error message.
+//   - "wizard-complete": the last step was validated.
+
+// Step describes a step of a wizard.
+type Step struct {
+	ID    string
+	Title string
+	// Content creates the element holding the content of the step.
+	Content func(d *Document, id string) *ui.Element
+	// Validate, if not nil, is called before leaving the step forward.
+	Validate func() error
+}
+
+type WizardElement struct {
+	*ui.Element
+}
+
+type config struct {
+	param  string
+	next   string
+	back   string
+	finish string
+}
+
+// Option allows to configure a wizard.
+type Option func(*config)
+
+// WithQueryParam reflects the id of the current step in the given query parameter of the URL.
+func WithQueryParam(name string) Option {
+	return func(c *config) { c.param = name }
+}
+
+// WithLabels changes the labels of the navigation buttons.
+func WithLabels(next, back, finish string) Option {
+	return func(c *config) { c.next, c.back, c.finish = next, back, finish }
+}
+
+// registries holds the steps of each wizard, indexed by wizard id.
+var registries = make(map[string][]Step)
+
+// New returns a wizard made of the given steps.
+func New(d *Document, id string, steps []Step, options ...Option) WizardElement {
+	cfg := &config{next: "Next", back: "Back", finish: "Finish"}
+	for _, opt := range options {
+		opt(cfg)
+	}
+
+	root := d.Div.WithID(id)
+	e := root.AsElement()
+	MarkComponent(e, "github.com/atdiar/particleui/drivers/js/components/wizard")
+	AddClass(e, "zui-wizard")
+	w := WizardElement{e}
+	registries[id] = steps
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		delete(registries, id)
+		return false
+	}).RunOnce())
+
+	progress := d.Ol.WithID(id+"-progress", "1", 1)
+	AddClass(progress.AsElement(), "zui-wizard-progress")
+	SetAttribute(progress.AsElement(), "aria-label", "Progress")
+	items := make([]*ui.Element, 0, len(steps))
+	panels := make([]*ui.Element, 0, len(steps))
+	for i, step := range steps {
+		li := d.Li.WithID(id + "-progress-" + strconv.Itoa(i))
+		li.AsElement().SetChildren(d.Span.WithID(id + "-progress-" + strconv.Itoa(i) + "-title").SetText(step.Title).AsElement())
+		AddClass(li.AsElement(), "zui-wizard-progress-step")
+		items = append(items, li.AsElement())
+
+		panel := d.Section.WithID(id + "-step-" + strconv.Itoa(i))
+		AddClass(panel.AsElement(), "zui-wizard-step")
+		SetAttribute(panel.AsElement(), "tabindex", "-1")
+		SetAttribute(panel.AsElement(), "aria-label", step.Title)
+		if step.Content != nil {
+			panel.AsElement().SetChildren(step.Content(d, id+"-step-"+strconv.Itoa(i)+"-content"))
+		}
+		panels = append(panels, panel.AsElement())
+	}
+	progress.AsElement().SetChildren(items...)
+
+	body := d.Div.WithID(id + "-body")
+	body.AsElement().SetChildren(panels...)
+
+	alert := d.Div.WithID(id + "-error")
+	AddClass(alert.AsElement(), "zui-wizard-error")
+	SetAttribute(alert.AsElement(), "role", "alert")
+	message := d.Span.WithID(id + "-error-message")
+	alert.AsElement().SetChildren(message.AsElement())
+
+	back := d.Button.WithID(id+"-back", "button").SetText(cfg.back)
+	next := d.Button.WithID(id+"-next", "button").SetText(cfg.next)
+	nav := d.Div.WithID(id + "-nav")
+	AddClass(nav.AsElement(), "zui-wizard-nav")
+	nav.AsElement().SetChildren(back.AsElement(), next.AsElement())
+
+	e.SetChildren(progress.AsElement(), body.AsElement(), alert.AsElement(), nav.AsElement())
+
+	state := d.NewObservable(id+"-state", EnableSessionPersistence())
+
+	e.Watch(Namespace.Data, "step", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		idx := int(evt.NewValue().(ui.Number))
+		for i, li := range items {
+			RemoveClass(li, "zui-wizard-current")
+			RemoveClass(li, "zui-wizard-done")
+			RemoveAttribute(li, "aria-current")
+			switch {
+			case i < idx:
+				AddClass(li, "zui-wizard-done")
+			case i == idx:
+				AddClass(li, "zui-wizard-current")
+				SetAttribute(li, "aria-current", "step")
+			}
+		}
+		for i, p := range panels {
+			if i == idx {
+				RemoveAttribute(p, "hidden")
+				continue
+			}
+			SetAttribute(p, "hidden", "")
+		}
+		if idx == 0 {
+			SetAttribute(back.AsElement(), "disabled", "")
+		} else {
+			RemoveAttribute(back.AsElement(), "disabled")
+		}
+		if idx == len(steps)-1 {
+			next.SetText(cfg.finish)
+		} else {
+			next.SetText(cfg.next)
+		}
+		e.SetData("error", ui.String(""))
+
+		state.AsElement().SetData("step", ui.Number(idx))
+		PutInStorage(state.AsElement())
+		if cfg.param != "" && idx < len(steps) {
+			syncQueryParam(cfg.param, steps[idx].ID)
+		}
+		if idx < len(panels) {
+			SetFocus(panels[idx], false)
+		}
+		e.TriggerEvent("wizard-step", ui.Number(idx))
+		return false
+	}))
+
+	e.Watch(Namespace.Data, "error", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		message.SetText(string(evt.NewValue().(ui.String)))
+		return false
+	}))
+
+	next.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		w.Next()
+		return false
+	}))
+	back.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		w.Back()
+		return false
+	}))
+
+	// the initial step is restored from the URL, then from storage.
+	initial := 0
+	if v, ok := state.AsElement().GetData("step"); ok {
+		initial = int(v.(ui.Number))
+	}
+	if cfg.param != "" {
+		if sid := queryParam(cfg.param); sid != "" {
+			for i, s := range steps {
+				if s.ID == sid {
+					initial = i
+				}
+			}
+		}
+	}
+	if initial < 0 || initial >= len(steps) {
+		initial = 0
+	}
+	e.SetData("step", ui.Number(initial))
+
+	return w
+}
+
+// Current returns the index of the current step.
+func (w WizardElement) Current() int {
+	v, ok := w.AsElement().GetData("step")
+	if !ok {
+		return 0
+	}
+	return int(v.(ui.Number))
+}
+
+func (w WizardElement) steps() []Step {
+	return registries[w.AsElement().ID]
+}
+
+// Next validates the current step and moves to the next one. On the last step, the wizard completes.
+func (w WizardElement) Next() WizardElement {
+	steps := w.steps()
+	idx := w.Current()
+	if idx >= len(steps) {
+		return w
+	}
+	if v := steps[idx].Validate; v != nil {
+		if err := v(); err != nil {
+			w.AsElement().SetData("error", ui.String(err.Error()))
+			w.AsElement().TriggerEvent("wizard-invalid", ui.String(err.Error()))
+			return w
+		}
+	}
+	if idx == len(steps)-1 {
+		w.AsElement().SetData("error", ui.String(""))
+		w.AsElement().TriggerEvent("wizard-complete")
+		return w
+	}
+	w.AsElement().SetData("step", ui.Number(idx+1))
+	return w
+}
+
+// Back moves to the previous step, without validation.
+func (w WizardElement) Back() WizardElement {
+	if idx := w.Current(); idx > 0 {
+		w.AsElement().SetData("step", ui.Number(idx-1))
+	}
+	return w
+}
+
+// GoTo moves to the step with the given id, provided that every step before it validates.
+func (w WizardElement) GoTo(stepID string) WizardElement {
+	for i, s := range w.steps() {
+		if s.ID != stepID {
+			continue
+		}
+		for w.Current() < i {
+			cur := w.Current()
+			w.Next()
+			if w.Current() == cur {
+				return w
+			}
+		}
+		if i < w.Current() {
+			w.AsElement().SetData("step", ui.Number(i))
+		}
+		return w
+	}
+	return w
+}
+
+// OnComplete registers a handler called when the last step has been validated.
+func (w WizardElement) OnComplete(h *ui.MutationHandler) WizardElement {
+	w.AsElement().WatchEvent("wizard-complete", w, h)
+	return w
+}
+
+// OnStep registers a handler called when the current step changes.
+func (w WizardElement) OnStep(h *ui.MutationHandler) WizardElement {
+	w.AsElement().WatchEvent("wizard-step", w, h)
+	return w
+}
+
+func queryParam(name string) string {
+	if !InBrowser() {
+		return ""
+	}
+	params := js.Global().Get("URLSearchParams").New(js.Global().Get("location").Get("search"))
+	v := params.Call("get", name)
+	if v.IsNull() {
+		return ""
+	}
+	return v.String()
+}
+
+func syncQueryParam(name, value string) {
+	if !InBrowser() {
+		return
+	}
+	u := js.Global().Get("URL").New(js.Global().Get("location").Get("href"))
+	u.Get("searchParams").Call("set", name, value)
+	history := js.Global().Get("history")
+	history.Call("replaceState", history.Get("state"), "", u.Call("toString"))
+}
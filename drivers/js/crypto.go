@@ -0,0 +1,262 @@
+package doc
+
+import (
+	"context"
+	"errors"
+
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// WebCrypto
+//
+// The Crypto service of a document wraps the browser SubtleCrypto API, which is much faster than pure-Go
+// cryptography compiled to wasm. Every method blocks until the underlying promise settles and should
+// therefore be called from a goroutine, typically within ui.DoAsync, never from the UI thread.
+//
+// Keys may be generated as non-extractable and stored as is in IndexedDB (StoreKey/LoadKey): the raw key
+// material never becomes accessible to the app.
+
+var ErrCryptoUnavailable = errors.New("WebCrypto is not available")
+
+// KeyStoreDB is the name of the IndexedDB database in which crypto keys are stored.
+var KeyStoreDB = "zui-keystore"
+
+// CryptoKey wraps a js CryptoKey object.
+type CryptoKey struct {
+	js.Value
+}
+
+// Algorithm returns the name of the algorithm the key is used with.
+func (k CryptoKey) Algorithm() string {
+	return k.Get("algorithm").Get("name").String()
+}
+
+// Extractable returns whether the key material may be exported.
+func (k CryptoKey) Extractable() bool {
+	return k.Get("extractable").Bool()
+}
+
+// CryptoKeyPair holds the keys of an asymmetric algorithm.
+type CryptoKeyPair struct {
+	Public  CryptoKey
+	Private CryptoKey
+}
+
+// Crypto is the document service giving access to WebCrypto.
+type Crypto struct {
+	d *Document
+}
+
+// Crypto returns the WebCrypto service of the document.
+func (d *Document) Crypto() Crypto {
+	return Crypto{d}
+}
+
+func (c Crypto) subtle() (js.Value, error) {
+	if !InBrowser() {
+		return js.Undefined(), ErrCryptoUnavailable
+	}
+	s := js.Global().Get("crypto").Get("subtle")
+	if !s.Truthy() {
+		return js.Undefined(), ErrCryptoUnavailable // e.g. not a secure context
+	}
+	return s, nil
+}
+
+func (c Crypto) call(ctx context.Context, method string, args ...interface{}) (js.Value, error) {
+	s, err := c.subtle()
+	if err != nil {
+		return js.Undefined(), err
+	}
+	return awaitPromise(ctx, s.Call(method, args...))
+}
+
+func (c Crypto) bytes(ctx context.Context, method string, args ...interface{}) ([]byte, error) {
+	buf, err := c.call(ctx, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return goBytes(js.Global().Get("Uint8Array").New(buf)), nil
+}
+
+// Digest returns the hash of the data. The algorithm is one of "SHA-1", "SHA-256", "SHA-384", "SHA-512".
+func (c Crypto) Digest(ctx context.Context, algorithm string, data []byte) ([]byte, error) {
+	return c.bytes(ctx, "digest", algorithm, jsBytes(data))
+}
+
+func (c Crypto) key(ctx context.Context, method string, args ...interface{}) (CryptoKey, error) {
+	k, err := c.call(ctx, method, args...)
+	if err != nil {
+		return CryptoKey{js.Undefined()}, err
+	}
+	return CryptoKey{k}, nil
+}
+
+// GenerateHMACKey returns a new HMAC key for the given hash algorithm (e.g. "SHA-256").
+func (c Crypto) GenerateHMACKey(ctx context.Context, hash string, extractable bool) (CryptoKey, error) {
+	return c.key(ctx, "generateKey", map[string]interface{}{"name": "HMAC", "hash": hash}, extractable, []interface{}{"sign", "verify"})
+}
+
+// ImportHMACKey returns an HMAC key from raw key material.
+func (c Crypto) ImportHMACKey(ctx context.Context, raw []byte, hash string, extractable bool) (CryptoKey, error) {
+	return c.key(ctx, "importKey", "raw", jsBytes(raw), map[string]interface{}{"name": "HMAC", "hash": hash}, extractable, []interface{}{"sign", "verify"})
+}
+
+// GenerateAESKey returns a new AES-GCM key. The length is 128 or 256 bits.
+func (c Crypto) GenerateAESKey(ctx context.Context, bits int, extractable bool) (CryptoKey, error) {
+	return c.key(ctx, "generateKey", map[string]interface{}{"name": "AES-GCM", "length": bits}, extractable, []interface{}{"encrypt", "decrypt"})
+}
+
+// ImportAESKey returns an AES-GCM key from raw key material.
+func (c Crypto) ImportAESKey(ctx context.Context, raw []byte, extractable bool) (CryptoKey, error) {
+	return c.key(ctx, "importKey", "raw", jsBytes(raw), "AES-GCM", extractable, []interface{}{"encrypt", "decrypt"})
+}
+
+// GenerateECDSAKeyPair returns a new ECDSA key pair for the given curve ("P-256", "P-384" or "P-521").
+// The public key is always extractable.
+func (c Crypto) GenerateECDSAKeyPair(ctx context.Context, curve string, extractable bool) (CryptoKeyPair, error) {
+	p, err := c.call(ctx, "generateKey", map[string]interface{}{"name": "ECDSA", "namedCurve": curve}, extractable, []interface{}{"sign", "verify"})
+	if err != nil {
+		return CryptoKeyPair{CryptoKey{js.Undefined()}, CryptoKey{js.Undefined()}}, err
+	}
+	return CryptoKeyPair{CryptoKey{p.Get("publicKey")}, CryptoKey{p.Get("privateKey")}}, nil
+}
+
+// ImportECDSAPublicKey returns an ECDSA public key from its SPKI encoding.
+func (c Crypto) ImportECDSAPublicKey(ctx context.Context, spki []byte, curve string) (CryptoKey, error) {
+	return c.key(ctx, "importKey", "spki", jsBytes(spki), map[string]interface{}{"name": "ECDSA", "namedCurve": curve}, true, []interface{}{"verify"})
+}
+
+// ExportKey returns the key material of an extractable key in the given format: "raw", "spki", "pkcs8"
+// or "jwk" (in which case the JSON encoding of the key is returned).
+func (c Crypto) ExportKey(ctx context.Context, format string, k CryptoKey) ([]byte, error) {
+	if format == "jwk" {
+		v, err := c.call(ctx, "exportKey", format, k.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(js.Global().Get("JSON").Call("stringify", v).String()), nil
+	}
+	return c.bytes(ctx, "exportKey", format, k.Value)
+}
+
+// signatureParams returns the algorithm parameters used to sign or verify with a key.
+func signatureParams(k CryptoKey) interface{} {
+	if k.Algorithm() != "ECDSA" {
+		return k.Algorithm()
+	}
+	hash := "SHA-256"
+	switch k.Get("algorithm").Get("namedCurve").String() {
+	case "P-384":
+		hash = "SHA-384"
+	case "P-521":
+		hash = "SHA-512"
+	}
+	return map[string]interface{}{"name": "ECDSA", "hash": hash}
+}
+
+// Sign returns the signature of the data, using an HMAC key or an ECDSA private key.
+// ECDSA signatures use the hash matching the size of the curve.
+func (c Crypto) Sign(ctx context.Context, k CryptoKey, data []byte) ([]byte, error) {
+	return c.bytes(ctx, "sign", signatureParams(k), k.Value, jsBytes(data))
+}
+
+// Verify checks the signature of the data, using an HMAC key or an ECDSA public key.
+func (c Crypto) Verify(ctx context.Context, k CryptoKey, signature, data []byte) (bool, error) {
+	v, err := c.call(ctx, "verify", signatureParams(k), k.Value, jsBytes(signature), jsBytes(data))
+	if err != nil {
+		return false, err
+	}
+	return v.Bool(), nil
+}
+
+// Encrypt encrypts the plaintext with an AES-GCM key. The random 12-byte IV is prepended to the returned
+// ciphertext. The additional data, which may be nil, is authenticated but not encrypted.
+func (c Crypto) Encrypt(ctx context.Context, k CryptoKey, plaintext, additionalData []byte) ([]byte, error) {
+	iv := js.Global().Get("crypto").Call("getRandomValues", js.Global().Get("Uint8Array").New(12))
+	params := map[string]interface{}{"name": "AES-GCM", "iv": iv}
+	if additionalData != nil {
+		params["additionalData"] = jsBytes(additionalData)
+	}
+	ciphertext, err := c.bytes(ctx, "encrypt", params, k.Value, jsBytes(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	return append(goBytes(iv), ciphertext...), nil
+}
+
+// Decrypt decrypts a ciphertext returned by Encrypt.
+func (c Crypto) Decrypt(ctx context.Context, k CryptoKey, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < 12 {
+		return nil, errors.New("ciphertext is too short")
+	}
+	params := map[string]interface{}{"name": "AES-GCM", "iv": jsBytes(ciphertext[:12])}
+	if additionalData != nil {
+		params["additionalData"] = jsBytes(additionalData)
+	}
+	return c.bytes(ctx, "decrypt", params, k.Value, jsBytes(ciphertext[12:]))
+}
+
+// StoreKey saves a key in IndexedDB under the given name. Non-extractable keys remain non-extractable.
+func (c Crypto) StoreKey(ctx context.Context, name string, k CryptoKey) error {
+	_, err := c.keystore(ctx, "readwrite", func(store js.Value) js.Value {
+		return store.Call("put", map[string]interface{}{"key": name, "value": k.Value})
+	})
+	return err
+}
+
+// LoadKey retrieves a key saved with StoreKey. The boolean is false if no key was saved under that name.
+func (c Crypto) LoadKey(ctx context.Context, name string) (CryptoKey, bool, error) {
+	res, err := c.keystore(ctx, "readonly", func(store js.Value) js.Value {
+		return store.Call("get", name)
+	})
+	if err != nil || !res.Truthy() {
+		return CryptoKey{js.Undefined()}, false, err
+	}
+	return CryptoKey{res.Get("value")}, true, nil
+}
+
+// DeleteKey removes a key saved with StoreKey.
+func (c Crypto) DeleteKey(ctx context.Context, name string) error {
+	_, err := c.keystore(ctx, "readwrite", func(store js.Value) js.Value {
+		return store.Call("delete", name)
+	})
+	return err
+}
+
+// keystore runs an IndexedDB request on the key store and waits for its result.
+func (c Crypto) keystore(ctx context.Context, mode string, request func(store js.Value) js.Value) (js.Value, error) {
+	if _, err := c.subtle(); err != nil {
+		return js.Undefined(), err
+	}
+	db, err := c.d.ensureDBOpen(KeyStoreDB)
+	if err != nil {
+		return js.Undefined(), err
+	}
+	res := make(chan js.Value, 1)
+	errc := make(chan error, 1)
+
+	tx := db.Call("transaction", js.ValueOf([]interface{}{"store"}), mode)
+	req := request(tx.Call("objectStore", "store"))
+	onsuccess := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		res <- req.Get("result")
+		return nil
+	})
+	defer onsuccess.Release()
+	onerror := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		errc <- js.Error{Value: req.Get("error")}
+		return nil
+	})
+	defer onerror.Release()
+	req.Set("onsuccess", onsuccess)
+	req.Set("onerror", onerror)
+
+	select {
+	case v := <-res:
+		return v, nil
+	case err := <-errc:
+		return js.Undefined(), err
+	case <-ctx.Done():
+		return js.Undefined(), ctx.Err()
+	}
+}
@@ -0,0 +1,150 @@
+package doc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// CSS custom properties (variables)
+//
+// CSS variables are stored in the "cssvars" property category of an element, one property per variable,
+// so that each of them can be watched.
+// In the browser, they are written to the native style declaration of the element via style.setProperty
+// instead of rewriting the whole style attribute. Otherwise, they are serialized at the end of the style attribute
+// so that they are part of the rendered html.
+// Variables set on the Document apply to the whole page since its native element is the root element (:root).
+
+func cssVariableName(name string) string {
+	if strings.HasPrefix(name, "--") {
+		return name
+	}
+	return "--" + name
+}
+
+// SetCSSVariable sets the value of a CSS custom property on an element.
+// The "--" prefix of the variable name may be omitted.
+func SetCSSVariable(e *ui.Element, name string, value string) {
+	name = cssVariableName(name)
+	if _, ok := e.Get(Namespace.Internals, "cssvar-"+name); !ok {
+		e.Set(Namespace.Internals, "cssvar-"+name, ui.Bool(true))
+		e.Watch("cssvars", name, e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			applyCSSVariable(evt.Origin(), name, string(evt.NewValue().(ui.String)))
+			return false
+		}))
+	}
+	e.Set("cssvars", name, ui.String(value))
+}
+
+// GetCSSVariable returns the value of a CSS custom property that was set on an element via SetCSSVariable.
+func GetCSSVariable(e *ui.Element, name string) (string, bool) {
+	v, ok := e.Get("cssvars", cssVariableName(name))
+	if !ok {
+		return "", false
+	}
+	return string(v.(ui.String)), true
+}
+
+// RemoveCSSVariable removes a CSS custom property from an element.
+func RemoveCSSVariable(e *ui.Element, name string) {
+	name = cssVariableName(name)
+	if _, ok := e.Get("cssvars", name); !ok {
+		return
+	}
+	e.Properties.Delete("cssvars", name)
+	applyCSSVariable(e, name, "")
+}
+
+// CSSVariables returns the CSS custom properties set on an element.
+func CSSVariables(e *ui.Element) map[string]string {
+	res := make(map[string]string)
+	ps, ok := e.Properties.Categories["cssvars"]
+	if !ok {
+		return res
+	}
+	for k, v := range ps.Local {
+		if s, ok := v.(ui.String); ok {
+			res[k] = string(s)
+		}
+	}
+	return res
+}
+
+// BindCSSVariable keeps a CSS custom property of an element in sync with a property of a source element,
+// e.g. the value of a slider. ui.Number values are formatted without unit.
+func BindCSSVariable(e *ui.Element, name string, source ui.Watchable, category string, propname string) {
+	e.Watch(category, propname, source, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		switch v := evt.NewValue().(type) {
+		case ui.String:
+			SetCSSVariable(evt.Origin(), name, string(v))
+		case ui.Number:
+			SetCSSVariable(evt.Origin(), name, strconv.FormatFloat(float64(v), 'f', -1, 64))
+		case ui.Bool:
+			SetCSSVariable(evt.Origin(), name, strconv.FormatBool(bool(v)))
+		default:
+			DEBUG("unsupported value type for css variable ", name, ": ", v.ValueType())
+		}
+		return false
+	}))
+}
+
+func applyCSSVariable(e *ui.Element, name string, value string) {
+	native, ok := e.Native.(NativeElement)
+	if !ok {
+		return
+	}
+	if InBrowser() {
+		if value == "" {
+			native.Value.Get("style").Call("removeProperty", name)
+			return
+		}
+		native.Value.Get("style").Call("setProperty", name, value)
+		return
+	}
+
+	vars := CSSVariables(e)
+	names := make([]string, 0, len(vars))
+	for k := range vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var style strings.Builder
+	style.WriteString(GetInlineCSS(e))
+	for _, k := range names {
+		style.WriteString(k)
+		style.WriteString(":")
+		style.WriteString(vars[k])
+		style.WriteString(";")
+	}
+	native.Value.Call("setAttribute", "style", style.String())
+}
+
+// SetCSSVariable sets a CSS custom property for the whole document.
+func (d *Document) SetCSSVariable(name string, value string) *Document {
+	SetCSSVariable(d.AsElement(), name, value)
+	return d
+}
+
+// CSSVariable returns the value of a document-level CSS custom property.
+func (d *Document) CSSVariable(name string) (string, bool) {
+	return GetCSSVariable(d.AsElement(), name)
+}
+
+// CSSVariables returns the document-level CSS custom properties.
+func (d *Document) CSSVariables() map[string]string {
+	return CSSVariables(d.AsElement())
+}
+
+// RemoveCSSVariable removes a document-level CSS custom property.
+func (d *Document) RemoveCSSVariable(name string) *Document {
+	RemoveCSSVariable(d.AsElement(), name)
+	return d
+}
+
+// OnCSSVariableChange registers a handler called whenever a document-level CSS custom property is set.
+func (d *Document) OnCSSVariableChange(name string, h *ui.MutationHandler) {
+	d.AsElement().Watch("cssvars", cssVariableName(name), d, h)
+}
@@ -0,0 +1,302 @@
+package doc
+
+import (
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Custom elements
+//
+// Web Components and zui can be combined both ways.
+//
+// DefineCustomElement registers a custom element with the CustomElementRegistry of the browser. The content of
+// each instance is a zui element built when the instance is connected to the page, including when the tag is
+// written in HTML that zui did not produce. The observed attributes of the instance are mirrored as String ui
+// properties of the built element, set to ui.Bool(false) when the attribute is removed. The built element is
+// deleted once the instance is disconnected. A custom element class cannot be unregistered.
+// The built elements are attached to a container of the document head so that they are mounted as far as the
+// framework is concerned, while their native node lives in the custom element instance.
+//
+// Document.CustomElement wraps an instance of a third-party custom element, whether already defined or defined
+// later by its library. Its attributes and properties are bound to ui properties with BindAttribute and
+// BindProperty, and property changes notified by an event are synchronized back with SyncProperty.
+// Events are listened to with AddEventListener as usual: the detail of a CustomEvent is available under the
+// "detail" key of the event value.
+
+// CustomElementsContainerID is the id of the container of the elements built for custom element instances.
+const CustomElementsContainerID = "zui-custom-elements"
+
+const customElementHostKey = "__zuiCustomElement"
+
+// DefineCustomElement registers the custom element named tag, whose instances hold the element returned by build.
+// The name of a custom element must contain a hyphen.
+func (d *Document) DefineCustomElement(tag string, build func(*Document) *ui.Element, observedAttributes ...string) {
+	if !strings.Contains(tag, "-") {
+		panic("invalid custom element name, it should contain a hyphen: " + tag)
+	}
+	if !InBrowser() {
+		return
+	}
+	registry := js.Global().Get("customElements")
+	if !registry.Truthy() {
+		DEBUG("custom elements are not supported")
+		return
+	}
+	if registry.Call("get", tag).Truthy() {
+		panic("custom element already defined: " + tag)
+	}
+
+	connected := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		host := args[0]
+		go ui.DoSync(func() { d.connectCustomElement(host, build, observedAttributes) })
+		return nil
+	})
+	disconnected := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		host := args[0]
+		go ui.DoSync(func() { d.disconnectCustomElement(host) })
+		return nil
+	})
+	attributeChanged := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		host, name, value := args[0], args[1].String(), args[2]
+		go ui.DoSync(func() {
+			e := d.customElementContent(host)
+			if e == nil {
+				return // the attributes are read on connection
+			}
+			if value.IsNull() {
+				e.SetUI(name, ui.Bool(false))
+				return
+			}
+			e.SetUI(name, ui.String(value.String()))
+		})
+		return nil
+	})
+	observed := make([]interface{}, 0, len(observedAttributes))
+	for _, a := range observedAttributes {
+		observed = append(observed, a)
+	}
+	define := js.Global().Get("Function").New("tag", "connected", "disconnected", "attributeChanged", "observed", `
+		customElements.define(tag, class extends HTMLElement {
+			static get observedAttributes() { return observed; }
+			connectedCallback() { connected(this); }
+			disconnectedCallback() { disconnected(this); }
+			attributeChangedCallback(name, oldValue, newValue) {
+				if (oldValue !== newValue) attributeChanged(this, name, newValue);
+			}
+		});
+	`)
+	define.Invoke(tag, connected, disconnected, attributeChanged, observed)
+}
+
+func (d *Document) customElementsContainer() *ui.Element {
+	if c := d.GetElementById(CustomElementsContainerID); c != nil {
+		return c
+	}
+	c := d.Div.WithID(CustomElementsContainerID).AsElement()
+	SetAttribute(c, "hidden", "")
+	d.Head().AppendChild(c)
+	return c
+}
+
+// customElementContent returns the element built for a custom element instance, if any.
+func (d *Document) customElementContent(host js.Value) *ui.Element {
+	id := host.Get(customElementHostKey)
+	if !id.Truthy() {
+		return nil
+	}
+	return d.GetElementById(id.String())
+}
+
+func (d *Document) connectCustomElement(host js.Value, build func(*Document) *ui.Element, observedAttributes []string) {
+	if !host.Get("isConnected").Bool() {
+		return
+	}
+	if e := d.customElementContent(host); e != nil {
+		// the instance was moved: the content moved with it.
+		return
+	}
+	e := build(d)
+	for _, name := range observedAttributes {
+		if v := host.Call("getAttribute", name); !v.IsNull() {
+			e.SetUI(name, ui.String(v.String()))
+		}
+	}
+	d.customElementsContainer().AppendChild(e)
+	n, ok := JSValue(e)
+	if !ok {
+		panic("custom element content is not connected to a native element")
+	}
+	host.Call("append", n)
+	host.Set(customElementHostKey, e.ID)
+}
+
+func (d *Document) disconnectCustomElement(host js.Value) {
+	if host.Get("isConnected").Bool() {
+		return // the instance was moved
+	}
+	e := d.customElementContent(host)
+	if e == nil {
+		return
+	}
+	host.Set(customElementHostKey, js.Undefined())
+	d.customElementsContainer().DeleteChild(e)
+}
+
+// CustomElement wraps an instance of a custom element.
+type CustomElement struct {
+	*ui.Element
+}
+
+var customElementConstructors = newscsmap[string, func(id string, optionNames ...string) *ui.Element]()
+
+func customElementConstructor(tag string) func(id string, optionNames ...string) *ui.Element {
+	if c, ok := customElementConstructors.Get(tag); ok {
+		return c
+	}
+	c := Elements.NewConstructor(tag, func(id string) *ui.Element {
+		e := Elements.NewElement(id, DOCTYPE)
+		e = enableClasses(e)
+		ConnectNative(e, tag)
+		return e
+	}, AllowSessionStoragePersistence, AllowAppLocalStoragePersistence)
+	customElementConstructors.Set(tag, c)
+	return c
+}
+
+// CustomElement returns a new instance of the custom element named tag.
+// An id may be provided. Otherwise, one is generated.
+func (d *Document) CustomElement(tag string, id ...string) CustomElement {
+	if !strings.Contains(tag, "-") {
+		panic("invalid custom element name, it should contain a hyphen: " + tag)
+	}
+	start := d.constructionStart()
+	eid := d.newID()
+	if len(id) > 0 && id[0] != "" {
+		eid = id[0]
+	}
+	e := customElementConstructor(tag)(eid)
+	ui.RegisterElement(d.AsElement(), e)
+	d.recordConstruction(e, start)
+	return CustomElement{e}
+}
+
+// BindAttribute reflects the ui property of the same name onto the attribute. A String is used as is, a
+// Number is formatted, and a Bool adds or removes the attribute.
+func (c CustomElement) BindAttribute(name string) CustomElement {
+	e := c.AsElement()
+	e.Watch(Namespace.UI, name, e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		switch v := evt.NewValue().(type) {
+		case ui.String:
+			SetAttribute(evt.Origin(), name, string(v))
+		case ui.Number:
+			SetAttribute(evt.Origin(), name, strconv.FormatFloat(float64(v), 'f', -1, 64))
+		case ui.Bool:
+			if v {
+				SetAttribute(evt.Origin(), name, "")
+			} else {
+				RemoveAttribute(evt.Origin(), name)
+			}
+		default:
+			DEBUG("unsupported attribute value type for ", name)
+		}
+		return false
+	}).RunASAP())
+	return c
+}
+
+// BindProperty sets the native property of the same name whenever the ui property changes.
+// Objects and Lists are converted to plain javascript objects and arrays.
+func (c CustomElement) BindProperty(name string) CustomElement {
+	e := c.AsElement()
+	e.Watch(Namespace.UI, name, e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if !InBrowser() {
+			return false
+		}
+		n, ok := JSValue(evt.Origin())
+		if !ok {
+			return false
+		}
+		n.Set(name, valueToJS(evt.NewValue()))
+		return false
+	}).RunASAP())
+	return c
+}
+
+// SyncProperty updates the ui property of the same name with the value of the native property each time the
+// event is dispatched on the element, typically a "change" event of the component.
+func (c CustomElement) SyncProperty(name string, event string) CustomElement {
+	e := c.AsElement()
+	e.AddEventListener(event, ui.NewEventHandler(func(evt ui.Event) bool {
+		n, ok := JSValue(e)
+		if !ok {
+			return false
+		}
+		if v := jsToValue(n.Get(name)); v != nil {
+			e.SetUI(name, v)
+		}
+		return false
+	}))
+	return c
+}
+
+// jsToValue converts a plain javascript value into a ui.Value. It returns nil for undefined, null and values
+// that cannot be converted, such as functions.
+func jsToValue(v js.Value) ui.Value {
+	switch v.Type() {
+	case js.TypeBoolean:
+		return ui.Bool(v.Bool())
+	case js.TypeNumber:
+		return ui.Number(v.Float())
+	case js.TypeString:
+		return ui.String(v.String())
+	case js.TypeObject:
+		if js.Global().Get("Array").Call("isArray", v).Bool() {
+			l := ui.NewList()
+			for i := 0; i < v.Length(); i++ {
+				if w := jsToValue(v.Index(i)); w != nil {
+					l = l.Append(w)
+				}
+			}
+			return l.Commit()
+		}
+		o := ui.NewObject()
+		keys := js.Global().Get("Object").Call("keys", v)
+		for i := 0; i < keys.Length(); i++ {
+			k := keys.Index(i).String()
+			if w := jsToValue(v.Get(k)); w != nil {
+				o.Set(k, w)
+			}
+		}
+		return o.Commit()
+	}
+	return nil
+}
+
+// valueToJS converts a ui.Value into a value that can be passed to javascript.
+func valueToJS(v ui.Value) interface{} {
+	switch v := v.(type) {
+	case ui.Bool:
+		return bool(v)
+	case ui.Number:
+		return float64(v)
+	case ui.String:
+		return string(v)
+	case ui.List:
+		res := make([]interface{}, 0, len(v.UnsafelyUnwrap()))
+		for _, w := range v.UnsafelyUnwrap() {
+			res = append(res, valueToJS(w))
+		}
+		return res
+	case ui.Object:
+		res := make(map[string]interface{})
+		v.Range(func(k string, w ui.Value) bool {
+			res[k] = valueToJS(w)
+			return false
+		})
+		return res
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package doc
+
+import (
+	ui "github.com/atdiar/particleui"
+)
+
+// Diagnostics
+//
+// Misuses of the framework that can be recovered from (an element that is not connected to its native
+// counterpart, a native value of the wrong type...) are reported as FrameworkErrors instead of panicking.
+// A report is logged and triggers a "framework-error" event on the document, whose value is an Object holding
+// the "code", "message", "element" id and "severity" of the error, so that apps can render a friendly error UI.
+// In dev mode, reports can be turned into panics with PanicOnFrameworkError in order to surface them early.
+// Profiles with PanicOnError set always panic.
+
+// PanicOnFrameworkError makes reported framework errors panic in dev mode.
+var PanicOnFrameworkError = false
+
+// Severity of a FrameworkError.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Framework error codes.
+const (
+	ErrCodeNotConnected    = "not-connected"
+	ErrCodeWrongNativeType = "wrong-native-type"
+	ErrCodeMissingElement  = "missing-element"
+)
+
+// FrameworkError describes a misuse of the framework.
+type FrameworkError struct {
+	Code      string
+	Message   string
+	ElementID string
+	Severity  Severity
+}
+
+func (f FrameworkError) Error() string {
+	s := "zui " + f.Severity.String() + " [" + f.Code + "]: " + f.Message
+	if f.ElementID != "" {
+		s += " (element: " + f.ElementID + ")"
+	}
+	return s
+}
+
+// ReportError logs a framework error related to an element and notifies the document of it.
+func ReportError(e *ui.Element, f FrameworkError) {
+	if e != nil && f.ElementID == "" {
+		f.ElementID = e.ID
+	}
+	DEBUG(f.Error())
+
+	if (DevMode != "false" && PanicOnFrameworkError) || profile().PanicOnError {
+		panic(f)
+	}
+
+	if e == nil || e.Root == nil {
+		return
+	}
+	d, ok := documents.Get(e.Root)
+	if !ok {
+		return
+	}
+	d.TriggerEvent("framework-error", ui.NewObject().
+		Set("code", ui.String(f.Code)).
+		Set("message", ui.String(f.Message)).
+		Set("element", ui.String(f.ElementID)).
+		Set("severity", ui.String(f.Severity.String())).
+		Commit())
+}
+
+// OnFrameworkError registers a function called with every framework error reported for the document.
+func (d *Document) OnFrameworkError(fn func(FrameworkError)) {
+	d.WatchEvent("framework-error", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		o, ok := evt.NewValue().(ui.Object)
+		if !ok {
+			return false
+		}
+		f := FrameworkError{
+			Code:      string(o.MustGetString("code")),
+			Message:   string(o.MustGetString("message")),
+			ElementID: string(o.MustGetString("element")),
+		}
+		if o.MustGetString("severity") == "error" {
+			f.Severity = SeverityError
+		}
+		fn(f)
+		return false
+	}))
+}
+
+func notConnected(e *ui.Element) {
+	ReportError(e, FrameworkError{Code: ErrCodeNotConnected, Message: "element is not connected to its native DOM element", Severity: SeverityError})
+}
+
+func wrongNativeType(e *ui.Element) {
+	ReportError(e, FrameworkError{Code: ErrCodeWrongNativeType, Message: "native element should be of doc.NativeElement type", Severity: SeverityError})
+}
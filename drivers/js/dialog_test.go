@@ -0,0 +1,63 @@
+package doc
+
+import (
+	"strconv"
+	"testing"
+
+	ui "github.com/atdiar/particleui"
+)
+
+func TestDialogOpenClose(t *testing.T) {
+	d := newTestDocument(t, "dialogtest")
+	dialog := d.Dialog.WithID("dialog")
+	d.Body().AppendChild(dialog)
+
+	if dialog.IsOpened() {
+		t.Fatal("dialog should be closed on creation")
+	}
+	dialog.Open()
+	if !dialog.IsOpened() {
+		t.Fatal("dialog should be opened")
+	}
+	dialog.Close()
+	if dialog.IsOpened() {
+		t.Fatal("dialog should be closed")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	d := newTestDocument(t, "confirmtest")
+
+	for _, tc := range []struct {
+		button string
+		answer bool
+	}{
+		{"ok", true},
+		{"cancel", false},
+	} {
+		ch := d.Confirm("Proceed?")
+		n, _ := d.Get(Namespace.Internals, "modal-count")
+		id := "zui-modal-" + strconv.Itoa(int(n.(ui.Number)))
+		dialog := DialogElement{d.GetElementById(id)}
+		if dialog.Element == nil || !dialog.IsOpened() {
+			t.Fatalf("%s: the confirm dialog should be opened", tc.button)
+		}
+
+		button := d.GetElementById(id + "-" + tc.button)
+		click := ui.NewEvent("click", true, true, button, button, nil, nil)
+		click.SetPhase(2)
+		button.Handle(click)
+
+		select {
+		case answer := <-ch:
+			if answer != tc.answer {
+				t.Errorf("%s: got %v, expected %v", tc.button, answer, tc.answer)
+			}
+		default:
+			t.Fatalf("%s: the confirm dialog should have been answered", tc.button)
+		}
+		if dialog.IsOpened() || dialog.Parent != nil {
+			t.Errorf("%s: the dismissed dialog should have been closed and removed", tc.button)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package doc
+
+import (
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Modal questions
+//
+// Confirm, Prompt and Alert are replacements for window.confirm, window.prompt and window.alert.
+// They are built from a DialogElement appended to the document body, so that they can be styled
+// (zui-modal class and its sub-classes) and translated (DialogLabels), and they trap the focus while open.
+// The focus is restored to the previously focused element once the dialog is dismissed.
+//
+// They should be called from the UI thread, e.g. from a mutation handler. The answer is delivered
+// asynchronously over the returned channel, which can be waited upon in a DoAsync goroutine:
+//
+//	ch := d.Confirm("Delete this item?")
+//	ui.DoAsync(e, func(ctx context.Context) {
+//		if <-ch {
+//			ui.DoSync(func() { ... })
+//		}
+//	})
+
+// DialogLabels holds the default button labels of the modal dialogs. It may be changed for i18n purposes.
+var DialogLabels = struct {
+	OK     string
+	Cancel string
+}{"OK", "Cancel"}
+
+// DialogOptions allows to customize a modal dialog.
+type DialogOptions struct {
+	Title  string
+	OK     string // label of the confirmation button. Defaults to DialogLabels.OK
+	Cancel string // label of the cancellation button. Defaults to DialogLabels.Cancel
+	Value  string // initial value of the Prompt input
+	Class  string // additional class added to the dialog element
+}
+
+func dialogOptions(opts []DialogOptions) DialogOptions {
+	var o DialogOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.OK == "" {
+		o.OK = DialogLabels.OK
+	}
+	if o.Cancel == "" {
+		o.Cancel = DialogLabels.Cancel
+	}
+	return o
+}
+
+// Confirm displays a modal question. The channel receives true if the user confirmed, false otherwise.
+func (d *Document) Confirm(msg string, opts ...DialogOptions) <-chan bool {
+	ch := make(chan bool, 1)
+	d.modal("confirm", msg, dialogOptions(opts), func(ok bool, value string) {
+		ch <- ok
+	})
+	return ch
+}
+
+// Prompt displays a modal text input. The channel receives the text entered if the user confirmed.
+// It is closed without receiving any value if the user cancelled.
+func (d *Document) Prompt(msg string, opts ...DialogOptions) <-chan string {
+	ch := make(chan string, 1)
+	d.modal("prompt", msg, dialogOptions(opts), func(ok bool, value string) {
+		if ok {
+			ch <- value
+		}
+		close(ch)
+	})
+	return ch
+}
+
+// Alert displays a modal message. The channel is closed once the message has been dismissed.
+func (d *Document) Alert(msg string, opts ...DialogOptions) <-chan struct{} {
+	ch := make(chan struct{})
+	d.modal("alert", msg, dialogOptions(opts), func(ok bool, value string) {
+		close(ch)
+	})
+	return ch
+}
+
+func (d *Document) modal(kind string, msg string, o DialogOptions, answer func(ok bool, value string)) {
+	n := 1
+	if v, ok := d.Get(Namespace.Internals, "modal-count"); ok {
+		n = int(v.(ui.Number)) + 1
+	}
+	d.Set(Namespace.Internals, "modal-count", ui.Number(n))
+	id := "zui-modal-" + strconv.Itoa(n)
+
+	dialog := d.Dialog.WithID(id)
+	AddClass(dialog.AsElement(), "zui-modal")
+	AddClass(dialog.AsElement(), "zui-modal-"+kind)
+	if o.Class != "" {
+		AddClass(dialog.AsElement(), o.Class)
+	}
+	if kind == "prompt" {
+		SetAttribute(dialog.AsElement(), "role", "dialog")
+	} else {
+		SetAttribute(dialog.AsElement(), "role", "alertdialog")
+	}
+	SetAttribute(dialog.AsElement(), "aria-modal", "true")
+	SetAttribute(dialog.AsElement(), "aria-describedby", id+"-message")
+
+	children := make([]*ui.Element, 0, 4)
+	if o.Title != "" {
+		title := d.H2.WithID(id + "-title").SetText(o.Title)
+		AddClass(title.AsElement(), "zui-modal-title")
+		SetAttribute(dialog.AsElement(), "aria-labelledby", id+"-title")
+		children = append(children, title.AsElement())
+	}
+	message := d.Paragraph.WithID(id + "-message").SetText(msg)
+	AddClass(message.AsElement(), "zui-modal-message")
+	children = append(children, message.AsElement())
+
+	var input InputElement
+	// the dialog is dismissed only once, by whichever of its buttons or keys comes first.
+	var dismissed bool
+	dismiss := func(ok bool) {
+		if dismissed {
+			return
+		}
+		dismissed = true
+		d.dismissModal(dialog, ok, answer, input)
+	}
+	if kind == "prompt" {
+		input = d.Input.WithID(id+"-input", "text")
+		SyncValueOnInput()(input.AsElement())
+		input.AsElement().SetDataSetUI("value", ui.String(o.Value))
+		SetAttribute(input.AsElement(), "aria-labelledby", id+"-message")
+		AddClass(input.AsElement(), "zui-modal-input")
+		children = append(children, input.AsElement())
+	}
+
+	actions := d.Div.WithID(id + "-actions")
+	AddClass(actions.AsElement(), "zui-modal-actions")
+	ok := d.Button.WithID(id+"-ok", "button").SetText(o.OK)
+	AddClass(ok.AsElement(), "zui-modal-ok")
+	if kind != "alert" {
+		cancel := d.Button.WithID(id+"-cancel", "button").SetText(o.Cancel)
+		AddClass(cancel.AsElement(), "zui-modal-cancel")
+		actions.AsElement().SetChildren(cancel.AsElement(), ok.AsElement())
+		cancel.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+			dismiss(false)
+			return false
+		}))
+	} else {
+		actions.AsElement().SetChildren(ok.AsElement())
+	}
+	children = append(children, actions.AsElement())
+	dialog.AsElement().SetChildren(children...)
+
+	ok.AsElement().AddEventListener("click", ui.NewEventHandler(func(evt ui.Event) bool {
+		dismiss(true)
+		return false
+	}))
+
+	dialog.AsElement().AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		k, isk := evt.(KeyboardEvent)
+		if !isk {
+			return false
+		}
+		switch k.Key() {
+		case "Escape":
+			evt.PreventDefault()
+			dismiss(kind == "alert")
+		case "Enter":
+			if kind == "prompt" {
+				evt.PreventDefault()
+				dismiss(true)
+			}
+		}
+		return false
+	}))
+
+	// the previously focused element gets the focus back once the dialog is dismissed.
+	// The page underneath does not scroll in the meantime.
+	if InBrowser() {
+		previous := js.Global().Get("document").Get("activeElement")
+		unlock := LockScroll(d)
+		dialog.AsElement().OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			unlock()
+			if previous.Truthy() {
+				focus(previous)
+			}
+			return false
+		}).RunOnce())
+	}
+
+	TrapFocus(dialog.AsElement())
+	d.Body().AppendChild(dialog)
+	dialog.Open()
+
+	if kind == "prompt" {
+		SetFocus(input, false)
+	} else {
+		SetFocus(ok, false)
+	}
+}
+
+func (d *Document) dismissModal(dialog DialogElement, ok bool, answer func(bool, string), input InputElement) {
+	var value string
+	if input.Element != nil {
+		value = string(input.Value())
+	}
+	dialog.Close()
+	ui.Delete(dialog.AsElement())
+	answer(ok, value)
+}
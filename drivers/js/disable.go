@@ -0,0 +1,98 @@
+package doc
+
+import (
+	ui "github.com/atdiar/particleui"
+)
+
+// Subtree disabling
+//
+// SetSubtreeDisabled gives fieldset semantics to any element: every interactive element of its logical subtree
+// is disabled at once and recovers its prior state when the subtree is enabled again.
+// Every element of the subtree also gets an "is-disabled" ui property (ui.Bool) that components may watch to
+// adjust their own behavior.
+// Calls may be nested: an element is only re-enabled once every subtree it was disabled with is enabled again.
+// Elements added to the subtree after it has been disabled are not affected.
+
+// disableable lists the constructors of the elements that support the disabled attribute.
+var disableable = []string{"button", "input", "select", "textarea", "fieldset", "option", "optgroup"}
+
+// SetSubtreeDisabled disables or enables all the interactive elements within the subtree of e, e included.
+func SetSubtreeDisabled(e *ui.Element, disabled bool) {
+	native := ui.ByConstructor(disableable...)
+	anchor := ui.ByConstructor("a")
+	for _, el := range ui.QuerySubtree(e) {
+		count := 0
+		if v, ok := el.Get(Namespace.Internals, "subtree-disabled"); ok {
+			count = int(v.(ui.Number))
+		}
+		if disabled {
+			count++
+		} else if count > 0 {
+			count--
+		} else {
+			continue
+		}
+		el.Set(Namespace.Internals, "subtree-disabled", ui.Number(count))
+
+		// only transitions matter
+		if (disabled && count != 1) || (!disabled && count != 0) {
+			continue
+		}
+
+		el.SetUI("is-disabled", ui.Bool(disabled))
+
+		switch {
+		case native(el):
+			if disabled {
+				prior := ui.Bool(false)
+				if v, ok := el.GetUI("disabled"); ok {
+					prior = v.(ui.Bool)
+				}
+				el.Set(Namespace.Internals, "disabled-before-subtree", prior)
+				el.SetDataSetUI("disabled", ui.Bool(true))
+				continue
+			}
+			prior := ui.Bool(false)
+			if v, ok := el.Get(Namespace.Internals, "disabled-before-subtree"); ok {
+				prior = v.(ui.Bool)
+			}
+			el.SetDataSetUI("disabled", prior)
+
+		case anchor(el):
+			if disabled {
+				SetAttribute(el, "aria-disabled", "true")
+				el.Set(Namespace.Internals, "tabindex-before-subtree", ui.String(GetAttribute(el, "tabindex")))
+				SetAttribute(el, "tabindex", "-1")
+				if _, ok := el.Get(Namespace.Internals, "subtree-click-guard"); !ok {
+					el.Set(Namespace.Internals, "subtree-click-guard", ui.Bool(true))
+					el.AddEventListener("click", disabledClickGuard)
+				}
+				continue
+			}
+			RemoveAttribute(el, "aria-disabled")
+			if v, ok := el.Get(Namespace.Internals, "tabindex-before-subtree"); ok && string(v.(ui.String)) != "" {
+				SetAttribute(el, "tabindex", string(v.(ui.String)))
+			} else {
+				RemoveAttribute(el, "tabindex")
+			}
+		}
+	}
+}
+
+// disabledClickGuard prevents the activation of disabled links.
+var disabledClickGuard = ui.NewEventHandler(func(evt ui.Event) bool {
+	if IsSubtreeDisabled(evt.CurrentTarget()) {
+		evt.PreventDefault()
+		evt.StopImmediatePropagation()
+	}
+	return false
+}).ForCapture()
+
+// IsSubtreeDisabled returns whether an element belongs to a disabled subtree.
+func IsSubtreeDisabled(e *ui.Element) bool {
+	v, ok := e.GetUI("is-disabled")
+	if !ok {
+		return false
+	}
+	return bool(v.(ui.Bool))
+}
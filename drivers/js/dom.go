@@ -136,17 +136,21 @@ func SerializeStateHistory(e *ui.Element) string {
 	}
 	state := sth.(ui.List)
 
-	return stringify(state.RawValue())
+	s, err := ui.EncodeText(ui.PersistenceCodec, state)
+	if err != nil {
+		panic(err)
+	}
+	return s
 }
 
+// DeserializeStateHistory decodes a state history serialized by SerializeStateHistory or SerializeSSRState,
+// including one serialized by a previous version of the framework.
 func DeserializeStateHistory(rawstate string) (ui.Value, error) {
-	state := ui.NewObject()
-	err := json.Unmarshal([]byte(rawstate), &state)
+	rawstate, err := decompressState(rawstate)
 	if err != nil {
 		return nil, err
 	}
-
-	return state.Value(), nil
+	return ui.DecodeText(rawstate)
 }
 
 var dEBUGJS = func(v js.Value, isJsonString ...bool) {
@@ -184,29 +188,46 @@ func NewNativeHTMLElement(v js.Value) NativeElement {
 }
 
 func (n NativeElement) AppendChild(child *ui.Element) {
+	if insertedInZone(child) {
+		return
+	}
 	v, ok := child.Native.(NativeElement)
 	if !ok {
 		log.Print("wrong format for native element underlying objects.Cannot append " + child.ID)
 		return
 	}
 	if n.typ == "HTMLElement" {
+		if last := n.Value.Get("lastElementChild"); isSentinel(last) {
+			n.Value.Call("insertBefore", v.Value, last)
+			return
+		}
 		n.Value.Call("append", v.Value)
 	}
 
 }
 
 func (n NativeElement) PrependChild(child *ui.Element) {
+	if insertedInZone(child) {
+		return
+	}
 	v, ok := child.Native.(NativeElement)
 	if !ok {
 		log.Print("wrong format for native element underlying objects.Cannot prepend " + child.ID)
 		return
 	}
 	if n.typ == "HTMLElement" {
+		if first := n.Value.Get("firstElementChild"); isSentinel(first) {
+			first.Call("after", v.Value)
+			return
+		}
 		n.Value.Call("prepend", v.Value)
 	}
 }
 
 func (n NativeElement) InsertChild(child *ui.Element, index int) {
+	if insertedInZone(child) {
+		return
+	}
 	v, ok := child.Native.(NativeElement)
 	if !ok {
 		log.Print("wrong format for native element underlying objects.Cannot insert " + child.ID)
@@ -214,6 +235,10 @@ func (n NativeElement) InsertChild(child *ui.Element, index int) {
 	}
 	if n.typ == "HTMLElement" {
 		childlist := n.Value.Get("children")
+		// sentinels are not part of the element tree and do not count.
+		if hasSentinels(n.Value) {
+			childlist = n.Value.Call("querySelectorAll", ":scope > :not([data-zui-sentinel])")
+		}
 		length := childlist.Get("length").Int()
 		if index > length {
 			log.Print("insertion attempt out of bounds.")
@@ -221,6 +246,10 @@ func (n NativeElement) InsertChild(child *ui.Element, index int) {
 		}
 
 		if index == length {
+			if last := n.Value.Get("lastElementChild"); isSentinel(last) {
+				n.Value.Call("insertBefore", v.Value, last)
+				return
+			}
 			n.Value.Call("append", v.Value)
 			return
 		}
@@ -230,6 +259,9 @@ func (n NativeElement) InsertChild(child *ui.Element, index int) {
 }
 
 func (n NativeElement) ReplaceChild(old *ui.Element, new *ui.Element) {
+	if insertedInZone(new) {
+		return
+	}
 	nold, ok := old.Native.(NativeElement)
 	if !ok {
 		log.Print("wrong format for native element underlying objects.Cannot replace " + old.ID)
@@ -807,6 +839,7 @@ var routerConfig = func(r *ui.Router) {
 
 	r.History.NewState = ns
 	r.History.RecoverState = rs
+	r.SetHistoryBackend(browserHistory{})
 
 	r.History.AppRoot.WatchEvent("history-change", r.History.AppRoot, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 		PutInStorage(r.History.State[r.History.Cursor].AsElement())
@@ -814,6 +847,21 @@ var routerConfig = func(r *ui.Router) {
 	}))
 
 	doc := GetDocument(r.Outlet.AsElement())
+
+	// The HTTP response metadata is reset each time the current route changes.
+	doc.Watch(Namespace.UI, "currentroute", doc, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		resetResponse(doc)
+		return false
+	}))
+
+	// Critical resources of the views warmed by link prefetching.
+	doc.WatchEvent("prefetch-resource", doc, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if href, ok := evt.NewValue().(ui.String); ok && InBrowser() {
+			doc.PrefetchResource(string(href))
+		}
+		return false
+	}))
+
 	// Add default navigation error handlers
 	// notfound:
 	pnf := doc.Div.WithID(r.Outlet.AsElement().Root.ID + "-notfound").SetText("Page Not Found.")
@@ -827,6 +875,7 @@ var routerConfig = func(r *ui.Router) {
 		}
 		document := GetDocument(r.Outlet.AsElement())
 		document.Window().SetTitle("Page Not Found")
+		document.SetResponseStatus(http.StatusNotFound)
 
 		tv := ui.ViewElement{document.GetElementById(v.(ui.String).String())}
 		if tv.HasStaticView("notfound") {
@@ -854,6 +903,7 @@ var routerConfig = func(r *ui.Router) {
 
 		document := GetDocument(r.Outlet.AsElement())
 		document.Window().SetTitle("Unauthorized")
+		document.SetResponseStatus(http.StatusUnauthorized)
 
 		tv := ui.ViewElement{GetDocument(r.Outlet.AsElement()).GetElementById(v.(ui.String).String())}
 		if tv.HasStaticView("unauthorized") {
@@ -865,11 +915,36 @@ var routerConfig = func(r *ui.Router) {
 	}))
 
 	// appfailure
+	// The error view registered for the failed route is displayed if any, or a generic failure message.
 	afd := doc.Div.WithID(" -appfailure").SetText("App Failure")
+	SetAttribute(afd.AsElement(), "role", "alert")
 	r.OnAppfailure(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 		document := GetDocument(r.Outlet.AsElement())
 		document.Window().SetTitle("App Failure")
-		r.Outlet.AsElement().Root.SetChildren(afd.AsElement())
+		document.SetResponseStatus(http.StatusInternalServerError)
+
+		body := document.Body().AsElement()
+		var errobj ui.Object
+		switch p := evt.NewValue().(type) {
+		case ui.Object:
+			errobj = p
+		case ui.String:
+			errobj = ui.NewNavigationError(string(p), "navigation", nil)
+		default:
+			body.SetChildren(afd.AsElement())
+			return false
+		}
+		DEBUG("navigation failure: ", errobj.MustGetString("message"))
+		v, ev, ok := r.ErrorView(errobj)
+		if !ok {
+			body.SetChildren(afd.AsElement())
+			return false
+		}
+		if v.AsElement() != nil {
+			v.AsElement().SetChildren(ev)
+			return false
+		}
+		body.SetChildren(ev)
 		return false
 	}))
 
@@ -889,13 +964,15 @@ type constiface[T any] interface {
 type gconstructor[T ui.AnyElement, U constiface[T]] func() T
 
 func (c *gconstructor[T, U]) WithID(id string, options ...string) T {
-	var u U
-	e := u.WithID(id, options...)
 	d := c.owner()
 	if d == nil {
 		panic("constructor should have an owner")
 	}
+	start := d.constructionStart()
+	var u U
+	e := u.WithID(id, options...)
 	ui.RegisterElement(d.AsElement(), e.AsElement()) // DEBUG
+	d.recordConstruction(e.AsElement(), start)
 
 	return e
 }
@@ -926,13 +1003,15 @@ type buttonconstiface[T any] interface {
 type buttongconstructor[T ui.AnyElement, U buttonconstiface[T]] func(typ ...string) T
 
 func (c *buttongconstructor[T, U]) WithID(id string, typ string, options ...string) T {
-	var u U
-	e := u.WithID(id, typ, options...)
 	d := c.owner()
 	if d == nil {
 		panic("constructor should have an owner")
 	}
+	start := d.constructionStart()
+	var u U
+	e := u.WithID(id, typ, options...)
 	ui.RegisterElement(d.AsElement(), e.AsElement()) // DEBUG
+	d.recordConstruction(e.AsElement(), start)
 
 	return e
 }
@@ -959,13 +1038,15 @@ type inputconstiface[T any] interface {
 type inputgconstructor[T ui.AnyElement, U inputconstiface[T]] func(typ string) T
 
 func (c *inputgconstructor[T, U]) WithID(id string, typ string, options ...string) T {
-	var u U
-	e := u.WithID(id, typ, options...)
 	d := c.owner()
 	if d == nil {
 		panic("constructor should have an owner")
 	}
+	start := d.constructionStart()
+	var u U
+	e := u.WithID(id, typ, options...)
 	ui.RegisterElement(d.AsElement(), e.AsElement()) // DEBUG
+	d.recordConstruction(e.AsElement(), start)
 
 	return e
 }
@@ -992,13 +1073,15 @@ type olconstiface[T any] interface {
 type olgconstructor[T ui.AnyElement, U olconstiface[T]] func(typ string, offset int) T
 
 func (c *olgconstructor[T, U]) WithID(id string, typ string, offset int, options ...string) T {
-	var u U
-	e := u.WithID(id, typ, offset, options...)
 	d := c.owner()
 	if d == nil {
 		panic("constructor should have an owner")
 	}
+	start := d.constructionStart()
+	var u U
+	e := u.WithID(id, typ, offset, options...)
 	ui.RegisterElement(d.AsElement(), e.AsElement()) // DEBUG
+	d.recordConstruction(e.AsElement(), start)
 
 	return e
 }
@@ -1025,13 +1108,15 @@ type iframeconstiface[T any] interface {
 type iframeconstructor[T ui.AnyElement, U iframeconstiface[T]] func() T
 
 func (c *iframeconstructor[T, U]) WithID(id string, src string, options ...string) T {
-	var u U
-	e := u.WithID(id, src, options...)
 	d := c.owner()
 	if d == nil {
 		panic("constructor should have an owner")
 	}
+	start := d.constructionStart()
+	var u U
+	e := u.WithID(id, src, options...)
 	ui.RegisterElement(d.AsElement(), e.AsElement()) // DEBUG
+	d.recordConstruction(e.AsElement(), start)
 
 	return e
 }
@@ -1120,6 +1205,17 @@ type Document struct {
 	StyleSheets   map[string]StyleSheet
 	HttpClient    *http.Client
 	DBConnections map[string]js.Value
+
+	timings     *lifecycleTimings
+	storageKeys *storageKeyring
+	speech      *speechState
+
+	constructionHooks []ConstructionHook
+	routeUsage        RouteUsage
+	replayReport      ui.ReplayReport
+	deferredReplay    *deferredReplay
+	streamBoundaries  []*ui.Element
+	streamedReplay    *streamedReplay
 }
 
 /*
@@ -1436,85 +1532,302 @@ func (s StyleSheet) AsElement() *ui.Element {
 	return s.raw
 }
 
-func (s StyleSheet) InsertRule(selector string, ruleset string) StyleSheet {
-	o := ui.NewObject().Set(selector, ui.String(ruleset)).Commit()
+// Stylesheet rules
+//
+// A stylesheet is stored as an ordered list of rules. Each rule is a single-entry ui.Object whose key is
+// the rule selector (or the at-rule prelude, e.g. "@media (max-width: 600px)") and whose value is either:
+//   - a ui.String holding the declarations of the rule (or its verbatim body when it uses CSS nesting)
+//   - a ui.List of nested rules, for grouping at-rules such as @media, @supports or @keyframes
+//
+// Statement at-rules such as @import are stored with an empty body.
+// Rules are addressed by key. Since a key may be used several times (e.g. several @font-face rules),
+// updating or removing a rule applies to every rule with that key.
+// As for InsertRule, Update should be called for the modifications to be applied to the native stylesheet.
+
+// groupingAtRules lists the at-rules whose body is made of nested rules.
+var groupingAtRules = newset("media", "supports", "container", "layer", "document", "scope", "starting-style", "keyframes", "-webkit-keyframes", "-moz-keyframes")
+
+// statementAtRules lists the at-rules that may be used without a body.
+var statementAtRules = newset("import", "charset", "namespace", "layer")
 
+func atRuleName(selector string) (string, bool) {
+	if !strings.HasPrefix(selector, "@") {
+		return "", false
+	}
+	name := selector[1:]
+	if i := strings.IndexAny(name, " \t\r\n({;"); i >= 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name), true
+}
+
+func ruleEntry(rule ui.Value) (key string, value ui.Value) {
+	rule.(ui.Object).Range(func(k string, v ui.Value) bool {
+		key, value = k, v
+		return true
+	})
+	return key, value
+}
+
+func newRule(key string, value ui.Value) ui.Object {
+	return ui.NewObject().Set(key, value).Commit()
+}
+
+// findRule returns the value of the first rule with the given key.
+func findRule(rules ui.List, key string) (ui.Value, bool) {
+	for _, r := range rules.UnsafelyUnwrap() {
+		if k, v := ruleEntry(r); k == key {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// replaceRule returns a list of rules where the first rule with the given key has its value replaced and
+// the other rules with that key are dropped. If value is nil, all the rules with that key are removed.
+// If no rule matches, a new rule is appended.
+func replaceRule(rules ui.List, key string, value ui.Value) ui.List {
+	res := ui.NewList()
+	done := value == nil
+	for _, r := range rules.UnsafelyUnwrap() {
+		if k, _ := ruleEntry(r); k != key {
+			res.Append(r)
+			continue
+		}
+		if !done {
+			res.Append(newRule(key, value))
+			done = true
+		}
+	}
+	if !done {
+		res.Append(newRule(key, value))
+	}
+	return res.Commit()
+}
+
+func (s StyleSheet) rules() ui.List {
 	r, ok := s.raw.GetData("stylesheet")
 	if !ok {
-		rulelist := ui.NewList(o).Commit()
-		s.raw.SetData("stylesheet", rulelist)
-		return s
+		return ui.NewList().Commit()
 	}
-	rulelist := r.(ui.List).MakeCopy()
-	s.raw.SetData("stylesheet", rulelist.Append(o).Commit())
+	return r.(ui.List)
+}
+
+func (s StyleSheet) InsertRule(selector string, ruleset string) StyleSheet {
+	s.raw.SetData("stylesheet", s.rules().MakeCopy().Append(newRule(selector, ui.String(ruleset))).Commit())
 	return s
 }
 
-func (s *StyleSheet) FromRawstring(text string) *StyleSheet {
-	text = strings.TrimSpace(text)
-	for len(text) > 0 {
-		selector, body, remainder := parseNextRule(text)
-		if selector != "" && body != "" {
-			s.InsertRule(selector, body)
+// InsertNestedRule inserts a rule within a grouping at-rule such as @media or @keyframes.
+// The grouping rule is created if it does not exist.
+//
+// e.g. s.InsertNestedRule("@media (max-width: 600px)", ".sidebar", "display: none;")
+func (s StyleSheet) InsertNestedRule(parent string, selector string, ruleset string) StyleSheet {
+	group := ui.NewList()
+	if v, ok := findRule(s.rules(), parent); ok {
+		if l, ok := v.(ui.List); ok {
+			group = l.MakeCopy()
 		}
-		text = remainder
 	}
+	group.Append(newRule(selector, ui.String(ruleset)))
+	s.raw.SetData("stylesheet", replaceRule(s.rules(), parent, group.Commit()))
 	return s
 }
 
-func parseNextRule(text string) (selector, body, remainder string) {
-	openIndex := strings.Index(text, "{")
-	if openIndex == -1 {
-		return "", "", ""
-	}
+// InsertMediaRule inserts a rule that only applies when the media query matches.
+func (s StyleSheet) InsertMediaRule(query string, selector string, ruleset string) StyleSheet {
+	return s.InsertNestedRule("@media "+query, selector, ruleset)
+}
 
-	selector = strings.TrimSpace(text[:openIndex])
-	text = text[openIndex+1:]
+// InsertKeyframe adds a keyframe (e.g. "from", "50%", "to") to the animation with the given name.
+func (s StyleSheet) InsertKeyframe(animation string, offset string, ruleset string) StyleSheet {
+	return s.InsertNestedRule("@keyframes "+animation, offset, ruleset)
+}
 
-	nestingLevel := 1
-	bodyEnd := 0
+// InsertFontFace adds a @font-face rule.
+func (s StyleSheet) InsertFontFace(descriptors string) StyleSheet {
+	return s.InsertRule("@font-face", descriptors)
+}
 
-	for i, char := range text {
-		if char == '{' {
-			nestingLevel++
-		} else if char == '}' {
-			nestingLevel--
-			if nestingLevel == 0 {
-				bodyEnd = i
-				break
-			}
+// UpdateRule replaces the ruleset of the rule with the given selector, or inserts it if it does not exist.
+func (s StyleSheet) UpdateRule(selector string, ruleset string) StyleSheet {
+	s.raw.SetData("stylesheet", replaceRule(s.rules(), selector, ui.String(ruleset)))
+	return s
+}
+
+// UpdateNestedRule replaces the ruleset of a rule nested within a grouping at-rule, or inserts it if it
+// does not exist.
+func (s StyleSheet) UpdateNestedRule(parent string, selector string, ruleset string) StyleSheet {
+	group := ui.NewList().Commit()
+	if v, ok := findRule(s.rules(), parent); ok {
+		if l, ok := v.(ui.List); ok {
+			group = l
 		}
 	}
+	s.raw.SetData("stylesheet", replaceRule(s.rules(), parent, replaceRule(group, selector, ui.String(ruleset))))
+	return s
+}
+
+// RemoveRule removes the rules with the given selector. For a grouping at-rule, the nested rules are
+// removed as well.
+func (s StyleSheet) RemoveRule(selector string) StyleSheet {
+	s.raw.SetData("stylesheet", replaceRule(s.rules(), selector, nil))
+	return s
+}
 
-	if bodyEnd == 0 {
-		return "", "", ""
+// RemoveNestedRule removes a rule nested within a grouping at-rule. The grouping rule is removed once
+// it is empty.
+func (s StyleSheet) RemoveNestedRule(parent string, selector string) StyleSheet {
+	v, ok := findRule(s.rules(), parent)
+	if !ok {
+		return s
 	}
+	group, ok := v.(ui.List)
+	if !ok {
+		return s
+	}
+	group = replaceRule(group, selector, nil)
+	if len(group.UnsafelyUnwrap()) == 0 {
+		return s.RemoveRule(parent)
+	}
+	s.raw.SetData("stylesheet", replaceRule(s.rules(), parent, group))
+	return s
+}
 
-	body = strings.TrimSpace(text[:bodyEnd])
-	remainder = strings.TrimSpace(text[bodyEnd+1:])
+func (s *StyleSheet) FromRawstring(text string) *StyleSheet {
+	rules := s.rules().MakeCopy()
+	rules.Append(parseRules(text)...)
+	s.raw.SetData("stylesheet", rules.Commit())
+	return s
+}
 
-	return selector, body, remainder
+// parseRules parses css text into a list of rules.
+func parseRules(text string) []ui.Value {
+	var res []ui.Value
+	text = strings.TrimSpace(stripCSSComments(text))
+	for len(text) > 0 {
+		selector, body, remainder, block := parseNextRule(text)
+		text = remainder
+		if selector == "" {
+			continue
+		}
+		name, atrule := atRuleName(selector)
+		if !block {
+			if atrule && statementAtRules.Contains(name) {
+				res = append(res, newRule(selector, ui.String("")))
+			}
+			continue
+		}
+		if atrule && groupingAtRules.Contains(name) {
+			res = append(res, newRule(selector, ui.NewList(parseRules(body)...).Commit()))
+			continue
+		}
+		if body != "" {
+			res = append(res, newRule(selector, ui.String(body)))
+		}
+	}
+	return res
+}
+
+// parseNextRule extracts the first rule of a css text. block is false for statements, which are terminated
+// by a semicolon instead of a body. Braces and semicolons found within quoted strings are ignored.
+func parseNextRule(text string) (selector, body, remainder string, block bool) {
+	depth := 0
+	start := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote = c
+		case ';':
+			if depth == 0 {
+				return strings.TrimSpace(text[:i]), "", strings.TrimSpace(text[i+1:]), false
+			}
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 { // unbalanced closing brace: skipped
+				return "", "", strings.TrimSpace(text[i+1:]), false
+			}
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(text[:start]), strings.TrimSpace(text[start+1 : i]), strings.TrimSpace(text[i+1:]), true
+			}
+		}
+	}
+	return "", "", "", false
+}
+
+func stripCSSComments(text string) string {
+	var res strings.Builder
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if quote != 0 {
+			res.WriteByte(c)
+			if c == '\\' && i+1 < len(text) {
+				i++
+				res.WriteByte(text[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '/' && i+1 < len(text) && text[i+1] == '*' {
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				break
+			}
+			i = i + 2 + end + 1
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+		}
+		res.WriteByte(c)
+	}
+	return res.String()
 }
 
 func (s StyleSheet) String() string {
 	var res strings.Builder
+	writeRules(&res, s.rules(), "")
+	return res.String()
+}
 
-	r, ok := s.raw.GetData("stylesheet")
-	if !ok {
-		return ""
-	}
-	rules := r.(ui.List).UnsafelyUnwrap()
-	for _, rule := range rules {
-		o := rule.(ui.Object)
-		o.Range(func(k string, v ui.Value) bool {
-			res.WriteString(k)
-			res.WriteString("{")
-			res.WriteString(v.(ui.String).String())
-			res.WriteString("}\n") // TODO check carriage return necessity
-			return false
-		})
+func writeRules(w *strings.Builder, rules ui.List, indent string) {
+	for _, rule := range rules.UnsafelyUnwrap() {
+		key, value := ruleEntry(rule)
+		w.WriteString(indent)
+		w.WriteString(key)
+		switch v := value.(type) {
+		case ui.List:
+			w.WriteString("{\n")
+			writeRules(w, v, indent+"  ")
+			w.WriteString(indent)
+			w.WriteString("}\n")
+		case ui.String:
+			if name, ok := atRuleName(key); ok && v == "" && statementAtRules.Contains(name) {
+				w.WriteString(";\n")
+				continue
+			}
+			w.WriteString("{")
+			w.WriteString(string(v))
+			w.WriteString("}\n")
+		}
 	}
-	return res.String()
 }
 
 func makeStyleSheet(observable *ui.Element, id string) *ui.Element {
@@ -1730,7 +2043,7 @@ func (m *mutationRecorder) Capture() {
 			if !ok {
 				m.raw.SetData("mutationlist", ui.NewList(v).Commit())
 			} else {
-				if len(list.UnsafelyUnwrap()) > CaptureLimit {
+				if len(list.UnsafelyUnwrap()) > captureLimit() {
 					DEBUG("mutation capture limit reached")
 					return false
 				}
@@ -1770,6 +2083,15 @@ func (m *mutationRecorder) Replay() error {
 	}
 
 	d.Set(Namespace.Internals, "mutation-replaying", ui.Bool(false))
+	if streamedDocument() {
+		if err := awaitStreamedChunks(d); err != nil {
+			return ui.ErrReplayFailure
+		}
+	}
+	if replayPending(d) {
+		// the deferred mutations of a progressive replay are replayed at idle time.
+		return nil
+	}
 	d.TriggerEvent("mutation-replayed")
 
 	return nil
@@ -1867,8 +2189,6 @@ var newDocument = Elements.NewConstructor("html", func(id string) *ui.Element {
 		return false
 	}).RunASAP())
 
-	e.Watch(Namespace.UI, "history", e, historyMutationHandler)
-
 	// makes ViewElements focusable (focus management support)
 	e.Watch(Namespace.Internals, "views", e.Root, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 		l := evt.NewValue().(ui.List)
@@ -1943,45 +2263,28 @@ func mutationreplay(d *Document) error {
 	}
 
 	mutNum := len(mutationtrace.UnsafelyUnwrap())
+	d.replayReport = ui.ReplayReport{Total: mutNum}
 
-	for m.pos < mutNum {
-		rawop := mutationtrace.Get(m.pos)
-		op := rawop.(ui.Object)
-		id, ok := op.Get("id")
-		if !ok {
-			panic("mutation entry badly encoded. Expectted a ui.Object with an 'id' property")
-		}
-
-		cat, ok := op.Get("cat")
-		if !ok {
-			panic("mutation entry badly encoded. Expectted a ui.Object with a 'cat' property")
-		}
-
-		prop, ok := op.Get("prop")
-		if !ok {
-			panic("mutation entry badly encoded. Expectted a ui.Object with a 'prop' property")
-		}
-
-		val, ok := op.Get("val")
-		if !ok {
-			panic("mutation entry badly encoded. Expectted a ui.Object with a 'val' property")
-		}
+	if progressiveReplayEnabled(mutNum) {
+		return progressiveReplay(d, mutationtrace)
+	}
 
-		el := GetDocument(e).GetElementById(id.(ui.String).String())
-		if el == nil {
-			// Unable to recover state for this element id. Element  doesn't exist"
-			DEBUG("!!!!  Unable to recover state for this element id. Element  doesn't exist: " + id.(ui.String).String())
-			return ui.ErrReplayFailure
-		}
+	return replaytrace(d, mutationtrace)
+}
 
-		el.BindValue(Namespace.Event, "connect-native", e)
-		el.BindValue(Namespace.Event, "mutation-replayed", e)
+// replaytrace replays the mutations of the trace that follow the current position of the recorder.
+func replaytrace(d *Document, mutationtrace ui.List) error {
+	m := d.mutationRecorder()
+	report := &d.replayReport
+	mutNum := len(mutationtrace.UnsafelyUnwrap())
+	step := mutNum / 100
+	if step < 1 {
+		step = 1
+	}
 
-		_, ok = op.Get("sync")
-		if !ok {
-			ui.ReplayMutation(el, cat.(ui.String).String(), prop.(ui.String).String(), val, false)
-		} else {
-			ui.ReplayMutation(el, cat.(ui.String).String(), prop.(ui.String).String(), val, true)
+	for m.pos < mutNum {
+		if err := replayop(d, mutationtrace.Get(m.pos).(ui.Object)); err != nil {
+			return err
 		}
 
 		i, ok := d.Get(Namespace.Internals, "mutation-list-index")
@@ -1995,11 +2298,72 @@ func mutationreplay(d *Document) error {
 		m.pos = int(i.(ui.Number).Float64())
 		m.pos = m.pos + 1
 		d.Set(Namespace.Internals, "mutation-list-index", ui.Number(m.pos))
+
+		if m.pos%step == 0 || m.pos >= mutNum {
+			d.TriggerEvent("replay-progress", ui.NewObject().Set("processed", ui.Number(m.pos)).Set("total", ui.Number(mutNum)).Commit())
+		}
+	}
+
+	if !report.Complete() {
+		DEBUG(report.String())
+	}
+	return nil
+}
+
+// replayop replays a single mutation of the trace.
+func replayop(d *Document, op ui.Object) error {
+	e := d.mutationRecorder().raw
+	report := &d.replayReport
+
+	id, ok := op.Get("id")
+	if !ok {
+		panic("mutation entry badly encoded. Expectted a ui.Object with an 'id' property")
+	}
+
+	cat, ok := op.Get("cat")
+	if !ok {
+		panic("mutation entry badly encoded. Expectted a ui.Object with a 'cat' property")
+	}
+
+	prop, ok := op.Get("prop")
+	if !ok {
+		panic("mutation entry badly encoded. Expectted a ui.Object with a 'prop' property")
+	}
+
+	val, ok := op.Get("val")
+	if !ok {
+		panic("mutation entry badly encoded. Expectted a ui.Object with a 'val' property")
+	}
+
+	el := d.GetElementById(id.(ui.String).String())
+	if el == nil {
+		// Unable to recover state for this element id. Element  doesn't exist"
+		DEBUG("!!!!  Unable to recover state for this element id. Element  doesn't exist: " + id.(ui.String).String())
+		if e.Configuration.ReplayPolicy != ui.SkipUnknownElements {
+			report.Aborted = true
+			return ui.ErrReplayFailure
+		}
+		report.Skipped = append(report.Skipped, ui.SkippedMutation{ID: id.(ui.String).String(), Category: cat.(ui.String).String(), Property: prop.(ui.String).String()})
+		return nil
 	}
+	el.BindValue(Namespace.Event, "connect-native", e)
+	el.BindValue(Namespace.Event, "mutation-replayed", e)
 
+	_, ok = op.Get("sync")
+	if !ok {
+		ui.ReplayMutation(el, cat.(ui.String).String(), prop.(ui.String).String(), val, false)
+	} else {
+		ui.ReplayMutation(el, cat.(ui.String).String(), prop.(ui.String).String(), val, true)
+	}
+	report.Applied++
 	return nil
 }
 
+// ReplayReport returns the report of the last mutation replay.
+func (d *Document) ReplayReport() ui.ReplayReport {
+	return d.replayReport
+}
+
 //
 // Focus support (includes focus restoration support)
 //
@@ -2292,20 +2656,24 @@ func withNativejshelpers(d *Document) *Document {
 				queueMicrotask(() => window.clearFieldValue(element));
 			}
 
-			window.scrollToElement = function(element, x, y) {
+			window.scrollToElement = function(element, x, y, behavior) {
 				if(element) {
 					x = x || 0;
 					y = y || 0;
-					element.scrollTo(x, y);
+					if(behavior) {
+						element.scrollTo({left: x, top: y, behavior: behavior});
+					} else {
+						element.scrollTo(x, y);
+					}
 				} else {
 					console.error('Element is not defined');
 				}
 			}
 			
-			window.queueScroll = function(element, x, y) {
+			window.queueScroll = function(element, x, y, behavior) {
 				x = x || 0;
 				y = y || 0;
-				queueMicrotask(() => window.scrollToElement(element, x, y));
+				queueMicrotask(() => window.scrollToElement(element, x, y, behavior));
 			};
 
 			(function() {
@@ -2386,6 +2754,10 @@ func (m *scsmap[K, T]) Range(fn func(key K, value T) bool) {
 // since *Document holds state that is neither available to a mere *ui.Element nor in global scope (stylesheets, id generator, ...)
 var documents *scsmap[*ui.Element, *Document] = newscsmap[*ui.Element, *Document]()
 
+// defaultFavicon is a png header without image data. It keeps browsers from requesting /favicon.ico.
+// Its media type is explicit, as data URLs are only accepted for images on icon links.
+const defaultFavicon = "data:image/png;base64,iVBORw0KGgo="
+
 // constructorDocumentLinker maps constructors id to the document they are created for.
 // Since we do not have dependent types, it is used to  have access to the document within
 // WithID methods, for element registration purposes (methods on function types do not have access to non-global user-defined state)
@@ -2452,13 +2824,29 @@ func NewDocument(id string, options ...string) *Document {
 		d.Head().AppendChild(l)
 		return false
 	}).RunASAP())
-	d.SetFavicon("data:;base64,iVBORw0KGgo=") // TODO default favicon
+	d.SetFavicon(defaultFavicon) // TODO default favicon
 
 	e.OnRouterMounted(routerConfig)
 	d.OnReady(navinitHandler)
 	e.Watch(Namespace.UI, "title", e, documentTitleHandler)
 
 	activityStateSupport(e)
+	orientationSupport(d)
+
+	if !profileSelected() {
+		// the profile inferred by the driver becomes the default profile of Elements, so that the ui package,
+		// which reads the profile of the configuration of an element, warns on the mutation of deleted elements
+		// in development.
+		Elements.WithDefaultProfile(profile())
+	}
+	applyLogLevel()
+	if profile().Debug {
+		enableAccessibilityAudit(d)
+		d.EnableLifecycleTimings()
+		enableCallbackAudit()
+		enableTreeExport(d)
+		d.EnableRouteUsage()
+	}
 
 	if InBrowser() {
 		document = d
@@ -2469,42 +2857,27 @@ func NewDocument(id string, options ...string) *Document {
 	return d
 }
 
-var historyMutationHandler = ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
-	var route string
-	r, ok := evt.Origin().Get(Namespace.UI, "currentroute")
-	if !ok {
-		panic("current route is unknown")
-	}
-	route = string(r.(ui.String))
+// browserHistory is the default history backend of the routers: it records the navigation in the
+// browser history.
+type browserHistory struct{}
+
+func (b browserHistory) Push(route string, state ui.Value) {
 	route, _ = url.JoinPath(BasePath, route)
+	js.Global().Get("history").Call("pushState", js.ValueOf(encodeHistoryState(state.(ui.Object))), "", route)
+}
 
-	history := evt.NewValue().(ui.Object)
+func (b browserHistory) Replace(route string, state ui.Value) {
+	route, _ = url.JoinPath(BasePath, route)
+	js.Global().Get("history").Call("replaceState", js.ValueOf(encodeHistoryState(state.(ui.Object))), "", route)
+}
 
-	browserhistory, ok := evt.OldValue().(ui.Object)
-	if ok {
-		bcursor, ok := browserhistory.Get("cursor")
-		if ok {
-			bhc := bcursor.(ui.Number)
-			hcursor, ok := history.Get("cursor")
-			if !ok {
-				panic("history cursor is missing")
-			}
-			hc := hcursor.(ui.Number)
-			if bhc == hc {
-				s := stringify(history.RawValue())
-				js.Global().Get("history").Call("replaceState", js.ValueOf(s), "", route)
-			} else {
-				s := stringify(history.RawValue())
-				js.Global().Get("history").Call("pushState", js.ValueOf(s), "", route)
-			}
-		}
-		return false
+func encodeHistoryState(history ui.Object) string {
+	s, err := ui.EncodeText(ui.PersistenceCodec, history)
+	if err != nil {
+		panic(err)
 	}
-
-	s := stringify(history.RawValue())
-	js.Global().Get("history").Call("replaceState", js.ValueOf(s), "", route)
-	return false
-})
+	return s
+}
 
 var navinitHandler = ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 	route := js.Global().Get("location").Get("pathname").String()
@@ -2533,10 +2906,9 @@ func loadNavHistory(d *Document) {
 	hstate := js.Global().Get("history").Get("state")
 
 	if hstate.Truthy() {
-		hstateobj := make(map[string]interface{})
-		err := json.Unmarshal([]byte(hstate.String()), &hstateobj)
+		v, err := ui.DecodeText(hstate.String())
 		if err == nil {
-			hso := ui.ValueFrom(hstateobj).(ui.Object)
+			hso := v.(ui.Object)
 			// Check that the state is valid. It is valid if it contains a cursor.
 			_, ok := hso.Get("cursor")
 			if ok {
@@ -2558,8 +2930,13 @@ func activityStateSupport(e *ui.Element) *ui.Element {
 	}))
 
 	// visibilitychange
+	// The document visibility is exposed as the "visibilitystate" ui property of the document ("visible" or "hidden").
+	if InBrowser() {
+		e.SetUI("visibilitystate", ui.String(js.Global().Get("document").Get("visibilityState").String()))
+	}
 	e.AddEventListener("visibilitychange", ui.NewEventHandler(func(evt ui.Event) bool {
 		visibilityState := js.Global().Get("document").Get("visibilityState").String()
+		e.SetUI("visibilitystate", ui.String(visibilityState))
 		if visibilityState == "hidden" {
 			e.TriggerEvent("before-unactive")
 		}
@@ -2632,30 +3009,30 @@ var AllowScrollRestoration = ui.NewConstructorOption("scrollrestoration", func(e
 						router.History.Set(e.ID+"-"+"scrollTop", scrolltop)
 						router.History.Set(e.ID+"-"+"scrollLeft", scrollleft)
 						return false
-					}))
+					}).AsPassive())
 
+					var from ui.Route
 					h := ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 						b, ok := e.GetEventValue("shouldscroll")
 						if !ok {
 							return false
 						}
 						if scroll := b.(ui.Bool); scroll {
-							t, ok := router.History.Get(e.ID + "-" + "scrollTop")
-							if !ok {
-								ejs.Set("scrollTop", 0)
-								ejs.Set("scrollLeft", 0)
-								return false
-							}
-							l, ok := router.History.Get(e.ID + "-" + "scrollLeft")
-							if !ok {
+							to := currentRoute(e)
+							saved := savedScrollPosition(router, e)
+							decision := router.ScrollDecision(from, to, saved)
+							from = to
+							switch {
+							case decision == ui.ScrollPreserve:
+							case decision == ui.ScrollRestore && saved != nil:
+								ejs.Set("scrollTop", saved.Y)
+								ejs.Set("scrollLeft", saved.X)
+							default:
+								// anchors are scrolled into view by the document.
 								ejs.Set("scrollTop", 0)
 								ejs.Set("scrollLeft", 0)
 								return false
 							}
-							top := t.(ui.Number)
-							left := l.(ui.Number)
-							ejs.Set("scrollTop", float64(top))
-							ejs.Set("scrollLeft", float64(left))
 							if e.ID != e.Root.ID {
 								e.TriggerEvent("shouldscroll", ui.Bool(false)) //always scroll root
 							}
@@ -2710,26 +3087,33 @@ var rootScrollRestorationSupport = func(root *ui.Element) *ui.Element {
 		r.History.Set(e.ID+"-"+"scrollTop", scrolltop)
 		r.History.Set(e.ID+"-"+"scrollLeft", scrollleft)
 		return false
-	}))
+	}).AsPassive())
 
+	var from ui.Route
 	h := ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 		router := ui.GetRouter(evt.Origin().Root)
 		newpageaccess := router.History.CurrentEntryIsNew()
 
-		t, oktop := router.History.Get(e.ID + "-" + "scrollTop")
-		l, okleft := router.History.Get(e.ID + "-" + "scrollLeft")
-
-		if !oktop || !okleft {
+		to := currentRoute(e)
+		saved := savedScrollPosition(router, e)
+		switch router.ScrollDecision(from, to, saved) {
+		case ui.ScrollRestore:
+			if saved == nil {
+				saved = &ui.Position{}
+			}
+			ejs.Set("scrollTop", saved.Y)
+			ejs.Set("scrollLeft", saved.X)
+		case ui.ScrollToAnchor:
+			if t := js.Global().Get("document").Call("getElementById", to.Fragment); to.Fragment != "" && t.Truthy() {
+				t.Call("scrollIntoView")
+				break
+			}
+			fallthrough
+		case ui.ScrollToTop:
 			ejs.Set("scrollTop", 0)
 			ejs.Set("scrollLeft", 0)
-		} else {
-			top := t.(ui.Number)
-			left := l.(ui.Number)
-
-			ejs.Set("scrollTop", float64(top))
-			ejs.Set("scrollLeft", float64(left))
-
 		}
+		from = to
 
 		// focus restoration if applicable
 		v, ok := router.History.Get("focusedElementId")
@@ -2777,6 +3161,25 @@ var rootScrollRestorationSupport = func(root *ui.Element) *ui.Element {
 	return e
 }
 
+// currentRoute returns the route the router of the document is at.
+func currentRoute(e *ui.Element) ui.Route {
+	v, ok := e.Root.GetUI("currentroute")
+	if !ok {
+		return ui.Route{}
+	}
+	return ui.ParseRoute(string(v.(ui.String)))
+}
+
+// savedScrollPosition returns the scroll position of an element saved in the current history entry, if any.
+func savedScrollPosition(r *ui.Router, e *ui.Element) *ui.Position {
+	t, oktop := r.History.Get(e.ID + "-" + "scrollTop")
+	l, okleft := r.History.Get(e.ID + "-" + "scrollLeft")
+	if !oktop || !okleft {
+		return nil
+	}
+	return &ui.Position{X: float64(l.(ui.Number)), Y: float64(t.(ui.Number))}
+}
+
 func withStdConstructors(d *Document) *Document {
 
 	d.Configuration.NewConstructor("observable", func(id string) *ui.Element {
@@ -3070,6 +3473,13 @@ func withStdConstructors(d *Document) *Document {
 	})
 	d.Iframe.ownedBy(d)
 
+	d.Dialog = gconstructor[DialogElement, dialogConstructor](func() DialogElement {
+		e := DialogElement{newDialog(d.newID())}
+		ui.RegisterElement(d.Element, e.AsElement())
+		return e
+	})
+	d.Dialog.ownedBy(d)
+
 	return d
 }
 
@@ -3587,7 +3997,8 @@ func SetTextContent(e *ui.Element, text string) {
 	if e.Native != nil {
 		nat, ok := e.Native.(NativeElement)
 		if !ok {
-			panic("trying to set text content on a non-DOM element")
+			wrongNativeType(e)
+			return
 		}
 		nat.Value.Set("textContent", string(text))
 	}
@@ -4610,12 +5021,19 @@ func SetPrefetchMaxAge(t time.Duration) {
 	ui.PrefetchMaxAge = t
 }
 
+// SetNestedPrefetch configures how many levels of nested views are prefetched along with the target
+// view of a link, and how many elements may be visited per link prefetch.
+func SetNestedPrefetch(depth, budget int) {
+	ui.PrefetchDepth = depth
+	ui.PrefetchBudget = budget
+}
+
 func DisablePrefetching() {
 	ui.PrefetchMaxAge = -1
 }
 
 func prefetchDisabled() bool {
-	return ui.PrefetchMaxAge < 0
+	return ui.PrefetchMaxAge < 0 || profile().PrefetchMaxAge < 0
 }
 
 type ButtonElement struct {
@@ -4778,7 +5196,8 @@ type InputElement struct {
 func (i InputElement) Blur() {
 	native, ok := i.AsElement().Native.(NativeElement)
 	if !ok {
-		panic("native element should be of doc.NativeELement type")
+		wrongNativeType(i.AsElement())
+		return
 	}
 	js.Global().Call("queueBlur", native.Value)
 }
@@ -4786,7 +5205,8 @@ func (i InputElement) Blur() {
 func (i InputElement) Focus() {
 	native, ok := i.AsElement().Native.(NativeElement)
 	if !ok {
-		panic("native element should be of doc.NativeELement type")
+		wrongNativeType(i.AsElement())
+		return
 	}
 	js.Global().Call("queueFocus", native.Value)
 
@@ -4795,7 +5215,8 @@ func (i InputElement) Focus() {
 func (i InputElement) Clear() {
 	native, ok := i.AsElement().Native.(NativeElement)
 	if !ok {
-		panic("native element should be of doc.NativeELement type")
+		wrongNativeType(i.AsElement())
+		return
 	}
 	js.Global().Call("queueClear", native.Value)
 
@@ -6297,11 +6718,11 @@ func (d DetailsElement) IsOpened() bool {
 	if !ok {
 		return false
 	}
-	_, ok = o.(ui.Bool)
+	b, ok := o.(ui.Bool)
 	if !ok {
 		return false
 	}
-	return true
+	return bool(b)
 }
 
 var newDetails = Elements.NewConstructor("details", func(id string) *ui.Element {
@@ -6335,20 +6756,20 @@ func (d DialogElement) Open() DialogElement {
 }
 
 func (d DialogElement) Close() DialogElement {
-	d.AsElement().SetUI("open", nil)
+	d.AsElement().SetUI("open", ui.Bool(false))
 	return d
 }
 
 func (d DialogElement) IsOpened() bool {
-	o, ok := d.AsElement().GetData("open")
+	o, ok := d.AsElement().GetUI("open")
 	if !ok {
 		return false
 	}
-	_, ok = o.(ui.Bool)
+	b, ok := o.(ui.Bool)
 	if !ok {
 		return false
 	}
-	return true
+	return bool(b)
 }
 
 var newDialog = Elements.NewConstructor("dialog", func(id string) *ui.Element {
@@ -7223,7 +7644,8 @@ func numericPropertyWatcher(propname string) *ui.MutationHandler {
 	return ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 		j, ok := JSValue(evt.Origin())
 		if !ok {
-			panic("element doesn't seem to have been connected to thecorresponding Native DOM Element")
+			notConnected(evt.Origin())
+			return false
 		}
 		j.Set(propname, float64(evt.NewValue().(ui.Number)))
 		return false
@@ -7234,7 +7656,8 @@ func boolPropertyWatcher(propname string) *ui.MutationHandler {
 	return ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 		j, ok := JSValue(evt.Origin())
 		if !ok {
-			panic("element doesn't seem to have been connected to thecorresponding Native DOM Element")
+			notConnected(evt.Origin())
+			return false
 		}
 		j.Set(propname, bool(evt.NewValue().(ui.Bool)))
 		return false
@@ -7245,9 +7668,11 @@ func stringPropertyWatcher(propname string) *ui.MutationHandler {
 	return ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
 		j, ok := JSValue(evt.Origin())
 		if !ok {
-			panic("element doesn't seem to have been connected to thecorresponding Native DOM Element")
+			notConnected(evt.Origin())
+			return false
 		}
-		j.Set(propname, string(evt.NewValue().(ui.String)))
+		v := sanitizeAttribute(evt.Origin(), propname, string(evt.NewValue().(ui.String)))
+		j.Set(propname, trustedValue(evt.Origin(), propname, v))
 		return false
 	}).RunASAP()
 }
@@ -7308,6 +7733,7 @@ func GetAttribute(target *ui.Element, name string) string {
 }
 
 func SetAttribute(target *ui.Element, name string, value string) {
+	value = sanitizeAttribute(target, name, value)
 	var attrmap ui.Object
 	var am = ui.NewObject()
 	m, ok := target.Get(Namespace.Data, "attrs")
@@ -7326,7 +7752,7 @@ func SetAttribute(target *ui.Element, name string, value string) {
 		log.Print("Cannot set Attribute on non-expected wrapper type")
 		return
 	}
-	native.Value.Call("setAttribute", name, value)
+	native.Value.Call("setAttribute", name, trustedValue(target, name, value))
 }
 
 func RemoveAttribute(target *ui.Element, name string) {
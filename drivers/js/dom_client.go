@@ -29,6 +29,9 @@ func init() {
 }
 
 func (n NativeElement) SetChildren(children ...*ui.Element) {
+	if len(children) > 0 && insertedInZone(children[0]) {
+		return
+	}
 	if n.typ == "HTMLElement" {
 		fragment := js.Global().Get("document").Call("createDocumentFragment")
 		for _, child := range children {
@@ -43,6 +46,10 @@ func (n NativeElement) SetChildren(children ...*ui.Element) {
 }
 
 func (n NativeElement) BatchExecute(parentid string, opslist string) {
+	if _, ok := thirdPartyZones.Get(parentid); ok {
+		log.Print("third-party zone " + parentid + " does not accept zui children.")
+		return
+	}
 	if n.typ == "HTMLElement" {
 		js.Global().Call("applyBatchOperations", parentid, opslist)
 	}
@@ -129,6 +136,10 @@ func NewBuilder(f func() *Document, buildEnvModifiers ...func()) (ListenAndServe
 				d.ErrorTransition("replay", ui.String(err.Error()))
 				return true // DEBUG may want to return false, should check
 			}
+			if replayPending(d) {
+				// the transition ends once the progressive replay is complete.
+				return false
+			}
 			d.EndTransition("replay")
 			return false
 		}))
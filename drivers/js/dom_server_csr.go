@@ -194,11 +194,13 @@ var NewBuilder = func(f func() *Document, buildEnvModifiers ...func()) (ListenAn
 			ctx = context.Background()
 		}
 		ctx, shutdown := context.WithCancel(ctx)
+		defer shutdown()
 		var activehmr bool
 
 		var SSEChannel *SSEController
 		var mu = &sync.Mutex{}
 
+		serveOperationalEndpoints()
 		ServeMux.Handle(BasePath, RenderHTMLhandler)
 
 		if DevMode != "false" {
@@ -298,30 +300,8 @@ var NewBuilder = func(f func() *Document, buildEnvModifiers ...func()) (ListenAn
 			fmt.Fprintln(w, "HMR status active: ", activehmr)
 		}))
 
-		go func() { // allows for graceful shutdown signaling
-			if Server.TLSConfig == nil {
-				if err := Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-					log.Fatal(err)
-				}
-			} else {
-				if err := Server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
-					log.Fatal(err)
-				}
-			}
-		}()
-
-		log.Print("Listening on: " + Server.Addr)
-
-		for {
-			select {
-			case <-ctx.Done():
-				err := Server.Shutdown(ctx)
-				if err != nil {
-					panic(err)
-				}
-				log.Printf("Server shutdown")
-				os.Exit(0)
-			}
+		if err := serve(ctx); err != nil {
+			log.Print(err)
 		}
 	}
 }
@@ -346,7 +326,7 @@ func newHTMLDocument(document Document) *html.Node {
 }
 
 func generateStateHistoryRecordElement(root *ui.Element) *html.Node {
-	state := SerializeStateHistory(root)
+	state := SerializeSSRState(root)
 	script := `<script id='` + SSRStateElementID + `' type="application/json">
 	` + state + `
 	<script>`
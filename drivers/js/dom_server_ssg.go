@@ -37,6 +37,7 @@ var (
 	release  bool
 	nohmr    bool
 	noserver bool
+	crawl    bool
 
 	ServeMux *http.ServeMux
 	Server   *http.Server = newDefaultServer()
@@ -53,6 +54,7 @@ func init() {
 	flag.StringVar(&port, "port", "8888", "Port number for the server")
 
 	flag.BoolVar(&noserver, "noserver", false, "Generate the pages without starting a server")
+	flag.BoolVar(&crawl, "crawl", false, "Discover the routes to prerender by following links")
 	flag.BoolVar(&release, "release", false, "Build the app in release mode")
 	flag.BoolVar(&nohmr, "nohmr", false, "Disable hot module reloading")
 	flag.BoolVar(&verbose, "verbose", false, "Enable verbose logging")
@@ -72,6 +74,9 @@ func init() {
 }
 
 func (n NativeElement) SetChildren(children ...*ui.Element) {
+	if len(children) > 0 && insertedInZone(children[0]) {
+		return
+	}
 	// should delete all children and add the new ones
 	n.Value.Get("children").Call("remove")
 
@@ -201,6 +206,13 @@ func NewBuilder(f func() Document, buildEnvModifiers ...func()) (ListenAndServe
 				fmt.Printf("Created %d pages\n", numPages)
 			}
 		}
+
+		// The route usage report is used by the builder for bundle analysis. It is not part of the static files.
+		if usage := document.RouteUsage(); len(usage) > 0 {
+			if err := os.WriteFile("route-usage.json", usage.JSON(), 0644); err != nil {
+				fmt.Printf("Error writing route usage report: %v\n", err)
+			}
+		}
 	})
 
 	RenderHTMLhandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -240,7 +252,9 @@ func NewBuilder(f func() Document, buildEnvModifiers ...func()) (ListenAndServe
 	// ******************************
 	return func(ctx context.Context) {
 		ctx, shutdown := context.WithCancel(ctx)
+		defer shutdown()
 
+		serveOperationalEndpoints()
 		ServeMux.Handle(BasePath, RenderHTMLhandler)
 
 		if DevMode != "false" {
@@ -251,30 +265,8 @@ func NewBuilder(f func() Document, buildEnvModifiers ...func()) (ListenAndServe
 			}))
 		}
 
-		go func() { // allows for graceful shutdown signaling
-			if Server.TLSConfig == nil {
-				if err := Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-					log.Fatal(err)
-				}
-			} else {
-				if err := Server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
-					log.Fatal(err)
-				}
-			}
-		}()
-
-		log.Print("Listening on: " + Server.Addr)
-
-		for {
-			select {
-			case <-ctx.Done():
-				err := Server.Shutdown(ctx)
-				if err != nil {
-					panic(err)
-				}
-				log.Printf("Server shutdown")
-				os.Exit(0)
-			}
+		if err := serve(ctx); err != nil {
+			log.Print(err)
 		}
 
 	}
@@ -301,7 +293,7 @@ func newHTMLDocument(document Document) js.Value {
 }
 
 func generateStateHistoryRecordElement(root *ui.Element) *html.Node {
-	state := SerializeStateHistory(root)
+	state := SerializeSSRState(root)
 	script := `<script id='` + SSRStateElementID + `' type="application/json">
 	` + state + `
 	<script>`
@@ -327,6 +319,13 @@ func CreatePages(doc Document) (int, error) {
 		return 1, nil
 	}
 
+	if crawl && Crawling == nil {
+		Crawling = &CrawlOptions{}
+	}
+	if Crawling != nil {
+		return crawlPages(doc, basePath, *Crawling)
+	}
+
 	routes := router.RouteList()
 
 	var count int
@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	ui "github.com/atdiar/particleui"
 
@@ -177,26 +178,48 @@ func NewBuilder(f func() Document, buildEnvModifiers ...func()) (ListenAndServe
 		}()
 
 		ui.DoSync(func() {
+			setRequestContext(&document, r)
 			router := document.Router()
 			route := r.URL.Path
 			_, routeexist := router.Match(route)
 			if routeexist != nil {
-				w.WriteHeader(http.StatusNotFound)
+				document.SetResponseStatus(http.StatusNotFound)
 			}
 			router.GoTo(route)
 		})
 
-		err = document.Render(w)
+		if StreamSSR && streamRender(&document, w, r) {
+			return
+		}
+
+		// The status code and headers declared during the render (by the router or the views)
+		// must be written before the body.
+		var buf bytes.Buffer
+		start := time.Now()
+		err = document.Render(&buf)
+		observeRender(time.Since(start))
 		if err != nil {
 			switch err {
 			case ui.ErrNotFound:
-				w.WriteHeader(http.StatusNotFound)
-			case ui.ErrFrameworkFailure:
-				w.WriteHeader(http.StatusInternalServerError)
+				document.SetResponseStatus(http.StatusNotFound)
 			case ui.ErrUnauthorized:
-				w.WriteHeader(http.StatusUnauthorized)
+				document.SetResponseStatus(http.StatusUnauthorized)
+			default:
+				document.SetResponseStatus(http.StatusInternalServerError)
 			}
 		}
+		status := document.ResponseStatus()
+		if len(ClientHints) > 0 {
+			w.Header().Set("Accept-CH", strings.Join(ClientHints, ", "))
+		}
+		writeResponseHeader(&document, w)
+		if status >= 300 && status < 400 {
+			return
+		}
+		_, err = buf.WriteTo(w)
+		if err != nil {
+			log.Print(err)
+		}
 
 	})
 
@@ -209,6 +232,10 @@ func NewBuilder(f func() Document, buildEnvModifiers ...func()) (ListenAndServe
 			ctx = context.Background()
 		}
 		ctx, shutdown := context.WithCancel(ctx)
+		defer shutdown()
+
+		serveOperationalEndpoints()
+		ServeMux.Handle(BasePath, RenderHTMLhandler)
 
 		if DevMode != "false" {
 			ServeMux.Handle("/stop", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -218,30 +245,8 @@ func NewBuilder(f func() Document, buildEnvModifiers ...func()) (ListenAndServe
 			}))
 		}
 
-		go func() { // allows for graceful shutdown signaling
-			if Server.TLSConfig == nil {
-				if err := Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-					log.Fatal(err)
-				}
-			} else {
-				if err := Server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
-					log.Fatal(err)
-				}
-			}
-		}()
-
-		log.Print("Listening on: " + Server.Addr)
-
-		for {
-			select {
-			case <-ctx.Done():
-				err := Server.Shutdown(ctx)
-				if err != nil {
-					panic(err)
-				}
-				log.Printf("Server shutdown")
-				os.Exit(0)
-			}
+		if err := serve(ctx); err != nil {
+			log.Print(err)
 		}
 
 	}
@@ -268,7 +273,7 @@ func newHTMLDocument(document Document) js.Value {
 }
 
 func generateStateHistoryRecordElement(root *ui.Element) *html.Node {
-	state := SerializeStateHistory(root)
+	state := SerializeSSRState(root)
 	script := `<script id='` + SSRStateElementID + `' type="application/json">
 	` + state + `
 	<script>`
@@ -0,0 +1,184 @@
+package doc
+
+import (
+	"testing"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// The tests run in node, which has no DOM. domShim installs a minimal one, replacing any previous one, sufficient to create documents
+// and elements. It does not render nor lay out anything.
+const domShim = `
+(function(){
+	class EventTarget {
+		constructor(){ this._listeners = {}; }
+		addEventListener(t, f){ (this._listeners[t] = this._listeners[t] || []).push(f); }
+		removeEventListener(t, f){ const l = this._listeners[t] || []; const i = l.indexOf(f); if (i >= 0) l.splice(i, 1); }
+		dispatchEvent(e){ (this._listeners[e.type] || []).slice().forEach(f => f.call(this, e)); return true; }
+	}
+	class ClassList {
+		constructor(){ this._c = new Set(); }
+		add(...c){ c.forEach(x => this._c.add(x)); }
+		remove(...c){ c.forEach(x => this._c.delete(x)); }
+		contains(c){ return this._c.has(c); }
+		toggle(c){ if (this._c.has(c)) { this._c.delete(c); return false; } this._c.add(c); return true; }
+	}
+	class Node extends EventTarget {
+		constructor(tag){
+			super();
+			this.tagName = (tag || "").toUpperCase();
+			this.nodeName = this.tagName;
+			this.nodeType = 1;
+			this.childNodes = [];
+			this.children = this.childNodes;
+			this.parentNode = null;
+			this.attributes = {};
+			this.style = { setProperty(){}, removeProperty(){}, getPropertyValue(){ return ""; } };
+			this.classList = new ClassList();
+			this.dataset = {};
+			this.textContent = "";
+			this.innerHTML = "";
+			this.value = "";
+			this.clientWidth = this.clientHeight = this.scrollWidth = this.scrollHeight = 0;
+			this.offsetWidth = this.offsetHeight = 0;
+		}
+		get scrollTop(){ return this._scrollTop || 0; }
+		set scrollTop(v){ this._scrollTop = v; }
+		get scrollLeft(){ return this._scrollLeft || 0; }
+		set scrollLeft(v){ this._scrollLeft = v; }
+		get firstChild(){ return this.childNodes[0] || null; }
+		get lastChild(){ return this.childNodes[this.childNodes.length - 1] || null; }
+		get isConnected(){ return true; }
+		setAttribute(n, v){ this.attributes[n] = String(v); if (n === "id") this.id = String(v); }
+		getAttribute(n){ return n in this.attributes ? this.attributes[n] : null; }
+		hasAttribute(n){ return n in this.attributes; }
+		removeAttribute(n){ delete this.attributes[n]; }
+		toggleAttribute(n, f){ if (f) this.attributes[n] = ""; else delete this.attributes[n]; return !!f; }
+		appendChild(c){
+			if (c.parentNode) c.parentNode.removeChild(c);
+			this.childNodes.push(c);
+			c.parentNode = this;
+			// inline scripts run once inserted, except the wasm loader: the test binary is already running.
+			const src = c.tagName === "SCRIPT" ? (c.textContent || c.innerHTML) : "";
+			if (src && !src.includes("WebAssembly")) (0, eval)(src);
+			return c;
+		}
+		append(...c){ c.forEach(x => this.appendChild(x)); }
+		removeChild(c){ const i = this.childNodes.indexOf(c); if (i >= 0) this.childNodes.splice(i, 1); c.parentNode = null; return c; }
+		remove(){ if (this.parentNode) this.parentNode.removeChild(this); }
+		insertBefore(c, ref){
+			if (c.parentNode) c.parentNode.removeChild(c);
+			const i = ref ? this.childNodes.indexOf(ref) : -1;
+			if (i < 0) this.childNodes.push(c); else this.childNodes.splice(i, 0, c);
+			c.parentNode = this;
+			return c;
+		}
+		replaceChild(n, o){ const i = this.childNodes.indexOf(o); if (i >= 0) { this.childNodes[i] = n; n.parentNode = this; o.parentNode = null; } return o; }
+		replaceChildren(...c){ this.childNodes.splice(0); c.forEach(x => this.appendChild(x)); }
+		cloneNode(){ return new Node(this.tagName); }
+		contains(n){ for (; n; n = n.parentNode) if (n === this) return true; return false; }
+		querySelector(){ return null; }
+		querySelectorAll(){ return []; }
+		getElementsByTagName(){ return []; }
+		getBoundingClientRect(){ return { top: 0, left: 0, right: 0, bottom: 0, width: 0, height: 0, x: 0, y: 0 }; }
+		focus(){ globalThis.document.activeElement = this; }
+		blur(){}
+		click(){}
+		scrollIntoView(){}
+		scrollTo(){}
+		show(){ this.open = true; }
+		showModal(){ this.open = true; }
+		close(){ this.open = false; }
+		attachShadow(){ return new Node("#shadow-root"); }
+	}
+	class Document extends Node {
+		constructor(){
+			super("#document");
+			this._root = new Node("html");
+			this.head = new Node("head");
+			this.body = new Node("body");
+			this.documentElement.appendChild(this.head);
+			this.documentElement.appendChild(this.body);
+			this.activeElement = this.body;
+			this.visibilityState = "visible";
+			this.readyState = "complete";
+			this.cookie = "";
+			this.title = "";
+		}
+		get documentElement(){ return this._root; } // read-only, as in browsers
+		createElement(tag){ return new Node(tag); }
+		createElementNS(ns, tag){ return new Node(tag); }
+		createTextNode(t){ const n = new Node("#text"); n.nodeType = 3; n.textContent = t; return n; }
+		createDocumentFragment(){ return new Node("#document-fragment"); }
+		createComment(t){ const n = new Node("#comment"); n.nodeType = 8; return n; }
+		getElementById(id){
+			const find = n => { if (n.id === id) return n; for (const c of n.childNodes) { const r = find(c); if (r) return r; } return null; };
+			return find(this._root);
+		}
+		hasFocus(){ return true; }
+	}
+	class Storage {
+		constructor(){ this._m = new Map(); }
+		get length(){ return this._m.size; }
+		key(i){ return Array.from(this._m.keys())[i] ?? null; }
+		getItem(k){ return this._m.has(k) ? this._m.get(k) : null; }
+		setItem(k, v){ this._m.set(k, String(v)); }
+		removeItem(k){ this._m.delete(k); }
+		clear(){ this._m.clear(); }
+	}
+	class Observer { constructor(){} observe(){} unobserve(){} disconnect(){} takeRecords(){ return []; } }
+	const w = globalThis;
+	const doc = new Document();
+	doc.defaultView = w;
+	w.document = doc;
+	w.window = w;
+	w.self = w;
+	const wt = new EventTarget();
+	w.addEventListener = wt.addEventListener.bind(wt);
+	w.removeEventListener = wt.removeEventListener.bind(wt);
+	w.dispatchEvent = wt.dispatchEvent.bind(wt);
+	w.location = { pathname: "/", search: "", hash: "", href: "http://localhost/", origin: "http://localhost", host: "localhost", hostname: "localhost", protocol: "http:", reload(){}, assign(){}, replace(){} };
+	w.history = { state: null, length: 1, pushState(s){ this.state = s; this.length++; }, replaceState(s){ this.state = s; }, back(){}, forward(){}, go(){}, scrollRestoration: "auto" };
+	w.localStorage = new Storage();
+	w.sessionStorage = new Storage();
+	w.screen = { width: 1024, height: 768, orientation: { type: "landscape-primary", angle: 0, addEventListener(){}, removeEventListener(){} } };
+	w.innerWidth = 1024;
+	w.innerHeight = 768;
+	w.devicePixelRatio = 1;
+	w.scrollX = 0;
+	w.scrollY = 0;
+	w.matchMedia = () => ({ matches: false, addEventListener(){}, removeEventListener(){}, addListener(){}, removeListener(){} });
+	w.getComputedStyle = () => ({ getPropertyValue(){ return ""; } });
+	w.requestAnimationFrame = f => setTimeout(() => f(Date.now()), 0);
+	w.cancelAnimationFrame = id => clearTimeout(id);
+	w.requestIdleCallback = f => setTimeout(() => f({ timeRemaining(){ return 50; }, didTimeout: false }), 0);
+	w.cancelIdleCallback = id => clearTimeout(id);
+	w.scrollTo = () => {};
+	w.MutationObserver = Observer;
+	w.IntersectionObserver = Observer;
+	w.ResizeObserver = Observer;
+	w.Event = class { constructor(type, init){ this.type = type; Object.assign(this, init || {}); } preventDefault(){ this.defaultPrevented = true; } stopPropagation(){} stopImmediatePropagation(){} };
+	w.CustomEvent = w.Event;
+	w.KeyboardEvent = w.Event;
+	w.MouseEvent = w.Event;
+	w.HTMLElement = Node;
+	w.Element = Node;
+	w.Node = Node;
+	w.Document = Document;
+	if (!w.navigator) w.navigator = {};
+	w.console = console;
+})();
+`
+
+// newTestDocument returns a document backed by a fresh DOM shim.
+func newTestDocument(t *testing.T, id string) *Document {
+	t.Helper()
+	js.Global().Call("eval", domShim)
+	d := NewDocument(id)
+	withNativejshelpers(d)
+	t.Cleanup(func() {
+		ui.Delete(d.AsElement())
+	})
+	return d
+}
@@ -8,7 +8,6 @@ import (
 	js "github.com/atdiar/particleui/drivers/js/compat"
 
 	//"net/url"
-	"encoding/json"
 	"log"
 
 	ui "github.com/atdiar/particleui"
@@ -76,7 +75,12 @@ func NativeDispatch(evt ui.Event) {
 	}
 }
 
-var NativeEventBridge = func(NativeEventName string, listener *ui.Element, capture bool) {
+var NativeEventBridge = func(NativeEventName string, listener *ui.Element, opts ui.ListenerOptions) {
+	// Go handlers with the same capture and passive options share a single native listener.
+	key := ui.NativeListenerKey(NativeEventName, opts)
+	if listener.NativeEventUnlisteners.List != nil && listener.NativeEventUnlisteners.Has(key) {
+		return
+	}
 
 	// Let's create the callback that will be called from the js side
 	cb := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
@@ -209,10 +213,9 @@ var NativeEventBridge = func(NativeEventName string, listener *ui.Element, captu
 				hstate := js.Global().Get("history").Get("state")
 
 				if hstate.Truthy() {
-					hstateobj := make(map[string]interface{})
-					err := json.Unmarshal([]byte(hstate.String()), &hstateobj)
+					v, err := ui.DecodeText(hstate.String())
 					if err == nil {
-						hso := ui.ValueFrom(hstateobj).(ui.Object)
+						hso := v.(ui.Object)
 						_, ok := hso.Get("cursor")
 						if !ok {
 							panic("popstate event fired but the state object has no cursor which is unexpected")
@@ -233,6 +236,7 @@ var NativeEventBridge = func(NativeEventName string, listener *ui.Element, captu
 			jsUIEvent := js.Global().Get("UIEvent")
 			jsInputEvent := js.Global().Get("InputEvent")
 			jsHashChangeEvent := js.Global().Get("HashChangeEvent")
+			jsCustomEvent := js.Global().Get("CustomEvent")
 			jsKeyboardEvent := js.Global().Get("KeyboardEvent")
 			jsMouseEvent := js.Global().Get("MouseEvent")
 
@@ -241,6 +245,12 @@ var NativeEventBridge = func(NativeEventName string, listener *ui.Element, captu
 				rv.Set("which", ui.Number(evt.Get("which").Float()))
 			}
 
+			if evt.InstanceOf(jsCustomEvent) {
+				if detail := jsToValue(evt.Get("detail")); detail != nil {
+					rv.Set("detail", detail)
+				}
+			}
+
 			if evt.InstanceOf(jsInputEvent) {
 				rv = rv.Set(Namespace.Data, ui.String(evt.Get("data").String()))
 				rv = rv.Set("inputType", ui.String(evt.Get("inputType").String()))
@@ -294,12 +304,16 @@ var NativeEventBridge = func(NativeEventName string, listener *ui.Element, captu
 	if !tgt.Truthy() {
 		panic("trying to add an event listener to non-existing HTML element on the JS side")
 	}
-	tgt.Call("addEventListener", NativeEventName, cb, capture)
+	// Once handlers are removed on the Go side, so the native listener is kept.
+	tgt.Call("addEventListener", NativeEventName, cb, map[string]interface{}{
+		"capture": opts.Capture,
+		"passive": opts.Passive,
+	})
 	if listener.NativeEventUnlisteners.List == nil {
 		listener.NativeEventUnlisteners = ui.NewNativeEventUnlisteners()
 	}
-	listener.NativeEventUnlisteners.Add(NativeEventName, func() {
-		tgt.Call("removeEventListener", NativeEventName, cb)
+	listener.NativeEventUnlisteners.Add(key, func() {
+		tgt.Call("removeEventListener", NativeEventName, cb, opts.Capture)
 		cb.Release()
 	})
 
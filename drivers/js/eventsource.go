@@ -0,0 +1,226 @@
+package doc
+
+import (
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Server-Sent Events observables
+//
+// NewEventSource returns an observable element wrapping an EventSource connection. Every server-sent event
+// becomes a mutation event of the element: an event named "update" on the wire triggers the "update" event on
+// the observable, whose value is the data of the event (ui.String). Unnamed events are "message" events.
+// The id of the last received event is stored in the "last-event-id" data property.
+//
+// Named events are only received once they have been listened to, either by listing them in the options or
+// by registering a handler with OnEvent.
+//
+// The "state" ui property (ui.String) holds the state of the connection: "connecting", "open" or "closed".
+// The "eventsource-open" and "eventsource-error" events are triggered on the observable when the connection
+// opens and fails. The browser retries by itself after transient failures, at the pace requested by the server.
+// When the browser gives up, the connection is reopened after a delay that doubles after each attempt, from
+// MinBackoff up to MaxBackoff, if reconnection is enabled.
+// The connection is closed when the element is deleted. Outside of the browser, the state remains "closed".
+
+// EventSourceOptions configures an EventSource observable.
+type EventSourceOptions struct {
+	Events          []string // names of the events to listen to from the start
+	WithCredentials bool
+	Reconnect       bool
+	MinBackoff      time.Duration // defaults to one second
+	MaxBackoff      time.Duration // defaults to one minute
+	MaxRetries      int           // zero means no limit
+}
+
+// EventSource is an observable wrapping an EventSource connection.
+type EventSource struct {
+	*ui.Element
+}
+
+type eventSourceState struct {
+	url       string
+	options   EventSourceOptions
+	source    js.Value
+	listeners map[string]js.Func
+	retries   int
+	delay     time.Duration
+	timer     *time.Timer
+	closed    bool
+}
+
+var eventSources = newscsmap[*ui.Element, *eventSourceState]()
+
+// NewEventSource returns the observable connected to the given url.
+func (d *Document) NewEventSource(id, url string, options ...EventSourceOptions) EventSource {
+	var o EventSourceOptions
+	if len(options) > 0 {
+		o = options[0]
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = time.Second
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = time.Minute
+	}
+
+	if e := d.GetElementById(id); e != nil {
+		if _, ok := eventSources.Get(e); ok {
+			return EventSource{e}
+		}
+	}
+	e := d.NewObservable(id).AsElement()
+	s := &eventSourceState{url: url, options: o, listeners: make(map[string]js.Func), delay: o.MinBackoff}
+	eventSources.Set(e, s)
+	e.SetUI("state", ui.String("closed"))
+
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		s.close()
+		eventSources.Delete(evt.Origin())
+		return false
+	}).RunOnce())
+
+	if InBrowser() && js.Global().Get("EventSource").Truthy() {
+		s.connect(e)
+	}
+	for _, name := range append([]string{"message"}, o.Events...) {
+		s.listen(e, name)
+	}
+	return EventSource{e}
+}
+
+func (s *eventSourceState) connect(e *ui.Element) {
+	init := js.Global().Get("Object").New()
+	init.Set("withCredentials", s.options.WithCredentials)
+	src := js.Global().Get("EventSource").New(s.url, init)
+	s.source = src
+	e.SetUI("state", ui.String("connecting"))
+
+	for name, f := range s.listeners {
+		src.Call("addEventListener", name, f)
+	}
+
+	var onopen, onerror js.Func
+	onopen = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			s.retries = 0
+			s.delay = s.options.MinBackoff
+			e.SetUI("state", ui.String("open"))
+			e.TriggerEvent("eventsource-open")
+		})
+		return nil
+	})
+	onerror = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		// readyState is CLOSED (2) once the browser has given up reconnecting.
+		giveup := src.Get("readyState").Int() == 2
+		if giveup {
+			src.Call("removeEventListener", "open", onopen)
+			src.Call("removeEventListener", "error", onerror)
+			onopen.Release()
+			onerror.Release()
+		}
+		go ui.DoSync(func() {
+			e.TriggerEvent("eventsource-error")
+			if !giveup {
+				e.SetUI("state", ui.String("connecting"))
+				return
+			}
+			e.SetUI("state", ui.String("closed"))
+			s.reconnect(e)
+		})
+		return nil
+	})
+	src.Call("addEventListener", "open", onopen)
+	src.Call("addEventListener", "error", onerror)
+}
+
+// listen forwards the events of the given name to the observable.
+func (s *eventSourceState) listen(e *ui.Element, name string) {
+	if _, ok := s.listeners[name]; ok || !InBrowser() {
+		return
+	}
+	f := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		data := ui.String(args[0].Get("data").String())
+		id := args[0].Get("lastEventId").String()
+		go ui.DoSync(func() {
+			if id != "" {
+				e.SetData("last-event-id", ui.String(id))
+			}
+			e.TriggerEvent(name, data)
+		})
+		return nil
+	})
+	s.listeners[name] = f
+	if s.source.Truthy() {
+		s.source.Call("addEventListener", name, f)
+	}
+}
+
+func (s *eventSourceState) reconnect(e *ui.Element) {
+	if s.closed || !s.options.Reconnect {
+		return
+	}
+	if s.options.MaxRetries > 0 && s.retries >= s.options.MaxRetries {
+		return
+	}
+	s.retries++
+	delay := s.delay
+	if s.delay *= 2; s.delay > s.options.MaxBackoff {
+		s.delay = s.options.MaxBackoff
+	}
+	s.timer = time.AfterFunc(delay, func() {
+		ui.DoSync(func() {
+			if !s.closed {
+				s.connect(e)
+			}
+		})
+	})
+}
+
+func (s *eventSourceState) close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if s.source.Truthy() {
+		s.source.Call("close")
+	}
+	for name, f := range s.listeners {
+		if s.source.Truthy() {
+			s.source.Call("removeEventListener", name, f)
+		}
+		f.Release()
+	}
+	s.listeners = nil
+}
+
+// OnEvent registers a handler called with the data of every server-sent event of the given name.
+func (es EventSource) OnEvent(name string, h *ui.MutationHandler) {
+	if s, ok := eventSources.Get(es.Element); ok && !s.closed {
+		s.listen(es.Element, name)
+	}
+	es.WatchEvent(name, es, h)
+}
+
+// Close closes the connection for good. The observable remains available until it is deleted.
+func (es EventSource) Close() {
+	s, ok := eventSources.Get(es.Element)
+	if !ok || s.closed {
+		return
+	}
+	s.close()
+	es.SetUI("state", ui.String("closed"))
+}
+
+// State returns the state of the connection.
+func (es EventSource) State() string {
+	v, ok := es.GetUI("state")
+	if !ok {
+		return "closed"
+	}
+	return string(v.(ui.String))
+}
@@ -0,0 +1,129 @@
+package doc
+
+import (
+	"context"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Exit beacons
+//
+// OnBeforeUnactive is the place to persist state to the browser storage. Data bound to a server (analytics,
+// draft saves...) needs a transport that outlives the page: producers registered with OnExit are called when
+// the page becomes unactive, and the payloads they return are sent with navigator.sendBeacon. If a beacon is
+// unavailable or refused, a fetch request with keepalive is made instead.
+//
+// Browsers cap the data in flight for beacons and keepalive requests to about 64KiB per page. Payloads are
+// sent in registration order within ExitBudget. Those which do not fit are dropped and an "exit-payload-dropped"
+// event is triggered on the document with their URL, so that the app may save them locally for a later visit.
+//
+// Producers are called at most once each time the page becomes unactive, and again only after the page was
+// visible anew. They run synchronously: the context expires after ExitTimeout and should be checked by
+// producers doing significant work.
+
+// ExitBudget is the maximum number of bytes sent when the page becomes unactive.
+var ExitBudget = 60 * 1024
+
+// ExitTimeout is the time producers have to provide their payloads.
+var ExitTimeout = 50 * time.Millisecond
+
+// ExitPayload is data sent to a server when the page becomes unactive. The request method is POST.
+type ExitPayload struct {
+	URL         string
+	ContentType string
+	Body        []byte
+}
+
+type exitState struct {
+	producers map[int]func(ctx context.Context) []ExitPayload
+	next      int
+	flushed   bool
+}
+
+var exits = newscsmap[*ui.Element, *exitState]()
+
+// OnExit registers a producer of payloads to send when the page becomes unactive.
+// The returned function removes it.
+func (d *Document) OnExit(producer func(ctx context.Context) []ExitPayload) (remove func()) {
+	s, ok := exits.Get(d.AsElement())
+	if !ok {
+		s = &exitState{producers: make(map[int]func(ctx context.Context) []ExitPayload)}
+		exits.Set(d.AsElement(), s)
+		d.OnBeforeUnactive(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if s.flushed {
+				return false
+			}
+			s.flushed = true
+			s.flush(d)
+			return false
+		}))
+		d.Watch(Namespace.UI, "visibilitystate", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if string(evt.NewValue().(ui.String)) == "visible" {
+				s.flushed = false
+			}
+			return false
+		}))
+	}
+	id := s.next
+	s.next++
+	s.producers[id] = producer
+	return func() {
+		delete(s.producers, id)
+	}
+}
+
+func (s *exitState) flush(d *Document) {
+	if !InBrowser() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ExitTimeout)
+	defer cancel()
+
+	budget := ExitBudget
+	for id := 0; id < s.next; id++ {
+		producer, ok := s.producers[id]
+		if !ok {
+			continue
+		}
+		for _, p := range producer(ctx) {
+			if len(p.Body) > budget {
+				DEBUG("exit payload dropped, budget exceeded: ", p.URL)
+				d.TriggerEvent("exit-payload-dropped", ui.String(p.URL))
+				continue
+			}
+			if !sendExitPayload(p) {
+				d.TriggerEvent("exit-payload-dropped", ui.String(p.URL))
+				continue
+			}
+			budget -= len(p.Body)
+		}
+	}
+}
+
+// sendExitPayload sends a payload with a beacon, or a keepalive request as a fallback.
+func sendExitPayload(p ExitPayload) bool {
+	ct := p.ContentType
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	nav := js.Global().Get("navigator")
+	if nav.Get("sendBeacon").Truthy() {
+		blob := js.Global().Get("Blob").New([]interface{}{jsBytes(p.Body)}, map[string]interface{}{"type": ct})
+		if nav.Call("sendBeacon", p.URL, blob).Bool() {
+			return true
+		}
+	}
+	fetch := js.Global().Get("fetch")
+	if !fetch.Truthy() {
+		return false
+	}
+	fetch.Invoke(p.URL, map[string]interface{}{
+		"method":    "POST",
+		"body":      jsBytes(p.Body),
+		"keepalive": true,
+		"headers":   map[string]interface{}{"Content-Type": ct},
+	}).Call("catch", js.Global().Get("Function").New())
+	return true
+}
@@ -0,0 +1,152 @@
+package doc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Image export
+//
+// ExportAsImage rasterizes the subtree of an element, e.g. to download a chart or share a card as a PNG.
+// The subtree is cloned with its computed styles inlined, serialized into an SVG foreignObject, and drawn
+// onto a canvas.
+// Images embedded in the subtree must be same-origin or data urls: cross-origin resources taint the canvas,
+// in which case the export fails. Web fonts that are not installed locally are replaced by fallback fonts.
+//
+// Like the Crypto service, ExportAsImage blocks until the image is encoded and should be called from a
+// goroutine, typically within ui.DoAsync.
+
+var ErrExportUnavailable = errors.New("image export is not available")
+
+// ImageOptions configures the export of an element as an image.
+type ImageOptions struct {
+	// Format is the MIME type of the image. The default is "image/png".
+	Format string
+	// Quality is the quality of lossy formats, between 0 and 1. The browser default is used if zero.
+	Quality float64
+	// Scale is the ratio between image pixels and CSS pixels. The default is the device pixel ratio.
+	Scale float64
+	// Background is a CSS color painted under the element. The default is transparent.
+	Background string
+	// Timeout bounds the time spent loading the serialized image. The default is 10 seconds.
+	Timeout time.Duration
+}
+
+// ExportAsImage returns the encoded image of the element subtree.
+func ExportAsImage(e *ui.Element, options ImageOptions) ([]byte, error) {
+	if !InBrowser() {
+		return nil, ErrExportUnavailable
+	}
+	n, ok := JSValue(e)
+	if !ok {
+		return nil, ErrExportUnavailable
+	}
+	if options.Format == "" {
+		options.Format = "image/png"
+	}
+	if options.Scale <= 0 {
+		options.Scale = 1
+		if r := js.Global().Get("devicePixelRatio"); r.Truthy() {
+			options.Scale = r.Float()
+		}
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+
+	rect := n.Call("getBoundingClientRect")
+	width, height := rect.Get("width").Float(), rect.Get("height").Float()
+	if width == 0 || height == 0 {
+		return nil, errors.New("element has no size")
+	}
+
+	clone := n.Call("cloneNode", true)
+	inlineStyles(n, clone)
+	clone.Get("style").Call("setProperty", "margin", "0")
+	clone.Call("setAttribute", "xmlns", "http://www.w3.org/1999/xhtml")
+	xml := js.Global().Get("XMLSerializer").New().Call("serializeToString", clone).String()
+
+	w, h := strconv.FormatFloat(width, 'f', -1, 64), strconv.FormatFloat(height, 'f', -1, 64)
+	var svg strings.Builder
+	svg.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="` + w + `" height="` + h + `">`)
+	svg.WriteString(`<foreignObject x="0" y="0" width="100%" height="100%">`)
+	svg.WriteString(xml)
+	svg.WriteString(`</foreignObject></svg>`)
+	src := "data:image/svg+xml;charset=utf-8," + js.Global().Call("encodeURIComponent", svg.String()).String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout)
+	defer cancel()
+	img := js.Global().Get("Image").New()
+	img.Set("width", width)
+	img.Set("height", height)
+	img.Set("src", src)
+	if _, err := awaitPromise(ctx, img.Call("decode")); err != nil {
+		return nil, err
+	}
+
+	canvas := js.Global().Get("document").Call("createElement", "canvas")
+	canvas.Set("width", int(width*options.Scale+0.5))
+	canvas.Set("height", int(height*options.Scale+0.5))
+	c := canvas.Call("getContext", "2d")
+	c.Call("scale", options.Scale, options.Scale)
+	if options.Background != "" {
+		c.Set("fillStyle", options.Background)
+		c.Call("fillRect", 0, 0, width, height)
+	}
+	c.Call("drawImage", img, 0, 0, width, height)
+
+	var url string
+	var err error
+	func() {
+		// toDataURL throws on a tainted canvas.
+		defer func() {
+			if r := recover(); r != nil {
+				err = errors.New("canvas is tainted by cross-origin content")
+			}
+		}()
+		if options.Quality > 0 {
+			url = canvas.Call("toDataURL", options.Format, options.Quality).String()
+			return
+		}
+		url = canvas.Call("toDataURL", options.Format).String()
+	}()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(url, "data:"+options.Format) {
+		return nil, errors.New("image format " + options.Format + " is not supported")
+	}
+	i := strings.Index(url, ",")
+	return base64.StdEncoding.DecodeString(url[i+1:])
+}
+
+// inlineStyles copies the computed styles of a source subtree onto its clone.
+func inlineStyles(src, dst js.Value) {
+	if src.Get("nodeType").Int() != 1 {
+		return
+	}
+	cs := js.Global().Call("getComputedStyle", src)
+	style := dst.Get("style")
+	for i := 0; i < cs.Get("length").Int(); i++ {
+		name := cs.Index(i).String()
+		style.Call("setProperty", name, cs.Call("getPropertyValue", name), cs.Call("getPropertyPriority", name))
+	}
+	if tag := src.Get("tagName").String(); tag == "INPUT" || tag == "TEXTAREA" {
+		// the current value is not part of the serialized markup.
+		dst.Call("setAttribute", "value", src.Get("value"))
+		if tag == "TEXTAREA" {
+			dst.Set("textContent", src.Get("value"))
+		}
+	}
+	sc, dc := src.Get("children"), dst.Get("children")
+	for i := 0; i < sc.Get("length").Int(); i++ {
+		inlineStyles(sc.Index(i), dc.Index(i))
+	}
+}
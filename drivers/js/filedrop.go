@@ -0,0 +1,326 @@
+package doc
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// File drop
+//
+// OnFileDrop turns the whole window into a drop target for files. While files are dragged over the window,
+// an overlay (zui-drop-overlay class) displays the label of the handler that would receive them, and the zone
+// under the pointer, if any, has the zui-drop-target class.
+// The browser default of opening dropped files is always suppressed, even when no handler is relevant.
+//
+// A drop is routed to a single handler, by order of precedence:
+//   - the handler of the zone the files are dropped onto,
+//   - the handler of the zone holding the focus,
+//   - the handler of the longest route prefix matching the current route,
+//   - a handler registered without zone nor route.
+//
+// Handlers only receive the files whose type matches their Accept list, if any.
+
+// FileDropStyleSheetID is the id of the stylesheet holding the default file drop rules.
+const FileDropStyleSheetID = "zui-filedrop"
+
+// DropOverlayLabel is the text of the overlay when the relevant handler has no label.
+var DropOverlayLabel = "Drop files here"
+
+// FileDropOptions scopes a file drop handler.
+type FileDropOptions struct {
+	Route  string      // route prefix under which the handler is relevant
+	Zone   *ui.Element // element receiving the drops made onto it or while it holds the focus
+	Accept []string    // MIME types ("image/*", "application/pdf") or extensions (".csv")
+	Label  string      // text of the overlay
+}
+
+// DroppedFile describes a file dropped onto the document.
+type DroppedFile struct {
+	Name         string
+	Type         string
+	Size         int64
+	LastModified time.Time
+	js.Value
+}
+
+// Bytes returns the content of the file. It blocks until the file is read and should be called from a
+// goroutine, typically within ui.DoAsync.
+func (f DroppedFile) Bytes(ctx context.Context) ([]byte, error) {
+	buf, err := awaitPromise(ctx, f.Call("arrayBuffer"))
+	if err != nil {
+		return nil, err
+	}
+	return goBytes(js.Global().Get("Uint8Array").New(buf)), nil
+}
+
+type fileDropHandler struct {
+	opts FileDropOptions
+	h    func([]DroppedFile)
+}
+
+type fileDropState struct {
+	handlers []*fileDropHandler
+	overlay  js.Value
+	depth    int
+	target   js.Value
+}
+
+var fileDrops = newscsmap[*ui.Element, *fileDropState]()
+
+// OnFileDrop registers a handler for the files dropped onto the window. The returned function removes it.
+func (d *Document) OnFileDrop(h func(files []DroppedFile), opts ...FileDropOptions) (remove func()) {
+	var o FileDropOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	fh := &fileDropHandler{o, h}
+	remove = func() {}
+	if !InBrowser() {
+		return remove
+	}
+
+	s, ok := fileDrops.Get(d.AsElement())
+	if !ok {
+		s = &fileDropState{}
+		fileDrops.Set(d.AsElement(), s)
+		s.listen(d)
+	}
+	s.handlers = append(s.handlers, fh)
+	remove = func() {
+		for i, r := range s.handlers {
+			if r == fh {
+				s.handlers = append(s.handlers[:i], s.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+	if o.Zone != nil {
+		o.Zone.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			remove()
+			return false
+		}).RunOnce())
+	}
+	return remove
+}
+
+func (s *fileDropState) listen(d *Document) {
+	fileDropStyleSheet(d)
+	w := d.Window().AsElement()
+
+	hasFiles := func(evt ui.Event) (js.Value, bool) {
+		e := evt.Native().(NativeEvent).Value
+		dt := e.Get("dataTransfer")
+		if !dt.Truthy() {
+			return e, false
+		}
+		return e, dt.Get("types").Call("includes", "Files").Bool()
+	}
+
+	w.AddEventListener("dragenter", ui.NewEventHandler(func(evt ui.Event) bool {
+		if _, ok := hasFiles(evt); !ok {
+			return false
+		}
+		evt.PreventDefault()
+		s.depth++
+		if s.depth == 1 {
+			s.showOverlay(d)
+		}
+		return false
+	}))
+	w.AddEventListener("dragover", ui.NewEventHandler(func(evt ui.Event) bool {
+		e, ok := hasFiles(evt)
+		if !ok {
+			return false
+		}
+		evt.PreventDefault()
+		h := s.resolve(d, e.Get("target"))
+		if h == nil {
+			e.Get("dataTransfer").Set("dropEffect", "none")
+		} else {
+			e.Get("dataTransfer").Set("dropEffect", "copy")
+		}
+		s.highlight(h)
+		return false
+	}))
+	w.AddEventListener("dragleave", ui.NewEventHandler(func(evt ui.Event) bool {
+		if _, ok := hasFiles(evt); !ok {
+			return false
+		}
+		s.depth--
+		if s.depth <= 0 {
+			s.reset()
+		}
+		return false
+	}))
+	w.AddEventListener("drop", ui.NewEventHandler(func(evt ui.Event) bool {
+		e, ok := hasFiles(evt)
+		if !ok {
+			return false
+		}
+		evt.PreventDefault()
+		h := s.resolve(d, e.Get("target"))
+		s.reset()
+		if h == nil {
+			return false
+		}
+		var files []DroppedFile
+		list := e.Get("dataTransfer").Get("files")
+		for i := 0; i < list.Get("length").Int(); i++ {
+			f := list.Call("item", i)
+			df := DroppedFile{
+				Name:         f.Get("name").String(),
+				Type:         f.Get("type").String(),
+				Size:         int64(f.Get("size").Float()),
+				LastModified: time.UnixMilli(int64(f.Get("lastModified").Float())),
+				Value:        f,
+			}
+			if accepts(h.opts.Accept, df) {
+				files = append(files, df)
+			}
+		}
+		if len(files) > 0 {
+			h.h(files)
+		}
+		return false
+	}))
+	w.AddEventListener("dragend", ui.NewEventHandler(func(evt ui.Event) bool {
+		s.reset()
+		return false
+	}))
+}
+
+// resolve returns the handler a drop onto the target node should be routed to.
+func (s *fileDropState) resolve(d *Document, target js.Value) *fileDropHandler {
+	active := js.Global().Get("document").Get("activeElement")
+	var focused, routed, global *fileDropHandler
+	var prefix int = -1
+	route := ""
+	if v, ok := d.GetUI("currentroute"); ok {
+		route = string(v.(ui.String))
+	}
+	for _, h := range s.handlers {
+		if z := h.opts.Zone; z != nil {
+			n, ok := JSValue(z)
+			if !ok || !z.Mounted() {
+				continue
+			}
+			if target.Truthy() && n.Call("contains", target).Bool() {
+				return h
+			}
+			if focused == nil && active.Truthy() && n.Call("contains", active).Bool() {
+				focused = h
+			}
+			continue
+		}
+		if h.opts.Route != "" {
+			if strings.HasPrefix(route, h.opts.Route) && len(h.opts.Route) > prefix {
+				routed, prefix = h, len(h.opts.Route)
+			}
+			continue
+		}
+		if global == nil {
+			global = h
+		}
+	}
+	switch {
+	case focused != nil:
+		return focused
+	case routed != nil:
+		return routed
+	}
+	return global
+}
+
+func accepts(accept []string, f DroppedFile) bool {
+	if len(accept) == 0 {
+		return true
+	}
+	for _, a := range accept {
+		a = strings.ToLower(strings.TrimSpace(a))
+		switch {
+		case strings.HasPrefix(a, "."):
+			if strings.ToLower(path.Ext(f.Name)) == a {
+				return true
+			}
+		case strings.HasSuffix(a, "/*"):
+			if strings.HasPrefix(f.Type, strings.TrimSuffix(a, "*")) {
+				return true
+			}
+		case a == f.Type:
+			return true
+		}
+	}
+	return false
+}
+
+func (s *fileDropState) showOverlay(d *Document) {
+	doc := js.Global().Get("document")
+	if !s.overlay.Truthy() {
+		s.overlay = doc.Call("createElement", "div")
+		s.overlay.Set("className", "zui-drop-overlay")
+		s.overlay.Call("setAttribute", "aria-hidden", "true")
+	}
+	label := DropOverlayLabel
+	if h := s.resolve(d, js.Null()); h != nil && h.opts.Label != "" {
+		label = h.opts.Label
+	}
+	s.overlay.Set("textContent", label)
+	doc.Get("body").Call("append", s.overlay)
+}
+
+func (s *fileDropState) highlight(h *fileDropHandler) {
+	var n js.Value
+	if h != nil && h.opts.Zone != nil {
+		n, _ = JSValue(h.opts.Zone)
+	}
+	if s.target.Truthy() && !s.target.Equal(n) {
+		s.target.Get("classList").Call("remove", "zui-drop-target")
+	}
+	s.target = n
+	if n.Truthy() {
+		n.Get("classList").Call("add", "zui-drop-target")
+	}
+	if s.overlay.Truthy() && h != nil && h.opts.Label != "" {
+		s.overlay.Set("textContent", h.opts.Label)
+	}
+}
+
+func (s *fileDropState) reset() {
+	s.depth = 0
+	s.highlight(nil)
+	s.target = js.Value{}
+	if s.overlay.Truthy() {
+		s.overlay.Call("remove")
+	}
+}
+
+func fileDropStyleSheet(d *Document) {
+	if _, ok := d.GetStyleSheet(FileDropStyleSheetID); ok {
+		return
+	}
+	sheet := d.NewStyleSheet(FileDropStyleSheetID)
+	actives := append([]string{FileDropStyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+
+	sheet.InsertRule(".zui-drop-overlay", `
+		position: fixed;
+		inset: 0;
+		z-index: 2147483646;
+		display: flex;
+		align-items: center;
+		justify-content: center;
+		pointer-events: none;
+		font-size: 1.5rem;
+		color: #fff;
+		background: rgb(0 0 0 / 0.45);
+		outline: 3px dashed #fff;
+		outline-offset: -1rem;
+	`)
+	sheet.InsertRule(".zui-drop-target", "outline: 2px dashed Highlight; outline-offset: 2px;")
+	sheet.Update()
+}
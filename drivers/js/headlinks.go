@@ -0,0 +1,199 @@
+package doc
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Head links
+//
+// These helpers manage the <link> elements of the document head. Links are deduplicated by rel and href, so
+// that calling a helper several times for the same resource returns the same LinkElement.
+// Stylesheet links have a "status" ui property which is "loading" until the native load or error event
+// fires, at which point it becomes "loaded" or "error".
+//
+// Fonts loaded with LoadFont use the FontFace API in the browser. While fonts are loading, the document has
+// the "fonts-loading" class. It is replaced by "fonts-loaded" (or "fonts-failed" if any font failed to load)
+// once they are done, so that stylesheets can mitigate the flash of unstyled text.
+// The status of each font is available via FontStatus and the "font-<family>" ui property of the document.
+// On the server, a preload link is rendered for the first source of the font instead.
+//
+// Prefetch links are added in the browser for the critical resources announced by nested prefetching.
+
+// Font display strategies, see the CSS font-display descriptor.
+const (
+	FontDisplayAuto     = "auto"
+	FontDisplayBlock    = "block"
+	FontDisplaySwap     = "swap"
+	FontDisplayFallback = "fallback"
+	FontDisplayOptional = "optional"
+)
+
+func linkID(rel, href string) string {
+	h := fnv.New64a()
+	h.Write([]byte(href))
+	return "link-" + strings.ReplaceAll(rel, " ", "-") + "-" + strconv.FormatUint(h.Sum64(), 36)
+}
+
+func (d *Document) headLink(rel, href string, init func(LinkElement)) LinkElement {
+	id := linkID(rel, href)
+	if e := d.GetElementById(id); e != nil {
+		return LinkElement{e}
+	}
+	l := d.Link.WithID(id).SetRel(rel).SetHref(href)
+	if init != nil {
+		init(l)
+	}
+	d.Head().AppendChild(l)
+	return l
+}
+
+// AddStylesheetLink adds a stylesheet link to the head of the document. The media argument is optional.
+func (d *Document) AddStylesheetLink(href, media string) LinkElement {
+	return d.headLink("stylesheet", href, func(l LinkElement) {
+		if media != "" {
+			l.SetAttribute("media", media)
+		}
+		trackLinkStatus(l, href)
+	})
+}
+
+// Preconnect adds a preconnect link for the given origin to the head of the document.
+func (d *Document) Preconnect(origin string, crossorigin bool) LinkElement {
+	return d.headLink("preconnect", origin, func(l LinkElement) {
+		if crossorigin {
+			l.SetAttribute("crossorigin", "")
+		}
+	})
+}
+
+// PrefetchResource adds a prefetch link for the given resource to the head of the document.
+// Critical resources of the views warmed by link prefetching are added this way.
+func (d *Document) PrefetchResource(href string) LinkElement {
+	return d.headLink("prefetch", href, nil)
+}
+
+// RemoveLink removes the head link with the given rel and href, if any.
+func (d *Document) RemoveLink(rel, href string) {
+	if e := d.GetElementById(linkID(rel, href)); e != nil {
+		ui.Delete(e)
+	}
+}
+
+func trackLinkStatus(l LinkElement, href string) {
+	e := l.AsElement()
+	if !InBrowser() {
+		return
+	}
+	e.SetUI("status", ui.String("loading"))
+	e.AddEventListener("load", ui.NewEventHandler(func(evt ui.Event) bool {
+		e.SetUI("status", ui.String("loaded"))
+		return false
+	}).TriggerOnce())
+	e.AddEventListener("error", ui.NewEventHandler(func(evt ui.Event) bool {
+		e.SetUI("status", ui.String("error"))
+		ReportError(e, FrameworkError{Code: "link-error", Message: "unable to load " + href, Severity: SeverityWarning})
+		return false
+	}).TriggerOnce())
+}
+
+// LoadFont loads a font family from a list of source urls, using the given font-display strategy.
+func (d *Document) LoadFont(family string, urls []string, display string) {
+	if len(urls) == 0 {
+		return
+	}
+	if display == "" {
+		display = FontDisplaySwap
+	}
+	prop := "font-" + family
+	if _, ok := d.GetUI(prop); ok {
+		return
+	}
+
+	if !InBrowser() {
+		d.headLink("preload", urls[0], func(l LinkElement) {
+			l.SetAttribute("as", "font")
+			l.SetAttribute("crossorigin", "")
+		})
+		return
+	}
+
+	d.SetUI(prop, ui.String("loading"))
+	d.updateFontClasses()
+
+	srcs := make([]string, 0, len(urls))
+	for _, u := range urls {
+		srcs = append(srcs, "url("+strconv.Quote(u)+")")
+	}
+	face := js.Global().Get("FontFace").New(family, strings.Join(srcs, ", "), map[string]interface{}{"display": display})
+
+	var then, catch js.Func
+	release := func() {
+		then.Release()
+		catch.Release()
+	}
+	then = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		js.Global().Get("document").Get("fonts").Call("add", args[0])
+		go ui.DoSync(func() {
+			d.SetUI(prop, ui.String("loaded"))
+			d.updateFontClasses()
+		})
+		release()
+		return nil
+	})
+	catch = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			d.SetUI(prop, ui.String("error"))
+			d.updateFontClasses()
+			ReportError(d.AsElement(), FrameworkError{Code: "font-error", Message: "unable to load font " + family, Severity: SeverityWarning})
+		})
+		release()
+		return nil
+	})
+	face.Call("load").Call("then", then).Call("catch", catch)
+}
+
+// FontStatus returns the loading status of a font family loaded with LoadFont: "loading", "loaded", "error",
+// or the empty string if it is unknown.
+func (d *Document) FontStatus(family string) string {
+	v, ok := d.GetUI("font-" + family)
+	if !ok {
+		return ""
+	}
+	return string(v.(ui.String))
+}
+
+func (d *Document) updateFontClasses() {
+	var loading, failed bool
+	for prop, v := range d.Properties.Categories[Namespace.UI].Local {
+		if !strings.HasPrefix(prop, "font-") {
+			continue
+		}
+		s, ok := v.(ui.String)
+		if !ok {
+			continue
+		}
+		switch s {
+		case "loading":
+			loading = true
+		case "error":
+			failed = true
+		}
+	}
+	e := d.AsElement()
+	RemoveClass(e, "fonts-loading")
+	RemoveClass(e, "fonts-loaded")
+	RemoveClass(e, "fonts-failed")
+	switch {
+	case loading:
+		AddClass(e, "fonts-loading")
+	case failed:
+		AddClass(e, "fonts-failed")
+	default:
+		AddClass(e, "fonts-loaded")
+	}
+}
@@ -127,15 +127,24 @@ type SSEController struct{
     // The channel to send messages to the client
     Message chan string
     Once *sync.Once
+    closed chan struct{}
 }
 
 func NewSSEController() *SSEController {
     return &SSEController{
         Message: make(chan string),
         Once: &sync.Once{},
+        closed: make(chan struct{}),
     }
 }
 
+// Close sends a "server-restarting" event to the client and ends the connection.
+func(s *SSEController) Close() {
+    s.Once.Do(func() {
+        close(s.closed)
+    })
+}
+
 func(s *SSEController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
     // Make sure that the writer supports flushing.
     fw, ok := w.(http.Flusher)
@@ -154,9 +163,14 @@ func(s *SSEController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
     clientMsgChan := make(chan string)
     clientClosed := make(chan struct{})
+    senderDone := make(chan struct{})
+
+    trackSSEController(s)
+    defer untrackSSEController(s)
 
     // Goroutine to handle sending messages to this client
     go func() {
+        defer close(senderDone)
         for {
             select {
             case msg, ok := <-clientMsgChan:
@@ -178,6 +192,11 @@ func(s *SSEController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
             close(clientClosed) // Notify the sending goroutine to stop
             close(clientMsgChan) // Close the channel to stop sending messages
             return
+        case <-s.closed:
+            clientMsgChan <- Msg("server-restarting", "", "", "").String()
+            close(clientMsgChan)
+            <-senderDone // the response must not be written to once the handler has returned
+            return
         case msg := <-s.Message:
             // Send the message to the client-specific channel
             clientMsgChan <- msg
@@ -186,5 +205,34 @@ func(s *SSEController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func(s *SSEController) SendEvent(event, data, id, retry string) {
-    s.Message <- Msg(event, data, id, retry).String()
-}
\ No newline at end of file
+    select {
+    case s.Message <- Msg(event, data, id, retry).String():
+    case <-s.closed:
+    }
+}
+
+// The SSE controllers are tracked so that they can be closed on shutdown.
+var sseControllers = struct {
+    sync.Mutex
+    m map[*SSEController]struct{}
+}{m: make(map[*SSEController]struct{})}
+
+func trackSSEController(s *SSEController) {
+    sseControllers.Lock()
+    sseControllers.m[s] = struct{}{}
+    sseControllers.Unlock()
+}
+
+func untrackSSEController(s *SSEController) {
+    sseControllers.Lock()
+    delete(sseControllers.m, s)
+    sseControllers.Unlock()
+}
+
+func closeSSEControllers() {
+    sseControllers.Lock()
+    defer sseControllers.Unlock()
+    for s := range sseControllers.m {
+        s.Close()
+    }
+}
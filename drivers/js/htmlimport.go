@@ -0,0 +1,161 @@
+package doc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTML import
+//
+// ParseHTML builds a managed Element subtree from a fragment of trusted HTML, e.g. content coming from a CMS,
+// so that it takes part in the reactive tree instead of being injected as an opaque innerHTML blob.
+//   - Elements are created with the constructor registered for their tag. The tags of text-level and grouping
+//     content without dedicated constructor (b, em, strong, pre, blockquote...) get a generic one.
+//   - Attributes are imported. The id attribute is used as the element id if it is not already taken, classes
+//     are added with AddClass, and inline event handlers (on* attributes) are dropped.
+//   - Text is set as the text of its parent if the parent has no child element, and imported as span
+//     elements otherwise. Whitespace-only text holding a line break is considered indentation and dropped.
+//
+// A fragment made of a single element returns that element. Otherwise, its nodes are wrapped in a div.
+// The fragment is not sanitized beyond the attribute sanitization applied by SetAttribute.
+
+// ErrUnsupportedTag is returned by ParseHTML when the fragment holds an element that has no constructor.
+var ErrUnsupportedTag = errors.New("no constructor available for this tag")
+
+// genericTags lists the tags that ParseHTML supports through a generic constructor.
+var genericTags = []string{
+	"b", "strong", "i", "em", "u", "s", "small", "mark", "sub", "sup", "abbr", "cite", "q", "dfn", "kbd",
+	"samp", "var", "time", "data", "del", "ins", "br", "wbr", "hr", "pre", "blockquote", "figure", "figcaption",
+	"dl", "dt", "dd", "caption", "address", "picture",
+}
+
+var newGenericElements = func() map[string]func(id string, options ...string) *ui.Element {
+	m := make(map[string]func(id string, options ...string) *ui.Element, len(genericTags))
+	for _, tag := range genericTags {
+		tag := tag
+		m[tag] = Elements.NewConstructor(tag, func(id string) *ui.Element {
+			e := Elements.NewElement(id, DOCTYPE)
+			e = enableClasses(e)
+			ConnectNative(e, tag)
+			e.Watch(Namespace.UI, "text", e, textContentHandler)
+			return e
+		})
+	}
+	return m
+}()
+
+// ParseHTML parses a fragment of trusted HTML into an Element subtree owned by the document.
+func (d *Document) ParseHTML(fragment string) (*ui.Element, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return nil, err
+	}
+	children, err := d.importNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	wrapper := d.Div.WithID(d.newID())
+	wrapper.AsElement().SetChildren(children...)
+	return wrapper.AsElement(), nil
+}
+
+// importNodes creates the elements for a list of sibling nodes.
+func (d *Document) importNodes(nodes []*html.Node) ([]*ui.Element, error) {
+	res := make([]*ui.Element, 0, len(nodes))
+	for _, n := range nodes {
+		switch n.Type {
+		case html.ElementNode:
+			e, err := d.importElement(n)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, e)
+		case html.TextNode:
+			if isIndentation(n.Data) {
+				continue
+			}
+			res = append(res, d.Span.WithID(d.newID()).SetText(n.Data).AsElement())
+		}
+	}
+	return res, nil
+}
+
+func (d *Document) importElement(n *html.Node) (*ui.Element, error) {
+	tag := n.Data
+	c, ok := Elements.Constructors[tag]
+	if !ok {
+		c, ok = newGenericElements[tag]
+	}
+	if !ok || tag == "script" || tag == "html" || tag == "head" || tag == "body" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedTag, tag)
+	}
+
+	id := d.newID()
+	var options []string
+	for _, a := range n.Attr {
+		switch {
+		case a.Key == "id" && a.Val != "" && d.GetElementById(a.Val) == nil:
+			id = a.Val
+		case a.Key == "type" && (tag == "input" || tag == "button"):
+			options = append(options, a.Val)
+		}
+	}
+
+	start := d.constructionStart()
+	e := c(id, options...)
+	ui.RegisterElement(d.AsElement(), e)
+	d.recordConstruction(e, start)
+
+	for _, a := range n.Attr {
+		switch {
+		case a.Namespace != "", a.Key == "id", strings.HasPrefix(a.Key, "on"):
+			continue
+		case a.Key == "class":
+			for _, class := range strings.Fields(a.Val) {
+				AddClass(e, class)
+			}
+		default:
+			SetAttribute(e, a.Key, a.Val)
+		}
+	}
+
+	var text strings.Builder
+	haselements := false
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			haselements = true
+		case html.TextNode:
+			text.WriteString(c.Data)
+		}
+	}
+	if !haselements {
+		if text.Len() > 0 {
+			e.SetDataSetUI("text", ui.String(text.String()))
+		}
+		return e, nil
+	}
+
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	children, err := d.importNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+	e.SetChildren(children...)
+	return e, nil
+}
+
+func isIndentation(s string) bool {
+	return strings.TrimSpace(s) == "" && strings.ContainsAny(s, "\n\r")
+}
@@ -0,0 +1,218 @@
+package doc
+
+import (
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Progressive hydration
+//
+// Replaying a long mutation trace delays the moment the page becomes interactive. When the trace holds more
+// than ProgressiveReplayThreshold mutations, the replay is progressive: the mutations of the elements
+// displayed in or near the viewport are replayed first, and the other ones are deferred to idle periods.
+//
+// The elements without a rendered node (observables, router state...) and the ancestors of the displayed
+// elements are never deferred, so that a replayed element never depends on an ancestor whose state is
+// missing. Mutations are otherwise replayed in the order of the trace, and the mutations derived from a
+// replayed one by its watchers are not replayed twice.
+// As a safeguard, the remaining mutations are replayed at once on the first user interaction or navigation.
+//
+// The "replay" transition of the document, hence the "mutation-replayed" event, only ends once every
+// mutation has been replayed. Progressive replay is only available in the browser. It does not apply to
+// streamed documents, whose chunks are replayed as they arrive instead (see StreamSSR).
+
+// ProgressiveReplayThreshold is the number of mutations above which the replay is progressive.
+// Zero disables progressive replay.
+var ProgressiveReplayThreshold = 0
+
+// ProgressiveReplayMargin is the distance to the viewport, as a fraction of its height, within which
+// elements are replayed first.
+var ProgressiveReplayMargin = 0.5
+
+// progressiveReplayBudget is the time spent replaying deferred mutations per idle period.
+var progressiveReplayBudget = 8 * time.Millisecond
+
+type deferredReplay struct {
+	ops       []ui.Value
+	done      []bool
+	pending   []int
+	processed int
+	step      int
+}
+
+func progressiveReplayEnabled(n int) bool {
+	return ProgressiveReplayThreshold > 0 && n > ProgressiveReplayThreshold && InBrowser() && !streamedDocument()
+}
+
+func replayPending(d *Document) bool {
+	return d.deferredReplay != nil || d.streamedReplay != nil
+}
+
+func progressiveReplay(d *Document, trace ui.List) error {
+	ops := trace.UnsafelyUnwrap()
+	r := &deferredReplay{ops: ops, done: make([]bool, len(ops)), step: len(ops) / 100}
+	if r.step < 1 {
+		r.step = 1
+	}
+
+	first := firstPaintElements(ops)
+	for pos, op := range ops {
+		if r.done[pos] {
+			continue
+		}
+		if !first[op.(ui.Object).MustGetString("id").String()] {
+			r.pending = append(r.pending, pos)
+			continue
+		}
+		if err := r.apply(d, pos); err != nil {
+			return err
+		}
+	}
+	if len(r.pending) == 0 {
+		d.Set(Namespace.Internals, "mutation-list-index", ui.Number(len(ops)))
+		return nil
+	}
+
+	d.deferredReplay = r
+	var cb js.Func
+	schedule := func() {
+		if ric := js.Global().Get("requestIdleCallback"); ric.Truthy() {
+			ric.Invoke(cb)
+			return
+		}
+		js.Global().Call("setTimeout", cb, 16)
+	}
+	cb = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			if d.deferredReplay != r {
+				cb.Release()
+				return
+			}
+			r.resume(d, false)
+			if d.deferredReplay == r {
+				schedule()
+				return
+			}
+			cb.Release()
+		})
+		return nil
+	})
+	schedule()
+
+	flush := ui.NewEventHandler(func(evt ui.Event) bool {
+		if d.deferredReplay == r {
+			r.resume(d, true)
+		}
+		return false
+	})
+	for _, typ := range []string{"pointerdown", "keydown", "focusin"} {
+		d.Window().AsElement().AddEventListener(typ, flush)
+	}
+	d.WatchEvent("navigation-start", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if d.deferredReplay == r {
+			r.resume(d, true)
+		}
+		return false
+	}))
+	return nil
+}
+
+// apply replays the mutation at the given position of the trace, as well as its derived mutations.
+func (r *deferredReplay) apply(d *Document, pos int) error {
+	d.Set(Namespace.Internals, "mutation-list-index", ui.Number(pos))
+	if err := replayop(d, r.ops[pos].(ui.Object)); err != nil {
+		return err
+	}
+	r.done[pos] = true
+	r.processed++
+
+	// The mutations set by the watchers of the replayed one were recorded right after it. They are counted by
+	// the mutation list index. Those which were already replayed did not change anything and were not counted.
+	i, ok := d.Get(Namespace.Internals, "mutation-list-index")
+	if ok {
+		derived := int(i.(ui.Number)) - pos
+		for q := pos + 1; derived > 0 && q < len(r.ops); q++ {
+			if r.done[q] {
+				continue
+			}
+			r.done[q] = true
+			r.processed++
+			derived--
+		}
+	}
+	if r.processed%r.step == 0 || r.processed >= len(r.ops) {
+		d.TriggerEvent("replay-progress", ui.NewObject().Set("processed", ui.Number(r.processed)).Set("total", ui.Number(len(r.ops))).Commit())
+	}
+	return nil
+}
+
+// resume replays deferred mutations for the duration of the replay budget, or all of them.
+func (r *deferredReplay) resume(d *Document, all bool) {
+	start := time.Now()
+	d.Set(Namespace.Internals, "mutation-replaying", ui.Bool(true))
+	for len(r.pending) > 0 {
+		pos := r.pending[0]
+		r.pending = r.pending[1:]
+		if r.done[pos] {
+			continue
+		}
+		if err := r.apply(d, pos); err != nil {
+			d.Set(Namespace.Internals, "mutation-replaying", ui.Bool(false))
+			d.deferredReplay = nil
+			d.ErrorTransition("replay", ui.String(err.Error()))
+			return
+		}
+		if !all && time.Since(start) > progressiveReplayBudget {
+			break
+		}
+	}
+	d.Set(Namespace.Internals, "mutation-replaying", ui.Bool(false))
+	if len(r.pending) > 0 {
+		return
+	}
+
+	d.deferredReplay = nil
+	d.Set(Namespace.Internals, "mutation-list-index", ui.Number(len(r.ops)))
+	if report := d.ReplayReport(); !report.Complete() {
+		DEBUG(report.String())
+	}
+	d.TriggerEvent("mutation-replayed")
+	d.EndTransition("replay")
+}
+
+// firstPaintElements returns the ids of the elements whose mutations should not be deferred.
+func firstPaintElements(ops []ui.Value) map[string]bool {
+	res := make(map[string]bool)
+	seen := make(map[string]bool)
+	doc := js.Global().Get("document")
+	vh := js.Global().Get("innerHeight").Float()
+	margin := vh * ProgressiveReplayMargin
+
+	for _, op := range ops {
+		id := op.(ui.Object).MustGetString("id").String()
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		n := doc.Call("getElementById", id)
+		if n.IsNull() {
+			res[id] = true
+			continue
+		}
+		rect := n.Call("getBoundingClientRect")
+		if rect.Get("width").Float() == 0 && rect.Get("height").Float() == 0 {
+			continue
+		}
+		if rect.Get("bottom").Float() < -margin || rect.Get("top").Float() > vh+margin {
+			continue
+		}
+		for ; !n.IsNull(); n = n.Get("parentElement") {
+			if pid := n.Get("id").String(); pid != "" {
+				res[pid] = true
+			}
+		}
+	}
+	return res
+}
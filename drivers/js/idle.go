@@ -0,0 +1,171 @@
+package doc
+
+import (
+	"sync"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Idle detection
+//
+// The idle detector tracks user activity (pointer, keyboard, wheel, touch and scroll events) and sets the
+// "activity" ui property of the document to "idle" once no activity has been observed for a given threshold.
+// It is set back to "active" on the next user interaction.
+// Optionally, the document is considered idle as soon as it is hidden (see the "visibilitystate" property),
+// and the Idle Detection API is used when available to also detect system-wide inactivity and screen locking.
+// Note that the Idle Detection API requires the "idle-detection" permission, which should be requested by the
+// app from a user gesture (IdleDetector.requestPermission).
+
+// DefaultIdleThreshold is the default inactivity duration after which the user is considered idle.
+var DefaultIdleThreshold = 5 * time.Minute
+
+// IdleOptions configures the idle detector.
+type IdleOptions struct {
+	Threshold      time.Duration
+	IdleWhenHidden bool // the user is considered idle as soon as the document is hidden
+	UseIdleAPI     bool // use the Idle Detection API if available and permitted
+}
+
+var activityEvents = []string{"pointermove", "pointerdown", "keydown", "wheel", "touchstart"}
+
+type idleDetector struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	last      time.Time
+	timer     ui.Timer
+}
+
+// EnableIdleDetection starts tracking user inactivity.
+func (d *Document) EnableIdleDetection(opts ...IdleOptions) *Document {
+	o := IdleOptions{Threshold: DefaultIdleThreshold, IdleWhenHidden: true}
+	if len(opts) > 0 {
+		o = opts[0]
+		if o.Threshold <= 0 {
+			o.Threshold = DefaultIdleThreshold
+		}
+	}
+	if _, ok := d.Get(Namespace.Internals, "idle-detection"); ok {
+		return d
+	}
+	d.Set(Namespace.Internals, "idle-detection", ui.Bool(true))
+	d.SetUI("activity", ui.String("active"))
+
+	det := &idleDetector{threshold: o.Threshold, last: ui.Now()}
+
+	var check func()
+	check = func() {
+		det.mu.Lock()
+		remaining := det.threshold - ui.Now().Sub(det.last)
+		if remaining > 0 {
+			det.timer = ui.AfterFunc(remaining, check)
+			det.mu.Unlock()
+			return
+		}
+		det.timer = nil
+		det.mu.Unlock()
+		ui.DoSync(func() {
+			d.SetUI("activity", ui.String("idle"))
+		})
+	}
+	det.timer = ui.AfterFunc(o.Threshold, check)
+
+	onactivity := ui.NewEventHandler(func(evt ui.Event) bool {
+		det.mu.Lock()
+		det.last = ui.Now()
+		if det.timer == nil {
+			det.timer = ui.AfterFunc(det.threshold, check)
+		}
+		det.mu.Unlock()
+		if d.IsIdle() {
+			d.SetUI("activity", ui.String("active"))
+		}
+		return false
+	}).AsPassive()
+	for _, evt := range activityEvents {
+		d.AddEventListener(evt, onactivity)
+	}
+	d.Window().AsElement().AddEventListener("scroll", onactivity)
+
+	if o.IdleWhenHidden {
+		d.Watch(Namespace.UI, "visibilitystate", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if string(evt.NewValue().(ui.String)) == "hidden" {
+				d.SetUI("activity", ui.String("idle"))
+				return false
+			}
+			onactivity.Fn(nil)
+			return false
+		}))
+	}
+
+	d.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		det.mu.Lock()
+		if det.timer != nil {
+			det.timer.Stop()
+		}
+		det.mu.Unlock()
+		return false
+	}).RunOnce())
+
+	if o.UseIdleAPI && InBrowser() {
+		useIdleDetectionAPI(d, o.Threshold)
+	}
+	return d
+}
+
+func useIdleDetectionAPI(d *Document, threshold time.Duration) {
+	ctor := js.Global().Get("IdleDetector")
+	if !ctor.Truthy() {
+		return
+	}
+	// The Idle Detection API does not accept thresholds below one minute.
+	if threshold < time.Minute {
+		threshold = time.Minute
+	}
+	detector := ctor.New()
+	detector.Call("addEventListener", "change", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		idle := detector.Get("userState").String() == "idle" || detector.Get("screenState").String() == "locked"
+		go ui.DoSync(func() {
+			if idle {
+				d.SetUI("activity", ui.String("idle"))
+			} else {
+				d.SetUI("activity", ui.String("active"))
+			}
+		})
+		return nil
+	}))
+	detector.Call("start", map[string]interface{}{"threshold": threshold.Milliseconds()}).Call("catch", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		DEBUG("idle detection API unavailable: ", args[0].Call("toString").String())
+		return nil
+	}))
+}
+
+// IsIdle returns whether the user is currently considered idle.
+func (d *Document) IsIdle() bool {
+	v, ok := d.GetUI("activity")
+	if !ok {
+		return false
+	}
+	return string(v.(ui.String)) == "idle"
+}
+
+// OnIdle registers a handler called when the user becomes idle.
+func (d *Document) OnIdle(h *ui.MutationHandler) {
+	d.Watch(Namespace.UI, "activity", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if string(evt.NewValue().(ui.String)) != "idle" {
+			return false
+		}
+		return h.Fn(evt)
+	}))
+}
+
+// OnActive registers a handler called when the user becomes active again after having been idle.
+func (d *Document) OnActive(h *ui.MutationHandler) {
+	d.Watch(Namespace.UI, "activity", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if string(evt.NewValue().(ui.String)) != "active" {
+			return false
+		}
+		return h.Fn(evt)
+	}))
+}
@@ -0,0 +1,267 @@
+package doc
+
+import (
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Inert subtrees and focus visibility
+//
+// SetInert makes a subtree inert: its elements can neither be focused nor clicked and are hidden from
+// assistive technologies. It relies on the inert attribute. Browsers which lack support for it get a
+// fallback: the subtree is marked aria-hidden, its focusable elements are removed from the tab order, pointer
+// events are disabled by the "zui-a11y" stylesheet, and focus moving into the subtree is reverted.
+// As for SetSubtreeDisabled, calls may be nested. InertOutside makes everything but an element inert, which is
+// what modal dialogs and menus need.
+//
+// Modifier.FocusVisible gives the zui-focus-visible class to an element while it has the focus and the focus
+// should be shown, i.e. when the last user input came from the keyboard, or when the element takes text input.
+// The class is toggled on the native element only, as it reflects a transient state.
+
+// A11yStyleSheetID is the id of the stylesheet holding the inert and focus visibility rules.
+const A11yStyleSheetID = "zui-a11y"
+
+const inertFocusable = `button, [href], input, select, textarea, iframe, [contenteditable], [tabindex]`
+
+// inputModalities holds the modality of the last user input per document: "keyboard" or "pointer".
+var inputModalities = newscsmap[*ui.Element, string]()
+
+// inertGuards records the documents whose focus is guarded against inert subtrees.
+var inertGuards = newscsmap[*ui.Element, bool]()
+
+// SetInert makes the subtree of an element inert, or interactive again.
+func SetInert(e *ui.Element, inert bool) {
+	count := 0
+	if v, ok := e.Get(Namespace.Internals, "inert-count"); ok {
+		count = int(v.(ui.Number))
+	}
+	if inert {
+		count++
+	} else if count > 0 {
+		count--
+	} else {
+		return
+	}
+	e.Set(Namespace.Internals, "inert-count", ui.Number(count))
+	if (inert && count != 1) || (!inert && count != 0) {
+		return
+	}
+
+	if inert {
+		SetAttribute(e, "inert", "")
+	} else {
+		RemoveAttribute(e, "inert")
+	}
+	if !InBrowser() || inertSupported() {
+		return
+	}
+	a11yStyleSheet(GetDocument(e))
+	if e.Mounted() {
+		inertFallback(e, inert)
+		return
+	}
+	e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		inertFallback(e, IsInert(e))
+		return false
+	}).RunOnce())
+}
+
+// IsInert returns whether an element was made inert with SetInert.
+func IsInert(e *ui.Element) bool {
+	v, ok := e.Get(Namespace.Internals, "inert-count")
+	return ok && int(v.(ui.Number)) > 0
+}
+
+// Inert returns an element modifier making the subtree of the element inert, or interactive again.
+func (m modifier) Inert(inert bool) func(e *ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		SetInert(e, inert)
+		return e
+	}
+}
+
+// InertOutside makes every element of the document inert, except for the subtree of e and its ancestors.
+// The returned function restores them.
+func InertOutside(e *ui.Element) (restore func()) {
+	var inerts []*ui.Element
+	for c := e; c.Parent != nil; c = c.Parent {
+		if c.Parent.Children == nil {
+			continue
+		}
+		for _, s := range c.Parent.Children.List {
+			if s == c {
+				continue
+			}
+			SetInert(s, true)
+			inerts = append(inerts, s)
+		}
+	}
+	return func() {
+		for _, s := range inerts {
+			SetInert(s, false)
+		}
+		inerts = nil
+	}
+}
+
+func inertSupported() bool {
+	return js.Global().Get("HTMLElement").Get("prototype").Call("hasOwnProperty", "inert").Bool()
+}
+
+// inertFallback emulates the inert attribute on the native element.
+func inertFallback(e *ui.Element, inert bool) {
+	n, ok := JSValue(e)
+	if !ok {
+		return
+	}
+	inertFocusGuard(GetDocument(e))
+
+	focusables := n.Call("querySelectorAll", inertFocusable)
+	if inert {
+		n.Call("setAttribute", "aria-hidden", "true")
+		for i := 0; i < focusables.Get("length").Int(); i++ {
+			f := focusables.Index(i)
+			if f.Call("hasAttribute", "data-zui-inert-tabindex").Bool() {
+				continue
+			}
+			prior := f.Call("getAttribute", "tabindex")
+			if prior.IsNull() {
+				f.Call("setAttribute", "data-zui-inert-tabindex", "")
+			} else {
+				f.Call("setAttribute", "data-zui-inert-tabindex", prior)
+			}
+			f.Call("setAttribute", "tabindex", "-1")
+		}
+		if n.Call("contains", js.Global().Get("document").Get("activeElement")).Bool() {
+			js.Global().Get("document").Get("activeElement").Call("blur")
+		}
+		return
+	}
+
+	n.Call("removeAttribute", "aria-hidden")
+	for i := 0; i < focusables.Get("length").Int(); i++ {
+		f := focusables.Index(i)
+		if !f.Call("hasAttribute", "data-zui-inert-tabindex").Bool() {
+			continue
+		}
+		// nested inert subtrees keep their elements out of the tab order.
+		if p := f.Get("parentElement"); p.Truthy() && p.Call("closest", "[inert]").Truthy() {
+			continue
+		}
+		if prior := f.Call("getAttribute", "data-zui-inert-tabindex").String(); prior != "" {
+			f.Call("setAttribute", "tabindex", prior)
+		} else {
+			f.Call("removeAttribute", "tabindex")
+		}
+		f.Call("removeAttribute", "data-zui-inert-tabindex")
+	}
+}
+
+// inertFocusGuard reverts the focus moving into an inert subtree, e.g. on a click or programmatically.
+func inertFocusGuard(d *Document) {
+	if _, ok := inertGuards.Get(d.AsElement()); ok {
+		return
+	}
+	inertGuards.Set(d.AsElement(), true)
+	d.Window().AsElement().AddEventListener("focusin", ui.NewEventHandler(func(evt ui.Event) bool {
+		t := evt.Native().(NativeEvent).Value.Get("target")
+		if !t.Truthy() || t.Get("closest").IsUndefined() {
+			return false
+		}
+		if t.Call("closest", "[inert]").Truthy() {
+			t.Call("blur")
+		}
+		return false
+	}).ForCapture())
+}
+
+// InputModality returns the modality of the last user input in the document: "keyboard", "pointer", or ""
+// if there was none yet, or if no element of the document uses Modifier.FocusVisible.
+func InputModality(d *Document) string {
+	m, _ := inputModalities.Get(d.AsElement())
+	return m
+}
+
+// FocusVisible returns an element modifier giving the zui-focus-visible class to the element while its
+// focus should be visible.
+func (m modifier) FocusVisible() func(e *ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		if !InBrowser() {
+			return e
+		}
+		d := GetDocument(e)
+		trackInputModality(d)
+
+		e.AddEventListener("focus", ui.NewEventHandler(func(evt ui.Event) bool {
+			n, ok := JSValue(e)
+			if !ok {
+				return false
+			}
+			if InputModality(d) != "pointer" || takesTextInput(n) {
+				n.Get("classList").Call("add", "zui-focus-visible")
+			}
+			return false
+		}))
+		e.AddEventListener("blur", ui.NewEventHandler(func(evt ui.Event) bool {
+			if n, ok := JSValue(e); ok {
+				n.Get("classList").Call("remove", "zui-focus-visible")
+			}
+			return false
+		}))
+		return e
+	}
+}
+
+func takesTextInput(n js.Value) bool {
+	if n.Get("isContentEditable").Truthy() {
+		return true
+	}
+	switch n.Get("tagName").String() {
+	case "TEXTAREA":
+		return !n.Get("readOnly").Bool()
+	case "INPUT":
+		switch n.Get("type").String() {
+		case "button", "submit", "reset", "checkbox", "radio", "range", "color", "file", "image", "hidden":
+			return false
+		}
+		return !n.Get("readOnly").Bool()
+	}
+	return false
+}
+
+func trackInputModality(d *Document) {
+	if _, ok := inputModalities.Get(d.AsElement()); ok {
+		return
+	}
+	inputModalities.Set(d.AsElement(), "")
+	a11yStyleSheet(d)
+
+	w := d.Window().AsElement()
+	w.AddEventListener("keydown", ui.NewEventHandler(func(evt ui.Event) bool {
+		e := evt.Native().(NativeEvent).Value
+		if e.Get("metaKey").Bool() || e.Get("altKey").Bool() || e.Get("ctrlKey").Bool() {
+			return false
+		}
+		inputModalities.Set(d.AsElement(), "keyboard")
+		return false
+	}).ForCapture())
+	for _, typ := range []string{"pointerdown", "mousedown", "touchstart"} {
+		w.AddEventListener(typ, ui.NewEventHandler(func(evt ui.Event) bool {
+			inputModalities.Set(d.AsElement(), "pointer")
+			return false
+		}).ForCapture())
+	}
+}
+
+func a11yStyleSheet(d *Document) {
+	if _, ok := d.GetStyleSheet(A11yStyleSheetID); ok {
+		return
+	}
+	sheet := d.NewStyleSheet(A11yStyleSheetID)
+	actives := append([]string{A11yStyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+
+	sheet.InsertRule("[inert], [inert] *", "pointer-events: none; user-select: none; cursor: default;")
+	sheet.InsertRule(":where(.zui-focus-visible)", "outline: 2px solid Highlight; outline-offset: 2px;")
+	sheet.Update()
+}
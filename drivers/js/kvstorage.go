@@ -0,0 +1,299 @@
+package doc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Storage backends
+//
+// Document.Storage returns a key/value store with the same API regardless of the backend: sessionStorage,
+// localStorage, IndexedDB or an in-memory map. Keys are namespaced by the id of the document, so that several
+// apps served from the same origin do not overwrite each other's entries. Values are stored as bytes;
+// GetValue and SetValue store ui.Values, encoded as by the persistence modes.
+//
+// The methods take a context since the IndexedDB backend is asynchronous: its methods block until the
+// request completes and, as such, must not be called from the UI thread (use ui.DoAsync).
+// Outside of the browser, every kind of storage is backed by memory.
+//
+// SetStorage replaces the backend of a kind of storage for a document, e.g. with a MemoryStorage in tests.
+
+// StorageKind identifies a storage backend.
+type StorageKind string
+
+const (
+	SessionStorage   StorageKind = "session"
+	LocalStorage     StorageKind = "local"
+	IndexedDBStorage StorageKind = "indexeddb"
+	MemoryStorage    StorageKind = "memory"
+)
+
+// Storage is a key/value store.
+type Storage interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte) error
+	Delete(ctx context.Context, key string) error
+	Keys(ctx context.Context) ([]string, error)
+}
+
+// ErrStorageUnavailable is returned when a storage backend cannot be reached.
+var ErrStorageUnavailable = errors.New("storage backend is unavailable")
+
+var storages = newscsmap[*ui.Element, map[StorageKind]Storage]()
+
+// Storage returns the store of the given kind for the document.
+func (d *Document) Storage(kind StorageKind) Storage {
+	m, ok := storages.Get(d.AsElement())
+	if !ok {
+		m = make(map[StorageKind]Storage)
+		storages.Set(d.AsElement(), m)
+	}
+	if s, ok := m[kind]; ok {
+		return s
+	}
+
+	var s Storage
+	namespace := d.AsElement().ID
+	switch {
+	case kind == MemoryStorage || !InBrowser():
+		s = NewMemoryStorage()
+	case kind == SessionStorage:
+		s = webStorage{jsStore{js.Global().Get("sessionStorage")}, namespace + "/"}
+	case kind == LocalStorage:
+		s = webStorage{jsStore{js.Global().Get("localStorage")}, namespace + "/"}
+	case kind == IndexedDBStorage:
+		s = &indexedDBStorage{name: "zui-" + namespace}
+	default:
+		panic("unknown storage kind: " + string(kind))
+	}
+	m[kind] = s
+	return s
+}
+
+// SetStorage replaces the store of the given kind for the document.
+func (d *Document) SetStorage(kind StorageKind, s Storage) *Document {
+	m, ok := storages.Get(d.AsElement())
+	if !ok {
+		m = make(map[StorageKind]Storage)
+		storages.Set(d.AsElement(), m)
+	}
+	m[kind] = s
+	return d
+}
+
+// GetValue retrieves a ui.Value stored with SetValue.
+func GetValue(ctx context.Context, s Storage, key string) (ui.Value, bool, error) {
+	b, ok, err := s.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	v, err := ui.DecodeText(string(b))
+	if err != nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// SetValue stores a ui.Value.
+func SetValue(ctx context.Context, s Storage, key string, v ui.Value) error {
+	txt, err := ui.EncodeText(ui.PersistenceCodec, v)
+	if err != nil {
+		return err
+	}
+	return s.Set(ctx, key, []byte(txt))
+}
+
+// memoryStorage is a Storage backed by a map.
+type memoryStorage struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+// NewMemoryStorage returns an empty in-memory Storage.
+func NewMemoryStorage() Storage {
+	return &memoryStorage{entries: make(map[string][]byte)}
+}
+
+func (m *memoryStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.entries[key]
+	return append([]byte(nil), v...), ok, nil
+}
+
+func (m *memoryStorage) Set(ctx context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *memoryStorage) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+func (m *memoryStorage) Keys(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.entries))
+	for k := range m.entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// webStorage is a Storage backed by sessionStorage or localStorage. Values are base64 encoded.
+type webStorage struct {
+	store  jsStore
+	prefix string
+}
+
+func (w webStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v := w.store.store.Call("getItem", w.prefix+key)
+	if v.IsNull() {
+		return nil, false, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(v.String())
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+func (w webStorage) Set(ctx context.Context, key string, value []byte) error {
+	w.store.store.Call("setItem", w.prefix+key, base64.StdEncoding.EncodeToString(value))
+	return nil
+}
+
+func (w webStorage) Delete(ctx context.Context, key string) error {
+	w.store.Delete(w.prefix + key)
+	return nil
+}
+
+func (w webStorage) Keys(ctx context.Context) ([]string, error) {
+	var keys []string
+	n := w.store.store.Get("length").Int()
+	for i := 0; i < n; i++ {
+		k := w.store.store.Call("key", i).String()
+		if strings.HasPrefix(k, w.prefix) {
+			keys = append(keys, strings.TrimPrefix(k, w.prefix))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// indexedDBStorage is a Storage backed by an IndexedDB object store. The database is opened lazily.
+type indexedDBStorage struct {
+	mu   sync.Mutex
+	name string
+	db   js.Value
+}
+
+const indexedDBObjectStore = "kv"
+
+func (s *indexedDBStorage) open(ctx context.Context) (js.Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db.Truthy() {
+		return s.db, nil
+	}
+	idb := js.Global().Get("indexedDB")
+	if !idb.Truthy() {
+		return js.Undefined(), ErrStorageUnavailable
+	}
+	req := idb.Call("open", s.name, 1)
+	upgrade := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		req.Get("result").Call("createObjectStore", indexedDBObjectStore)
+		return nil
+	})
+	defer upgrade.Release()
+	req.Set("onupgradeneeded", upgrade)
+	db, err := awaitIDBRequest(ctx, req)
+	if err != nil {
+		return js.Undefined(), err
+	}
+	s.db = db
+	return db, nil
+}
+
+func (s *indexedDBStorage) request(ctx context.Context, mode string, fn func(store js.Value) js.Value) (js.Value, error) {
+	db, err := s.open(ctx)
+	if err != nil {
+		return js.Undefined(), err
+	}
+	store := db.Call("transaction", indexedDBObjectStore, mode).Call("objectStore", indexedDBObjectStore)
+	return awaitIDBRequest(ctx, fn(store))
+}
+
+func (s *indexedDBStorage) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, err := s.request(ctx, "readonly", func(store js.Value) js.Value { return store.Call("get", key) })
+	if err != nil {
+		return nil, false, err
+	}
+	if v.IsUndefined() {
+		return nil, false, nil
+	}
+	return goBytes(v), true, nil
+}
+
+func (s *indexedDBStorage) Set(ctx context.Context, key string, value []byte) error {
+	_, err := s.request(ctx, "readwrite", func(store js.Value) js.Value { return store.Call("put", jsBytes(value), key) })
+	return err
+}
+
+func (s *indexedDBStorage) Delete(ctx context.Context, key string) error {
+	_, err := s.request(ctx, "readwrite", func(store js.Value) js.Value { return store.Call("delete", key) })
+	return err
+}
+
+func (s *indexedDBStorage) Keys(ctx context.Context) ([]string, error) {
+	v, err := s.request(ctx, "readonly", func(store js.Value) js.Value { return store.Call("getAllKeys") })
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, v.Length())
+	for i := 0; i < v.Length(); i++ {
+		keys = append(keys, v.Index(i).String())
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// awaitIDBRequest waits for the completion of an IndexedDB request. It should not be called from the UI thread.
+func awaitIDBRequest(ctx context.Context, req js.Value) (js.Value, error) {
+	res := make(chan js.Value, 1)
+	errc := make(chan error, 1)
+	onsuccess := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		res <- req.Get("result")
+		return nil
+	})
+	defer onsuccess.Release()
+	onerror := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		errc <- js.Error{Value: req.Get("error")}
+		return nil
+	})
+	defer onerror.Release()
+	req.Set("onsuccess", onsuccess)
+	req.Set("onerror", onerror)
+
+	select {
+	case v := <-res:
+		return v, nil
+	case err := <-errc:
+		return js.Undefined(), err
+	case <-ctx.Done():
+		return js.Undefined(), ctx.Err()
+	}
+}
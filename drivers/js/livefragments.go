@@ -0,0 +1,273 @@
+package doc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Server-driven partial updates
+//
+// Regions of a document may be rendered live by the server (dashboards, admin feeds...) while the rest of the
+// document stays client-reactive. The server pushes fragments of HTML, each targeting an element by id, over
+// Server-Sent Events (as "fragment" events) or over a WebSocket. The messages are JSON encoded LiveFragments.
+//
+// A fragment is reconciled with the current subtree of its target instead of replacing it wholesale:
+// the elements whose id matches the id of a sibling node of the fragment are kept, so that their state,
+// watchers and event listeners survive the update, and only their attributes, classes, text and children are
+// updated. The other elements are imported (see ParseHTML) and the elements that are no longer part of the
+// fragment are deleted.
+//
+// A "fragment-applied" event is triggered on the target element once a fragment has been reconciled.
+
+// LiveFragment modes.
+const (
+	FragmentReplace = "replace" // the fragment becomes the content of the target
+	FragmentAppend  = "append"  // the fragment is appended to the content of the target
+	FragmentPrepend = "prepend" // the fragment is prepended to the content of the target
+)
+
+// LiveFragment is a piece of server rendered HTML targeting an element of the document.
+type LiveFragment struct {
+	Target string `json:"target"`
+	HTML   string `json:"html"`
+	Mode   string `json:"mode,omitempty"`
+}
+
+// ErrFragmentTarget is returned when the target of a fragment does not exist in the document.
+var ErrFragmentTarget = errors.New("fragment target not found")
+
+// ApplyFragment reconciles a fragment with the subtree of its target.
+func (d *Document) ApplyFragment(f LiveFragment) error {
+	target := d.GetElementById(f.Target)
+	if target == nil {
+		return fmt.Errorf("%w: %s", ErrFragmentTarget, f.Target)
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(f.HTML), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return err
+	}
+	// A fragment holding the target itself is reconciled with it.
+	if len(nodes) == 1 && nodes[0].Type == html.ElementNode && nodeID(nodes[0]) == target.ID && f.Mode != FragmentAppend && f.Mode != FragmentPrepend {
+		err = d.reconcileElement(target, nodes[0])
+	} else {
+		err = d.reconcileChildren(target, nodes, f.Mode)
+	}
+	if err != nil {
+		return err
+	}
+	target.TriggerEvent("fragment-applied", ui.String(f.Mode))
+	return nil
+}
+
+// OnFragmentApplied registers a handler called each time a fragment has been reconciled with the element.
+func OnFragmentApplied(e *ui.Element, h *ui.MutationHandler) {
+	e.WatchEvent("fragment-applied", e, h)
+}
+
+// SubscribeFragments connects the document to a stream of fragments pushed by the server.
+// URLs with the ws or wss scheme are opened as WebSockets, other URLs as Server-Sent Events sources.
+// It returns a function that closes the connection. It does nothing outside of the browser.
+func (d *Document) SubscribeFragments(url string, onerror func(error)) (unsubscribe func()) {
+	if !InBrowser() {
+		return func() {}
+	}
+	if onerror == nil {
+		onerror = func(err error) { DEBUG(err) }
+	}
+
+	onmessage := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		var f LiveFragment
+		if err := json.Unmarshal([]byte(args[0].Get("data").String()), &f); err != nil {
+			onerror(err)
+			return nil
+		}
+		go ui.DoSync(func() {
+			if err := d.ApplyFragment(f); err != nil {
+				onerror(err)
+			}
+		})
+		return nil
+	})
+
+	var conn js.Value
+	if strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://") {
+		conn = js.Global().Get("WebSocket").New(url)
+		conn.Call("addEventListener", "message", onmessage)
+	} else {
+		conn = js.Global().Get("EventSource").New(url)
+		conn.Call("addEventListener", "fragment", onmessage)
+	}
+
+	var closed bool
+	return func() {
+		if closed {
+			return
+		}
+		closed = true
+		conn.Call("close")
+		onmessage.Release()
+	}
+}
+
+// reconcileElement updates an element so that it matches a node.
+func (d *Document) reconcileElement(e *ui.Element, n *html.Node) error {
+	if nodeTag(e, n.Data) != n.Data {
+		return fmt.Errorf("cannot reconcile %s with a %s element", e.ID, n.Data)
+	}
+
+	attrs := make(map[string]string)
+	var classes []string
+	for _, a := range n.Attr {
+		switch {
+		case a.Namespace != "", a.Key == "id", strings.HasPrefix(a.Key, "on"):
+			continue
+		case a.Key == "class":
+			classes = strings.Fields(a.Val)
+		default:
+			attrs[a.Key] = a.Val
+		}
+	}
+	if m, ok := e.Get(Namespace.Data, "attrs"); ok {
+		m.(ui.Object).Range(func(key string, val ui.Value) bool {
+			if _, ok := attrs[key]; !ok {
+				RemoveAttribute(e, key)
+			}
+			return false
+		})
+	}
+	for k, v := range attrs {
+		if m, ok := e.Get(Namespace.Data, "attrs"); ok {
+			if old, ok := m.(ui.Object).Get(k); ok && string(old.(ui.String)) == v {
+				continue
+			}
+		}
+		SetAttribute(e, k, v)
+	}
+	if strings.Join(Classes(e), " ") != strings.Join(classes, " ") {
+		e.Set("css", "class", ui.String(strings.Join(classes, " ")))
+	}
+
+	var nodes []*html.Node
+	haselements := false
+	var text strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+		switch c.Type {
+		case html.ElementNode:
+			haselements = true
+		case html.TextNode:
+			text.WriteString(c.Data)
+		}
+	}
+	if !haselements {
+		if e.Children != nil && len(e.Children.List) > 0 {
+			e.DeleteChildren()
+		}
+		if t, ok := e.GetData("text"); !ok || string(t.(ui.String)) != text.String() {
+			e.SetDataSetUI("text", ui.String(text.String()))
+		}
+		return nil
+	}
+	return d.reconcileChildren(e, nodes, FragmentReplace)
+}
+
+// reconcileChildren updates the children of an element so that they match a list of sibling nodes.
+func (d *Document) reconcileChildren(e *ui.Element, nodes []*html.Node, mode string) error {
+	existing := make(map[string]*ui.Element)
+	var current []*ui.Element
+	if e.Children != nil {
+		current = append(current, e.Children.List...)
+		for _, c := range current {
+			existing[c.ID] = c
+		}
+	}
+
+	var imported []*ui.Element
+	kept := make(map[string]bool)
+	for _, n := range nodes {
+		switch n.Type {
+		case html.ElementNode:
+			if c, ok := existing[nodeID(n)]; ok && !kept[c.ID] {
+				if err := d.reconcileElement(c, n); err != nil {
+					return err
+				}
+				kept[c.ID] = true
+				imported = append(imported, c)
+				continue
+			}
+			c, err := d.importElement(n)
+			if err != nil {
+				return err
+			}
+			imported = append(imported, c)
+		case html.TextNode:
+			if isIndentation(n.Data) {
+				continue
+			}
+			span, err := d.importElement(&html.Node{Type: html.ElementNode, Data: "span", DataAtom: atom.Span})
+			if err != nil {
+				return err
+			}
+			span.SetDataSetUI("text", ui.String(n.Data))
+			imported = append(imported, span)
+		}
+	}
+
+	var children []*ui.Element
+	switch mode {
+	case FragmentAppend:
+		for _, c := range current {
+			if !kept[c.ID] {
+				children = append(children, c)
+			}
+		}
+		children = append(children, imported...)
+	case FragmentPrepend:
+		children = append(children, imported...)
+		for _, c := range current {
+			if !kept[c.ID] {
+				children = append(children, c)
+			}
+		}
+	default:
+		children = imported
+		defer func() {
+			for _, c := range current {
+				if !kept[c.ID] {
+					ui.Delete(c)
+				}
+			}
+		}()
+	}
+	e.SetChildren(children...)
+	return nil
+}
+
+func nodeID(n *html.Node) string {
+	for _, a := range n.Attr {
+		if a.Key == "id" && a.Namespace == "" {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeTag returns the tag name of an element, or def if it cannot be determined.
+func nodeTag(e *ui.Element, def string) string {
+	n, ok := JSValue(e)
+	if !ok {
+		return def
+	}
+	tag := n.Get("tagName")
+	if !tag.Truthy() {
+		return def
+	}
+	return strings.ToLower(tag.String())
+}
@@ -0,0 +1,35 @@
+//go:build server
+
+package doc
+
+import (
+	"bytes"
+	"encoding/json"
+
+	ui "github.com/atdiar/particleui"
+	"golang.org/x/net/html"
+)
+
+// RenderFragment renders an element of a server-side document as a fragment replacing the element of the same
+// id in the subscribed documents.
+func RenderFragment(e *ui.Element) (LiveFragment, error) {
+	n, ok := JSValue(e)
+	if !ok {
+		return LiveFragment{}, ErrFragmentTarget
+	}
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n.Node()); err != nil {
+		return LiveFragment{}, err
+	}
+	return LiveFragment{Target: e.ID, HTML: buf.String(), Mode: FragmentReplace}, nil
+}
+
+// SendFragment pushes a fragment to the client as a "fragment" event (see Document.SubscribeFragments).
+func (s *SSEController) SendFragment(f LiveFragment) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	s.SendEvent("fragment", string(b), "", "")
+	return nil
+}
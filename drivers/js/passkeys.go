@@ -0,0 +1,261 @@
+package doc
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Passkeys
+//
+// The Passkeys service wraps the WebAuthn API (navigator.credentials) to register and use passkeys.
+// Challenges, ids and binary responses are exchanged as base64url strings so that they can be sent to the
+// server as is for verification. The server remains responsible for generating challenges and verifying
+// attestations and assertions.
+// As with the Crypto service, the methods block until the browser prompt is settled and should be called
+// from a goroutine, typically within ui.DoAsync.
+
+var (
+	ErrPasskeysUnsupported = errors.New("passkeys are not supported")
+	ErrPasskeyCancelled    = errors.New("passkey operation was cancelled or timed out")
+	ErrPasskeyExists       = errors.New("passkey already registered for this authenticator")
+	ErrPasskeyInsecure     = errors.New("passkey operation is not allowed in this context")
+)
+
+// PasskeyUser identifies the account a passkey is registered for.
+type PasskeyUser struct {
+	ID          string // base64url encoded user handle
+	Name        string
+	DisplayName string
+}
+
+// PasskeyRegistrationOptions holds the parameters of a passkey registration, as provided by the server.
+type PasskeyRegistrationOptions struct {
+	Challenge string // base64url encoded
+	RPID      string
+	RPName    string
+	User      PasskeyUser
+	// ExcludeCredentials lists the base64url ids of the credentials already registered for the user.
+	ExcludeCredentials []string
+	// Algorithms lists the COSE identifiers of the accepted public key algorithms. Defaults to ES256 and RS256.
+	Algorithms       []int
+	UserVerification string // "required", "preferred" (default) or "discouraged"
+	Attestation      string // "none" (default), "indirect", "direct" or "enterprise"
+	TimeoutMillis    int
+}
+
+// PasskeyAuthenticationOptions holds the parameters of a passkey authentication, as provided by the server.
+type PasskeyAuthenticationOptions struct {
+	Challenge string // base64url encoded
+	RPID      string
+	// AllowCredentials lists the base64url ids of the acceptable credentials. If empty, the user chooses
+	// among the discoverable credentials of the relying party.
+	AllowCredentials []string
+	UserVerification string
+	TimeoutMillis    int
+	// Conditional enables conditional mediation (autofill UI), when supported.
+	Conditional bool
+}
+
+// PasskeyRegistration is the result of a registration, to be verified by the server.
+type PasskeyRegistration struct {
+	ID                string   `json:"id"`
+	Type              string   `json:"type"`
+	ClientDataJSON    string   `json:"clientDataJSON"`
+	AttestationObject string   `json:"attestationObject"`
+	Transports        []string `json:"transports,omitempty"`
+}
+
+// PasskeyAssertion is the result of an authentication, to be verified by the server.
+type PasskeyAssertion struct {
+	ID                string `json:"id"`
+	Type              string `json:"type"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"userHandle,omitempty"`
+}
+
+// Passkeys is the document service handling WebAuthn flows.
+type Passkeys struct {
+	d *Document
+}
+
+// Passkeys returns the passkeys service of the document.
+func (d *Document) Passkeys() Passkeys {
+	return Passkeys{d}
+}
+
+// Supported returns whether the browser supports WebAuthn.
+func (p Passkeys) Supported() bool {
+	return InBrowser() && js.Global().Get("PublicKeyCredential").Truthy()
+}
+
+// Register creates a new passkey for the user.
+func (p Passkeys) Register(ctx context.Context, opts PasskeyRegistrationOptions) (PasskeyRegistration, error) {
+	var res PasskeyRegistration
+	if !p.Supported() {
+		return res, ErrPasskeysUnsupported
+	}
+	challenge, err := base64urlBytes(opts.Challenge)
+	if err != nil {
+		return res, fmt.Errorf("invalid challenge: %w", err)
+	}
+	userid, err := base64urlBytes(opts.User.ID)
+	if err != nil {
+		return res, fmt.Errorf("invalid user id: %w", err)
+	}
+	algs := opts.Algorithms
+	if len(algs) == 0 {
+		algs = []int{-7, -257}
+	}
+	params := make([]interface{}, 0, len(algs))
+	for _, alg := range algs {
+		params = append(params, map[string]interface{}{"type": "public-key", "alg": alg})
+	}
+	rp := map[string]interface{}{"name": opts.RPName}
+	if opts.RPID != "" {
+		rp["id"] = opts.RPID
+	}
+	publicKey := map[string]interface{}{
+		"challenge": jsBytes(challenge),
+		"rp":        rp,
+		"user": map[string]interface{}{
+			"id":          jsBytes(userid),
+			"name":        opts.User.Name,
+			"displayName": opts.User.DisplayName,
+		},
+		"pubKeyCredParams": params,
+		"authenticatorSelection": map[string]interface{}{
+			"residentKey":      "required",
+			"userVerification": orDefault(opts.UserVerification, "preferred"),
+		},
+		"attestation": orDefault(opts.Attestation, "none"),
+	}
+	if opts.TimeoutMillis > 0 {
+		publicKey["timeout"] = opts.TimeoutMillis
+	}
+	if exclude, err := credentialDescriptors(opts.ExcludeCredentials); err != nil {
+		return res, err
+	} else if len(exclude) > 0 {
+		publicKey["excludeCredentials"] = exclude
+	}
+
+	cred, err := awaitPromise(ctx, js.Global().Get("navigator").Get("credentials").Call("create", map[string]interface{}{"publicKey": publicKey}))
+	if err != nil {
+		return res, passkeyError(err)
+	}
+	if !cred.Truthy() {
+		return res, ErrPasskeyCancelled
+	}
+	response := cred.Get("response")
+	res.ID = cred.Get("id").String()
+	res.Type = cred.Get("type").String()
+	res.ClientDataJSON = base64urlString(response.Get("clientDataJSON"))
+	res.AttestationObject = base64urlString(response.Get("attestationObject"))
+	if response.Get("getTransports").Truthy() {
+		t := response.Call("getTransports")
+		for i := 0; i < t.Length(); i++ {
+			res.Transports = append(res.Transports, t.Index(i).String())
+		}
+	}
+	return res, nil
+}
+
+// Authenticate requests an assertion from one of the passkeys of the user.
+func (p Passkeys) Authenticate(ctx context.Context, opts PasskeyAuthenticationOptions) (PasskeyAssertion, error) {
+	var res PasskeyAssertion
+	if !p.Supported() {
+		return res, ErrPasskeysUnsupported
+	}
+	challenge, err := base64urlBytes(opts.Challenge)
+	if err != nil {
+		return res, fmt.Errorf("invalid challenge: %w", err)
+	}
+	publicKey := map[string]interface{}{
+		"challenge":        jsBytes(challenge),
+		"userVerification": orDefault(opts.UserVerification, "preferred"),
+	}
+	if opts.RPID != "" {
+		publicKey["rpId"] = opts.RPID
+	}
+	if opts.TimeoutMillis > 0 {
+		publicKey["timeout"] = opts.TimeoutMillis
+	}
+	if allow, err := credentialDescriptors(opts.AllowCredentials); err != nil {
+		return res, err
+	} else if len(allow) > 0 {
+		publicKey["allowCredentials"] = allow
+	}
+	req := map[string]interface{}{"publicKey": publicKey}
+	if opts.Conditional {
+		req["mediation"] = "conditional"
+	}
+
+	cred, err := awaitPromise(ctx, js.Global().Get("navigator").Get("credentials").Call("get", req))
+	if err != nil {
+		return res, passkeyError(err)
+	}
+	if !cred.Truthy() {
+		return res, ErrPasskeyCancelled
+	}
+	response := cred.Get("response")
+	res.ID = cred.Get("id").String()
+	res.Type = cred.Get("type").String()
+	res.ClientDataJSON = base64urlString(response.Get("clientDataJSON"))
+	res.AuthenticatorData = base64urlString(response.Get("authenticatorData"))
+	res.Signature = base64urlString(response.Get("signature"))
+	if uh := response.Get("userHandle"); uh.Truthy() {
+		res.UserHandle = base64urlString(uh)
+	}
+	return res, nil
+}
+
+func credentialDescriptors(ids []string) ([]interface{}, error) {
+	res := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		b, err := base64urlBytes(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential id %q: %w", id, err)
+		}
+		res = append(res, map[string]interface{}{"type": "public-key", "id": jsBytes(b)})
+	}
+	return res, nil
+}
+
+// passkeyError maps the DOMException names raised by the WebAuthn API to the package errors.
+func passkeyError(err error) error {
+	var jserr js.Error
+	if !errors.As(err, &jserr) {
+		return err
+	}
+	switch jserr.Get("name").String() {
+	case "NotAllowedError", "AbortError":
+		return ErrPasskeyCancelled
+	case "InvalidStateError":
+		return ErrPasskeyExists
+	case "SecurityError":
+		return ErrPasskeyInsecure
+	case "NotSupportedError":
+		return ErrPasskeysUnsupported
+	}
+	return err
+}
+
+func base64urlBytes(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func base64urlString(buf js.Value) string {
+	return base64.RawURLEncoding.EncodeToString(goBytes(js.Global().Get("Uint8Array").New(buf)))
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
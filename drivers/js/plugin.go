@@ -0,0 +1,198 @@
+package doc
+
+import (
+	"errors"
+	"fmt"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// Plugins
+//
+// A Plugin packages a Document-level extension (analytics, auth, theming, devtools overlay...) so that
+// it can be installed uniformly via document.Use.
+// Plugins may declare dependencies on other plugins by name: they are installed after them.
+// Each plugin has its own configuration namespace, stored in the "plugins" property category of the document
+// under the plugin name, which can be watched for changes.
+// When the document is deleted, the plugins implementing PluginUninstaller are uninstalled in the reverse
+// order of their installation.
+
+var (
+	ErrPluginMissingDependency = errors.New("plugin dependency is missing")
+	ErrPluginCyclicDependency  = errors.New("plugin dependencies are cyclic")
+	ErrPluginAlreadyInstalled  = errors.New("plugin is already installed")
+	ErrPluginDuplicateName     = errors.New("plugin name is provided more than once")
+)
+
+// Plugin defines a Document extension.
+type Plugin interface {
+	Name() string
+	Install(d *Document) error
+}
+
+// PluginUninstaller is implemented by plugins that need to release resources when the document is deleted
+// or when they are explicitly removed.
+type PluginUninstaller interface {
+	Uninstall(d *Document) error
+}
+
+// PluginDependent is implemented by plugins that require other plugins to be installed first.
+type PluginDependent interface {
+	Requires() []string
+}
+
+type pluginRegistry struct {
+	installed map[string]Plugin
+	order     []string
+}
+
+// plugins holds the plugin registry of each document, by document root.
+var plugins = newscsmap[*ui.Element, *pluginRegistry]()
+
+// Use installs plugins on the document. The plugins are installed in the order they are provided,
+// except that dependencies are always installed before their dependents.
+// Dependencies may be either already installed or part of the same call.
+// Either all the plugins are installed or none: if one of them fails to install, the ones that were
+// installed by the same call are uninstalled.
+func (d *Document) Use(ps ...Plugin) error {
+	r, ok := plugins.Get(d.AsElement())
+	if !ok {
+		r = &pluginRegistry{make(map[string]Plugin), nil}
+		plugins.Set(d.AsElement(), r)
+		d.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			for i := len(r.order) - 1; i >= 0; i-- {
+				if err := d.RemovePlugin(r.order[i]); err != nil {
+					DEBUG(err)
+				}
+			}
+			plugins.Delete(evt.Origin())
+			return false
+		}).RunOnce())
+	}
+
+	pending := make(map[string]Plugin, len(ps))
+	for _, p := range ps {
+		if _, ok := r.installed[p.Name()]; ok {
+			return fmt.Errorf("%w: %s", ErrPluginAlreadyInstalled, p.Name())
+		}
+		if _, ok := pending[p.Name()]; ok {
+			return fmt.Errorf("%w: %s", ErrPluginDuplicateName, p.Name())
+		}
+		pending[p.Name()] = p
+	}
+
+	// the installation order is resolved first so that missing or cyclic dependencies are reported
+	// before any plugin is installed.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(ps))
+	order := make([]Plugin, 0, len(ps))
+	var resolve func(p Plugin) error
+	resolve = func(p Plugin) error {
+		switch state[p.Name()] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrPluginCyclicDependency, p.Name())
+		}
+		state[p.Name()] = visiting
+
+		if dp, ok := p.(PluginDependent); ok {
+			for _, dep := range dp.Requires() {
+				if _, ok := r.installed[dep]; ok {
+					continue
+				}
+				q, ok := pending[dep]
+				if !ok {
+					return fmt.Errorf("%w: %s requires %s", ErrPluginMissingDependency, p.Name(), dep)
+				}
+				if err := resolve(q); err != nil {
+					return err
+				}
+			}
+		}
+		state[p.Name()] = visited
+		order = append(order, p)
+		return nil
+	}
+	for _, p := range ps {
+		if err := resolve(p); err != nil {
+			return err
+		}
+	}
+
+	for i, p := range order {
+		if err := p.Install(d); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				if rerr := d.RemovePlugin(order[j].Name()); rerr != nil {
+					DEBUG(rerr)
+				}
+			}
+			return fmt.Errorf("unable to install plugin %s: %w", p.Name(), err)
+		}
+		r.installed[p.Name()] = p
+		r.order = append(r.order, p.Name())
+		d.TriggerEvent("plugin-installed", ui.String(p.Name()))
+	}
+	return nil
+}
+
+// GetPlugin returns the installed plugin registered under the given name.
+func (d *Document) GetPlugin(name string) (Plugin, bool) {
+	r, ok := plugins.Get(d.AsElement())
+	if !ok {
+		return nil, false
+	}
+	p, ok := r.installed[name]
+	return p, ok
+}
+
+// RemovePlugin uninstalls a plugin, if it is installed.
+func (d *Document) RemovePlugin(name string) error {
+	r, ok := plugins.Get(d.AsElement())
+	if !ok {
+		return nil
+	}
+	p, ok := r.installed[name]
+	if !ok {
+		return nil
+	}
+	if u, ok := p.(PluginUninstaller); ok {
+		if err := u.Uninstall(d); err != nil {
+			return err
+		}
+	}
+	delete(r.installed, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	d.TriggerEvent("plugin-uninstalled", ui.String(name))
+	return nil
+}
+
+// ConfigurePlugin sets the configuration of a plugin. It may be done before or after the plugin is installed.
+func (d *Document) ConfigurePlugin(name string, config ui.Object) *Document {
+	d.AsElement().Set("plugins", name, config)
+	return d
+}
+
+// PluginConfig returns the configuration of a plugin. The returned object is empty if no configuration
+// was provided.
+func (d *Document) PluginConfig(name string) ui.Object {
+	v, ok := d.AsElement().Get("plugins", name)
+	if !ok {
+		return ui.NewObject().Commit()
+	}
+	return v.(ui.Object)
+}
+
+// OnPluginConfigChange registers a handler called when the configuration of a plugin changes.
+func (d *Document) OnPluginConfigChange(name string, h *ui.MutationHandler) {
+	d.AsElement().Watch("plugins", name, d, h)
+}
@@ -0,0 +1,70 @@
+package doc
+
+import (
+	"errors"
+	"testing"
+)
+
+type testPlugin struct {
+	name     string
+	requires []string
+	err      error
+	log      *[]string
+}
+
+func (p testPlugin) Name() string       { return p.name }
+func (p testPlugin) Requires() []string { return p.requires }
+
+func (p testPlugin) Install(d *Document) error {
+	if p.err != nil {
+		return p.err
+	}
+	*p.log = append(*p.log, "install "+p.name)
+	return nil
+}
+
+func (p testPlugin) Uninstall(d *Document) error {
+	*p.log = append(*p.log, "uninstall "+p.name)
+	return nil
+}
+
+func TestUse(t *testing.T) {
+	d := newTestDocument(t, "plugintest")
+	var log []string
+
+	err := d.Use(testPlugin{name: "a", log: &log}, testPlugin{name: "a", log: &log})
+	if !errors.Is(err, ErrPluginDuplicateName) {
+		t.Fatalf("expected a duplicate name error, got %v", err)
+	}
+	if len(log) != 0 {
+		t.Fatalf("no plugin should have been installed, got %v", log)
+	}
+
+	err = d.Use(testPlugin{name: "b", requires: []string{"missing"}, log: &log}, testPlugin{name: "c", log: &log})
+	if !errors.Is(err, ErrPluginMissingDependency) || len(log) != 0 {
+		t.Fatalf("expected a missing dependency error before any installation, got %v and %v", err, log)
+	}
+
+	failure := errors.New("failure")
+	err = d.Use(
+		testPlugin{name: "dependent", requires: []string{"dependency"}, err: failure, log: &log},
+		testPlugin{name: "dependency", log: &log},
+	)
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the installation error, got %v", err)
+	}
+	if len(log) != 2 || log[0] != "install dependency" || log[1] != "uninstall dependency" {
+		t.Fatalf("expected the dependency to be rolled back, got %v", log)
+	}
+	if _, ok := d.GetPlugin("dependency"); ok {
+		t.Fatal("the rolled back plugin should not be installed anymore")
+	}
+
+	if err := d.Use(testPlugin{name: "dependency", log: &log}); err != nil {
+		t.Fatal(err)
+	}
+	copied := *d
+	if _, ok := copied.GetPlugin("dependency"); !ok {
+		t.Fatal("the plugins should be shared by copies of the document")
+	}
+}
@@ -0,0 +1,149 @@
+package doc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// Polling
+//
+// Poll runs a fetch function periodically for as long as an element is mounted.
+// Polling is paused while the document is hidden and resumes with an immediate refresh when the document
+// becomes visible again, unless the element follows a PauseWhenHidden policy without resumption, or when the
+// window regains focus.
+// On consecutive failures, the interval doubles up to MaxPollBackoff times the base interval. It is reset
+// after the first success. Each failure triggers a "poll-error" event on the element, whose value is the error
+// message.
+
+// MaxPollBackoff is the maximum factor by which the polling interval is multiplied after consecutive failures.
+var MaxPollBackoff = 32
+
+type poller struct {
+	mu       sync.Mutex
+	e        *ui.Element
+	interval time.Duration
+	fetch    func(context.Context) error
+	timer    *time.Timer
+	failures int
+	inflight bool
+	stopped  bool
+}
+
+// Poll starts polling on behalf of the element. The returned function stops polling.
+func Poll(e *ui.Element, interval time.Duration, fetch func(ctx context.Context) error) (stop func()) {
+	p := &poller{e: e, interval: interval, fetch: fetch}
+	d := GetDocument(e)
+
+	e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		p.refresh()
+		return false
+	}))
+	e.OnUnmounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		p.pause()
+		return false
+	}))
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		p.stop()
+		return false
+	}).RunOnce())
+
+	e.Watch(Namespace.UI, "visibilitystate", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if string(evt.NewValue().(ui.String)) == "hidden" {
+			p.pause()
+			return false
+		}
+		if resumeWhenVisible(e) {
+			p.refresh()
+		}
+		return false
+	}))
+	d.Window().AsElement().AddEventListener("focus", ui.NewEventHandler(func(evt ui.Event) bool {
+		p.refresh()
+		return false
+	}))
+
+	if e.Mounted() {
+		p.refresh()
+	}
+	return p.stop
+}
+
+func (p *poller) active() bool {
+	if p.stopped || !p.e.Mounted() {
+		return false
+	}
+	if v, ok := GetDocument(p.e).GetUI("visibilitystate"); ok && string(v.(ui.String)) == "hidden" {
+		return false
+	}
+	return true
+}
+
+// refresh fetches immediately, then resumes the periodic polling.
+func (p *poller) refresh() {
+	if !p.active() {
+		return
+	}
+	p.pause()
+	p.run()
+}
+
+func (p *poller) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+}
+
+func (p *poller) stop() {
+	p.pause()
+	p.stopped = true
+}
+
+func (p *poller) next() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	factor := 1
+	for i := 0; i < p.failures && factor < MaxPollBackoff; i++ {
+		factor *= 2
+	}
+	if factor > MaxPollBackoff {
+		factor = MaxPollBackoff
+	}
+	p.timer = time.AfterFunc(p.interval*time.Duration(factor), func() {
+		ui.DoSync(func() {
+			if p.active() {
+				p.run()
+			}
+		})
+	})
+}
+
+func (p *poller) run() {
+	if p.inflight {
+		return
+	}
+	p.inflight = true
+	ui.DoAsync(p.e, func(ctx context.Context) {
+		err := p.fetch(ctx)
+		ui.DoSync(func() {
+			p.inflight = false
+			if err != nil {
+				p.failures++
+				p.e.TriggerEvent("poll-error", ui.String(err.Error()))
+			} else {
+				p.failures = 0
+			}
+			if p.active() {
+				p.next()
+			}
+		})
+	})
+}
@@ -0,0 +1,428 @@
+package doc
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Presence
+//
+// A Presence service announces the local user to the peers of a CollabSession and keeps track of the remote
+// users, including the route they are currently on.
+// Announcements are repeated every PresenceHeartbeat. A peer which has not been heard of for three heartbeats,
+// or which has sent a leave message (e.g. when its page is hidden or the service is closed), is removed.
+//
+// The service also shares the pointer position and the text selection of the local user, so that they can be
+// rendered by the peers in an overlay. Positions are anchored to the closest element with an id, as offsets
+// relative to its bounding box for the pointer, and as character offsets within its text for the selection,
+// so that they are meaningful regardless of the viewport of each peer.
+// Pointer positions are broadcast at most once every CursorThrottle.
+// Only the peers on the same route as the local user are rendered in the overlay.
+//
+// The list of peers is available as the "peers" data property (ui.List of Objects with "replica", "name",
+// "color" and "route") of the element returned by AsElement.
+
+var (
+	// PresenceHeartbeat is the interval at which the presence of the local user is announced.
+	PresenceHeartbeat = 5 * time.Second
+	// CursorThrottle is the minimum interval between two broadcasts of the pointer position.
+	CursorThrottle = 50 * time.Millisecond
+)
+
+// Peer describes a remote user.
+type Peer struct {
+	Replica string
+	Name    string
+	Color   string
+	Route   string
+}
+
+type presenceInfo struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Route string `json:"route"`
+}
+
+type textPosition struct {
+	ID     string `json:"id"`
+	Offset int    `json:"offset"`
+}
+
+type cursorPosition struct {
+	ID string  `json:"id"`
+	X  float64 `json:"x"`
+	Y  float64 `json:"y"`
+}
+
+type selectionRange struct {
+	Start textPosition `json:"start"`
+	End   textPosition `json:"end"`
+}
+
+type peerState struct {
+	Peer
+	seen      time.Time
+	cursor    *cursorPosition
+	selection *selectionRange
+}
+
+// Presence tracks the users connected to a CollabSession.
+type Presence struct {
+	session *CollabSession
+	d       *Document
+	info    presenceInfo
+	peers   map[string]*peerState
+	element *ui.Element
+	overlay *ui.Element
+	stop    chan struct{}
+}
+
+func presenceColor(replica string) string {
+	h := fnv.New32a()
+	h.Write([]byte(replica))
+	return "hsl(" + strconv.Itoa(int(h.Sum32()%360)) + " 70% 45%)"
+}
+
+// Presence starts announcing the local user, under the given display name, to the peers of the session.
+func (s *CollabSession) Presence(d *Document, name string) *Presence {
+	p := &Presence{
+		session: s,
+		d:       d,
+		info:    presenceInfo{Name: name, Color: presenceColor(s.replica)},
+		peers:   make(map[string]*peerState),
+		element: d.NewObservable("zui-presence").AsElement(),
+		stop:    make(chan struct{}),
+	}
+	if r, ok := d.GetUI("currentroute"); ok {
+		p.info.Route = string(r.(ui.String))
+	}
+
+	s.handle("presence", func(from string, data json.RawMessage) {
+		var info presenceInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			return
+		}
+		ps, ok := p.peers[from]
+		if !ok {
+			ps = &peerState{Peer: Peer{Replica: from}}
+			p.peers[from] = ps
+			// the newcomer learns about us without waiting for the next heartbeat
+			p.announce()
+		}
+		ps.Name, ps.Color, ps.Route, ps.seen = info.Name, info.Color, info.Route, time.Now()
+		p.update()
+	})
+	s.handle("leave", func(from string, data json.RawMessage) {
+		if _, ok := p.peers[from]; ok {
+			delete(p.peers, from)
+			p.update()
+		}
+	})
+	s.handle("cursor", func(from string, data json.RawMessage) {
+		ps, ok := p.peers[from]
+		if !ok {
+			return
+		}
+		var c *cursorPosition
+		if err := json.Unmarshal(data, &c); err != nil {
+			return
+		}
+		ps.cursor, ps.seen = c, time.Now()
+		p.render()
+	})
+	s.handle("selection", func(from string, data json.RawMessage) {
+		ps, ok := p.peers[from]
+		if !ok {
+			return
+		}
+		var sel *selectionRange
+		if err := json.Unmarshal(data, &sel); err != nil {
+			return
+		}
+		ps.selection, ps.seen = sel, time.Now()
+		p.render()
+	})
+	if r, ok := s.transport.(interface{ OnOpen(func()) }); ok {
+		r.OnOpen(func() { go ui.DoSync(p.announce) })
+	}
+
+	d.Watch(Namespace.UI, "currentroute", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		p.info.Route = string(evt.NewValue().(ui.String))
+		p.announce()
+		p.render()
+		return false
+	}))
+
+	if InBrowser() {
+		p.trackPointer()
+		p.trackSelection()
+		d.Window().AsElement().AddEventListener("pagehide", ui.NewEventHandler(func(evt ui.Event) bool {
+			s.sendData("leave", nil)
+			return false
+		}))
+		d.Window().AsElement().AddEventListener("scroll", ui.NewEventHandler(func(evt ui.Event) bool {
+			p.render()
+			return false
+		}).Throttle(CursorThrottle))
+	}
+
+	p.announce()
+	go func() {
+		t := time.NewTicker(PresenceHeartbeat)
+		defer t.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-t.C:
+				ui.DoSync(func() {
+					p.announce()
+					p.expire()
+				})
+			}
+		}
+	}()
+	return p
+}
+
+// AsElement returns the element holding the "peers" data property.
+func (p *Presence) AsElement() *ui.Element {
+	return p.element
+}
+
+// Peers returns the remote users, sorted by name.
+func (p *Presence) Peers() []Peer {
+	res := make([]Peer, 0, len(p.peers))
+	for _, ps := range p.peers {
+		res = append(res, ps.Peer)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name != res[j].Name {
+			return res[i].Name < res[j].Name
+		}
+		return res[i].Replica < res[j].Replica
+	})
+	return res
+}
+
+// At returns the remote users currently on the given route.
+func (p *Presence) At(route string) []Peer {
+	var res []Peer
+	for _, peer := range p.Peers() {
+		if peer.Route == route {
+			res = append(res, peer)
+		}
+	}
+	return res
+}
+
+// Close announces that the local user leaves and stops the service.
+func (p *Presence) Close() {
+	select {
+	case <-p.stop:
+		return
+	default:
+	}
+	close(p.stop)
+	p.session.sendData("leave", nil)
+	p.peers = make(map[string]*peerState)
+	p.update()
+}
+
+func (p *Presence) announce() {
+	p.session.sendData("presence", p.info)
+}
+
+func (p *Presence) expire() {
+	changed := false
+	for id, ps := range p.peers {
+		if time.Since(ps.seen) > 3*PresenceHeartbeat {
+			delete(p.peers, id)
+			changed = true
+		}
+	}
+	if changed {
+		p.update()
+	}
+}
+
+// update publishes the list of peers and renders the overlay.
+func (p *Presence) update() {
+	l := ui.NewList()
+	for _, peer := range p.Peers() {
+		l.Append(ui.NewObject().
+			Set("replica", ui.String(peer.Replica)).
+			Set("name", ui.String(peer.Name)).
+			Set("color", ui.String(peer.Color)).
+			Set("route", ui.String(peer.Route)).
+			Commit())
+	}
+	p.element.SetData("peers", l.Commit())
+	p.render()
+}
+
+func (p *Presence) trackPointer() {
+	p.d.AddEventListener("pointermove", ui.NewEventHandler(func(evt ui.Event) bool {
+		m, ok := evt.(MouseEvent)
+		if !ok {
+			return false
+		}
+		target := evt.Target()
+		if target == nil || target.ID == "" {
+			return false
+		}
+		n, ok := JSValue(target)
+		if !ok {
+			return false
+		}
+		rect := n.Call("getBoundingClientRect")
+		w, h := rect.Get("width").Float(), rect.Get("height").Float()
+		if w <= 0 || h <= 0 {
+			return false
+		}
+		p.session.sendData("cursor", cursorPosition{
+			ID: target.ID,
+			X:  (m.ClientX() - rect.Get("left").Float()) / w,
+			Y:  (m.ClientY() - rect.Get("top").Float()) / h,
+		})
+		return false
+	}).Throttle(CursorThrottle))
+	p.d.AddEventListener("pointerleave", ui.NewEventHandler(func(evt ui.Event) bool {
+		p.session.sendData("cursor", nil)
+		return false
+	}))
+}
+
+func (p *Presence) trackSelection() {
+	p.d.AddEventListener("selectionchange", ui.NewEventHandler(func(evt ui.Event) bool {
+		sel := js.Global().Call("getSelection")
+		if sel.IsNull() || sel.Get("rangeCount").Int() == 0 || sel.Get("isCollapsed").Bool() {
+			p.session.sendData("selection", nil)
+			return false
+		}
+		r := sel.Call("getRangeAt", 0)
+		start, ok := toTextPosition(r.Get("startContainer"), r.Get("startOffset").Int())
+		if !ok {
+			return false
+		}
+		end, ok := toTextPosition(r.Get("endContainer"), r.Get("endOffset").Int())
+		if !ok {
+			return false
+		}
+		p.session.sendData("selection", selectionRange{start, end})
+		return false
+	}).Throttle(CursorThrottle))
+}
+
+// toTextPosition converts a DOM range boundary into a character offset within the closest element with an id.
+func toTextPosition(node js.Value, offset int) (textPosition, bool) {
+	el := node
+	if node.Get("nodeType").Int() != 1 {
+		el = node.Get("parentElement")
+	}
+	if el.IsNull() {
+		return textPosition{}, false
+	}
+	el = el.Call("closest", "[id]")
+	if el.IsNull() {
+		return textPosition{}, false
+	}
+	r := js.Global().Get("document").Call("createRange")
+	r.Call("selectNodeContents", el)
+	r.Call("setEnd", node, offset)
+	return textPosition{el.Get("id").String(), r.Call("toString").Length()}, true
+}
+
+// fromTextPosition is the inverse of toTextPosition.
+func fromTextPosition(pos textPosition) (js.Value, int, bool) {
+	document := js.Global().Get("document")
+	el := document.Call("getElementById", pos.ID)
+	if el.IsNull() {
+		return el, 0, false
+	}
+	walker := document.Call("createTreeWalker", el, 4) // NodeFilter.SHOW_TEXT
+	offset := pos.Offset
+	for n := walker.Call("nextNode"); !n.IsNull(); n = walker.Call("nextNode") {
+		l := n.Get("data").Length()
+		if offset <= l {
+			return n, offset, true
+		}
+		offset -= l
+	}
+	return el, el.Get("childNodes").Length(), true
+}
+
+func (p *Presence) render() {
+	if !InBrowser() {
+		return
+	}
+	if p.overlay == nil {
+		p.overlay = p.d.Div.WithID("zui-presence-overlay").AsElement()
+		SetAttribute(p.overlay, "aria-hidden", "true")
+		SetInlineCSS(p.overlay, "position: fixed; inset: 0; pointer-events: none; z-index: 2147483647; overflow: hidden;")
+		p.d.Body().AppendChild(p.overlay)
+	}
+	p.overlay.DeleteChildren()
+
+	var children []*ui.Element
+	for _, peer := range p.Peers() {
+		ps := p.peers[peer.Replica]
+		if ps.Route != p.info.Route {
+			continue
+		}
+		if ps.selection != nil {
+			children = append(children, p.selectionHighlights(ps)...)
+		}
+		if ps.cursor == nil {
+			continue
+		}
+		el := js.Global().Get("document").Call("getElementById", ps.cursor.ID)
+		if el.IsNull() {
+			continue
+		}
+		rect := el.Call("getBoundingClientRect")
+		x := rect.Get("left").Float() + ps.cursor.X*rect.Get("width").Float()
+		y := rect.Get("top").Float() + ps.cursor.Y*rect.Get("height").Float()
+		c := p.d.Div.WithID("zui-presence-cursor-" + ps.Replica).AsElement()
+		AddClass(c, "zui-presence-cursor")
+		SetInlineCSS(c, "position: absolute; left: "+strconv.FormatFloat(x, 'f', 1, 64)+"px; top: "+strconv.FormatFloat(y, 'f', 1, 64)+"px; width: 0.6rem; height: 0.6rem; border-radius: 0 50% 50% 50%; background: "+ps.Color+";")
+		label := p.d.Span.WithID("zui-presence-cursor-" + ps.Replica + "-name").SetText(ps.Name)
+		AddClass(label.AsElement(), "zui-presence-name")
+		SetInlineCSS(label.AsElement(), "position: absolute; left: 0.75rem; top: 0.5rem; padding: 0 0.25rem; white-space: nowrap; font-size: 0.75rem; color: #fff; background: "+ps.Color+";")
+		c.SetChildren(label.AsElement())
+		children = append(children, c)
+	}
+	p.overlay.SetChildren(children...)
+}
+
+func (p *Presence) selectionHighlights(ps *peerState) []*ui.Element {
+	startNode, startOffset, ok := fromTextPosition(ps.selection.Start)
+	if !ok {
+		return nil
+	}
+	endNode, endOffset, ok := fromTextPosition(ps.selection.End)
+	if !ok {
+		return nil
+	}
+	r := js.Global().Get("document").Call("createRange")
+	r.Call("setStart", startNode, startOffset)
+	r.Call("setEnd", endNode, endOffset)
+	rects := r.Call("getClientRects")
+	res := make([]*ui.Element, 0, rects.Length())
+	for i := 0; i < rects.Length(); i++ {
+		rect := rects.Index(i)
+		h := p.d.Div.WithID("zui-presence-selection-" + ps.Replica + "-" + strconv.Itoa(i)).AsElement()
+		AddClass(h, "zui-presence-selection")
+		SetInlineCSS(h, "position: absolute; left: "+strconv.FormatFloat(rect.Get("left").Float(), 'f', 1, 64)+"px; top: "+
+			strconv.FormatFloat(rect.Get("top").Float(), 'f', 1, 64)+"px; width: "+strconv.FormatFloat(rect.Get("width").Float(), 'f', 1, 64)+
+			"px; height: "+strconv.FormatFloat(rect.Get("height").Float(), 'f', 1, 64)+"px; background: "+ps.Color+"; opacity: 0.25;")
+		res = append(res, h)
+	}
+	return res
+}
@@ -0,0 +1,208 @@
+package doc
+
+import (
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Live preview
+//
+// A Preview renders content into a sandboxed iframe, as playgrounds built on the codearea component need:
+// the previewed code runs with an opaque origin, so that it can neither access the app nor its storage.
+// The content is updated in place via messages, without reloading the frame: SetHTML replaces the body of
+// the preview and runs the scripts it holds, SetCSS replaces its stylesheet, and Render previews an element
+// subtree. Reload gives a clean slate to the previewed code.
+// BindHTML and BindCSS update the preview when a property holding the code changes, e.g. the content of an
+// editor, once it has not changed for PreviewUpdateDelay.
+//
+// The output of the previewed code is reported as events triggered on the iframe element:
+//   - "preview-ready" once the frame can receive updates, and after each reload,
+//   - "preview-console" for each console call, with the "level" ("log", "info", "warn", "error", "debug")
+//     and the "message",
+//   - "preview-error" for uncaught errors and unhandled rejections, with the "message", "source", "line" and
+//     "column" of the error when known.
+
+// PreviewUpdateDelay is the delay without modification after which a bound preview is updated.
+var PreviewUpdateDelay = 300 * time.Millisecond
+
+// Preview is a live preview of content rendered in a sandboxed iframe.
+type Preview struct {
+	Frame IframeElement
+
+	ready bool
+	html  string
+	css   string
+}
+
+var previewBootstrap = `<!DOCTYPE html><html><head><meta charset="utf-8"><style id="zui-preview-style"></style><script>
+(function(){
+  var post = function(msg){ msg["zui-preview"] = true; parent.postMessage(msg, "*"); };
+  var format = function(v){
+    if (typeof v === "string") return v;
+    if (v instanceof Error) return v.stack || v.message;
+    try { return JSON.stringify(v); } catch (e) { return String(v); }
+  };
+  ["log","info","warn","error","debug"].forEach(function(level){
+    var native = console[level];
+    console[level] = function(){
+      post({type: "console", level: level, message: Array.prototype.map.call(arguments, format).join(" ")});
+      if (native) native.apply(console, arguments);
+    };
+  });
+  window.addEventListener("error", function(e){
+    post({type: "error", message: e.message || "", source: e.filename || "", line: e.lineno || 0, column: e.colno || 0});
+  });
+  window.addEventListener("unhandledrejection", function(e){
+    post({type: "error", message: format(e.reason), source: "", line: 0, column: 0});
+  });
+  window.addEventListener("message", function(e){
+    if (e.source !== parent || !e.data || !e.data["zui-preview"]) return;
+    var m = e.data;
+    if (m.type === "css") {
+      document.getElementById("zui-preview-style").textContent = m.css;
+    } else if (m.type === "html") {
+      document.body.innerHTML = m.html;
+      Array.prototype.forEach.call(document.body.querySelectorAll("script"), function(old){
+        var s = document.createElement("script");
+        Array.prototype.forEach.call(old.attributes, function(a){ s.setAttribute(a.name, a.value); });
+        s.textContent = old.textContent;
+        old.replaceWith(s);
+      });
+    }
+  });
+  document.addEventListener("DOMContentLoaded", function(){ post({type: "ready"}); });
+})();
+</script></head><body></body></html>`
+
+// NewPreview creates a live preview. The iframe element of the preview is to be inserted in the document.
+func (d *Document) NewPreview(id string) *Preview {
+	p := &Preview{Frame: d.Iframe.WithID(id, "about:blank")}
+	f := p.Frame.AsElement()
+	IframeModifier.Sandbox().AllowScripts()(f)
+	IframeModifier.Sandbox().AllowModals()(f)
+	IframeModifier.SrcDoc(previewBootstrap)(f)
+	if !InBrowser() {
+		return p
+	}
+
+	w := d.Window().AsElement()
+	h := ui.NewEventHandler(func(evt ui.Event) bool {
+		msg := evt.Native().(NativeEvent).Value
+		n, ok := JSValue(f)
+		if !ok || !msg.Get("source").Equal(n.Get("contentWindow")) {
+			return false
+		}
+		data := msg.Get("data")
+		if !data.Truthy() || !data.Get("zui-preview").Truthy() {
+			return false
+		}
+		p.receive(data)
+		return false
+	})
+	w.AddEventListener("message", h)
+	f.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		w.RemoveEventListener("message", h)
+		return false
+	}).RunOnce())
+	f.OnUnmounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		// the frame is reloaded when it is inserted again.
+		p.ready = false
+		return false
+	}))
+	return p
+}
+
+func (p *Preview) receive(data js.Value) {
+	f := p.Frame.AsElement()
+	switch data.Get("type").String() {
+	case "ready":
+		p.ready = true
+		// the content is sent once the frame is ready, and again after a reload.
+		p.post(map[string]interface{}{"type": "css", "css": p.css})
+		p.post(map[string]interface{}{"type": "html", "html": p.html})
+		f.TriggerEvent("preview-ready")
+	case "console":
+		f.TriggerEvent("preview-console", ui.NewObject().
+			Set("level", ui.String(data.Get("level").String())).
+			Set("message", ui.String(data.Get("message").String())).
+			Commit())
+	case "error":
+		f.TriggerEvent("preview-error", ui.NewObject().
+			Set("message", ui.String(data.Get("message").String())).
+			Set("source", ui.String(data.Get("source").String())).
+			Set("line", ui.Number(data.Get("line").Float())).
+			Set("column", ui.Number(data.Get("column").Float())).
+			Commit())
+	}
+}
+
+// post sends a message to the frame. Messages are dropped until the frame is ready.
+func (p *Preview) post(msg map[string]interface{}) {
+	if !p.ready {
+		return
+	}
+	n, ok := JSValue(p.Frame.AsElement())
+	if !ok || !n.Get("contentWindow").Truthy() {
+		return
+	}
+	msg["zui-preview"] = true
+	n.Get("contentWindow").Call("postMessage", msg, "*")
+}
+
+// SetHTML replaces the body of the preview. The scripts it holds are run.
+func (p *Preview) SetHTML(html string) {
+	if html == p.html {
+		return
+	}
+	p.html = html
+	p.post(map[string]interface{}{"type": "html", "html": html})
+}
+
+// SetCSS replaces the stylesheet of the preview.
+func (p *Preview) SetCSS(css string) {
+	if css == p.css {
+		return
+	}
+	p.css = css
+	p.post(map[string]interface{}{"type": "css", "css": css})
+}
+
+// Render previews an element subtree, as rendered by RenderToString.
+func (p *Preview) Render(e *ui.Element) error {
+	html, err := RenderToString(e)
+	if err != nil {
+		return err
+	}
+	p.SetHTML(html)
+	return nil
+}
+
+// Reload reloads the frame, so that the previewed code runs anew. The content of the preview is kept.
+func (p *Preview) Reload() {
+	p.ready = false
+	if n, ok := JSValue(p.Frame.AsElement()); ok {
+		n.Set("srcdoc", previewBootstrap)
+	}
+}
+
+// BindHTML updates the body of the preview with the string held by a property.
+func (p *Preview) BindHTML(source ui.Watchable, category string, propname string) {
+	p.Frame.AsElement().Watch(category, propname, source, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if s, ok := evt.NewValue().(ui.String); ok {
+			p.SetHTML(string(s))
+		}
+		return false
+	}).Debounce(PreviewUpdateDelay).RunASAP())
+}
+
+// BindCSS updates the stylesheet of the preview with the string held by a property.
+func (p *Preview) BindCSS(source ui.Watchable, category string, propname string) {
+	p.Frame.AsElement().Watch(category, propname, source, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if s, ok := evt.NewValue().(ui.String); ok {
+			p.SetCSS(string(s))
+		}
+		return false
+	}).Debounce(PreviewUpdateDelay).RunASAP())
+}
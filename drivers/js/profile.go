@@ -0,0 +1,58 @@
+package doc
+
+import (
+	"log"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// Profiles
+//
+// The behavior of the documents follows the ui.Profile of the Elements configuration:
+//   - Debug enables the accessibility audit, lifecycle timings, callback audit and route usage tracking,
+//   - CaptureLimit bounds the mutation capture (CaptureLimit by default),
+//   - PanicOnError turns reported framework errors into panics,
+//   - LogLevel silences the driver logs (DEBUG) below ui.LogDebug.
+//
+// The profile is set with WithProfile, or selected by name at build time with ui.ProfileName.
+// When neither is done, DevMode selects ui.DevelopmentProfile, for compatibility with the existing build flags.
+
+// WithProfile returns a build environment modifier, to be passed to NewBuilder, which sets the profile of
+// the documents.
+func WithProfile(p ui.Profile) func() {
+	return func() {
+		Elements.WithProfile(p)
+	}
+}
+
+// profile returns the profile of the documents.
+func profile() ui.Profile {
+	if !Elements.HasProfile() && ui.ProfileName == "" && DevMode != "false" {
+		return ui.DevelopmentProfile
+	}
+	return Elements.Profile()
+}
+
+// profileSelected returns whether a profile was chosen explicitly, rather than inferred.
+func profileSelected() bool {
+	return Elements.HasProfile() || ui.ProfileName != ""
+}
+
+func captureLimit() int {
+	if l := profile().CaptureLimit; l > 0 {
+		return l
+	}
+	return CaptureLimit
+}
+
+// applyLogLevel adjusts the driver logs to the level of an explicitly selected profile.
+func applyLogLevel() {
+	if !profileSelected() {
+		return
+	}
+	if profile().LogLevel >= ui.LogDebug {
+		DEBUG = log.Print
+		return
+	}
+	DEBUG = func(v ...any) {}
+}
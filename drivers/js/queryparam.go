@@ -0,0 +1,180 @@
+package doc
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Query parameter state
+//
+// SyncWithQueryParam keeps a data property of an element in sync with a query parameter of the URL, so that
+// filters, sort orders or search terms can be shared and restored via the URL.
+//   - When the property changes, the parameter is updated with history.replaceState, once the property has
+//     not changed for QueryParamDebounce. No history entry is added. The parameter is removed when the
+//     property has its initial value, which keeps URLs short.
+//   - When the document navigates, the property is set from the parameter of the new route, or reset to its
+//     initial value if the parameter is absent.
+//   - During a server render, the property is set from the query of the request (see RequestContext).
+//
+// A ParamCodec converts the property to and from the parameter value. Codecs are provided for strings,
+// numbers, booleans and lists of strings.
+// SyncRegionWithQueryParam applies the same to the route of a region router, which is otherwise memory-based.
+
+// QueryParamDebounce is the delay after which a query parameter is updated following a property change.
+var QueryParamDebounce = 300 * time.Millisecond
+
+// ParamCodec converts a property value to and from a query parameter value.
+type ParamCodec struct {
+	Encode func(ui.Value) string
+	Decode func(string) (ui.Value, error)
+}
+
+var (
+	StringParam = ParamCodec{
+		Encode: func(v ui.Value) string { return string(v.(ui.String)) },
+		Decode: func(s string) (ui.Value, error) { return ui.String(s), nil },
+	}
+	NumberParam = ParamCodec{
+		Encode: func(v ui.Value) string { return strconv.FormatFloat(float64(v.(ui.Number)), 'f', -1, 64) },
+		Decode: func(s string) (ui.Value, error) {
+			f, err := strconv.ParseFloat(s, 64)
+			return ui.Number(f), err
+		},
+	}
+	BoolParam = ParamCodec{
+		Encode: func(v ui.Value) string { return strconv.FormatBool(bool(v.(ui.Bool))) },
+		Decode: func(s string) (ui.Value, error) {
+			b, err := strconv.ParseBool(s)
+			return ui.Bool(b), err
+		},
+	}
+	// ListParam encodes a list of strings as comma separated values.
+	ListParam = ParamCodec{
+		Encode: func(v ui.Value) string {
+			var res []string
+			for _, s := range v.(ui.List).UnsafelyUnwrap() {
+				res = append(res, string(s.(ui.String)))
+			}
+			return strings.Join(res, ",")
+		},
+		Decode: func(s string) (ui.Value, error) {
+			l := ui.NewList()
+			if s == "" {
+				return l.Commit(), nil
+			}
+			for _, v := range strings.Split(s, ",") {
+				l.Append(ui.String(v))
+			}
+			return l.Commit(), nil
+		},
+	}
+)
+
+// SyncWithQueryParam keeps the data property of an element in sync with the named query parameter.
+func SyncWithQueryParam(e *ui.Element, prop string, paramName string, codec ParamCodec) {
+	d := GetDocument(e)
+	initial, hasInitial := e.GetData(prop)
+
+	apply := func(query url.Values) {
+		s, ok := query[paramName]
+		if !ok || len(s) == 0 {
+			if hasInitial {
+				e.SetData(prop, initial)
+			}
+			return
+		}
+		v, err := codec.Decode(s[0])
+		if err != nil {
+			DEBUG("query parameter ", paramName, " could not be decoded: ", err)
+			return
+		}
+		e.SetData(prop, v)
+	}
+
+	// initial state
+	if InBrowser() {
+		apply(currentQuery())
+	} else if rc, ok := d.RequestContext(); ok {
+		apply(rc.Query)
+	}
+
+	var timer *time.Timer
+	write := func() {
+		v, ok := e.GetData(prop)
+		u, err := url.Parse(js.Global().Get("location").Get("href").String())
+		if err != nil {
+			return
+		}
+		q := u.Query()
+		if !ok || (hasInitial && ui.Equal(v, initial)) {
+			q.Del(paramName)
+		} else {
+			q.Set(paramName, codec.Encode(v))
+		}
+		if q.Encode() == u.Query().Encode() {
+			return
+		}
+		u.RawQuery = q.Encode()
+		h := js.Global().Get("history")
+		h.Call("replaceState", h.Get("state"), "", u.String())
+	}
+
+	e.Watch(Namespace.Data, prop, e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if !InBrowser() {
+			return false
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(QueryParamDebounce, func() {
+			ui.DoSync(write)
+		})
+		return false
+	}))
+
+	e.Watch(Namespace.UI, "currentroute", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		route := string(evt.NewValue().(ui.String))
+		u, err := url.Parse(route)
+		if err != nil {
+			return false
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		query := u.Query()
+		if u.RawQuery == "" && InBrowser() {
+			// the route may have been restored from the location (e.g. popstate), without its query.
+			loc, _ := url.JoinPath(BasePath, u.Path)
+			if js.Global().Get("location").Get("pathname").String() == loc {
+				query = currentQuery()
+			}
+		}
+		apply(query)
+		return false
+	}))
+
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if timer != nil {
+			timer.Stop()
+		}
+		return false
+	}).RunOnce())
+}
+
+// SyncRegionWithQueryParam keeps the route of a region router in sync with the named query parameter.
+func SyncRegionWithQueryParam(r *ui.RegionRouter, paramName string) {
+	SyncWithQueryParam(r.Outlet.AsElement(), "regionroute", paramName, StringParam)
+}
+
+func currentQuery() url.Values {
+	q, err := url.ParseQuery(strings.TrimPrefix(js.Global().Get("location").Get("search").String(), "?"))
+	if err != nil {
+		return url.Values{}
+	}
+	return q
+}
@@ -0,0 +1,28 @@
+package doc
+
+import (
+	"errors"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// HTML export
+//
+// RenderToString serializes the current state of a subtree to HTML, e.g. to preview an email template, to copy
+// a fragment as HTML, or to feed a custom static generation pipeline.
+// In the browser, the state held by the properties of form controls (value, checked, selected) is not reflected
+// by the markup of the native elements. It is written as attributes in the output, so that the HTML renders the
+// subtree as it is displayed. The native elements are left untouched.
+// On the server, the native elements already hold the attributes set by the reactive tree.
+
+// ErrNotRendered is returned by RenderToString when an element has no native HTML element.
+var ErrNotRendered = errors.New("element is not rendered as an HTML element")
+
+// RenderToString returns the HTML serialization of the subtree of an element.
+func RenderToString(e *ui.Element) (string, error) {
+	n, ok := JSValue(e)
+	if !ok {
+		return "", ErrNotRendered
+	}
+	return renderNative(n)
+}
@@ -0,0 +1,59 @@
+//go:build !server
+
+package doc
+
+import (
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+func renderNative(n js.Value) (string, error) {
+	if n.Get("outerHTML").IsUndefined() {
+		return "", ErrNotRendered
+	}
+	clone := n.Call("cloneNode", true)
+	syncControlState(n, clone)
+	return clone.Get("outerHTML").String(), nil
+}
+
+// syncControlState writes the state of the form controls of the original subtree as attributes of the clone.
+func syncControlState(original, clone js.Value) {
+	selector := "input, textarea, select, option"
+	originals := []js.Value{}
+	clones := []js.Value{}
+	if original.Call("matches", selector).Bool() {
+		originals = append(originals, original)
+		clones = append(clones, clone)
+	}
+	o := original.Call("querySelectorAll", selector)
+	c := clone.Call("querySelectorAll", selector)
+	for i := 0; i < o.Get("length").Int() && i < c.Get("length").Int(); i++ {
+		originals = append(originals, o.Index(i))
+		clones = append(clones, c.Index(i))
+	}
+
+	for i, o := range originals {
+		c := clones[i]
+		switch o.Get("tagName").String() {
+		case "INPUT":
+			switch o.Get("type").String() {
+			case "checkbox", "radio":
+				toggleAttribute(c, "checked", o.Get("checked").Bool())
+			case "file", "password":
+			default:
+				c.Call("setAttribute", "value", o.Get("value"))
+			}
+		case "TEXTAREA":
+			c.Set("textContent", o.Get("value"))
+		case "OPTION":
+			toggleAttribute(c, "selected", o.Get("selected").Bool())
+		}
+	}
+}
+
+func toggleAttribute(n js.Value, name string, on bool) {
+	if on {
+		n.Call("setAttribute", name, "")
+		return
+	}
+	n.Call("removeAttribute", name)
+}
@@ -0,0 +1,27 @@
+//go:build server
+
+package doc
+
+import (
+	"strings"
+
+	js "github.com/atdiar/particleui/drivers/js/compat"
+	"golang.org/x/net/html"
+)
+
+func renderNative(n js.Value) (res string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			res, err = "", ErrNotRendered
+		}
+	}()
+	node := n.Node()
+	if node.Type != html.ElementNode {
+		return "", ErrNotRendered
+	}
+	var b strings.Builder
+	if err := html.Render(&b, node); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
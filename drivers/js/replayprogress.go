@@ -0,0 +1,133 @@
+package doc
+
+import (
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Replay progress
+//
+// While a long mutation trace is replayed, the page is displayed but does not respond yet.
+// ShowReplayProgress signals it with an indicator: a thin bar at the top of the page, and optionally a
+// shimmer over the outlets whose content is being restored.
+//
+// The indicator is made of the pseudo-elements of the root element and outlets: no element is added to the
+// tree. It is displayed while the root element bears the data-zui-replaying attribute, which is set on the
+// server so that the indicator is part of the first paint, or when the replay starts otherwise.
+// The bar grows with the "replay-progress" events. Since a replay that is not progressive blocks the page
+// until it is over, the bar also runs an animation which does not depend on the main thread.
+// The attributes are removed once the replay is over, whatever its outcome.
+
+// ReplayProgressStyleSheetID is the id of the stylesheet holding the replay indicator rules.
+const ReplayProgressStyleSheetID = "zui-replay-progress"
+
+// ReplayProgressOptions configures the replay indicator.
+type ReplayProgressOptions struct {
+	Color    string        // color of the bar, "Highlight" by default
+	Skeleton bool          // displays a shimmer over the outlets
+	Outlets  []*ui.Element // outlets covered by the shimmer, the outlet of the router by default
+}
+
+// ShowReplayProgress displays an indicator while the mutations of the document are replayed.
+func (d *Document) ShowReplayProgress(opts ...ReplayProgressOptions) *Document {
+	var o ReplayProgressOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Color == "" {
+		o.Color = "Highlight"
+	}
+	replayProgressStyleSheet(d, o)
+
+	mark := func() {
+		setNativeAttribute(d.AsElement(), "data-zui-replaying", "")
+		if !o.Skeleton {
+			return
+		}
+		outlets := o.Outlets
+		if len(outlets) == 0 {
+			if r := d.Router(); r != nil {
+				outlets = []*ui.Element{r.Outlet.AsElement()}
+			}
+		}
+		for _, outlet := range outlets {
+			setNativeAttribute(outlet, "data-zui-replay-skeleton", "")
+		}
+	}
+
+	if !InBrowser() {
+		if shouldbereplayable() {
+			d.OnRouterMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+				mark()
+				return false
+			}).RunOnce())
+			mark()
+		}
+		return d
+	}
+
+	d.OnTransitionStart("replay", ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		mark()
+		return false
+	}).RunOnce())
+
+	d.WatchEvent("replay-progress", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		p := evt.NewValue().(ui.Object)
+		total := float64(p.MustGetNumber("total"))
+		if total <= 0 {
+			return false
+		}
+		ratio := float64(p.MustGetNumber("processed")) / total
+		js.Global().Get("document").Get("documentElement").Get("style").Call("setProperty", "--zui-replay-progress", strconv.FormatFloat(ratio, 'f', 3, 64))
+		return false
+	}))
+
+	d.AfterTransition("replay", ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		doc := js.Global().Get("document")
+		root := doc.Get("documentElement")
+		root.Call("removeAttribute", "data-zui-replaying")
+		root.Get("style").Call("removeProperty", "--zui-replay-progress")
+		skeletons := doc.Call("querySelectorAll", "[data-zui-replay-skeleton]")
+		for i := 0; i < skeletons.Length(); i++ {
+			skeletons.Index(i).Call("removeAttribute", "data-zui-replay-skeleton")
+		}
+		return false
+	}).RunOnce())
+	return d
+}
+
+// setNativeAttribute sets an attribute on the native element only, so that it is neither captured nor
+// replayed.
+func setNativeAttribute(e *ui.Element, name, value string) {
+	n, ok := JSValue(e)
+	if !ok {
+		return
+	}
+	n.Call("setAttribute", name, value)
+}
+
+func replayProgressStyleSheet(d *Document, o ReplayProgressOptions) {
+	if _, ok := d.GetStyleSheet(ReplayProgressStyleSheetID); ok {
+		return
+	}
+	sheet := d.NewStyleSheet(ReplayProgressStyleSheetID)
+	actives := append([]string{ReplayProgressStyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+
+	bar := "content: ''; position: fixed; top: 0; left: 0; height: 3px; z-index: 2147483647; pointer-events: none;"
+	sheet.InsertRule("[data-zui-replaying]::before", bar+" right: 0; background: "+o.Color+"; transform-origin: 0 50%; transform: scaleX(var(--zui-replay-progress, 0)); transition: transform 0.2s;")
+	sheet.InsertRule("[data-zui-replaying]::after", bar+" width: 30%; background: linear-gradient(90deg, transparent, "+o.Color+", transparent); animation: zui-replay-indeterminate 1.2s linear infinite;")
+	sheet.InsertKeyframe("zui-replay-indeterminate", "from", "transform: translateX(-100%);")
+	sheet.InsertKeyframe("zui-replay-indeterminate", "to", "transform: translateX(400%);")
+
+	if o.Skeleton {
+		sheet.InsertRule("[data-zui-replay-skeleton]", "position: relative;")
+		sheet.InsertRule("[data-zui-replay-skeleton]::after", "content: ''; position: absolute; inset: 0; pointer-events: none; background: linear-gradient(90deg, rgba(128, 128, 128, 0) 0%, rgba(128, 128, 128, 0.15) 50%, rgba(128, 128, 128, 0) 100%); background-size: 200% 100%; animation: zui-replay-shimmer 1.5s linear infinite;")
+		sheet.InsertKeyframe("zui-replay-shimmer", "from", "background-position: 100% 0;")
+		sheet.InsertKeyframe("zui-replay-shimmer", "to", "background-position: -100% 0;")
+	}
+	sheet.InsertMediaRule("(prefers-reduced-motion: reduce)", "[data-zui-replaying]::after, [data-zui-replay-skeleton]::after", "animation: none;")
+	sheet.Update()
+}
@@ -0,0 +1,196 @@
+package doc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// Request context
+//
+// During a server render, the incoming HTTP request is exposed to the rendering code via
+// Document.RequestContext, so that the initial render can be localized or personalized. It is set before
+// the first navigation of the document, i.e. it is available from the view activation handlers onward.
+//
+// Only a safe subset of the request is serialized into the hydration state, so that the client renders the
+// same thing as the server: the path, the preferred languages, the user agent and the device hints.
+// Headers and cookies may hold credentials: they are only available on the server and are nil on the client.
+//
+// Device hints are read from the client hint request headers. Browsers only send them once the server has
+// asked for them with the Accept-CH response header, listed in ClientHints.
+
+// ClientHints is the list of client hints requested in the Accept-CH header of server responses.
+var ClientHints = []string{"Sec-CH-UA-Mobile", "Sec-CH-UA-Platform", "Sec-CH-Viewport-Width", "Sec-CH-DPR"}
+
+// RequestContext describes the HTTP request a document was rendered for.
+type RequestContext struct {
+	Method string
+	Path   string
+	Query  url.Values
+
+	// Header and Cookies are only available on the server.
+	Header  http.Header
+	Cookies []*http.Cookie
+
+	// Languages holds the languages of the Accept-Language header, by order of preference.
+	Languages []string
+	UserAgent string
+
+	// Device hints. Their zero value means that the hint is unknown.
+	Mobile        bool
+	Platform      string
+	ViewportWidth int
+	DPR           float64
+	SaveData      bool
+}
+
+// requests holds the requests being rendered, by document root.
+var requests = newscsmap[*ui.Element, *http.Request]()
+
+// RequestContext returns the context of the request the document was rendered for. The boolean is false if
+// the document was not server rendered.
+func (d *Document) RequestContext() (RequestContext, bool) {
+	var c RequestContext
+	v, ok := d.AsElement().GetData("requestcontext")
+	if !ok {
+		return c, false
+	}
+	o := v.(ui.Object)
+	c.Method = o.MustGetString("method").String()
+	c.Path = o.MustGetString("path").String()
+	c.Query, _ = url.ParseQuery(o.MustGetString("query").String())
+	c.UserAgent = o.MustGetString("useragent").String()
+	c.Platform = o.MustGetString("platform").String()
+	c.ViewportWidth = int(o.MustGetNumber("viewportwidth"))
+	c.DPR = float64(o.MustGetNumber("dpr"))
+	c.Mobile = o.MustGetBool("mobile").Bool()
+	c.SaveData = o.MustGetBool("savedata").Bool()
+	for _, lang := range o.MustGetList("languages").UnsafelyUnwrap() {
+		c.Languages = append(c.Languages, string(lang.(ui.String)))
+	}
+	if r, ok := requests.Get(d.AsElement()); ok {
+		c.Header = r.Header.Clone()
+		c.Cookies = r.Cookies()
+	}
+	return c, true
+}
+
+// Cookie returns the value of a cookie of the request being rendered. It is only available on the server.
+func (d *Document) Cookie(name string) (string, bool) {
+	r, ok := requests.Get(d.AsElement())
+	if !ok {
+		return "", false
+	}
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+	return c.Value, true
+}
+
+// PreferredLanguage returns the supported language that best matches the preferences of the request, or the
+// first supported language if none matches. Languages match on their primary subtag, so that "fr-CA" matches
+// "fr" when "fr-CA" is not supported.
+func (d *Document) PreferredLanguage(supported ...string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	c, ok := d.RequestContext()
+	if !ok {
+		return supported[0]
+	}
+	return matchLanguage(c.Languages, supported)
+}
+
+func matchLanguage(preferred, supported []string) string {
+	primary := func(lang string) string {
+		if i := strings.IndexAny(lang, "-_"); i >= 0 {
+			return strings.ToLower(lang[:i])
+		}
+		return strings.ToLower(lang)
+	}
+	for _, p := range preferred {
+		for _, s := range supported {
+			if strings.EqualFold(p, s) {
+				return s
+			}
+		}
+		for _, s := range supported {
+			if primary(p) == primary(s) {
+				return s
+			}
+		}
+	}
+	return supported[0]
+}
+
+// setRequestContext exposes the request to the rendering code of the document.
+func setRequestContext(d *Document, r *http.Request) {
+	e := d.AsElement()
+	requests.Set(e, r)
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		requests.Delete(evt.Origin())
+		return false
+	}).RunOnce())
+	// the per request services are released once the response is sent.
+	context.AfterFunc(r.Context(), func() { releaseServices(e) })
+
+	h := r.Header
+	o := ui.NewObject()
+	o.Set("method", ui.String(r.Method))
+	o.Set("path", ui.String(r.URL.Path))
+	o.Set("query", ui.String(r.URL.RawQuery))
+	o.Set("useragent", ui.String(h.Get("User-Agent")))
+	o.Set("platform", ui.String(strings.Trim(h.Get("Sec-CH-UA-Platform"), `"`)))
+	o.Set("mobile", ui.Bool(h.Get("Sec-CH-UA-Mobile") == "?1"))
+	o.Set("savedata", ui.Bool(strings.EqualFold(h.Get("Save-Data"), "on")))
+	w, _ := strconv.Atoi(h.Get("Sec-CH-Viewport-Width"))
+	o.Set("viewportwidth", ui.Number(w))
+	dpr, _ := strconv.ParseFloat(h.Get("Sec-CH-DPR"), 64)
+	o.Set("dpr", ui.Number(dpr))
+	langs := ui.NewList()
+	for _, lang := range parseAcceptLanguage(h.Get("Accept-Language")) {
+		langs.Append(ui.String(lang))
+	}
+	o.Set("languages", langs.Commit())
+	e.SetData("requestcontext", o.Commit())
+}
+
+// parseAcceptLanguage returns the languages of an Accept-Language header, by decreasing quality.
+func parseAcceptLanguage(header string) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		l := lang{tag: part, q: 1}
+		if i := strings.Index(part, ";"); i >= 0 {
+			l.tag = strings.TrimSpace(part[:i])
+			if q, ok := strings.CutPrefix(strings.TrimSpace(part[i+1:]), "q="); ok {
+				if f, err := strconv.ParseFloat(q, 64); err == nil {
+					l.q = f
+				}
+			}
+		}
+		if l.tag == "*" || l.q <= 0 {
+			continue
+		}
+		langs = append(langs, l)
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	res := make([]string, 0, len(langs))
+	for _, l := range langs {
+		res = append(res, l.tag)
+	}
+	return res
+}
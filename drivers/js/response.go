@@ -0,0 +1,82 @@
+package doc
+
+import (
+	"net/http"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// HTTP response metadata
+//
+// When a document is rendered on the server, the outcome of the navigation should be reflected in the
+// status code of the HTTP response instead of always returning 200 (soft-404s).
+// Views may declare the status and headers of the response via the methods below. The router does it
+// automatically for the notfound (404), unauthorized (401) and appfailure (500) outcomes.
+// On the client, these values are merely informative.
+
+// SetResponseStatus sets the HTTP status code that should be used for the server response
+// once the document has been rendered.
+func (d *Document) SetResponseStatus(code int) *Document {
+	d.AsElement().Set(Namespace.Internals, "response-status", ui.Number(code))
+	return d
+}
+
+// ResponseStatus returns the HTTP status code that should be used for the server response.
+// It defaults to 200.
+func (d *Document) ResponseStatus() int {
+	v, ok := d.AsElement().Get(Namespace.Internals, "response-status")
+	if !ok {
+		return http.StatusOK
+	}
+	return int(v.(ui.Number))
+}
+
+// SetResponseHeader adds a header to the server response.
+func (d *Document) SetResponseHeader(key, value string) *Document {
+	var h *ui.TempObject
+	v, ok := d.AsElement().Get(Namespace.Internals, "response-headers")
+	if ok {
+		h = v.(ui.Object).MakeCopy()
+	} else {
+		h = ui.NewObject()
+	}
+	d.AsElement().Set(Namespace.Internals, "response-headers", h.Set(http.CanonicalHeaderKey(key), ui.String(value)).Commit())
+	return d
+}
+
+// ResponseHeaders returns the headers that were declared for the server response.
+func (d *Document) ResponseHeaders() http.Header {
+	res := make(http.Header)
+	v, ok := d.AsElement().Get(Namespace.Internals, "response-headers")
+	if !ok {
+		return res
+	}
+	v.(ui.Object).Range(func(key string, val ui.Value) bool {
+		res.Set(key, string(val.(ui.String)))
+		return false
+	})
+	return res
+}
+
+// RedirectResponse declares that the server response should be a redirection to the given location.
+// code should be a 3xx status code such as http.StatusMovedPermanently.
+func (d *Document) RedirectResponse(location string, code int) *Document {
+	return d.SetResponseHeader("Location", location).SetResponseStatus(code)
+}
+
+// resetResponse clears the response metadata at the start of a new navigation.
+func resetResponse(d *Document) {
+	e := d.AsElement()
+	e.Properties.Delete(Namespace.Internals, "response-status")
+	e.Properties.Delete(Namespace.Internals, "response-headers")
+}
+
+// writeResponseHeader writes the declared headers and the status code to the http.ResponseWriter.
+func writeResponseHeader(d *Document, w http.ResponseWriter) {
+	for k, v := range d.ResponseHeaders() {
+		for _, s := range v {
+			w.Header().Add(k, s)
+		}
+	}
+	w.WriteHeader(d.ResponseStatus())
+}
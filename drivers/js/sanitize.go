@@ -0,0 +1,189 @@
+package doc
+
+import (
+	"log"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// URL sanitization
+//
+// Attributes such as href or src may be set from data and are reactive. Their values are therefore checked
+// centrally, whenever an attribute or the corresponding IDL property is set, so that every constructor benefits.
+// URLs with a dangerous scheme (javascript:, vbscript:, data: outside of media sources and icons) are blocked and
+// replaced by BlockedURL.
+
+// URLAttributes lists the attributes whose values are URLs and should be sanitized.
+var URLAttributes = newset("href", "src", "action", "formaction", "poster", "cite", "data", "background", "ping", "manifest", "xlink:href")
+
+// AllowedURLSchemes lists the URL schemes that are accepted for URL attributes.
+// Relative URLs are always accepted.
+var AllowedURLSchemes = newset("http", "https", "mailto", "tel", "ftp", "blob")
+
+// URLAllowlist is an optional hook that may be set to accept URLs that would otherwise be blocked.
+// It is called before the default checks. If it returns true, the URL is accepted as is.
+// The element is nil for the script URLs checked by the Trusted Types policy.
+var URLAllowlist func(e *ui.Element, attr string, rawurl string) bool
+
+// BlockedURL is the value that replaces a URL which failed validation.
+const BlockedURL = "about:blank#blocked"
+
+// SanitizeURL checks the value of a URL attribute and returns the value that should be used.
+// The boolean is false if the URL was blocked.
+func SanitizeURL(e *ui.Element, attr string, rawurl string) (string, bool) {
+	attr = strings.ToLower(attr)
+	if URLAllowlist != nil && URLAllowlist(e, attr, rawurl) {
+		return rawurl, true
+	}
+
+	scheme, ok := urlScheme(rawurl)
+	if !ok {
+		return rawurl, true // relative URL
+	}
+
+	switch scheme {
+	case "javascript", "vbscript":
+		return BlockedURL, false
+	case "data":
+		if dataURLAllowed(e, attr, rawurl) {
+			return rawurl, true
+		}
+		return BlockedURL, false
+	}
+
+	if AllowedURLSchemes.Contains(scheme) {
+		return rawurl, true
+	}
+	return BlockedURL, false
+}
+
+// mediaElements lists the elements whose sources may be data URLs.
+var mediaElements = newset("img", "video", "audio", "source", "track")
+
+// dataURLAllowed reports whether a data URL is accepted for the attribute of an element.
+// data URLs are only accepted as sources of media elements, with a media type matching the element, and
+// as the target of icon links with an image media type. Elsewhere, e.g. as the src of an iframe, an
+// image/svg+xml document could run script.
+// The rel attribute of a link has therefore to be set before its href.
+func dataURLAllowed(e *ui.Element, attr string, rawurl string) bool {
+	if e == nil {
+		return false
+	}
+	mediatype := strings.ToLower(strings.TrimSpace(strings.SplitN(rawurl, ":", 2)[1]))
+	image := strings.HasPrefix(mediatype, "image/")
+
+	etype := elementType(e)
+	switch {
+	case mediaElements.Contains(etype):
+		if attr != "src" && attr != "poster" {
+			return false
+		}
+		if etype == "track" {
+			return strings.HasPrefix(mediatype, "text/vtt")
+		}
+		return image || strings.HasPrefix(mediatype, "video/") || strings.HasPrefix(mediatype, "audio/")
+	case etype == "link":
+		if attr != "href" || !image {
+			return false
+		}
+		rel, _ := attrValue(e, "rel")
+		for _, r := range strings.Fields(strings.ToLower(rel)) {
+			if r == "icon" || r == "apple-touch-icon" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// urlScheme returns the lowercased scheme of a URL if it has one.
+// Control characters and whitespace are ignored as browsers do when parsing URLs.
+func urlScheme(rawurl string) (string, bool) {
+	var b strings.Builder
+	for _, r := range rawurl {
+		if r <= ' ' || r == 0x7f {
+			continue
+		}
+		if r == ':' {
+			s := strings.ToLower(b.String())
+			if s == "" {
+				return "", false
+			}
+			return s, true
+		}
+		if r == '/' || r == '?' || r == '#' {
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	return "", false
+}
+
+func sanitizeAttribute(e *ui.Element, attr string, value string) string {
+	attr = strings.ToLower(attr)
+	if !URLAttributes.Contains(attr) {
+		return value
+	}
+	v, ok := SanitizeURL(e, attr, value)
+	if !ok {
+		log.Printf("blocked unsafe URL for attribute %s of element %s: %q", attr, e.ID, value)
+	}
+	return v
+}
+
+// Trusted Types support
+//
+// When a policy is enabled and the browser supports the Trusted Types API, script sources are wrapped into
+// TrustedScriptURL objects, after having been sanitized. This allows apps to serve a
+// require-trusted-types-for CSP directive.
+// The policy does not create TrustedHTML: markup has to be built from elements. It only creates TrustedScript
+// objects if TrustedScriptAllowlist is set, for the scripts it accepts.
+
+// TrustedScriptAllowlist is an optional hook deciding which inline scripts the Trusted Types policy accepts.
+var TrustedScriptAllowlist func(script string) bool
+
+var trustedTypesPolicy js.Value
+
+// EnableTrustedTypes creates a Trusted Types policy with the given name, if supported by the browser.
+func EnableTrustedTypes(policyname string) {
+	if !InBrowser() {
+		return
+	}
+	tt := js.Global().Get("trustedTypes")
+	if !tt.Truthy() {
+		return
+	}
+	rules := map[string]interface{}{
+		"createScriptURL": js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			u, ok := SanitizeURL(nil, "src", args[0].String())
+			if !ok {
+				log.Printf("blocked unsafe script URL: %q", args[0].String())
+			}
+			return u
+		}),
+	}
+	if TrustedScriptAllowlist != nil {
+		rules["createScript"] = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			if !TrustedScriptAllowlist(args[0].String()) {
+				log.Print("blocked untrusted inline script")
+				return ""
+			}
+			return args[0].String()
+		})
+	}
+	trustedTypesPolicy = tt.Call("createPolicy", policyname, rules)
+}
+
+// trustedValue returns the value to be set on the native element, wrapped by the Trusted Types policy
+// when needed.
+func trustedValue(e *ui.Element, attr string, value string) interface{} {
+	if !trustedTypesPolicy.Truthy() {
+		return value
+	}
+	if strings.ToLower(attr) == "src" && elementType(e) == "script" {
+		return trustedTypesPolicy.Call("createScriptURL", value)
+	}
+	return value
+}
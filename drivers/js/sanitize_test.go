@@ -0,0 +1,60 @@
+package doc
+
+import (
+	"testing"
+
+	ui "github.com/atdiar/particleui"
+)
+
+func TestSanitizeDataURL(t *testing.T) {
+	c := ui.NewConfiguration("sanitizetest", "test")
+	newelement := func(id string, etype string, attrs ...string) *ui.Element {
+		e := c.NewElement(id, "test")
+		e.Set(Namespace.Internals, "constructor", ui.String(etype))
+		m := ui.NewObject()
+		for i := 0; i+1 < len(attrs); i += 2 {
+			m.Set(attrs[i], ui.String(attrs[i+1]))
+		}
+		e.SetData("attrs", m.Commit())
+		return e
+	}
+
+	tcs := []struct {
+		element *ui.Element
+		attr    string
+		url     string
+		allowed bool
+	}{
+		{newelement("img", "img"), "src", "data:image/png;base64,iVBORw0KGgo=", true},
+		{newelement("video", "video"), "poster", "data:image/jpeg;base64,/9j/4AAQ", true},
+		{newelement("source", "source"), "src", "data:audio/ogg;base64,T2dnUw==", true},
+		{newelement("imgtext", "img"), "src", "data:text/html,<script>alert(1)</script>", false},
+		{newelement("iframe", "iframe"), "src", "data:image/svg+xml,<svg onload=alert(1)></svg>", false},
+		{newelement("embed", "embed"), "src", "data:image/svg+xml;base64,PHN2Zz4=", false},
+		{newelement("object", "object"), "data", "data:image/svg+xml;base64,PHN2Zz4=", false},
+		{newelement("icon", "link", "rel", "shortcut icon"), "href", "data:image/png;base64,iVBORw0KGgo=", true},
+		{newelement("iconhtml", "link", "rel", "icon"), "href", "data:text/html,<script>alert(1)</script>", false},
+		{newelement("favicon", "link", "rel", "icon"), "href", defaultFavicon, true},
+		{newelement("iconuntyped", "link", "rel", "icon"), "href", "data:;base64,iVBORw0KGgo=", false},
+		{newelement("stylesheet", "link", "rel", "stylesheet"), "href", "data:text/html,<script>alert(1)</script>", false},
+		{newelement("preload", "link", "rel", "preload"), "href", "data:image/png;base64,iVBORw0KGgo=", false},
+		{nil, "src", "data:image/png;base64,iVBORw0KGgo=", false},
+	}
+
+	for _, tc := range tcs {
+		u, ok := SanitizeURL(tc.element, tc.attr, tc.url)
+		if ok != tc.allowed {
+			t.Errorf("%s of %s: got allowed=%v, expected %v", tc.attr, elementTypeOf(tc.element), ok, tc.allowed)
+		}
+		if !ok && u != BlockedURL {
+			t.Errorf("blocked URL should be replaced by %q, got %q", BlockedURL, u)
+		}
+	}
+}
+
+func elementTypeOf(e *ui.Element) string {
+	if e == nil {
+		return "<nil>"
+	}
+	return elementType(e)
+}
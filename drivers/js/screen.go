@@ -0,0 +1,144 @@
+package doc
+
+import (
+	"context"
+	"errors"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Screen orientation and wake lock
+//
+// The orientation of the screen is exposed as the "orientation" ui property of the document: an Object
+// holding its "type" ("portrait-primary", "landscape-secondary"...) and its "angle" in degrees.
+// LockOrientation locks the screen in an orientation, which browsers usually only allow for installed apps or
+// in fullscreen mode.
+//
+// KeepAwake prevents the screen from dimming or locking until its context is done. Browsers release the wake
+// lock when the page is hidden: it is requested again whenever the page becomes visible. While the screen is
+// kept awake, the "wakelock" ui property of the document is true.
+//
+// LockOrientation and KeepAwake wait for the browser and should not be called from the UI thread (use
+// DoAsync).
+
+var (
+	ErrOrientationUnsupported = errors.New("screen orientation API is not supported")
+	ErrWakeLockUnsupported    = errors.New("wake lock API is not supported")
+)
+
+// wakeLocks counts the active KeepAwake calls per document.
+var wakeLocks = newscsmap[*ui.Element, int]()
+
+func screenOrientation() (js.Value, bool) {
+	if !InBrowser() {
+		return js.Undefined(), false
+	}
+	o := js.Global().Get("screen").Get("orientation")
+	return o, o.Truthy()
+}
+
+func orientationSupport(d *Document) {
+	o, ok := screenOrientation()
+	if !ok {
+		return
+	}
+	update := func() {
+		d.SetUI("orientation", ui.NewObject().
+			Set("type", ui.String(o.Get("type").String())).
+			Set("angle", ui.Number(o.Get("angle").Float())).
+			Commit())
+	}
+	update()
+	o.Call("addEventListener", "change", RegisterCallback(d.AsElement(), func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(update)
+		return nil
+	}))
+}
+
+// Orientation returns the type of the screen orientation, e.g. "portrait-primary", or "" if unknown.
+func (d *Document) Orientation() string {
+	v, ok := d.GetUI("orientation")
+	if !ok {
+		return ""
+	}
+	return string(v.(ui.Object).MustGetString("type"))
+}
+
+// LockOrientation locks the screen in an orientation: "portrait", "landscape", "portrait-primary"...
+func (d *Document) LockOrientation(ctx context.Context, orientation string) error {
+	o, ok := screenOrientation()
+	if !ok || o.Get("lock").IsUndefined() {
+		return ErrOrientationUnsupported
+	}
+	_, err := awaitPromise(ctx, o.Call("lock", orientation))
+	return err
+}
+
+// UnlockOrientation lets the screen follow the orientation of the device again.
+func (d *Document) UnlockOrientation() {
+	o, ok := screenOrientation()
+	if !ok || o.Get("unlock").IsUndefined() {
+		return
+	}
+	o.Call("unlock")
+}
+
+// KeepAwake keeps the screen awake until the context is done. It returns once the wake lock is acquired.
+func (d *Document) KeepAwake(ctx context.Context) error {
+	if !InBrowser() {
+		return ErrWakeLockUnsupported
+	}
+	wl := js.Global().Get("navigator").Get("wakeLock")
+	if !wl.Truthy() {
+		return ErrWakeLockUnsupported
+	}
+	lock, err := awaitPromise(ctx, wl.Call("request", "screen"))
+	if err != nil {
+		return err
+	}
+
+	reacquire := ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if string(evt.NewValue().(ui.String)) != "visible" || !lock.Get("released").Bool() {
+			return false
+		}
+		go func() {
+			l, err := awaitPromise(ctx, wl.Call("request", "screen"))
+			if err != nil {
+				DEBUG("unable to acquire the wake lock: ", err)
+				return
+			}
+			ui.DoSync(func() {
+				if ctx.Err() != nil {
+					l.Call("release")
+					return
+				}
+				lock = l
+			})
+		}()
+		return false
+	})
+
+	ui.DoSync(func() {
+		n, _ := wakeLocks.Get(d.AsElement())
+		wakeLocks.Set(d.AsElement(), n+1)
+		d.SetUI("wakelock", ui.Bool(true))
+		d.Watch(Namespace.UI, "visibilitystate", d, reacquire)
+	})
+
+	go func() {
+		<-ctx.Done()
+		ui.DoSync(func() {
+			d.RemoveMutationHandler(Namespace.UI, "visibilitystate", d, reacquire)
+			lock.Call("release").Call("catch", js.Global().Get("Function").New())
+			n, _ := wakeLocks.Get(d.AsElement())
+			if n--; n > 0 {
+				wakeLocks.Set(d.AsElement(), n)
+				return
+			}
+			wakeLocks.Delete(d.AsElement())
+			d.SetUI("wakelock", ui.Bool(false))
+		})
+	}()
+	return nil
+}
@@ -0,0 +1,223 @@
+package doc
+
+import (
+	"math"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Scroll containers
+//
+// ScrollTo, ScrollBy and ScrollToChild scroll any scrollable element. The html and body elements, as well as
+// the document itself, stand for the scrolling element of the document.
+// Scroll requests go through window.queueScroll: they are applied in a microtask, once the pending DOM updates
+// are done.
+//
+// When the scroll is over, a "scroll-settled" event is triggered on the container, with an Object holding its
+// final "x" and "y" positions. The native scrollend event is used where supported. Otherwise, the scroll is
+// considered over once the position has not changed for ScrollSettleDelay.
+
+// ScrollSettleDelay is the duration without position change after which a scroll is considered over, in
+// browsers which do not support the scrollend event.
+var ScrollSettleDelay = 100 * time.Millisecond
+
+// ScrollBehavior determines whether a scroll is animated.
+type ScrollBehavior string
+
+const (
+	ScrollAuto    ScrollBehavior = "auto"
+	ScrollSmooth  ScrollBehavior = "smooth"
+	ScrollInstant ScrollBehavior = "instant"
+)
+
+// ScrollAlign determines where a child ends up within its scroll container.
+type ScrollAlign string
+
+const (
+	AlignStart   ScrollAlign = "start"
+	AlignCenter  ScrollAlign = "center"
+	AlignEnd     ScrollAlign = "end"
+	AlignNearest ScrollAlign = "nearest" // scrolls as little as possible, not at all if the child is visible
+)
+
+// ScrollTo scrolls a container to the given position.
+func ScrollTo(e *ui.Element, x, y float64, behavior ScrollBehavior) {
+	n, ok := scrollNode(e)
+	if !ok {
+		return
+	}
+	scrollTo(e, n, x, y, behavior)
+}
+
+// ScrollBy scrolls a container by the given offsets.
+func ScrollBy(e *ui.Element, dx, dy float64, behavior ScrollBehavior) {
+	n, ok := scrollNode(e)
+	if !ok {
+		return
+	}
+	scrollTo(e, n, n.Get("scrollLeft").Float()+dx, n.Get("scrollTop").Float()+dy, behavior)
+}
+
+// ScrollToChild scrolls the closest scrollable ancestor of an element so that the element is aligned as
+// requested. It returns the scrolled container, or nil if the element is not rendered.
+func ScrollToChild(child *ui.Element, align ScrollAlign, behavior ScrollBehavior) *ui.Element {
+	c, ok := JSValue(child)
+	if !ok {
+		return nil
+	}
+	container, n := scrollContainer(child)
+	if container == nil {
+		return nil
+	}
+
+	doc := js.Global().Get("document")
+	crect := c.Call("getBoundingClientRect")
+	var top, left, height, width float64
+	if n.Equal(doc.Get("scrollingElement")) {
+		height, width = js.Global().Get("innerHeight").Float(), js.Global().Get("innerWidth").Float()
+	} else {
+		r := n.Call("getBoundingClientRect")
+		top, left = r.Get("top").Float()+n.Get("clientTop").Float(), r.Get("left").Float()+n.Get("clientLeft").Float()
+		height, width = n.Get("clientHeight").Float(), n.Get("clientWidth").Float()
+	}
+
+	// position of the child within the scrolled content
+	y := crect.Get("top").Float() - top + n.Get("scrollTop").Float()
+	x := crect.Get("left").Float() - left + n.Get("scrollLeft").Float()
+	h, w := crect.Get("height").Float(), crect.Get("width").Float()
+
+	tx := alignOffset(x, w, n.Get("scrollLeft").Float(), width, align)
+	ty := alignOffset(y, h, n.Get("scrollTop").Float(), height, align)
+	scrollTo(container, n, tx, ty, behavior)
+	return container
+}
+
+// alignOffset returns the scroll position aligning an item at pos, of the given size, within a viewport.
+func alignOffset(pos, size, current, viewport float64, align ScrollAlign) float64 {
+	switch align {
+	case AlignCenter:
+		return pos - (viewport-size)/2
+	case AlignEnd:
+		return pos + size - viewport
+	case AlignNearest:
+		if pos >= current && pos+size <= current+viewport {
+			return current
+		}
+		if pos < current || size > viewport {
+			return pos
+		}
+		return pos + size - viewport
+	}
+	return pos
+}
+
+func scrollTo(e *ui.Element, n js.Value, x, y float64, behavior ScrollBehavior) {
+	maxx := math.Max(0, n.Get("scrollWidth").Float()-n.Get("clientWidth").Float())
+	maxy := math.Max(0, n.Get("scrollHeight").Float()-n.Get("clientHeight").Float())
+	x = math.Min(math.Max(0, x), maxx)
+	y = math.Min(math.Max(0, y), maxy)
+
+	if behavior == "" {
+		behavior = ScrollAuto
+	}
+	if q := js.Global().Get("queueScroll"); q.Truthy() {
+		q.Invoke(n, x, y, string(behavior))
+	} else {
+		n.Call("scrollTo", map[string]interface{}{"left": x, "top": y, "behavior": string(behavior)})
+	}
+
+	if math.Abs(n.Get("scrollLeft").Float()-x) < 1 && math.Abs(n.Get("scrollTop").Float()-y) < 1 {
+		scrollSettled(e, n)
+		return
+	}
+	watchScrollSettle(e, n)
+}
+
+func scrollSettled(e *ui.Element, n js.Value) {
+	e.TriggerEvent("scroll-settled", ui.NewObject().
+		Set("x", ui.Number(n.Get("scrollLeft").Float())).
+		Set("y", ui.Number(n.Get("scrollTop").Float())).
+		Commit())
+}
+
+// watchScrollSettle triggers the scroll-settled event once the ongoing scroll of a container is over.
+func watchScrollSettle(e *ui.Element, n js.Value) {
+	target := n
+	if n.Equal(js.Global().Get("document").Get("scrollingElement")) {
+		target = js.Global().Get("document")
+	}
+
+	if !js.Global().Get("onscrollend").IsUndefined() {
+		var cb js.Func
+		cb = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			cb.Release()
+			go ui.DoSync(func() { scrollSettled(e, n) })
+			return nil
+		})
+		target.Call("addEventListener", "scrollend", cb, map[string]interface{}{"once": true})
+		return
+	}
+
+	go func() {
+		var x, y float64 = -1, -1
+		for {
+			time.Sleep(ScrollSettleDelay)
+			done := false
+			ui.DoSync(func() {
+				nx, ny := n.Get("scrollLeft").Float(), n.Get("scrollTop").Float()
+				if nx == x && ny == y {
+					done = true
+					scrollSettled(e, n)
+					return
+				}
+				x, y = nx, ny
+			})
+			if done {
+				return
+			}
+		}
+	}()
+}
+
+// scrollNode returns the native element scrolled for a container.
+func scrollNode(e *ui.Element) (js.Value, bool) {
+	if !InBrowser() {
+		return js.Value{}, false
+	}
+	n, ok := JSValue(e)
+	if !ok {
+		return n, false
+	}
+	if e.IsRoot() || n.Get("tagName").String() == "BODY" || n.Get("tagName").String() == "HTML" {
+		return js.Global().Get("document").Get("scrollingElement"), true
+	}
+	return n, true
+}
+
+// scrollContainer returns the closest scrollable ancestor of an element, or the document.
+func scrollContainer(e *ui.Element) (*ui.Element, js.Value) {
+	if !InBrowser() {
+		return nil, js.Value{}
+	}
+	for p := e.Parent; p != nil; p = p.Parent {
+		n, ok := JSValue(p)
+		if !ok {
+			continue
+		}
+		if p.IsRoot() || n.Get("tagName").String() == "BODY" {
+			break
+		}
+		style := js.Global().Call("getComputedStyle", n)
+		scrollable := func(overflow string, scroll, client string) bool {
+			o := style.Get(overflow).String()
+			return (o == "auto" || o == "scroll") && n.Get(scroll).Float() > n.Get(client).Float()
+		}
+		if scrollable("overflowY", "scrollHeight", "clientHeight") || scrollable("overflowX", "scrollWidth", "clientWidth") {
+			return p, n
+		}
+	}
+	d := GetDocument(e)
+	return d.AsElement(), js.Global().Get("document").Get("scrollingElement")
+}
@@ -0,0 +1,164 @@
+package doc
+
+import (
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Scroll locking
+//
+// LockScroll prevents the document from scrolling while a dialog, a menu or a drawer is displayed.
+// Locks nest: the document is unlocked once every lock has been released.
+//
+// While locked, the root element does not overflow. The scrollbar that disappears is compensated by a padding
+// on the body so that the layout does not shift. Its width is also available to fixed-position elements as the
+// --zui-scrollbar-width CSS variable of the root element. Overlay scrollbars take no room and need no
+// compensation.
+// On iOS, where hiding the overflow does not prevent the viewport from scrolling, the body is fixed at the
+// current scroll offset instead.
+// The scroll position is restored once unlocked.
+
+type scrollLock struct {
+	count   int
+	x, y    float64
+	fixed   bool
+	inline  map[string]string // saved inline styles of the body
+	overflw string            // saved inline overflow of the root element
+}
+
+var scrollLocks = newscsmap[*Document, *scrollLock]()
+
+// LockScroll prevents the document from scrolling until the returned function is called.
+// Calling the returned function more than once has no effect.
+func LockScroll(d *Document) (unlock func()) {
+	l, ok := scrollLocks.Get(d)
+	if !ok {
+		l = &scrollLock{}
+		scrollLocks.Set(d, l)
+	}
+	l.count++
+	if l.count == 1 {
+		l.lock()
+	}
+
+	var released bool
+	return func() {
+		if released {
+			return
+		}
+		released = true
+		l.count--
+		if l.count == 0 {
+			l.unlock()
+		}
+	}
+}
+
+// IsScrollLocked returns whether the scrolling of the document is currently locked.
+func IsScrollLocked(d *Document) bool {
+	l, ok := scrollLocks.Get(d)
+	return ok && l.count > 0
+}
+
+var scrollLockBodyProperties = []string{"padding-right", "position", "top", "left", "right"}
+
+func (l *scrollLock) lock() {
+	if !InBrowser() {
+		return
+	}
+	w := js.Global()
+	root := w.Get("document").Get("documentElement")
+	body := w.Get("document").Get("body")
+
+	l.x, l.y = w.Get("scrollX").Float(), w.Get("scrollY").Float()
+	l.overflw = root.Get("style").Call("getPropertyValue", "overflow").String()
+	l.inline = make(map[string]string, len(scrollLockBodyProperties))
+	for _, p := range scrollLockBodyProperties {
+		l.inline[p] = body.Get("style").Call("getPropertyValue", p).String()
+	}
+
+	scrollbar := w.Get("innerWidth").Float() - root.Get("clientWidth").Float()
+	if scrollbar < 0 {
+		scrollbar = 0
+	}
+	root.Get("style").Call("setProperty", "--zui-scrollbar-width", px(scrollbar))
+	if scrollbar > 0 {
+		padding := w.Call("parseFloat", w.Call("getComputedStyle", body).Get("paddingRight")).Float()
+		body.Get("style").Call("setProperty", "padding-right", px(padding+scrollbar))
+	}
+
+	root.Get("style").Call("setProperty", "overflow", "hidden")
+	l.fixed = isIOS()
+	if l.fixed {
+		s := body.Get("style")
+		s.Call("setProperty", "position", "fixed")
+		s.Call("setProperty", "top", px(-l.y))
+		s.Call("setProperty", "left", px(-l.x))
+		s.Call("setProperty", "right", "0")
+	}
+}
+
+func (l *scrollLock) unlock() {
+	if !InBrowser() {
+		return
+	}
+	w := js.Global()
+	root := w.Get("document").Get("documentElement")
+	body := w.Get("document").Get("body")
+
+	restoreProperty(root.Get("style"), "overflow", l.overflw)
+	root.Get("style").Call("removeProperty", "--zui-scrollbar-width")
+	for p, v := range l.inline {
+		restoreProperty(body.Get("style"), p, v)
+	}
+	w.Call("scrollTo", map[string]interface{}{"left": l.x, "top": l.y, "behavior": "instant"})
+}
+
+func restoreProperty(style js.Value, name, value string) {
+	if value == "" {
+		style.Call("removeProperty", name)
+		return
+	}
+	style.Call("setProperty", name, value)
+}
+
+func px(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64) + "px"
+}
+
+// isIOS reports whether the document runs on iOS or iPadOS, which reports itself as macOS but has touch points.
+func isIOS() bool {
+	nav := js.Global().Get("navigator")
+	platform := nav.Get("platform").String()
+	switch platform {
+	case "iPhone", "iPad", "iPod":
+		return true
+	case "MacIntel":
+		return nav.Get("maxTouchPoints").Int() > 1
+	}
+	return false
+}
+
+// LockScrollWhileMounted returns an element modifier locking the scrolling of the document while the element
+// is mounted, e.g. for menus and drawers.
+func (m modifier) LockScrollWhileMounted() func(e *ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		var unlock func()
+		e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if unlock == nil {
+				unlock = LockScroll(GetDocument(evt.Origin()))
+			}
+			return false
+		}))
+		e.OnUnmounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if unlock != nil {
+				unlock()
+				unlock = nil
+			}
+			return false
+		}))
+		return e
+	}
+}
@@ -0,0 +1,206 @@
+package doc
+
+import (
+	"math"
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Scroll progress
+//
+// TrackScrollProgress maintains the "scrollprogress" ui property (ui.Number) of the document, or of a scroll
+// container when used as a Modifier: the ratio between the vertical scroll position and the maximum scroll
+// position, from 0 at the top to 1 at the bottom. A container whose content does not overflow is at 0.
+//
+// The scroll and resize events only request an animation frame, in which the position is read, so that the
+// property changes at most once per frame, and only when the value, rounded to a thousandth, changes.
+// Like the other properties derived from the layout, it is excluded from the mutation capture.
+//
+// ReadingProgressBar returns a bar whose width follows the scroll progress, as commonly found on top of
+// articles.
+
+// ReadingProgressStyleSheetID is the id of the stylesheet holding the reading progress bar rules.
+const ReadingProgressStyleSheetID = "zui-reading-progress"
+
+var scrollProgressTracked = newscsmap[*ui.Element, bool]()
+var scrollProgressConfigs = newscsmap[*ui.Configuration, bool]()
+
+// TrackScrollProgress maintains the "scrollprogress" ui property of the document.
+func (d *Document) TrackScrollProgress() *Document {
+	e := d.AsElement()
+	if !InBrowser() || !startScrollProgress(e) {
+		return d
+	}
+	request := scrollProgressRequester(e)
+	d.Window().AsElement().AddEventListener("scroll", ui.NewEventHandler(func(evt ui.Event) bool {
+		request()
+		return false
+	}).AsPassive())
+	d.Window().AsElement().AddEventListener("resize", ui.NewEventHandler(func(evt ui.Event) bool {
+		request()
+		return false
+	}).AsPassive())
+	request()
+	return d
+}
+
+// TrackScrollProgress returns an element modifier maintaining the "scrollprogress" ui property of a scroll
+// container.
+func (m modifier) TrackScrollProgress() func(e *ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		if !InBrowser() || !startScrollProgress(e) {
+			return e
+		}
+		request := scrollProgressRequester(e)
+		h := ui.NewEventHandler(func(evt ui.Event) bool {
+			request()
+			return false
+		}).AsPassive()
+		e.AddEventListener("scroll", h)
+
+		e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			GetDocument(e).Window().AsElement().AddEventListener("resize", h)
+			request()
+			return false
+		}))
+		e.OnUnmounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			GetDocument(e).Window().AsElement().RemoveEventListener("resize", h)
+			return false
+		}))
+		return e
+	}
+}
+
+// ScrollProgress returns the last recorded scroll progress of the document or of a tracked scroll container.
+func ScrollProgress(e *ui.Element) float64 {
+	v, ok := e.GetUI("scrollprogress")
+	if !ok {
+		return 0
+	}
+	return float64(v.(ui.Number))
+}
+
+// startScrollProgress registers an element as tracked. It returns false if it already was.
+func startScrollProgress(e *ui.Element) bool {
+	if _, ok := scrollProgressTracked.Get(e); ok {
+		return false
+	}
+	scrollProgressTracked.Set(e, true)
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		scrollProgressTracked.Delete(evt.Origin())
+		return false
+	}).RunOnce())
+
+	if _, ok := scrollProgressConfigs.Get(e.Configuration); !ok {
+		scrollProgressConfigs.Set(e.Configuration, true)
+		e.Configuration.ExcludeFromCapture(ui.CaptureFilter{Category: Namespace.UI, Property: "scrollprogress"})
+	}
+	return true
+}
+
+// scrollProgressRequester returns a function which schedules an update of the scroll progress of an element
+// at the next animation frame, unless one is already pending.
+func scrollProgressRequester(e *ui.Element) func() {
+	var pending bool
+	frame := RegisterCallback(e, func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			pending = false
+			updateScrollProgress(e)
+		})
+		return nil
+	})
+	return func() {
+		if pending {
+			return
+		}
+		pending = true
+		js.Global().Call("requestAnimationFrame", frame)
+	}
+}
+
+func updateScrollProgress(e *ui.Element) {
+	n, ok := scrollNode(e)
+	if !ok {
+		return
+	}
+	var progress float64
+	if max := n.Get("scrollHeight").Float() - n.Get("clientHeight").Float(); max > 0 {
+		progress = math.Min(1, math.Max(0, n.Get("scrollTop").Float()/max))
+	}
+	progress = math.Round(progress*1000) / 1000
+	if v, ok := e.GetUI("scrollprogress"); ok && float64(v.(ui.Number)) == progress {
+		return
+	}
+	e.SetUI("scrollprogress", ui.Number(progress))
+}
+
+// ReadingProgressOptions configures a reading progress bar.
+type ReadingProgressOptions struct {
+	ID     string      // "zui-reading-progress-bar" by default
+	Source *ui.Element // tracked scroll container, the document by default
+	Color  string      // "Highlight" by default
+	Height string      // "3px" by default
+}
+
+// ReadingProgressBar returns a progressbar following the scroll progress of the document, or of a scroll
+// container. By default, it is fixed at the top of the viewport; it only has to be appended somewhere in the
+// document. Its width is driven by a custom property of the native element, so that the updates do not go
+// through the mutation capture.
+func (d *Document) ReadingProgressBar(opts ...ReadingProgressOptions) DivElement {
+	var o ReadingProgressOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.ID == "" {
+		o.ID = "zui-reading-progress-bar"
+	}
+	if o.Color == "" {
+		o.Color = "Highlight"
+	}
+	if o.Height == "" {
+		o.Height = "3px"
+	}
+	source := o.Source
+	if source == nil {
+		d.TrackScrollProgress()
+		source = d.AsElement()
+	} else {
+		Modifier.TrackScrollProgress()(source)
+	}
+
+	bar := d.Div.WithID(o.ID)
+	AddClass(bar.AsElement(), "zui-reading-progress")
+	SetAttribute(bar.AsElement(), "role", "progressbar")
+	SetAttribute(bar.AsElement(), "aria-label", "Reading progress")
+	SetAttribute(bar.AsElement(), "aria-valuemin", "0")
+	SetAttribute(bar.AsElement(), "aria-valuemax", "100")
+	readingProgressStyleSheet(d)
+	SetInlineCSS(bar.AsElement(), "--zui-reading-progress-color: "+o.Color+"; --zui-reading-progress-height: "+o.Height+";")
+
+	bar.AsElement().Watch(Namespace.UI, "scrollprogress", source, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		n, ok := JSValue(bar.AsElement())
+		if !ok {
+			return false
+		}
+		p := float64(evt.NewValue().(ui.Number))
+		n.Get("style").Call("setProperty", "--zui-scroll-progress", strconv.FormatFloat(p, 'f', 3, 64))
+		n.Call("setAttribute", "aria-valuenow", strconv.Itoa(int(math.Round(p*100))))
+		return false
+	}))
+	return bar
+}
+
+func readingProgressStyleSheet(d *Document) {
+	if _, ok := d.GetStyleSheet(ReadingProgressStyleSheetID); ok {
+		return
+	}
+	sheet := d.NewStyleSheet(ReadingProgressStyleSheetID)
+	actives := append([]string{ReadingProgressStyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+
+	sheet.InsertRule(".zui-reading-progress", "position: fixed; top: 0; left: 0; right: 0; height: var(--zui-reading-progress-height); z-index: 2147483646; pointer-events: none; background: var(--zui-reading-progress-color); transform-origin: 0 50%; transform: scaleX(var(--zui-scroll-progress, 0));")
+	sheet.InsertMediaRule("(prefers-reduced-motion: no-preference)", ".zui-reading-progress", "transition: transform 0.1s linear;")
+	sheet.Update()
+}
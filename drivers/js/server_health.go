@@ -0,0 +1,290 @@
+//go:build server && (csr || ssr || ssg)
+
+package doc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Health, readiness and metrics
+//
+// The server exposes operational endpoints, so that it can be deployed behind an orchestrator or a load
+// balancer without being wrapped:
+//   - /healthz answers 200 as long as the server process serves requests,
+//   - /readyz answers 200 once the server listens and every readiness check passes, and 503 otherwise,
+//     including while the server shuts down,
+//   - /metrics exposes metrics in the Prometheus text format: request counts and durations, SSR render
+//     durations, and cache hits and misses reported with RecordCacheAccess.
+//
+// The paths are set with WithOperationalEndpoints. An empty path disables its endpoint, and
+// WithoutOperationalEndpoints disables them all.
+
+// OperationalEndpoints holds the paths of the operational endpoints.
+type OperationalEndpoints struct {
+	Health  string
+	Ready   string
+	Metrics string
+}
+
+var operationalEndpoints = OperationalEndpoints{Health: "/healthz", Ready: "/readyz", Metrics: "/metrics"}
+
+// ReadinessTimeout bounds the time taken by the readiness checks of a /readyz request.
+var ReadinessTimeout = 2 * time.Second
+
+// WithOperationalEndpoints returns a build environment modifier setting the paths of the operational endpoints.
+func WithOperationalEndpoints(e OperationalEndpoints) func() {
+	return func() {
+		operationalEndpoints = e
+	}
+}
+
+// WithoutOperationalEndpoints returns a build environment modifier disabling the operational endpoints.
+func WithoutOperationalEndpoints() func() {
+	return WithOperationalEndpoints(OperationalEndpoints{})
+}
+
+var serverReady atomic.Bool
+
+var readinessChecks = struct {
+	sync.Mutex
+	checks map[string]func(ctx context.Context) error
+}{checks: make(map[string]func(ctx context.Context) error)}
+
+// AddReadinessCheck registers a check which must pass for the server to be ready, e.g. a database ping.
+func AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	readinessChecks.Lock()
+	defer readinessChecks.Unlock()
+	readinessChecks.checks[name] = check
+}
+
+// RecordCacheAccess counts a hit or a miss of a cache, reported in the metrics.
+func RecordCacheAccess(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	serverMetrics.cache.inc(cache + "\x00" + result)
+}
+
+// histogramBuckets are the upper bounds, in seconds, of the duration histograms.
+var histogramBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(histogramBuckets))}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	s := d.Seconds()
+	h.Lock()
+	defer h.Unlock()
+	for i, b := range histogramBuckets {
+		if s <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += s
+	h.count++
+}
+
+func (h *histogram) write(w io.Writer, name string) {
+	h.Lock()
+	defer h.Unlock()
+	for i, b := range histogramBuckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(b, 'f', -1, 64), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.sum, 'f', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+type counters struct {
+	sync.Mutex
+	m map[string]uint64
+}
+
+func (c *counters) inc(key string) {
+	c.Lock()
+	c.m[key]++
+	c.Unlock()
+}
+
+func (c *counters) sorted() ([]string, map[string]uint64) {
+	c.Lock()
+	defer c.Unlock()
+	keys := make([]string, 0, len(c.m))
+	m := make(map[string]uint64, len(c.m))
+	for k, v := range c.m {
+		keys = append(keys, k)
+		m[k] = v
+	}
+	sort.Strings(keys)
+	return keys, m
+}
+
+var serverMetrics = struct {
+	requests  counters
+	durations *histogram
+	renders   *histogram
+	cache     counters
+	inflight  atomic.Int64
+}{
+	requests:  counters{m: make(map[string]uint64)},
+	durations: newHistogram(),
+	renders:   newHistogram(),
+	cache:     counters{m: make(map[string]uint64)},
+}
+
+// observeRender records the duration of a server-side render.
+func observeRender(d time.Duration) {
+	serverMetrics.renders.observe(d)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// instrument counts the requests served by h and measures their duration.
+func instrument(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case operationalEndpoints.Health, operationalEndpoints.Ready, operationalEndpoints.Metrics:
+			h.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		serverMetrics.inflight.Add(1)
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			serverMetrics.inflight.Add(-1)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			serverMetrics.requests.inc(r.Method + "\x00" + strconv.Itoa(rec.status))
+			serverMetrics.durations.observe(time.Since(start))
+		}()
+		h.ServeHTTP(rec, r)
+	})
+}
+
+// serveOperationalEndpoints registers the operational endpoints and instruments the server handler.
+func serveOperationalEndpoints() {
+	if ServeMux == nil {
+		ServeMux = http.NewServeMux()
+	}
+	Server.Handler = instrument(ServeMux)
+
+	if p := operationalEndpoints.Health; p != "" {
+		ServeMux.Handle(p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			fmt.Fprintln(w, "ok")
+		}))
+	}
+	if p := operationalEndpoints.Ready; p != "" {
+		ServeMux.Handle(p, http.HandlerFunc(serveReadiness))
+	}
+	if p := operationalEndpoints.Metrics; p != "" {
+		ServeMux.Handle(p, http.HandlerFunc(serveMetrics))
+	}
+}
+
+func serveReadiness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if !serverReady.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), ReadinessTimeout)
+	defer cancel()
+
+	readinessChecks.Lock()
+	checks := make(map[string]func(ctx context.Context) error, len(readinessChecks.checks))
+	for name, check := range readinessChecks.checks {
+		checks[name] = check
+	}
+	readinessChecks.Unlock()
+
+	for name, check := range checks {
+		if err := check(ctx); err != nil {
+			http.Error(w, name+": "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	fmt.Fprintln(w, "# HELP zui_http_requests_total Number of HTTP requests served.")
+	fmt.Fprintln(w, "# TYPE zui_http_requests_total counter")
+	keys, requests := serverMetrics.requests.sorted()
+	for _, k := range keys {
+		method, code := splitMetricKey(k)
+		fmt.Fprintf(w, "zui_http_requests_total{method=%q,code=%q} %d\n", method, code, requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP zui_http_requests_in_flight Number of HTTP requests being served.")
+	fmt.Fprintln(w, "# TYPE zui_http_requests_in_flight gauge")
+	fmt.Fprintf(w, "zui_http_requests_in_flight %d\n", serverMetrics.inflight.Load())
+
+	fmt.Fprintln(w, "# HELP zui_http_request_duration_seconds Duration of HTTP requests.")
+	fmt.Fprintln(w, "# TYPE zui_http_request_duration_seconds histogram")
+	serverMetrics.durations.write(w, "zui_http_request_duration_seconds")
+
+	fmt.Fprintln(w, "# HELP zui_ssr_render_duration_seconds Duration of server-side renders.")
+	fmt.Fprintln(w, "# TYPE zui_ssr_render_duration_seconds histogram")
+	serverMetrics.renders.write(w, "zui_ssr_render_duration_seconds")
+
+	fmt.Fprintln(w, "# HELP zui_cache_requests_total Number of cache lookups, by result.")
+	fmt.Fprintln(w, "# TYPE zui_cache_requests_total counter")
+	keys, cache := serverMetrics.cache.sorted()
+	for _, k := range keys {
+		name, result := splitMetricKey(k)
+		fmt.Fprintf(w, "zui_cache_requests_total{cache=%q,result=%q} %d\n", name, result, cache[k])
+	}
+}
+
+func splitMetricKey(k string) (string, string) {
+	for i := 0; i < len(k); i++ {
+		if k[i] == 0 {
+			return k[:i], k[i+1:]
+		}
+	}
+	return k, ""
+}
@@ -0,0 +1,87 @@
+//go:build server && (csr || ssr || ssg)
+
+package doc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Graceful shutdown
+//
+// The context passed to ListenAndServe controls the lifetime of the server. Once it is cancelled:
+//   - the server stops accepting connections and /readyz starts failing,
+//   - the dev SSE connections receive a "server-restarting" event and are closed,
+//   - the in-flight requests, and thus the in-flight renders, are given ShutdownTimeout to complete,
+//   - the hooks registered with OnShutdown release the server resources, in reverse order of registration.
+//
+// ListenAndServe returns afterwards instead of exiting the process.
+
+// ShutdownTimeout is the time given to in-flight requests to complete once shutdown has started.
+var ShutdownTimeout = 15 * time.Second
+
+var shutdownHooks = struct {
+	sync.Mutex
+	hooks []func(ctx context.Context) error
+}{}
+
+// OnShutdown registers a function releasing a server resource (database connection, cache...) on shutdown.
+// The context expires with the shutdown deadline.
+func OnShutdown(fn func(ctx context.Context) error) {
+	shutdownHooks.Lock()
+	defer shutdownHooks.Unlock()
+	shutdownHooks.hooks = append(shutdownHooks.hooks, fn)
+}
+
+// serve starts the server and blocks until ctx is cancelled or the server fails.
+func serve(ctx context.Context) error {
+	errc := make(chan error, 1)
+	go func() {
+		if Server.TLSConfig == nil {
+			errc <- Server.ListenAndServe()
+		} else {
+			errc <- Server.ListenAndServeTLS("", "")
+		}
+	}()
+
+	serverReady.Store(true)
+	log.Print("Listening on: " + Server.Addr)
+
+	select {
+	case err := <-errc:
+		serverReady.Store(false)
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return shutdownServer()
+	}
+}
+
+func shutdownServer() error {
+	serverReady.Store(false)
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	closeSSEControllers()
+	err := Server.Shutdown(ctx)
+	if err != nil {
+		log.Print("Shutdown deadline exceeded, closing remaining connections")
+		Server.Close()
+	}
+
+	shutdownHooks.Lock()
+	hooks := append([]func(ctx context.Context) error(nil), shutdownHooks.hooks...)
+	shutdownHooks.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		err = errors.Join(err, hooks[i](ctx))
+	}
+
+	log.Printf("Server shutdown")
+	return err
+}
@@ -0,0 +1,138 @@
+package doc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// Services
+//
+// Services are the dependencies of data loading code (database handles, API clients authenticated as the
+// user...). Their factories are registered with the builder via WithService, instead of being global
+// singletons, and are called lazily, at most once per document.
+// On the server, a new document is created for each request, so services are per request: their factory
+// can access the request via Document.RequestContext and Document.Cookie, and the context it receives is
+// the request context. Services implementing io.Closer are closed once the request is done.
+//
+// The server and client implementations of a service are registered under the same name, in files with the
+// corresponding build constraints, e.g. a database backed store on the server and an HTTP client stub in
+// the browser. Loaders then retrieve them with GetService, regardless of where they run.
+
+var ErrServiceNotFound = errors.New("service not registered")
+
+// ServiceFactory creates an instance of a service for a document.
+type ServiceFactory func(ctx context.Context, d *Document) (any, error)
+
+var serviceFactories = make(map[string]ServiceFactory)
+
+// WithService returns a build environment modifier, to be passed to NewBuilder, which registers the
+// factory of a named service.
+func WithService(name string, factory ServiceFactory) func() {
+	return func() {
+		serviceFactories[name] = factory
+	}
+}
+
+type serviceScope struct {
+	mu        sync.Mutex
+	instances map[string]any
+	order     []string
+	closed    bool
+}
+
+// services holds the service instances of each document, by document root.
+var services = newscsmap[*ui.Element, *serviceScope]()
+
+// Service returns the instance of the named service for the document, creating it if needed.
+func (d *Document) Service(name string) (any, error) {
+	s, ok := services.Get(d.AsElement())
+	if !ok {
+		s = &serviceScope{instances: make(map[string]any)}
+		services.Set(d.AsElement(), s)
+		d.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			releaseServices(evt.Origin())
+			return false
+		}).RunOnce())
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("service %s: document services were released", name)
+	}
+	v, ok := s.instances[name]
+	s.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+	factory, ok := serviceFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, name)
+	}
+	ctx := context.Background()
+	if r, ok := requests.Get(d.AsElement()); ok {
+		ctx = r.Context()
+	}
+	// the lock is not held by the factory, which may depend on other services.
+	v, err := factory(ctx, d)
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w, ok := s.instances[name]; ok || s.closed {
+		// created concurrently, or released in the meantime.
+		if c, ok := v.(io.Closer); ok {
+			c.Close()
+		}
+		if s.closed {
+			return nil, fmt.Errorf("service %s: document services were released", name)
+		}
+		return w, nil
+	}
+	s.instances[name] = v
+	s.order = append(s.order, name)
+	return v, nil
+}
+
+// GetService returns the instance of the named service for the document, with its expected type.
+func GetService[T any](d *Document, name string) (T, error) {
+	var zero T
+	v, err := d.Service(name)
+	if err != nil {
+		return zero, err
+	}
+	t, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("service %s: unexpected type %T", name, v)
+	}
+	return t, nil
+}
+
+// releaseServices closes the service instances of a document, in the reverse order of their creation.
+func releaseServices(root *ui.Element) {
+	s, ok := services.Get(root)
+	if !ok {
+		return
+	}
+	services.Delete(root)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for i := len(s.order) - 1; i >= 0; i-- {
+		c, ok := s.instances[s.order[i]].(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := c.Close(); err != nil {
+			DEBUG(err)
+		}
+	}
+	s.instances = nil
+}
@@ -0,0 +1,411 @@
+package doc
+
+import (
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Simulated user interactions
+//
+// A Simulator reproduces user interactions (clicks, typing, keyboard navigation, drag and drop) against a
+// mounted document so that integration tests may exercise event handlers realistically.
+// In the browser, native events are dispatched on the native nodes: they go through the event bridge and
+// handlers receive exactly the events a user interaction would produce.
+// Outside of the browser, the Go events are created as the bridge would and dispatched directly in the
+// Go tree.
+//
+// The methods block until the events have been handled. They must be called from a goroutine other than
+// the UI thread, typically the test goroutine.
+
+// KeyModifiers describes the modifier keys held during a simulated interaction.
+type KeyModifiers struct {
+	Alt   bool
+	Ctrl  bool
+	Meta  bool
+	Shift bool
+}
+
+// Simulator dispatches simulated user interactions on a document.
+type Simulator struct {
+	d       *Document
+	focused *ui.Element // only tracked outside of the browser
+}
+
+// Simulate returns a simulator of user interactions for the document.
+func (d *Document) Simulate() *Simulator {
+	return &Simulator{d: d}
+}
+
+// simEvent describes an event to be dispatched. kind is the name of the js event constructor.
+type simEvent struct {
+	typ        string
+	kind       string
+	bubbles    bool
+	cancelable bool
+
+	mods    KeyModifiers
+	key     string
+	code    string
+	button  int
+	buttons int
+
+	data      string
+	inputType string
+
+	related *ui.Element
+}
+
+func (evt simEvent) init() map[string]interface{} {
+	m := map[string]interface{}{
+		"bubbles":    evt.bubbles,
+		"cancelable": evt.cancelable,
+		"composed":   true,
+		"view":       js.Global(),
+		"altKey":     evt.mods.Alt,
+		"ctrlKey":    evt.mods.Ctrl,
+		"metaKey":    evt.mods.Meta,
+		"shiftKey":   evt.mods.Shift,
+	}
+	switch evt.kind {
+	case "KeyboardEvent":
+		m["key"] = evt.key
+		m["code"] = evt.code
+	case "MouseEvent", "PointerEvent", "DragEvent":
+		m["button"] = evt.button
+		m["buttons"] = evt.buttons
+	case "InputEvent":
+		m["data"] = evt.data
+		m["inputType"] = evt.inputType
+	}
+	if evt.related != nil {
+		if n, ok := JSValue(evt.related); ok {
+			m["relatedTarget"] = n
+		}
+	}
+	return m
+}
+
+func (s *Simulator) dispatch(e *ui.Element, evt simEvent) {
+	if InBrowser() {
+		n, ok := JSValue(e)
+		if !ok {
+			return
+		}
+		if e.IsRoot() {
+			n = js.Global().Get("document")
+		}
+		ctor := js.Global().Get(evt.kind)
+		if !ctor.Truthy() {
+			ctor = js.Global().Get("Event")
+		}
+		n.Call("dispatchEvent", ctor.New(evt.typ, evt.init()))
+		return
+	}
+
+	ui.DoSync(func() {
+		if !e.Mounted() {
+			return
+		}
+		e.DispatchEvent(s.goEvent(e, evt))
+	})
+}
+
+// goEvent creates the Go event that the event bridge would have created for the native event.
+func (s *Simulator) goEvent(e *ui.Element, evt simEvent) ui.Event {
+	rv := ui.NewObject()
+	if v, ok := e.GetData("value"); ok {
+		if str, ok := v.(ui.String); ok {
+			rv.Set("value", str)
+		}
+	}
+	newEvent := func(value ui.Value) ui.Event {
+		return ui.NewEvent(evt.typ, evt.bubbles, evt.cancelable, e, e, nil, value)
+	}
+
+	switch evt.kind {
+	case "KeyboardEvent":
+		k := KeyboardEvent{altKey: evt.mods.Alt, ctrlKey: evt.mods.Ctrl, metaKey: evt.mods.Meta, shiftKey: evt.mods.Shift, key: evt.key, code: evt.code}
+		keyboardEventSerialized(rv, k)
+		k.Event = newEvent(rv.Commit())
+		return k
+	case "MouseEvent", "PointerEvent", "DragEvent":
+		m := MouseEvent{altKey: evt.mods.Alt, ctrlKey: evt.mods.Ctrl, metaKey: evt.mods.Meta, shiftKey: evt.mods.Shift, button: float64(evt.button), buttons: float64(evt.buttons), relatedTarget: evt.related}
+		mouseEventSerialized(rv, m)
+		m.Event = newEvent(rv.Commit())
+		return m
+	case "InputEvent":
+		rv.Set(Namespace.Data, ui.String(evt.data))
+		rv.Set("inputType", ui.String(evt.inputType))
+	}
+	return newEvent(rv.Commit())
+}
+
+func (s *Simulator) mouse(e *ui.Element, typ string, mods KeyModifiers, buttons int) {
+	kind := "MouseEvent"
+	if strings.HasPrefix(typ, "pointer") {
+		kind = "PointerEvent"
+	}
+	s.dispatch(e, simEvent{typ: typ, kind: kind, bubbles: true, cancelable: true, mods: mods, buttons: buttons})
+}
+
+// Click simulates a primary button click on the element, focusing it first.
+func (s *Simulator) Click(e *ui.Element, mods ...KeyModifiers) {
+	var m KeyModifiers
+	if len(mods) > 0 {
+		m = mods[0]
+	}
+	s.mouse(e, "pointerdown", m, 1)
+	s.mouse(e, "mousedown", m, 1)
+	s.Focus(e)
+	s.mouse(e, "pointerup", m, 0)
+	s.mouse(e, "mouseup", m, 0)
+	s.mouse(e, "click", m, 0)
+}
+
+// DoubleClick simulates a double click on the element.
+func (s *Simulator) DoubleClick(e *ui.Element) {
+	s.Click(e)
+	s.Click(e)
+	s.mouse(e, "dblclick", KeyModifiers{}, 0)
+}
+
+// Focus moves the focus to the element.
+func (s *Simulator) Focus(e *ui.Element) {
+	if InBrowser() {
+		if n, ok := JSValue(e); ok {
+			n.Call("focus")
+		}
+		return
+	}
+	prev := s.focused
+	if prev != nil && prev.ID == e.ID {
+		return
+	}
+	if prev != nil {
+		s.dispatch(prev, simEvent{typ: "blur", kind: "FocusEvent", related: e})
+		s.dispatch(prev, simEvent{typ: "focusout", kind: "FocusEvent", bubbles: true, related: e})
+	}
+	s.focused = e
+	s.dispatch(e, simEvent{typ: "focus", kind: "FocusEvent", related: prev})
+	s.dispatch(e, simEvent{typ: "focusin", kind: "FocusEvent", bubbles: true, related: prev})
+}
+
+// Blur removes the focus from the element, which fires a change event if its value was modified
+// (in the browser).
+func (s *Simulator) Blur(e *ui.Element) {
+	if InBrowser() {
+		if n, ok := JSValue(e); ok {
+			n.Call("blur")
+		}
+		return
+	}
+	if s.focused == nil || s.focused.ID != e.ID {
+		return
+	}
+	s.focused = nil
+	s.dispatch(e, simEvent{typ: "blur", kind: "FocusEvent"})
+	s.dispatch(e, simEvent{typ: "focusout", kind: "FocusEvent", bubbles: true})
+}
+
+// Focused returns the element that currently has the focus, if any.
+func (s *Simulator) Focused() *ui.Element {
+	if !InBrowser() {
+		return s.focused
+	}
+	a := js.Global().Get("document").Get("activeElement")
+	if !a.Truthy() || !a.Get("id").Truthy() {
+		return nil
+	}
+	return s.d.GetElementById(a.Get("id").String())
+}
+
+// keyCode returns the code of the physical key usually producing the given key value.
+func keyCode(key string) string {
+	r := []rune(key)
+	if len(r) != 1 {
+		return key // named keys such as Enter, Tab, ArrowDown...
+	}
+	c := r[0]
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return "Key" + strings.ToUpper(string(c))
+	case c >= '0' && c <= '9':
+		return "Digit" + string(c)
+	case c == ' ':
+		return "Space"
+	}
+	return ""
+}
+
+// Press simulates pressing and releasing a key on the element, e.g. "Enter", "Escape" or "a".
+func (s *Simulator) Press(e *ui.Element, key string, mods ...KeyModifiers) {
+	var m KeyModifiers
+	if len(mods) > 0 {
+		m = mods[0]
+	}
+	s.dispatch(e, simEvent{typ: "keydown", kind: "KeyboardEvent", bubbles: true, cancelable: true, mods: m, key: key, code: keyCode(key)})
+	s.dispatch(e, simEvent{typ: "keyup", kind: "KeyboardEvent", bubbles: true, cancelable: true, mods: m, key: key, code: keyCode(key)})
+}
+
+// Type simulates typing text in a text field: each character produces keydown, beforeinput, input
+// and keyup events, and the value of the field is updated accordingly.
+func (s *Simulator) Type(e *ui.Element, text string) {
+	s.Focus(e)
+	for _, r := range text {
+		key := string(r)
+		mods := KeyModifiers{Shift: r >= 'A' && r <= 'Z'}
+		s.dispatch(e, simEvent{typ: "keydown", kind: "KeyboardEvent", bubbles: true, cancelable: true, mods: mods, key: key, code: keyCode(key)})
+		s.dispatch(e, simEvent{typ: "beforeinput", kind: "InputEvent", bubbles: true, cancelable: true, data: key, inputType: "insertText"})
+		s.setValue(e, func(v string) string { return v + key })
+		s.dispatch(e, simEvent{typ: "input", kind: "InputEvent", bubbles: true, data: key, inputType: "insertText"})
+		s.dispatch(e, simEvent{typ: "keyup", kind: "KeyboardEvent", bubbles: true, cancelable: true, mods: mods, key: key, code: keyCode(key)})
+	}
+}
+
+// Clear simulates erasing the content of a text field.
+func (s *Simulator) Clear(e *ui.Element) {
+	s.Focus(e)
+	s.setValue(e, func(string) string { return "" })
+	s.dispatch(e, simEvent{typ: "input", kind: "InputEvent", bubbles: true, inputType: "deleteContentBackward"})
+}
+
+func (s *Simulator) setValue(e *ui.Element, f func(string) string) {
+	if InBrowser() {
+		if n, ok := JSValue(e); ok {
+			n.Set("value", f(n.Get("value").String()))
+		}
+		return
+	}
+	ui.DoSync(func() {
+		var v string
+		if val, ok := e.GetData("value"); ok {
+			if str, ok := val.(ui.String); ok {
+				v = string(str)
+			}
+		}
+		e.SetData("value", ui.String(f(v)))
+	})
+}
+
+var tabbableTypes = newset("a", "button", "input", "select", "textarea")
+
+// tabbable returns whether an element is part of the sequential keyboard navigation, according to the
+// logical tree.
+func tabbable(e *ui.Element) bool {
+	if !e.Mounted() {
+		return false
+	}
+	if _, disabled := attrValue(e, "disabled"); disabled {
+		return false
+	}
+	if t, ok := attrValue(e, "tabindex"); ok {
+		i, err := strconv.Atoi(t)
+		return err == nil && i >= 0
+	}
+	if elementType(e) == "a" {
+		_, ok := attrValue(e, "href")
+		return ok
+	}
+	return tabbableTypes.Contains(elementType(e))
+}
+
+// Tab simulates pressing the Tab key (Shift+Tab if backward is true) and returns the element that
+// receives the focus, if any. Positive tabindex values are not taken into account for the ordering.
+func (s *Simulator) Tab(backward bool) *ui.Element {
+	mods := KeyModifiers{Shift: backward}
+	current := s.Focused()
+	from := current
+	if from == nil {
+		from = s.d.Body()
+	}
+	s.dispatch(from, simEvent{typ: "keydown", kind: "KeyboardEvent", bubbles: true, cancelable: true, mods: mods, key: "Tab", code: "Tab"})
+
+	var candidates []*ui.Element
+	if InBrowser() {
+		nodes := js.Global().Get("document").Call("querySelectorAll", `a[href], button:not([disabled]), input:not([disabled]), select:not([disabled]), textarea:not([disabled]), [tabindex]:not([tabindex="-1"])`)
+		for i := 0; i < nodes.Length(); i++ {
+			id := nodes.Index(i).Get("id")
+			if !id.Truthy() {
+				continue
+			}
+			if e := s.d.GetElementById(id.String()); e != nil {
+				candidates = append(candidates, e)
+			}
+		}
+	} else {
+		ui.DoSync(func() {
+			walkElements(s.d.AsElement(), func(e *ui.Element) {
+				if tabbable(e) {
+					candidates = append(candidates, e)
+				}
+			})
+		})
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	idx := -1
+	for i, c := range candidates {
+		if current != nil && c.ID == current.ID {
+			idx = i
+			break
+		}
+	}
+	var next *ui.Element
+	switch {
+	case idx < 0 && backward:
+		next = candidates[len(candidates)-1]
+	case idx < 0:
+		next = candidates[0]
+	case backward:
+		next = candidates[(idx-1+len(candidates))%len(candidates)]
+	default:
+		next = candidates[(idx+1)%len(candidates)]
+	}
+	s.Focus(next)
+	s.dispatch(next, simEvent{typ: "keyup", kind: "KeyboardEvent", bubbles: true, cancelable: true, mods: mods, key: "Tab", code: "Tab"})
+	return next
+}
+
+// Drag simulates dragging the source element and dropping it onto the target element.
+// In the browser, the same DataTransfer object is shared by all the drag events.
+func (s *Simulator) Drag(source, target *ui.Element) {
+	drag := func(e *ui.Element, typ string, cancelable bool, transfer js.Value) {
+		if !InBrowser() {
+			s.dispatch(e, simEvent{typ: typ, kind: "DragEvent", bubbles: true, cancelable: cancelable, buttons: 1})
+			return
+		}
+		n, ok := JSValue(e)
+		if !ok {
+			return
+		}
+		evt := simEvent{typ: typ, kind: "DragEvent", bubbles: true, cancelable: cancelable, buttons: 1}
+		init := evt.init()
+		if transfer.Truthy() {
+			init["dataTransfer"] = transfer
+		}
+		ctor := js.Global().Get("DragEvent")
+		if !ctor.Truthy() {
+			ctor = js.Global().Get("MouseEvent")
+		}
+		n.Call("dispatchEvent", ctor.New(typ, init))
+	}
+
+	transfer := js.Undefined()
+	if InBrowser() && js.Global().Get("DataTransfer").Truthy() {
+		transfer = js.Global().Get("DataTransfer").New()
+	}
+
+	s.mouse(source, "pointerdown", KeyModifiers{}, 1)
+	s.mouse(source, "mousedown", KeyModifiers{}, 1)
+	drag(source, "dragstart", true, transfer)
+	drag(source, "drag", true, transfer)
+	drag(target, "dragenter", true, transfer)
+	drag(target, "dragover", true, transfer)
+	drag(target, "drop", true, transfer)
+	drag(source, "dragend", false, transfer)
+}
@@ -0,0 +1,321 @@
+package doc
+
+import (
+	"errors"
+	"strconv"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Speech
+//
+// Speech synthesis: Speak queues an utterance and returns its id. The progress of utterances is reported via
+// events triggered on the document, whose value is an object holding the utterance "id":
+//   - "speech-start", "speech-end", "speech-pause", "speech-resume"
+//   - "speech-boundary": a word or sentence boundary was reached ("name", "charIndex", "charLength")
+//   - "speech-error": the utterance failed ("error")
+//
+// Speech recognition: StartSpeechRecognition starts listening to the microphone. The recognition state is
+// stored in the "speechrecognition" ui property of the document ("listening", "stopped" or "denied" when the
+// microphone permission was refused). Results are reported via events:
+//   - "speech-result": an object with "transcript", "confidence" and "final" (false for interim results)
+//   - "speech-recognition-error": an object with "error" and "message"
+
+var ErrSpeechUnsupported = errors.New("speech API is not supported")
+
+// Voice describes a speech synthesis voice.
+type Voice struct {
+	Name    string
+	Lang    string
+	Default bool
+	Local   bool
+}
+
+// Utterance describes a text to be spoken. Zero values use the browser defaults.
+type Utterance struct {
+	Text   string
+	Lang   string
+	Voice  string // name of the voice, see Voices
+	Rate   float64
+	Pitch  float64
+	Volume float64
+}
+
+type speechState struct {
+	count       int
+	recognition js.Value
+}
+
+func (d *Document) speechState() *speechState {
+	if d.speech == nil {
+		d.speech = &speechState{recognition: js.Undefined()}
+		d.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			d.CancelSpeech()
+			d.AbortSpeechRecognition()
+			return false
+		}).RunOnce())
+	}
+	return d.speech
+}
+
+func speechSynthesis() (js.Value, bool) {
+	if !InBrowser() {
+		return js.Undefined(), false
+	}
+	s := js.Global().Get("speechSynthesis")
+	return s, s.Truthy()
+}
+
+// Voices returns the speech synthesis voices available. The list may be empty until the browser
+// has loaded them.
+func (d *Document) Voices() []Voice {
+	s, ok := speechSynthesis()
+	if !ok {
+		return nil
+	}
+	voices := s.Call("getVoices")
+	res := make([]Voice, 0, voices.Length())
+	for i := 0; i < voices.Length(); i++ {
+		v := voices.Index(i)
+		res = append(res, Voice{v.Get("name").String(), v.Get("lang").String(), v.Get("default").Bool(), v.Get("localService").Bool()})
+	}
+	return res
+}
+
+// Speak queues an utterance and returns its id.
+func (d *Document) Speak(u Utterance) (string, error) {
+	s, ok := speechSynthesis()
+	if !ok {
+		return "", ErrSpeechUnsupported
+	}
+	st := d.speechState()
+	st.count++
+	id := "utterance-" + strconv.Itoa(st.count)
+
+	utterance := js.Global().Get("SpeechSynthesisUtterance").New(u.Text)
+	if u.Lang != "" {
+		utterance.Set("lang", u.Lang)
+	}
+	if u.Rate > 0 {
+		utterance.Set("rate", u.Rate)
+	}
+	if u.Pitch > 0 {
+		utterance.Set("pitch", u.Pitch)
+	}
+	if u.Volume > 0 {
+		utterance.Set("volume", u.Volume)
+	}
+	if u.Voice != "" {
+		voices := s.Call("getVoices")
+		for i := 0; i < voices.Length(); i++ {
+			if v := voices.Index(i); v.Get("name").String() == u.Voice {
+				utterance.Set("voice", v)
+				break
+			}
+		}
+	}
+
+	var callbacks []js.Func
+	release := func() {
+		for _, f := range callbacks {
+			f.Release()
+		}
+	}
+	on := func(jsevt, evt string, last bool, detail func(js.Value, *ui.TempObject)) {
+		f := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			v := ui.NewObject().Set("id", ui.String(id))
+			if detail != nil {
+				detail(args[0], v)
+			}
+			value := v.Commit()
+			go ui.DoSync(func() {
+				d.TriggerEvent(evt, value)
+			})
+			if last {
+				release()
+			}
+			return nil
+		})
+		callbacks = append(callbacks, f)
+		utterance.Set("on"+jsevt, f)
+	}
+	on("start", "speech-start", false, nil)
+	on("pause", "speech-pause", false, nil)
+	on("resume", "speech-resume", false, nil)
+	on("boundary", "speech-boundary", false, func(e js.Value, v *ui.TempObject) {
+		v.Set("name", ui.String(e.Get("name").String()))
+		v.Set("charIndex", ui.Number(e.Get("charIndex").Int()))
+		if l := e.Get("charLength"); l.Truthy() {
+			v.Set("charLength", ui.Number(l.Int()))
+		}
+	})
+	on("end", "speech-end", true, nil)
+	on("error", "speech-error", true, func(e js.Value, v *ui.TempObject) {
+		v.Set("error", ui.String(e.Get("error").String()))
+	})
+
+	s.Call("speak", utterance)
+	return id, nil
+}
+
+// CancelSpeech empties the utterance queue and stops the current one.
+func (d *Document) CancelSpeech() {
+	if s, ok := speechSynthesis(); ok {
+		s.Call("cancel")
+	}
+}
+
+// PauseSpeech pauses speech synthesis.
+func (d *Document) PauseSpeech() {
+	if s, ok := speechSynthesis(); ok {
+		s.Call("pause")
+	}
+}
+
+// ResumeSpeech resumes a paused speech synthesis.
+func (d *Document) ResumeSpeech() {
+	if s, ok := speechSynthesis(); ok {
+		s.Call("resume")
+	}
+}
+
+// IsSpeaking returns whether an utterance is being spoken.
+func (d *Document) IsSpeaking() bool {
+	s, ok := speechSynthesis()
+	return ok && s.Get("speaking").Bool()
+}
+
+// SpeechRecognitionOptions configures speech recognition.
+type SpeechRecognitionOptions struct {
+	Lang            string // defaults to the document language
+	Continuous      bool
+	InterimResults  bool
+	MaxAlternatives int
+}
+
+func speechRecognitionConstructor() (js.Value, bool) {
+	if !InBrowser() {
+		return js.Undefined(), false
+	}
+	ctor := js.Global().Get("SpeechRecognition")
+	if !ctor.Truthy() {
+		ctor = js.Global().Get("webkitSpeechRecognition")
+	}
+	return ctor, ctor.Truthy()
+}
+
+// SpeechRecognitionSupported returns whether the browser supports speech recognition.
+func (d *Document) SpeechRecognitionSupported() bool {
+	_, ok := speechRecognitionConstructor()
+	return ok
+}
+
+// StartSpeechRecognition starts listening. The browser prompts the user for the microphone permission
+// if it was not granted yet. A recognition that is already running is aborted first.
+func (d *Document) StartSpeechRecognition(opts SpeechRecognitionOptions) error {
+	ctor, ok := speechRecognitionConstructor()
+	if !ok {
+		return ErrSpeechUnsupported
+	}
+	d.AbortSpeechRecognition()
+	st := d.speechState()
+
+	r := ctor.New()
+	lang := opts.Lang
+	if lang == "" {
+		lang = js.Global().Get("document").Get("documentElement").Get("lang").String()
+	}
+	if lang != "" {
+		r.Set("lang", lang)
+	}
+	r.Set("continuous", opts.Continuous)
+	r.Set("interimResults", opts.InterimResults)
+	if opts.MaxAlternatives > 0 {
+		r.Set("maxAlternatives", opts.MaxAlternatives)
+	}
+
+	// the callbacks are released once the recognition has ended.
+	var callbacks []js.Func
+	handle := func(jsevt string, fn func(e js.Value)) {
+		f := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			fn(args[0])
+			return nil
+		})
+		callbacks = append(callbacks, f)
+		r.Set("on"+jsevt, f)
+	}
+	handle("start", func(e js.Value) {
+		go ui.DoSync(func() {
+			d.SetUI("speechrecognition", ui.String("listening"))
+		})
+	})
+	handle("end", func(e js.Value) {
+		for _, f := range callbacks {
+			f.Release()
+		}
+		go ui.DoSync(func() {
+			if v, ok := d.GetUI("speechrecognition"); ok && string(v.(ui.String)) == "denied" {
+				return
+			}
+			d.SetUI("speechrecognition", ui.String("stopped"))
+		})
+	})
+	handle("result", func(e js.Value) {
+		results := e.Get("results")
+		var values []ui.Value
+		for i := e.Get("resultIndex").Int(); i < results.Length(); i++ {
+			res := results.Index(i)
+			best := res.Index(0)
+			values = append(values, ui.NewObject().
+				Set("transcript", ui.String(best.Get("transcript").String())).
+				Set("confidence", ui.Number(best.Get("confidence").Float())).
+				Set("final", ui.Bool(res.Get("isFinal").Bool())).
+				Commit())
+		}
+		go ui.DoSync(func() {
+			for _, v := range values {
+				d.TriggerEvent("speech-result", v)
+			}
+		})
+	})
+	handle("error", func(e js.Value) {
+		code := e.Get("error").String()
+		msg := ""
+		if m := e.Get("message"); m.Truthy() {
+			msg = m.String()
+		}
+		go ui.DoSync(func() {
+			if code == "not-allowed" || code == "service-not-allowed" {
+				d.SetUI("speechrecognition", ui.String("denied"))
+			}
+			d.TriggerEvent("speech-recognition-error", ui.NewObject().Set("error", ui.String(code)).Set("message", ui.String(msg)).Commit())
+		})
+	})
+
+	st.recognition = r
+	r.Call("start")
+	return nil
+}
+
+// StopSpeechRecognition stops listening. Results for the audio captured so far are still reported.
+func (d *Document) StopSpeechRecognition() {
+	if d.speech == nil || !d.speech.recognition.Truthy() {
+		return
+	}
+	d.speech.recognition.Call("stop")
+}
+
+// AbortSpeechRecognition stops listening and discards pending results.
+func (d *Document) AbortSpeechRecognition() {
+	if d.speech == nil || !d.speech.recognition.Truthy() {
+		return
+	}
+	d.speech.recognition.Call("abort")
+	d.speech.recognition = js.Undefined()
+}
+
+// OnSpeechResult registers a handler called for each recognition result.
+func (d *Document) OnSpeechResult(h *ui.MutationHandler) {
+	d.WatchEvent("speech-result", d, h)
+}
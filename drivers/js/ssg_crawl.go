@@ -0,0 +1,166 @@
+//go:build server && ssg
+
+package doc
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// Prerender crawling
+//
+// Instead of prerendering the explicit list of routes known to the router, the SSG builder may crawl the app:
+// starting from the Start route, every page is rendered and the anchors it contains are followed, so that only
+// the reachable routes are prerendered.
+// Routes with parameters (e.g. /blog/:post) that are not linked to may still be prerendered by providing
+// the values a parameter may take.
+// Crawling is enabled with the -crawl flag or by setting Crawling before calling NewBuilder.
+
+// CrawlOptions configures the prerender crawler.
+type CrawlOptions struct {
+	// Start is the route the crawl starts from. Defaults to "/".
+	Start string
+	// Include and Exclude are lists of path.Match patterns. If Include is not empty, a route has to match
+	// one of its patterns to be prerendered. Routes matching an Exclude pattern are skipped.
+	Include []string
+	Exclude []string
+	// ParamValues provides, per parameter name, the values used to expand the router's parameterized routes.
+	ParamValues map[string]func() []string
+	// MaxPages limits the number of prerendered pages. Zero means no limit.
+	MaxPages int
+}
+
+// Crawling holds the options of the prerender crawler. The crawler is disabled if nil.
+var Crawling *CrawlOptions
+
+func (o CrawlOptions) accepts(route string) bool {
+	for _, p := range o.Exclude {
+		if ok, _ := path.Match(p, route); ok {
+			return false
+		}
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	for _, p := range o.Include {
+		if ok, _ := path.Match(p, route); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expandRoute returns the routes obtained by substituting the parameters of a route pattern with the values
+// provided for them. It returns nil if a parameter has no provider.
+func (o CrawlOptions) expandRoute(pattern string) []string {
+	res := []string{""}
+	for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		values := []string{segment}
+		if strings.HasPrefix(segment, ":") {
+			provider, ok := o.ParamValues[strings.TrimPrefix(segment, ":")]
+			if !ok {
+				return nil
+			}
+			values = provider()
+		}
+		next := make([]string, 0, len(res)*len(values))
+		for _, prefix := range res {
+			for _, v := range values {
+				next = append(next, prefix+"/"+v)
+			}
+		}
+		res = next
+	}
+	return res
+}
+
+// linkedRoutes returns the internal routes targeted by the anchors of the rendered document.
+func linkedRoutes(doc Document) []string {
+	var routes []string
+	walkElements(doc.AsElement(), func(e *ui.Element) {
+		if elementType(e) != "a" || !e.Mounted() {
+			return
+		}
+		href, ok := attrValue(e, "href")
+		if !ok {
+			return
+		}
+		if _, external := urlScheme(href); external || strings.HasPrefix(href, "//") {
+			return
+		}
+		if i := strings.IndexAny(href, "?#"); i >= 0 {
+			href = href[:i]
+		}
+		if href == "" {
+			return
+		}
+		if !strings.HasPrefix(href, "/") {
+			href = path.Join(doc.Router().CurrentRoute(), href)
+		}
+		routes = append(routes, "/"+strings.TrimPrefix(path.Clean(strings.TrimPrefix(href, BasePath)), "/"))
+	})
+	return routes
+}
+
+// crawlPages prerenders the pages reachable from the start route.
+func crawlPages(doc Document, basePath string, opts CrawlOptions) (int, error) {
+	router := doc.Router()
+	start := opts.Start
+	if start == "" {
+		start = "/"
+	}
+
+	queue := []string{start}
+	for _, pattern := range router.RouteList() {
+		if strings.Contains(pattern, ":") {
+			queue = append(queue, opts.expandRoute(pattern)...)
+		}
+	}
+
+	visited := make(map[string]bool)
+	var count int
+	for len(queue) > 0 {
+		if opts.MaxPages > 0 && count >= opts.MaxPages {
+			break
+		}
+		route := queue[0]
+		queue = queue[1:]
+		if visited[route] {
+			continue
+		}
+		visited[route] = true
+		if !opts.accepts(route) {
+			continue
+		}
+		if _, err := router.Match(route); err != nil {
+			if verbose {
+				fmt.Printf("Skipping unknown route '%s'\n", route)
+			}
+			continue
+		}
+
+		fullPath := filepath.Join(basePath, route, "index.html")
+		if verbose {
+			fmt.Printf("Creating page for route '%s' at '%s'\n", route, fullPath)
+		}
+		router.GoTo(route)
+		if err := doc.CreatePage(fullPath); err != nil {
+			return count, fmt.Errorf("error creating page for route '%s': %w", route, err)
+		}
+		count++
+
+		links := linkedRoutes(doc)
+		sort.Strings(links)
+		for _, l := range links {
+			if !visited[l] {
+				queue = append(queue, l)
+			}
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,68 @@
+package doc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// SSR state compression
+//
+// The state history embedded in server rendered pages (see SSRStateElementID) is gzip compressed and base64
+// encoded at render time. It is inflated in Go during hydration, before the mutation trace is replayed.
+// Uncompressed payloads, such as those rendered by a previous version of the framework, are still accepted.
+
+// CompressSSRState determines whether the state history embedded in server rendered pages is compressed.
+var CompressSSRState = true
+
+const compressedStatePrefix = "zui-gzip:"
+
+// SerializeSSRState returns the state history of the document, compressed if CompressSSRState is true,
+// as embedded in server rendered pages.
+func SerializeSSRState(e *ui.Element) string {
+	state := SerializeStateHistory(e)
+	if !CompressSSRState {
+		return state
+	}
+	return compressState(state)
+}
+
+func compressState(s string) string {
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := zw.Write([]byte(s)); err != nil {
+		panic(err)
+	}
+	if err := zw.Close(); err != nil {
+		panic(err)
+	}
+	return compressedStatePrefix + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func decompressState(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, compressedStatePrefix) {
+		return s, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, compressedStatePrefix))
+	if err != nil {
+		return "", err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	res, err := io.ReadAll(zr)
+	if err != nil {
+		return "", err
+	}
+	return string(res), nil
+}
@@ -0,0 +1,215 @@
+package doc
+
+import (
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Sticky and scroll shadow states
+//
+// Toolbars and table headers pinned with position: sticky, as well as scroll containers which show a shadow
+// where content is cut off, need to know about their scroll state to style themselves.
+//   - Modifier.TrackStuck toggles the "is-stuck" state of a sticky element, while it is pinned to the top
+//     edge of its scroll container.
+//   - Modifier.TrackScrollShadows toggles the "has-overflow-above" and "has-overflow-below" states of a scroll
+//     container, while some of its content is hidden above or below its visible area.
+//
+// Each state is reflected by a class of the same name on the native element, for stylesheets, and by a ui
+// property holding a Bool, for watchers. Since these properties derive from the layout, they are excluded
+// from the mutation capture.
+//
+// The states are detected by IntersectionObservers watching sentinels: invisible native elements inserted
+// next to the tracked element, or at both ends of the scroll container. Sentinels carry the data-zui-sentinel
+// attribute and are ignored when children are inserted.
+
+// StickyStyleSheetID is the id of the stylesheet holding the sentinel rules.
+const StickyStyleSheetID = "zui-sticky"
+
+var stickyConfigs = newscsmap[*ui.Configuration, bool]()
+
+// TrackStuck returns an element modifier tracking whether a sticky element is pinned.
+func (m modifier) TrackStuck() func(e *ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		if !InBrowser() {
+			return e
+		}
+		var sentinel js.Value
+		var observer js.Value
+		cb := RegisterCallback(e, func(this js.Value, args []js.Value) interface{} {
+			entries := args[0]
+			entry := entries.Index(entries.Length() - 1)
+			if !entry.Get("rootBounds").Truthy() {
+				return nil
+			}
+			stuck := !entry.Get("isIntersecting").Bool() &&
+				entry.Get("boundingClientRect").Get("top").Float() < entry.Get("rootBounds").Get("top").Float()
+			go ui.DoSync(func() { setScrollState(e, "is-stuck", stuck) })
+			return nil
+		})
+
+		e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			n, ok := JSValue(e)
+			if !ok {
+				return false
+			}
+			stickyStyleSheet(e)
+			sentinel = newSentinel("stuck")
+			n.Call("before", sentinel)
+
+			// the sentinel leaves the root when the element reaches its sticky offset.
+			var top float64
+			if t := js.Global().Call("getComputedStyle", n).Get("top").String(); strings.HasSuffix(t, "px") {
+				top, _ = strconv.ParseFloat(strings.TrimSuffix(t, "px"), 64)
+			}
+			observer = js.Global().Get("IntersectionObserver").New(cb, map[string]interface{}{
+				"root":       observerRoot(e),
+				"rootMargin": strconv.FormatFloat(-top, 'f', -1, 64) + "px 0px 0px 0px",
+				"threshold":  []interface{}{0},
+			})
+			observer.Call("observe", sentinel)
+			return false
+		}))
+
+		e.OnUnmounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if observer.Truthy() {
+				observer.Call("disconnect")
+				sentinel.Call("remove")
+				observer = js.Value{}
+			}
+			setScrollState(e, "is-stuck", false)
+			return false
+		}))
+		return e
+	}
+}
+
+// TrackScrollShadows returns an element modifier tracking whether the content of a scroll container
+// overflows above or below its visible area.
+func (m modifier) TrackScrollShadows() func(e *ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		if !InBrowser() {
+			return e
+		}
+		var top, bottom js.Value
+		var observer js.Value
+		cb := RegisterCallback(e, func(this js.Value, args []js.Value) interface{} {
+			entries := args[0]
+			var above, below *bool
+			for i := 0; i < entries.Length(); i++ {
+				entry := entries.Index(i)
+				hidden := !entry.Get("isIntersecting").Bool()
+				if entry.Get("target").Equal(top) {
+					above = &hidden
+				} else {
+					below = &hidden
+				}
+			}
+			go ui.DoSync(func() {
+				if above != nil {
+					setScrollState(e, "has-overflow-above", *above)
+				}
+				if below != nil {
+					setScrollState(e, "has-overflow-below", *below)
+				}
+			})
+			return nil
+		})
+
+		e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			n, ok := scrollNode(e)
+			if !ok {
+				return false
+			}
+			stickyStyleSheet(e)
+			top, bottom = newSentinel("overflow-above"), newSentinel("overflow-below")
+			n.Call("prepend", top)
+			n.Call("append", bottom)
+
+			root := interface{}(n)
+			if n.Equal(js.Global().Get("document").Get("scrollingElement")) {
+				root = nil
+			}
+			observer = js.Global().Get("IntersectionObserver").New(cb, map[string]interface{}{
+				"root":      root,
+				"threshold": []interface{}{0},
+			})
+			observer.Call("observe", top)
+			observer.Call("observe", bottom)
+			return false
+		}))
+
+		e.OnUnmounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if observer.Truthy() {
+				observer.Call("disconnect")
+				top.Call("remove")
+				bottom.Call("remove")
+				observer = js.Value{}
+			}
+			setScrollState(e, "has-overflow-above", false)
+			setScrollState(e, "has-overflow-below", false)
+			return false
+		}))
+		return e
+	}
+}
+
+// observerRoot returns the root of an IntersectionObserver watching the scroll position of an element:
+// its closest scrollable ancestor, or nil for the viewport.
+func observerRoot(e *ui.Element) interface{} {
+	_, n := scrollContainer(e)
+	if n.Equal(js.Global().Get("document").Get("scrollingElement")) {
+		return nil
+	}
+	return n
+}
+
+func setScrollState(e *ui.Element, state string, on bool) {
+	if n, ok := JSValue(e); ok {
+		n.Get("classList").Call("toggle", state, on)
+	}
+	if v, ok := e.GetUI(state); ok && bool(v.(ui.Bool)) == on {
+		return
+	}
+	e.SetUI(state, ui.Bool(on))
+}
+
+func newSentinel(kind string) js.Value {
+	s := js.Global().Get("document").Call("createElement", "div")
+	s.Call("setAttribute", "data-zui-sentinel", kind)
+	s.Call("setAttribute", "aria-hidden", "true")
+	return s
+}
+
+// isSentinel reports whether a native element is a sentinel.
+func isSentinel(v js.Value) bool {
+	return InBrowser() && v.Truthy() && v.Call("hasAttribute", "data-zui-sentinel").Bool()
+}
+
+// hasSentinels reports whether some children of a native element are sentinels.
+func hasSentinels(v js.Value) bool {
+	return InBrowser() && v.Call("querySelector", ":scope > [data-zui-sentinel]").Truthy()
+}
+
+func stickyStyleSheet(e *ui.Element) {
+	if _, ok := stickyConfigs.Get(e.Configuration); !ok {
+		stickyConfigs.Set(e.Configuration, true)
+		for _, state := range []string{"is-stuck", "has-overflow-above", "has-overflow-below"} {
+			e.Configuration.ExcludeFromCapture(ui.CaptureFilter{Category: Namespace.UI, Property: state})
+		}
+	}
+
+	d := GetDocument(e)
+	if _, ok := d.GetStyleSheet(StickyStyleSheetID); ok {
+		return
+	}
+	sheet := d.NewStyleSheet(StickyStyleSheetID)
+	actives := append([]string{StickyStyleSheetID}, d.GetActiveStyleSheets()...)
+	d.SetActiveStyleSheets(actives...)
+
+	sheet.InsertRule("[data-zui-sentinel]", "display: block; flex: none; grid-column: 1 / -1; height: 1px; margin: 0 0 -1px 0; padding: 0; border: 0; visibility: hidden; pointer-events: none;")
+	sheet.InsertRule("[data-zui-sentinel=overflow-below]", "margin: -1px 0 0 0;")
+	sheet.Update()
+}
@@ -69,8 +69,11 @@ func storer(s string) func(element *ui.Element, category string, propname string
 			store.Set(strings.Join([]string{element.ID, category}, "/"), v)
 		}
 
-		item := value.RawValue()
-		v := stringify(item)
+		v, err := ui.EncodeText(ui.PersistenceCodec, value)
+		if err != nil {
+			DEBUG("unable to store property ", propname, " of ", element.ID, ": ", err)
+			return
+		}
 		store.Set(strings.Join([]string{element.ID, category, propname}, "/"), js.ValueOf(v))
 		return
 	}
@@ -79,7 +82,7 @@ func storer(s string) func(element *ui.Element, category string, propname string
 var sessionstorefn = storer("sessionStorage")
 var localstoragefn = storer("localStorage")
 
-func loader(s string) func(e *ui.Element) error {
+func loader(s string, mode string) func(e *ui.Element) error {
 	return func(e *ui.Element) error {
 
 		store := jsStore{js.Global().Get(s)}
@@ -112,6 +115,9 @@ func loader(s string) func(e *ui.Element) error {
 				// log.Print("debug...", category, property) // DEBUG
 
 				propname := property
+				if ui.PropertyPersistenceMode(e, category, propname) != mode {
+					continue
+				}
 				jsonvalue, ok := store.Get(strings.Join([]string{e.ID, category, propname}, "/"))
 				if ok {
 					var rawvaluemapstring string
@@ -120,12 +126,20 @@ func loader(s string) func(e *ui.Element) error {
 						return err
 					}
 
-					rawvalue := make(map[string]interface{})
-					err = json.Unmarshal([]byte(rawvaluemapstring), &rawvalue)
+					rawvalue, err := ui.DecodeText(rawvaluemapstring)
 					if err != nil {
 						return err
 					}
-					val := ui.ValueFrom(rawvalue)
+					val, err := ui.DecodePersisted(e, category, propname, rawvalue)
+					if err != nil {
+						// the property falls back to its ephemeral state
+						if errors.Is(err, ui.ErrPersistedValueExpired) {
+							store.Delete(strings.Join([]string{e.ID, category, propname}, "/"))
+						} else {
+							DEBUG("unable to load persisted property ", propname, " of ", e.ID, ": ", err)
+						}
+						continue
+					}
 
 					if category == Namespace.Data {
 						ui.LoadProperty(e, category, propname, val)
@@ -156,8 +170,8 @@ func loader(s string) func(e *ui.Element) error {
 	}
 }
 
-var loadfromsession = loader("sessionStorage")
-var loadfromlocalstorage = loader("localStorage")
+var loadfromsession = loader("sessionStorage", "sessionstorage")
+var loadfromlocalstorage = loader("localStorage", "localstorage")
 
 func clearer(s string) func(element *ui.Element) {
 	return func(element *ui.Element) {
@@ -246,11 +260,11 @@ func LoadFromStorage(a ui.AnyElement) *ui.Element {
 		panic("loading a nil element")
 	}
 
-	pmode := ui.PersistenceMode(e)
-
-	storage, ok := e.Configuration.PersistentStorer[pmode]
-
-	if ok {
+	for _, pmode := range ui.PersistenceModes(e) {
+		storage, ok := e.Configuration.PersistentStorer[pmode]
+		if !ok {
+			continue
+		}
 		err := storage.Load(e)
 		if err != nil {
 			panic(err)
@@ -260,20 +274,25 @@ func LoadFromStorage(a ui.AnyElement) *ui.Element {
 }
 
 // PutInStorage stores an element data in storage (localstorage or sessionstorage).
+// Each property is stored according to its persistence policy, if any.
 func PutInStorage(a ui.AnyElement) *ui.Element {
 	e := a.AsElement()
-	pmode := ui.PersistenceMode(e)
-	storage, ok := e.Configuration.PersistentStorer[pmode]
-	if !ok {
-		return e
-	}
 
 	for cat, props := range e.Properties.Categories {
 		if cat != Namespace.Data && cat != Namespace.UI {
 			continue
 		}
 		for prop, val := range props.Local {
-			storage.Store(e, cat, prop, val)
+			storage, ok := e.Configuration.PersistentStorer[ui.PropertyPersistenceMode(e, cat, prop)]
+			if !ok {
+				continue
+			}
+			v, err := ui.EncodePersisted(e, cat, prop, val)
+			if err != nil {
+				DEBUG("unable to persist property ", prop, " of ", e.ID, ": ", err)
+				continue
+			}
+			storage.Store(e, cat, prop, v)
 		}
 	}
 	return e
@@ -282,11 +301,10 @@ func PutInStorage(a ui.AnyElement) *ui.Element {
 // ClearFromStorage will clear an element properties from storage.
 func ClearFromStorage(a ui.AnyElement) *ui.Element {
 	e := a.AsElement()
-	pmode := ui.PersistenceMode(e)
-
-	storage, ok := e.Configuration.PersistentStorer[pmode]
-	if ok {
-		storage.Clear(e)
+	for _, pmode := range ui.PersistenceModes(e) {
+		if storage, ok := e.Configuration.PersistentStorer[pmode]; ok {
+			storage.Clear(e)
+		}
 	}
 	return e
 }
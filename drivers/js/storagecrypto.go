@@ -0,0 +1,266 @@
+package doc
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Encrypted storage
+//
+// Persisted properties may be encrypted with AES-GCM by using the EncryptedPersistence modifier, which sets
+// a persistence policy on the selected properties. It works with any persistence mode.
+//
+// The key is either served by the backend (NewStorageKey) or derived from a user secret with PBKDF2-SHA256
+// (DeriveStorageKey, which relies on WebCrypto when available). Keys are registered on the document with
+// SetStorageKey. Every encrypted value records the id of the key it was encrypted with: previous keys
+// remain usable for decryption after a rotation and values are re-encrypted with the current key the next
+// time they are stored.
+//
+// If no key is available (e.g. not yet derived, or removed on logout via ClearStorageKeys), encrypted
+// properties are neither stored nor loaded: they gracefully fall back to ephemeral state.
+//
+// Values are encoded with the persistence codec before being encrypted, so that they are versioned and
+// migrated like unencrypted ones.
+// The ciphertext format is the one produced by WebCrypto (the 12-byte IV is stored separately and the
+// authentication tag is appended to the ciphertext). Encryption itself is performed synchronously in Go since
+// persistence hooks run on the UI thread where the WebCrypto promises cannot be awaited.
+
+// StorageKeyIterations is the number of PBKDF2 iterations used to derive a storage key from a secret.
+var StorageKeyIterations = 210000
+
+var (
+	ErrStorageKeyUnavailable = errors.New("storage encryption key unavailable")
+	ErrInvalidStorageKey     = errors.New("storage encryption key should be 16, 24 or 32 bytes long")
+)
+
+// StorageKey is an AES key used to encrypt persisted properties.
+type StorageKey struct {
+	ID   string
+	aead cipher.AEAD
+}
+
+// NewStorageKey returns a storage key from raw key material, e.g. served by the backend.
+func NewStorageKey(id string, raw []byte) (StorageKey, error) {
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return StorageKey{}, ErrInvalidStorageKey
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return StorageKey{}, err
+	}
+	return StorageKey{id, aead}, nil
+}
+
+// DeriveStorageKey derives a 256-bit storage key from a user secret and a salt, using PBKDF2-SHA256.
+// It may take a while and should not be called from the UI thread (use DoAsync).
+func DeriveStorageKey(ctx context.Context, id string, secret string, salt []byte) (StorageKey, error) {
+	var raw []byte
+	if subtle := js.Global().Get("crypto").Get("subtle"); InBrowser() && subtle.Truthy() {
+		material, err := awaitPromise(ctx, subtle.Call("importKey", "raw", jsBytes([]byte(secret)), "PBKDF2", false, []interface{}{"deriveBits"}))
+		if err != nil {
+			return StorageKey{}, err
+		}
+		bits, err := awaitPromise(ctx, subtle.Call("deriveBits", map[string]interface{}{
+			"name":       "PBKDF2",
+			"hash":       "SHA-256",
+			"salt":       jsBytes(salt),
+			"iterations": StorageKeyIterations,
+		}, material, 256))
+		if err != nil {
+			return StorageKey{}, err
+		}
+		raw = goBytes(js.Global().Get("Uint8Array").New(bits))
+	} else {
+		raw = pbkdf2SHA256([]byte(secret), salt, StorageKeyIterations, 32)
+	}
+	return NewStorageKey(id, raw)
+}
+
+func pbkdf2SHA256(password, salt []byte, iterations, keylen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hlen := prf.Size()
+	nblocks := (keylen + hlen - 1) / hlen
+	res := make([]byte, 0, nblocks*hlen)
+	buf := make([]byte, 4)
+	for block := 1; block <= nblocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		prf.Write(buf)
+		u := prf.Sum(nil)
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		res = append(res, t...)
+	}
+	return res[:keylen]
+}
+
+type storageKeyring struct {
+	current string
+	keys    map[string]StorageKey
+}
+
+// SetStorageKey registers a key and makes it the current encryption key. Previously registered keys are
+// kept for decryption.
+func (d *Document) SetStorageKey(k StorageKey) *Document {
+	if d.storageKeys == nil {
+		d.storageKeys = &storageKeyring{keys: make(map[string]StorageKey)}
+	}
+	d.storageKeys.keys[k.ID] = k
+	d.storageKeys.current = k.ID
+	return d
+}
+
+// RemoveStorageKey unregisters a key. The values encrypted with it can no longer be loaded.
+func (d *Document) RemoveStorageKey(id string) *Document {
+	if d.storageKeys == nil {
+		return d
+	}
+	delete(d.storageKeys.keys, id)
+	if d.storageKeys.current == id {
+		d.storageKeys.current = ""
+	}
+	return d
+}
+
+// ClearStorageKeys unregisters every storage key, e.g. on logout.
+func (d *Document) ClearStorageKeys() *Document {
+	d.storageKeys = nil
+	return d
+}
+
+func (d *Document) encryptValue(v ui.Value) (ui.Value, error) {
+	if d.storageKeys == nil || d.storageKeys.current == "" {
+		return nil, ErrStorageKeyUnavailable
+	}
+	k := d.storageKeys.keys[d.storageKeys.current]
+	plaintext, err := ui.EncodeText(ui.PersistenceCodec, v)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	ciphertext := k.aead.Seal(nil, iv, []byte(plaintext), []byte(k.ID))
+	return ui.NewObject().
+		Set("kid", ui.String(k.ID)).
+		Set("iv", ui.String(base64.StdEncoding.EncodeToString(iv))).
+		Set("data", ui.String(base64.StdEncoding.EncodeToString(ciphertext))).
+		Commit(), nil
+}
+
+func (d *Document) decryptValue(v ui.Value) (ui.Value, error) {
+	o, ok := v.(ui.Object)
+	if !ok {
+		return nil, errors.New("persisted value is not encrypted")
+	}
+	kid, ok := o.Get("kid")
+	if !ok {
+		return nil, errors.New("persisted value is not encrypted")
+	}
+	if d.storageKeys == nil {
+		return nil, ErrStorageKeyUnavailable
+	}
+	k, ok := d.storageKeys.keys[string(kid.(ui.String))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrStorageKeyUnavailable, string(kid.(ui.String)))
+	}
+	iv, err := base64.StdEncoding.DecodeString(string(o.MustGetString("iv")))
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(string(o.MustGetString("data")))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := k.aead.Open(nil, iv, ciphertext, []byte(k.ID))
+	if err != nil {
+		return nil, err
+	}
+	return ui.DecodeText(string(plaintext))
+}
+
+// EncryptedPersistence is an element modifier that encrypts the given properties of a category when they
+// are persisted. If no property name is given, every property of the category is encrypted.
+// The persistence mode and TTL of existing policies are preserved.
+func EncryptedPersistence(category string, propnames ...string) func(*ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		if len(propnames) == 0 {
+			propnames = []string{""}
+		}
+		for _, propname := range propnames {
+			p, _ := e.GetPersistencePolicy(category, propname)
+			p.Encode = func(v ui.Value) (ui.Value, error) {
+				return GetDocument(e).encryptValue(v)
+			}
+			p.Decode = func(v ui.Value) (ui.Value, error) {
+				return GetDocument(e).decryptValue(v)
+			}
+			e.SetPersistencePolicy(category, propname, p)
+		}
+		return e
+	}
+}
+
+// awaitPromise waits for the settlement of a js promise. It should not be called from the UI thread.
+func awaitPromise(ctx context.Context, p js.Value) (js.Value, error) {
+	res := make(chan js.Value, 1)
+	errc := make(chan error, 1)
+	then := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) == 0 {
+			res <- js.Undefined()
+			return nil
+		}
+		res <- args[0]
+		return nil
+	})
+	defer then.Release()
+	catch := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		errc <- js.Error{Value: args[0]}
+		return nil
+	})
+	defer catch.Release()
+	p.Call("then", then, catch)
+
+	select {
+	case v := <-res:
+		return v, nil
+	case err := <-errc:
+		return js.Undefined(), err
+	case <-ctx.Done():
+		return js.Undefined(), ctx.Err()
+	}
+}
+
+func jsBytes(b []byte) js.Value {
+	a := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(a, b)
+	return a
+}
+
+func goBytes(a js.Value) []byte {
+	b := make([]byte, a.Get("length").Int())
+	js.CopyBytesToGo(b, a)
+	return b
+}
@@ -0,0 +1,43 @@
+package doc
+
+import (
+	"testing"
+
+	ui "github.com/atdiar/particleui"
+)
+
+func TestEncryptedValueRoundTrip(t *testing.T) {
+	k, err := NewStorageKey("k1", make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := (&Document{}).SetStorageKey(k)
+
+	values := []ui.Value{
+		ui.String("secret"),
+		ui.Number(42),
+		ui.NewList(ui.String("a"), ui.Bool(true)).Commit(),
+		ui.NewObject().Set("token", ui.String("t")).Commit(),
+	}
+	for _, v := range values {
+		encrypted, err := d.encryptValue(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ui.Equal(encrypted, v) {
+			t.Fatal("expected the value to be encrypted")
+		}
+		w, err := d.decryptValue(encrypted)
+		if err != nil {
+			t.Fatalf("unable to decrypt %v: %v", v, err)
+		}
+		if !ui.Equal(v, w) {
+			t.Errorf("expected %v, got %v", v, w)
+		}
+	}
+
+	d.ClearStorageKeys()
+	if _, err := d.encryptValue(ui.String("secret")); err != ErrStorageKeyUnavailable {
+		t.Errorf("expected encryption to fail without key, got %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package doc
+
+import (
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Streaming SSR
+//
+// When StreamSSR is enabled, the server does not wait for the whole document to be ready before responding.
+// It writes the shell of the page right after the navigation, leaving empty the stream boundaries whose subtree
+// is still busy (see SetBusy). Each boundary is then written as a chunk as soon as its subtree is no longer busy,
+// or once StreamTimeout has elapsed.
+//
+// The mutation trace is split accordingly: the shell embeds the mutations recorded until it was written and each
+// chunk embeds the mutations recorded since the previous one. In the browser, the shell trace is replayed as
+// usual, then each chunk is swapped into its boundary and its trace replayed as it arrives, after which the
+// elements of the boundary are connected to their native node. The "replay" transition of the document, hence
+// the "mutation-replayed" event, only ends once the end of the stream has been received.
+//
+// A "stream-chunk-hydrated" event is triggered on the document with the id of the boundary each time a chunk
+// has been replayed.
+
+// StreamSSR determines whether server rendered pages are streamed.
+var StreamSSR = false
+
+// StreamTimeout is the maximum duration a stream boundary may stay busy before being written anyway.
+var StreamTimeout = 5 * time.Second
+
+const (
+	streamPendingAttribute   = "data-zui-pending"
+	streamDocumentAttribute  = "data-zui-streaming"
+	streamBoundaryAttribute  = "data-zui-stream"
+	streamStateElementPrefix = SSRStateElementID + "-"
+	streamEndID              = "end"
+)
+
+// streamScript defines the functions called by the chunks of a streamed document. The chunks received
+// before the client is ready are queued.
+const streamScript = `
+window.zuiStream = { chunks: [], done: false };
+
+window.zuiStreamChunk = function(id) {
+	const tpl = document.querySelector('template[` + streamBoundaryAttribute + `="' + id + '"]');
+	const target = document.getElementById(id);
+	if (tpl && target) {
+		target.replaceChildren(tpl.content);
+		target.removeAttribute('` + streamPendingAttribute + `');
+	}
+	if (tpl) {
+		tpl.remove();
+	}
+	window.zuiStream.chunks.push(id);
+	document.dispatchEvent(new CustomEvent('zui-stream-chunk', { detail: id }));
+};
+
+window.zuiStreamEnd = function() {
+	window.zuiStream.done = true;
+	document.dispatchEvent(new CustomEvent('zui-stream-end'));
+};
+`
+
+// StreamBoundary marks an element as a stream boundary: when the page is streamed, its subtree is written in
+// its own chunk, once it is no longer busy.
+func StreamBoundary(e *ui.Element) *ui.Element {
+	d := GetDocument(e)
+	for _, b := range d.streamBoundaries {
+		if b == e {
+			return e
+		}
+	}
+	d.streamBoundaries = append(d.streamBoundaries, e)
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		for i, b := range d.streamBoundaries {
+			if b == evt.Origin() {
+				d.streamBoundaries = append(d.streamBoundaries[:i:i], d.streamBoundaries[i+1:]...)
+				break
+			}
+		}
+		return false
+	}).RunOnce())
+	return e
+}
+
+// OnStreamChunkHydrated registers a handler called each time the chunk of a stream boundary has been replayed.
+// The value of the event is the id of the boundary.
+func (d *Document) OnStreamChunkHydrated(h *ui.MutationHandler) {
+	d.WatchEvent("stream-chunk-hydrated", d, h)
+}
+
+// serializeStateSegment returns the mutations recorded since the given position, serialized as an embedded
+// state, along with the position of the next segment.
+func serializeStateSegment(d *Document, from int) (string, int) {
+	var ops []ui.Value
+	if l, ok := d.mutationRecorder().raw.GetData("mutationlist"); ok {
+		ops = l.(ui.List).UnsafelyUnwrap()
+	}
+	if from > len(ops) {
+		from = len(ops)
+	}
+	s, err := ui.EncodeText(ui.PersistenceCodec, ui.NewList(ops[from:]...).Commit())
+	if err != nil {
+		panic(err)
+	}
+	if CompressSSRState {
+		s = compressState(s)
+	}
+	return s, len(ops)
+}
+
+type streamedReplay struct {
+	hydrated map[string]bool
+	onchunk  js.Func
+	onend    js.Func
+}
+
+// streamedDocument reports whether the page being hydrated is still streamed or was streamed.
+func streamedDocument() bool {
+	if !InBrowser() {
+		return false
+	}
+	return js.Global().Get("document").Get("documentElement").Call("hasAttribute", streamDocumentAttribute).Bool()
+}
+
+// awaitStreamedChunks replays the chunks that were already received and waits for those that are still to come.
+// If the stream is already over, the replay is completed synchronously.
+func awaitStreamedChunks(d *Document) error {
+	r := &streamedReplay{hydrated: make(map[string]bool)}
+
+	queue := js.Global().Get("zuiStream")
+	if queue.Truthy() {
+		chunks := queue.Get("chunks")
+		for i := 0; i < chunks.Length(); i++ {
+			if err := r.hydrate(d, chunks.Index(i).String()); err != nil {
+				return err
+			}
+		}
+		if !queue.Get("done").Bool() {
+			r.listen(d)
+			return nil
+		}
+	}
+	return r.finish(d)
+}
+
+func (r *streamedReplay) listen(d *Document) {
+	d.streamedReplay = r
+	r.onchunk = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		id := args[0].Get("detail").String()
+		go ui.DoSync(func() {
+			if d.streamedReplay != r {
+				return
+			}
+			if err := r.hydrate(d, id); err != nil {
+				r.abort(d, err)
+			}
+		})
+		return nil
+	})
+	r.onend = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			if d.streamedReplay != r {
+				return
+			}
+			if err := r.finish(d); err != nil {
+				r.abort(d, err)
+				return
+			}
+			d.TriggerEvent("mutation-replayed")
+			d.EndTransition("replay")
+		})
+		return nil
+	})
+	doc := js.Global().Get("document")
+	doc.Call("addEventListener", "zui-stream-chunk", r.onchunk)
+	doc.Call("addEventListener", "zui-stream-end", r.onend)
+}
+
+// hydrate replays the trace of the chunk of a boundary and connects the elements of the boundary.
+func (r *streamedReplay) hydrate(d *Document, id string) error {
+	if r.hydrated[id] {
+		return nil
+	}
+	r.hydrated[id] = true
+	if err := r.replaySegment(d, id); err != nil {
+		return err
+	}
+	if b := d.GetElementById(id); b != nil {
+		walkElements(b, func(e *ui.Element) {
+			e.TriggerEvent("connect-native")
+		})
+	}
+	d.TriggerEvent("stream-chunk-hydrated", ui.String(id))
+	return nil
+}
+
+// finish replays the trailing trace of the stream.
+func (r *streamedReplay) finish(d *Document) error {
+	r.release()
+	d.streamedReplay = nil
+	js.Global().Get("document").Get("documentElement").Call("removeAttribute", streamDocumentAttribute)
+	return r.replaySegment(d, streamEndID)
+}
+
+func (r *streamedReplay) abort(d *Document, err error) {
+	r.release()
+	d.streamedReplay = nil
+	d.ErrorTransition("replay", ui.String(err.Error()))
+}
+
+func (r *streamedReplay) release() {
+	if r.onchunk.IsUndefined() {
+		return
+	}
+	doc := js.Global().Get("document")
+	doc.Call("removeEventListener", "zui-stream-chunk", r.onchunk)
+	doc.Call("removeEventListener", "zui-stream-end", r.onend)
+	r.onchunk.Release()
+	r.onend.Release()
+}
+
+// replaySegment appends the trace embedded in a chunk to the mutation list and replays it.
+func (r *streamedReplay) replaySegment(d *Document, id string) error {
+	statenode := js.Global().Get("document").Call("getElementById", streamStateElementPrefix+id)
+	if !statenode.Truthy() {
+		return nil
+	}
+	defer statenode.Call("remove")
+
+	v, err := DeserializeStateHistory(statenode.Get("textContent").String())
+	if err != nil {
+		return err
+	}
+	segment, ok := v.(ui.List)
+	if !ok || len(segment.UnsafelyUnwrap()) == 0 {
+		return nil
+	}
+
+	m := d.mutationRecorder()
+	trace := ui.NewList()
+	if l, ok := m.raw.GetData("mutationlist"); ok {
+		trace = l.(ui.List).MakeCopy()
+	}
+	list := trace.Append(segment.UnsafelyUnwrap()...).Commit()
+	m.raw.SetData("mutationlist", list)
+	d.replayReport.Total = len(list.UnsafelyUnwrap())
+
+	d.Set(Namespace.Internals, "mutation-replaying", ui.Bool(true))
+	defer d.Set(Namespace.Internals, "mutation-replaying", ui.Bool(false))
+	return replaytrace(d, list)
+}
@@ -0,0 +1,235 @@
+//go:build server && ssr && !csr
+
+package doc
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	"golang.org/x/net/html"
+)
+
+// streamRender writes the document as a stream of chunks (see StreamSSR).
+// It returns false if the response writer does not support flushing, in which case nothing has been written.
+func streamRender(d *Document, w http.ResponseWriter, r *http.Request) bool {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return false
+	}
+	start := time.Now()
+
+	var shell, tail []byte
+	var pending []*ui.Element
+	var pos int
+	var err error
+	ui.DoSync(func() {
+		pending = pendingStreamBoundaries(d)
+		shell, tail, pos, err = renderStreamShell(d, pending)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+
+	if len(ClientHints) > 0 {
+		w.Header().Set("Accept-CH", strings.Join(ClientHints, ", "))
+	}
+	writeResponseHeader(d, w)
+	if status := d.ResponseStatus(); status >= 300 && status < 400 {
+		return true
+	}
+	if _, err := w.Write(shell); err != nil {
+		return true
+	}
+	flusher.Flush()
+
+	for _, b := range pending {
+		awaitStreamBoundary(r.Context(), b)
+
+		var chunk bytes.Buffer
+		ui.DoSync(func() {
+			pos, err = renderStreamChunk(&chunk, d, b, pos)
+		})
+		if err != nil {
+			break
+		}
+		if _, err := chunk.WriteTo(w); err != nil {
+			return true
+		}
+		flusher.Flush()
+	}
+
+	var end bytes.Buffer
+	ui.DoSync(func() {
+		state, _ := serializeStateSegment(d, pos)
+		writeStreamState(&end, streamEndID, state)
+		end.WriteString("<script>zuiStreamEnd()</script>")
+	})
+	end.Write(tail)
+	end.WriteTo(w)
+	flusher.Flush()
+	observeRender(time.Since(start))
+	return true
+}
+
+// pendingStreamBoundaries returns the outermost stream boundaries of the document that are still busy.
+func pendingStreamBoundaries(d *Document) []*ui.Element {
+	var res []*ui.Element
+	for _, b := range d.streamBoundaries {
+		if !b.Mounted() || !IsSubtreeBusy(b) {
+			continue
+		}
+		nested := false
+		for _, a := range res {
+			if ui.IsDescendantOf(b, a) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			res = append(res, b)
+		}
+	}
+	return res
+}
+
+// awaitStreamBoundary blocks until the subtree of the boundary is no longer busy, StreamTimeout has elapsed
+// or the request is cancelled.
+func awaitStreamBoundary(ctx context.Context, b *ui.Element) {
+	done := make(chan struct{})
+	ui.DoSync(func() {
+		if !IsSubtreeBusy(b) {
+			close(done)
+			return
+		}
+		var h *ui.MutationHandler
+		h = ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if bool(evt.NewValue().(ui.Bool)) {
+				return false
+			}
+			b.RemoveMutationHandler(Namespace.UI, "is-busy", b, h)
+			close(done)
+			return false
+		})
+		b.Watch(Namespace.UI, "is-busy", b, h)
+	})
+
+	timer := time.NewTimer(StreamTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// renderStreamShell renders the document with the children of the pending boundaries left out.
+// The rendered page is split before the closing body tag so that the chunks can be written in-between.
+func renderStreamShell(d *Document, pending []*ui.Element) (shell, tail []byte, pos int, err error) {
+	type detached struct {
+		node     *html.Node
+		children []*html.Node
+	}
+	var nodes []detached
+	for _, b := range pending {
+		n, ok := JSValue(b)
+		if !ok {
+			continue
+		}
+		node := n.Node()
+		var children []*html.Node
+		for c := node.FirstChild; c != nil; c = node.FirstChild {
+			node.RemoveChild(c)
+			children = append(children, c)
+		}
+		nodes = append(nodes, detached{node, children})
+		node.Attr = append(node.Attr, html.Attribute{Key: streamPendingAttribute})
+	}
+
+	root, _ := JSValue(d.AsElement())
+	head, _ := JSValue(d.Head())
+	state, pos := serializeStateSegment(d, 0)
+	statenode := newScriptNode(SSRStateElementID, "application/json", state)
+	runtime := newScriptNode("", "", streamScript)
+	head.Node().AppendChild(runtime)
+	head.Node().AppendChild(statenode)
+	root.Node().Attr = append(root.Node().Attr, html.Attribute{Key: streamDocumentAttribute})
+
+	defer func() {
+		head.Node().RemoveChild(statenode)
+		head.Node().RemoveChild(runtime)
+		root.Node().Attr = withoutAttribute(root.Node().Attr, streamDocumentAttribute)
+		for _, n := range nodes {
+			n.node.Attr = withoutAttribute(n.node.Attr, streamPendingAttribute)
+			for _, c := range n.children {
+				n.node.AppendChild(c)
+			}
+		}
+	}()
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, &html.Node{Type: html.DoctypeNode, Data: "html"}); err != nil {
+		return nil, nil, 0, err
+	}
+	if err := html.Render(&buf, root.Node()); err != nil {
+		return nil, nil, 0, err
+	}
+	page := buf.Bytes()
+	i := bytes.LastIndex(page, []byte("</body>"))
+	if i < 0 {
+		return page, nil, pos, nil
+	}
+	return page[:i], page[i:], pos, nil
+}
+
+// renderStreamChunk writes the children of a boundary within a template, along with the mutations recorded
+// since the previous chunk.
+func renderStreamChunk(w *bytes.Buffer, d *Document, b *ui.Element, from int) (int, error) {
+	n, ok := JSValue(b)
+	if !ok {
+		return from, nil
+	}
+	w.WriteString(`<template ` + streamBoundaryAttribute + `="` + template.HTMLEscapeString(b.ID) + `">`)
+	for c := n.Node().FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(w, c); err != nil {
+			return from, err
+		}
+	}
+	w.WriteString("</template>")
+
+	state, pos := serializeStateSegment(d, from)
+	writeStreamState(w, b.ID, state)
+	w.WriteString(`<script>zuiStreamChunk("` + template.JSEscapeString(b.ID) + `")</script>`)
+	return pos, nil
+}
+
+func writeStreamState(w *bytes.Buffer, id string, state string) {
+	html.Render(w, newScriptNode(streamStateElementPrefix+id, "application/json", state))
+}
+
+func newScriptNode(id, typ, content string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: "script"}
+	if id != "" {
+		n.Attr = append(n.Attr, html.Attribute{Key: "id", Val: id})
+	}
+	if typ != "" {
+		n.Attr = append(n.Attr, html.Attribute{Key: "type", Val: typ})
+	}
+	n.AppendChild(&html.Node{Type: html.TextNode, Data: content})
+	return n
+}
+
+func withoutAttribute(attrs []html.Attribute, key string) []html.Attribute {
+	res := attrs[:0]
+	for _, a := range attrs {
+		if a.Key != key {
+			res = append(res, a)
+		}
+	}
+	return res
+}
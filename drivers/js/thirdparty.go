@@ -0,0 +1,112 @@
+package doc
+
+import (
+	"log"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Third-party zones
+//
+// External libraries (maps, charts, ad tags, embeds...) build and mutate the DOM subtree of their host node
+// themselves. ThirdPartyZone marks an element as such a host:
+//   - the framework does not manage the native children of the host: zui elements cannot be inserted into it,
+//   - the host is rendered empty on the server, with a data-zui-zone attribute,
+//   - mount is called with the native host node once the element is mounted and connected to the native
+//     document, i.e. after mutation replay. The function it returns is called when the element is unmounted or
+//     deleted, and mount is called again if the element is mounted anew.
+//
+// The library owns the subtree between mount and unmount. It must not outlive the host.
+
+var thirdPartyZones = newscsmap[string, *thirdPartyZone]()
+
+type thirdPartyZone struct {
+	mount   func(host js.Value) (unmount func())
+	unmount func()
+}
+
+// ThirdPartyZone marks the element as the host of a DOM subtree owned by an external library.
+func ThirdPartyZone(e *ui.Element, mount func(host js.Value) (unmount func())) *ui.Element {
+	if _, ok := thirdPartyZones.Get(e.ID); ok {
+		panic("element " + e.ID + " is already a third-party zone")
+	}
+	if e.Children != nil && len(e.Children.List) > 0 {
+		panic("a third-party zone cannot have zui children: " + e.ID)
+	}
+	z := &thirdPartyZone{mount: mount}
+	thirdPartyZones.Set(e.ID, z)
+	setNativeAttribute(e, "data-zui-zone", "")
+
+	if !InBrowser() {
+		return e
+	}
+
+	e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if evt.Origin().Configuration.Disconnected {
+			evt.Origin().WatchEvent("connect-native", evt.Origin(), ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+				if evt.Origin().Mounted() {
+					z.mountOn(evt.Origin())
+				}
+				return false
+			}).RunOnce())
+			return false
+		}
+		z.mountOn(evt.Origin())
+		return false
+	}))
+
+	e.OnUnmounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		z.unmountFrom()
+		return false
+	}))
+
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		z.unmountFrom()
+		thirdPartyZones.Delete(evt.Origin().ID)
+		return false
+	}).RunOnce())
+
+	return e
+}
+
+// IsThirdPartyZone reports whether the element hosts a DOM subtree owned by an external library.
+func IsThirdPartyZone(e *ui.Element) bool {
+	_, ok := thirdPartyZones.Get(e.ID)
+	return ok
+}
+
+func (z *thirdPartyZone) mountOn(e *ui.Element) {
+	if z.unmount != nil {
+		return
+	}
+	host, ok := JSValue(e)
+	if !ok {
+		return
+	}
+	z.unmount = z.mount(host)
+	if z.unmount == nil {
+		z.unmount = func() {}
+	}
+}
+
+func (z *thirdPartyZone) unmountFrom() {
+	if z.unmount == nil {
+		return
+	}
+	u := z.unmount
+	z.unmount = nil
+	u()
+}
+
+// insertedInZone reports whether a zui child is being inserted into a third-party zone, which is not allowed.
+func insertedInZone(child *ui.Element) bool {
+	if child.Parent == nil {
+		return false
+	}
+	if _, ok := thirdPartyZones.Get(child.Parent.ID); !ok {
+		return false
+	}
+	log.Print("third-party zone " + child.Parent.ID + " does not accept zui children. Cannot insert " + child.ID)
+	return true
+}
@@ -0,0 +1,222 @@
+package doc
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Time-based state
+//
+// Clock returns an observable whose "now" data property is updated at every tick of the given interval.
+// Ticks are aligned on multiples of the interval, so that a clock with a one minute interval ticks when the
+// minute changes. Clocks are shared: every call with the same interval returns the same observable.
+//
+// Countdown returns an observable whose "remaining" data property (ui.Number) holds the number of seconds left
+// until a deadline, rounded up. Once the deadline is reached, "finished" (ui.Bool) becomes true and the
+// "countdown-finished" event is triggered on the observable.
+//
+// RelativeTime is an element modifier displaying a date relative to now ("3 minutes ago", "in 2 days"). The
+// text is refreshed as often as needed for it to remain accurate: every few seconds for recent dates, then
+// every minute, hour or day. It is formatted in the language of the document, using Intl.RelativeTimeFormat in
+// the browser, and in english otherwise.
+//
+// These replace Modifier.OnTick for the common cases. Ticks are only scheduled while the element or observable
+// exists.
+
+// Clock returns the observable clock ticking at the given interval. Its "now" data property is the current
+// time in RFC 3339 format, with milliseconds, and its "unix" data property is the current unix time in
+// milliseconds.
+func (d *Document) Clock(interval time.Duration) ui.Observable {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	id := "zui-clock-" + interval.String()
+	if e := d.GetElementById(id); e != nil {
+		return ui.Observable{UIElement: e}
+	}
+	o := d.NewObservable(id)
+	e := o.AsElement()
+	set := func(now time.Time) {
+		e.SetData("now", ui.String(now.Format("2006-01-02T15:04:05.000Z07:00")))
+		e.SetData("unix", ui.Number(now.UnixMilli()))
+	}
+	set(time.Now())
+
+	var timer *time.Timer
+	var schedule func()
+	schedule = func() {
+		now := time.Now()
+		timer = time.AfterFunc(now.Truncate(interval).Add(interval).Sub(now), func() {
+			ui.DoSync(func() {
+				set(time.Now())
+				schedule()
+			})
+		})
+	}
+	schedule()
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		timer.Stop()
+		return false
+	}).RunOnce())
+	return o
+}
+
+// Countdown returns an observable counting down the seconds until the deadline.
+func (d *Document) Countdown(id string, deadline time.Time) ui.Observable {
+	o := d.NewObservable(id)
+	e := o.AsElement()
+
+	var timer *time.Timer
+	var tick func()
+	tick = func() {
+		left := time.Until(deadline)
+		if left <= 0 {
+			e.SetData("remaining", ui.Number(0))
+			if v, ok := e.GetData("finished"); !ok || !bool(v.(ui.Bool)) {
+				e.SetData("finished", ui.Bool(true))
+				e.TriggerEvent("countdown-finished")
+			}
+			return
+		}
+		e.SetData("remaining", ui.Number(math.Ceil(left.Seconds())))
+		e.SetData("finished", ui.Bool(false))
+		// the next tick happens when the number of remaining seconds changes.
+		next := left - left.Truncate(time.Second)
+		if next == 0 {
+			next = time.Second
+		}
+		timer = time.AfterFunc(next, func() { ui.DoSync(tick) })
+	}
+	tick()
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if timer != nil {
+			timer.Stop()
+		}
+		return false
+	}).RunOnce())
+	return o
+}
+
+// RelativeTime returns an element modifier displaying the date t relative to now. The absolute date is
+// available as the title of the element.
+func RelativeTime(t time.Time) func(*ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		var timer *time.Timer
+		stop := func() {
+			if timer != nil {
+				timer.Stop()
+				timer = nil
+			}
+		}
+		var render func()
+		render = func() {
+			stop()
+			lang := ""
+			if v, ok := GetDocument(e).GetUI("lang"); ok {
+				lang = string(v.(ui.String))
+			}
+			now := time.Now()
+			SetTextContent(e, FormatRelativeTime(t, now, lang))
+			timer = time.AfterFunc(relativeTimeRefresh(t, now), func() { ui.DoSync(render) })
+		}
+
+		SetAttribute(e, "title", t.Format(time.RFC1123))
+		e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			render()
+			return false
+		}))
+		e.OnUnmounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			stop()
+			return false
+		}))
+		e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			stop()
+			return false
+		}).RunOnce())
+		e.Watch(Namespace.UI, "lang", GetDocument(e), ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			if e.Mounted() {
+				render()
+			}
+			return false
+		}))
+		return e
+	}
+}
+
+var relativeUnits = []struct {
+	unit string
+	d    time.Duration
+}{
+	{"year", 365 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+	{"second", time.Second},
+}
+
+func relativeUnit(delta time.Duration) (int, string) {
+	abs := delta
+	if abs < 0 {
+		abs = -abs
+	}
+	for _, u := range relativeUnits {
+		if abs >= u.d {
+			n := int(math.Round(float64(delta) / float64(u.d)))
+			return n, u.unit
+		}
+	}
+	return 0, "second"
+}
+
+// relativeTimeRefresh returns the delay after which the relative representation of t may change.
+func relativeTimeRefresh(t, now time.Time) time.Duration {
+	delta := now.Sub(t)
+	if delta < 0 {
+		delta = -delta
+	}
+	switch {
+	case delta < time.Minute:
+		return 10 * time.Second
+	case delta < time.Hour:
+		return time.Minute
+	case delta < 24*time.Hour:
+		return 10 * time.Minute
+	default:
+		return time.Hour
+	}
+}
+
+// FormatRelativeTime returns the representation of t relative to now in the given language. Outside of the
+// browser, or if the language is not supported, the representation is in english.
+func FormatRelativeTime(t, now time.Time, lang string) string {
+	n, unit := relativeUnit(t.Sub(now))
+	if InBrowser() && js.Global().Get("Intl").Truthy() {
+		var locale interface{} = js.Undefined()
+		if lang != "" {
+			locale = lang
+		}
+		f := js.Global().Get("Intl").Get("RelativeTimeFormat").New(locale, map[string]interface{}{"numeric": "auto"})
+		return f.Call("format", n, unit).String()
+	}
+	if n == 0 && unit == "second" {
+		return "now"
+	}
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	s := strconv.Itoa(abs) + " " + unit
+	if abs != 1 {
+		s += "s"
+	}
+	if n < 0 {
+		return s + " ago"
+	}
+	return "in " + s
+}
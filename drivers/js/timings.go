@@ -0,0 +1,203 @@
+package doc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Lifecycle timings
+//
+// When enabled (by default in dev mode), the lifecycle of each element created via the document constructors
+// is timed. All durations are measured from the start of the constructor call:
+//   - Constructed: the element has been created and registered
+//   - Connected: the element is connected to its native DOM node
+//   - Mounted: the element is mounted for the first time
+//   - Hydrated: the document state has been replayed (only for elements created during hydration)
+//
+// Timings are aggregated per constructor in order to identify slow components.
+// In the browser, the report is also available from the console by calling zuiLifecycleReport().
+
+// LifecycleTiming holds the lifecycle timings of an element. A zero duration means that the corresponding
+// stage has not been reached.
+type LifecycleTiming struct {
+	ID          string
+	Constructor string
+	Constructed time.Duration
+	Connected   time.Duration
+	Mounted     time.Duration
+	Hydrated    time.Duration
+}
+
+// ConstructorTimings aggregates the lifecycle timings of the elements created by a given constructor.
+type ConstructorTimings struct {
+	Constructor      string
+	Count            int
+	TotalConstructed time.Duration
+	MaxConstructed   time.Duration
+	Mounts           int
+	TotalMounted     time.Duration
+	MaxMounted       time.Duration
+}
+
+// MeanConstructed returns the average construction duration.
+func (c ConstructorTimings) MeanConstructed() time.Duration {
+	if c.Count == 0 {
+		return 0
+	}
+	return c.TotalConstructed / time.Duration(c.Count)
+}
+
+// MeanMounted returns the average duration until first mount.
+func (c ConstructorTimings) MeanMounted() time.Duration {
+	if c.Mounts == 0 {
+		return 0
+	}
+	return c.TotalMounted / time.Duration(c.Mounts)
+}
+
+// LifecycleReport lists the aggregated timings per constructor, slowest first.
+type LifecycleReport []ConstructorTimings
+
+func (r LifecycleReport) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "constructor\tcount\ttotal\tmean\tmax\tmounts\tmean mount\tmax mount")
+	for _, c := range r {
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%v\t%d\t%v\t%v\n", c.Constructor, c.Count, c.TotalConstructed, c.MeanConstructed(), c.MaxConstructed, c.Mounts, c.MeanMounted(), c.MaxMounted)
+	}
+	w.Flush()
+	return b.String()
+}
+
+type lifecycleTimings struct {
+	elements   map[string]*LifecycleTiming
+	aggregates map[string]*ConstructorTimings
+}
+
+// EnableLifecycleTimings starts recording the lifecycle timings of the elements created from now on.
+func (d *Document) EnableLifecycleTimings() *Document {
+	if d.timings != nil {
+		return d
+	}
+	d.timings = &lifecycleTimings{make(map[string]*LifecycleTiming), make(map[string]*ConstructorTimings)}
+
+	if InBrowser() {
+		js.Global().Set("zuiLifecycleReport", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return d.LifecycleReport().String()
+		}))
+	}
+	return d
+}
+
+// LifecycleTiming returns the lifecycle timings recorded for an element, if any.
+func (d *Document) LifecycleTiming(id string) (LifecycleTiming, bool) {
+	if d.timings == nil {
+		return LifecycleTiming{}, false
+	}
+	t, ok := d.timings.elements[id]
+	if !ok {
+		return LifecycleTiming{}, false
+	}
+	return *t, true
+}
+
+// LifecycleReport returns the lifecycle timings aggregated per constructor, sorted by total construction time.
+func (d *Document) LifecycleReport() LifecycleReport {
+	if d.timings == nil {
+		return nil
+	}
+	r := make(LifecycleReport, 0, len(d.timings.aggregates))
+	for _, a := range d.timings.aggregates {
+		r = append(r, *a)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].TotalConstructed == r[j].TotalConstructed {
+			return r[i].Constructor < r[j].Constructor
+		}
+		return r[i].TotalConstructed > r[j].TotalConstructed
+	})
+	return r
+}
+
+// constructionStart returns the time at which the construction of an element starts. It is the zero time
+// when neither lifecycle timings nor construction hooks are enabled, so that the clock is not read for nothing.
+func (d *Document) constructionStart() time.Time {
+	if d.timings == nil && len(d.constructionHooks) == 0 {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// recordConstruction runs the construction hooks and starts tracking the lifecycle of an element whose
+// constructor was called at time start. Nothing is recorded if start is the zero time.
+func (d *Document) recordConstruction(e *ui.Element, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	if len(d.constructionHooks) > 0 {
+		elapsed := time.Since(start)
+		for _, h := range d.constructionHooks {
+			h(e, elapsed)
+		}
+	}
+	if d.timings == nil {
+		return
+	}
+	constructor := "unknown"
+	if c, ok := e.Get(Namespace.Internals, "constructor"); ok {
+		constructor = string(c.(ui.String))
+	}
+	t := &LifecycleTiming{ID: e.ID, Constructor: constructor, Constructed: time.Since(start)}
+	d.timings.elements[e.ID] = t
+
+	a, ok := d.timings.aggregates[constructor]
+	if !ok {
+		a = &ConstructorTimings{Constructor: constructor}
+		d.timings.aggregates[constructor] = a
+	}
+	a.Count++
+	a.TotalConstructed += t.Constructed
+	if t.Constructed > a.MaxConstructed {
+		a.MaxConstructed = t.Constructed
+	}
+
+	if e.Native != nil {
+		t.Connected = t.Constructed
+	} else {
+		e.WatchEvent("connect-native", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			t.Connected = time.Since(start)
+			return false
+		}).RunOnce())
+	}
+
+	if replaying, ok := d.Get(Namespace.Internals, "mutation-replaying"); ok && bool(replaying.(ui.Bool)) {
+		e.WatchEvent("mutation-replayed", e, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			t.Hydrated = time.Since(start)
+			return false
+		}).RunOnce())
+	}
+
+	e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if t.Mounted != 0 {
+			return false
+		}
+		t.Mounted = time.Since(start)
+		a.Mounts++
+		a.TotalMounted += t.Mounted
+		if t.Mounted > a.MaxMounted {
+			a.MaxMounted = t.Mounted
+		}
+		return false
+	}))
+
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		delete(d.timings.elements, evt.Origin().ID)
+		return false
+	}).RunOnce())
+}
@@ -0,0 +1,18 @@
+package doc
+
+import "testing"
+
+func TestLifecycleTimings(t *testing.T) {
+	d := newTestDocument(t, "timingstest")
+
+	d.Div.WithID("untimed")
+	if _, ok := d.LifecycleTiming("untimed"); ok {
+		t.Fatal("no timing should be recorded before lifecycle timings are enabled")
+	}
+
+	d.EnableLifecycleTimings()
+	d.Div.WithID("timed")
+	if _, ok := d.LifecycleTiming("timed"); !ok {
+		t.Fatal("the construction should have been recorded")
+	}
+}
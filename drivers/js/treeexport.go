@@ -0,0 +1,194 @@
+package doc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Tree export
+//
+// ExportTree writes the logical tree rooted at an element in the form of the declarative DSL, so that a
+// developer can see what a running app actually built, or paste it in a bug report:
+//
+//	E(document.Div.WithID("card"),
+//		Class("card", "active"),
+//		Attr("role", "region"),
+//		Children(
+//			E(document.H1.WithID("title"), Text("Hello")),
+//		),
+//	)
+//
+// The output is pseudo-code: the modifiers describe the state of the elements (classes, attributes, text,
+// value) and not how it was obtained. Observables and elements that are not part of the tree are omitted.
+// The component an element is the root of and its declared props are reported as comments.
+//
+// With a debug profile, zuiExportTree() returns the export of the document in the browser console, or of the
+// element of the given id.
+
+// ExportTree returns the declarative form of the logical tree rooted at e.
+func ExportTree(e *ui.Element) string {
+	var b strings.Builder
+	exportElement(&b, e, 0)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// ExportTree returns the declarative form of the logical tree of the document.
+func (d *Document) ExportTree() string {
+	return ExportTree(d.AsElement())
+}
+
+func enableTreeExport(d *Document) {
+	if !InBrowser() {
+		return
+	}
+	js.Global().Set("zuiExportTree", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) > 0 && args[0].Truthy() {
+			e := d.GetElementById(args[0].String())
+			if e == nil {
+				return "no element of id " + args[0].String()
+			}
+			return ExportTree(e)
+		}
+		return d.ExportTree()
+	}))
+}
+
+func exportElement(b *strings.Builder, e *ui.Element, depth int) {
+	indent := strings.Repeat("\t", depth)
+	var modifiers []string
+
+	if classes := quoteAll(Classes(e)); classes != "" {
+		modifiers = append(modifiers, "Class("+classes+")")
+	}
+	if m, ok := e.Get(Namespace.Data, "attrs"); ok {
+		var names []string
+		attrs := make(map[string]string)
+		m.(ui.Object).Range(func(key string, val ui.Value) bool {
+			if s, ok := val.(ui.String); ok {
+				names = append(names, key)
+				attrs[key] = string(s)
+			}
+			return false
+		})
+		sort.Strings(names)
+		for _, name := range names {
+			modifiers = append(modifiers, "Attr("+strconv.Quote(name)+", "+strconv.Quote(attrs[name])+")")
+		}
+	}
+	if v, ok := e.GetData("value"); ok {
+		modifiers = append(modifiers, "Value("+exportValue(v)+")")
+	}
+	if v, ok := e.GetData("text"); ok {
+		if s, ok := v.(ui.String); ok && s != "" {
+			modifiers = append(modifiers, "Text("+strconv.Quote(string(s))+")")
+		}
+	}
+
+	var children []*ui.Element
+	if e.Children != nil {
+		for _, c := range e.Children.List {
+			if elementType(c) != "observable" {
+				children = append(children, c)
+			}
+		}
+	}
+
+	var comments []string
+	if c, ok := ComponentOf(e); ok {
+		comments = append(comments, "component "+c)
+	}
+	if names := ui.PropNames(e); len(names) > 0 {
+		props := ui.Props(e)
+		var parts []string
+		for _, name := range names {
+			if v, ok := props.Get(name); ok {
+				parts = append(parts, name+"="+exportValue(v))
+			}
+		}
+		if len(parts) > 0 {
+			comments = append(comments, "props "+strings.Join(parts, ", "))
+		}
+	}
+
+	b.WriteString("E(" + exportConstructor(e))
+	if len(children) == 0 && len(modifiers) <= 1 && len(comments) == 0 {
+		for _, m := range modifiers {
+			b.WriteString(", " + m)
+		}
+		b.WriteString(")")
+		return
+	}
+	b.WriteString(",")
+	if len(comments) > 0 {
+		b.WriteString(" // " + strings.Join(comments, "; "))
+	}
+	for _, m := range modifiers {
+		b.WriteString("\n" + indent + "\t" + m + ",")
+	}
+	if len(children) > 0 {
+		b.WriteString("\n" + indent + "\tChildren(")
+		for _, c := range children {
+			b.WriteString("\n" + indent + "\t\t")
+			exportElement(b, c, depth+2)
+			b.WriteString(",")
+		}
+		b.WriteString("\n" + indent + "\t),")
+	}
+	b.WriteString("\n" + indent + ")")
+}
+
+// exportConstructor returns the expression constructing an element.
+func exportConstructor(e *ui.Element) string {
+	c := elementType(e)
+	switch c {
+	case "html":
+		if e.IsRoot() {
+			return "document"
+		}
+	case "body":
+		return "document.Body()"
+	case "head":
+		return "document.Head()"
+	case "p":
+		c = "Paragraph"
+	case "a":
+		c = "Anchor"
+	case "textarea":
+		c = "TextArea"
+	case "noscript":
+		c = "NoScript"
+	case "colgroup":
+		c = "ColGroup"
+	}
+	if c == "" {
+		c = "element"
+	}
+	return "document." + strings.ToUpper(c[:1]) + c[1:] + ".WithID(" + strconv.Quote(e.ID) + ")"
+}
+
+func exportValue(v ui.Value) string {
+	switch t := v.(type) {
+	case ui.String:
+		return strconv.Quote(string(t))
+	case ui.Bool:
+		return strconv.FormatBool(bool(t))
+	case ui.Number:
+		return strconv.FormatFloat(float64(t), 'f', -1, 64)
+	}
+	return v.ValueType()
+}
+
+func quoteAll(s []string) string {
+	q := make([]string, 0, len(s))
+	for _, v := range s {
+		if v != "" {
+			q = append(q, strconv.Quote(v))
+		}
+	}
+	return strings.Join(q, ", ")
+}
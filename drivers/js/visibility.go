@@ -0,0 +1,167 @@
+package doc
+
+import (
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// Visibility-aware pausing
+//
+// Modifier.PauseWhenHidden opts an element into pausing its activity while the document is hidden:
+//   - audio and video elements which are playing are paused,
+//   - animation loops registered with OnFrame stop requesting frames,
+//   - pollers started with Poll are paused (they always are, the policy only controls whether they resume).
+//
+// When the document becomes visible again, the activity resumes if the policy says so. Media elements only
+// resume if they were paused by the policy, not if the user paused them.
+// The policy is stored in the "pausewhenhidden" internal property of the element.
+
+// PauseWhenHidden returns an element modifier pausing the activity of the element while the document is
+// hidden. If resume is true, the activity resumes once the document is visible again.
+func (m modifier) PauseWhenHidden(resume bool) func(e *ui.Element) *ui.Element {
+	return func(e *ui.Element) *ui.Element {
+		e.Set(Namespace.Internals, "pausewhenhidden", ui.NewObject().Set("resume", ui.Bool(resume)).Commit())
+		if !InBrowser() {
+			return e
+		}
+		if !isMediaElement(e) {
+			return e
+		}
+		e.Watch(Namespace.UI, "visibilitystate", GetDocument(e), ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+			n, ok := JSValue(e)
+			if !ok {
+				return false
+			}
+			if documentHidden(GetDocument(e)) {
+				if !n.Get("paused").Bool() {
+					n.Call("pause")
+					e.Set(Namespace.Internals, "pausedwhenhidden", ui.Bool(true))
+				}
+				return false
+			}
+			if v, ok := e.Get(Namespace.Internals, "pausedwhenhidden"); ok && bool(v.(ui.Bool)) {
+				e.Set(Namespace.Internals, "pausedwhenhidden", ui.Bool(false))
+				if resumeWhenVisible(e) {
+					// playback may be refused by the autoplay policy of the browser.
+					n.Call("play").Call("catch", js.Global().Get("Function").New())
+				}
+			}
+			return false
+		}))
+		return e
+	}
+}
+
+func isMediaElement(e *ui.Element) bool {
+	n, ok := JSValue(e)
+	if !ok {
+		return false
+	}
+	return n.InstanceOf(js.Global().Get("HTMLMediaElement"))
+}
+
+func documentHidden(d *Document) bool {
+	v, ok := d.GetUI("visibilitystate")
+	return ok && string(v.(ui.String)) == "hidden"
+}
+
+// pausedWhenHidden returns whether an element follows the pause policy.
+func pausedWhenHidden(e *ui.Element) bool {
+	_, ok := e.Get(Namespace.Internals, "pausewhenhidden")
+	return ok
+}
+
+// resumeWhenVisible returns whether the activity of an element should resume when the document becomes visible.
+// Elements without policy resume.
+func resumeWhenVisible(e *ui.Element) bool {
+	v, ok := e.Get(Namespace.Internals, "pausewhenhidden")
+	if !ok {
+		return true
+	}
+	r, ok := v.(ui.Object).Get("resume")
+	return !ok || bool(r.(ui.Bool))
+}
+
+// OnFrame runs fn at every animation frame while the element is mounted, with the time elapsed since the
+// loop started. If the element follows the PauseWhenHidden policy, frames are not requested while the
+// document is hidden. The returned function stops the loop.
+func OnFrame(e *ui.Element, fn func(elapsed time.Duration)) (stop func()) {
+	if !InBrowser() {
+		return func() {}
+	}
+	d := GetDocument(e)
+	var start time.Time
+	var running, stopped bool
+	var cb js.Func
+	var handle js.Value
+
+	request := func() {
+		handle = js.Global().Call("requestAnimationFrame", cb)
+	}
+	cb = RegisterCallback(e, func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			if !running {
+				return
+			}
+			fn(time.Since(start))
+			request()
+		})
+		return nil
+	})
+
+	run := func() {
+		if running || stopped || !e.Mounted() {
+			return
+		}
+		if pausedWhenHidden(e) && documentHidden(d) {
+			return
+		}
+		if start.IsZero() {
+			start = time.Now()
+		}
+		running = true
+		request()
+	}
+	pause := func() {
+		if !running {
+			return
+		}
+		running = false
+		js.Global().Call("cancelAnimationFrame", handle)
+	}
+	stop = func() {
+		pause()
+		stopped = true
+	}
+
+	e.OnMounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		run()
+		return false
+	}))
+	e.OnUnmounted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		pause()
+		return false
+	}))
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		stop()
+		return false
+	}).RunOnce())
+	e.Watch(Namespace.UI, "visibilitystate", d, ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		if !pausedWhenHidden(e) {
+			return false
+		}
+		if documentHidden(d) {
+			pause()
+			return false
+		}
+		if resumeWhenVisible(e) {
+			run()
+		}
+		return false
+	}))
+
+	run()
+	return stop
+}
@@ -0,0 +1,228 @@
+package doc
+
+import (
+	"errors"
+	"time"
+
+	ui "github.com/atdiar/particleui"
+	js "github.com/atdiar/particleui/drivers/js/compat"
+)
+
+// WebSocket observables
+//
+// WebSocket returns an observable element wrapping a WebSocket connection, so that live data fits the
+// mutation handler model:
+//   - every incoming text message is stored in the "message" data property (ui.String) and triggers a
+//     "message" event on the element, whose value is the message, so that identical consecutive messages are
+//     not missed,
+//   - the "state" ui property (ui.String) holds the state of the connection: "connecting", "open", "closing",
+//     "closed", or "error" after a failure. The "retries" ui property (ui.Number) counts the reconnection
+//     attempts since the connection was last open.
+//
+// When reconnection is enabled, a connection that is lost is reopened after a delay that doubles after each
+// failed attempt, from MinBackoff up to MaxBackoff. Messages sent while the socket is not open are queued.
+// The connection is closed for good when the element is deleted.
+// Outside of the browser, the state remains "closed".
+
+// WebSocketOptions configures a WebSocket observable.
+type WebSocketOptions struct {
+	ID         string   // id of the observable. By default, it is derived from the url.
+	Protocols  []string // subprotocols requested to the server
+	Reconnect  bool
+	MinBackoff time.Duration // defaults to one second
+	MaxBackoff time.Duration // defaults to one minute
+	MaxRetries int           // zero means no limit
+}
+
+// WebSocket is an observable wrapping a WebSocket connection.
+type WebSocket struct {
+	*ui.Element
+}
+
+type webSocketState struct {
+	url     string
+	options WebSocketOptions
+	socket  js.Value
+	queue   []string
+	retries int
+	delay   time.Duration
+	timer   *time.Timer
+	closed  bool
+}
+
+var webSockets = newscsmap[*ui.Element, *webSocketState]()
+
+// ErrWebSocketClosed is returned when sending a message on a WebSocket that was closed.
+var ErrWebSocketClosed = errors.New("websocket is closed")
+
+// WebSocket returns the observable connected to the given url. Calls with the same id return the same
+// observable.
+func (d *Document) WebSocket(url string, options ...WebSocketOptions) WebSocket {
+	var o WebSocketOptions
+	if len(options) > 0 {
+		o = options[0]
+	}
+	if o.ID == "" {
+		o.ID = "zui-websocket-" + url
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = time.Second
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = time.Minute
+	}
+
+	if e := d.GetElementById(o.ID); e != nil {
+		if _, ok := webSockets.Get(e); ok {
+			return WebSocket{e}
+		}
+	}
+	e := d.NewObservable(o.ID).AsElement()
+	s := &webSocketState{url: url, options: o, delay: o.MinBackoff}
+	webSockets.Set(e, s)
+	e.SetUI("state", ui.String("closed"))
+	e.SetUI("retries", ui.Number(0))
+
+	e.OnDeleted(ui.NewMutationHandler(func(evt ui.MutationEvent) bool {
+		s.close()
+		webSockets.Delete(evt.Origin())
+		return false
+	}).RunOnce())
+
+	if InBrowser() && js.Global().Get("WebSocket").Truthy() {
+		s.connect(e)
+	}
+	return WebSocket{e}
+}
+
+func (s *webSocketState) connect(e *ui.Element) {
+	var protocols []interface{}
+	for _, p := range s.options.Protocols {
+		protocols = append(protocols, p)
+	}
+	ws := js.Global().Get("WebSocket").New(s.url, js.ValueOf(protocols))
+	s.socket = ws
+	e.SetUI("state", ui.String("connecting"))
+
+	var onopen, onmessage, onerror, onclose js.Func
+	onopen = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			s.retries = 0
+			s.delay = s.options.MinBackoff
+			e.SetUI("retries", ui.Number(0))
+			e.SetUI("state", ui.String("open"))
+			queue := s.queue
+			s.queue = nil
+			for _, msg := range queue {
+				ws.Call("send", msg)
+			}
+		})
+		return nil
+	})
+	onmessage = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		msg := ui.String(args[0].Get("data").String())
+		go ui.DoSync(func() {
+			e.SetData("message", msg)
+			e.TriggerEvent("message", msg)
+		})
+		return nil
+	})
+	onerror = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		go ui.DoSync(func() {
+			e.SetUI("state", ui.String("error"))
+		})
+		return nil
+	})
+	onclose = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		onopen.Release()
+		onmessage.Release()
+		onerror.Release()
+		onclose.Release()
+		go ui.DoSync(func() {
+			if v, ok := e.GetUI("state"); !ok || string(v.(ui.String)) != "error" {
+				e.SetUI("state", ui.String("closed"))
+			}
+			s.reconnect(e)
+		})
+		return nil
+	})
+	ws.Call("addEventListener", "open", onopen)
+	ws.Call("addEventListener", "message", onmessage)
+	ws.Call("addEventListener", "error", onerror)
+	ws.Call("addEventListener", "close", onclose)
+}
+
+func (s *webSocketState) reconnect(e *ui.Element) {
+	if s.closed || !s.options.Reconnect {
+		return
+	}
+	if s.options.MaxRetries > 0 && s.retries >= s.options.MaxRetries {
+		return
+	}
+	s.retries++
+	e.SetUI("retries", ui.Number(s.retries))
+	delay := s.delay
+	if s.delay *= 2; s.delay > s.options.MaxBackoff {
+		s.delay = s.options.MaxBackoff
+	}
+	s.timer = time.AfterFunc(delay, func() {
+		ui.DoSync(func() {
+			if !s.closed {
+				s.connect(e)
+			}
+		})
+	})
+}
+
+func (s *webSocketState) close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if s.socket.Truthy() {
+		s.socket.Call("close")
+	}
+}
+
+// Send sends a text message, or queues it until the socket is open.
+func (w WebSocket) Send(msg string) error {
+	s, ok := webSockets.Get(w.Element)
+	if !ok || s.closed {
+		return ErrWebSocketClosed
+	}
+	if !s.socket.Truthy() || s.socket.Get("readyState").Int() != 1 {
+		s.queue = append(s.queue, msg)
+		return nil
+	}
+	s.socket.Call("send", msg)
+	return nil
+}
+
+// Close closes the connection for good. The observable remains available until it is deleted.
+func (w WebSocket) Close() {
+	s, ok := webSockets.Get(w.Element)
+	if !ok {
+		return
+	}
+	if !s.closed && s.socket.Truthy() {
+		w.SetUI("state", ui.String("closing"))
+	}
+	s.close()
+}
+
+// State returns the state of the connection.
+func (w WebSocket) State() string {
+	v, ok := w.GetUI("state")
+	if !ok {
+		return "closed"
+	}
+	return string(v.(ui.String))
+}
+
+// OnMessage registers a handler called with every incoming message.
+func (w WebSocket) OnMessage(h *ui.MutationHandler) {
+	w.WatchEvent("message", w, h)
+}
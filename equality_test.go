@@ -0,0 +1,72 @@
+package ui
+
+import "testing"
+
+func TestIdentical(t *testing.T) {
+	l := NewList(String("a"), String("b")).Commit()
+	same := l
+	if !Identical(l, same) {
+		t.Error("expected a list to be identical to itself")
+	}
+	if Identical(l, NewList(String("a"), String("b")).Commit()) {
+		t.Error("expected equal lists with different storage not to be identical")
+	}
+	if Identical(NewList().Commit(), NewList().Commit()) {
+		t.Error("expected distinct empty lists not to be identical")
+	}
+	if Identical(NewListFrom(make([]Value, 0)), NewListFrom(make([]Value, 0))) {
+		t.Error("expected distinct empty lists without storage not to be identical")
+	}
+	if !Identical(List{}, List{}) {
+		t.Error("expected nil lists to be identical")
+	}
+
+	backing := []Value{String("a"), String("b"), String("c")}
+	if Identical(NewListFrom(backing[:2]), NewListFrom(backing)) {
+		t.Error("expected lists sharing storage but of different lengths not to be identical")
+	}
+	if Identical(NewListFrom(backing[1:]), NewListFrom(backing[:2])) {
+		t.Error("expected lists starting at different positions of the same storage not to be identical")
+	}
+	if Identical(NewListFrom(backing[:0]), NewListFrom(backing[:0:0])) {
+		t.Error("expected empty lists of different capacities not to be identical")
+	}
+
+	o := NewObject().Set("a", Number(1)).Commit()
+	if !Identical(o, o) || Identical(o, NewObject().Set("a", Number(1)).Commit()) {
+		t.Error("expected objects to be compared by reference")
+	}
+	if !Identical(String("s"), String("s")) {
+		t.Error("expected scalar values to be compared by value")
+	}
+}
+
+func TestSetSkipsEqualValues(t *testing.T) {
+	c := NewConfiguration("equalitytest", "test")
+	e := c.NewElement("e", "test")
+
+	count := 0
+	e.Watch(Namespace.Data, "items", e, NewMutationHandler(func(evt MutationEvent) bool {
+		count++
+		return false
+	}))
+
+	e.SetData("items", NewList(String("a"), String("b")).Commit())
+	e.SetData("items", NewList(String("a"), String("b")).Commit())
+	if count != 1 {
+		t.Errorf("expected 1 mutation event for equal values, got %d", count)
+	}
+
+	e.SetEqualityFunc(Namespace.Data, "items", Identical)
+	e.SetData("items", NewList(String("a"), String("b")).Commit())
+	if count != 2 {
+		t.Errorf("expected a mutation event for a non-identical value, got %d", count)
+	}
+
+	e.SetEqualityFunc(Namespace.Data, "items", NeverEqual)
+	v, _ := e.GetData("items")
+	e.SetData("items", v)
+	if count != 3 {
+		t.Errorf("expected a mutation event when equality checking is disabled, got %d", count)
+	}
+}
@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// Route error views
+//
+// When a navigation fails, the "navigation-appfailure" event is triggered on the root of the document with an
+// error object describing the failure:
+//   - "route" (String): the route being navigated to
+//   - "kind" (String): one of "navigation", "loader" or "panic"
+//   - "message" (String): the error message
+//   - "stack" (String): the stack trace, for panics only
+//   - "view" (String): the id of the view targeted by the route, if known
+//
+// Views may register an error view factory which receives this object, e.g. to display a contextual retry
+// button instead of a generic failure message. The factory of the closest view, from the targeted view up to
+// the router outlet, is used. Otherwise, the router falls back to its own factory, if any.
+// The error view is displayed in the view element whose factory produced it, or in the body of the document
+// for the router-level factory and the default failure message.
+// Loaders report their errors with Router.Fail. Panics occurring during view activation are recovered and
+// reported as well.
+
+// ErrorViewFactory returns the element displayed in place of a view when a navigation fails.
+type ErrorViewFactory func(errobj Object) *Element
+
+var errorViews = newscsmap[*Element, ErrorViewFactory]()
+
+// SetErrorView registers the factory of the error view displayed when a navigation to this view, or one
+// of its subviews, fails.
+func (v ViewElement) SetErrorView(f ErrorViewFactory) ViewElement {
+	e := v.AsElement()
+	if _, ok := errorViews.Get(e); !ok {
+		e.OnDeleted(NewMutationHandler(func(evt MutationEvent) bool {
+			errorViews.Delete(evt.Origin())
+			return false
+		}).RunOnce())
+	}
+	errorViews.Set(e, f)
+	return v
+}
+
+// SetErrorView registers the factory of the error view used when no view along the failed route has one.
+func (r *Router) SetErrorView(f ErrorViewFactory) *Router {
+	r.errorView = f
+	return r
+}
+
+// ErrorView returns the error view for a navigation failure, and the view element it should be displayed
+// in. The view element is the zero ViewElement when the router-level factory is used.
+func (r *Router) ErrorView(errobj Object) (ViewElement, *Element, bool) {
+	root := r.Outlet.AsElement().Root
+	if id, ok := errobj.Get("view"); ok {
+		e := GetById(root, string(id.(String)))
+		for ; e != nil; e = e.Parent {
+			if !e.isViewElement() {
+				continue
+			}
+			if f, ok := errorViews.Get(e); ok {
+				return ViewElement{e}, f(errobj), true
+			}
+			if e == r.Outlet.AsElement() {
+				break
+			}
+		}
+	}
+	if r.errorView != nil {
+		return ViewElement{}, r.errorView(errobj), true
+	}
+	return ViewElement{}, nil, false
+}
+
+// NewNavigationError returns the error object describing a navigation failure.
+func NewNavigationError(route string, kind string, err error) Object {
+	o := NewObject()
+	o.Set("route", String(route))
+	o.Set("kind", String(kind))
+	if err == nil {
+		err = ErrFrameworkFailure
+	}
+	o.Set("message", String(err.Error()))
+	return o.Commit()
+}
+
+// Fail reports the failure of the navigation to a route. kind is typically "loader" for data loading errors.
+func (r *Router) Fail(route string, kind string, err error) {
+	r.fail(route, kind, err, "")
+}
+
+func (r *Router) fail(route string, kind string, err error, stack string) {
+	root := r.Outlet.AsElement().Root
+	o := NewNavigationError(route, kind, err).MakeCopy()
+	if stack != "" {
+		o.Set("stack", String(stack))
+	}
+	if v, ok := root.Get(Namespace.Navigation, "targetviewid"); ok {
+		o.Set("view", v)
+	}
+	root.TriggerEvent("navigation-appfailure", o.Commit())
+}
+
+// activate runs a view activation function, recovering from its panics which are reported as navigation
+// failures.
+func (r *Router) activate(route string, a func() error) (err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		err = errActivationPanic
+		r.fail(route, "panic", fmt.Errorf("%v", p), string(debug.Stack()))
+	}()
+	return a()
+}
+
+var errActivationPanic = errors.New("view activation panicked")
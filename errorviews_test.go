@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorViews(t *testing.T) {
+	c := NewConfiguration("errorviewstest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	root := c.NewAppRoot("root")
+	app, home, admin := newdiv("app"), newdiv("home"), newdiv("admin")
+	for _, e := range []*Element{app, home, admin} {
+		RegisterElement(root, e)
+	}
+	outlet := NewViewElement(app, NewView("home", home), NewView("admin", admin))
+	root.AppendChild(outlet)
+	r := NewRouter(outlet)
+
+	failure := func(view string) Object {
+		o := NewNavigationError("/admin", "loader", errors.New("unavailable")).MakeCopy()
+		if view != "" {
+			o.Set("view", String(view))
+		}
+		return o.Commit()
+	}
+
+	if _, _, ok := r.ErrorView(failure("app")); ok {
+		t.Fatal("no error view should be found without factory")
+	}
+
+	fallback := newdiv("fallback")
+	r.SetErrorView(func(errobj Object) *Element { return fallback })
+	v, ev, ok := r.ErrorView(failure("app"))
+	if !ok || ev != fallback || v.AsElement() != nil {
+		t.Fatal("the router-level error view should be used when no view has a factory")
+	}
+
+	retry := newdiv("retry")
+	outlet.SetErrorView(func(errobj Object) *Element {
+		if errobj.MustGetString("message") != "unavailable" {
+			t.Error("the factory should receive the navigation error")
+		}
+		return retry
+	})
+	v, ev, ok = r.ErrorView(failure("app"))
+	if !ok || ev != retry || v.AsElement() != app {
+		t.Fatal("the error view of the targeted view should take precedence over the router-level one")
+	}
+	if _, ev, _ = r.ErrorView(failure("")); ev != fallback {
+		t.Fatal("the router-level error view should be used when the targeted view is unknown")
+	}
+
+	Delete(app)
+	if _, ok := errorViews.Get(app); ok {
+		t.Fatal("the error view factory should be released with its view")
+	}
+}
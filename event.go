@@ -2,6 +2,7 @@
 package ui
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -135,6 +136,22 @@ func (e EventListeners) RemoveEventHandler(event string, handler *EventHandler)
 	eh.Remove(handler)
 }
 
+// sharesNativeListener returns whether a handler of the event relies on the native listener registered
+// for the given options.
+func (e EventListeners) sharesNativeListener(event string, opts ListenerOptions) bool {
+	eh, ok := e.list[event]
+	if !ok {
+		return false
+	}
+	key := NativeListenerKey(event, opts)
+	for _, h := range eh.List {
+		if NativeListenerKey(event, h.Options()) == key {
+			return true
+		}
+	}
+	return false
+}
+
 func (e EventListeners) Handle(evt Event) bool {
 	evh, ok := e.list[evt.Type()]
 	if !ok {
@@ -233,6 +250,21 @@ type EventHandler struct {
 
 	Once   bool
 	Bubble bool
+
+	Passive bool            // the handler does not prevent the default behavior of the event
+	Signal  context.Context // if not nil, the handler is removed once the context is done
+}
+
+// ListenerOptions holds the options of an event listener. They mirror the options of native event listeners.
+// Passive listeners tell the native side that they will not prevent the default behavior of the event, which
+// allows scrolling or touch gestures to proceed without waiting for them. Calling PreventDefault from a passive
+// listener has no effect.
+// A listener with a Signal is removed once the signal context is done.
+type ListenerOptions struct {
+	Capture bool
+	Passive bool
+	Once    bool
+	Signal  context.Context
 }
 
 func (e EventHandler) Handle(evt Event) bool {
@@ -245,7 +277,7 @@ func (e EventHandler) Handle(evt Event) bool {
 }
 
 func NewEventHandler(fn func(Event) bool) *EventHandler {
-	return &EventHandler{fn, false, false, true}
+	return &EventHandler{Fn: fn, Bubble: true}
 }
 func (e *EventHandler) ForCapture() *EventHandler {
 	if e.Capture {
@@ -262,6 +294,9 @@ func (e *EventHandler) ForCapture() *EventHandler {
 		n.Bubble = false
 	}
 
+	n.Passive = e.Passive
+	n.Signal = e.Signal
+
 	return n
 }
 
@@ -280,6 +315,9 @@ func (e *EventHandler) TriggerOnce() *EventHandler {
 		n.Bubble = false
 	}
 
+	n.Passive = e.Passive
+	n.Signal = e.Signal
+
 	return n
 }
 
@@ -301,6 +339,9 @@ func (e *EventHandler) NoBubble() *EventHandler {
 	}
 
 	n.Bubble = false
+	n.Passive = e.Passive
+	n.Signal = e.Signal
+
 	return n
 }
 
@@ -718,3 +759,29 @@ func (l LifecycleHandlers) MutationReplaying() bool {
 func (l LifecycleHandlers) OnMutationsReplayed(h *MutationHandler) {
 	l.root.WatchEvent(TransitionPhase("replay", "ended"), l.root, h.RunASAP())
 }
+
+// AsPassive returns a copy of the event handler that is registered as a passive listener.
+func (e *EventHandler) AsPassive() *EventHandler {
+	return e.WithOptions(ListenerOptions{e.Capture, true, e.Once, e.Signal})
+}
+
+// WithSignal returns a copy of the event handler that is removed once the context is done.
+func (e *EventHandler) WithSignal(ctx context.Context) *EventHandler {
+	return e.WithOptions(ListenerOptions{e.Capture, e.Passive, e.Once, ctx})
+}
+
+// WithOptions returns a copy of the event handler configured with the given listener options.
+func (e *EventHandler) WithOptions(o ListenerOptions) *EventHandler {
+	n := NewEventHandler(e.Fn)
+	n.Bubble = e.Bubble
+	n.Capture = o.Capture
+	n.Passive = o.Passive
+	n.Once = o.Once
+	n.Signal = o.Signal
+	return n
+}
+
+// Options returns the listener options of the event handler.
+func (e *EventHandler) Options() ListenerOptions {
+	return ListenerOptions{e.Capture, e.Passive, e.Once, e.Signal}
+}
@@ -0,0 +1,75 @@
+package ui
+
+import "sync/atomic"
+
+// Frozen namespaces
+//
+// Freeze makes the properties of an element immutable once it has been set up. It is meant for
+// configuration-like elements and for shared template elements that should never be mutated by accident.
+// A later write to a frozen namespace panics when the profile of the configuration is a debug one or turns
+// errors into panics. Otherwise, the write is ignored and a warning is logged.
+//
+// Freezing the whole element freezes every namespace but the internals and event ones, which hold the
+// runtime state the framework needs to keep handling the element (lifecycle, deletion...).
+// Replaying mutations, e.g. during hydration, is not affected.
+
+var frozenNamespaces = newscsmap[*Element, map[string]bool]()
+
+// Freeze makes the given namespaces of the element read-only. Without argument, the whole element is frozen.
+func (e *Element) Freeze(namespaces ...string) *Element {
+	frozen, ok := frozenNamespaces.Get(e)
+	if !ok {
+		frozen = make(map[string]bool)
+		e.OnDeleted(NewMutationHandler(func(evt MutationEvent) bool {
+			frozenNamespaces.Delete(evt.Origin())
+			return false
+		}).RunOnce())
+	} else {
+		// the map is replaced rather than modified since it may be read concurrently.
+		m := make(map[string]bool, len(frozen)+len(namespaces))
+		for k, v := range frozen {
+			m[k] = v
+		}
+		frozen = m
+	}
+	if len(namespaces) == 0 {
+		frozen[""] = true
+	}
+	for _, ns := range namespaces {
+		frozen[ns] = true
+	}
+	frozenNamespaces.Set(e, frozen)
+	atomic.StoreInt32(&e.frozen, 1)
+	return e
+}
+
+// IsFrozen returns whether the namespace of the element is read-only.
+func (e *Element) IsFrozen(namespace string) bool {
+	frozen, ok := frozenNamespaces.Get(e)
+	if !ok {
+		return false
+	}
+	if frozen[namespace] {
+		return true
+	}
+	return frozen[""] && namespace != Namespace.Internals && namespace != Namespace.Event
+}
+
+// rejectFrozenMutation reports whether a mutation of a frozen namespace must be ignored. It panics instead
+// with a debug profile.
+func rejectFrozenMutation(e *Element, category, propname string) bool {
+	if atomic.LoadInt32(&e.frozen) == 0 {
+		return false
+	}
+	if !e.IsFrozen(category) || MutationReplaying(e) {
+		return false
+	}
+	msg := "mutation of frozen element " + e.ID + ": " + category + "/" + propname
+	if e.Configuration != nil {
+		if p := e.Configuration.Profile(); p.Debug || p.PanicOnError {
+			panic(msg)
+		}
+	}
+	DEBUG(msg)
+	return true
+}
@@ -0,0 +1,42 @@
+package ui
+
+import "testing"
+
+func TestFreeze(t *testing.T) {
+	c := NewConfiguration("freezetest", "test").WithProfile(ProductionProfile)
+	root := c.NewAppRoot("root")
+	e := c.NewElement("meta", "test")
+	RegisterElement(root, e)
+
+	e.SetData("content", String("initial"))
+	e.Freeze(Namespace.Data)
+	if !e.IsFrozen(Namespace.Data) || e.IsFrozen(Namespace.UI) {
+		t.Fatal("expected only the data namespace to be frozen")
+	}
+	e.SetData("content", String("changed"))
+	if v, _ := e.GetData("content"); v != String("initial") {
+		t.Fatalf("expected the write to be ignored, got %v", v)
+	}
+	e.SetUI("hidden", Bool(true))
+	if _, ok := e.GetUI("hidden"); !ok {
+		t.Fatal("expected the ui namespace to remain writable")
+	}
+
+	e.Freeze()
+	if !e.IsFrozen(Namespace.UI) || e.IsFrozen(Namespace.Internals) || e.IsFrozen(Namespace.Event) {
+		t.Fatal("expected the whole element but its runtime namespaces to be frozen")
+	}
+	e.TriggerEvent("ping")
+
+	dev := NewConfiguration("freezetestdev", "test").WithProfile(DevelopmentProfile)
+	droot := dev.NewAppRoot("root")
+	d := dev.NewElement("base", "test")
+	RegisterElement(droot, d)
+	d.Freeze()
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a write to a frozen element to panic with a debug profile")
+		}
+	}()
+	d.SetUI("href", String("/"))
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"sync"
+)
+
+// Element handles
+//
+// Handlers and goroutines often keep a pointer to an Element that may be deleted in the meantime.
+// A Handle holds such a reference and is invalidated as soon as the Element is deleted: Deref then reports
+// that the Element is gone, and the Handle no longer keeps it alive.
+// All the handles to the same Element share their state, so that creating many of them is cheap.
+// Handles may be dereferenced from any goroutine.
+
+// A warning is logged whenever the ui or data properties of a deleted Element are modified, if the configuration
+// of the Element has a Debug profile.
+
+// Handle is a weak reference to an Element.
+type Handle struct {
+	ref *handleRef
+}
+
+// handleRef is the state shared by the handles to an Element.
+type handleRef struct {
+	mu sync.RWMutex
+	e  *Element
+}
+
+func (r *handleRef) load() *Element {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.e
+}
+
+func (r *handleRef) store(e *Element) {
+	r.mu.Lock()
+	r.e = e
+	r.mu.Unlock()
+}
+
+var (
+	handlesMu sync.Mutex
+	handles   = make(map[*Element]*handleRef)
+)
+
+// NewHandle returns a handle to the Element. The handle of a deleted Element is invalid from the start.
+func NewHandle(e *Element) Handle {
+	if isDeleted(e) {
+		return Handle{new(handleRef)}
+	}
+	handlesMu.Lock()
+	ref, ok := handles[e]
+	if !ok {
+		ref = &handleRef{e: e}
+		handles[e] = ref
+	}
+	handlesMu.Unlock()
+	if !ok {
+		e.OnDeleted(NewMutationHandler(func(evt MutationEvent) bool {
+			handlesMu.Lock()
+			delete(handles, e)
+			handlesMu.Unlock()
+			ref.store(nil)
+			return false
+		}).RunOnce())
+	}
+	return Handle{ref}
+}
+
+// Deref returns the Element if it has not been deleted.
+func (h Handle) Deref() (*Element, bool) {
+	if h.ref == nil {
+		return nil, false
+	}
+	e := h.ref.load()
+	return e, e != nil
+}
+
+// Valid returns whether the referenced Element still exists.
+func (h Handle) Valid() bool {
+	_, ok := h.Deref()
+	return ok
+}
+
+// With calls f with the Element if it has not been deleted. It returns whether f was called.
+func (h Handle) With(f func(*Element)) bool {
+	e, ok := h.Deref()
+	if !ok {
+		return false
+	}
+	f(e)
+	return true
+}
+
+func warnDeadElementMutation(e *Element, category, propname string) {
+	if (category != Namespace.UI && category != Namespace.Data) || !isDeleted(e) {
+		return
+	}
+	if e.Configuration != nil && e.Configuration.Profile().Debug {
+		DEBUG("mutation of deleted element ", e.ID, ": ", category, "/", propname)
+	}
+}
@@ -0,0 +1,136 @@
+package ui
+
+// History backends
+//
+// A Router keeps its navigation history in a NavHistory, whose state is mirrored in the "history" ui property
+// of the root. A HistoryBackend reflects this history into the environment of the app: a browser driver pushes
+// entries onto the browser history, so that the URL and the back button follow the navigation.
+//
+// The backend is selected per router at construction, with the WithHistoryBackend option. Drivers install
+// their own backend on the routers created without one. MemoryHistory keeps the entries in memory and has no
+// side effect, which suits secondary routers, tests and drivers without a native history.
+
+// HistoryBackend records the navigation of a router.
+// Push is called when a navigation adds an entry to the history, and Replace when it updates the current one.
+// The state is the serialized NavHistory, as returned by its Value method.
+type HistoryBackend interface {
+	Push(route string, state Value)
+	Replace(route string, state Value)
+}
+
+// WithHistoryBackend is a router option selecting the backend of the navigation history.
+func WithHistoryBackend(b HistoryBackend) func(*Router) *Router {
+	return func(r *Router) *Router {
+		r.backend = b
+		return r
+	}
+}
+
+// InMemoryHistory is a router option keeping the navigation history in memory.
+func InMemoryHistory(r *Router) *Router {
+	r.backend = NewMemoryHistory()
+	return r
+}
+
+// HistoryBackend returns the backend of the navigation history of the router, or nil if it has none.
+func (r *Router) HistoryBackend() HistoryBackend {
+	return r.backend
+}
+
+// SetHistoryBackend sets the backend of the navigation history if the router has none.
+// It is meant for drivers, which provide the default backend.
+func (r *Router) SetHistoryBackend(b HistoryBackend) {
+	if r.backend == nil {
+		r.backend = b
+	}
+}
+
+func (r *Router) historyBackendHandler() *MutationHandler {
+	return NewMutationHandler(func(evt MutationEvent) bool {
+		if r.backend == nil {
+			return false
+		}
+		route, ok := evt.Origin().Get(Namespace.UI, "currentroute")
+		if !ok {
+			panic("current route is unknown")
+		}
+		state, ok := evt.NewValue().(Object)
+		if !ok {
+			return false
+		}
+		previous, ok := evt.OldValue().(Object)
+		if ok {
+			pc, ok := previous.Get("cursor")
+			c, cok := state.Get("cursor")
+			if ok && cok && !Equal(pc, c) {
+				r.backend.Push(string(route.(String)), state)
+				return false
+			}
+		}
+		r.backend.Replace(string(route.(String)), state)
+		return false
+	})
+}
+
+// MemoryHistory is a HistoryBackend which keeps the history entries in memory.
+// It mirrors the stack of the NavHistory, so that going back and forth moves its cursor.
+type MemoryHistory struct {
+	Entries []string
+	Cursor  int
+	State   Value
+}
+
+// NewMemoryHistory returns an empty in-memory history.
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{Cursor: -1}
+}
+
+func (m *MemoryHistory) Push(route string, state Value) {
+	if !m.mirror(state) {
+		m.Cursor++
+		m.Entries = append(m.Entries[:m.Cursor], route)
+	}
+	m.State = state
+}
+
+func (m *MemoryHistory) Replace(route string, state Value) {
+	if !m.mirror(state) {
+		if m.Cursor < 0 {
+			m.Cursor = 0
+		}
+		m.Entries = append(m.Entries[:m.Cursor], route)
+	}
+	m.State = state
+}
+
+// mirror copies the stack and cursor of a serialized NavHistory.
+func (m *MemoryHistory) mirror(state Value) bool {
+	o, ok := state.(Object)
+	if !ok {
+		return false
+	}
+	c, ok := o.Get("cursor")
+	if !ok {
+		return false
+	}
+	s, ok := o.Get("stack")
+	if !ok {
+		return false
+	}
+	stack := s.(List).UnsafelyUnwrap()
+	entries := make([]string, 0, len(stack))
+	for _, v := range stack {
+		entries = append(entries, string(v.(String)))
+	}
+	m.Entries = entries
+	m.Cursor = int(c.(Number))
+	return true
+}
+
+// Location returns the route of the current entry.
+func (m *MemoryHistory) Location() string {
+	if m.Cursor < 0 || m.Cursor >= len(m.Entries) {
+		return ""
+	}
+	return m.Entries[m.Cursor]
+}
@@ -0,0 +1,35 @@
+package ui
+
+import "testing"
+
+func TestMemoryHistory(t *testing.T) {
+	c := NewConfiguration("historytest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	root := c.NewAppRoot("root")
+	app, a, b := newdiv("app"), newdiv("a"), newdiv("b")
+	for _, e := range []*Element{app, a, b} {
+		RegisterElement(root, e)
+	}
+	outlet := NewViewElement(app, NewView("a", a), NewView("b", b))
+	root.AppendChild(outlet)
+
+	r := NewRouter(outlet, InMemoryHistory)
+	m, ok := r.HistoryBackend().(*MemoryHistory)
+	if !ok {
+		t.Fatal("expected an in-memory history backend")
+	}
+
+	r.GoTo("/a")
+	r.GoTo("/b")
+	if m.Location() != "/b" || len(m.Entries) != 2 {
+		t.Fatalf("expected two entries ending with /b, got %v", m.Entries)
+	}
+
+	r.SetHistoryBackend(NewMemoryHistory())
+	if r.HistoryBackend() != m {
+		t.Fatal("expected the backend selected at construction to be kept")
+	}
+}
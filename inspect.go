@@ -0,0 +1,259 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Introspection
+//
+// Inspect returns what an element is wired to: the property mutations it watches, the event listeners it
+// holds, the elements watching its own properties, and its children. It is meant for developer tools, and for
+// tests asserting that handlers were registered, or cleaned up.
+// Handlers are described by the name of their function and the location of its definition in the source, as
+// reported by the runtime. Closures are named after the function that defines them.
+
+// Inspection describes the handlers and bindings of an element.
+type Inspection struct {
+	ID        string
+	Watchers  []WatcherInfo  // mutation handlers of the element, sorted by source, category and property
+	Listeners []ListenerInfo // event listeners of the element, sorted by event
+	WatchedBy []WatchedInfo  // properties of the element watched by elements, sorted by category and property
+	Children  []string       // ids of the children, in order
+}
+
+// WatcherInfo describes a mutation handler registered by an element.
+type WatcherInfo struct {
+	Source   string // id of the element owning the property
+	Category string
+	Property string
+	Value    Value // current value of the property, nil if unset
+	Handler  HandlerInfo
+	Once     bool
+	ASAP     bool
+	Binding  bool
+}
+
+// ListenerInfo describes an event listener.
+type ListenerInfo struct {
+	Event   string
+	Handler HandlerInfo
+	Capture bool
+	Once    bool
+	Passive bool
+}
+
+// WatchedInfo lists the elements watching a property.
+type WatchedInfo struct {
+	Category string
+	Property string
+	Watchers []string // ids of the watching elements
+}
+
+// HandlerInfo identifies the function of a handler.
+type HandlerInfo struct {
+	Func     string
+	Location string // file:line
+}
+
+func (h HandlerInfo) String() string {
+	if h.Location == "" {
+		return h.Func
+	}
+	return h.Func + " (" + h.Location + ")"
+}
+
+// Inspect returns the handlers and bindings of an element.
+func Inspect(e *Element) Inspection {
+	in := Inspection{ID: e.ID}
+
+	if e.PropMutationHandlers != nil {
+		for key, hs := range e.PropMutationHandlers.list {
+			source, category, propname, ok := splitHandlerKey(key)
+			if !ok {
+				continue
+			}
+			var value Value
+			s := e
+			if source != e.ID && e.Root != nil {
+				s = GetById(e.Root, source)
+			}
+			if s != nil && s.ID == source {
+				value, _ = s.Get(category, propname)
+			}
+			for _, h := range hs.list {
+				in.Watchers = append(in.Watchers, WatcherInfo{
+					Source:   source,
+					Category: category,
+					Property: propname,
+					Value:    value,
+					Handler:  describeFunc(h.Fn),
+					Once:     h.Once,
+					ASAP:     h.ASAP,
+					Binding:  h.binding,
+				})
+			}
+		}
+	}
+	sort.SliceStable(in.Watchers, func(i, j int) bool {
+		a, b := in.Watchers[i], in.Watchers[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Property < b.Property
+	})
+
+	for event, hs := range e.EventHandlers.list {
+		for _, h := range hs.List {
+			in.Listeners = append(in.Listeners, ListenerInfo{
+				Event:   event,
+				Handler: describeFunc(h.Fn),
+				Capture: h.Capture,
+				Once:    h.Once,
+				Passive: h.Passive,
+			})
+		}
+	}
+	sort.SliceStable(in.Listeners, func(i, j int) bool { return in.Listeners[i].Event < in.Listeners[j].Event })
+
+	for category, ps := range e.Properties.Categories {
+		for propname, watchers := range ps.Watchers {
+			if watchers == nil || len(watchers.List) == 0 {
+				continue
+			}
+			w := WatchedInfo{Category: category, Property: propname}
+			for _, watcher := range watchers.List {
+				if watcher != nil {
+					w.Watchers = append(w.Watchers, watcher.ID)
+				}
+			}
+			if len(w.Watchers) > 0 {
+				in.WatchedBy = append(in.WatchedBy, w)
+			}
+		}
+	}
+	sort.Slice(in.WatchedBy, func(i, j int) bool {
+		a, b := in.WatchedBy[i], in.WatchedBy[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Property < b.Property
+	})
+
+	if e.Children != nil {
+		for _, c := range e.Children.List {
+			in.Children = append(in.Children, c.ID)
+		}
+	}
+	return in
+}
+
+// WatchersOf returns the mutation handlers registered for a property of a source element.
+func (in Inspection) WatchersOf(source, category, propname string) []WatcherInfo {
+	var res []WatcherInfo
+	for _, w := range in.Watchers {
+		if w.Source == source && w.Category == category && w.Property == propname {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+// ListenersOf returns the listeners of an event.
+func (in Inspection) ListenersOf(event string) []ListenerInfo {
+	var res []ListenerInfo
+	for _, l := range in.Listeners {
+		if l.Event == event {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+// String returns a tabular description of the inspection.
+func (in Inspection) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "element %s\n", in.ID)
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	if len(in.Watchers) > 0 {
+		fmt.Fprintln(tw, "WATCHES\tVALUE\tHANDLER\tFLAGS")
+		for _, w := range in.Watchers {
+			var flags []string
+			if w.Binding {
+				flags = append(flags, "binding")
+			}
+			if w.Once {
+				flags = append(flags, "once")
+			}
+			if w.ASAP {
+				flags = append(flags, "asap")
+			}
+			value := "-"
+			if w.Value != nil {
+				value = fmt.Sprint(w.Value)
+			}
+			fmt.Fprintf(tw, "%s/%s/%s\t%s\t%s\t%s\n", w.Source, w.Category, w.Property, value, w.Handler, strings.Join(flags, ","))
+		}
+	}
+	if len(in.Listeners) > 0 {
+		fmt.Fprintln(tw, "LISTENS\t\tHANDLER\tFLAGS")
+		for _, l := range in.Listeners {
+			var flags []string
+			if l.Capture {
+				flags = append(flags, "capture")
+			}
+			if l.Once {
+				flags = append(flags, "once")
+			}
+			if l.Passive {
+				flags = append(flags, "passive")
+			}
+			fmt.Fprintf(tw, "%s\t\t%s\t%s\n", l.Event, l.Handler, strings.Join(flags, ","))
+		}
+	}
+	if len(in.WatchedBy) > 0 {
+		fmt.Fprintln(tw, "WATCHED\tBY\t\t")
+		for _, w := range in.WatchedBy {
+			fmt.Fprintf(tw, "%s/%s\t%s\t\t\n", w.Category, w.Property, strings.Join(w.Watchers, ","))
+		}
+	}
+	tw.Flush()
+	if len(in.Children) > 0 {
+		fmt.Fprintf(&b, "children %s\n", strings.Join(in.Children, ","))
+	}
+	return b.String()
+}
+
+// splitHandlerKey splits the key of a mutation handler into its source id, category and property name.
+// Element ids may hold slashes, categories and property names may not.
+func splitHandlerKey(key string) (source, category, propname string, ok bool) {
+	i := strings.LastIndex(key, "/")
+	if i < 0 {
+		return "", "", "", false
+	}
+	j := strings.LastIndex(key[:i], "/")
+	if j < 0 {
+		return "", "", "", false
+	}
+	return key[:j], key[j+1 : i], key[i+1:], true
+}
+
+func describeFunc(fn interface{}) HandlerInfo {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() || v.IsNil() {
+		return HandlerInfo{Func: "<nil>"}
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return HandlerInfo{Func: "<unknown>"}
+	}
+	file, line := f.FileLine(f.Entry())
+	return HandlerInfo{Func: f.Name(), Location: fmt.Sprintf("%s:%d", file, line)}
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	c := NewConfiguration("inspecttest", "test")
+	root := c.NewAppRoot("root")
+	source := c.NewElement("source", "test")
+	watcher := c.NewElement("watcher", "test")
+	RegisterElement(root, source)
+	RegisterElement(root, watcher)
+	root.AppendChild(source)
+
+	source.SetData("count", Number(3))
+	h := NewMutationHandler(func(evt MutationEvent) bool { return false })
+	watcher.Watch(Namespace.Data, "count", source, h)
+	watcher.EventHandlers.AddEventHandler("click", NewEventHandler(func(evt Event) bool { return false }).ForCapture())
+
+	in := Inspect(watcher)
+	ws := in.WatchersOf("source", Namespace.Data, "count")
+	if len(ws) != 1 {
+		t.Fatalf("expected one watcher of source count, got %+v", in.Watchers)
+	}
+	if ws[0].Value != Number(3) || !strings.Contains(ws[0].Handler.Func, "TestInspect") {
+		t.Fatalf("unexpected watcher description %+v", ws[0])
+	}
+	if ls := in.ListenersOf("click"); len(ls) != 1 || !ls[0].Capture {
+		t.Fatalf("expected a capturing click listener, got %+v", in.Listeners)
+	}
+
+	var watched bool
+	for _, w := range Inspect(source).WatchedBy {
+		if w.Category == Namespace.Data && w.Property == "count" && len(w.Watchers) == 1 && w.Watchers[0] == "watcher" {
+			watched = true
+		}
+	}
+	if !watched {
+		t.Fatalf("expected source count to be watched by watcher, got %+v", Inspect(source).WatchedBy)
+	}
+	if children := Inspect(root).Children; len(children) != 1 || children[0] != "source" {
+		t.Fatalf("unexpected children %v", children)
+	}
+
+	watcher.Unwatch(Namespace.Data, "count", source)
+	if ws := Inspect(watcher).WatchersOf("source", Namespace.Data, "count"); len(ws) != 0 {
+		t.Fatalf("expected the watcher to be removed, got %+v", ws)
+	}
+	if !strings.Contains(Inspect(watcher).String(), "click") {
+		t.Fatal("expected the description to list the click listener")
+	}
+}
@@ -0,0 +1,69 @@
+package ui
+
+import "testing"
+
+func TestSharedNativeListeners(t *testing.T) {
+	defer func(b NativeEventBridger) { NativeEventBridge = b }(NativeEventBridge)
+	bridged := make(map[string]int)
+	NativeEventBridge = func(event string, target *Element, opts ListenerOptions) {
+		key := NativeListenerKey(event, opts)
+		if target.NativeEventUnlisteners.Has(key) {
+			return
+		}
+		bridged[key]++
+		target.NativeEventUnlisteners.Add(key, func() { bridged[key]-- })
+	}
+
+	c := NewConfiguration("listenerstest", "test")
+	root := c.NewAppRoot("root")
+	e := c.NewElement("button", "test")
+	RegisterElement(root, e)
+	root.AppendChild(e)
+
+	noop := func(Event) bool { return false }
+	a, b := NewEventHandler(noop), NewEventHandler(noop)
+	passive := NewEventHandler(noop).AsPassive()
+	e.AddEventListener("click", a)
+	e.AddEventListener("click", b)
+	e.AddEventListener("click", passive)
+	if bridged["click"] != 1 || bridged["click/passive"] != 1 {
+		t.Fatalf("expected one native listener per set of options, got %v", bridged)
+	}
+
+	e.RemoveEventListener("click", a)
+	if bridged["click"] != 1 {
+		t.Fatal("expected the native listener to be kept while a handler relies on it")
+	}
+	e.RemoveEventListener("click", b)
+	if bridged["click"] != 0 || bridged["click/passive"] != 1 {
+		t.Fatalf("expected only the native listener of the removed handlers to be removed, got %v", bridged)
+	}
+
+	e.AddEventListener("click", a)
+	if bridged["click"] != 1 {
+		t.Fatal("expected a handler added afterwards to be bridged again")
+	}
+
+	var ran int
+	once := NewEventHandler(func(Event) bool { ran++; return false }).WithOptions(ListenerOptions{Once: true})
+	e.AddEventListener("click", once)
+	if bridged["click"] != 1 {
+		t.Fatalf("expected once handlers to share the native listener, got %v", bridged)
+	}
+	e.RemoveEventListener("click", once)
+	if bridged["click"] != 1 {
+		t.Fatal("expected the removal of a once handler to keep the native listener of the other handlers")
+	}
+	e.AddEventListener("click", once)
+	e.DispatchEvent(NewEvent("click", true, false, e, e, nil, nil))
+	e.DispatchEvent(NewEvent("click", true, false, e, e, nil, nil))
+	if ran != 1 {
+		t.Fatalf("expected the once handler to run once, ran %d times", ran)
+	}
+	e.RemoveEventListener("click", a)
+	if bridged["click"] != 0 {
+		t.Fatal("expected the native listener to be removed along with the last handler")
+	}
+
+	e.RemoveEventListener("click", nil)
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"sort"
+)
+
+// Namespace snapshots
+//
+// SnapshotNamespace reads all the properties an element holds in a namespace at once. The returned Object is
+// a copy: it is not affected by later mutations of the element, and can be kept to be compared with a later
+// snapshot, e.g. to find out whether a form was modified, or to restore its state on reset.
+
+// SnapshotNamespace returns an Object holding the properties of the element in the given namespace, keyed by
+// property name.
+func (e *Element) SnapshotNamespace(category string) Object {
+	o := NewObject()
+	ps, ok := e.Properties.Categories[category]
+	if !ok {
+		return o.Commit()
+	}
+	for propname, v := range ps.Local {
+		if v == nil {
+			continue
+		}
+		o.Set(propname, Copy(v))
+	}
+	return o.Commit()
+}
+
+// NamespaceDiff lists the properties which differ between two snapshots of a namespace. Property names are
+// sorted.
+type NamespaceDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty reports whether the snapshots are identical.
+func (d NamespaceDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffNamespaces compares an older snapshot of a namespace with a newer one.
+func DiffNamespaces(old, new Object) NamespaceDiff {
+	var d NamespaceDiff
+	new.Range(func(key string, val Value) bool {
+		prev, ok := old.Get(key)
+		if !ok {
+			d.Added = append(d.Added, key)
+		} else if !Equal(prev, val) {
+			d.Changed = append(d.Changed, key)
+		}
+		return false
+	})
+	old.Range(func(key string, val Value) bool {
+		if _, ok := new.Get(key); !ok {
+			d.Removed = append(d.Removed, key)
+		}
+		return false
+	})
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
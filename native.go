@@ -17,7 +17,7 @@ type NativeElement interface {
 
 type NativeDispatcher func(evt Event)
 
-type NativeEventBridger func(event string, target *Element, capture bool) 
+type NativeEventBridger func(event string, target *Element, opts ListenerOptions)
 
 type NativeEventUnlisteners struct {
 	List map[string]func()
@@ -27,18 +27,41 @@ func NewNativeEventUnlisteners() NativeEventUnlisteners {
 	return NativeEventUnlisteners{make(map[string]func(), 0)}
 }
 
-func (n NativeEventUnlisteners) Add(event string, f func()) {
-	_, ok := n.List[event]
+// NativeListenerKey returns the key under which the native listener of an event is registered.
+// Go handlers of the same event share a native listener as long as they have the same capture and passive
+// options. Once and Signal are handled on the Go side, so they do not take part in the key: the shared native
+// listener is only removed along with the last handler relying on it.
+func NativeListenerKey(event string, opts ListenerOptions) string {
+	key := event
+	if opts.Capture {
+		key += "/capture"
+	}
+	if opts.Passive {
+		key += "/passive"
+	}
+	return key
+}
+
+// Has returns whether a native listener is registered under the given key.
+func (n NativeEventUnlisteners) Has(key string) bool {
+	_, ok := n.List[key]
+	return ok
+}
+
+func (n NativeEventUnlisteners) Add(key string, f func()) {
+	_, ok := n.List[key]
 	if ok {
 		return
 	}
-	n.List[event] = f
+	n.List[key] = f
 }
 
-func (n NativeEventUnlisteners) Apply(event string) {
-	removeNativeEventListener, ok := n.List[event]
+// Apply removes the native listener registered under the given key.
+func (n NativeEventUnlisteners) Apply(key string) {
+	removeNativeEventListener, ok := n.List[key]
 	if !ok {
 		return
 	}
+	delete(n.List, key)
 	removeNativeEventListener()
 }
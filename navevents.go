@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"strings"
+	"time"
+)
+
+// Navigation records
+//
+// Every navigation of the Router ends with a "navigation-record" event triggered on the root. Its value is an
+// Object describing the navigation:
+//   - from, to: the previous route and the requested one,
+//   - params: the route parameters of the new route, by parameter name,
+//   - query: the query parameters of the new route,
+//   - duration: the duration of the navigation, in milliseconds,
+//   - trigger: "link", "popstate", "programmatic", or "initial" for the first navigation of the document,
+//   - outcome: "ok", "notfound", "unauthorized", "appfailure", "cancelled" or "delayed".
+//
+// A navigation is cancelled when another one starts before it ends, e.g. when a view redirects on activation,
+// or when a route guard abandons it. It is delayed when a route guard postpones the activation of its views:
+// the navigation is resumed as a new one once the guard loader has returned.
+// The record is emitted before the record of the navigation that cancelled it.
+// It is the canonical source of navigation data for analytics, logging or breadcrumb components.
+
+// Navigation triggers
+const (
+	NavigationTriggerLink         = "link"
+	NavigationTriggerPopstate     = "popstate"
+	NavigationTriggerProgrammatic = "programmatic"
+	NavigationTriggerInitial      = "initial"
+)
+
+// Navigation outcomes
+const (
+	NavigationOK           = "ok"
+	NavigationNotFound     = "notfound"
+	NavigationUnauthorized = "unauthorized"
+	NavigationAppFailure   = "appfailure"
+	NavigationCancelled    = "cancelled"
+	NavigationDelayed      = "delayed"
+)
+
+type navigationRecord struct {
+	from, to string
+	trigger  string
+	outcome  string
+	params   map[string]string
+	start    time.Time
+}
+
+// OnNavigationRecord registers a handler receiving the record of every navigation.
+func (r *Router) OnNavigationRecord(h *MutationHandler) *Router {
+	r.Outlet.AsElement().Root.WatchEvent("navigation-record", r.Outlet.AsElement().Root, h)
+	return r
+}
+
+// trigger returns the trigger set by the caller of the navigation, or def if none was set.
+func (r *Router) trigger(def string) string {
+	t := r.navTrigger
+	r.navTrigger = ""
+	if t == "" {
+		return def
+	}
+	return t
+}
+
+func (r *Router) beginNavigation(route string, trigger string) {
+	if r.navigation != nil {
+		r.endNavigation(NavigationCancelled)
+	}
+	from := r.CurrentRoute()
+	if from == "" && trigger == NavigationTriggerPopstate {
+		trigger = NavigationTriggerInitial
+	}
+	r.navigation = &navigationRecord{
+		from:    from,
+		to:      route,
+		trigger: trigger,
+		outcome: NavigationOK,
+		params:  make(map[string]string),
+		start:   time.Now(),
+	}
+}
+
+func (r *Router) setNavigationOutcome(outcome string) {
+	if r.navigation != nil && r.navigation.outcome == NavigationOK {
+		r.navigation.outcome = outcome
+	}
+}
+
+// endNavigation triggers the navigation-record event. The outcome, if not empty, overrides the recorded one.
+func (r *Router) endNavigation(outcome string) {
+	n := r.navigation
+	if n == nil {
+		return
+	}
+	r.navigation = nil
+	if outcome != "" {
+		n.outcome = outcome
+	}
+
+	params := NewObject()
+	for k, v := range n.params {
+		params.Set(k, String(v))
+	}
+	_, query := canonicalizeRoute(n.to)
+
+	rec := NewObject()
+	rec.Set("from", String(n.from))
+	rec.Set("to", String(n.to))
+	rec.Set("params", params.Commit())
+	if query != nil {
+		rec.Set("query", *query)
+	} else {
+		rec.Set("query", NewObject().Commit())
+	}
+	rec.Set("duration", Number(float64(time.Since(n.start).Microseconds())/1000))
+	rec.Set("trigger", String(n.trigger))
+	rec.Set("outcome", String(n.outcome))
+
+	r.Outlet.AsElement().Root.TriggerEvent("navigation-record", rec.Commit())
+}
+
+// recordRouteParam records the value of a route parameter for the ongoing navigation.
+func recordRouteParam(e *Element, view string, value string) {
+	if e.Root == nil || e.Root.router == nil || e.Root.router.navigation == nil {
+		return
+	}
+	e.Root.router.navigation.params[strings.TrimPrefix(view, ":")] = value
+}
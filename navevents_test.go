@@ -0,0 +1,52 @@
+package ui
+
+import "testing"
+
+func TestNavigationRecord(t *testing.T) {
+	c := NewConfiguration("navrecordtest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	root := c.NewAppRoot("root")
+	app, a, b := newdiv("app"), newdiv("a"), newdiv("b")
+	for _, e := range []*Element{app, a, b} {
+		RegisterElement(root, e)
+	}
+	outlet := NewViewElement(app, NewView("a", a), NewView("b", b))
+	root.AppendChild(outlet)
+
+	var records []Object
+	r := NewRouter(outlet, InMemoryHistory)
+	r.OnNavigationRecord(NewMutationHandler(func(evt MutationEvent) bool {
+		records = append(records, evt.NewValue().(Object))
+		return false
+	}))
+
+	r.GoTo("/a")
+	r.GoTo("/b?tab=2")
+	r.GoTo("/missing")
+
+	if len(records) != 3 {
+		t.Fatalf("expected 3 navigation records, got %d", len(records))
+	}
+	field := func(o Object, k string) string {
+		v, _ := o.Get(k)
+		s, _ := v.(String)
+		return string(s)
+	}
+	second := records[1]
+	if field(second, "from") != "/a" || field(second, "to") != "/b?tab=2" {
+		t.Fatalf("unexpected route fields: from %q to %q", field(second, "from"), field(second, "to"))
+	}
+	if field(second, "trigger") != NavigationTriggerProgrammatic || field(second, "outcome") != NavigationOK {
+		t.Fatalf("unexpected trigger %q or outcome %q", field(second, "trigger"), field(second, "outcome"))
+	}
+	q, _ := second.Get("query")
+	if _, ok := q.(Object).Get("tab"); !ok {
+		t.Fatal("expected the tab query parameter to be recorded")
+	}
+	if got := field(records[2], "outcome"); got != NavigationNotFound {
+		t.Fatalf("expected a notfound outcome, got %q", got)
+	}
+}
@@ -46,6 +46,8 @@ func newObservable(id string) Observable {
 		nil,
 		nil,
 		nil,
+		nil,
+		0,
 	}
 
 	e.OnDeleted(NewMutationHandler(func(evt MutationEvent) bool {
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"errors"
+	"time"
+)
+
+// Property persistence policies
+//
+// By default, an element persists all of its data and ui properties using its persistence mode (see
+// PersistenceMode), which is chosen at construction time via a constructor option.
+// A persistence policy refines this per property, or per category when no property name is given:
+// a property may use another persistence mode than its element (or be persisted although its element
+// is not), never be persisted, expire after a while (TTL), or be transformed before being stored and
+// after being loaded (e.g. encryption).
+//
+// Drivers apply policies by calling EncodePersisted before storing a value and DecodePersisted after
+// loading it.
+
+// PersistNever is the persistence mode of properties that should never be persisted.
+const PersistNever = "never"
+
+// PersistencePolicy describes how a property is persisted.
+type PersistencePolicy struct {
+	Mode   string        // persistence mode, PersistNever, or empty to use the element's persistence mode
+	TTL    time.Duration // duration after which a persisted value expires. Zero means no expiration
+	Encode func(Value) (Value, error)
+	Decode func(Value) (Value, error)
+}
+
+// ErrPersistedValueExpired is returned by DecodePersisted when the TTL of a persisted value has elapsed.
+var ErrPersistedValueExpired = errors.New("persisted value expired")
+
+func policyKey(category, propname string) string {
+	return category + "/" + propname
+}
+
+// SetPersistencePolicy sets the persistence policy of a property. If propname is empty, the policy applies
+// to every property of the category that does not have its own policy.
+func (e *Element) SetPersistencePolicy(category string, propname string, p PersistencePolicy) *Element {
+	if e.persistencePolicies == nil {
+		e.persistencePolicies = make(map[string]PersistencePolicy)
+	}
+	e.persistencePolicies[policyKey(category, propname)] = p
+	return e
+}
+
+// GetPersistencePolicy returns the persistence policy that applies to a property, if any.
+func (e *Element) GetPersistencePolicy(category string, propname string) (PersistencePolicy, bool) {
+	if e.persistencePolicies == nil {
+		return PersistencePolicy{}, false
+	}
+	if p, ok := e.persistencePolicies[policyKey(category, propname)]; ok {
+		return p, true
+	}
+	p, ok := e.persistencePolicies[policyKey(category, "")]
+	return p, ok
+}
+
+// PersistenceModes returns the list of persistence modes used by an element, i.e. its own persistence mode
+// and the modes of its property policies.
+func PersistenceModes(e *Element) []string {
+	var modes []string
+	seen := make(map[string]bool)
+	add := func(m string) {
+		if m == "" || m == PersistNever || seen[m] {
+			return
+		}
+		seen[m] = true
+		modes = append(modes, m)
+	}
+	add(PersistenceMode(e))
+	for _, p := range e.persistencePolicies {
+		add(p.Mode)
+	}
+	return modes
+}
+
+// PropertyPersistenceMode returns the persistence mode that applies to a property.
+// The empty string is returned if the property should not be persisted.
+func PropertyPersistenceMode(e *Element, category string, propname string) string {
+	p, ok := e.GetPersistencePolicy(category, propname)
+	if !ok || p.Mode == "" {
+		return PersistenceMode(e)
+	}
+	if p.Mode == PersistNever {
+		return ""
+	}
+	return p.Mode
+}
+
+// EncodePersisted returns the value that should be stored for a property, according to its policy.
+// When the policy has a TTL, the value is wrapped along with its expiration date.
+func EncodePersisted(e *Element, category string, propname string, v Value) (Value, error) {
+	p, ok := e.GetPersistencePolicy(category, propname)
+	if !ok {
+		return v, nil
+	}
+	if p.Encode != nil {
+		var err error
+		v, err = p.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.TTL > 0 {
+		v = NewObject().
+			Set("zui_persisted_expires", Number(time.Now().Add(p.TTL).UnixMilli())).
+			Set("zui_persisted_value", v).
+			Commit()
+	}
+	return v, nil
+}
+
+// DecodePersisted returns the value of a property from its stored value, according to its policy.
+// ErrPersistedValueExpired is returned if the value has expired, in which case it should be discarded.
+// Only the values of properties whose policy has a TTL are unwrapped, so that an object value which happens
+// to have the same fields is returned as is.
+func DecodePersisted(e *Element, category string, propname string, v Value) (Value, error) {
+	p, ok := e.GetPersistencePolicy(category, propname)
+	if !ok {
+		return v, nil
+	}
+	if o, isobj := v.(Object); isobj && p.TTL > 0 {
+		if exp, ok := o.Get("zui_persisted_expires"); ok {
+			if n, ok := exp.(Number); ok && time.Now().UnixMilli() > int64(n) {
+				return nil, ErrPersistedValueExpired
+			}
+			v, _ = o.Get("zui_persisted_value")
+		}
+	}
+	if p.Decode == nil {
+		return v, nil
+	}
+	return p.Decode(v)
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodePersisted(t *testing.T) {
+	c := NewConfiguration("persistencetest", "test")
+	e := c.NewElement("e", "test")
+
+	wrapped := NewObject().
+		Set("zui_persisted_expires", Number(time.Now().Add(-time.Hour).UnixMilli())).
+		Set("zui_persisted_value", String("v")).
+		Commit()
+
+	e.SetPersistencePolicy(Namespace.Data, "plain", PersistencePolicy{})
+	v, err := DecodePersisted(e, Namespace.Data, "plain", wrapped)
+	if err != nil || !Equal(v, wrapped) {
+		t.Fatal("values of properties without TTL should not be unwrapped")
+	}
+
+	e.SetPersistencePolicy(Namespace.Data, "expiring", PersistencePolicy{TTL: time.Minute})
+	if _, err := DecodePersisted(e, Namespace.Data, "expiring", wrapped); err != ErrPersistedValueExpired {
+		t.Fatal("expired values should be reported")
+	}
+	stored, err := EncodePersisted(e, Namespace.Data, "expiring", String("v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := DecodePersisted(e, Namespace.Data, "expiring", stored); err != nil || v != String("v") {
+		t.Fatal("values within their TTL should be unwrapped")
+	}
+}
@@ -0,0 +1,76 @@
+package ui
+
+// Nested prefetching
+//
+// Prefetching a link warms the data of the views that the target route activates.
+// The elements of those views may themselves hold ViewElements that display their default view once mounted.
+// Their data is prefetched as well, down to PrefetchDepth levels of nested ViewElements and for at most
+// PrefetchBudget elements per link prefetch, so that nested outlets are not cold on navigation.
+//
+// Elements may also declare critical resources, such as images, with the CriticalResources modifier.
+// Those are announced on the root element via a "prefetch-resource" event so that the driver can warm them.
+
+// PrefetchDepth is the number of levels of nested ViewElements whose default view is prefetched
+// along with the target view of a link.
+var PrefetchDepth = 2
+
+// PrefetchBudget is the maximum number of elements visited when prefetching a link.
+var PrefetchBudget = 64
+
+// CriticalResources is an *Element modifier that registers the URLs of resources that should be
+// prefetched along with the data of an element.
+func CriticalResources(urls ...string) func(*Element) *Element {
+	return func(e *Element) *Element {
+		l := NewList()
+		if v, ok := e.Get(Namespace.Internals, "critical-resources"); ok {
+			l = v.(List).MakeCopy()
+		}
+		for _, u := range urls {
+			l = l.Append(String(u))
+		}
+		e.Set(Namespace.Internals, "critical-resources", l.Commit())
+		return e
+	}
+}
+
+// prefetchTree prefetches the data and the critical resources of an element and of its descendants.
+// Nested ViewElements are descended into through the view they will display once mounted, as long as
+// depth allows it. Every visited element consumes the budget.
+func prefetchTree(e *Element, depth int, budget *int) {
+	if e == nil || *budget <= 0 || !e.Registered() {
+		return
+	}
+	*budget--
+
+	e.Prefetch()
+	prefetchResources(e)
+
+	children := e.Children
+	if e.isViewElement() {
+		if depth <= 0 {
+			return
+		}
+		depth--
+		if _, ok := e.Get(Namespace.Internals, "mountdefaultview"); ok {
+			if v := e.retrieveView(""); v != nil {
+				children = v.Elements()
+			}
+		}
+	}
+	if children == nil {
+		return
+	}
+	for _, c := range children.List {
+		prefetchTree(c, depth, budget)
+	}
+}
+
+func prefetchResources(e *Element) {
+	v, ok := e.Get(Namespace.Internals, "critical-resources")
+	if !ok {
+		return
+	}
+	for _, u := range v.(List).UnsafelyUnwrap() {
+		e.Root.TriggerEvent("prefetch-resource", u)
+	}
+}
@@ -0,0 +1,51 @@
+package ui
+
+import "testing"
+
+func TestNestedPrefetch(t *testing.T) {
+	c := NewConfiguration("nestedprefetchtest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	root := c.NewAppRoot("root")
+	app, a, b, inner, hero, deep, deeper := newdiv("app"), newdiv("a"), newdiv("b"), newdiv("inner"), newdiv("hero"), newdiv("deep"), newdiv("deeper")
+	for _, e := range []*Element{app, a, b, inner, hero, deep, deeper} {
+		RegisterElement(root, e)
+	}
+	CriticalResources("/hero.png")(hero)
+	CriticalResources("/deeper.png")(deeper)
+
+	NewViewElement(deep).ChangeDefaultView(deeper)
+	NewViewElement(inner).ChangeDefaultView(hero, deep)
+	b.AppendChild(inner)
+	outlet := NewViewElement(app, NewView("a", a), NewView("b", b))
+	root.AppendChild(outlet)
+
+	var resources []string
+	root.WatchEvent("prefetch-resource", root, NewMutationHandler(func(evt MutationEvent) bool {
+		resources = append(resources, string(evt.NewValue().(String)))
+		return false
+	}))
+
+	r := NewRouter(outlet, InMemoryHistory)
+	r.GoTo("/a")
+
+	defer func(depth int) { PrefetchDepth = depth }(PrefetchDepth)
+	PrefetchDepth = 1
+	p, err := r.Match("/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p()
+	if len(resources) != 1 || resources[0] != "/hero.png" {
+		t.Fatalf("expected only the resources within depth to be prefetched, got %v", resources)
+	}
+
+	resources = nil
+	PrefetchDepth = 2
+	p()
+	if len(resources) != 2 {
+		t.Fatalf("expected the nested default view resources to be prefetched, got %v", resources)
+	}
+}
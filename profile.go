@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"sync"
+	"time"
+)
+
+// Configuration profiles
+//
+// A Profile bundles the settings that differ between the development, staging and production builds of an app,
+// so that they are switched together rather than one package-level variable at a time.
+// Three profiles are predefined. Others can be added with RegisterProfile.
+//
+// The profile of a Configuration is set with WithProfile. Otherwise, it is the profile registered under
+// ProfileName, which can be set at build time:
+//
+//	go build -ldflags "-X github.com/atdiar/particleui.ProfileName=staging"
+//
+// and ProductionProfile if ProfileName is unknown. When ProfileName is empty, drivers may provide the profile
+// they infer from their environment with WithDefaultProfile. ProductionProfile is used otherwise.
+// Drivers read the profile to adjust their behavior. The ui package resolves the prefetch expiry and the
+// warnings on the mutation of deleted elements from the profile of the configuration of the element concerned,
+// so that a profile never leaks from one configuration to another.
+
+// LogLevel determines which messages are logged.
+type LogLevel int
+
+const (
+	LogSilent LogLevel = iota
+	LogError
+	LogWarn
+	LogInfo
+	LogDebug
+)
+
+// Profile holds a set of framework settings.
+type Profile struct {
+	Name string
+
+	// Debug enables the development tooling: audits, timings, warnings on misuse...
+	Debug bool
+	// CaptureLimit is the maximum number of mutations kept by the mutation capture. Zero means the driver default.
+	CaptureLimit int
+	// PanicOnError turns the framework errors reported by drivers into panics instead of warnings.
+	PanicOnError bool
+	// PrefetchMaxAge is the duration for which prefetched data remain valid. Zero means the package default
+	// (PrefetchMaxAge). A negative value disables prefetching.
+	PrefetchMaxAge time.Duration
+	// LogLevel is the verbosity of the framework logs.
+	LogLevel LogLevel
+}
+
+var (
+	DevelopmentProfile = Profile{
+		Name:     "development",
+		Debug:    true,
+		LogLevel: LogDebug,
+	}
+
+	StagingProfile = Profile{
+		Name:     "staging",
+		LogLevel: LogWarn,
+	}
+
+	ProductionProfile = Profile{
+		Name:     "production",
+		LogLevel: LogError,
+	}
+)
+
+// ProfileName is the name of the profile used by the configurations which have none.
+var ProfileName = ""
+
+var profiles = struct {
+	sync.Mutex
+	m map[string]Profile
+}{m: map[string]Profile{
+	DevelopmentProfile.Name: DevelopmentProfile,
+	StagingProfile.Name:     StagingProfile,
+	ProductionProfile.Name:  ProductionProfile,
+}}
+
+// RegisterProfile makes a profile selectable by name via ProfileName. It replaces any profile with the same name.
+func RegisterProfile(p Profile) {
+	profiles.Lock()
+	defer profiles.Unlock()
+	profiles.m[p.Name] = p
+}
+
+// LookupProfile returns the profile registered under a name.
+func LookupProfile(name string) (Profile, bool) {
+	profiles.Lock()
+	defer profiles.Unlock()
+	p, ok := profiles.m[name]
+	return p, ok
+}
+
+// WithProfile sets the profile of the configuration.
+func (e *Configuration) WithProfile(p Profile) *Configuration {
+	e.profile = &p
+	return e
+}
+
+// WithDefaultProfile sets the profile of the configuration used when none was set with WithProfile or
+// selected via ProfileName.
+func (e *Configuration) WithDefaultProfile(p Profile) *Configuration {
+	e.defaultProfile = &p
+	return e
+}
+
+// Profile returns the profile of the configuration.
+func (e *Configuration) Profile() Profile {
+	if e.profile != nil {
+		return *e.profile
+	}
+	if ProfileName == "" && e.defaultProfile != nil {
+		return *e.defaultProfile
+	}
+	if p, ok := LookupProfile(ProfileName); ok {
+		return p
+	}
+	return ProductionProfile
+}
+
+// HasProfile returns whether a profile was set explicitly with WithProfile.
+func (e *Configuration) HasProfile() bool {
+	return e.profile != nil
+}
+
+// prefetchMaxAge returns the duration for which the prefetched data of the elements of the configuration
+// remain valid. Prefetching remains disabled whatever the profile when PrefetchMaxAge is negative.
+func (e *Configuration) prefetchMaxAge() time.Duration {
+	if PrefetchMaxAge < 0 {
+		return PrefetchMaxAge
+	}
+	if d := e.Profile().PrefetchMaxAge; d != 0 {
+		return d
+	}
+	return PrefetchMaxAge
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfigurationProfile(t *testing.T) {
+	c := NewConfiguration("profiletest", "test")
+	if p := c.Profile(); p.Name != ProductionProfile.Name {
+		t.Fatalf("expected the production profile by default, got %q", p.Name)
+	}
+
+	RegisterProfile(Profile{Name: "qa", CaptureLimit: 10, PrefetchMaxAge: time.Minute})
+	defer func(name string) { ProfileName = name }(ProfileName)
+	ProfileName = "qa"
+	if p := c.Profile(); p.Name != "qa" || p.CaptureLimit != 10 {
+		t.Fatalf("expected the profile selected by name, got %+v", p)
+	}
+	if d := c.prefetchMaxAge(); d != time.Minute {
+		t.Fatalf("expected the prefetch expiry of the profile selected by name, got %v", d)
+	}
+
+	other := NewConfiguration("profiletest-other", "test")
+	c.WithProfile(DevelopmentProfile)
+	if p := c.Profile(); p.Name != DevelopmentProfile.Name || !c.HasProfile() {
+		t.Fatalf("expected the profile set on the configuration, got %q", p.Name)
+	}
+	if d := c.prefetchMaxAge(); d != PrefetchMaxAge {
+		t.Fatalf("expected the default prefetch expiry, got %v", d)
+	}
+	if p := other.Profile(); p.Name != "qa" || other.prefetchMaxAge() != time.Minute {
+		t.Fatalf("expected the profile of a configuration not to affect the others, got %q", p.Name)
+	}
+
+	other.WithDefaultProfile(DevelopmentProfile)
+	if p := other.Profile(); p.Name != "qa" {
+		t.Fatalf("expected the profile selected by name to take precedence over the default one, got %q", p.Name)
+	}
+	ProfileName = ""
+	if p := other.Profile(); p.Name != DevelopmentProfile.Name || other.HasProfile() {
+		t.Fatalf("expected the default profile of the configuration, got %q", p.Name)
+	}
+	if p := NewConfiguration("profiletest-third", "test").Profile(); p.Name != ProductionProfile.Name {
+		t.Fatalf("expected the default profile of a configuration not to affect the others, got %q", p.Name)
+	}
+}
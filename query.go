@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+)
+
+// Element queries
+//
+// The elements of a document can be looked up by predicate, either among all the registered elements of the
+// document (Query) or within the logical subtree of a given element (QuerySubtree).
+// Both return a snapshot: the list of elements matching at the time of the call.
+// A LiveQuery can be kept instead, which is evaluated anew each time its elements are requested.
+
+// Predicate reports whether an element should be selected by a query.
+type Predicate func(*Element) bool
+
+func constructorName(e *Element) string {
+	c, ok := e.Get(Namespace.Internals, "constructor")
+	if !ok {
+		return ""
+	}
+	s, _ := c.(String)
+	return string(s)
+}
+
+// ByConstructor selects the elements created by any of the named constructors (e.g. "input", "button").
+func ByConstructor(names ...string) Predicate {
+	return func(e *Element) bool {
+		c := constructorName(e)
+		for _, n := range names {
+			if c == n {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ByProperty selects the elements having the given property, and whose value satisfies the test if not nil.
+func ByProperty(category string, propname string, test func(Value) bool) Predicate {
+	return func(e *Element) bool {
+		v, ok := e.Get(category, propname)
+		if !ok {
+			return false
+		}
+		return test == nil || test(v)
+	}
+}
+
+// ByPropertyValue selects the elements whose property is equal to the given value.
+func ByPropertyValue(category string, propname string, value Value) Predicate {
+	return ByProperty(category, propname, func(v Value) bool {
+		return Equal(v, value)
+	})
+}
+
+// ByRoute selects the elements that are displayed under the given route, i.e. whose route is
+// the given route or one of its subroutes.
+func ByRoute(route string) Predicate {
+	route = strings.TrimSuffix(route, "/")
+	return func(e *Element) bool {
+		r := e.Route()
+		if r == "" {
+			return false
+		}
+		return r == route || strings.HasPrefix(r, route+"/")
+	}
+}
+
+// Not negates a predicate.
+func Not(p Predicate) Predicate {
+	return func(e *Element) bool { return !p(e) }
+}
+
+// Or selects the elements satisfying any of the predicates.
+func Or(ps ...Predicate) Predicate {
+	return func(e *Element) bool {
+		for _, p := range ps {
+			if p(e) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func matches(e *Element, predicates []Predicate) bool {
+	for _, p := range predicates {
+		if !p(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the elements registered in the document of the given element that satisfy all
+// the predicates, sorted by id.
+func Query(e *Element, predicates ...Predicate) []*Element {
+	root := e.Root
+	if root == nil {
+		root = e
+	}
+	l, ok := root.Configuration.Registry.Get(root.uuid)
+	if !ok {
+		return nil
+	}
+	var res []*Element
+	for _, el := range l {
+		if el == nil || !matches(el, predicates) {
+			continue
+		}
+		res = append(res, el)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// QuerySubtree returns the elements of the subtree rooted at e (e included) that satisfy all the predicates,
+// in depth-first order. Elements belonging to inactive views are not part of the subtree.
+func QuerySubtree(e *Element, predicates ...Predicate) []*Element {
+	var res []*Element
+	var walk func(*Element)
+	walk = func(el *Element) {
+		if matches(el, predicates) {
+			res = append(res, el)
+		}
+		if el.Children == nil {
+			return
+		}
+		for _, c := range el.Children.List {
+			if c != nil {
+				walk(c)
+			}
+		}
+	}
+	walk(e)
+	return res
+}
+
+// LiveQuery is a query whose result is recomputed each time it is accessed.
+type LiveQuery struct {
+	scope      *Element
+	subtree    bool
+	predicates []Predicate
+}
+
+// NewLiveQuery returns a live query over the whole document of the given element.
+func NewLiveQuery(e *Element, predicates ...Predicate) LiveQuery {
+	return LiveQuery{e, false, predicates}
+}
+
+// NewLiveSubtreeQuery returns a live query over the subtree rooted at the given element.
+func NewLiveSubtreeQuery(e *Element, predicates ...Predicate) LiveQuery {
+	return LiveQuery{e, true, predicates}
+}
+
+// Elements returns the elements currently matching the query.
+func (q LiveQuery) Elements() []*Element {
+	if q.subtree {
+		return QuerySubtree(q.scope, q.predicates...)
+	}
+	return Query(q.scope, q.predicates...)
+}
+
+// Len returns the number of elements currently matching the query.
+func (q LiveQuery) Len() int {
+	return len(q.Elements())
+}
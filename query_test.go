@@ -0,0 +1,35 @@
+package ui
+
+import "testing"
+
+func TestQuerySubtree(t *testing.T) {
+	c := NewConfiguration("querytest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+	newinput := c.NewConstructor("input", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	root := newdiv("root")
+	a := newinput("a")
+	b := newinput("b")
+	inner := newdiv("inner")
+	b.SetData("required", Bool(true))
+	inner.Children.InsertLast(b)
+	root.Children.InsertLast(a, inner)
+
+	res := QuerySubtree(root, ByConstructor("input"))
+	if len(res) != 2 || res[0] != a || res[1] != b {
+		t.Fatalf("expected inputs a and b, got %v", res)
+	}
+
+	res = QuerySubtree(root, ByConstructor("input"), ByPropertyValue(Namespace.Data, "required", Bool(true)))
+	if len(res) != 1 || res[0] != b {
+		t.Fatalf("expected input b only, got %v", res)
+	}
+
+	if n := NewLiveSubtreeQuery(root, Not(ByConstructor("input"))).Len(); n != 2 {
+		t.Errorf("expected 2 non-input elements, got %d", n)
+	}
+}
@@ -0,0 +1,247 @@
+package ui
+
+import (
+	"sync"
+	"time"
+)
+
+// Rate limiting of handlers
+//
+// Debounce, Throttle and Latest return copies of a handler whose function is wrapped so that it is not
+// called for every single occurence of an event.
+// The deferred calls are run on the UI thread (via the WorkQueue) and are skipped if the Element
+// the event originated from has been deleted in the meantime, so that no manual cleanup is required.
+// Note that the deferred calls' return values are ignored: the wrapped handler always returns false.
+
+// isDeleted returns whether an Element has been deleted.
+func isDeleted(e *Element) bool {
+	if e == nil {
+		return true
+	}
+	_, ok := e.Get(Namespace.Internals, prop.Deleted)
+	return ok
+}
+
+// schedule pushes a function onto the WorkQueue without blocking the caller.
+func schedule(fn func()) {
+	if s := currentScheduler(); s != nil {
+		s.enqueue(fn)
+		return
+	}
+	go func() {
+		WorkQueue <- fn
+	}()
+}
+
+// debouncer holds the state of a debounced function. Only the last call within a quiet period
+// of duration d is forwarded.
+type debouncer[T any] struct {
+	mu      sync.Mutex
+	d       time.Duration
+	timer   Timer
+	pending T
+}
+
+func (db *debouncer[T]) call(v T, run func(T)) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.pending = v
+	if db.timer != nil {
+		db.timer.Stop()
+	}
+	db.timer = AfterFunc(db.d, func() {
+		db.mu.Lock()
+		w := db.pending
+		db.mu.Unlock()
+		schedule(func() { run(w) })
+	})
+}
+
+// throttler holds the state of a throttled function. It is forwarded at most once per period d.
+// The last call that occured during a period is forwarded at the end of it (trailing call).
+type throttler[T any] struct {
+	mu       sync.Mutex
+	d        time.Duration
+	last     time.Time
+	timer    Timer
+	pending  T
+	trailing bool
+}
+
+func (th *throttler[T]) call(v T, run func(T)) {
+	if th.leading(v, run) {
+		run(v)
+	}
+}
+
+// leading returns whether the call opens a new period, in which case it should run right away.
+// Otherwise, the call is recorded to be forwarded at the end of the current period.
+func (th *throttler[T]) leading(v T, run func(T)) bool {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+
+	now := Now()
+	if th.timer == nil && now.Sub(th.last) >= th.d {
+		th.last = now
+		return true
+	}
+
+	th.pending = v
+	th.trailing = true
+	if th.timer != nil {
+		return false
+	}
+	th.timer = AfterFunc(th.d-now.Sub(th.last), func() {
+		th.mu.Lock()
+		w := th.pending
+		trailing := th.trailing
+		th.trailing = false
+		th.timer = nil
+		th.last = Now()
+		th.mu.Unlock()
+		if trailing {
+			schedule(func() { run(w) })
+		}
+	})
+	return false
+}
+
+// coalescer holds the state of a function for which only the latest value submitted before the UI thread
+// gets to run it is forwarded.
+type coalescer[T any] struct {
+	mu        sync.Mutex
+	scheduled bool
+	pending   T
+}
+
+func (c *coalescer[T]) call(v T, run func(T)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = v
+	if c.scheduled {
+		return
+	}
+	c.scheduled = true
+	schedule(func() {
+		c.mu.Lock()
+		w := c.pending
+		c.scheduled = false
+		c.mu.Unlock()
+		run(w)
+	})
+}
+
+func (m *MutationHandler) withFn(fn func(MutationEvent) bool) *MutationHandler {
+	n := NewMutationHandler(fn)
+	n.Once = m.Once
+	n.ASAP = m.ASAP
+	n.binding = m.binding
+	n.fetching = m.fetching
+	return n
+}
+
+func runMutationHandler(fn func(MutationEvent) bool) func(MutationEvent) {
+	return func(evt MutationEvent) {
+		if isDeleted(evt.Origin()) {
+			return
+		}
+		fn(evt)
+	}
+}
+
+// Debounce returns a copy of the mutation handler which only runs once no new mutation event has occured
+// for the duration d. It runs with the latest mutation event.
+// Typical use is search-as-you-type.
+func (m *MutationHandler) Debounce(d time.Duration) *MutationHandler {
+	db := &debouncer[MutationEvent]{d: d}
+	run := runMutationHandler(m.Fn)
+	return m.withFn(func(evt MutationEvent) bool {
+		db.call(evt, run)
+		return false
+	})
+}
+
+// Throttle returns a copy of the mutation handler which runs at most once per duration d.
+// The last mutation event occuring during a period is handled at the end of the period.
+func (m *MutationHandler) Throttle(d time.Duration) *MutationHandler {
+	th := &throttler[MutationEvent]{d: d}
+	run := runMutationHandler(m.Fn)
+	return m.withFn(func(evt MutationEvent) bool {
+		th.call(evt, run)
+		return false
+	})
+}
+
+// Latest returns a copy of the mutation handler for which intermediate mutation events are dropped:
+// the handler runs on the next turn of the UI thread with the latest mutation event only.
+func (m *MutationHandler) Latest() *MutationHandler {
+	c := &coalescer[MutationEvent]{}
+	run := runMutationHandler(m.Fn)
+	return m.withFn(func(evt MutationEvent) bool {
+		c.call(evt, run)
+		return false
+	})
+}
+
+func (e *EventHandler) withFn(fn func(Event) bool) *EventHandler {
+	n := NewEventHandler(fn)
+	n.Capture = e.Capture
+	n.Once = e.Once
+	n.Bubble = e.Bubble
+	n.Passive = e.Passive
+	n.Signal = e.Signal
+	return n
+}
+
+// eventCall records the current target of an event at the time it was handled, since the event object
+// keeps being dispatched to other targets afterwards.
+type eventCall struct {
+	evt    Event
+	target *Element
+}
+
+func runEventHandler(fn func(Event) bool) func(eventCall) {
+	return func(c eventCall) {
+		if isDeleted(c.target) {
+			return
+		}
+		c.evt.SetCurrentTarget(c.target)
+		fn(c.evt)
+	}
+}
+
+// Debounce returns a copy of the event handler which only runs once no new event has occured
+// for the duration d. It runs with the latest event.
+// Since the handler runs after the event has been dispatched, it may not prevent the default behavior
+// or stop the propagation of the event.
+func (e *EventHandler) Debounce(d time.Duration) *EventHandler {
+	db := &debouncer[eventCall]{d: d}
+	run := runEventHandler(e.Fn)
+	return e.withFn(func(evt Event) bool {
+		db.call(eventCall{evt, evt.CurrentTarget()}, run)
+		return false
+	})
+}
+
+// Throttle returns a copy of the event handler which runs at most once per duration d.
+// The first event of a period is handled synchronously. The last event occuring during a period is handled at
+// the end of the period.
+func (e *EventHandler) Throttle(d time.Duration) *EventHandler {
+	th := &throttler[eventCall]{d: d}
+	run := runEventHandler(e.Fn)
+	return e.withFn(func(evt Event) bool {
+		th.call(eventCall{evt, evt.CurrentTarget()}, run)
+		return false
+	})
+}
+
+// Latest returns a copy of the event handler for which intermediate events are dropped:
+// the handler runs on the next turn of the UI thread with the latest event only.
+func (e *EventHandler) Latest() *EventHandler {
+	c := &coalescer[eventCall]{}
+	run := runEventHandler(e.Fn)
+	return e.withFn(func(evt Event) bool {
+		c.call(eventCall{evt, evt.CurrentTarget()}, run)
+		return false
+	})
+}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+// limited submits values to a rate limited handler and records the values it has handled, in order.
+type limited struct {
+	submit  func(string)
+	handled *[]string
+}
+
+// rateLimitedHandlers returns a mutation handler and an event handler rate limited by the limit functions.
+func rateLimitedHandlers(t *testing.T, limitm func(*MutationHandler) *MutationHandler, limite func(*EventHandler) *EventHandler) map[string]limited {
+	c := NewConfiguration("ratelimittest", "test")
+	root := c.NewAppRoot("root")
+	e := c.NewElement("input", "test")
+	RegisterElement(root, e)
+
+	var mutations, events []string
+	e.Watch(Namespace.UI, "value", e, limitm(NewMutationHandler(func(evt MutationEvent) bool {
+		mutations = append(mutations, string(evt.NewValue().(String)))
+		return false
+	})))
+	h := limite(NewEventHandler(func(evt Event) bool {
+		if evt.CurrentTarget() != e {
+			t.Errorf("expected the current target to be restored, got %v", evt.CurrentTarget())
+		}
+		events = append(events, string(evt.Value().(String)))
+		return false
+	}))
+
+	return map[string]limited{
+		"MutationHandler": {func(v string) { e.SetUI("value", String(v)) }, &mutations},
+		"EventHandler": {func(v string) {
+			h.Handle(NewEvent("input", true, false, e, e, nil, String(v)))
+		}, &events},
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestThrottle(t *testing.T) {
+	s, restore := UseTestScheduler()
+	defer restore()
+
+	d := 100 * time.Millisecond
+	handlers := rateLimitedHandlers(t,
+		func(m *MutationHandler) *MutationHandler { return m.Throttle(d) },
+		func(e *EventHandler) *EventHandler { return e.Throttle(d) },
+	)
+	for name, h := range handlers {
+		h.submit("a")
+		if !equalStrings(*h.handled, []string{"a"}) {
+			t.Fatalf("%s: expected the leading call to run right away, got %v", name, *h.handled)
+		}
+		s.Clock.Advance(10 * time.Millisecond)
+		h.submit("b")
+		h.submit("c")
+		if !equalStrings(*h.handled, []string{"a"}) {
+			t.Fatalf("%s: expected no call before the end of the period, got %v", name, *h.handled)
+		}
+		s.Clock.Advance(d)
+		if !equalStrings(*h.handled, []string{"a", "c"}) {
+			t.Fatalf("%s: expected a trailing call with the latest value, got %v", name, *h.handled)
+		}
+		s.Clock.Advance(d)
+		h.submit("d")
+		if !equalStrings(*h.handled, []string{"a", "c", "d"}) {
+			t.Fatalf("%s: expected a new leading call once the period is over, got %v", name, *h.handled)
+		}
+		s.Clock.Advance(d)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	s, restore := UseTestScheduler()
+	defer restore()
+
+	handlers := rateLimitedHandlers(t,
+		func(m *MutationHandler) *MutationHandler { return m.Latest() },
+		func(e *EventHandler) *EventHandler { return e.Latest() },
+	)
+	for name, h := range handlers {
+		h.submit("a")
+		h.submit("b")
+		h.submit("c")
+		if len(*h.handled) != 0 {
+			t.Fatalf("%s: expected no call before the UI thread runs, got %v", name, *h.handled)
+		}
+		s.Flush()
+		if !equalStrings(*h.handled, []string{"c"}) {
+			t.Fatalf("%s: expected a single call with the latest value, got %v", name, *h.handled)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package ui
+
+import (
+	"strings"
+)
+
+// Region routers
+//
+// A document has a single Router, bound to the URL. Embedded tools (a settings wizard, a preview pane...) may
+// need to navigate within their own region of the page without affecting the main navigation.
+// A RegionRouter routes the views of the subtree of its outlet, with the same route scheme as the Router, the
+// routes being relative to the outlet: "/step2" activates the step2 view of the outlet.
+//
+// The current route of a region is held by the "regionroute" data property of its outlet. Its history is kept
+// in memory: navigating a region neither changes the URL nor adds entries to the browser history, unless a
+// driver binds the property to the URL.
+// The views of a region can only be reached by its region router, not by the main Router nor by the router of
+// an enclosing region.
+// Navigation events ("navigation-start", "navigation-end", "navigation-notfound", "navigation-unauthorized",
+// "navigation-appfailure") are triggered on the outlet instead of the root.
+
+var regionRouters = newscsmap[*Element, *RegionRouter]()
+
+// RegionRouter handles the navigation within a region of the document.
+type RegionRouter struct {
+	Outlet ViewElement
+	Routes *rnode
+
+	stack  []string
+	cursor int
+
+	LeaveTrailingSlash bool
+}
+
+// NewRegionRouter creates a router for the subtree of the outlet.
+func NewRegionRouter(outlet ViewElement) *RegionRouter {
+	o := outlet.AsElement()
+	if _, ok := regionRouters.Get(o); ok {
+		panic("a region router has already been created for this outlet")
+	}
+	r := &RegionRouter{Outlet: outlet, Routes: newrootrnode(outlet), cursor: -1}
+	regionRouters.Set(o, r)
+	o.OnDeleted(NewMutationHandler(func(evt MutationEvent) bool {
+		regionRouters.Delete(evt.Origin())
+		return false
+	}).RunOnce())
+
+	o.Watch(Namespace.Data, "regionroute", o, NewMutationHandler(func(evt MutationEvent) bool {
+		route := string(evt.NewValue().(String))
+		if r.cursor < 0 || r.stack[r.cursor] != route {
+			r.push(route)
+		}
+		if !o.Mounted() {
+			o.OnMounted(NewMutationHandler(func(evt MutationEvent) bool {
+				r.navigate(r.CurrentRoute())
+				return false
+			}).RunOnce())
+			return false
+		}
+		r.navigate(route)
+		return false
+	}))
+	return r
+}
+
+// GetRegionRouter returns the router of the closest region enclosing an element, if any.
+func GetRegionRouter(e AnyElement) (*RegionRouter, bool) {
+	for el := e.AsElement(); el != nil; el = el.Parent {
+		if r, ok := regionRouters.Get(el); ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func isRegionOutlet(e *Element) bool {
+	_, ok := regionRouters.Get(e)
+	return ok
+}
+
+// CurrentRoute returns the route the region is at.
+func (r *RegionRouter) CurrentRoute() string {
+	if r.cursor < 0 {
+		return ""
+	}
+	return r.stack[r.cursor]
+}
+
+// GoTo navigates the region to a route and adds it to the history of the region.
+func (r *RegionRouter) GoTo(route string) {
+	route = r.canonical(route)
+	if route == r.CurrentRoute() {
+		return
+	}
+	r.push(route)
+	r.Outlet.AsElement().SetData("regionroute", String(route))
+}
+
+// Replace navigates the region to a route which replaces the current entry of the history of the region.
+func (r *RegionRouter) Replace(route string) {
+	route = r.canonical(route)
+	if r.cursor < 0 {
+		r.push(route)
+	} else {
+		r.stack[r.cursor] = route
+	}
+	r.Outlet.AsElement().SetData("regionroute", String(route))
+}
+
+// GoBack navigates the region to the previous entry of its history, if any.
+func (r *RegionRouter) GoBack() {
+	if !r.BackAllowed() {
+		return
+	}
+	r.cursor--
+	r.Outlet.AsElement().SetData("regionroute", String(r.stack[r.cursor]))
+}
+
+// GoForward navigates the region to the next entry of its history, if any.
+func (r *RegionRouter) GoForward() {
+	if !r.ForwardAllowed() {
+		return
+	}
+	r.cursor++
+	r.Outlet.AsElement().SetData("regionroute", String(r.stack[r.cursor]))
+}
+
+func (r *RegionRouter) BackAllowed() bool {
+	return r.cursor > 0
+}
+
+func (r *RegionRouter) ForwardAllowed() bool {
+	return r.cursor < len(r.stack)-1
+}
+
+// Match returns whether a route is valid for the region.
+func (r *RegionRouter) Match(route string) error {
+	r.refresh()
+	_, _, _, err := r.Routes.match(r.canonical(route))
+	return err
+}
+
+func (r *RegionRouter) canonical(route string) string {
+	if !r.LeaveTrailingSlash && route != "/" {
+		route = strings.TrimSuffix(route, "/")
+	}
+	return route
+}
+
+func (r *RegionRouter) push(route string) {
+	r.cursor++
+	r.stack = append(r.stack[:r.cursor], route)
+}
+
+// refresh registers the views of the region which were mounted since the last navigation.
+func (r *RegionRouter) refresh() {
+	o := r.Outlet.AsElement()
+	v, ok := o.Root.Get(Namespace.Internals, "views")
+	if !ok {
+		return
+	}
+	for _, val := range v.(List).UnsafelyUnwrap() {
+		e := GetById(o.Root, val.(String).String())
+		if e == nil || e == o || !e.Mountable() || !IsDescendantOf(e, o) {
+			continue
+		}
+		r.Routes.insert(newchildrnode(ViewElement{e}, r.Routes))
+	}
+}
+
+func (r *RegionRouter) navigate(route string) {
+	o := r.Outlet.AsElement()
+	route, _, _ = strings.Cut(route, "#")
+	if route == "" || route == "/" {
+		return
+	}
+	r.refresh()
+
+	o.TriggerEvent("navigation-start", String(route))
+	_, _, activate, err := r.Routes.match(route)
+	switch err {
+	case nil:
+		if err := activate(); err != nil {
+			DEBUG("region activation failure ", err)
+			o.TriggerEvent("navigation-unauthorized", String(route))
+		}
+	case ErrNotFound:
+		o.TriggerEvent("navigation-notfound", String(route))
+	case ErrUnauthorized:
+		o.TriggerEvent("navigation-unauthorized", String(route))
+	default:
+		o.TriggerEvent("navigation-appfailure", NewNavigationError(route, "navigation", err))
+	}
+	o.TriggerEvent("navigation-end", String(route))
+}
@@ -0,0 +1,41 @@
+package ui
+
+import "testing"
+
+func TestRegionRouter(t *testing.T) {
+	c := NewConfiguration("regiontest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	root := c.NewAppRoot("root")
+	panel, step1, step2 := newdiv("panel"), newdiv("step1"), newdiv("step2")
+	for _, e := range []*Element{panel, step1, step2} {
+		RegisterElement(root, e)
+	}
+	wizard := NewViewElement(panel, NewView("step1", step1), NewView("step2", step2))
+	root.AppendChild(wizard)
+
+	r := NewRegionRouter(wizard)
+
+	r.GoTo("/step2")
+	if panel.ActiveView != "step2" {
+		t.Fatalf("expected the step2 view to be active, got %q", panel.ActiveView)
+	}
+	if got, ok := GetRegionRouter(step2); !ok || got != r {
+		t.Fatal("expected the region router of the view to be found")
+	}
+
+	r.GoTo("/step1")
+	r.GoBack()
+	if panel.ActiveView != "step2" || r.CurrentRoute() != "/step2" {
+		t.Fatalf("expected to be back on step2, got %q", panel.ActiveView)
+	}
+	if !r.ForwardAllowed() {
+		t.Fatal("expected the region history to allow going forward")
+	}
+
+	if err := r.Match("/unknown"); err != ErrNotFound {
+		t.Fatalf("expected an unknown route not to match, got %v", err)
+	}
+}
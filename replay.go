@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Replay recovery
+//
+// A mutation trace may reference elements that no longer exist, typically after a deploy that changed
+// element ids. Depending on the ReplayPolicy of the Configuration, the replay either aborts (the default) or
+// skips the mutations of unknown elements. In both cases, a ReplayReport describes what was applied so that
+// the app can degrade gracefully.
+// While replaying, a "replay-progress" event is triggered on the root with an Object holding the number of
+// "processed" mutations and the "total".
+
+// ReplayPolicy determines how a mutation replay handles mutations of elements that can't be found.
+type ReplayPolicy int
+
+const (
+	// AbortReplay aborts the replay at the first mutation of an unknown element.
+	AbortReplay ReplayPolicy = iota
+	// SkipUnknownElements skips and logs the mutations of unknown elements.
+	SkipUnknownElements
+)
+
+// WithReplayPolicy sets the policy applied when replaying mutations of unknown elements.
+func (e *Configuration) WithReplayPolicy(p ReplayPolicy) *Configuration {
+	e.ReplayPolicy = p
+	return e
+}
+
+// SkippedMutation describes a mutation that could not be replayed.
+type SkippedMutation struct {
+	ID       string
+	Category string
+	Property string
+}
+
+// ReplayReport describes the outcome of a mutation replay.
+type ReplayReport struct {
+	Total   int
+	Applied int
+	Skipped []SkippedMutation
+	Aborted bool
+}
+
+// Complete returns whether every mutation of the trace was replayed.
+func (r ReplayReport) Complete() bool {
+	return !r.Aborted && len(r.Skipped) == 0
+}
+
+// MissingElements returns the ids of the elements whose mutations were skipped.
+func (r ReplayReport) MissingElements() []string {
+	seen := make(map[string]struct{})
+	res := make([]string, 0)
+	for _, s := range r.Skipped {
+		if _, ok := seen[s.ID]; ok {
+			continue
+		}
+		seen[s.ID] = struct{}{}
+		res = append(res, s.ID)
+	}
+	return res
+}
+
+func (r ReplayReport) String() string {
+	var b strings.Builder
+	b.WriteString("replayed " + strconv.Itoa(r.Applied) + "/" + strconv.Itoa(r.Total) + " mutations")
+	if r.Aborted {
+		b.WriteString(" (aborted)")
+	}
+	if len(r.Skipped) > 0 {
+		b.WriteString(", skipped " + strconv.Itoa(len(r.Skipped)) + " for missing elements: " + strings.Join(r.MissingElements(), ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"context"
+	"strings"
+)
+
+// Route guards
+//
+// OnBeforeActivate registers a guard consulted before the views of the routes matching a pattern are
+// activated. A pattern is a route path whose segments may be parameters (":name"); it matches the routes
+// that have its segments as leading segments, so that "/admin" guards "/admin" as well as "/admin/users".
+// The guards matching a route are consulted in the order of registration, each returning a Decision:
+//   - Allow lets the next guard decide, the views being activated once all the guards have allowed it,
+//   - Cancel abandons the navigation and returns to the previous route,
+//   - Redirect abandons the navigation and redirects to another route,
+//   - Delay runs a loader, e.g. to fetch the data the views need, and resumes the navigation with the next
+//     guards once it has returned. Meanwhile, the "loading" ui property of the targeted ViewElement is true.
+//     The loader context is cancelled when another navigation starts, in which case the navigation is not
+//     resumed. An error returned by the loader is reported as a "loader" navigation failure (see Fail).
+//
+// Abandoned and delayed navigations are recorded with the "cancelled" and "delayed" outcomes respectively.
+
+// NavContext describes a navigation to a guard.
+type NavContext struct {
+	Context context.Context // cancelled when another navigation starts
+	Router  *Router
+	From    string
+	To      string
+	Params  map[string]string // values of the parameters of the pattern, by parameter name
+	Query   Object
+	View    ViewElement // ViewElement targeted by the route
+}
+
+type decisionKind int
+
+const (
+	decisionAllow decisionKind = iota
+	decisionCancel
+	decisionRedirect
+	decisionDelay
+)
+
+// Decision is the verdict of a route guard.
+type Decision struct {
+	kind  decisionKind
+	route string
+	load  func(context.Context) error
+}
+
+// Allow lets the navigation proceed.
+func Allow() Decision { return Decision{kind: decisionAllow} }
+
+// Cancel abandons the navigation.
+func Cancel() Decision { return Decision{kind: decisionCancel} }
+
+// Redirect abandons the navigation in favor of another route.
+func Redirect(route string) Decision { return Decision{kind: decisionRedirect, route: route} }
+
+// Delay postpones the activation of the views until load returns.
+func Delay(load func(ctx context.Context) error) Decision {
+	return Decision{kind: decisionDelay, load: load}
+}
+
+type routeGuard struct {
+	pattern []string
+	fn      func(NavContext) Decision
+}
+
+// guardResume records where the guards of a delayed navigation resume.
+type guardResume struct {
+	route string
+	from  string
+	next  int
+}
+
+// OnBeforeActivate registers a guard for the routes matching the pattern.
+func (r *Router) OnBeforeActivate(pattern string, guard func(ctx NavContext) Decision) *Router {
+	r.guards = append(r.guards, routeGuard{routeSegments(pattern), guard})
+	return r
+}
+
+// IsLoading returns whether the activation of the view is delayed by a route guard.
+func (v ViewElement) IsLoading() bool {
+	b, ok := v.AsElement().GetUI("loading")
+	return ok && bool(b.(Bool))
+}
+
+// runGuards consults the guards matching a route. It returns whether the views may be activated.
+func (r *Router) runGuards(route string, target ViewElement) bool {
+	if len(r.guards) == 0 {
+		return true
+	}
+	var from string
+	if r.navigation != nil {
+		from = r.navigation.from
+	}
+	start := 0
+	if res := r.resumed; res != nil {
+		r.resumed = nil
+		if res.route == route {
+			start, from = res.next, res.from
+		}
+	}
+
+	path, query := canonicalizeRoute(route)
+	q := NewObject().Commit()
+	if query != nil {
+		q = *query
+	}
+	segments := routeSegments(path)
+
+	for i := start; i < len(r.guards); i++ {
+		g := r.guards[i]
+		params, ok := matchGuard(g.pattern, segments)
+		if !ok {
+			continue
+		}
+		d := g.fn(NavContext{r.NavContext, r, from, route, params, q, target})
+		switch d.kind {
+		case decisionCancel:
+			r.setNavigationOutcome(NavigationCancelled)
+			if from != "" && from != route {
+				r.RedirectTo(from)
+			}
+			return false
+		case decisionRedirect:
+			r.RedirectTo(d.route)
+			return false
+		case decisionDelay:
+			r.setNavigationOutcome(NavigationDelayed)
+			r.delayActivation(route, from, i+1, target, d.load)
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Router) delayActivation(route, from string, next int, target ViewElement, load func(context.Context) error) {
+	e := target.AsElement()
+	ctx := r.NavContext
+	e.SetUI("loading", Bool(true))
+
+	DoAsync(nil, func(context.Context) {
+		err := load(ctx)
+		DoSync(func() {
+			e.SetUI("loading", Bool(false))
+			if current, _, _ := strings.Cut(r.CurrentRoute(), "#"); ctx.Err() != nil || current != route {
+				return
+			}
+			if err != nil {
+				r.Fail(route, "loader", err)
+				return
+			}
+			r.resumed = &guardResume{route, from, next}
+			r.RedirectTo(route)
+		})
+	})
+}
+
+func routeSegments(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// matchGuard returns whether the leading segments of a route match a pattern, along with the values of
+// the parameters of the pattern.
+func matchGuard(pattern, segments []string) (map[string]string, bool) {
+	if len(segments) < len(pattern) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, p := range pattern {
+		if strings.HasPrefix(p, ":") {
+			params[strings.TrimPrefix(p, ":")] = segments[i]
+			continue
+		}
+		if p != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRouteGuards(t *testing.T) {
+	s, restore := UseTestScheduler()
+	defer restore()
+
+	c := NewConfiguration("routeguardstest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	root := c.NewAppRoot("root")
+	app, home, admin, report := newdiv("app"), newdiv("home"), newdiv("admin"), newdiv("report")
+	for _, e := range []*Element{app, home, admin, report} {
+		RegisterElement(root, e)
+	}
+	outlet := NewViewElement(app, NewView("home", home), NewView("admin", admin), NewView("report", report))
+	root.AppendChild(outlet)
+
+	active := func() string {
+		n, _ := outlet.ActiveViewName()
+		return n
+	}
+
+	var outcomes []string
+	r := NewRouter(outlet, InMemoryHistory)
+	// as done by ListenAndServe, which blocks
+	root.WatchEvent("navigation-routeredirectrequest", root, r.redirecthandler())
+	r.OnNavigationRecord(NewMutationHandler(func(evt MutationEvent) bool {
+		o, _ := evt.NewValue().(Object).Get("outcome")
+		outcomes = append(outcomes, string(o.(String)))
+		return false
+	}))
+
+	loggedin := false
+	r.OnBeforeActivate("/admin", func(ctx NavContext) Decision {
+		if !loggedin {
+			return Redirect("/home")
+		}
+		return Allow()
+	})
+
+	var loaded, failing bool
+	var after int
+	r.OnBeforeActivate("/report", func(ctx NavContext) Decision {
+		if loaded {
+			t.Fatal("expected the guards consulted before a delay not to be consulted again")
+		}
+		return Delay(func(ctx context.Context) error {
+			loaded = true
+			if failing {
+				return errors.New("unavailable")
+			}
+			return nil
+		})
+	})
+	r.OnBeforeActivate("/report", func(ctx NavContext) Decision {
+		after++
+		return Allow()
+	})
+
+	r.GoTo("/home")
+	r.GoTo("/admin")
+	if got := r.CurrentRoute(); got != "/home" {
+		t.Fatalf("expected a redirection to /home, got %s", got)
+	}
+	loggedin = true
+	r.GoTo("/admin")
+	if active() != "admin" {
+		t.Fatalf("expected the admin view to be active, got %s", active())
+	}
+
+	r.GoTo("/report")
+	if active() != "admin" || !outlet.IsLoading() || after != 0 {
+		t.Fatal("expected the activation to be delayed until the loader returns")
+	}
+	s.Flush()
+	if active() != "report" || outlet.IsLoading() || after != 1 {
+		t.Fatalf("expected the report view to be active once loaded, got %s", active())
+	}
+	if last := outcomes[len(outcomes)-2:]; last[0] != NavigationDelayed || last[1] != NavigationOK {
+		t.Fatalf("unexpected outcomes: %v", outcomes)
+	}
+
+	loaded = false
+	failing = true
+	var failed bool
+	root.WatchEvent("navigation-appfailure", root, NewMutationHandler(func(evt MutationEvent) bool {
+		failed = true
+		return false
+	}))
+	r.GoTo("/home")
+	r.GoTo("/report")
+	s.Flush()
+	if !failed || active() != "home" {
+		t.Fatal("expected the loader error to be reported as a navigation failure")
+	}
+}
@@ -70,6 +70,14 @@ type Router struct {
 	History *NavHistory
 
 	LeaveTrailingSlash bool
+
+	errorView      ErrorViewFactory
+	backend        HistoryBackend
+	scrollBehavior func(from, to Route, saved *Position) ScrollDecision
+	navigation     *navigationRecord
+	navTrigger     string
+	guards         []routeGuard
+	resumed        *guardResume
 }
 
 func TrailingSlashMatters(r *Router) *Router {
@@ -86,7 +94,8 @@ func NewRouter(rootview ViewElement, options ...func(*Router) *Router) *Router {
 		panic("router can only use a view attached to the main tree as a navigation Outlet.")
 	}
 
-	r := &Router{rootview, nil, nil, make(map[string]Link, 300), newrootrnode(rootview), NewNavigationHistory(rootview.AsElement().Root), false}
+	navctx, cancelnav := newCancelableNavContext()
+	r := &Router{rootview, navctx, cancelnav, make(map[string]Link, 300), newrootrnode(rootview), NewNavigationHistory(rootview.AsElement().Root), false, nil, nil, nil, nil, "", nil, nil}
 
 	r.Outlet.AsElement().Root.WatchEvent("docupdate", r.Outlet.AsElement().Root, NewMutationHandler(func(evt MutationEvent) bool {
 		_, navready := evt.Origin().Get(Namespace.Navigation, "ready")
@@ -119,6 +128,20 @@ func NewRouter(rootview ViewElement, options ...func(*Router) *Router) *Router {
 		return false
 	}))
 
+	for event, outcome := range map[string]string{
+		"navigation-notfound":     NavigationNotFound,
+		"navigation-unauthorized": NavigationUnauthorized,
+		"navigation-appfailure":   NavigationAppFailure,
+	} {
+		outcome := outcome
+		r.Outlet.AsElement().Root.WatchEvent(event, r.Outlet.AsElement().Root, NewMutationHandler(func(evt MutationEvent) bool {
+			r.setNavigationOutcome(outcome)
+			return false
+		}))
+	}
+
+	r.Outlet.AsElement().Root.Watch(Namespace.UI, "history", r.Outlet.AsElement().Root, r.historyBackendHandler())
+
 	r.Outlet.AsElement().Configuration.NewConstructor("zui_link", func(id string) *Element {
 		e := r.Outlet.AsElement().Configuration.NewElement(id, "ROUTER")
 		RegisterElement(r.Outlet.AsElement().Root, e)
@@ -191,11 +214,17 @@ func (r *Router) tryNavigate(newroute string) bool {
 		}
 		if err == ErrFrameworkFailure {
 			log.Print(err) //DEBUG
-			r.Outlet.AsElement().Root.TriggerEvent("navigation-appfailure", String(newroute))
+			r.fail(newroute, "navigation", err, "")
 			return false
 		}
 	}
-	err = a()
+	if !r.runGuards(newroute, v) {
+		return false
+	}
+	err = r.activate(newroute, a)
+	if err == errActivationPanic {
+		return false
+	}
 	if err != nil {
 		log.Print("activation failure ", err) // DEBUG
 		r.Outlet.AsElement().Root.TriggerEvent("navigation-unauthorized", String(newroute))
@@ -219,6 +248,7 @@ func (r *Router) GoTo(route string) {
 	if !r.LeaveTrailingSlash {
 		route = strings.TrimSuffix(route, "/")
 	}
+	r.beginNavigation(route, r.trigger(NavigationTriggerProgrammatic))
 
 	r.History.Push(route)
 	r.Outlet.AsElement().Root.SetUI("currentroute", String(route))
@@ -231,18 +261,21 @@ func (r *Router) GoTo(route string) {
 		DEBUG("NAVIGATION FAILED FOR SOME REASON.") // DEBUG
 	}
 
+	r.endNavigation("")
 	r.Outlet.AsElement().Root.TriggerEvent("navigation-end", String(route))
 
 }
 
 func (r *Router) GoBack() {
 	if r.History.BackAllowed() {
+		r.navTrigger = NavigationTriggerProgrammatic
 		r.Outlet.AsElement().Root.TriggerEvent("navigation-routechangerequest", String(r.History.Back()))
 	}
 }
 
 func (r *Router) GoForward() {
 	if r.History.ForwardAllowed() {
+		r.navTrigger = NavigationTriggerProgrammatic
 		r.Outlet.AsElement().Root.TriggerEvent("navigation-routechangerequest", String(r.History.Forward()))
 	}
 }
@@ -344,7 +377,7 @@ func (r *Router) handler() *MutationHandler {
 		nroute, ok := evt.NewValue().(String)
 		if !ok {
 			log.Print("route mutation has wrong type... something must be wrong", evt.NewValue())
-			r.Outlet.AsElement().Root.TriggerEvent("navigation-appfailure", Bool(true))
+			r.fail("", "navigation", errors.New("route should be a String"), "")
 			return true
 		}
 		newroute := string(nroute)
@@ -357,6 +390,7 @@ func (r *Router) handler() *MutationHandler {
 		if found {
 			newroute = route
 		}
+		r.beginNavigation(newroute, r.trigger(NavigationTriggerPopstate))
 
 		// Determination of navigation history action
 		h, ok := r.Outlet.AsElement().Root.Get(Namespace.Data, "history")
@@ -411,22 +445,25 @@ func (r *Router) handler() *MutationHandler {
 			}
 			if err == ErrFrameworkFailure {
 				log.Print("APPFAILURE: ", err) // DEBUG
-				r.Outlet.AsElement().Root.TriggerEvent("navigation-appfailure", String(newroute))
+				r.fail(newroute, "navigation", err, "")
 				//return false
 			}
 		} else {
 			r.Outlet.AsElement().Root.TriggerEvent("navigation-start", String(newroute))
-			err = a()
-			if err != nil {
-				r.Outlet.AsElement().Root.TriggerEvent("navigation-unauthorized", String(newroute))
-				DEBUG("activation failure", err)
-			}
+			if r.runGuards(newroute, v) {
+				err = r.activate(newroute, a)
+				if err != nil && err != errActivationPanic {
+					r.Outlet.AsElement().Root.TriggerEvent("navigation-unauthorized", String(newroute))
+					DEBUG("activation failure", err)
+				}
 
-			if found {
-				r.Outlet.AsElement().Root.Set(Namespace.Navigation, "hash", String(hash))
+				if found {
+					r.Outlet.AsElement().Root.Set(Namespace.Navigation, "hash", String(hash))
+				}
 			}
 		}
 
+		r.endNavigation("")
 		r.Outlet.AsElement().Root.TriggerEvent("navigation-end", String(newroute))
 
 		return false
@@ -440,7 +477,7 @@ func (r *Router) redirecthandler() *MutationHandler {
 		nroute, ok := evt.NewValue().(String)
 		if !ok {
 			log.Print("route mutation has wrong type... something must be wrong", evt.NewValue())
-			r.Outlet.AsElement().Root.TriggerEvent("navigation-appfailure", Bool(true))
+			r.fail("", "navigation", errors.New("route should be a String"), "")
 			return true
 		}
 		newroute := string(nroute)
@@ -453,6 +490,7 @@ func (r *Router) redirecthandler() *MutationHandler {
 		if found {
 			newroute = route
 		}
+		r.beginNavigation(newroute, r.trigger(NavigationTriggerProgrammatic))
 
 		r.History.Replace(newroute)
 		r.Outlet.AsElement().Root.SetUI("currentroute", String(newroute))
@@ -474,23 +512,26 @@ func (r *Router) redirecthandler() *MutationHandler {
 			}
 			if err == ErrFrameworkFailure {
 				log.Print(err) //DEBUG
-				r.Outlet.AsElement().Root.TriggerEvent("navigation-appfailure", String(newroute))
+				r.fail(newroute, "navigation", err, "")
 				//return false
 			}
 		} else {
 			r.Outlet.AsElement().Root.TriggerEvent("navigation-start", String(newroute))
-			err = a()
-			if err != nil {
-				log.Print(err) // DEBUG
-				log.Print("unauthorized for: " + newroute)
-				r.Outlet.AsElement().Root.TriggerEvent("navigation-unauthorized", String(newroute))
-			}
+			if r.runGuards(newroute, v) {
+				err = r.activate(newroute, a)
+				if err != nil && err != errActivationPanic {
+					log.Print(err) // DEBUG
+					log.Print("unauthorized for: " + newroute)
+					r.Outlet.AsElement().Root.TriggerEvent("navigation-unauthorized", String(newroute))
+				}
 
-			if found {
-				r.Outlet.AsElement().Root.Set(Namespace.Navigation, "hash", String(hash))
+				if found {
+					r.Outlet.AsElement().Root.Set(Namespace.Navigation, "hash", String(hash))
+				}
 			}
 		}
 
+		r.endNavigation("")
 		r.Outlet.AsElement().Root.TriggerEvent("navigation-end", String(newroute))
 
 		return false
@@ -738,16 +779,32 @@ func (rn *rnode) insert(nrn *rnode) {
 		return
 	}
 	viewpathnodes := viewpath.Nodes
-	var ancestor *Element
 	if len(viewpathnodes) == 0 {
 		return
-	} else {
-		ancestor = viewpathnodes[0].Element
 	}
-	if ancestor.ID != rn.root.ViewElement.AsElement().ID {
+	// The root ViewElement may be the outlet of a region router, nested within other views.
+	start := -1
+	for i, node := range viewpathnodes {
+		if node.Element.ID == rn.root.ViewElement.AsElement().ID {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
 		log.Print("Houston, we have a problem. Everything shall start from rnode toot ViewElement")
 		return
 	}
+	viewpathnodes = viewpathnodes[start:]
+
+	// views of a nested region belong to the router of that region.
+	if isRegionOutlet(v.AsElement()) {
+		return
+	}
+	for _, node := range viewpathnodes[1:] {
+		if isRegionOutlet(node.Element) {
+			return
+		}
+	}
 	l := len(viewpathnodes)
 	// attach iteratively the rnodes
 	refnode := rn
@@ -787,6 +844,7 @@ func (r *rnode) match(route string) (targetview ViewElement, prefetchFn func(),
 
 	activations := make([]func() error, 0, 10)
 	prefetchers := make([]func(), 0, 10)
+	budget := PrefetchBudget
 	route = strings.TrimPrefix(route, "/")
 	segments := strings.Split(route, "/")
 	ls := len(segments)
@@ -843,7 +901,7 @@ func (r *rnode) match(route string) (targetview ViewElement, prefetchFn func(),
 
 				p := func() {
 					r.ViewElement.AsElement().Prefetch()
-					r.ViewElement.prefetchView(segments[0])
+					r.ViewElement.prefetchView(segments[0], &budget)
 				}
 				prefetchers = append(prefetchers, p)
 			} else {
@@ -916,7 +974,7 @@ func (r *rnode) match(route string) (targetview ViewElement, prefetchFn func(),
 			} else {
 				p := func() {
 					r.ViewElement.AsElement().Prefetch()
-					r.ViewElement.prefetchView(nextroutesegment)
+					r.ViewElement.prefetchView(nextroutesegment, &budget)
 				}
 				prefetchers = append(prefetchers, p)
 			}
@@ -934,6 +992,7 @@ func (r *rnode) match(route string) (targetview ViewElement, prefetchFn func(),
 	}
 
 	prefetchFn = func() {
+		budget = PrefetchBudget
 		for _, p := range prefetchers {
 			p()
 		}
@@ -1095,6 +1154,7 @@ func (r *Router) NewLink(viewname string, modifiers ...func(Link) Link) Link {
 		if s, ok := evt.NewValue().(String); ok {
 			hash = "#" + string(s)
 		}
+		r.navTrigger = NavigationTriggerLink
 		r.GoTo(l.URI() + hash)
 		return false
 	}))
@@ -0,0 +1,208 @@
+package ui
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Deterministic scheduling
+//
+// By default, asynchronous work is scheduled on goroutines and real timers: the deferred calls of rate limited
+// handlers (Debounce, Throttle, Latest) go through the WorkQueue, DoAsync runs its function on a new goroutine,
+// and DoAfter waits on a timer.
+// For tests, UseTestScheduler installs a Scheduler which is driven manually instead:
+//   - time is virtual: Now and AfterFunc use the scheduler Clock, which only moves with Clock.Advance,
+//   - scheduled work is queued, and only runs on Scheduler.Flush, on the calling goroutine which plays the
+//     part of the UI thread. DoSync queues its function as well, without waiting for it to run.
+//
+// Clock.Advance fires the timers that are due in order, flushing the queue after each of them, so that work
+// runs at the virtual time it was scheduled for.
+//
+//	s, restore := ui.UseTestScheduler()
+//	defer restore()
+//	input.SetUI("value", ui.String("zui")) // watched by a handler debounced for 300ms
+//	s.Clock.Advance(300 * time.Millisecond)
+//	// the debounced handler has run
+
+// Timer is a scheduled call which can be stopped.
+type Timer interface {
+	Stop() bool
+}
+
+var schedulers = struct {
+	sync.Mutex
+	active *Scheduler
+	// installed is non-zero while a test scheduler is active. It is read atomically so that the default
+	// scheduling does not contend on the mutex.
+	installed int32
+}{}
+
+func currentScheduler() *Scheduler {
+	if atomic.LoadInt32(&schedulers.installed) == 0 {
+		return nil
+	}
+	schedulers.Lock()
+	defer schedulers.Unlock()
+	return schedulers.active
+}
+
+// Now returns the current time, which is virtual when a test scheduler is in use.
+func Now() time.Time {
+	if s := currentScheduler(); s != nil {
+		return s.Clock.Now()
+	}
+	return time.Now()
+}
+
+// AfterFunc calls f on its own goroutine after the duration d, or when the test Clock has been advanced by d
+// if a test scheduler is in use.
+func AfterFunc(d time.Duration, f func()) Timer {
+	if s := currentScheduler(); s != nil {
+		return s.Clock.afterFunc(d, f)
+	}
+	return time.AfterFunc(d, f)
+}
+
+// Scheduler queues the asynchronous work of the framework until it is flushed.
+type Scheduler struct {
+	mu    sync.Mutex
+	queue []func()
+
+	Clock *Clock
+}
+
+// UseTestScheduler installs a manually driven scheduler. The returned function restores the default scheduling.
+// It should not be used concurrently by parallel tests.
+func UseTestScheduler() (s *Scheduler, restore func()) {
+	s = &Scheduler{}
+	s.Clock = &Clock{now: time.Unix(0, 0).UTC(), s: s}
+	schedulers.Lock()
+	previous := schedulers.active
+	schedulers.active = s
+	atomic.StoreInt32(&schedulers.installed, 1)
+	schedulers.Unlock()
+	return s, func() {
+		schedulers.Lock()
+		schedulers.active = previous
+		if previous == nil {
+			atomic.StoreInt32(&schedulers.installed, 0)
+		}
+		schedulers.Unlock()
+	}
+}
+
+func (s *Scheduler) enqueue(fn func()) {
+	s.mu.Lock()
+	s.queue = append(s.queue, fn)
+	s.mu.Unlock()
+}
+
+// Pending returns the number of queued functions.
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Flush runs the queued functions, including the ones they queue, until the queue is empty.
+// It returns the number of functions that were run.
+func (s *Scheduler) Flush() int {
+	var n int
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			return n
+		}
+		fn := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		fn()
+		n++
+	}
+}
+
+// Clock is the virtual clock of a test scheduler.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*virtualTimer
+	seq    int
+	s      *Scheduler
+}
+
+type virtualTimer struct {
+	c    *Clock
+	when time.Time
+	seq  int
+	f    func()
+}
+
+// Now returns the current virtual time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) afterFunc(d time.Duration, f func()) *virtualTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	t := &virtualTimer{c, c.now.Add(d), c.seq, f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Stop cancels the timer. It returns false if the timer had already fired or been stopped.
+func (t *virtualTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	for i, v := range t.c.timers {
+		if v == t {
+			t.c.timers = append(t.c.timers[:i], t.c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// next removes and returns the earliest timer due at the deadline, if any, and moves the clock to its time.
+func (c *Clock) next(deadline time.Time) *virtualTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.timers) == 0 {
+		return nil
+	}
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		if c.timers[i].when.Equal(c.timers[j].when) {
+			return c.timers[i].seq < c.timers[j].seq
+		}
+		return c.timers[i].when.Before(c.timers[j].when)
+	})
+	t := c.timers[0]
+	if t.when.After(deadline) {
+		return nil
+	}
+	c.timers = c.timers[1:]
+	if t.when.After(c.now) {
+		c.now = t.when
+	}
+	return t
+}
+
+// Advance moves the virtual time forward by d, firing the timers that are due in order. The queued work is
+// flushed before and after each timer.
+func (c *Clock) Advance(d time.Duration) {
+	deadline := c.Now().Add(d)
+	c.s.Flush()
+	for t := c.next(deadline); t != nil; t = c.next(deadline) {
+		t.f()
+		c.s.Flush()
+	}
+	c.mu.Lock()
+	c.now = deadline
+	c.mu.Unlock()
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTestScheduler(t *testing.T) {
+	s, restore := UseTestScheduler()
+	defer restore()
+
+	c := NewConfiguration("schedulertest", "test")
+	root := c.NewAppRoot("root")
+	e := c.NewElement("input", "test")
+	RegisterElement(root, e)
+
+	var debounced []string
+	e.Watch(Namespace.UI, "value", e, NewMutationHandler(func(evt MutationEvent) bool {
+		debounced = append(debounced, string(evt.NewValue().(String)))
+		return false
+	}).Debounce(300*time.Millisecond))
+
+	e.SetUI("value", String("z"))
+	s.Clock.Advance(200 * time.Millisecond)
+	e.SetUI("value", String("zu"))
+	s.Clock.Advance(200 * time.Millisecond)
+	e.SetUI("value", String("zui"))
+	if len(debounced) != 0 {
+		t.Fatalf("expected no call before the quiet period, got %v", debounced)
+	}
+	s.Clock.Advance(300 * time.Millisecond)
+	if len(debounced) != 1 || debounced[0] != "zui" {
+		t.Fatalf("expected a single call with the latest value, got %v", debounced)
+	}
+
+	var async, after bool
+	DoAsync(nil, func(ctx context.Context) {
+		DoSync(func() { async = true })
+	})
+	DoAfter(time.Second, nil, func(ctx context.Context) { after = true })
+	if async || s.Pending() != 1 {
+		t.Fatal("expected DoAsync to be queued until flushed")
+	}
+	s.Flush()
+	if !async || after {
+		t.Fatalf("expected only DoAsync to have run, got async=%v after=%v", async, after)
+	}
+	s.Clock.Advance(time.Second)
+	if !after {
+		t.Fatal("expected DoAfter to run once the clock has been advanced")
+	}
+
+	var synced bool
+	DoSync(func() { synced = true })
+	if synced || s.Pending() != 1 {
+		t.Fatal("expected DoSync to be queued until flushed")
+	}
+	s.Flush()
+	if !synced {
+		t.Fatal("expected DoSync to have run")
+	}
+
+	restore()
+	if currentScheduler() != nil {
+		t.Fatal("expected the default scheduling to be restored")
+	}
+}
@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"strings"
+)
+
+// Scroll behavior
+//
+// After each navigation, drivers which manage the scroll position ask the router what to do with it.
+// The decision is taken by the function registered with ScrollBehavior, from the route left, the route
+// reached, and the scroll position saved for the history entry reached, if any. Without one,
+// DefaultScrollBehavior applies: the saved position is restored when going back or forth, the target of the
+// fragment is scrolled into view, and the page is scrolled to the top otherwise.
+
+// Route is a route split into its path and fragment.
+type Route struct {
+	Path     string
+	Fragment string
+}
+
+// ParseRoute splits a route into its path and fragment.
+func ParseRoute(route string) Route {
+	path, fragment, _ := strings.Cut(route, "#")
+	return Route{path, fragment}
+}
+
+func (r Route) String() string {
+	if r.Fragment == "" {
+		return r.Path
+	}
+	return r.Path + "#" + r.Fragment
+}
+
+// Position is a scroll position.
+type Position struct {
+	X, Y float64
+}
+
+// ScrollDecision is what happens to the scroll position after a navigation.
+type ScrollDecision int
+
+const (
+	ScrollRestore  ScrollDecision = iota // scrolls to the saved position, or to the top if there is none
+	ScrollToTop                          // scrolls to the top
+	ScrollToAnchor                       // scrolls the element targeted by the fragment into view, or to the top
+	ScrollPreserve                       // leaves the scroll position as it is
+)
+
+// DefaultScrollBehavior is the scroll behavior of routers which have none registered.
+func DefaultScrollBehavior(from, to Route, saved *Position) ScrollDecision {
+	if saved != nil {
+		return ScrollRestore
+	}
+	if to.Fragment != "" {
+		return ScrollToAnchor
+	}
+	return ScrollToTop
+}
+
+// ScrollBehavior registers the function deciding what happens to the scroll position after a navigation.
+func (r *Router) ScrollBehavior(fn func(from, to Route, saved *Position) ScrollDecision) *Router {
+	r.scrollBehavior = fn
+	return r
+}
+
+// ScrollDecision returns what should happen to the scroll position after a navigation.
+// It is meant for drivers.
+func (r *Router) ScrollDecision(from, to Route, saved *Position) ScrollDecision {
+	if r.scrollBehavior == nil {
+		return DefaultScrollBehavior(from, to, saved)
+	}
+	return r.scrollBehavior(from, to, saved)
+}
@@ -0,0 +1,30 @@
+package ui
+
+import "testing"
+
+func TestScrollDecision(t *testing.T) {
+	r := &Router{}
+	from := ParseRoute("/a")
+	if d := r.ScrollDecision(from, ParseRoute("/b"), nil); d != ScrollToTop {
+		t.Fatalf("expected a new page to be scrolled to the top, got %v", d)
+	}
+	if d := r.ScrollDecision(from, ParseRoute("/b#section"), nil); d != ScrollToAnchor {
+		t.Fatalf("expected the fragment target to be scrolled into view, got %v", d)
+	}
+	if d := r.ScrollDecision(from, ParseRoute("/b"), &Position{0, 120}); d != ScrollRestore {
+		t.Fatalf("expected the saved position to be restored, got %v", d)
+	}
+
+	r.ScrollBehavior(func(from, to Route, saved *Position) ScrollDecision {
+		if from.Path == to.Path {
+			return ScrollPreserve
+		}
+		return DefaultScrollBehavior(from, to, saved)
+	})
+	if d := r.ScrollDecision(from, ParseRoute("/a#tab2"), nil); d != ScrollPreserve {
+		t.Fatalf("expected the registered behavior to be used, got %v", d)
+	}
+	if to := ParseRoute("/b#section"); to.Path != "/b" || to.Fragment != "section" || to.String() != "/b#section" {
+		t.Fatalf("unexpected route %+v", to)
+	}
+}
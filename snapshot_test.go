@@ -0,0 +1,76 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnapshotHTML(t *testing.T) {
+	c := NewConfiguration("snapshottest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *Element {
+		return c.NewElement(id, "test")
+	})
+
+	root := newdiv("root")
+	child := newdiv("child")
+	child.Properties.Set(Namespace.UI, "text", String("hello"))
+	child.Properties.Set(Namespace.Data, "count", Number(2))
+	root.Children.InsertLast(child)
+
+	want := "<div id=\"root\">\n  <div id=\"child\" text=\"hello\" data-count=\"2\"></div>\n</div>\n"
+	if got := Snapshot(root).HTML(); got != want {
+		t.Errorf("unexpected snapshot:\n%s", SnapshotDiff(want, got))
+	}
+
+	js := Snapshot(root).JSON()
+	if !strings.Contains(js, `"constructor": "div"`) || !strings.Contains(js, `"count": 2`) {
+		t.Errorf("unexpected JSON snapshot: %s", js)
+	}
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	got := SnapshotDiff("a\nb\nc", "a\nd\nc")
+	want := " a\n-b\n+d\n c\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestSnapshotNamespace(t *testing.T) {
+	c := NewConfiguration("nssnapshottest", "test")
+	e := c.NewElement("form", "test")
+	e.Properties.Set(Namespace.Data, "name", String("zui"))
+	e.Properties.Set(Namespace.Data, "count", Number(1))
+	e.Properties.Set(Namespace.Data, "tags", NewList(String("a")).Commit())
+
+	before := e.SnapshotNamespace(Namespace.Data)
+	count := func(o Object) int {
+		n := 0
+		o.Range(func(string, Value) bool { n++; return false })
+		return n
+	}
+	if n := count(before); n != 3 {
+		t.Fatalf("expected 3 properties in the snapshot, got %d", n)
+	}
+
+	e.Properties.Set(Namespace.Data, "count", Number(2))
+	e.Properties.Delete(Namespace.Data, "name")
+	e.Properties.Set(Namespace.Data, "dirty", Bool(true))
+	after := e.SnapshotNamespace(Namespace.Data)
+
+	if v, _ := before.Get("count"); v != Number(1) {
+		t.Fatalf("expected the snapshot to be unaffected by later mutations, got %v", v)
+	}
+
+	d := DiffNamespaces(before, after)
+	if len(d.Added) != 1 || d.Added[0] != "dirty" || len(d.Removed) != 1 || d.Removed[0] != "name" ||
+		len(d.Changed) != 1 || d.Changed[0] != "count" {
+		t.Fatalf("unexpected diff %+v", d)
+	}
+	if !DiffNamespaces(after, e.SnapshotNamespace(Namespace.Data)).Empty() {
+		t.Fatal("expected identical snapshots to have an empty diff")
+	}
+	if count(e.SnapshotNamespace(Namespace.UI)) != 0 {
+		t.Fatal("expected an empty snapshot for an unused namespace")
+	}
+}
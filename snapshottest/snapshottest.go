@@ -0,0 +1,66 @@
+// Package snapshottest provides golden-file helpers for the snapshot testing of element trees.
+//
+// Golden files are stored under SnapshotDir. They are (re)generated by running the tests with the
+// ZUI_UPDATE_SNAPSHOTS environment variable set to a non-empty value.
+package snapshottest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// SnapshotDir is the directory in which golden snapshot files are stored.
+var SnapshotDir = filepath.Join("testdata", "snapshots")
+
+// MatchSnapshot compares got with the golden file named name in SnapshotDir.
+// If the golden file does not exist or snapshots are being updated, the file is written instead.
+// On mismatch, the test fails with a line diff of the expected and actual output.
+func MatchSnapshot(t testing.TB, name string, got string) {
+	t.Helper()
+	path := filepath.Join(SnapshotDir, name+".golden")
+
+	if os.Getenv("ZUI_UPDATE_SNAPSHOTS") != "" {
+		writeSnapshot(t, path, got)
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			writeSnapshot(t, path, got)
+			return
+		}
+		t.Fatalf("unable to read snapshot %s: %v", path, err)
+		return
+	}
+
+	if string(want) != got {
+		t.Errorf("snapshot %s mismatch (-want +got):\n%s", name, ui.SnapshotDiff(string(want), got))
+	}
+}
+
+// MatchHTMLSnapshot renders the Element subtree to canonicalized HTML and compares it to the named golden file.
+func MatchHTMLSnapshot(t testing.TB, name string, e *ui.Element) {
+	t.Helper()
+	MatchSnapshot(t, name+".html", ui.Snapshot(e).HTML())
+}
+
+// MatchJSONSnapshot renders the Element subtree to its semantic JSON tree and compares it to the named golden file.
+func MatchJSONSnapshot(t testing.TB, name string, e *ui.Element, namespaces ...string) {
+	t.Helper()
+	MatchSnapshot(t, name+".json", ui.Snapshot(e, namespaces...).JSON())
+}
+
+func writeSnapshot(t testing.TB, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("unable to create snapshot directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("unable to write snapshot %s: %v", path, err)
+	}
+}
@@ -0,0 +1,85 @@
+package snapshottest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ui "github.com/atdiar/particleui"
+)
+
+// recorder records the failures reported by the helpers instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMatchSnapshot(t *testing.T) {
+	dir := SnapshotDir
+	SnapshotDir = t.TempDir()
+	defer func() { SnapshotDir = dir }()
+	t.Setenv("ZUI_UPDATE_SNAPSHOTS", "")
+
+	c := ui.NewConfiguration("snapshottest", "test")
+	newdiv := c.NewConstructor("div", func(id string) *ui.Element {
+		return c.NewElement(id, "test")
+	})
+	root := newdiv("root")
+	root.Properties.Set(ui.Namespace.UI, "text", ui.String("hello"))
+	golden := filepath.Join(SnapshotDir, "root.html.golden")
+
+	// a missing golden file is created.
+	r := &recorder{}
+	MatchHTMLSnapshot(r, "root", root)
+	if len(r.errors) != 0 {
+		t.Fatalf("unexpected failures: %v", r.errors)
+	}
+	b, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != ui.Snapshot(root).HTML() {
+		t.Fatalf("unexpected golden file content %q", b)
+	}
+
+	// match
+	MatchHTMLSnapshot(r, "root", root)
+	if len(r.errors) != 0 {
+		t.Fatalf("unexpected failures: %v", r.errors)
+	}
+
+	// mismatch
+	root.Properties.Set(ui.Namespace.UI, "text", ui.String("bye"))
+	MatchHTMLSnapshot(r, "root", root)
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], `-<div id="root" text="hello"></div>`) ||
+		!strings.Contains(r.errors[0], `+<div id="root" text="bye"></div>`) {
+		t.Fatalf("expected a single mismatch failure with a diff, got %v", r.errors)
+	}
+
+	// update
+	t.Setenv("ZUI_UPDATE_SNAPSHOTS", "1")
+	r = &recorder{}
+	MatchHTMLSnapshot(r, "root", root)
+	if len(r.errors) != 0 {
+		t.Fatalf("unexpected failures: %v", r.errors)
+	}
+	b, err = os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != ui.Snapshot(root).HTML() {
+		t.Fatalf("expected the golden file to be updated, got %q", b)
+	}
+}
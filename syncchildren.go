@@ -0,0 +1,69 @@
+package ui
+
+// Keyed children reconciliation
+//
+// SyncChildren keeps the children of an element in sync with a List of values, each value being rendered as
+// a child element identified by a key. On each call:
+//   - the children whose key is still present are kept, along with their state, and moved if needed,
+//   - new keys are rendered into new children,
+//   - the children whose key has disappeared are deleted.
+//
+// The children are then set with SetChildren which computes the minimal list of insertions and removals and
+// forwards them as a single batch to the native element when it supports batching.
+//
+// The value a child was rendered from is stored in its "item" data property and updated on subsequent calls, so
+// that a child may watch it to reflect changes of its value without being rendered again.
+
+var keyedChildren = newscsmap[*Element, map[string]*Element]()
+
+// SyncChildren reconciles the children of parent with the values of list.
+// key returns the key of a value and should be unique within the list: the values whose key has already been
+// seen are skipped. render creates the element of a new key, or of a key whose child has been deleted.
+func SyncChildren(parent AnyElement, list List, key func(Value) string, render func(Value) *Element) {
+	p := parent.AsElement()
+	old, ok := keyedChildren.Get(p)
+	if !ok {
+		old = make(map[string]*Element)
+		p.OnDeleted(NewMutationHandler(func(evt MutationEvent) bool {
+			keyedChildren.Delete(evt.Origin())
+			return false
+		}).RunOnce())
+	}
+
+	values := list.UnsafelyUnwrap()
+	keyed := make(map[string]*Element, len(values))
+	children := make([]*Element, 0, len(values))
+	for _, v := range values {
+		k := key(v)
+		if _, ok := keyed[k]; ok {
+			DEBUG("SyncChildren: duplicate key skipped:", k)
+			continue
+		}
+		c, ok := old[k]
+		if !ok || isDeleted(c) {
+			c = render(v)
+		}
+		c.SetData("item", v)
+		keyed[k] = c
+		children = append(children, c)
+	}
+
+	p.SetChildren(children...)
+	keyedChildren.Set(p, keyed)
+
+	for k, c := range old {
+		if _, ok := keyed[k]; !ok {
+			Delete(c)
+		}
+	}
+}
+
+// ChildByKey returns the child rendered for a key by SyncChildren.
+func ChildByKey(parent AnyElement, key string) (*Element, bool) {
+	m, ok := keyedChildren.Get(parent.AsElement())
+	if !ok {
+		return nil, false
+	}
+	c, ok := m[key]
+	return c, ok
+}
@@ -0,0 +1,66 @@
+package ui
+
+import "testing"
+
+func TestSyncChildren(t *testing.T) {
+	c := NewConfiguration("synctest", "test")
+	root := c.NewAppRoot("root")
+	list := c.NewElement("list", "test")
+	RegisterElement(root, list)
+	root.AppendChild(list)
+
+	rendered := 0
+	key := func(v Value) string { return string(v.(Object).MustGetString("id")) }
+	render := func(v Value) *Element {
+		rendered++
+		e := c.NewElement("item-"+key(v), "test")
+		RegisterElement(root, e)
+		return e
+	}
+	item := func(id, label string) Value {
+		return NewObject().Set("id", String(id)).Set("label", String(label)).Commit()
+	}
+	ids := func() []string {
+		var res []string
+		for _, c := range list.Children.List {
+			res = append(res, c.ID)
+		}
+		return res
+	}
+
+	SyncChildren(list, NewList(item("a", "A"), item("b", "B"), item("c", "C")).Commit(), key, render)
+	if rendered != 3 || len(list.Children.List) != 3 {
+		t.Fatalf("expected 3 rendered children, got %d", rendered)
+	}
+	b, _ := ChildByKey(list, "b")
+
+	SyncChildren(list, NewList(item("c", "C"), item("b", "B2"), item("d", "D")).Commit(), key, render)
+	got := ids()
+	if len(got) != 3 || got[0] != "item-c" || got[1] != "item-b" || got[2] != "item-d" {
+		t.Fatalf("unexpected children order %v", got)
+	}
+	if rendered != 4 {
+		t.Fatalf("expected only the new key to be rendered, got %d renders", rendered)
+	}
+	if nb, _ := ChildByKey(list, "b"); nb != b {
+		t.Fatal("expected the element of a kept key to be reused")
+	}
+	if v, _ := b.GetData("item"); v.(Object).MustGetString("label") != "B2" {
+		t.Fatal("expected the item of a kept key to be updated")
+	}
+	if _, ok := ChildByKey(list, "a"); ok {
+		t.Fatal("expected the removed key to be forgotten")
+	}
+
+	SyncChildren(list, NewList(item("c", "C"), item("c", "C2"), item("d", "D")).Commit(), key, render)
+	if got := ids(); len(got) != 2 || got[0] != "item-c" || got[1] != "item-d" {
+		t.Fatalf("expected the duplicate key to be skipped, got %v", got)
+	}
+
+	d, _ := ChildByKey(list, "d")
+	Delete(d)
+	SyncChildren(list, NewList(item("c", "C"), item("d", "D")).Commit(), key, render)
+	if nd, _ := ChildByKey(list, "d"); nd == d || rendered != 5 || len(list.Children.List) != 2 {
+		t.Fatal("expected the deleted child to be rendered again")
+	}
+}
@@ -0,0 +1,217 @@
+package ui
+
+import (
+	"encoding/json"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Snapshot testing of rendered trees.
+//
+// An Element subtree can be rendered into two canonical, driver-agnostic forms:
+//   - a semantic tree (constructor, id, properties per namespace, children) that can be encoded as JSON
+//   - a canonicalized HTML-like markup where the tag is the constructor name and attributes are the
+//     ui properties sorted by name.
+//
+// Both forms are stable across runs (sorted keys, no pointers, no generated handler information) so that
+// they can be compared to golden files. The snapshottest package provides the golden-file helpers for tests.
+
+// SnapshotNode is the semantic representation of an Element, used for snapshot testing.
+type SnapshotNode struct {
+	Constructor string                            `json:"constructor"`
+	ID          string                            `json:"id"`
+	Props       map[string]map[string]interface{} `json:"props,omitempty"`
+	Children    []SnapshotNode                    `json:"children,omitempty"`
+}
+
+// Snapshot returns the semantic tree of an Element subtree.
+// By default, only the data and ui namespaces are captured. Other namespaces can be specified.
+func Snapshot(e *Element, namespaces ...string) SnapshotNode {
+	if len(namespaces) == 0 {
+		namespaces = []string{Namespace.Data, Namespace.UI}
+	}
+	return snapshot(e, namespaces)
+}
+
+func snapshot(e *Element, namespaces []string) SnapshotNode {
+	n := SnapshotNode{ID: e.ID, Constructor: constructorName(e)}
+
+	for _, ns := range namespaces {
+		cat, ok := e.Properties.Categories[ns]
+		if !ok || len(cat.Local) == 0 {
+			continue
+		}
+		if n.Props == nil {
+			n.Props = make(map[string]map[string]interface{}, len(namespaces))
+		}
+		m := make(map[string]interface{}, len(cat.Local))
+		for k, v := range cat.Local {
+			m[k] = plainValue(v)
+		}
+		n.Props[ns] = m
+	}
+
+	if e.Children != nil {
+		for _, child := range e.Children.List {
+			if child == nil {
+				continue
+			}
+			n.Children = append(n.Children, snapshot(child, namespaces))
+		}
+	}
+	return n
+}
+
+// plainValue converts a Value into its plain Go equivalent (string, float64, bool, []interface{}, map[string]interface{})
+// so that it can be encoded deterministically.
+func plainValue(v Value) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case String:
+		return string(t)
+	case Number:
+		return float64(t)
+	case Bool:
+		return bool(t)
+	case List:
+		l := make([]interface{}, 0, len(t.l))
+		for _, val := range t.l {
+			l = append(l, plainValue(val))
+		}
+		return l
+	case Object:
+		m := make(map[string]interface{}, t.Size())
+		t.Range(func(key string, val Value) bool {
+			m[key] = plainValue(val)
+			return false
+		})
+		return m
+	case object:
+		return plainValue(t.Value())
+	default:
+		return v.RawValue()
+	}
+}
+
+// JSON returns the indented JSON encoding of the semantic tree. Map keys are sorted.
+func (n SnapshotNode) JSON() string {
+	b, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+// HTML returns a canonicalized markup for the semantic tree.
+// The tag name is the constructor name. The attributes are the ui properties, sorted by name.
+// Data properties are rendered as data-* attributes.
+func (n SnapshotNode) HTML() string {
+	var b strings.Builder
+	n.writeHTML(&b, 0)
+	return b.String()
+}
+
+func (n SnapshotNode) writeHTML(b *strings.Builder, depth int) {
+	tag := n.Constructor
+	if tag == "" {
+		tag = "element"
+	}
+	indent := strings.Repeat("  ", depth)
+	b.WriteString(indent)
+	b.WriteString("<")
+	b.WriteString(tag)
+	b.WriteString(` id="`)
+	b.WriteString(html.EscapeString(n.ID))
+	b.WriteString(`"`)
+
+	writeattrs := func(ns string, prefix string) {
+		props := n.Props[ns]
+		keys := make([]string, 0, len(props))
+		for k := range props {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(" ")
+			b.WriteString(prefix + k)
+			b.WriteString(`="`)
+			b.WriteString(html.EscapeString(attrString(props[k])))
+			b.WriteString(`"`)
+		}
+	}
+	writeattrs(Namespace.UI, "")
+	writeattrs(Namespace.Data, "data-")
+
+	if len(n.Children) == 0 {
+		b.WriteString("></")
+		b.WriteString(tag)
+		b.WriteString(">\n")
+		return
+	}
+	b.WriteString(">\n")
+	for _, child := range n.Children {
+		child.writeHTML(b, depth+1)
+	}
+	b.WriteString(indent)
+	b.WriteString("</")
+	b.WriteString(tag)
+	b.WriteString(">\n")
+}
+
+func attrString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			panic(err)
+		}
+		return string(b)
+	}
+}
+
+// SnapshotDiff returns a line-based diff of two snapshots. Removed lines are prefixed by "-",
+// inserted lines by "+" and unchanged lines by a space.
+func SnapshotDiff(want, got string) string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	removed := make(map[int]bool)
+	inserted := make(map[int]bool)
+	for _, op := range MyersDiff(a, b) {
+		switch op.Operation {
+		case "Remove":
+			removed[op.Index] = true
+		case "Insert":
+			inserted[op.Index] = true
+		}
+	}
+
+	var res strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && removed[i]:
+			res.WriteString("-" + a[i] + "\n")
+			i++
+		case j < len(b) && inserted[j]:
+			res.WriteString("+" + b[j] + "\n")
+			j++
+		default:
+			if i < len(a) {
+				res.WriteString(" " + a[i] + "\n")
+			}
+			i++
+			j++
+		}
+	}
+	return res.String()
+}
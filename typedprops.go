@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"fmt"
+)
+
+// Typed properties
+//
+// Prop describes a property by its category and name along with the type of its value, so that reading,
+// writing and watching it does not require type assertions and that a value of the wrong type cannot be set:
+//
+//	var Title = ui.Prop[ui.String](ui.Namespace.Data, "title")
+//
+//	Title.Set(e, ui.String("Home"))
+//	t, ok := Title.Get(e)
+//	Title.Of(e).OnChange(func(t ui.String) bool { ...; return false })
+//
+// A property descriptor holds no state and may be shared by any number of elements.
+// Reading a property that holds a value of a different type, which can only happen if it was set through
+// the untyped API, panics.
+
+// Property is a typed property descriptor.
+type Property[T Value] struct {
+	Category string
+	Name     string
+}
+
+// Prop returns the descriptor of the property of type T named name in the given category.
+func Prop[T Value](category, name string) Property[T] {
+	return Property[T]{category, name}
+}
+
+// Get returns the value of the property for an element.
+func (p Property[T]) Get(a AnyElement) (T, bool) {
+	var zero T
+	v, ok := a.AsElement().Get(p.Category, p.Name)
+	if !ok {
+		return zero, false
+	}
+	return p.cast(v), true
+}
+
+// GetOr returns the value of the property for an element, or def if it has not been set.
+func (p Property[T]) GetOr(a AnyElement, def T) T {
+	v, ok := p.Get(a)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// Set sets the value of the property for an element.
+func (p Property[T]) Set(a AnyElement, v T) {
+	a.AsElement().Set(p.Category, p.Name, v)
+}
+
+// Watch registers a handler, called with the new value, when the property of source changes.
+// The handler is bound to the lifetime of watcher. Returning true stops the propagation of the mutation event
+// to the handlers registered afterwards.
+func (p Property[T]) Watch(watcher AnyElement, source Watchable, h func(T) bool) *MutationHandler {
+	m := NewMutationHandler(func(evt MutationEvent) bool {
+		return h(p.cast(evt.NewValue()))
+	})
+	watcher.AsElement().Watch(p.Category, p.Name, source, m)
+	return m
+}
+
+// Unwatch removes a handler registered with Watch.
+func (p Property[T]) Unwatch(watcher AnyElement, source Watchable, m *MutationHandler) {
+	watcher.AsElement().RemoveMutationHandler(p.Category, p.Name, source, m)
+}
+
+// Of binds the property to an element.
+func (p Property[T]) Of(a AnyElement) BoundProperty[T] {
+	return BoundProperty[T]{p, a.AsElement()}
+}
+
+func (p Property[T]) cast(v Value) T {
+	t, ok := v.(T)
+	if !ok {
+		var zero T
+		panic(fmt.Sprintf("property %s/%s holds a %T value instead of a %T", p.Category, p.Name, v, zero))
+	}
+	return t
+}
+
+// BoundProperty is a typed property of a given element.
+type BoundProperty[T Value] struct {
+	prop    Property[T]
+	element *Element
+}
+
+// Get returns the value of the property.
+func (b BoundProperty[T]) Get() (T, bool) {
+	return b.prop.Get(b.element)
+}
+
+// GetOr returns the value of the property, or def if it has not been set.
+func (b BoundProperty[T]) GetOr(def T) T {
+	return b.prop.GetOr(b.element, def)
+}
+
+// Set sets the value of the property.
+func (b BoundProperty[T]) Set(v T) {
+	b.prop.Set(b.element, v)
+}
+
+// OnChange registers a handler, called with the new value, each time the property changes.
+func (b BoundProperty[T]) OnChange(h func(T) bool) *MutationHandler {
+	return b.prop.Watch(b.element, b.element, h)
+}
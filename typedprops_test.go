@@ -0,0 +1,40 @@
+package ui
+
+import "testing"
+
+func TestTypedProperty(t *testing.T) {
+	c := NewConfiguration("typedproptest", "test")
+	root := c.NewAppRoot("root")
+	e := c.NewElement("item", "test")
+	RegisterElement(root, e)
+
+	title := Prop[String](Namespace.Data, "title")
+	if _, ok := title.Get(e); ok {
+		t.Fatal("expected the property to be unset")
+	}
+	if v := title.GetOr(e, "default"); v != "default" {
+		t.Fatalf("expected the default value, got %v", v)
+	}
+
+	var seen []String
+	title.Of(e).OnChange(func(v String) bool {
+		seen = append(seen, v)
+		return false
+	})
+	title.Set(e, "first")
+	title.Of(e).Set("second")
+	if v, ok := title.Of(e).Get(); !ok || v != "second" {
+		t.Fatalf("expected second, got %v", v)
+	}
+	if len(seen) != 2 || seen[0] != "first" || seen[1] != "second" {
+		t.Fatalf("unexpected values observed: %v", seen)
+	}
+
+	count := Prop[Number](Namespace.Data, "title")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected reading a value of the wrong type to panic")
+		}
+	}()
+	count.Get(e)
+}
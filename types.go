@@ -542,6 +542,10 @@ func (l List) RawValue() object {
 
 	raw := make([]interface{}, 0, len(l.l))
 	for _, v := range l.l {
+		if v == nil {
+			raw = append(raw, nil)
+			continue
+		}
 		raw = append(raw, v.RawValue())
 	}
 	o["zui_object_value"] = raw
@@ -734,6 +738,34 @@ func Copy(v Value) Value {
 	}
 }
 
+// EqualityFunc reports whether two values should be considered equal.
+type EqualityFunc func(old Value, new Value) bool
+
+// NeverEqual is an EqualityFunc for which values are always different.
+var NeverEqual EqualityFunc = func(Value, Value) bool { return false }
+
+// Identical reports whether two values are the same: scalar values are compared by value, while Objects
+// and Lists are only compared by reference. It is a cheap alternative to Equal for large values.
+func Identical(v Value, w Value) bool {
+	switch vv := v.(type) {
+	case Object:
+		wv, ok := w.(Object)
+		return ok && reflect.ValueOf(vv.o).Pointer() == reflect.ValueOf(wv.o).Pointer()
+	case List:
+		wv, ok := w.(List)
+		if !ok || len(vv.l) != len(wv.l) || cap(vv.l) != cap(wv.l) {
+			return false
+		}
+		if cap(vv.l) == 0 {
+			// empty lists without storage may share the same zero-sized allocation
+			return vv.l == nil && wv.l == nil
+		}
+		return reflect.ValueOf(vv.l).Pointer() == reflect.ValueOf(wv.l).Pointer()
+	}
+	return Equal(v, w)
+}
+
+// Equal reports whether two values are deeply equal.
 func Equal(v Value, w Value) bool {
 	// first, let's deal with nil
 	nilv := v == nil
@@ -742,6 +774,9 @@ func Equal(v Value, w Value) bool {
 	if nilv != nilw {
 		return false
 	}
+	if nilv {
+		return true
+	}
 
 	// should be same value types
 	/*if v.ValueType() != w.ValueType() {
@@ -7,6 +7,7 @@ import (
 	"log"
 	"math/rand"
 	"net/http"
+	"path"
 	"strings"
 	"sync"
 	"time"
@@ -127,6 +128,11 @@ type Configuration struct {
 	MutationCapture bool
 	MutationReplay  bool
 	Disconnected    bool // true if the go element tree is not connected to its native counterpart
+	ReplayPolicy    ReplayPolicy
+
+	captureExclusions []CaptureFilter
+	profile           *Profile
+	defaultProfile    *Profile
 }
 
 type storageFunctions struct {
@@ -183,6 +189,10 @@ func NewConfiguration(storeid string, doctype string) *Configuration {
 		false,
 		false,
 		false,
+		AbortReplay,
+		nil,
+		nil,
+		nil,
 	}
 	es.RuntimePropTypes[Namespace.Event] = true
 	es.RuntimePropTypes[Namespace.Navigation] = true
@@ -223,6 +233,44 @@ func (e *Configuration) AddPersistenceMode(name string, loadFromStore func(*Elem
 // It basically captures a trace of the program execution that can be replayed later.
 func (e *Configuration) EnableMutationCapture() *Configuration { e.MutationCapture = true; return e }
 
+// CaptureFilter describes mutations by element id, category and property name patterns, as accepted by
+// path.Match. An empty pattern matches everything.
+type CaptureFilter struct {
+	ID       string
+	Category string
+	Property string
+}
+
+func (f CaptureFilter) matches(id, category, propname string) bool {
+	match := func(pattern, s string) bool {
+		if pattern == "" {
+			return true
+		}
+		ok, err := path.Match(pattern, s)
+		return ok && err == nil
+	}
+	return match(f.ID, id) && match(f.Category, category) && match(f.Property, propname)
+}
+
+// ExcludeFromCapture excludes the mutations matched by the filters from the mutation capture.
+// It is meant for high-frequency mutations that are derived from other mutations (ui echoes of data
+// properties, scroll positions...): since replaying the mutations they derive from recreates them, the trace
+// gets smaller and faster to replay.
+// Excluded mutations should not hold state that can't be derived, or it will be lost on replay.
+func (e *Configuration) ExcludeFromCapture(filters ...CaptureFilter) *Configuration {
+	e.captureExclusions = append(e.captureExclusions, filters...)
+	return e
+}
+
+func (e *Configuration) excludedFromCapture(id, category, propname string) bool {
+	for _, f := range e.captureExclusions {
+		if f.matches(id, category, propname) {
+			return true
+		}
+	}
+	return false
+}
+
 // EnableMutationReplay enables mutation replay of the UI tree. This is used to recover the state corresponding
 // to a UI tree that has already been rendered.
 func (e *Configuration) EnableMutationReplay() *Configuration { e.MutationReplay = true; return e }
@@ -422,6 +470,9 @@ type Element struct {
 	// document state.
 	router     *Router
 	HttpClient *http.Client
+
+	persistencePolicies map[string]PersistencePolicy
+	frozen              int32 // set once a namespace is frozen, so that writes to other elements skip the lookup
 }
 
 func (e *Element) RootUUID() string {
@@ -512,6 +563,8 @@ func (c *Configuration) NewElement(id string, doctype string) *Element {
 		nil,
 		nil,
 		nil,
+		nil,
+		0,
 	}
 
 	e.subtreeRoot = e
@@ -1574,16 +1627,34 @@ func (e *Element) Unwatch(category string, propname string, owner Watchable) *El
 	return e
 }
 
+// RemoveEventListener removes an event handler. The native listener is only removed along with the last
+// handler relying on it.
 func (e *Element) RemoveEventListener(event string, handler *EventHandler) *Element {
+	if handler == nil {
+		return e
+	}
 	e.EventHandlers.RemoveEventHandler(event, handler)
+	if stop, ok := signalledListeners.Get(signalledListener{e, event, handler}); ok {
+		stop()
+	}
 	if NativeEventBridge != nil {
-		if e.NativeEventUnlisteners.List != nil {
-			e.NativeEventUnlisteners.Apply(event)
+		if e.NativeEventUnlisteners.List != nil && !e.EventHandlers.sharesNativeListener(event, handler.Options()) {
+			e.NativeEventUnlisteners.Apply(NativeListenerKey(event, handler.Options()))
 		}
 	}
 	return e
 }
 
+// signalledListener identifies a listener registered with a Signal.
+type signalledListener struct {
+	element *Element
+	event   string
+	handler *EventHandler
+}
+
+// signalledListeners holds the functions stopping the goroutines waiting for the Signal of a listener.
+var signalledListeners = newscsmap[signalledListener, func()]()
+
 // AddEventListener registers a function to be run each time a given event occurs on an element.
 // Once the Go-defined event handler runs, event propagation stops on the native side. It is picked up
 // on the Go side however(the event propagates in the UI tree)
@@ -1596,7 +1667,7 @@ func (e *Element) AddEventListener(event string, handler *EventHandler) *Element
 	h := NewMutationHandler(func(evt MutationEvent) bool {
 		evt.Origin().EventHandlers.AddEventHandler(event, handler)
 		if nativebinding != nil {
-			nativebinding(event, evt.Origin(), handler.Capture)
+			nativebinding(event, evt.Origin(), handler.Options())
 		}
 		return false
 	})
@@ -1607,10 +1678,42 @@ func (e *Element) AddEventListener(event string, handler *EventHandler) *Element
 		return false
 	}))
 
+	if handler.Signal != nil {
+		// The goroutine waiting for the signal stops as soon as the listener is removed, whether because
+		// the signal fired, the listener was removed explicitly, or the element was deleted.
+		key := signalledListener{e, event, handler}
+		done := make(chan struct{})
+		var once sync.Once
+		stop := func() {
+			once.Do(func() {
+				signalledListeners.Delete(key)
+				close(done)
+			})
+		}
+		signalledListeners.Set(key, stop)
+		e.OnDeleted(NewMutationHandler(func(evt MutationEvent) bool {
+			stop()
+			return false
+		}).RunOnce())
+		go func() {
+			select {
+			case <-handler.Signal.Done():
+				DoSync(func() {
+					e.RemoveEventListener(event, handler)
+				})
+			case <-done:
+			}
+		}()
+	}
+
 	return e
 }
 
 func SwapNative(e *Element, newNative NativeElement) *Element {
+	// The native listeners of the previous native element are released so that they are bridged anew.
+	for key := range e.NativeEventUnlisteners.List {
+		e.NativeEventUnlisteners.Apply(key)
+	}
 	e.Native = newNative
 
 	nativebinding := NativeEventBridge
@@ -1621,16 +1724,17 @@ func SwapNative(e *Element, newNative NativeElement) *Element {
 					for _, handler := range handlerlist {
 						h := NewMutationHandler(func(evt MutationEvent) bool {
 							if nativebinding != nil {
-								nativebinding(event, evt.Origin(), handler.Capture)
+								nativebinding(event, evt.Origin(), handler.Options())
 							}
 							return false
 						})
 						e.OnMounted(h.RunASAP().RunOnce())
 
+						key := NativeListenerKey(event, handler.Options())
 						e.OnDeleted(NewMutationHandler(func(evt MutationEvent) bool {
 							if NativeEventBridge != nil {
 								if e.NativeEventUnlisteners.List != nil {
-									e.NativeEventUnlisteners.Apply(event)
+									e.NativeEventUnlisteners.Apply(key)
 								}
 							}
 							return false
@@ -1810,16 +1914,21 @@ func (e *Element) Set(category string, propname string, value Value) {
 		panic("category string and/or propname seems to contain a slash. This is not accepted, try a base32 encoding. (" + category + "," + propname + ")")
 	}
 
+	warnDeadElementMutation(e, category, propname)
+	if rejectFrozenMutation(e, category, propname) {
+		return
+	}
+
 	oldvalue, ok := e.Properties.Get(category, propname)
 
 	if ok && category != Namespace.Event {
-		if Equal(value, oldvalue) { // idempotency
+		if e.Properties.Categories[category].Equal(propname, oldvalue, value) { // idempotency
 			return
 		}
 	}
 
 	if MutationReplaying(e) {
-		if !shouldSkip(category, propname) {
+		if !skipMutation(e, category, propname) {
 			idx, ok := e.Root.Get(Namespace.Internals, "mutation-list-index")
 			if !ok {
 				e.Root.Set(Namespace.Internals, "mutation-list-index", Number(1))
@@ -1832,7 +1941,7 @@ func (e *Element) Set(category string, propname string, value Value) {
 	e.Properties.Set(category, propname, value)
 
 	if mutationcapturing(e) {
-		if !shouldSkip(category, propname) {
+		if !skipMutation(e, category, propname) {
 			m := NewObject()
 			m.Set("id", String(e.ID))
 			m.Set("cat", String(category))
@@ -1894,6 +2003,12 @@ func MutationReplaying(e *Element) bool {
 	return v.(Bool).Bool()
 }
 
+// skipMutation reports whether a mutation is neither captured nor counted during replay.
+// Both need to agree for the replay to remain in sync with the captured trace.
+func skipMutation(e *Element, category, propname string) bool {
+	return shouldSkip(category, propname) || e.Configuration.excludedFromCapture(e.ID, category, propname)
+}
+
 func shouldSkip(category, propname string) bool {
 	if category == Namespace.Internals && propname == "mutation-list-index" {
 		return true
@@ -1927,6 +2042,8 @@ func shouldSkip(category, propname string) bool {
 			return true
 		case "mutation-replayed":
 			return true
+		case "replay-progress":
+			return true
 		}
 	}
 
@@ -1958,7 +2075,7 @@ func ReplayMutation(e *Element, category string, propname string, value Value, s
 	oldvalue, ok := e.Properties.Get(category, propname)
 
 	if ok && category != Namespace.Event {
-		if Equal(value, oldvalue) { // idempotency
+		if e.Properties.Categories[category].Equal(propname, oldvalue, value) { // idempotency
 			return
 		}
 	}
@@ -2010,6 +2127,20 @@ func (e *Element) GetData(propname string) (Value, bool) {
 	return e.Get(Namespace.Data, propname)
 }
 
+// SetEqualityFunc changes the way a property value is compared to its previous value when the property
+// is set. Setting a property to an equal value is a no-op: no mutation event is dispatched.
+// By default, values are deeply compared, which may be costly for large values on hot paths: Identical
+// may then be used instead. NeverEqual disables the short-circuiting altogether.
+func (e *Element) SetEqualityFunc(category string, propname string, fn EqualityFunc) *Element {
+	ps, ok := e.Properties.Categories[category]
+	if !ok {
+		ps = newProperties()
+		e.Properties.Categories[category] = ps
+	}
+	ps.Equality[propname] = fn
+	return e
+}
+
 // SetData inserts a key/value pair under the "data" category in the element property store.
 // It does not automatically update any potential property representation stored
 // for rendering use in the "ui" category/namespace.
@@ -2053,9 +2184,18 @@ func (e *Element) SyncUI(propname string, value Value) *Element {
 	if strings.Contains(propname, "/") {
 		panic("category string and/or propname seems to contain a slash. This is not accepted, try a base32 encoding. (" + propname + ")")
 	}
+	if rejectFrozenMutation(e, Namespace.UI, propname) {
+		return e
+	}
+
+	if oldvalue, ok := e.Properties.Get(Namespace.UI, propname); ok {
+		if e.Properties.Categories[Namespace.UI].Equal(propname, oldvalue, value) { // idempotency
+			return e
+		}
+	}
 
 	if MutationReplaying(e) {
-		if !shouldSkip(Namespace.UI, propname) {
+		if !skipMutation(e, Namespace.UI, propname) {
 			idx, ok := e.Root.Get(Namespace.Internals, "mutation-list-index")
 			if !ok {
 				e.Root.Set(Namespace.Internals, "mutation-list-index", Number(1))
@@ -2068,7 +2208,7 @@ func (e *Element) SyncUI(propname string, value Value) *Element {
 	e.Properties.Set(Namespace.UI, propname, value)
 
 	if mutationcapturing(e) {
-		if e.Registered() {
+		if e.Registered() && !e.Configuration.excludedFromCapture(e.ID, Namespace.UI, propname) {
 			m := NewObject()
 			m.Set("id", String(e.ID))
 			m.Set("cat", String(Namespace.UI))
@@ -2404,10 +2544,20 @@ func (p PropertyStore) HasProperty(category string, propname string) bool {
 type Properties struct {
 	Local    map[string]Value
 	Watchers map[string]*Elements
+	Equality map[string]EqualityFunc
 }
 
 func newProperties() Properties {
-	return Properties{make(map[string]Value, 128), make(map[string]*Elements, 64)}
+	return Properties{make(map[string]Value, 128), make(map[string]*Elements, 64), make(map[string]EqualityFunc)}
+}
+
+// Equal reports whether two values of a property should be considered equal, using the equality function
+// registered for the property if any, or deep equality otherwise.
+func (p Properties) Equal(propname string, old, new Value) bool {
+	if eq, ok := p.Equality[propname]; ok && eq != nil {
+		return eq(old, new)
+	}
+	return Equal(old, new)
 }
 
 func (p Properties) NewWatcher(propName string, watcher *Element) {
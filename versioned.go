@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Versioned serialization
+//
+// Values that outlive a run of the app (persisted properties, navigation history, SSR state, mutation traces)
+// are encoded with Marshal, which wraps their raw representation in an envelope holding the version of the
+// encoding format.
+// When the format changes, a migration is registered to upgrade the raw representation of the previous version.
+// Unmarshal applies the migrations needed for older data. Data without envelope is considered to be of version 0.
+//
+// Decoding is tolerant: fields that are not zui-encoded are decoded as plain JSON values, null fields are
+// dropped (null list items are decoded as nil Values instead) and objects of an unknown type (e.g. written by a newer version of the framework) are decoded as
+// Objects with their type and fields preserved, instead of failing.
+
+// EncodingVersion is the current version of the encoding format of Values.
+const EncodingVersion = 1
+
+// Migration upgrades the raw (JSON decoded) representation of a value from a given version to the next one.
+type Migration func(raw any) (any, error)
+
+var migrations = struct {
+	sync.Mutex
+	m map[int]Migration
+}{m: make(map[int]Migration)}
+
+// RegisterMigration registers the migration from version from to version from+1.
+func RegisterMigration(from int, m Migration) {
+	migrations.Lock()
+	defer migrations.Unlock()
+	migrations.m[from] = m
+}
+
+type envelope struct {
+	Version int `json:"zui_version"`
+	Value   any `json:"zui_value"`
+}
+
+// Marshal returns the versioned JSON encoding of a Value.
+func Marshal(v Value) ([]byte, error) {
+	return json.Marshal(envelope{EncodingVersion, v.RawValue()})
+}
+
+// Unmarshal decodes data encoded by Marshal, or unversioned data, migrating it to the current version
+// if needed.
+func Unmarshal(data []byte) (Value, error) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	version := 0
+	if m, ok := raw.(map[string]any); ok {
+		if ver, ok := m["zui_version"].(float64); ok {
+			version = int(ver)
+			raw = m["zui_value"]
+		}
+	}
+
+	if version > EncodingVersion {
+		DEBUG("decoding a value encoded with a newer format version: ", version)
+	}
+	for ; version < EncodingVersion; version++ {
+		migrations.Lock()
+		m, ok := migrations.m[version]
+		migrations.Unlock()
+		if !ok {
+			continue
+		}
+		var err error
+		raw, err = m(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to migrate value from version %d: %w", version, err)
+		}
+	}
+
+	v := DecodeRaw(raw)
+	if v == nil {
+		return nil, fmt.Errorf("unable to decode value: %s", data)
+	}
+	return v, nil
+}
+
+// DecodeRaw returns the Value corresponding to a JSON decoded raw representation. It returns nil for null.
+func DecodeRaw(raw any) Value {
+	switch t := raw.(type) {
+	case bool:
+		return Bool(t)
+	case string:
+		return String(t)
+	case float64:
+		return Number(t)
+	case []any:
+		// null items are kept as nil Values so that the positions of the other items are preserved.
+		l := NewList()
+		for _, r := range t {
+			l.Append(DecodeRaw(r))
+		}
+		return l.Commit()
+	case map[string]any:
+		typ, _ := t["zui_object_typ"].(string)
+		switch typ {
+		case "Bool", "String", "Number", "List":
+			return DecodeRaw(t["zui_object_value"])
+		}
+		o := NewObject()
+		for k, r := range t {
+			if strings.HasPrefix(k, "zui_object_") {
+				continue
+			}
+			if v := DecodeRaw(r); v != nil {
+				o.Set(k, v)
+			}
+		}
+		obj := o.Commit()
+		if typ != "" && typ != "Object" {
+			obj = obj.setType(typ)
+		}
+		return obj
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	v := NewObject().
+		Set("name", String("zui")).
+		Set("count", Number(3)).
+		Set("tags", NewList(String("a"), Bool(true)).Commit()).
+		Set("nested", NewObject().Set("ok", Bool(false)).Commit()).
+		Commit()
+
+	b, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(v, w) {
+		t.Errorf("expected %v, got %v", v, w)
+	}
+}
+
+func TestMarshalListWithNull(t *testing.T) {
+	v := NewList(String("a"), nil, Number(2)).Commit()
+
+	b, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := w.(List)
+	if len(l.UnsafelyUnwrap()) != 3 || l.Get(1) != nil || l.Get(2) != Number(2) {
+		t.Fatalf("expected null items to keep their position, got %v", l.UnsafelyUnwrap())
+	}
+	if !Equal(v, w) {
+		t.Errorf("expected %v, got %v", v, w)
+	}
+
+	w, err = Unmarshal([]byte(`{"items":["a",null,"b"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := w.(Object).MustGetList("items").UnsafelyUnwrap(); len(items) != 3 || items[2] != String("b") {
+		t.Errorf("expected null items of plain JSON lists to keep their position, got %v", items)
+	}
+}
+
+func TestUnmarshalLegacyAndUnknownFields(t *testing.T) {
+	legacy := `{"zui_object_typ":"Object","a":{"zui_object_typ":"String","zui_object_value":"x"},"plain":{"b":1},"gone":null}`
+	v, err := Unmarshal([]byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := v.(Object)
+	if s := o.MustGetString("a"); s != "x" {
+		t.Errorf("expected a to be x, got %v", s)
+	}
+	if n := o.MustGetObject("plain").MustGetNumber("b"); n != 1 {
+		t.Errorf("expected plain field to be preserved, got %v", n)
+	}
+	if _, ok := o.Get("gone"); ok {
+		t.Error("null fields should be dropped")
+	}
+
+	future := `{"zui_version":99,"zui_value":{"zui_object_typ":"Set","x":{"zui_object_typ":"Number","zui_object_value":2}}}`
+	v, err = Unmarshal([]byte(future))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := v.(Object).MustGetNumber("x"); n != 2 {
+		t.Errorf("expected unknown object type to be decoded as an Object, got %v", v)
+	}
+	if typ := v.ValueType(); typ != "Set" {
+		t.Errorf("expected object type to be preserved, got %v", typ)
+	}
+}
+
+func TestUnmarshalMigration(t *testing.T) {
+	RegisterMigration(0, func(raw any) (any, error) {
+		m, ok := raw.(map[string]any)
+		if !ok {
+			return raw, nil
+		}
+		if v, ok := m["old"]; ok {
+			m["new"] = v
+			delete(m, "old")
+		}
+		return m, nil
+	})
+	defer func() {
+		migrations.Lock()
+		delete(migrations.m, 0)
+		migrations.Unlock()
+	}()
+
+	v, err := Unmarshal([]byte(`{"zui_object_typ":"Object","old":{"zui_object_typ":"Bool","zui_object_value":true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := v.(Object).MustGetBool("new"); !bool(b) {
+		t.Errorf("expected migrated field, got %v", v)
+	}
+}
+
+// TestBinaryTextSize checks that the base64 encoded binary encoding of typical state remains smaller than
+// its JSON encoding, since the binary codec is the default codec of the text-only stores.
+func TestBinaryTextSize(t *testing.T) {
+	record := func(i int) Value {
+		return NewObject().
+			Set("id", String("todo-"+strings.Repeat("x", i%8))).
+			Set("title", String("Buy some groceries")).
+			Set("done", Bool(i%2 == 0)).
+			Set("order", Number(i)).
+			Commit()
+	}
+	records := NewList()
+	for i := 0; i < 20; i++ {
+		records.Append(record(i))
+	}
+	history := NewObject().
+		Set("cursor", Number(2)).
+		Set("stack", NewList(String("/"), String("/todos"), String("/todos/active")).Commit()).
+		Commit()
+
+	for _, v := range []Value{String("draft"), record(1), records.Commit(), history} {
+		s, err := EncodeText(BinaryCodec, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j, err := EncodeText(JSONCodec, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if 2*len(s) > len(j) {
+			t.Errorf("expected base64 encoded binary encoding to be less than half the size of json: %d vs %d", len(s), len(j))
+		}
+	}
+}
+
+func TestBinaryCodec(t *testing.T) {
+	v := NewObject().
+		Set("name", String(strings.Repeat("z", 40))).
+		Set("ints", NewList(Number(0), Number(-1), Number(200), Number(-70000), Number(1<<40)).Commit()).
+		Set("float", Number(3.14)).
+		Set("flag", Bool(true)).
+		Set("nested", NewObject().Set("empty", NewList().Commit()).Commit()).
+		Commit()
+
+	s, err := EncodeText(BinaryCodec, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := DecodeText(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(v, w) {
+		t.Errorf("expected %v, got %v", v, w)
+	}
+
+	j, err := EncodeText(JSONCodec, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) >= len(j) {
+		t.Errorf("expected binary encoding to be smaller than json: %d >= %d", len(s), len(j))
+	}
+	w, err = DecodeText(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(v, w) {
+		t.Errorf("expected %v, got %v", v, w)
+	}
+
+	withnull := NewList(String("a"), nil, Number(2)).Commit()
+	s, err = EncodeText(BinaryCodec, withnull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, err = DecodeText(s); err != nil || !Equal(withnull, w) {
+		t.Errorf("expected nil list items to keep their position, got %v (%v)", w, err)
+	}
+
+	b, _ := BinaryCodec.Encode(v)
+	if _, err := BinaryCodec.Decode(b[:len(b)-3]); err == nil {
+		t.Error("expected truncated data to fail decoding")
+	}
+}
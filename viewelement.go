@@ -298,13 +298,17 @@ func (v ViewElement) IsParameterizedView(viewname string) bool {
 
 // prefetchView triggers data prefetching for a ViewElement.
 // It requires the name of the view that will be activated as argument so that it can start
-// prefetching the elements that are part of the target view (if unactivated).
-// and then triggers prefetching on the view itself.
-func (v ViewElement) prefetchView(name string) {
+// prefetching the elements that are part of the target view (if unactivated), including the default
+// views of the ViewElements nested within, as allowed by PrefetchDepth and the remaining budget.
+func (v ViewElement) prefetchView(name string, budget *int) {
 	ve := v.AsElement()
 	if v.HasStaticView(name) && v.IsViewAuthorized(name) && ve.ActiveView != name {
-		for _, c := range ve.Children.List {
-			c.Prefetch()
+		view := ve.retrieveView(name)
+		if view == nil || view.Elements() == nil {
+			return
+		}
+		for _, c := range view.Elements().List {
+			prefetchTree(c, PrefetchDepth, budget)
 		}
 	}
 }
@@ -404,10 +408,12 @@ func (e *Element) activateView(name string) {
 				panic("FAILURE: parameterized view is activated but no activeview name exists in state")
 			}
 			if nm := string(n.(String)); nm == name {
+				recordRouteParam(e, e.ActiveView, name)
 				e.EndTransition(prop.ActivateView, String(name)) // already active
 				return
 			}
 
+			recordRouteParam(e, e.ActiveView, name)
 			e.Set(Namespace.UI, "viewparameter", String(name)) // necessary because not every change of (ui,activeview) is a viewparameter change.
 			e.EndTransition(prop.ActivateView, String(name))
 			return
@@ -425,6 +431,7 @@ func (e *Element) activateView(name string) {
 
 		e.SetChildren(view.elements.List...)
 
+		recordRouteParam(e, e.ActiveView, name)
 		e.Set(Namespace.UI, "viewparameter", String(name))
 		e.EndTransition(prop.ActivateView, String(name))
 		return